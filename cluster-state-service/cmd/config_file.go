@@ -0,0 +1,146 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blox/blox/cluster-state-service/config"
+	"github.com/blox/blox/cluster-state-service/logger"
+	"github.com/blox/blox/shared/ecsclient"
+	log "github.com/cihub/seelog"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	maxSQSWaitTime          = 20 * time.Second
+	maxSQSVisibilityTimeout = 12 * time.Hour
+)
+
+// LoadConfigFile applies the settings from the config file and the environment to the
+// flags that were not set on the command line, then validates the resulting configuration.
+func LoadConfigFile() error {
+	return loadConfigFile(RootCmd.PersistentFlags())
+}
+
+func loadConfigFile(flags *pflag.FlagSet) error {
+	if config.ConfigFile != "" {
+		viper.SetConfigFile(config.ConfigFile)
+		if err := viper.ReadInConfig(); err != nil {
+			return errors.Wrapf(err, "Could not read the config file %s", config.ConfigFile)
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Name == configFlag || !isSetOutsideCommandLine(flag) {
+			return
+		}
+		err = setFlagValue(flag, viper.Get(flag.Name))
+	})
+	if err != nil {
+		return err
+	}
+
+	// the rate limits of single APIs are parsed once every source of settings has been applied
+	config.ECSThrottle.APIRateLimits, err = ecsclient.ParseAPIRateLimits(config.ECSAPIRateLimits)
+	if err != nil {
+		return err
+	}
+
+	return validate()
+}
+
+// WatchConfigFile reloads the config file whenever it changes and calls onChange with the new
+// reloadable settings. Changes to other settings only take effect after a restart, invalid
+// changes are logged and ignored, and flags set on the command line take precedence.
+func WatchConfigFile(onChange func(config.Reloadable)) {
+	if config.ConfigFile == "" {
+		return
+	}
+
+	viper.OnConfigChange(func(fsnotify.Event) {
+		reloadable, err := reloadableSettings(RootCmd.PersistentFlags())
+		if err != nil {
+			log.Errorf("Ignoring changes to the config file %s: %+v", config.ConfigFile, err)
+			return
+		}
+		onChange(reloadable)
+	})
+	viper.WatchConfig()
+}
+
+func reloadableSettings(flags *pflag.FlagSet) (config.Reloadable, error) {
+	reloadable := config.Reloadable{
+		LogLevel:          config.LogLevel,
+		ReconcileInterval: config.ReconcileInterval,
+	}
+	if isSetOutsideCommandLine(flags.Lookup(logLevelFlag)) {
+		reloadable.LogLevel = viper.GetString(logLevelFlag)
+	}
+	if isSetOutsideCommandLine(flags.Lookup(reconcileIntervalFlag)) {
+		reloadable.ReconcileInterval = viper.GetDuration(reconcileIntervalFlag)
+	}
+	return reloadable, validateReloadable(reloadable)
+}
+
+func isSetOutsideCommandLine(flag *pflag.Flag) bool {
+	return !flag.Changed && viper.IsSet(flag.Name)
+}
+
+func setFlagValue(flag *pflag.Flag, value interface{}) error {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	for _, v := range values {
+		if err := flag.Value.Set(fmt.Sprint(v)); err != nil {
+			return errors.Wrapf(err, "Invalid value for %s", flag.Name)
+		}
+	}
+	return nil
+}
+
+func validate() error {
+	if err := validateReloadable(config.Reloadable{
+		LogLevel:          config.LogLevel,
+		ReconcileInterval: config.ReconcileInterval,
+	}); err != nil {
+		return err
+	}
+	if config.SQSWaitTime < 0 || config.SQSWaitTime > maxSQSWaitTime {
+		return errors.Errorf("The %s must be between 0s and %s", sqsWaitTimeFlag, maxSQSWaitTime)
+	}
+	if config.SQSVisibilityTimeout < 0 || config.SQSVisibilityTimeout > maxSQSVisibilityTimeout {
+		return errors.Errorf("The %s must be between 0s and %s", sqsVisibilityFlag, maxSQSVisibilityTimeout)
+	}
+	if config.ServerReadTimeout <= 0 {
+		return errors.Errorf("The %s must be positive", serverReadTimeoutFlag)
+	}
+	return config.ECSThrottle.Validate()
+}
+
+func validateReloadable(reloadable config.Reloadable) error {
+	if !logger.IsValidLogLevel(reloadable.LogLevel) {
+		return errors.Errorf("Unknown %s %s", logLevelFlag, reloadable.LogLevel)
+	}
+	if reloadable.ReconcileInterval <= 0 {
+		return errors.Errorf("The %s must be positive", reconcileIntervalFlag)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blox/blox/cluster-state-service/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, name string, contents string) string {
+	dir, err := ioutil.TempDir("", "css-config")
+	assert.Nil(t, err, "Could not create a temp dir")
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(contents), 0600), "Could not write the config file")
+	return path
+}
+
+func executeWithConfigFile(t *testing.T, path string, args string) error {
+	viper.Reset()
+	rootCmd := createRootCommand()
+	rootCmd.SetArgs(strings.Split("--config "+path+" "+args, " "))
+	assert.NoError(t, rootCmd.Execute(), "Error processing the --config flag")
+	return loadConfigFile(rootCmd.PersistentFlags())
+}
+
+func TestLoadConfigFileYAML(t *testing.T) {
+	path := writeConfigFile(t, "css.yaml", `
+queue: sqs://events
+etcd-endpoint:
+  - e1
+  - e2
+reconcile-interval: 5m
+sqs-wait-time: 20s
+log-level: debug
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	err := executeWithConfigFile(t, path, "--log-level warn")
+	assert.Nil(t, err, "Unexpected error loading the config file")
+	assert.Equal(t, "sqs://events", config.QueueNameURI, "Unexpected queue name set")
+	assert.Equal(t, []string{"e1", "e2"}, config.EtcdEndpoints, "Unexpected etcd endpoints set")
+	assert.Equal(t, 5*time.Minute, config.ReconcileInterval, "Unexpected reconcile interval set")
+	assert.Equal(t, 20*time.Second, config.SQSWaitTime, "Unexpected sqs wait time set")
+	assert.Equal(t, "warn", config.LogLevel, "Expected the command line to take precedence over the config file")
+}
+
+func TestLoadConfigFileTOML(t *testing.T) {
+	path := writeConfigFile(t, "css.toml", `
+queue = "kinesis://events"
+reconcile-interval = "1h"
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	err := executeWithConfigFile(t, path, "--bind :3000")
+	assert.Nil(t, err, "Unexpected error loading the config file")
+	assert.Equal(t, "kinesis://events", config.QueueNameURI, "Unexpected queue name set")
+	assert.Equal(t, time.Hour, config.ReconcileInterval, "Unexpected reconcile interval set")
+	assert.Equal(t, ":3000", config.CSSBindAddr, "Unexpected bind address set")
+}
+
+func TestLoadConfigFileInvalidSettings(t *testing.T) {
+	path := writeConfigFile(t, "css.yaml", "sqs-wait-time: 1m\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	assert.Error(t, executeWithConfigFile(t, path, "--bind :3000"), "Expected error with a wait time above the SQS limit")
+
+	path = writeConfigFile(t, "css.yaml", "log-level: verbose\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	assert.Error(t, executeWithConfigFile(t, path, "--bind :3000"), "Expected error with an unknown log level")
+
+	path = writeConfigFile(t, "css.yaml", "ecs-api-rate-limit:\n  - ListTasks=fast\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	assert.Error(t, executeWithConfigFile(t, path, "--bind :3000"), "Expected error with an ECS API rate limit that is not a number")
+}
+
+func TestLoadConfigFileECSThrottle(t *testing.T) {
+	path := writeConfigFile(t, "css.toml", `
+ecs-burst = 5
+ecs-api-rate-limit = ["ListContainerInstances=2"]
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	err := executeWithConfigFile(t, path, "--ecs-max-retries 8")
+	assert.Nil(t, err, "Unexpected error loading the config file")
+	assert.Equal(t, 5, config.ECSThrottle.Burst, "Unexpected ECS burst set")
+	assert.Equal(t, map[string]float64{"ListContainerInstances": 2}, config.ECSThrottle.APIRateLimits,
+		"Unexpected ECS API rate limits set")
+	assert.Equal(t, 8, config.ECSThrottle.MaxRetries, "Unexpected ECS max retries set")
+}
+
+func TestReloadableSettings(t *testing.T) {
+	path := writeConfigFile(t, "css.yaml", "reconcile-interval: 5m\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	viper.Reset()
+	rootCmd := createRootCommand()
+	rootCmd.SetArgs(strings.Split("--config "+path+" --log-level error", " "))
+	assert.NoError(t, rootCmd.Execute(), "Error processing the --config flag")
+	assert.Nil(t, loadConfigFile(rootCmd.PersistentFlags()), "Unexpected error loading the config file")
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("reconcile-interval: 1m\nlog-level: debug\n"), 0600), "Could not update the config file")
+	assert.Nil(t, viper.ReadInConfig(), "Could not read the updated config file")
+
+	reloadable, err := reloadableSettings(rootCmd.PersistentFlags())
+	assert.Nil(t, err, "Unexpected error reloading settings")
+	assert.Equal(t, config.Reloadable{LogLevel: "error", ReconcileInterval: time.Minute}, reloadable, "Unexpected reloaded settings")
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("reconcile-interval: 0s\n"), 0600), "Could not update the config file")
+	assert.Nil(t, viper.ReadInConfig(), "Could not read the updated config file")
+	_, err = reloadableSettings(rootCmd.PersistentFlags())
+	assert.Error(t, err, "Expected error reloading an invalid interval")
+}
@@ -14,16 +14,46 @@
 package cmd
 
 import (
+	"strings"
+
 	"github.com/blox/blox/cluster-state-service/config"
+	"github.com/blox/blox/cluster-state-service/handler/clients"
+	"github.com/blox/blox/cluster-state-service/handler/event"
+	"github.com/blox/blox/cluster-state-service/handler/reconcile"
+	"github.com/blox/blox/cluster-state-service/handler/run"
+	"github.com/blox/blox/cluster-state-service/logger"
+	"github.com/blox/blox/shared/ecsclient"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 const (
-	queueNameURIFlag = "queue"
-	cssBindFlag      = "bind"
-	etcdEndpointFlag = "etcd-endpoint"
-	versionFlag      = "version"
+	queueNameURIFlag        = "queue"
+	cssBindFlag             = "bind"
+	etcdEndpointFlag        = "etcd-endpoint"
+	etcdCAFlag              = "etcd-ca"
+	etcdCertFlag            = "etcd-cert"
+	etcdKeyFlag             = "etcd-key"
+	etcdUsernameFlag        = "etcd-username"
+	etcdPasswordFlag        = "etcd-password"
+	etcdDialTimeoutFlag     = "etcd-dial-timeout"
+	etcdRequestTimeoutFlag  = "etcd-request-timeout"
+	etcdKeyPrefixFlag       = "etcd-key-prefix"
+	configFlag              = "config"
+	logLevelFlag            = "log-level"
+	reconcileIntervalFlag   = "reconcile-interval"
+	sqsWaitTimeFlag         = "sqs-wait-time"
+	sqsVisibilityFlag       = "sqs-visibility-timeout"
+	serverReadTimeoutFlag   = "server-read-timeout"
+	ecsRateLimitFlag        = "ecs-rate-limit"
+	ecsBurstFlag            = "ecs-burst"
+	ecsAPIRateLimitFlag     = "ecs-api-rate-limit"
+	ecsMaxRetriesFlag       = "ecs-max-retries"
+	ecsCircuitThresholdFlag = "ecs-circuit-threshold"
+	ecsCircuitCooldownFlag  = "ecs-circuit-cooldown"
+	versionFlag             = "version"
+
+	envPrefix = "CSS"
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -48,11 +78,34 @@ a localized data store, which provides you a near-real-time view of your cluster
 	rootCmd.PersistentFlags().StringVar(&config.QueueNameURI, queueNameURIFlag, "", "Queue name should be of the form sqs://name or kinesis://name")
 	rootCmd.PersistentFlags().StringVar(&config.CSSBindAddr, cssBindFlag, "", "Cluster State Service listen address")
 	rootCmd.PersistentFlags().StringArrayVar(&config.EtcdEndpoints, etcdEndpointFlag, make([]string, 0), "Etcd node addresses")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdCAFile, etcdCAFlag, "", "Path to the CA certificate used to verify etcd servers")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdCertFile, etcdCertFlag, "", "Path to the client certificate used to connect to etcd")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdKeyFile, etcdKeyFlag, "", "Path to the client key used to connect to etcd")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdUsername, etcdUsernameFlag, "", "Etcd username")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdPassword, etcdPasswordFlag, "", "Etcd password, read from ETCD_PASSWORD if not set")
+	rootCmd.PersistentFlags().DurationVar(&config.EtcdDialTimeout, etcdDialTimeoutFlag, clients.DefaultDialTimeout, "Timeout for connecting to etcd")
+	rootCmd.PersistentFlags().DurationVar(&config.EtcdRequestTimeout, etcdRequestTimeoutFlag, clients.DefaultRequestTimeout, "Timeout for a single etcd request")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdKeyPrefix, etcdKeyPrefixFlag, "", "Prefix of every key stored in etcd, to share an etcd cluster between deployments")
+	rootCmd.PersistentFlags().StringVar(&config.ConfigFile, configFlag, "", "Path to a YAML or TOML file with settings named after these flags")
+	rootCmd.PersistentFlags().StringVar(&config.LogLevel, logLevelFlag, logger.DefaultLogLevel, "Log level, one of debug, info, warn, error, crit or none")
+	rootCmd.PersistentFlags().DurationVar(&config.ReconcileInterval, reconcileIntervalFlag, reconcile.ReconcileDuration, "Interval between reconciling the datastore with ECS")
+	rootCmd.PersistentFlags().DurationVar(&config.SQSWaitTime, sqsWaitTimeFlag, event.DefaultSQSWaitTime, "Time to wait for messages when polling SQS, at most 20s")
+	rootCmd.PersistentFlags().DurationVar(&config.SQSVisibilityTimeout, sqsVisibilityFlag, event.DefaultSQSVisibilityTimeout, "Time received SQS messages are hidden from other consumers")
+	rootCmd.PersistentFlags().DurationVar(&config.ServerReadTimeout, serverReadTimeoutFlag, run.DefaultServerReadTimeout, "Maximum duration for reading a request")
+	rootCmd.PersistentFlags().Float64Var(&config.ECSThrottle.RateLimit, ecsRateLimitFlag, ecsclient.DefaultRateLimit, "Requests per second each ECS API is called at, 0 for no limit")
+	rootCmd.PersistentFlags().IntVar(&config.ECSThrottle.Burst, ecsBurstFlag, ecsclient.DefaultBurst, "Requests each ECS API may be called at once after being idle")
+	rootCmd.PersistentFlags().StringArrayVar(&config.ECSAPIRateLimits, ecsAPIRateLimitFlag, make([]string, 0), "Rate limit of a single ECS API as Operation=rate, such as DescribeTasks=20")
+	rootCmd.PersistentFlags().IntVar(&config.ECSThrottle.MaxRetries, ecsMaxRetriesFlag, ecsclient.DefaultMaxRetries, "Times a throttled or failed ECS request is retried")
+	rootCmd.PersistentFlags().IntVar(&config.ECSThrottle.CircuitThreshold, ecsCircuitThresholdFlag, ecsclient.DefaultCircuitThreshold, "Consecutive throttled or failed ECS requests after which ECS is not called for the circuit cooldown, 0 to never stop")
+	rootCmd.PersistentFlags().DurationVar(&config.ECSThrottle.CircuitCooldown, ecsCircuitCooldownFlag, ecsclient.DefaultCircuitCooldown, "Time ECS is not called for after too many throttled or failed requests")
 	rootCmd.PersistentFlags().BoolVar(&config.PrintVersion, versionFlag, false, "Print version and exit")
 	return rootCmd
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	// settings can be given as environment variables such as CSS_LOG_LEVEL
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv() // read in environment variables that match
 }
@@ -13,9 +13,30 @@
 
 package config
 
+import (
+	"time"
+
+	"github.com/blox/blox/shared/ecsclient"
+)
+
 // EtcdEndpoints represents the etcd servers to connect to.
 var EtcdEndpoints []string
 
+// EtcdCAFile, EtcdCertFile and EtcdKeyFile represent the TLS files used to connect to etcd.
+var EtcdCAFile, EtcdCertFile, EtcdKeyFile string
+
+// EtcdUsername and EtcdPassword represent the credentials used to authenticate with etcd.
+var EtcdUsername, EtcdPassword string
+
+// EtcdDialTimeout represents the timeout for connecting to etcd.
+var EtcdDialTimeout time.Duration
+
+// EtcdRequestTimeout represents the timeout for a single etcd request.
+var EtcdRequestTimeout time.Duration
+
+// EtcdKeyPrefix represents the prefix of every key stored in etcd.
+var EtcdKeyPrefix string
+
 // QueueName represents the queue name to listen to for ECS events. Formatted as
 // a URI with the scheme determining the type.  For example sqs://name or kinesis://name
 var QueueNameURI string
@@ -25,3 +46,32 @@ var CSSBindAddr string
 
 // PrintVersion represents the flag to set when printing version information.
 var PrintVersion bool
+
+// ConfigFile represents the path of the YAML or TOML file to read settings from.
+var ConfigFile string
+
+// LogLevel represents the minimum level of the messages that are logged.
+var LogLevel string
+
+// ReconcileInterval represents the interval between reconciling the datastore with ECS.
+var ReconcileInterval time.Duration
+
+// SQSWaitTime and SQSVisibilityTimeout represent how long to wait for messages when
+// polling SQS and how long received messages are hidden from other consumers.
+var SQSWaitTime, SQSVisibilityTimeout time.Duration
+
+// ServerReadTimeout represents the maximum duration for reading a request.
+var ServerReadTimeout time.Duration
+
+// ECSThrottle represents the rate limits, retries and circuit breaking of ECS requests. Its
+// APIRateLimits are parsed from ECSAPIRateLimits.
+var ECSThrottle = ecsclient.DefaultConfig()
+
+// ECSAPIRateLimits represents the rate limits of single ECS APIs, given as Operation=rate.
+var ECSAPIRateLimits []string
+
+// Reloadable holds the settings that can change while the service is running.
+type Reloadable struct {
+	LogLevel          string
+	ReconcileInterval time.Duration
+}
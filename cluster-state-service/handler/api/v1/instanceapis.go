@@ -169,7 +169,9 @@ func (instanceAPIs ContainerInstanceAPIs) ListInstances(w http.ResponseWriter, r
 
 // StreamInstances streams container instances that change (status, resources, etc.) across all clusters
 func (instanceAPIs ContainerInstanceAPIs) StreamInstances(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithCancel(context.TODO())
+	// the request context is cancelled when the client disconnects or the server shuts down,
+	// which ends the stream
+	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
 	instanceRespChan, err := instanceAPIs.instanceStore.StreamContainerInstances(ctx)
@@ -205,8 +207,6 @@ func (instanceAPIs ContainerInstanceAPIs) StreamInstances(w http.ResponseWriter,
 		}
 		flusher.Flush()
 	}
-
-	// TODO: Handle client-side termination (Ctrl+C) using w.(http.CloseNotifier).closeNotify()
 }
 
 func (instanceAPIs ContainerInstanceAPIs) isValidStatus(status string) bool {
@@ -18,6 +18,10 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// StreamPathPrefix is the path prefix of the APIs that stream changes, which hold requests open
+// until the client disconnects
+const StreamPathPrefix = "/v1/stream/"
+
 // TODO: add a map of path and query keys and use the map in task apis instead of hardcoding strings
 var (
 	// Stripping off '^' and '$' from the beginning and end of regexes respectively for the router
@@ -38,7 +42,7 @@ var (
 // NewRouter initializes a new router with registered routes redirected to appropriate handler functions
 func NewRouter(apis APIs) *mux.Router {
 	r := mux.NewRouter().StrictSlash(true)
-	s := r.Path("/v1").Subrouter()
+	s := r.PathPrefix("/v1").Subrouter()
 
 	// Tasks
 
@@ -74,5 +78,5 @@ func NewRouter(apis APIs) *mux.Router {
 		Methods("GET").
 		HandlerFunc(apis.ContainerInstanceApis.StreamInstances)
 
-	return s
+	return r
 }
@@ -177,7 +177,9 @@ func (taskAPIs TaskAPIs) ListTasks(w http.ResponseWriter, r *http.Request) {
 
 // StreamTasks streams tasks that change (status etc.) across all clusters
 func (taskAPIs TaskAPIs) StreamTasks(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithCancel(context.TODO())
+	// the request context is cancelled when the client disconnects or the server shuts down,
+	// which ends the stream
+	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
 	taskRespChan, err := taskAPIs.taskStore.StreamTasks(ctx)
@@ -213,8 +215,6 @@ func (taskAPIs TaskAPIs) StreamTasks(w http.ResponseWriter, r *http.Request) {
 		}
 		flusher.Flush()
 	}
-
-	// TODO: Handle client-side termination (Ctrl+C) using w.(http.CloseNotifier).closeNotify()
 }
 
 func (taskAPIs TaskAPIs) isValidStatus(status string) bool {
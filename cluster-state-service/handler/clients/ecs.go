@@ -20,28 +20,32 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/blox/blox/cluster-state-service/handler/httpclient"
+	"github.com/blox/blox/shared/ecsclient"
 	log "github.com/cihub/seelog"
 )
 
 const ecsEndpointEnvVarName = "ECS_ENDPOINT"
 
-func NewECSClient(sess *session.Session) *ecs.ECS {
+// NewECSClient returns an ECS client whose requests are rate limited, retried and circuit broken
+// by the throttle.
+func NewECSClient(sess *session.Session, throttle *ecsclient.Throttle) *ecs.ECS {
 	// TODO: Use session passed in args and get rid of the env var
 	endpoint := os.Getenv(ecsEndpointEnvVarName)
-	if endpoint == "" {
-		return ecs.New(sess)
+	if endpoint != "" {
+		var err error
+		sess, err = session.NewSessionWithOptions(session.Options{
+			Config: aws.Config{
+				Endpoint:   aws.String(endpoint),
+				HTTPClient: httpclient.New(),
+			},
+		})
+		if err != nil {
+			log.Critical("Error initializing ecs client")
+			return nil
+		}
 	}
 
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Endpoint:   aws.String(endpoint),
-			HTTPClient: httpclient.New(),
-		},
-	})
-	if err != nil {
-		log.Critical("Error initializing ecs client")
-		return nil
-	}
-
-	return ecs.New(sess)
+	client := ecs.New(sess)
+	throttle.Apply(client)
+	return client
 }
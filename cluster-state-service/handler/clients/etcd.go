@@ -14,10 +14,13 @@
 package clients
 
 import (
+	"crypto/tls"
 	"fmt"
+	"os"
 	"time"
 
 	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/pkg/tlsutil"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 )
@@ -57,23 +60,107 @@ type EtcdInterface interface {
 var _ EtcdInterface = (*etcd.Client)(nil)
 
 const (
-	dialTimeout = 5 * time.Second
+	// DefaultDialTimeout is the timeout for establishing a connection to etcd
+	DefaultDialTimeout = 5 * time.Second
+	// DefaultRequestTimeout is the timeout for a single etcd request. It is set
+	// to 1 minute to support list APIs with prefix match.
+	DefaultRequestTimeout = 1 * time.Minute
+
+	etcdPasswordEnvVarName = "ETCD_PASSWORD"
 )
 
+// EtcdConfig holds the settings used to connect to etcd
+type EtcdConfig struct {
+	Endpoints []string
+
+	// CAFile, CertFile and KeyFile enable TLS. CertFile and KeyFile are only
+	// needed when etcd requires client certificates.
+	CAFile   string
+	CertFile string
+	KeyFile  string
+
+	// Username and Password enable etcd authentication. The password is read
+	// from the ETCD_PASSWORD environment variable if it is not set.
+	Username string
+	Password string
+
+	DialTimeout    time.Duration
+	RequestTimeout time.Duration
+
+	// KeyPrefix namespaces every key written by the service so that several
+	// deployments can share an etcd cluster.
+	KeyPrefix string
+}
+
 // NewEtcdClient initializes an etcd client
-func NewEtcdClient(endpoints []string) (*etcd.Client, error) {
+func NewEtcdClient(config EtcdConfig) (*etcd.Client, error) {
 	//TODO: attach a lease TTL
-	if len(endpoints) == 0 {
+	if len(config.Endpoints) == 0 {
 		return nil, fmt.Errorf("etcd endpoints cannot be empty")
 	}
+
+	dialTimeout := config.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = DefaultDialTimeout
+	}
+
+	tlsConfig, err := newEtcdTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	password := config.Password
+	if password == "" {
+		password = os.Getenv(etcdPasswordEnvVarName)
+	}
+
+	if config.Username == "" && password != "" {
+		return nil, errors.New("Etcd username must be set when a password is provided")
+	}
+
 	etcd, err := etcd.New(etcd.Config{
-		Endpoints:   endpoints,
+		Endpoints:   config.Endpoints,
 		DialTimeout: dialTimeout,
+		TLS:         tlsConfig,
+		Username:    config.Username,
+		Password:    password,
 	})
 
 	if err != nil {
-		return nil, errors.Wrapf(err, "Etcd connection error connecting to '%v'", endpoints)
+		return nil, errors.Wrapf(err, "Etcd connection error connecting to '%v'", config.Endpoints)
 	}
 
 	return etcd, nil
 }
+
+func newEtcdTLSConfig(config EtcdConfig) (*tls.Config, error) {
+	if config.CAFile == "" && config.CertFile == "" && config.KeyFile == "" {
+		return nil, nil
+	}
+
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, errors.New("Both the etcd client certificate and key must be provided")
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.CAFile != "" {
+		certPool, err := tlsutil.NewCertPool([]string{config.CAFile})
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not load etcd CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	if config.CertFile != "" {
+		cert, err := tlsutil.NewCert(config.CertFile, config.KeyFile, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not load etcd client certificate %s", config.CertFile)
+		}
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clients
+
+import (
+	"strings"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"golang.org/x/net/context"
+)
+
+type namespacedEtcd struct {
+	etcd           EtcdInterface
+	prefix         string
+	requestTimeout time.Duration
+}
+
+// NewNamespacedEtcd wraps an etcd client so that every key is stored under prefix and every
+// request, except watches, is bounded by requestTimeout. Keys are returned without the prefix.
+func NewNamespacedEtcd(etcdInterface EtcdInterface, prefix string, requestTimeout time.Duration) EtcdInterface {
+	return namespacedEtcd{
+		etcd:           etcdInterface,
+		prefix:         prefix,
+		requestTimeout: requestTimeout,
+	}
+}
+
+func (n namespacedEtcd) Close() error {
+	return n.etcd.Close()
+}
+
+func (n namespacedEtcd) Put(ctx context.Context, key, val string, opts ...etcd.OpOption) (*etcd.PutResponse, error) {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := n.etcd.Put(ctx, n.prefix+key, val, opts...)
+	if resp != nil {
+		n.stripKeyValue(resp.PrevKv)
+	}
+	return resp, err
+}
+
+func (n namespacedEtcd) Get(ctx context.Context, key string, opts ...etcd.OpOption) (*etcd.GetResponse, error) {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := n.etcd.Get(ctx, n.prefix+key, opts...)
+	if resp != nil {
+		for _, kv := range resp.Kvs {
+			n.stripKeyValue(kv)
+		}
+	}
+	return resp, err
+}
+
+func (n namespacedEtcd) Watch(ctx context.Context, key string, opts ...etcd.OpOption) etcd.WatchChan {
+	watchChan := n.etcd.Watch(ctx, n.prefix+key, opts...)
+	if n.prefix == "" {
+		return watchChan
+	}
+
+	namespacedChan := make(chan etcd.WatchResponse)
+	go func() {
+		defer close(namespacedChan)
+		for resp := range watchChan {
+			for _, ev := range resp.Events {
+				n.stripKeyValue(ev.Kv)
+				n.stripKeyValue(ev.PrevKv)
+			}
+			select {
+			case namespacedChan <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return namespacedChan
+}
+
+func (n namespacedEtcd) Delete(ctx context.Context, key string, opts ...etcd.OpOption) (*etcd.DeleteResponse, error) {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := n.etcd.Delete(ctx, n.prefix+key, opts...)
+	if resp != nil {
+		for _, kv := range resp.PrevKvs {
+			n.stripKeyValue(kv)
+		}
+	}
+	return resp, err
+}
+
+func (n namespacedEtcd) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if n.requestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, n.requestTimeout)
+}
+
+func (n namespacedEtcd) stripKeyValue(kv *mvccpb.KeyValue) {
+	if kv == nil || n.prefix == "" {
+		return
+	}
+	kv.Key = []byte(strings.TrimPrefix(string(kv.Key), n.prefix))
+}
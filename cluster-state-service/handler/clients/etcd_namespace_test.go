@@ -0,0 +1,107 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clients
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blox/blox/cluster-state-service/handler/mocks"
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+const (
+	keyPrefix = "blox-prod/"
+	key       = "ecs/task/cluster/task1"
+	value     = "value"
+)
+
+func TestNamespacedEtcdPut(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	etcdInterface := mocks.NewMockEtcdInterface(mockCtrl)
+	namespaced := NewNamespacedEtcd(etcdInterface, keyPrefix, time.Minute)
+
+	etcdInterface.EXPECT().Put(gomock.Any(), keyPrefix+key, value).Do(func(ctx context.Context, key, val string) {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok, "Expected the request timeout to be applied")
+	}).Return(&etcd.PutResponse{}, nil)
+
+	_, err := namespaced.Put(context.Background(), key, value)
+	assert.Nil(t, err, "Unexpected error putting a key")
+}
+
+func TestNamespacedEtcdGetStripsPrefix(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	etcdInterface := mocks.NewMockEtcdInterface(mockCtrl)
+	namespaced := NewNamespacedEtcd(etcdInterface, keyPrefix, 0)
+
+	resp := &etcd.GetResponse{
+		Kvs: []*mvccpb.KeyValue{{Key: []byte(keyPrefix + key), Value: []byte(value)}},
+	}
+	etcdInterface.EXPECT().Get(gomock.Any(), keyPrefix+key).Return(resp, nil)
+
+	resp, err := namespaced.Get(context.Background(), key)
+	assert.Nil(t, err, "Unexpected error getting a key")
+	assert.Equal(t, key, string(resp.Kvs[0].Key), "Expected the prefix to be stripped")
+}
+
+func TestNamespacedEtcdDelete(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	etcdInterface := mocks.NewMockEtcdInterface(mockCtrl)
+	namespaced := NewNamespacedEtcd(etcdInterface, keyPrefix, 0)
+
+	etcdInterface.EXPECT().Delete(gomock.Any(), keyPrefix+key).Return(&etcd.DeleteResponse{Deleted: 1}, nil)
+
+	resp, err := namespaced.Delete(context.Background(), key)
+	assert.Nil(t, err, "Unexpected error deleting a key")
+	assert.Equal(t, int64(1), resp.Deleted, "Unexpected number of deleted keys")
+}
+
+func TestNamespacedEtcdWatchStripsPrefix(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	etcdInterface := mocks.NewMockEtcdInterface(mockCtrl)
+	namespaced := NewNamespacedEtcd(etcdInterface, keyPrefix, 0)
+
+	watchChan := make(chan etcd.WatchResponse, 1)
+	watchChan <- etcd.WatchResponse{
+		Events: []*etcd.Event{{Kv: &mvccpb.KeyValue{Key: []byte(keyPrefix + key), Value: []byte(value)}}},
+	}
+	close(watchChan)
+	etcdInterface.EXPECT().Watch(gomock.Any(), keyPrefix+key).Return(etcd.WatchChan(watchChan))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resp, ok := <-namespaced.Watch(ctx, key)
+	assert.True(t, ok, "Expected a watch response")
+	assert.Equal(t, key, string(resp.Events[0].Kv.Key), "Expected the prefix to be stripped")
+}
+
+func TestNewEtcdTLSConfigCertWithoutKey(t *testing.T) {
+	_, err := newEtcdTLSConfig(EtcdConfig{CertFile: "cert.pem"})
+	assert.Error(t, err, "Expected error when the client key is missing")
+}
+
+func TestNewEtcdTLSConfigDisabled(t *testing.T) {
+	tlsConfig, err := newEtcdTLSConfig(EtcdConfig{})
+	assert.Nil(t, err, "Unexpected error without TLS settings")
+	assert.Nil(t, tlsConfig, "Expected TLS to be disabled without TLS settings")
+}
@@ -61,12 +61,12 @@ func (kinesisConsumer *kinesisEventConsumer) PollForEvents(ctx context.Context)
 		case <-ctx.Done():
 			return
 		default:
-			kinesisConsumer.pollForMessages()
+			kinesisConsumer.pollForMessages(ctx)
 		}
 	}
 }
 
-func (kinesisConsumer *kinesisEventConsumer) pollForMessages() {
+func (kinesisConsumer *kinesisEventConsumer) pollForMessages(ctx context.Context) {
 	if kinesisConsumer.iterator == nil {
 		iteratorRequest := &kinesis.GetShardIteratorInput{
 			ShardId:           aws.String(kinesisStartingShardId),
@@ -98,6 +98,9 @@ func (kinesisConsumer *kinesisEventConsumer) pollForMessages() {
 
 	kinesisConsumer.iterator = recordsResponse.NextShardIterator
 	if len(recordsResponse.Records) == 0 {
-		time.Sleep(kinesisWaitTimeSeconds * time.Second)
+		select {
+		case <-ctx.Done():
+		case <-time.After(kinesisWaitTimeSeconds * time.Second):
+		}
 	}
 }
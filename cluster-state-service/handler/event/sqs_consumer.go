@@ -26,17 +26,24 @@ import (
 )
 
 const (
-	sqsVisibilityTimeout = 10
-	sqsWaitTimeSeconds   = 10
+	// DefaultSQSVisibilityTimeout is the default time a received message is hidden from other consumers
+	DefaultSQSVisibilityTimeout = 10 * time.Second
+	// DefaultSQSWaitTime is the default time a receive call waits for messages to arrive
+	DefaultSQSWaitTime = 10 * time.Second
 )
 
 type sqsEventConsumer struct {
-	sqs       sqsiface.SQSAPI
-	queueURL  string
-	processor Processor
+	sqs               sqsiface.SQSAPI
+	queueURL          string
+	processor         Processor
+	waitTime          time.Duration
+	visibilityTimeout time.Duration
 }
 
-func NewSQSConsumer(sqs sqsiface.SQSAPI, processor Processor, queueName string) (Consumer, error) {
+// NewSQSConsumer creates a consumer that long polls queueName for up to waitTime and hides
+// received messages from other consumers for visibilityTimeout. Both are rounded down to seconds.
+func NewSQSConsumer(sqs sqsiface.SQSAPI, processor Processor, queueName string,
+	waitTime time.Duration, visibilityTimeout time.Duration) (Consumer, error) {
 	if sqs == nil {
 		return nil, errors.Errorf("The SQS API interface is not initialized")
 	}
@@ -53,9 +60,11 @@ func NewSQSConsumer(sqs sqsiface.SQSAPI, processor Processor, queueName string)
 	}
 
 	return &sqsEventConsumer{
-		sqs:       sqs,
-		queueURL:  sqsQueueURL,
-		processor: processor,
+		sqs:               sqs,
+		queueURL:          sqsQueueURL,
+		processor:         processor,
+		waitTime:          waitTime,
+		visibilityTimeout: visibilityTimeout,
 	}, nil
 }
 
@@ -81,9 +90,12 @@ func getQueueURL(client sqsiface.SQSAPI, queueName string) (string, error) {
 	return aws.StringValue(output.QueueUrl), nil
 }
 
+// PollForEvents receives and processes batches of messages until ctx is
+// cancelled. A batch that has been received is always processed in full.
 func (sqsConsumer sqsEventConsumer) PollForEvents(ctx context.Context) {
 	log.Infof("Starting to poll for events from SQS")
 	statsTicker := time.NewTicker(time.Second * 30)
+	defer statsTicker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
@@ -122,8 +134,8 @@ func (sqsConsumer sqsEventConsumer) logQueueStats(ctx context.Context) error {
 func (sqsConsumer sqsEventConsumer) pollForMessages() {
 	receiveMessageInput := &sqs.ReceiveMessageInput{
 		QueueUrl:          aws.String(sqsConsumer.queueURL),
-		VisibilityTimeout: aws.Int64(sqsVisibilityTimeout),
-		WaitTimeSeconds:   aws.Int64(sqsWaitTimeSeconds),
+		VisibilityTimeout: aws.Int64(int64(sqsConsumer.visibilityTimeout / time.Second)),
+		WaitTimeSeconds:   aws.Int64(int64(sqsConsumer.waitTime / time.Second)),
 	}
 
 	output, err := sqsConsumer.sqs.ReceiveMessage(receiveMessageInput)
@@ -26,7 +26,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-// ReconcileDuration specifies the interval between each reconcile loop
+// ReconcileDuration specifies the default interval between each reconcile loop
 const ReconcileDuration = 20 * time.Minute
 
 type Reconciler struct {
@@ -34,9 +34,11 @@ type Reconciler struct {
 	instanceLoader loader.ContainerInstanceLoader
 	ticker         *time.Ticker
 	tickerDuration time.Duration
+	tickerLock     sync.Mutex
 	ctx            context.Context
 	inProgress     bool
 	inProgressLock sync.RWMutex
+	runs           sync.WaitGroup
 }
 
 func NewReconciler(ctx context.Context, stores store.Stores, ecsClient *ecs.ECS, tickerDuration time.Duration) (*Reconciler, error) {
@@ -56,16 +58,24 @@ func NewReconciler(ctx context.Context, stores store.Stores, ecsClient *ecs.ECS,
 	}, nil
 }
 
+// Run reconciles the datastore every tickerDuration until the context is
+// cancelled. It returns once any reconcile loop already in progress has finished.
 func (reconciler *Reconciler) Run() {
 	reconciler.initTicker()
 	for {
 		select {
 		case <-reconciler.ticker.C:
+			// both cases can be ready at once, so don't start another loop after cancellation
+			if reconciler.ctx.Err() != nil {
+				continue
+			}
 			if reconciler.isInProgress() {
 				log.Info("Reconcile loop in progress, skipping")
 				continue
 			}
+			reconciler.runs.Add(1)
 			go func() {
+				defer reconciler.runs.Done()
 				err := reconciler.RunOnce()
 				if err != nil {
 					log.Warnf("Error reconciling: %v", err)
@@ -73,6 +83,7 @@ func (reconciler *Reconciler) Run() {
 			}()
 		case <-reconciler.ctx.Done():
 			reconciler.ticker.Stop()
+			reconciler.runs.Wait()
 			return
 		}
 	}
@@ -112,7 +123,27 @@ func (reconciler *Reconciler) isInProgress() bool {
 	return reconciler.inProgress
 }
 
+// SetTickerDuration changes the interval between reconcile loops. It can be called while
+// the reconciler is running and takes effect from the next tick.
+func (reconciler *Reconciler) SetTickerDuration(tickerDuration time.Duration) error {
+	if tickerDuration <= 0 {
+		return fmt.Errorf("Invalid duration specified for running the reconciler: %s", tickerDuration.String())
+	}
+
+	reconciler.tickerLock.Lock()
+	defer reconciler.tickerLock.Unlock()
+
+	reconciler.tickerDuration = tickerDuration
+	if reconciler.ticker != nil {
+		reconciler.ticker.Reset(tickerDuration)
+	}
+	return nil
+}
+
 func (reconciler *Reconciler) initTicker() {
+	reconciler.tickerLock.Lock()
+	defer reconciler.tickerLock.Unlock()
+
 	if reconciler.ticker == nil {
 		reconciler.ticker = time.NewTicker(reconciler.tickerDuration)
 	}
@@ -135,3 +135,23 @@ func (suite *ReconcilerTestSuite) TestMultipleRunInvocations() {
 	case <-ctx.Done():
 	}
 }
+
+func (suite *ReconcilerTestSuite) TestSetTickerDuration() {
+	reconciler := Reconciler{
+		taskLoader:     suite.taskLoader,
+		instanceLoader: suite.instanceLoader,
+		tickerDuration: time.Hour,
+	}
+	reconciler.initTicker()
+	defer reconciler.ticker.Stop()
+
+	assert.Error(suite.T(), reconciler.SetTickerDuration(0), "Expected an error setting an invalid duration")
+	assert.Nil(suite.T(), reconciler.SetTickerDuration(time.Millisecond), "Unexpected error setting the duration")
+	assert.Equal(suite.T(), time.Millisecond, reconciler.tickerDuration, "Unexpected ticker duration")
+
+	select {
+	case <-reconciler.ticker.C:
+	case <-time.After(time.Second):
+		assert.Fail(suite.T(), "Expected the ticker to use the new duration")
+	}
+}
@@ -15,8 +15,11 @@ package run
 
 import (
 	"context"
+	"expvar"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	log "github.com/cihub/seelog"
@@ -27,39 +30,57 @@ import (
 	"github.com/blox/blox/cluster-state-service/handler/event"
 	"github.com/blox/blox/cluster-state-service/handler/reconcile"
 	"github.com/blox/blox/cluster-state-service/handler/store"
+	"github.com/blox/blox/shared/ecsclient"
+	"github.com/blox/blox/shared/shutdown"
+	"github.com/gorilla/mux"
 	"github.com/urfave/negroni"
 	"strings"
 )
 
 const (
-	serverReadTimeout = 10 * time.Second
-	kinesisPrefix     = "kinesis://"
-	sqsPrefix         = "sqs://"
+	// DefaultServerReadTimeout is the default maximum duration for reading a request
+	DefaultServerReadTimeout = 10 * time.Second
+
+	kinesisPrefix = "kinesis://"
+	sqsPrefix     = "sqs://"
 )
 
+// Options holds the intervals and timeouts used by the Cluster State Service.
+type Options struct {
+	ReconcileInterval    time.Duration
+	SQSWaitTime          time.Duration
+	SQSVisibilityTimeout time.Duration
+	ServerReadTimeout    time.Duration
+	// ECSThrottle holds the rate limits, retries and circuit breaking of ECS requests
+	ECSThrottle ecsclient.Config
+	// ReconcileIntervalUpdates delivers new reconcile intervals while the service is running
+	ReconcileIntervalUpdates <-chan time.Duration
+}
+
 // StartClusterStateService starts the Cluster State Service. It creates an ETCD
 // client, a data store using this client and an event processor to process
 // events from the provided queue. It also starts the RESTful server and blocks on
 // the listen method of the same to listen to requests that query for task and
-// instance state from the store.
-func StartClusterStateService(queueNameURI string, bindAddr string, etcdEndpoints []string) error {
+// instance state from the store. On SIGINT or SIGTERM it drains in-flight
+// requests and streams and waits for the consumer and reconciler to stop.
+func StartClusterStateService(queueNameURI string, bindAddr string, etcdConfig clients.EtcdConfig, options Options) error {
 	if bindAddr == "" {
 		return fmt.Errorf("The cluster state service listen address is not set")
 	}
 
-	etcdClient, err := clients.NewEtcdClient(etcdEndpoints)
+	etcdClient, err := clients.NewEtcdClient(etcdConfig)
 	if err != nil {
 		return errors.Wrapf(err, "Could not start etcd")
 	}
 	defer etcdClient.Close()
 
 	// initialize the datastore
-	datastore, err := store.NewDataStore(etcdClient)
+	datastore, err := store.NewDataStore(clients.NewNamespacedEtcd(etcdClient, etcdConfig.KeyPrefix, etcdConfig.RequestTimeout))
 	if err != nil {
 		return errors.Wrapf(err, "Could not initialize the datastore")
 	}
 
-	etcdTXStore, err := store.NewEtcdTXStore(etcdClient)
+	etcdTXStore, err := store.NewEtcdTXStore(etcdClient, etcdConfig.KeyPrefix)
 	if err != nil {
 		return errors.Wrapf(err, "Could not initialize the etcd transactional store")
 	}
@@ -75,10 +96,18 @@ func StartClusterStateService(queueNameURI string, bindAddr string, etcdEndpoint
 		return errors.Wrapf(err, "Could not load aws session")
 	}
 
-	ecsClient := clients.NewECSClient(awsSession)
+	// the ECS requests are throttled to stay within the ECS rate limits, and their metrics are
+	// served with the other expvar variables
+	ecsThrottle, err := ecsclient.NewThrottle(options.ECSThrottle)
+	if err != nil {
+		return errors.Wrapf(err, "Could not initialize the ecs throttle")
+	}
+	ecsclient.PublishMetrics(ecsThrottle)
+
+	ecsClient := clients.NewECSClient(awsSession, ecsThrottle)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	recon, err := reconcile.NewReconciler(ctx, stores, ecsClient, reconcile.ReconcileDuration)
+	recon, err := reconcile.NewReconciler(ctx, stores, ecsClient, options.ReconcileInterval)
 	if err != nil {
 		return errors.Wrapf(err, "Could not start reconciler")
 	}
@@ -87,7 +116,27 @@ func StartClusterStateService(queueNameURI string, bindAddr string, etcdEndpoint
 		return errors.Wrapf(err, "Error bootstrapping")
 	}
 	log.Infof("Bootstrapping completed")
-	go recon.Run()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		recon.Run()
+	}()
+	go func() {
+		for {
+			select {
+			case interval := <-options.ReconcileIntervalUpdates:
+				if err := recon.SetTickerDuration(interval); err != nil {
+					log.Errorf("Could not update the reconcile interval: %+v", err)
+					continue
+				}
+				log.Infof("Reconcile interval set to %s", interval)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
 	// initialize apis
 	apis := v1.NewAPIs(stores)
@@ -104,30 +153,84 @@ func StartClusterStateService(queueNameURI string, bindAddr string, etcdEndpoint
 			return errors.Wrapf(err, "Could not start the consumer")
 		}
 
-		go consumer.PollForEvents(ctx)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			consumer.PollForEvents(ctx)
+		}()
 	} else {
 		sqsClient := clients.NewSQSClient(awsSession)
 
 		// start event consumer
-		consumer, err := event.NewSQSConsumer(sqsClient, processor, strings.TrimPrefix(queueNameURI, sqsPrefix))
+		consumer, err := event.NewSQSConsumer(sqsClient, processor, strings.TrimPrefix(queueNameURI, sqsPrefix),
+			options.SQSWaitTime, options.SQSVisibilityTimeout)
 		if err != nil {
 			return errors.Wrapf(err, "Could not start the consumer")
 		}
 
-		go consumer.PollForEvents(ctx)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			consumer.PollForEvents(ctx)
+		}()
 	}
 
 	// start server
-	router := v1.NewRouter(apis)
+	router := newRouter(apis)
 
 	n := negroni.Classic()
-	n.UseHandler(router)
 
 	s := &http.Server{
 		Addr:        bindAddr,
-		Handler:     n,
-		ReadTimeout: serverReadTimeout,
+		ReadTimeout: options.ServerReadTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
 	}
+	n.UseHandler(endStreamsOnShutdown(s, router))
+	s.Handler = n
+
+	return shutdown.ServeUntilSignal(s, func(stopCtx context.Context) error {
+		// the base context is only cancelled once in-flight requests have been drained, and ECS
+		// requests waiting for the rate limit are not waited for
+		cancel()
+		ecsThrottle.Stop()
+		return shutdown.WaitFor(stopCtx, wg.Wait)
+	})
+}
+
+// newRouter returns the router of the APIs, which also serves the expvar variables at the root
+// of the server rather than under /v1
+func newRouter(apis v1.APIs) *mux.Router {
+	router := v1.NewRouter(apis)
+	router.Path(ecsclient.MetricsPath).Methods("GET").Handler(expvar.Handler())
+	return router
+}
+
+// endStreamsOnShutdown ends the streams served by h as soon as s starts shutting down, since
+// they would otherwise keep the server from ever becoming idle. Other requests are left to
+// complete.
+func endStreamsOnShutdown(s *http.Server, h http.Handler) http.Handler {
+	shuttingDown := make(chan struct{})
+	s.RegisterOnShutdown(func() {
+		close(shuttingDown)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, v1.StreamPathPrefix) {
+			h.ServeHTTP(w, r)
+			return
+		}
 
-	return s.ListenAndServe()
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-shuttingDown:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
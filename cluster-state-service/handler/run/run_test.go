@@ -0,0 +1,59 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package run
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blox/blox/cluster-state-service/handler/api/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndStreamsOnShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err, "Unexpected error listening")
+
+	streaming := make(chan struct{})
+	s := &http.Server{}
+	s.Handler = endStreamsOnShutdown(s, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(streaming)
+		<-r.Context().Done()
+	}))
+	go s.Serve(listener)
+	go http.Get("http://" + listener.Addr().String() + v1.StreamPathPrefix + "tasks")
+	<-streaming
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, s.Shutdown(ctx), "Expected the stream to end when the server shuts down")
+}
+
+func TestRouterServesMetricsAtRoot(t *testing.T) {
+	router := newRouter(v1.APIs{})
+
+	recorder := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/debug/vars", nil)
+	router.ServeHTTP(recorder, r)
+	assert.Equal(t, http.StatusOK, recorder.Code, "Expected the metrics to be served at the root")
+
+	recorder = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/v1/debug/vars", nil)
+	router.ServeHTTP(recorder, r)
+	assert.Equal(t, http.StatusNotFound, recorder.Code, "Expected the metrics not to be served under /v1")
+}
@@ -28,23 +28,54 @@ type EtcdTXStore interface {
 }
 
 type etcdTransactionalStore struct {
-	v3Client *clientv3.Client
+	v3Client  *clientv3.Client
+	keyPrefix string
 }
 
-// NewEtcdTXStore initializs the etcdTransactionalStore struct
-func NewEtcdTXStore(v3Client *clientv3.Client) (EtcdTXStore, error) {
+// NewEtcdTXStore initializs the etcdTransactionalStore struct. Keys read and written
+// in transactions are stored under keyPrefix.
+func NewEtcdTXStore(v3Client *clientv3.Client, keyPrefix string) (EtcdTXStore, error) {
 	if v3Client == nil {
 		return nil, errors.Errorf("Etcd client in not initialized")
 	}
 	return &etcdTransactionalStore{
-		v3Client: v3Client,
+		v3Client:  v3Client,
+		keyPrefix: keyPrefix,
 	}, nil
 }
 
 func (ts etcdTransactionalStore) NewSTMRepeatable(ctx context.Context, v3Client *clientv3.Client, apply func(concurrency.STM) error) (*clientv3.TxnResponse, error) {
-	return concurrency.NewSTMRepeatable(ctx, v3Client, apply)
+	if ts.keyPrefix == "" {
+		return concurrency.NewSTMRepeatable(ctx, v3Client, apply)
+	}
+	return concurrency.NewSTMRepeatable(ctx, v3Client, func(stm concurrency.STM) error {
+		return apply(namespacedSTM{STM: stm, prefix: ts.keyPrefix})
+	})
 }
 
 func (ts etcdTransactionalStore) GetV3Client() *clientv3.Client {
 	return ts.v3Client
 }
+
+// namespacedSTM prefixes the keys used in a transaction. The embedded STM
+// provides the unexported commit and reset methods.
+type namespacedSTM struct {
+	concurrency.STM
+	prefix string
+}
+
+func (stm namespacedSTM) Get(key string) string {
+	return stm.STM.Get(stm.prefix + key)
+}
+
+func (stm namespacedSTM) Put(key, val string, opts ...clientv3.OpOption) {
+	stm.STM.Put(stm.prefix+key, val, opts...)
+}
+
+func (stm namespacedSTM) Rev(key string) int64 {
+	return stm.STM.Rev(stm.prefix + key)
+}
+
+func (stm namespacedSTM) Del(key string) {
+	stm.STM.Del(stm.prefix + key)
+}
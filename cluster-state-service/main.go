@@ -15,12 +15,14 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/blox/blox/cluster-state-service/logger"
 	log "github.com/cihub/seelog"
 
 	"github.com/blox/blox/cluster-state-service/cmd"
 	"github.com/blox/blox/cluster-state-service/config"
+	"github.com/blox/blox/cluster-state-service/handler/clients"
 	"github.com/blox/blox/cluster-state-service/handler/run"
 	"github.com/blox/blox/cluster-state-service/versioning"
 	"os"
@@ -36,14 +38,63 @@ func main() {
 	}
 	if err := cmd.RootCmd.Execute(); err != nil {
 		log.Criticalf("Error executing: %+v", err)
-		os.Exit(errorCode)
+		exit(errorCode)
 	}
 	if config.PrintVersion {
 		versioning.PrintVersion()
-		os.Exit(0)
+		exit(0)
 	}
-	if err := run.StartClusterStateService(config.QueueNameURI, config.CSSBindAddr, config.EtcdEndpoints); err != nil {
+	if err := cmd.LoadConfigFile(); err != nil {
+		log.Criticalf("Invalid configuration: %+v", err)
+		exit(errorCode)
+	}
+	if err := logger.SetLogLevel(config.LogLevel); err != nil {
+		log.Criticalf("Could not set the log level: %+v", err)
+		exit(errorCode)
+	}
+	reconcileIntervalUpdates := make(chan time.Duration, 1)
+	cmd.WatchConfigFile(func(reloadable config.Reloadable) {
+		if err := logger.SetLogLevel(reloadable.LogLevel); err != nil {
+			log.Errorf("Could not update the log level: %+v", err)
+		}
+		// nothing receives updates once the service has stopped, and an update that has not been
+		// applied yet is superseded by this one
+		select {
+		case <-reconcileIntervalUpdates:
+		default:
+		}
+		select {
+		case reconcileIntervalUpdates <- reloadable.ReconcileInterval:
+		default:
+		}
+	})
+	etcdConfig := clients.EtcdConfig{
+		Endpoints:      config.EtcdEndpoints,
+		CAFile:         config.EtcdCAFile,
+		CertFile:       config.EtcdCertFile,
+		KeyFile:        config.EtcdKeyFile,
+		Username:       config.EtcdUsername,
+		Password:       config.EtcdPassword,
+		DialTimeout:    config.EtcdDialTimeout,
+		RequestTimeout: config.EtcdRequestTimeout,
+		KeyPrefix:      config.EtcdKeyPrefix,
+	}
+	options := run.Options{
+		ReconcileInterval:        config.ReconcileInterval,
+		SQSWaitTime:              config.SQSWaitTime,
+		SQSVisibilityTimeout:     config.SQSVisibilityTimeout,
+		ServerReadTimeout:        config.ServerReadTimeout,
+		ECSThrottle:              config.ECSThrottle,
+		ReconcileIntervalUpdates: reconcileIntervalUpdates,
+	}
+	if err := run.StartClusterStateService(config.QueueNameURI, config.CSSBindAddr, etcdConfig, options); err != nil {
 		log.Criticalf("Error starting event stream handler: %+v", err)
-		os.Exit(errorCode)
+		exit(errorCode)
 	}
 }
+
+// exit flushes the async logger before exiting, as os.Exit does not run deferred calls
+func exit(code int) {
+	log.Flush()
+	os.Exit(code)
+}
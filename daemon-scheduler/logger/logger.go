@@ -11,13 +11,43 @@ const (
 	defaultLogFile    = "/var/output/logs/daemon.log"
 	logFileEnvVarName = "DS_LOG_FILE"
 
-	defaultLogLevel    = "info"
+	// DefaultLogLevel is the log level used when none is configured
+	DefaultLogLevel    = "info"
 	logLevelEnvVarName = "DS_LOG_LEVEL"
 )
 
+var levels = map[string]string{
+	"debug": "debug",
+	"info":  "info",
+	"warn":  "warn",
+	"error": "error",
+	"crit":  "critical",
+	"none":  "off",
+}
+
 // InitLogger initializes and configures the logger
 func InitLogger() error {
-	logger, err := log.LoggerFromConfigAsString(loggerConfig())
+	return replaceLogger(logLevel())
+}
+
+// SetLogLevel replaces the logger with one that logs messages at or above level, which is
+// one of debug, info, warn, error, crit or none
+func SetLogLevel(level string) error {
+	seelogLevel, ok := levels[level]
+	if !ok {
+		return errors.Errorf("Unknown log level %s", level)
+	}
+	return replaceLogger(seelogLevel)
+}
+
+// IsValidLogLevel returns whether level can be passed to SetLogLevel
+func IsValidLogLevel(level string) bool {
+	_, ok := levels[level]
+	return ok
+}
+
+func replaceLogger(seelogLevel string) error {
+	logger, err := log.LoggerFromConfigAsString(loggerConfig(seelogLevel))
 	if err != nil {
 		return errors.Wrap(err, "Could not load logger config")
 	}
@@ -30,9 +60,9 @@ func InitLogger() error {
 	return nil
 }
 
-func loggerConfig() string {
+func loggerConfig(seelogLevel string) string {
 	return `
-	<seelog type="asyncloop" minlevel="` + logLevel() + `">
+	<seelog type="asyncloop" minlevel="` + seelogLevel + `">
 		<outputs formatid="main">
 			<console/>
 		    	<rollingfile filename="` + logFile() + `" type="date"
@@ -55,17 +85,9 @@ func logFile() string {
 }
 
 func logLevel() string {
-	levels := map[string]string{
-		"debug": "debug",
-		"info":  "info",
-		"warn":  "warn",
-		"error": "error",
-		"crit":  "critical",
-		"none":  "off",
-	}
 	level, ok := levels[os.Getenv(logLevelEnvVarName)]
 	if ok {
 		return level
 	}
-	return defaultLogLevel
+	return DefaultLogLevel
 }
@@ -35,16 +35,49 @@ func main() {
 
 	if err := cmd.RootCmd.Execute(); err != nil {
 		log.Criticalf("Error getting command line arguments: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if config.PrintVersion {
 		versioning.PrintVersion()
-		os.Exit(0)
+		exit(0)
 	}
 
-	if err := scheduler.Run(config.SchedulerBindAddr, config.ClusterStateServiceEndpoint); err != nil {
+	if err := cmd.LoadConfigFile(); err != nil {
+		log.Criticalf("Invalid configuration: %+v", err)
+		exit(1)
+	}
+
+	if err := logger.SetLogLevel(config.LogLevel); err != nil {
+		log.Criticalf("Could not set the log level: %+v", err)
+		exit(1)
+	}
+
+	reloads := make(chan config.Reloadable, 1)
+	cmd.WatchConfigFile(func(reloadable config.Reloadable) {
+		if err := logger.SetLogLevel(reloadable.LogLevel); err != nil {
+			log.Errorf("Could not update the log level: %+v", err)
+		}
+		// nothing receives reloads once the scheduler has stopped, and a reload that has not been
+		// applied yet is superseded by this one
+		select {
+		case <-reloads:
+		default:
+		}
+		select {
+		case reloads <- reloadable:
+		default:
+		}
+	})
+
+	if err := scheduler.Run(config.SchedulerBindAddr, config.ClusterStateServiceEndpoint, reloads); err != nil {
 		log.Criticalf("Error running scheduler: %v", err)
-		os.Exit(1)
+		exit(1)
 	}
 }
+
+// exit flushes the async logger before exiting, as os.Exit does not run deferred calls
+func exit(code int) {
+	log.Flush()
+	os.Exit(code)
+}
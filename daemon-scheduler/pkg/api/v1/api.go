@@ -14,13 +14,19 @@
 package v1
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/blox/blox/daemon-scheduler/pkg/auth"
 	"github.com/blox/blox/daemon-scheduler/pkg/deployment"
+	"github.com/blox/blox/daemon-scheduler/pkg/engine"
 	"github.com/blox/blox/daemon-scheduler/pkg/facade"
 	"github.com/blox/blox/daemon-scheduler/pkg/types"
 	"github.com/blox/blox/daemon-scheduler/pkg/validate"
@@ -33,6 +39,7 @@ import (
 const (
 	envNameKey      = "name"
 	deploymentIDKey = "id"
+	webhookIDKey    = "id"
 	clusterFilter   = "cluster"
 
 	// Client error messages
@@ -43,21 +50,26 @@ const (
 
 var (
 	// Using maps because arrays don't support easy lookup
-	supportedEnvironmentFilters = map[string]string{clusterFilter: ""}
+	supportedEnvironmentFilters = map[string]string{clusterFilter: "", statusKey: "", maxResultsKey: "", nextToken: ""}
 )
 
 type API struct {
 	environment deployment.Environment
 	deployment  deployment.Deployment
+	webhook     deployment.Webhook
 	ecs         facade.ECS
+	planner     engine.Planner
 }
 
 // NewAPI initializes the API struct
-func NewAPI(e deployment.Environment, d deployment.Deployment, ecs facade.ECS) API {
+func NewAPI(e deployment.Environment, d deployment.Deployment, webhook deployment.Webhook, ecs facade.ECS,
+	planner engine.Planner) API {
 	return API{
 		environment: e,
 		deployment:  d,
+		webhook:     webhook,
 		ecs:         ecs,
+		planner:     planner,
 	}
 }
 
@@ -79,19 +91,42 @@ func (api API) CreateEnvironment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ecsCluster, err := api.validateCluster(&createEnvReq.InstanceGroup.Cluster)
+	cluster := ""
+	selector := toClusterSelector(createEnvReq.InstanceGroup.ClusterSelector)
+	if selector.IsEmpty() {
+		ecsCluster, err := api.validateCluster(&createEnvReq.InstanceGroup.Cluster)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		cluster = *ecsCluster.ClusterArn
+	} else {
+		selector.Clusters, err = api.validateClusters(selector.Clusters)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+
+	ecsTaskDefinition, err := api.validateTaskDefinition(createEnvReq.TaskDefinition)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	ecsTaskDefinition, err := api.validateTaskDefinition(createEnvReq.TaskDefinition)
+	overrides := toTaskOverrides(createEnvReq.TaskOverrides)
+	err = overrides.ValidateFor(ecsTaskDefinition)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeBadRequestError(w, err.Error())
 		return
 	}
 
-	env, err := api.environment.CreateEnvironment(r.Context(), *createEnvReq.Name, *ecsTaskDefinition.TaskDefinitionArn, *ecsCluster.ClusterArn)
+	env, err := api.environment.CreateEnvironment(r.Context(), *createEnvReq.Name, *ecsTaskDefinition.TaskDefinitionArn,
+		cluster, selector, toPlacementConstraints(createEnvReq.InstanceGroup.PlacementConstraints),
+		toRolloutStrategy(createEnvReq.RolloutStrategy), toRollbackPolicy(createEnvReq.RollbackPolicy),
+		toCapacityPolicy(createEnvReq.CapacityPolicy), overrides, toMaintenanceWindows(createEnvReq.MaintenanceWindows),
+		types.DrainingPolicy(createEnvReq.DrainingPolicy), toSchedulingStrategy(createEnvReq.SchedulingStrategy),
+		toRestartPolicy(createEnvReq.RestartPolicy))
 	if err != nil {
 		handleBackendError(w, err)
 		return
@@ -105,6 +140,100 @@ func (api API) CreateEnvironment(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ReplaceEnvironment replaces the settings of an environment with the ones set in the request
+func (api API) ReplaceEnvironment(w http.ResponseWriter, r *http.Request) {
+	api.updateEnvironment(w, r, true)
+}
+
+// UpdateEnvironment updates the settings of an environment that are set in the request
+func (api API) UpdateEnvironment(w http.ResponseWriter, r *http.Request) {
+	api.updateEnvironment(w, r, false)
+}
+
+func (api API) updateEnvironment(w http.ResponseWriter, r *http.Request, replace bool) {
+	vars := mux.Vars(r)
+	name := vars[envNameKey]
+	token := r.URL.Query().Get(deploymentToken)
+
+	startDeployment := false
+	if value := r.URL.Query().Get(deploy); value != "" {
+		var err error
+		startDeployment, err = strconv.ParseBool(value)
+		if err != nil {
+			writeBadRequestError(w, fmt.Sprintf("Invalid value %s for %s", value, deploy))
+			return
+		}
+	}
+
+	var updateEnvReq models.UpdateEnvironmentRequest
+	b, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(b, &updateEnvReq)
+
+	err := updateEnvReq.Validate(nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	update := toEnvironmentUpdate(updateEnvReq, replace)
+	if replace && (update.TaskDefinition == nil || (update.Cluster == nil && update.ClusterSelector == nil)) {
+		writeBadRequestError(w, "Task definition and cluster or cluster selector are required to replace an environment")
+		return
+	}
+
+	if update.Cluster != nil {
+		ecsCluster, err := api.validateCluster(update.Cluster)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		update.Cluster = ecsCluster.ClusterArn
+	}
+
+	if update.ClusterSelector != nil {
+		update.ClusterSelector.Clusters, err = api.validateClusters(update.ClusterSelector.Clusters)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+
+	var ecsTaskDefinition *ecs.TaskDefinition
+	if update.TaskDefinition != nil {
+		ecsTaskDefinition, err = api.validateTaskDefinition(update.TaskDefinition)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		update.TaskDefinition = ecsTaskDefinition.TaskDefinitionArn
+	}
+
+	err = api.validateTaskOverridesUpdate(r.Context(), name, update, ecsTaskDefinition)
+	if err != nil {
+		handleBackendError(w, err)
+		return
+	}
+
+	env, d, err := api.environment.UpdateEnvironmentSettings(r.Context(), name, token, update, startDeployment)
+	if err != nil {
+		handleBackendError(w, err)
+		return
+	}
+
+	envModel := toEnvironmentModel(*env)
+	resp := models.UpdateEnvironmentResponse{Environment: &envModel}
+	if d != nil {
+		resp.Deployment = toDeploymentModel(&name, *d)
+	}
+
+	setJSONContentType(w)
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		log.Errorf("Error sending response for UpdateEnvironment: %+v", err)
+	}
+}
+
 // GetEnvironment gets an enironent by name
 func (api API) GetEnvironment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -143,8 +272,13 @@ func (api API) ListEnvironments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	options, err := parseListOptions(query, environmentStatuses)
+	if err != nil {
+		writeBadRequestError(w, err.Error())
+		return
+	}
+
 	var envs []types.Environment
-	var err error
 
 	cluster := query.Get(clusterFilter)
 	if cluster != "" {
@@ -161,16 +295,19 @@ func (api API) ListEnvironments(w http.ResponseWriter, r *http.Request) {
 		writeInternalServerError(w, err)
 		return
 	}
+	envs = allowedEnvironments(r, envs)
 
 	setJSONContentType(w)
 	w.WriteHeader(http.StatusOK)
+	page, next := pageEnvironments(envs, options)
 	envModels := []*models.Environment{}
-	for _, envType := range envs {
+	for _, envType := range page {
 		envModel := toEnvironmentModel(envType)
 		envModels = append(envModels, &envModel)
 	}
 	environments := models.Environments{
-		Items: envModels,
+		Items:     envModels,
+		NextToken: next,
 	}
 	err = json.NewEncoder(w).Encode(environments)
 	if err != nil {
@@ -178,18 +315,68 @@ func (api API) ListEnvironments(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// allowedEnvironments returns the environments the caller is allowed to list
+func allowedEnvironments(r *http.Request, envs []types.Environment) []types.Environment {
+	allowed := make([]types.Environment, 0, len(envs))
+	for _, env := range envs {
+		if auth.EnvironmentAllowed(r.Context(), env.Name) {
+			allowed = append(allowed, env)
+		}
+	}
+	return allowed
+}
+
 // DeleteEnvironment deletes an environment by name
 func (api API) DeleteEnvironment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars[envNameKey]
 
-	err := api.environment.DeleteEnvironment(r.Context(), name)
+	drain := true
+	if value := r.URL.Query().Get(cascade); value != "" {
+		var err error
+		drain, err = strconv.ParseBool(value)
+		if err != nil {
+			writeBadRequestError(w, fmt.Sprintf("Invalid value %s for %s", value, cascade))
+			return
+		}
+	}
+
+	if !drain {
+		err := api.environment.DeleteEnvironment(r.Context(), name)
+		if err != nil {
+			handleBackendError(w, err)
+			return
+		}
+		return
+	}
+
+	timeout := types.DefaultDrainTimeout
+	if value := r.URL.Query().Get(drainTimeoutSeconds); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			writeBadRequestError(w, fmt.Sprintf("Invalid value %s for %s", value, drainTimeoutSeconds))
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	env, err := api.environment.DrainEnvironment(r.Context(), name, timeout)
 	if err != nil {
 		handleBackendError(w, err)
 		return
 	}
 
-	//TODO: return something when successful?
+	if env == nil {
+		return
+	}
+
+	// the environment is deleted by the scheduler once its tasks have stopped
+	setJSONContentType(w)
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(toEnvironmentModel(*env))
+	if err != nil {
+		log.Errorf("Error sending response for DeleteEnvironment: %+v", err)
+	}
 }
 
 // CreateDeployment creates a deployment in an environment using details in the request
@@ -198,7 +385,17 @@ func (api API) CreateDeployment(w http.ResponseWriter, r *http.Request) {
 	name := vars[envNameKey]
 	token := vars[deploymentToken]
 
-	d, err := api.deployment.CreateDeployment(r.Context(), name, token)
+	var start time.Time
+	if value := r.URL.Query().Get(notBefore); value != "" {
+		var err error
+		start, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeBadRequestError(w, fmt.Sprintf("Invalid value %s for %s", value, notBefore))
+			return
+		}
+	}
+
+	d, err := api.deployment.CreateDeployment(r.Context(), name, token, start)
 	if err != nil {
 		handleBackendError(w, err)
 		return
@@ -214,6 +411,27 @@ func (api API) CreateDeployment(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// PlanDeployment shows what the scheduler would do to roll out a new deployment of an environment,
+// without starting or stopping any task
+func (api API) PlanDeployment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars[envNameKey]
+
+	plan, err := api.planner.PlanDeployment(r.Context(), name)
+	if err != nil {
+		handleBackendError(w, err)
+		return
+	}
+
+	setJSONContentType(w)
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(toDeploymentPlanModel(*plan))
+	if err != nil {
+		log.Errorf("Error sending response for PlanDeployment: %+v", err)
+	}
+}
+
 // GetDeployment gets the deployment in an environment using the environment name and deployment ID
 func (api API) GetDeployment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -241,33 +459,256 @@ func (api API) GetDeployment(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ListDeployments lists all deployments in an environment
+// ListDeploymentInstances lists the state of a deployment on every instance it targeted, including
+// the tasks it started that have since stopped
+func (api API) ListDeploymentInstances(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars[envNameKey]
+	id := vars[deploymentIDKey]
+
+	instances, err := api.deployment.ListDeploymentInstances(r.Context(), name, id)
+	if err != nil {
+		handleBackendError(w, err)
+		return
+	}
+
+	setJSONContentType(w)
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(toDeploymentInstancesModel(instances))
+	if err != nil {
+		log.Errorf("Error sending response for ListDeploymentInstances: %+v", err)
+	}
+}
+
+// PauseDeployment stops a deployment from being rolled out to further instances
+func (api API) PauseDeployment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars[envNameKey]
+	id := vars[deploymentIDKey]
+
+	d, err := api.deployment.PauseDeployment(r.Context(), name, id)
+	api.writeDeployment(w, name, d, err, "PauseDeployment")
+}
+
+// ResumeDeployment carries on with the rollout of a paused deployment
+func (api API) ResumeDeployment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars[envNameKey]
+	id := vars[deploymentIDKey]
+
+	d, err := api.deployment.ResumeDeployment(r.Context(), name, id)
+	api.writeDeployment(w, name, d, err, "ResumeDeployment")
+}
+
+// CancelDeployment stops a deployment for good, reverting the instances it updated if the
+// revert query parameter is true
+func (api API) CancelDeployment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars[envNameKey]
+	id := vars[deploymentIDKey]
+
+	revertUpdated := false
+	if value := r.URL.Query().Get(revert); value != "" {
+		var err error
+		revertUpdated, err = strconv.ParseBool(value)
+		if err != nil {
+			writeBadRequestError(w, fmt.Sprintf("Invalid value %s for %s", value, revert))
+			return
+		}
+	}
+
+	d, err := api.deployment.CancelDeployment(r.Context(), name, id, revertUpdated)
+	api.writeDeployment(w, name, d, err, "CancelDeployment")
+}
+
+// writeDeployment writes the deployment returned by the operation, or the error it failed with
+func (api API) writeDeployment(w http.ResponseWriter, name string, d *types.Deployment, err error, operation string) {
+	if err != nil {
+		handleBackendError(w, err)
+		return
+	}
+
+	setJSONContentType(w)
+	w.WriteHeader(http.StatusOK)
+
+	depModel := toDeploymentModel(&name, *d)
+	err = json.NewEncoder(w).Encode(depModel)
+	if err != nil {
+		log.Errorf("Error sending response for %s: %+v", operation, err)
+	}
+}
+
+// ListDeployments lists the deployments in an environment, latest first, a page at a time
 func (api API) ListDeployments(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars[envNameKey]
 
+	options, err := parseListOptions(r.URL.Query(), deploymentStatuses)
+	if err != nil {
+		writeBadRequestError(w, err.Error())
+		return
+	}
+
 	ds, err := api.deployment.ListDeploymentsSortedReverseChronologically(r.Context(), name)
 	if err != nil {
 		handleBackendError(w, err)
 		return
 	}
 
+	page, next, err := pageDeployments(ds, options)
+	if err != nil {
+		writeBadRequestError(w, err.Error())
+		return
+	}
+
 	setJSONContentType(w)
 	w.WriteHeader(http.StatusOK)
 
-	deploymentsModel := toDeploymentsModel(&name, ds)
+	deploymentsModel := toDeploymentsModel(&name, page, next)
 	err = json.NewEncoder(w).Encode(deploymentsModel)
 	if err != nil {
 		log.Errorf("Error sending response for ListDeployments: %+v", err)
 	}
 }
 
+// CreateWebhook subscribes the URL set in the request to the events of an environment
+func (api API) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars[envNameKey]
+
+	var createWebhookReq models.CreateWebhookRequest
+	b, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(b, &createWebhookReq)
+
+	err := createWebhookReq.Validate(nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hook, err := api.webhook.CreateWebhook(r.Context(), name, *createWebhookReq.URL, *createWebhookReq.Secret,
+		toWebhookEventTypes(createWebhookReq.Events))
+	if err != nil {
+		handleBackendError(w, err)
+		return
+	}
+
+	setJSONContentType(w)
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(toWebhookModel(*hook))
+	if err != nil {
+		log.Errorf("Error sending response for CreateWebhook: %+v", err)
+	}
+}
+
+// GetWebhook gets a webhook of an environment using the environment name and webhook ID
+func (api API) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars[envNameKey]
+	id := vars[webhookIDKey]
+
+	hook, err := api.webhook.GetWebhook(r.Context(), name, id)
+	if err != nil {
+		handleBackendError(w, err)
+		return
+	}
+
+	if hook == nil {
+		http.Error(w, fmt.Sprintf("Webhook %s does not exist for environment %s", id, name), http.StatusNotFound)
+		return
+	}
+
+	setJSONContentType(w)
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(toWebhookModel(*hook))
+	if err != nil {
+		log.Errorf("Error sending response for GetWebhook: %+v", err)
+	}
+}
+
+// ListWebhooks lists the webhooks of an environment
+func (api API) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars[envNameKey]
+
+	hooks, err := api.webhook.ListWebhooks(r.Context(), name)
+	if err != nil {
+		handleBackendError(w, err)
+		return
+	}
+
+	setJSONContentType(w)
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(toWebhooksModel(hooks))
+	if err != nil {
+		log.Errorf("Error sending response for ListWebhooks: %+v", err)
+	}
+}
+
+// DeleteWebhook deletes a webhook of an environment along with its deliveries
+func (api API) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars[envNameKey]
+	id := vars[webhookIDKey]
+
+	err := api.webhook.DeleteWebhook(r.Context(), name, id)
+	if err != nil {
+		handleBackendError(w, err)
+		return
+	}
+
+	setJSONContentType(w)
+	w.WriteHeader(http.StatusOK)
+}
+
+// ListWebhookDeliveries lists the latest deliveries to a webhook, latest first
+func (api API) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars[envNameKey]
+	id := vars[webhookIDKey]
+
+	deliveries, err := api.webhook.ListWebhookDeliveries(r.Context(), name, id)
+	if err != nil {
+		handleBackendError(w, err)
+		return
+	}
+
+	setJSONContentType(w)
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(toWebhookDeliveriesModel(deliveries))
+	if err != nil {
+		log.Errorf("Error sending response for ListWebhookDeliveries: %+v", err)
+	}
+}
+
+// validateClusters returns the ARNs of the clusters, which have to be active
+func (api API) validateClusters(clusterNames []string) ([]string, error) {
+	var clusterARNs []string
+	for _, clusterName := range clusterNames {
+		cluster, err := api.validateCluster(aws.String(clusterName))
+		if err != nil {
+			return nil, err
+		}
+		clusterARNs = append(clusterARNs, aws.StringValue(cluster.ClusterArn))
+	}
+	return clusterARNs, nil
+}
+
 func (api API) validateCluster(clusterName *string) (*ecs.Cluster, error) {
 	cluster, err := api.ecs.DescribeCluster(clusterName)
 	if err != nil {
 		return nil, err
 	}
 
+	if cluster == nil {
+		return nil, errors.Errorf("Cluster %s does not exist", aws.StringValue(clusterName))
+	}
+
 	if *cluster.Status == "INACTIVE" {
 		return nil, errors.New("Cluster is inactive")
 	}
@@ -288,6 +729,43 @@ func (api API) validateTaskDefinition(td *string) (*ecs.TaskDefinition, error) {
 	return taskDefinition, nil
 }
 
+// validateTaskOverridesUpdate returns an error if the task overrides the environment would have
+// after the update override containers that are not in the task definition it would have.
+// taskDefinition is the new task definition if the update sets one.
+func (api API) validateTaskOverridesUpdate(ctx context.Context, name string, update types.EnvironmentUpdate,
+	taskDefinition *ecs.TaskDefinition) error {
+
+	if update.TaskOverrides == nil && taskDefinition == nil {
+		return nil
+	}
+
+	overrides := update.TaskOverrides
+	if overrides == nil || taskDefinition == nil {
+		env, err := api.environment.GetEnvironment(ctx, name)
+		if err != nil {
+			return err
+		}
+		if env == nil {
+			return types.NewNotFoundError(errors.Errorf("Environment %s does not exist", name))
+		}
+		if overrides == nil {
+			overrides = &env.TaskOverrides
+		}
+		if taskDefinition == nil {
+			taskDefinition, err = api.ecs.DescribeTaskDefinition(aws.String(env.DesiredTaskDefinition))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err := overrides.ValidateFor(taskDefinition)
+	if err != nil {
+		return types.NewBadRequestError(err)
+	}
+	return nil
+}
+
 func (api API) hasUnsupportedFilters(filters map[string][]string) bool {
 	if len(filters) > len(supportedEnvironmentFilters) {
 		return true
@@ -19,9 +19,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/blox/blox/daemon-scheduler/pkg/auth"
 	"github.com/blox/blox/daemon-scheduler/pkg/mocks"
 	"github.com/blox/blox/daemon-scheduler/pkg/types"
 	"github.com/blox/blox/daemon-scheduler/swagger/v1/generated/models"
@@ -43,7 +47,9 @@ type APITestSuite struct {
 	suite.Suite
 	environment *mocks.MockEnvironment
 	deployment  *mocks.MockDeployment
+	webhook     *mocks.MockWebhook
 	ecs         *mocks.MockECS
+	planner     *mocks.MockPlanner
 	api         API
 
 	// We need a router because some of the apis use mux.Vars() which uses the URL
@@ -55,8 +61,10 @@ func (suite *APITestSuite) SetupTest() {
 	mockCtrl := gomock.NewController(suite.T())
 	suite.environment = mocks.NewMockEnvironment(mockCtrl)
 	suite.deployment = mocks.NewMockDeployment(mockCtrl)
+	suite.webhook = mocks.NewMockWebhook(mockCtrl)
 	suite.ecs = mocks.NewMockECS(mockCtrl)
-	suite.api = NewAPI(suite.environment, suite.deployment, suite.ecs)
+	suite.planner = mocks.NewMockPlanner(mockCtrl)
+	suite.api = NewAPI(suite.environment, suite.deployment, suite.webhook, suite.ecs, suite.planner)
 	suite.router = suite.getRouter()
 }
 
@@ -119,6 +127,46 @@ func (suite *APITestSuite) TestGetEnvironment() {
 	suite.assertSame(environment, &environmentModel)
 }
 
+func (suite *APITestSuite) TestGetEnvironmentDisconnectedInstances() {
+	name := "testEnv"
+	environment := suite.createEnvironmentObject(name, taskDefinitionARN, clusterARN1)
+	environment.UpdateDisconnectedInstances(clusterARN1, []string{"instance-1"})
+	suite.environment.EXPECT().GetEnvironment(gomock.Any(), name).Return(environment, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateGetEnvironmentRequest(name))
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var environmentModel models.Environment
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &environmentModel)
+	assert.Equal(suite.T(), models.EnvironmentDrainingPolicyKeepUntilLast, environmentModel.DrainingPolicy,
+		"Expected the default draining policy")
+	assert.Len(suite.T(), environmentModel.DisconnectedInstances, 1, "Expected the disconnected instance")
+	assert.Equal(suite.T(), "instance-1", aws.StringValue(environmentModel.DisconnectedInstances[0].InstanceARN))
+	assert.Equal(suite.T(), clusterARN1, environmentModel.DisconnectedInstances[0].Cluster)
+}
+
+func (suite *APITestSuite) TestGetEnvironmentMissingReplicas() {
+	name := "testEnv"
+	environment := suite.createEnvironmentObject(name, taskDefinitionARN, clusterARN1)
+	environment.SchedulingStrategy = types.SchedulingStrategy{Type: types.ReplicaStrategy, Replicas: 3}
+	environment.UpdateMissingReplicas(clusterARN1, 1)
+	suite.environment.EXPECT().GetEnvironment(gomock.Any(), name).Return(environment, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateGetEnvironmentRequest(name))
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var environmentModel models.Environment
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &environmentModel)
+	assert.Equal(suite.T(), map[string]int64{clusterARN1: 1}, environmentModel.MissingReplicas,
+		"Expected the replicas the cluster is short of")
+}
+
 func (suite *APITestSuite) TestListEnvironments() {
 	e1 := suite.createEnvironmentObject("e1", taskDefinitionARN, clusterARN1)
 	e2 := suite.createEnvironmentObject("e2", taskDefinitionARN, clusterARN2)
@@ -143,6 +191,26 @@ func (suite *APITestSuite) TestListEnvironments() {
 	}
 }
 
+func (suite *APITestSuite) TestListEnvironmentsFilteredByPolicy() {
+	e1 := suite.createEnvironmentObject("e1", taskDefinitionARN, clusterARN1)
+	e2 := suite.createEnvironmentObject("e2", taskDefinitionARN, clusterARN2)
+	suite.environment.EXPECT().ListEnvironments(gomock.Any()).Return([]types.Environment{*e1, *e2}, nil)
+
+	request := suite.generateListEnvironmentsRequest()
+	request = request.WithContext(auth.WithEnvironmentFilter(request.Context(), func(environment string) bool {
+		return environment == "e2"
+	}))
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+	var environmentsModel models.Environments
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &environmentsModel)
+	assert.Len(suite.T(), environmentsModel.Items, 1, "Expected only the allowed environment to be listed")
+	suite.assertSame(e2, environmentsModel.Items[0])
+}
+
 func (suite *APITestSuite) TestListEnvironmentsServerError() {
 	err := errors.New("Error when calling ListEnvironments")
 	suite.environment.EXPECT().ListEnvironments(gomock.Any()).Return(nil, err)
@@ -261,7 +329,7 @@ func (suite *APITestSuite) TestDeleteEnvironment() {
 	name := "testEnv"
 	suite.environment.EXPECT().DeleteEnvironment(gomock.Any(), name).Return(nil)
 
-	request := suite.generateDeleteEnvironmentRequest(name)
+	request := suite.generateDeleteEnvironmentRequest(name, "?cascade=false")
 
 	responseRecorder := httptest.NewRecorder()
 	suite.router.ServeHTTP(responseRecorder, request)
@@ -274,7 +342,7 @@ func (suite *APITestSuite) TestDeleteEnvironmentMissingEnvironment() {
 	notfounderr := types.NewNotFoundError(errors.New("Environment is missing"))
 	suite.environment.EXPECT().DeleteEnvironment(gomock.Any(), name).Return(notfounderr)
 
-	request := suite.generateDeleteEnvironmentRequest(name)
+	request := suite.generateDeleteEnvironmentRequest(name, "?cascade=false")
 
 	responseRecorder := httptest.NewRecorder()
 	suite.router.ServeHTTP(responseRecorder, request)
@@ -282,6 +350,41 @@ func (suite *APITestSuite) TestDeleteEnvironmentMissingEnvironment() {
 	assert.Equal(suite.T(), http.StatusNotFound, responseRecorder.Code)
 }
 
+func (suite *APITestSuite) TestDeleteEnvironmentDrainsTasks() {
+	name := "testEnv"
+	environment := suite.createEnvironmentObject(name, taskDefinitionARN, clusterARN1)
+	environment.StartDeleting(time.Now().Add(time.Minute))
+	suite.environment.EXPECT().DrainEnvironment(gomock.Any(), name, time.Minute).Return(environment, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateDeleteEnvironmentRequest(name, "?drainTimeoutSeconds=60"))
+
+	assert.Equal(suite.T(), http.StatusAccepted, responseRecorder.Code)
+
+	var environmentModel models.Environment
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &environmentModel)
+	suite.assertSame(environment, &environmentModel)
+	assert.Equal(suite.T(), models.EnvironmentStatusDeleting, environmentModel.Status)
+}
+
+func (suite *APITestSuite) TestDeleteEnvironmentDrainsTasksWithDefaultTimeout() {
+	name := "testEnv"
+	suite.environment.EXPECT().DrainEnvironment(gomock.Any(), name, types.DefaultDrainTimeout).Return(nil, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateDeleteEnvironmentRequest(name, ""))
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code, "Expected OK when the environment does not exist")
+}
+
+func (suite *APITestSuite) TestDeleteEnvironmentInvalidDrainTimeout() {
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateDeleteEnvironmentRequest("testEnv", "?drainTimeoutSeconds=-1"))
+
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code)
+}
+
 func (suite *APITestSuite) assertSame(environment *types.Environment, environmentModel *models.Environment) {
 	assert.Equal(suite.T(), environment.Name, aws.StringValue(environmentModel.Name))
 	assert.Equal(suite.T(), environment.Cluster, environmentModel.InstanceGroup.Cluster)
@@ -298,8 +401,8 @@ func (suite *APITestSuite) generateGetEnvironmentRequest(name string) *http.Requ
 	return request
 }
 
-func (suite *APITestSuite) generateDeleteEnvironmentRequest(name string) *http.Request {
-	request, err := http.NewRequest("DELETE", "/v1/environments/"+name, nil)
+func (suite *APITestSuite) generateDeleteEnvironmentRequest(name string, query string) *http.Request {
+	request, err := http.NewRequest("DELETE", "/v1/environments/"+name+query, nil)
 	assert.Nil(suite.T(), err, "Unexpected error generating delete environment request")
 	return request
 }
@@ -335,3 +438,666 @@ func (suite *APITestSuite) TestListEnvironmentsWithRedundantFilters() {
 	assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code, "Http response status is invalid")
 	assert.Equal(suite.T(), redundantFilterClientError+"\n", responseRecorder.Body.String(), "Error message is invalid")
 }
+
+func (suite *APITestSuite) TestListEnvironmentsPaginated() {
+	e1 := suite.createEnvironmentObject("e1", taskDefinitionARN, clusterARN1)
+	e2 := suite.createEnvironmentObject("e2", taskDefinitionARN, clusterARN1)
+	e3 := suite.createEnvironmentObject("e3", taskDefinitionARN, clusterARN1)
+	environments := []types.Environment{*e3, *e1, *e2}
+	suite.environment.EXPECT().ListEnvironments(gomock.Any()).Return(environments, nil).Times(2)
+
+	first := suite.listEnvironments("?maxResults=2")
+	assert.Len(suite.T(), first.Items, 2, "Expected a page of maxResults environments")
+	assert.Exactly(suite.T(), "e1", aws.StringValue(first.Items[0].Name), "Expected the environments ordered by name")
+	assert.Exactly(suite.T(), "e2", aws.StringValue(first.Items[1].Name), "Expected the environments ordered by name")
+	assert.NotEmpty(suite.T(), first.NextToken, "Expected a token for the next page")
+
+	second := suite.listEnvironments("?maxResults=2&nextToken=" + first.NextToken)
+	assert.Len(suite.T(), second.Items, 1, "Expected the remaining environments")
+	assert.Exactly(suite.T(), "e3", aws.StringValue(second.Items[0].Name), "Expected the environments after the first page")
+	assert.Empty(suite.T(), second.NextToken, "Expected no token on the last page")
+}
+
+func (suite *APITestSuite) TestListEnvironmentsWithStatusFilter() {
+	e1 := suite.createEnvironmentObject("e1", taskDefinitionARN, clusterARN1)
+	e2 := suite.createEnvironmentObject("e2", taskDefinitionARN, clusterARN1)
+	e2.Status = types.EnvironmentDeleting
+	suite.environment.EXPECT().ListEnvironments(gomock.Any()).Return([]types.Environment{*e1, *e2}, nil)
+
+	environments := suite.listEnvironments("?status=deleting")
+	assert.Len(suite.T(), environments.Items, 1, "Expected only the environments with the status")
+	assert.Exactly(suite.T(), "e2", aws.StringValue(environments.Items[0].Name), "Expected the deleting environment")
+}
+
+func (suite *APITestSuite) TestListEnvironmentsInvalidPagination() {
+	suite.environment.EXPECT().ListEnvironments(gomock.Any()).Times(0)
+
+	for _, query := range []string{"?maxResults=0", "?maxResults=101", "?maxResults=many", "?nextToken=!", "?status=unknown"} {
+		request, err := http.NewRequest("GET", "/v1/environments"+query, nil)
+		assert.Nil(suite.T(), err, "Unexpected error generating a list environments request")
+
+		responseRecorder := httptest.NewRecorder()
+		suite.router.ServeHTTP(responseRecorder, request)
+		assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code, "Expected a bad request for %s", query)
+	}
+}
+
+func (suite *APITestSuite) TestListDeploymentsPaginated() {
+	name := "testEnv"
+	startTime := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+	deployments := []types.Deployment{
+		{ID: "dep-4", Status: types.DeploymentInProgress, TaskDefinition: taskDefinitionARN, StartTime: startTime},
+		{ID: "dep-3", Status: types.DeploymentCompleted, TaskDefinition: taskDefinitionARN, StartTime: startTime.Add(-time.Hour)},
+		{ID: "dep-2", Status: types.DeploymentFailed, TaskDefinition: taskDefinitionARN, StartTime: startTime.Add(-time.Hour)},
+		{ID: "dep-1", Status: types.DeploymentCompleted, TaskDefinition: taskDefinitionARN, StartTime: startTime.Add(-2 * time.Hour)},
+	}
+	suite.deployment.EXPECT().ListDeploymentsSortedReverseChronologically(gomock.Any(), name).Return(deployments, nil).Times(3)
+
+	first := suite.listDeployments(name, "?maxResults=2")
+	assert.Exactly(suite.T(), []string{"dep-4", "dep-3"}, deploymentIDs(first), "Expected the latest deployments first")
+	assert.NotEmpty(suite.T(), first.NextToken, "Expected a token for the next page")
+
+	second := suite.listDeployments(name, "?maxResults=2&nextToken="+first.NextToken)
+	assert.Exactly(suite.T(), []string{"dep-2", "dep-1"}, deploymentIDs(second), "Expected the deployments after the first page")
+	assert.Empty(suite.T(), second.NextToken, "Expected no token on the last page")
+
+	completed := suite.listDeployments(name, "?status=completed")
+	assert.Exactly(suite.T(), []string{"dep-3", "dep-1"}, deploymentIDs(completed), "Expected only the completed deployments")
+}
+
+func (suite *APITestSuite) TestListDeploymentsInvalidPagination() {
+	name := "testEnv"
+	suite.deployment.EXPECT().ListDeploymentsSortedReverseChronologically(gomock.Any(), name).Return(nil, nil)
+
+	for _, query := range []string{"?maxResults=-1", "?status=running,failed", "?nextToken=" + encodeNextToken("invalid")} {
+		request, err := http.NewRequest("GET", "/v1/environments/"+name+"/deployments"+query, nil)
+		assert.Nil(suite.T(), err, "Unexpected error generating a list deployments request")
+
+		responseRecorder := httptest.NewRecorder()
+		suite.router.ServeHTTP(responseRecorder, request)
+		assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code, "Expected a bad request for %s", query)
+	}
+}
+
+func (suite *APITestSuite) listEnvironments(query string) models.Environments {
+	request, err := http.NewRequest("GET", "/v1/environments"+query, nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a list environments request")
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var environments models.Environments
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &environments)
+	return environments
+}
+
+func (suite *APITestSuite) listDeployments(name string, query string) models.Deployments {
+	request, err := http.NewRequest("GET", "/v1/environments/"+name+"/deployments"+query, nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a list deployments request")
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var deployments models.Deployments
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &deployments)
+	return deployments
+}
+
+func deploymentIDs(deployments models.Deployments) []string {
+	ids := make([]string, 0, len(deployments.Items))
+	for _, d := range deployments.Items {
+		ids = append(ids, aws.StringValue(d.ID))
+	}
+	return ids
+}
+
+func (suite *APITestSuite) TestPauseDeployment() {
+	name := "testEnv"
+	deployment := types.Deployment{ID: "dep-id", Status: types.DeploymentPaused, TaskDefinition: taskDefinitionARN}
+	suite.deployment.EXPECT().PauseDeployment(gomock.Any(), name, deployment.ID).Return(&deployment, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateDeploymentActionRequest(name, deployment.ID, "pause"))
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var deploymentModel models.Deployment
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &deploymentModel)
+	assert.Equal(suite.T(), models.DeploymentStatusPaused, aws.StringValue(deploymentModel.Status))
+}
+
+func (suite *APITestSuite) TestResumeDeploymentConflict() {
+	name := "testEnv"
+	conflict := types.NewConflictError(errors.New("Deployment has already completed"))
+	suite.deployment.EXPECT().ResumeDeployment(gomock.Any(), name, "dep-id").Return(nil, conflict)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateDeploymentActionRequest(name, "dep-id", "resume"))
+
+	assert.Equal(suite.T(), http.StatusConflict, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestCancelDeploymentWithRevert() {
+	name := "testEnv"
+	deployment := types.Deployment{ID: "dep-id", Status: types.DeploymentCancelled, TaskDefinition: taskDefinitionARN}
+	suite.deployment.EXPECT().CancelDeployment(gomock.Any(), name, deployment.ID, true).Return(&deployment, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateDeploymentActionRequest(name, deployment.ID, "cancel?revert=true"))
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var deploymentModel models.Deployment
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &deploymentModel)
+	assert.Equal(suite.T(), models.DeploymentStatusCancelled, aws.StringValue(deploymentModel.Status))
+}
+
+func (suite *APITestSuite) TestCancelDeploymentInvalidRevert() {
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateDeploymentActionRequest("testEnv", "dep-id", "cancel?revert=maybe"))
+
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestCreateDeploymentNotBefore() {
+	name := "testEnv"
+	token := "7d3d3d5a-0b7a-4d8e-9a34-1d2f3c4b5a69"
+	notBefore := time.Date(2017, time.March, 4, 2, 0, 0, 0, time.UTC)
+	deployment := types.Deployment{ID: "dep-id", Status: types.DeploymentPending, TaskDefinition: taskDefinitionARN,
+		NotBefore: notBefore}
+	suite.deployment.EXPECT().CreateDeployment(gomock.Any(), name, token, notBefore).Return(&deployment, nil)
+
+	request, err := http.NewRequest("POST", "/v1/environments/"+name+"/deployments?deploymentToken="+token+
+		"&notBefore=2017-03-04T02:00:00Z", nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a create deployment request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var deploymentModel models.Deployment
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &deploymentModel)
+	assert.Equal(suite.T(), notBefore, time.Time(deploymentModel.NotBefore).UTC(), "Expected the start time of the deployment")
+}
+
+func (suite *APITestSuite) TestCreateDeploymentInvalidNotBefore() {
+	request, err := http.NewRequest("POST", "/v1/environments/testEnv/deployments"+
+		"?deploymentToken=7d3d3d5a-0b7a-4d8e-9a34-1d2f3c4b5a69&notBefore=tomorrow", nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a create deployment request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestListDeploymentInstances() {
+	name := "testEnv"
+	startedAt := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+	instances := []types.DeploymentInstance{
+		{
+			Cluster:     clusterARN1,
+			InstanceARN: "instance-1",
+			Tasks: []types.DeploymentTask{{
+				TaskARN:       "task-1",
+				LastStatus:    "STOPPED",
+				StartedAt:     startedAt,
+				StoppedAt:     startedAt.Add(time.Minute),
+				StoppedReason: "Essential container in task exited",
+			}},
+		},
+		{Cluster: clusterARN1, InstanceARN: "instance-2", FailureReason: "RESOURCE:MEMORY"},
+	}
+	suite.deployment.EXPECT().ListDeploymentInstances(gomock.Any(), name, "dep-id").Return(instances, nil)
+
+	request, err := http.NewRequest("GET", "/v1/environments/"+name+"/deployments/dep-id/instances", nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a list deployment instances request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var instancesModel models.DeploymentInstances
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &instancesModel)
+	assert.Len(suite.T(), instancesModel.Items, 2, "Expected every instance of the deployment")
+
+	stopped := instancesModel.Items[0]
+	assert.Equal(suite.T(), "instance-1", aws.StringValue(stopped.InstanceARN))
+	assert.Len(suite.T(), stopped.Tasks, 1, "Expected the tasks on the instance")
+	assert.Equal(suite.T(), "task-1", aws.StringValue(stopped.Tasks[0].TaskARN))
+	assert.Equal(suite.T(), "STOPPED", stopped.Tasks[0].LastStatus)
+	assert.Equal(suite.T(), "Essential container in task exited", stopped.Tasks[0].StoppedReason)
+	assert.True(suite.T(), startedAt.Equal(time.Time(stopped.Tasks[0].StartedAt)), "Expected the start time of the task")
+
+	failed := instancesModel.Items[1]
+	assert.Equal(suite.T(), "instance-2", aws.StringValue(failed.InstanceARN))
+	assert.Equal(suite.T(), "RESOURCE:MEMORY", failed.FailureReason)
+	assert.Empty(suite.T(), failed.Tasks, "Expected no tasks on the instance the task failed to start on")
+}
+
+func (suite *APITestSuite) TestListDeploymentInstancesMissingDeployment() {
+	notFound := types.NewNotFoundError(errors.New("Deployment does not exist"))
+	suite.deployment.EXPECT().ListDeploymentInstances(gomock.Any(), "testEnv", "dep-id").Return(nil, notFound)
+
+	request, err := http.NewRequest("GET", "/v1/environments/testEnv/deployments/dep-id/instances", nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a list deployment instances request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusNotFound, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestPlanDeployment() {
+	name := "testEnv"
+	plan := &types.DeploymentPlan{
+		TaskDefinition: taskDefinitionARN,
+		Clusters: []types.ClusterPlan{{
+			Cluster:      clusterARN1,
+			NewInstances: []string{"instance-1"},
+			Batches:      [][]string{{"instance-2"}, {"instance-3"}},
+			StoppedTasks: []types.PlannedTask{
+				{TaskARN: "task-2", InstanceARN: "instance-2", Environment: name, DeploymentID: "old-dep-id"},
+				{TaskARN: "task-3", InstanceARN: "instance-3", Environment: name, DeploymentID: "old-dep-id"},
+			},
+			SkippedInstances: []types.SkippedInstance{{InstanceARN: "instance-4", Reason: "RESOURCE:MEMORY"}},
+		}},
+	}
+	suite.planner.EXPECT().PlanDeployment(gomock.Any(), name).Return(plan, nil)
+
+	request, err := http.NewRequest("GET", "/v1/environments/"+name+"/plan", nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a plan deployment request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var planModel models.DeploymentPlan
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &planModel)
+	assert.Equal(suite.T(), taskDefinitionARN, aws.StringValue(planModel.TaskDefinition))
+	assert.Len(suite.T(), planModel.Clusters, 1, "Expected a plan for the cluster of the environment")
+
+	clusterPlan := planModel.Clusters[0]
+	assert.Equal(suite.T(), clusterARN1, clusterPlan.Cluster)
+	assert.Equal(suite.T(), []string{"instance-1"}, clusterPlan.NewInstances)
+	assert.Equal(suite.T(), [][]string{{"instance-2"}, {"instance-3"}}, clusterPlan.Batches)
+	assert.Len(suite.T(), clusterPlan.StoppedTasks, 2, "Expected the tasks that would be stopped")
+	assert.Equal(suite.T(), "task-2", aws.StringValue(clusterPlan.StoppedTasks[0].TaskARN))
+	assert.Equal(suite.T(), "old-dep-id", clusterPlan.StoppedTasks[0].DeploymentID)
+	assert.Len(suite.T(), clusterPlan.SkippedInstances, 1, "Expected the instance that would be skipped")
+	assert.Equal(suite.T(), "instance-4", aws.StringValue(clusterPlan.SkippedInstances[0].InstanceARN))
+	assert.Equal(suite.T(), "RESOURCE:MEMORY", aws.StringValue(clusterPlan.SkippedInstances[0].Reason))
+}
+
+func (suite *APITestSuite) TestPlanDeploymentMissingEnvironment() {
+	notFound := types.NewNotFoundError(errors.New("Environment does not exist"))
+	suite.planner.EXPECT().PlanDeployment(gomock.Any(), "testEnv").Return(nil, notFound)
+
+	request, err := http.NewRequest("GET", "/v1/environments/testEnv/plan", nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a plan deployment request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusNotFound, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestCreateWebhook() {
+	name := "testEnv"
+	hook := &types.Webhook{
+		ID:              "webhook-id",
+		EnvironmentName: name,
+		URL:             "https://hooks.example.com/blox",
+		Secret:          "secret",
+		Events:          []types.WebhookEventType{types.WebhookDeploymentFailed},
+	}
+	suite.webhook.EXPECT().CreateWebhook(gomock.Any(), name, hook.URL, hook.Secret, hook.Events).Return(hook, nil)
+
+	request, err := http.NewRequest("POST", "/v1/environments/"+name+"/webhooks",
+		strings.NewReader(`{"url": "https://hooks.example.com/blox", "secret": "secret", "events": ["deployment.failed"]}`))
+	assert.Nil(suite.T(), err, "Unexpected error generating a create webhook request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var hookModel models.Webhook
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &hookModel)
+	assert.Equal(suite.T(), "webhook-id", aws.StringValue(hookModel.ID))
+	assert.Equal(suite.T(), []string{"deployment.failed"}, hookModel.Events)
+	assert.NotContains(suite.T(), string(b), "secret", "Expected the secret not to be returned")
+}
+
+func (suite *APITestSuite) TestCreateWebhookMissingURL() {
+	request, err := http.NewRequest("POST", "/v1/environments/testEnv/webhooks", strings.NewReader(`{"secret": "secret"}`))
+	assert.Nil(suite.T(), err, "Unexpected error generating a create webhook request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestCreateWebhookInvalidEvent() {
+	badRequest := types.NewBadRequestError(errors.New("Unknown event"))
+	suite.webhook.EXPECT().CreateWebhook(gomock.Any(), "testEnv", gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, badRequest)
+
+	request, err := http.NewRequest("POST", "/v1/environments/testEnv/webhooks",
+		strings.NewReader(`{"url": "https://hooks.example.com/blox", "secret": "secret", "events": ["deployment.exploded"]}`))
+	assert.Nil(suite.T(), err, "Unexpected error generating a create webhook request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestGetWebhookMissingWebhook() {
+	suite.webhook.EXPECT().GetWebhook(gomock.Any(), "testEnv", "webhook-id").Return(nil, nil)
+
+	request, err := http.NewRequest("GET", "/v1/environments/testEnv/webhooks/webhook-id", nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a get webhook request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusNotFound, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestDeleteWebhook() {
+	suite.webhook.EXPECT().DeleteWebhook(gomock.Any(), "testEnv", "webhook-id").Return(nil)
+
+	request, err := http.NewRequest("DELETE", "/v1/environments/testEnv/webhooks/webhook-id", nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a delete webhook request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestListWebhookDeliveries() {
+	deliveries := []types.WebhookDelivery{{
+		ID:             "delivery-id",
+		WebhookID:      "webhook-id",
+		Event:          types.WebhookDeploymentCompleted,
+		DeploymentID:   "dep-id",
+		Status:         types.WebhookDeliveryFailed,
+		Attempts:       5,
+		ResponseStatus: http.StatusServiceUnavailable,
+		Error:          "Unexpected response status 503",
+	}}
+	suite.webhook.EXPECT().ListWebhookDeliveries(gomock.Any(), "testEnv", "webhook-id").Return(deliveries, nil)
+
+	request, err := http.NewRequest("GET", "/v1/environments/testEnv/webhooks/webhook-id/deliveries", nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a list webhook deliveries request")
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, request)
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var deliveriesModel models.WebhookDeliveries
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &deliveriesModel)
+	assert.Len(suite.T(), deliveriesModel.Items, 1, "Expected the deliveries of the webhook")
+	delivery := deliveriesModel.Items[0]
+	assert.Equal(suite.T(), models.WebhookDeliveryStatusFailed, aws.StringValue(delivery.Status))
+	assert.Equal(suite.T(), int64(5), aws.Int64Value(delivery.Attempts))
+	assert.Equal(suite.T(), int64(http.StatusServiceUnavailable), delivery.ResponseStatus)
+	assert.Equal(suite.T(), "deployment.completed", aws.StringValue(delivery.Event))
+}
+
+func (suite *APITestSuite) generateDeploymentActionRequest(name string, id string, action string) *http.Request {
+	request, err := http.NewRequest("POST", "/v1/environments/"+name+"/deployments/"+id+"/"+action, nil)
+	assert.Nil(suite.T(), err, "Unexpected error generating a deployment action request")
+	return request
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentWithDeployment() {
+	name := "testEnv"
+	token := "token"
+	environment := suite.createEnvironmentObject(name, taskDefinitionARN, clusterARN1)
+	deployment := types.Deployment{ID: "dep-id", Status: types.DeploymentPending, TaskDefinition: taskDefinitionARN}
+	suite.ecs.EXPECT().DescribeTaskDefinition(aws.String("test")).Return(&ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String(taskDefinitionARN),
+		Status:            aws.String("ACTIVE"),
+	}, nil)
+	// the task overrides of the environment are checked against the new task definition
+	suite.environment.EXPECT().GetEnvironment(gomock.Any(), name).Return(environment, nil)
+	suite.environment.EXPECT().UpdateEnvironmentSettings(gomock.Any(), name, token, gomock.Any(), true).
+		Do(func(_ interface{}, _ string, _ string, update types.EnvironmentUpdate, _ bool) {
+			assert.Equal(suite.T(), taskDefinitionARN, aws.StringValue(update.TaskDefinition),
+				"Expected the task definition ARN")
+			assert.Nil(suite.T(), update.Cluster, "Expected the cluster to be left unchanged")
+			assert.Nil(suite.T(), update.RolloutStrategy, "Expected the rollout strategy to be left unchanged")
+		}).Return(environment, &deployment, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", name,
+		"?deploymentToken="+token+"&deploy=true", `{"taskDefinition": "test"}`))
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var response models.UpdateEnvironmentResponse
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &response)
+	suite.assertSame(environment, response.Environment)
+	assert.Equal(suite.T(), deployment.ID, aws.StringValue(response.Deployment.ID))
+}
+
+func (suite *APITestSuite) TestReplaceEnvironmentResetsMissingSettings() {
+	name := "testEnv"
+	environment := suite.createEnvironmentObject(name, taskDefinitionARN, clusterARN1)
+	suite.ecs.EXPECT().DescribeCluster(aws.String(clusterName1)).Return(&ecs.Cluster{
+		ClusterArn: aws.String(clusterARN1),
+		Status:     aws.String("ACTIVE"),
+	}, nil)
+	suite.ecs.EXPECT().DescribeTaskDefinition(aws.String(taskDefinitionARN)).Return(&ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String(taskDefinitionARN),
+		Status:            aws.String("ACTIVE"),
+	}, nil)
+	suite.environment.EXPECT().UpdateEnvironmentSettings(gomock.Any(), name, "token", gomock.Any(), false).
+		Do(func(_ interface{}, _ string, _ string, update types.EnvironmentUpdate, _ bool) {
+			assert.Equal(suite.T(), clusterARN1, aws.StringValue(update.Cluster), "Expected the cluster ARN")
+			assert.Equal(suite.T(), types.RolloutStrategy{}, *update.RolloutStrategy,
+				"Expected the rollout strategy to be reset")
+			assert.Equal(suite.T(), types.RollbackPolicy{}, *update.RollbackPolicy,
+				"Expected the rollback policy to be reset")
+			assert.Equal(suite.T(), types.RestartPolicy{}, *update.RestartPolicy,
+				"Expected the restart policy to be reset")
+		}).Return(environment, nil, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PUT", name, "?deploymentToken=token",
+		`{"taskDefinition": "`+taskDefinitionARN+`", "instanceGroup": {"cluster": "`+clusterName1+`"}}`))
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestReplaceEnvironmentMissingCluster() {
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PUT", "testEnv",
+		"?deploymentToken=token", `{"taskDefinition": "test"}`))
+
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentUnknownCluster() {
+	suite.ecs.EXPECT().DescribeCluster(aws.String("unknown")).Return(nil,
+		errors.New("Cluster with name unknown is missing"))
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", "testEnv",
+		"?deploymentToken=token", `{"instanceGroup": {"cluster": "unknown"}}`))
+
+	assert.Equal(suite.T(), http.StatusNotFound, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentSelectorUnknownCluster() {
+	suite.ecs.EXPECT().DescribeCluster(aws.String("unknown")).Return(nil, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", "testEnv",
+		"?deploymentToken=token", `{"instanceGroup": {"clusterSelector": {"clusters": ["unknown"]}}}`))
+
+	assert.Equal(suite.T(), http.StatusNotFound, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentInactiveTaskDefinition() {
+	suite.ecs.EXPECT().DescribeTaskDefinition(aws.String("test")).Return(&ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String(taskDefinitionARN),
+		Status:            aws.String("INACTIVE"),
+	}, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", "testEnv",
+		"?deploymentToken=token", `{"taskDefinition": "test"}`))
+
+	assert.Equal(suite.T(), http.StatusNotFound, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentOutdatedToken() {
+	name := "testEnv"
+	suite.environment.EXPECT().UpdateEnvironmentSettings(gomock.Any(), name, "outdated", gomock.Any(), false).
+		Return(nil, nil, types.NewConflictError(errors.New("Token is outdated")))
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", name,
+		"?deploymentToken=outdated", `{"rolloutStrategy": {"batchSize": 2}}`))
+
+	assert.Equal(suite.T(), http.StatusConflict, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentTaskOverrides() {
+	name := "testEnv"
+	environment := suite.createEnvironmentObject(name, taskDefinitionARN, clusterARN1)
+	suite.environment.EXPECT().GetEnvironment(gomock.Any(), name).Return(environment, nil)
+	suite.ecs.EXPECT().DescribeTaskDefinition(aws.String(taskDefinitionARN)).Return(&ecs.TaskDefinition{
+		TaskDefinitionArn:    aws.String(taskDefinitionARN),
+		Status:               aws.String("ACTIVE"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{{Name: aws.String("agent")}},
+	}, nil)
+	suite.environment.EXPECT().UpdateEnvironmentSettings(gomock.Any(), name, "token", gomock.Any(), false).
+		Do(func(_ interface{}, _ string, _ string, update types.EnvironmentUpdate, _ bool) {
+			assert.Equal(suite.T(), &types.TaskOverrides{
+				TaskRoleARN:      "arn:aws:iam::123456789012:role/agent",
+				ExecutionRoleARN: "arn:aws:iam::123456789012:role/execution",
+				CPU:              "0.5 vCPU",
+				Memory:           "1024",
+				Tags:             map[string]string{"team": "logs"},
+				ContainerOverrides: []types.ContainerOverride{{
+					Name:        "agent",
+					Command:     []string{"agent", "--verbose"},
+					Environment: map[string]string{"LEVEL": "debug"},
+				}},
+			}, update.TaskOverrides, "Expected the task overrides")
+			assert.Nil(suite.T(), update.TaskDefinition, "Expected the task definition to be left unchanged")
+		}).Return(environment, nil, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", name, "?deploymentToken=token",
+		`{"taskOverrides": {"taskRoleArn": "arn:aws:iam::123456789012:role/agent", `+
+			`"executionRoleArn": "arn:aws:iam::123456789012:role/execution", "cpu": "0.5 vCPU", "memory": "1024", `+
+			`"tags": {"team": "logs"}, "containerOverrides": `+
+			`[{"name": "agent", "command": ["agent", "--verbose"], "environment": {"LEVEL": "debug"}}]}}`))
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentMaintenanceWindows() {
+	name := "testEnv"
+	environment := suite.createEnvironmentObject(name, taskDefinitionARN, clusterARN1)
+	suite.environment.EXPECT().UpdateEnvironmentSettings(gomock.Any(), name, "token", gomock.Any(), false).
+		Do(func(_ interface{}, _ string, _ string, update types.EnvironmentUpdate, _ bool) {
+			assert.Equal(suite.T(), []types.MaintenanceWindow{{Schedule: "0 2 * * 6", Duration: 2 * time.Hour}},
+				update.MaintenanceWindows, "Expected the maintenance windows")
+		}).Return(environment, nil, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", name, "?deploymentToken=token",
+		`{"maintenanceWindows": [{"schedule": "0 2 * * 6", "durationSeconds": 7200}]}`))
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentDrainingPolicy() {
+	name := "testEnv"
+	environment := suite.createEnvironmentObject(name, taskDefinitionARN, clusterARN1)
+	suite.environment.EXPECT().UpdateEnvironmentSettings(gomock.Any(), name, "token", gomock.Any(), false).
+		Do(func(_ interface{}, _ string, _ string, update types.EnvironmentUpdate, _ bool) {
+			assert.Equal(suite.T(), types.DrainingStopImmediately, *update.DrainingPolicy, "Expected the draining policy")
+			assert.Nil(suite.T(), update.MaintenanceWindows, "Expected the maintenance windows to be left unchanged")
+		}).Return(environment, nil, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", name, "?deploymentToken=token",
+		`{"drainingPolicy": "stop-immediately"}`))
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentUnknownDrainingPolicy() {
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", "testEnv", "?deploymentToken=token",
+		`{"drainingPolicy": "drain-whenever"}`))
+
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentSchedulingStrategy() {
+	name := "testEnv"
+	environment := suite.createEnvironmentObject(name, taskDefinitionARN, clusterARN1)
+	environment.SchedulingStrategy = types.SchedulingStrategy{Type: types.ReplicaStrategy, Replicas: 3}
+	suite.environment.EXPECT().UpdateEnvironmentSettings(gomock.Any(), name, "token", gomock.Any(), false).
+		Do(func(_ interface{}, _ string, _ string, update types.EnvironmentUpdate, _ bool) {
+			assert.Equal(suite.T(), environment.SchedulingStrategy, *update.SchedulingStrategy, "Expected the scheduling strategy")
+			assert.Nil(suite.T(), update.RolloutStrategy, "Expected the rollout strategy to be left unchanged")
+		}).Return(environment, nil, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", name, "?deploymentToken=token",
+		`{"schedulingStrategy": {"type": "replica", "replicas": 3}}`))
+
+	assert.Equal(suite.T(), http.StatusOK, responseRecorder.Code)
+
+	var resp models.UpdateEnvironmentResponse
+	b, _ := ioutil.ReadAll(responseRecorder.Body)
+	json.Unmarshal(b, &resp)
+	assert.Exactly(suite.T(), models.SchedulingStrategyTypeReplica, resp.Environment.SchedulingStrategy.Type)
+	assert.Exactly(suite.T(), int64(3), resp.Environment.SchedulingStrategy.Replicas)
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentUnknownSchedulingStrategy() {
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", "testEnv", "?deploymentToken=token",
+		`{"schedulingStrategy": {"type": "singleton"}}`))
+
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) TestUpdateEnvironmentTaskOverridesUnknownContainer() {
+	name := "testEnv"
+	environment := suite.createEnvironmentObject(name, taskDefinitionARN, clusterARN1)
+	suite.environment.EXPECT().GetEnvironment(gomock.Any(), name).Return(environment, nil)
+	suite.ecs.EXPECT().DescribeTaskDefinition(aws.String(taskDefinitionARN)).Return(&ecs.TaskDefinition{
+		TaskDefinitionArn:    aws.String(taskDefinitionARN),
+		Status:               aws.String("ACTIVE"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{{Name: aws.String("agent")}},
+	}, nil)
+
+	responseRecorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(responseRecorder, suite.generateUpdateEnvironmentRequest("PATCH", name, "?deploymentToken=token",
+		`{"taskOverrides": {"containerOverrides": [{"name": "sidecar", "command": ["run"]}]}}`))
+
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRecorder.Code)
+}
+
+func (suite *APITestSuite) generateUpdateEnvironmentRequest(method string, name string, query string, body string) *http.Request {
+	request, err := http.NewRequest(method, "/v1/environments/"+name+query, strings.NewReader(body))
+	assert.Nil(suite.T(), err, "Unexpected error generating an update environment request")
+	return request
+}
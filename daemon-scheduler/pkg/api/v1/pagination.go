@@ -0,0 +1,167 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/blox/blox/daemon-scheduler/swagger/v1/generated/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	maxResultsKey = "maxResults"
+	statusKey     = "status"
+
+	defaultMaxResults = 100
+	maxMaxResults     = 100
+)
+
+var (
+	environmentStatuses = map[string]bool{
+		models.EnvironmentStatusActive:   true,
+		models.EnvironmentStatusDeleting: true,
+	}
+	deploymentStatuses = map[string]bool{
+		models.DeploymentStatusPending:   true,
+		models.DeploymentStatusRunning:   true,
+		models.DeploymentStatusCompleted: true,
+		models.DeploymentStatusFailed:    true,
+		models.DeploymentStatusPaused:    true,
+		models.DeploymentStatusCancelled: true,
+	}
+)
+
+// listOptions are the status filter and the page requested by a list request
+type listOptions struct {
+	status     string
+	maxResults int
+	// after is the decoded nextToken: the position of the last item of the previous page
+	after string
+}
+
+// parseListOptions returns the list options of the query. The status has to be one of statuses.
+func parseListOptions(query url.Values, statuses map[string]bool) (listOptions, error) {
+	options := listOptions{maxResults: defaultMaxResults}
+
+	if status := query.Get(statusKey); status != "" {
+		if !statuses[status] {
+			return options, errors.Errorf("Invalid status %s", status)
+		}
+		options.status = status
+	}
+
+	if maxResults := query.Get(maxResultsKey); maxResults != "" {
+		n, err := strconv.Atoi(maxResults)
+		if err != nil || n < 1 || n > maxMaxResults {
+			return options, errors.Errorf("maxResults has to be a number between 1 and %d", maxMaxResults)
+		}
+		options.maxResults = n
+	}
+
+	if token := query.Get(nextToken); token != "" {
+		after, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || len(after) == 0 {
+			return options, errors.New("Invalid nextToken")
+		}
+		options.after = string(after)
+	}
+
+	return options, nil
+}
+
+// encodeNextToken returns the token of the page that starts after position
+func encodeNextToken(position string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+// pageEnvironments returns the page of environments, ordered by name, that have the status of the
+// options, along with the token of the next page if there is one
+func pageEnvironments(envs []types.Environment, options listOptions) ([]types.Environment, string) {
+	sort.Sort(nameOrderedEnvironments(envs))
+
+	page := make([]types.Environment, 0, options.maxResults)
+	for _, env := range envs {
+		if options.after != "" && env.Name <= options.after {
+			continue
+		}
+		if options.status != "" && toEnvironmentStatus(env) != options.status {
+			continue
+		}
+		if len(page) == options.maxResults {
+			return page, encodeNextToken(page[len(page)-1].Name)
+		}
+		page = append(page, env)
+	}
+	return page, ""
+}
+
+type nameOrderedEnvironments []types.Environment
+
+func (p nameOrderedEnvironments) Len() int           { return len(p) }
+func (p nameOrderedEnvironments) Less(i, j int) bool { return p[i].Name < p[j].Name }
+func (p nameOrderedEnvironments) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// pageDeployments returns the page of reverse-chronologically ordered deployments that have the
+// status of the options, along with the token of the next page if there is one
+func pageDeployments(ds []types.Deployment, options listOptions) ([]types.Deployment, string, error) {
+	var afterTime time.Time
+	var afterID string
+	if options.after != "" {
+		var err error
+		afterTime, afterID, err = decodeDeploymentPosition(options.after)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	page := make([]types.Deployment, 0, options.maxResults)
+	for _, d := range ds {
+		if options.after != "" && !d.StartTime.Before(afterTime) &&
+			!(d.StartTime.Equal(afterTime) && d.ID < afterID) {
+			continue
+		}
+		if options.status != "" && d.Status.String() != options.status {
+			continue
+		}
+		if len(page) == options.maxResults {
+			return page, encodeNextToken(deploymentPosition(page[len(page)-1])), nil
+		}
+		page = append(page, d)
+	}
+	return page, "", nil
+}
+
+// deploymentPosition returns the position of the deployment in the reverse-chronological order
+func deploymentPosition(d types.Deployment) string {
+	return strconv.FormatInt(d.StartTime.UnixNano(), 10) + "/" + d.ID
+}
+
+func decodeDeploymentPosition(position string) (time.Time, string, error) {
+	parts := strings.SplitN(position, "/", 2)
+	if len(parts) != 2 {
+		return time.Time{}, "", errors.New("Invalid nextToken")
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, "", errors.New("Invalid nextToken")
+	}
+	return time.Unix(0, nanos), parts[1], nil
+}
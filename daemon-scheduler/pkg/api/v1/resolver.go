@@ -0,0 +1,77 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/auth"
+	"github.com/blox/blox/daemon-scheduler/swagger/v1/generated/models"
+	"github.com/gorilla/mux"
+)
+
+type actionResolver struct {
+	router *mux.Router
+}
+
+// NewActionResolver maps requests to auth actions using the names of the routes created by NewRouter
+func NewActionResolver(router *mux.Router) auth.ActionResolver {
+	return actionResolver{
+		router: router,
+	}
+}
+
+func (a actionResolver) Resolve(r *http.Request) (auth.Action, string, bool, bool) {
+	var match mux.RouteMatch
+	if !a.router.Match(r, &match) || match.Route == nil {
+		return "", "", false, false
+	}
+
+	name := match.Route.GetName()
+	if name == pingRoute {
+		return "", "", true, true
+	}
+
+	action := auth.Action(name)
+	if action == auth.ActionCreateEnvironment {
+		return action, environmentNameFromBody(r), false, true
+	}
+
+	return action, match.Vars[envNameKey], false, true
+}
+
+// environmentNameFromBody reads the environment name of a create environment request and restores the body
+func environmentNameFromBody(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return ""
+	}
+
+	var createEnvReq models.CreateEnvironmentRequest
+	json.Unmarshal(b, &createEnvReq)
+	if createEnvReq.Name == nil {
+		return ""
+	}
+
+	return *createEnvReq.Name
+}
@@ -0,0 +1,76 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePing(t *testing.T) {
+	resolver := NewActionResolver(NewRouter(API{}))
+	r, _ := http.NewRequest("GET", "/v1/ping", nil)
+	_, _, public, ok := resolver.Resolve(r)
+	assert.True(t, ok, "Expected ping to be resolved")
+	assert.True(t, public, "Expected ping to be public")
+}
+
+func TestResolveDeleteEnvironment(t *testing.T) {
+	resolver := NewActionResolver(NewRouter(API{}))
+	r, _ := http.NewRequest("DELETE", "/v1/environments/env1", nil)
+	action, environment, public, ok := resolver.Resolve(r)
+	assert.True(t, ok, "Expected delete environment to be resolved")
+	assert.False(t, public, "Expected delete environment not to be public")
+	assert.Equal(t, auth.ActionDeleteEnvironment, action, "Unexpected action")
+	assert.Equal(t, "env1", environment, "Unexpected environment")
+}
+
+func TestResolveCreateEnvironmentReadsNameFromBody(t *testing.T) {
+	resolver := NewActionResolver(NewRouter(API{}))
+	body := `{"name":"env1","taskDefinition":"td","instanceGroup":{"cluster":"cluster"}}`
+	r, _ := http.NewRequest("POST", "/v1/environments", bytes.NewBufferString(body))
+	action, environment, _, ok := resolver.Resolve(r)
+	assert.True(t, ok, "Expected create environment to be resolved")
+	assert.Equal(t, auth.ActionCreateEnvironment, action, "Unexpected action")
+	assert.Equal(t, "env1", environment, "Unexpected environment")
+
+	b, _ := ioutil.ReadAll(r.Body)
+	assert.Equal(t, body, string(b), "Body should be readable after resolving")
+}
+
+func TestResolveUnknownRoute(t *testing.T) {
+	resolver := NewActionResolver(NewRouter(API{}))
+	r, _ := http.NewRequest("GET", "/v1/unknown", nil)
+	_, _, _, ok := resolver.Resolve(r)
+	assert.False(t, ok, "Expected unknown route not to be resolved")
+}
+
+func TestResolveMetrics(t *testing.T) {
+	resolver := NewActionResolver(NewRouter(API{}))
+	r, _ := http.NewRequest("GET", "/debug/vars", nil)
+	action, environment, public, ok := resolver.Resolve(r)
+	assert.True(t, ok, "Expected the metrics to be resolved")
+	assert.False(t, public, "Expected the metrics not to be public")
+	assert.Equal(t, auth.ActionGetMetrics, action, "Unexpected action")
+	assert.Equal(t, "", environment, "Expected the metrics not to be scoped to an environment")
+
+	r, _ = http.NewRequest("GET", "/v1/debug/vars", nil)
+	_, _, _, ok = resolver.Resolve(r)
+	assert.False(t, ok, "Expected the metrics not to be served under /v1")
+}
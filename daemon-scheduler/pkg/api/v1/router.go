@@ -13,66 +13,159 @@
 
 package v1
 
-import "github.com/gorilla/mux"
+import (
+	"expvar"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/auth"
+	"github.com/blox/blox/shared/ecsclient"
+	"github.com/gorilla/mux"
+)
 
 const (
 	nextToken       = "nextToken"
 	deploymentToken = "deploymentToken"
+	revert          = "revert"
+	deploy          = "deploy"
+	cascade         = "cascade"
 	cluster         = "cluster"
+	notBefore       = "notBefore"
+
+	drainTimeoutSeconds = "drainTimeoutSeconds"
+
+	pingRoute = "Ping"
 )
 
 func NewRouter(api API) *mux.Router {
 	r := mux.NewRouter().StrictSlash(true)
-	s := r.Path("/v1").Subrouter()
+	s := r.PathPrefix("/v1").Subrouter()
 
 	// health
 
 	s.Path("/ping").
 		Methods("GET").
-		HandlerFunc(api.Ping)
+		HandlerFunc(api.Ping).
+		Name(pingRoute)
 
 	// environment
 
 	s.Path("/environments").
 		Methods("POST").
-		HandlerFunc(api.CreateEnvironment)
+		HandlerFunc(api.CreateEnvironment).
+		Name(string(auth.ActionCreateEnvironment))
 
 	s.Path("/environments/{name}").
 		Methods("GET").
-		HandlerFunc(api.GetEnvironment)
+		HandlerFunc(api.GetEnvironment).
+		Name(string(auth.ActionGetEnvironment))
 
 	s.Path("/environments").
 		Methods("GET").
-		HandlerFunc(api.ListEnvironments)
+		HandlerFunc(api.ListEnvironments).
+		Name(string(auth.ActionListEnvironments))
 
 	s.Path("/environments").
 		Queries(nextToken, "").
 		Methods("GET").
-		HandlerFunc(api.ListEnvironments)
+		HandlerFunc(api.ListEnvironments).
+		Name(string(auth.ActionListEnvironments))
+
+	s.Path("/environments/{name}").
+		Methods("PUT").
+		HandlerFunc(api.ReplaceEnvironment).
+		Name(string(auth.ActionUpdateEnvironment))
+
+	s.Path("/environments/{name}").
+		Methods("PATCH").
+		HandlerFunc(api.UpdateEnvironment).
+		Name(string(auth.ActionUpdateEnvironment))
 
 	s.Path("/environments/{name}").
 		Methods("DELETE").
-		HandlerFunc(api.DeleteEnvironment)
+		HandlerFunc(api.DeleteEnvironment).
+		Name(string(auth.ActionDeleteEnvironment))
 
 	// deployment
 
 	s.Path("/environments/{name}/deployments").
 		Queries(deploymentToken, "{deploymentToken:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}").
 		Methods("POST").
-		HandlerFunc(api.CreateDeployment)
+		HandlerFunc(api.CreateDeployment).
+		Name(string(auth.ActionCreateDeployment))
+
+	s.Path("/environments/{name}/plan").
+		Methods("GET").
+		HandlerFunc(api.PlanDeployment).
+		Name(string(auth.ActionPlanDeployment))
 
 	s.Path("/environments/{name}/deployments/{id}").
 		Methods("GET").
-		HandlerFunc(api.GetDeployment)
+		HandlerFunc(api.GetDeployment).
+		Name(string(auth.ActionGetDeployment))
+
+	s.Path("/environments/{name}/deployments/{id}/instances").
+		Methods("GET").
+		HandlerFunc(api.ListDeploymentInstances).
+		Name(string(auth.ActionListDeploymentInstances))
+
+	s.Path("/environments/{name}/deployments/{id}/pause").
+		Methods("POST").
+		HandlerFunc(api.PauseDeployment).
+		Name(string(auth.ActionPauseDeployment))
+
+	s.Path("/environments/{name}/deployments/{id}/resume").
+		Methods("POST").
+		HandlerFunc(api.ResumeDeployment).
+		Name(string(auth.ActionResumeDeployment))
+
+	s.Path("/environments/{name}/deployments/{id}/cancel").
+		Methods("POST").
+		HandlerFunc(api.CancelDeployment).
+		Name(string(auth.ActionCancelDeployment))
 
 	s.Path("/environments/{name}/deployments").
 		Methods("GET").
-		HandlerFunc(api.ListDeployments)
+		HandlerFunc(api.ListDeployments).
+		Name(string(auth.ActionListDeployments))
 
 	s.Path("/environments/{name}/deployments").
 		Queries(nextToken, "").
 		Methods("GET").
-		HandlerFunc(api.ListDeployments)
+		HandlerFunc(api.ListDeployments).
+		Name(string(auth.ActionListDeployments))
+
+	// webhook
+
+	s.Path("/environments/{name}/webhooks").
+		Methods("POST").
+		HandlerFunc(api.CreateWebhook).
+		Name(string(auth.ActionCreateWebhook))
+
+	s.Path("/environments/{name}/webhooks").
+		Methods("GET").
+		HandlerFunc(api.ListWebhooks).
+		Name(string(auth.ActionListWebhooks))
+
+	s.Path("/environments/{name}/webhooks/{id}").
+		Methods("GET").
+		HandlerFunc(api.GetWebhook).
+		Name(string(auth.ActionGetWebhook))
+
+	s.Path("/environments/{name}/webhooks/{id}").
+		Methods("DELETE").
+		HandlerFunc(api.DeleteWebhook).
+		Name(string(auth.ActionDeleteWebhook))
+
+	s.Path("/environments/{name}/webhooks/{id}/deliveries").
+		Methods("GET").
+		HandlerFunc(api.ListWebhookDeliveries).
+		Name(string(auth.ActionListWebhookDeliveries))
+
+	// metrics, served at the root rather than under /v1
+
+	r.Path(ecsclient.MetricsPath).
+		Methods("GET").
+		Handler(expvar.Handler()).
+		Name(string(auth.ActionGetMetrics))
 
-	return s
+	return r
 }
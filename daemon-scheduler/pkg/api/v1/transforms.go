@@ -14,62 +14,618 @@
 package v1
 
 import (
+	"sort"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/blox/blox/daemon-scheduler/pkg/types"
 	"github.com/blox/blox/daemon-scheduler/swagger/v1/generated/models"
+	"github.com/go-openapi/strfmt"
 )
 
 func toEnvironmentModel(envType types.Environment) models.Environment {
-	health := models.HealthStatusHealthy
-	if envType.Health == types.EnvironmentUnhealthy {
-		health = models.HealthStatusUnhealthy
-	}
 	return models.Environment{
 		Name: &envType.Name,
 		InstanceGroup: &models.InstanceGroup{
-			Cluster: envType.Cluster,
+			Cluster:              envType.Cluster,
+			ClusterSelector:      toClusterSelectorModel(envType.ClusterSelector),
+			PlacementConstraints: toPlacementConstraintsModel(envType.PlacementConstraints),
 		},
-		Health:          health,
-		DeploymentToken: envType.Token,
-		TaskDefinition:  envType.DesiredTaskDefinition,
+		Health:                models.HealthStatus(envType.Health.String()),
+		CrashLoopingInstances: toCrashLoopingInstanceModels(envType),
+		DisconnectedInstances: toDisconnectedInstanceModels(envType),
+		MissingReplicas:       toMissingReplicasModel(envType.MissingReplicas),
+		DeploymentToken:       envType.Token,
+		TaskDefinition:        envType.DesiredTaskDefinition,
+		RolloutStrategy:       toRolloutStrategyModel(envType.RolloutStrategy),
+		SchedulingStrategy:    toSchedulingStrategyModel(envType.SchedulingStrategy),
+		RollbackPolicy:        toRollbackPolicyModel(envType.RollbackPolicy),
+		RestartPolicy:         toRestartPolicyModel(envType.RestartPolicy),
+		CapacityPolicy:        toCapacityPolicyModel(envType.CapacityPolicy),
+		TaskOverrides:         toTaskOverridesModel(envType.TaskOverrides),
+		MaintenanceWindows:    toMaintenanceWindowModels(envType.MaintenanceWindows),
+		DrainingPolicy:        string(envType.DrainingPolicy.OrDefault()),
+		Status:                toEnvironmentStatus(envType),
+		DrainDeadline:         toDateTime(envType.DrainDeadline),
+	}
+}
+
+func toEnvironmentStatus(envType types.Environment) string {
+	if envType.IsDeleting() {
+		return models.EnvironmentStatusDeleting
+	}
+	return models.EnvironmentStatusActive
+}
+
+func toCrashLoopingInstanceModels(envType types.Environment) []*models.CrashLoopingInstance {
+	instances := []*models.CrashLoopingInstance{}
+	for _, instanceARN := range envType.CrashLoopingInstances() {
+		restart := envType.Restarts[instanceARN]
+		instances = append(instances, &models.CrashLoopingInstance{
+			InstanceARN:   aws.String(instanceARN),
+			RestartCount:  aws.Int64(int64(restart.Count)),
+			StoppedReason: restart.LastStoppedTask.StoppedReason,
+			ExitCodes:     restart.LastStoppedTask.ExitCodes,
+		})
+	}
+	return instances
+}
+
+func toDisconnectedInstanceModels(envType types.Environment) []*models.DisconnectedInstance {
+	instances := []*models.DisconnectedInstance{}
+	for _, instanceARN := range envType.DisconnectedInstanceARNs() {
+		instances = append(instances, &models.DisconnectedInstance{
+			InstanceARN: aws.String(instanceARN),
+			Cluster:     envType.DisconnectedInstances[instanceARN],
+		})
+	}
+	return instances
+}
+
+func toMissingReplicasModel(missing map[string]int) map[string]int64 {
+	if len(missing) == 0 {
+		return nil
+	}
+	model := make(map[string]int64, len(missing))
+	for cluster, replicas := range missing {
+		model[cluster] = int64(replicas)
+	}
+	return model
+}
+
+func toClusterSelectorModel(selector types.ClusterSelector) *models.ClusterSelector {
+	if selector.IsEmpty() {
+		return nil
+	}
+	return &models.ClusterSelector{
+		Clusters:    selector.Clusters,
+		NamePattern: selector.NamePattern,
+		Tags:        selector.Tags,
+	}
+}
+
+func toClusterSelector(selector *models.ClusterSelector) types.ClusterSelector {
+	if selector == nil {
+		return types.ClusterSelector{}
+	}
+	return types.ClusterSelector{
+		Clusters:    selector.Clusters,
+		NamePattern: selector.NamePattern,
+		Tags:        selector.Tags,
+	}
+}
+
+func toRolloutStrategyModel(strategy types.RolloutStrategy) *models.RolloutStrategy {
+	if !strategy.IsRolling() {
+		return nil
+	}
+	return &models.RolloutStrategy{
+		BatchSize:         int64(strategy.BatchSize),
+		BatchPercent:      int64(strategy.BatchPercent),
+		MinHealthyPercent: int64(strategy.MinHealthyPercent),
+		SoakSeconds:       int64(strategy.SoakTime / time.Second),
+	}
+}
+
+func toRolloutStrategy(strategy *models.RolloutStrategy) types.RolloutStrategy {
+	if strategy == nil {
+		return types.RolloutStrategy{}
+	}
+	return types.RolloutStrategy{
+		BatchSize:         int(strategy.BatchSize),
+		BatchPercent:      int(strategy.BatchPercent),
+		MinHealthyPercent: int(strategy.MinHealthyPercent),
+		SoakTime:          time.Duration(strategy.SoakSeconds) * time.Second,
+	}
+}
+
+func toSchedulingStrategyModel(strategy types.SchedulingStrategy) *models.SchedulingStrategy {
+	return &models.SchedulingStrategy{
+		Type:     string(strategy.TypeOrDefault()),
+		Replicas: int64(strategy.Replicas),
+	}
+}
+
+func toSchedulingStrategy(strategy *models.SchedulingStrategy) types.SchedulingStrategy {
+	if strategy == nil {
+		return types.SchedulingStrategy{}
+	}
+	return types.SchedulingStrategy{
+		Type:     types.SchedulingStrategyType(strategy.Type),
+		Replicas: int(strategy.Replicas),
+	}
+}
+
+// toRestartPolicyModel returns the settings the environment is restarted with, including the
+// defaults of the settings it does not set
+func toRestartPolicyModel(policy types.RestartPolicy) *models.RestartPolicy {
+	policy = policy.WithDefaults()
+	return &models.RestartPolicy{
+		InitialBackoffSeconds: int64(policy.InitialBackoff / time.Second),
+		MaxBackoffSeconds:     int64(policy.MaxBackoff / time.Second),
+		MaxRestarts:           int64(policy.MaxRestarts),
+		StableSeconds:         int64(policy.StableTime / time.Second),
+	}
+}
+
+func toRestartPolicy(policy *models.RestartPolicy) types.RestartPolicy {
+	if policy == nil {
+		return types.RestartPolicy{}
+	}
+	return types.RestartPolicy{
+		InitialBackoff: time.Duration(policy.InitialBackoffSeconds) * time.Second,
+		MaxBackoff:     time.Duration(policy.MaxBackoffSeconds) * time.Second,
+		MaxRestarts:    int(policy.MaxRestarts),
+		StableTime:     time.Duration(policy.StableSeconds) * time.Second,
+	}
+}
+
+func toRollbackPolicyModel(policy types.RollbackPolicy) *models.RollbackPolicy {
+	if !policy.IsEnabled() {
+		return nil
+	}
+	return &models.RollbackPolicy{
+		FailedInstancePercent: int64(policy.FailedInstancePercent),
+		PendingTaskCount:      int64(policy.PendingTaskCount),
+		PendingTimeoutSeconds: int64(policy.PendingTimeout / time.Second),
+		CrashCount:            int64(policy.CrashCount),
+		CrashWindowSeconds:    int64(policy.CrashWindow / time.Second),
+	}
+}
+
+func toRollbackPolicy(policy *models.RollbackPolicy) types.RollbackPolicy {
+	if policy == nil {
+		return types.RollbackPolicy{}
+	}
+	return types.RollbackPolicy{
+		FailedInstancePercent: int(policy.FailedInstancePercent),
+		PendingTaskCount:      int(policy.PendingTaskCount),
+		PendingTimeout:        time.Duration(policy.PendingTimeoutSeconds) * time.Second,
+		CrashCount:            int(policy.CrashCount),
+		CrashWindow:           time.Duration(policy.CrashWindowSeconds) * time.Second,
+	}
+}
+
+func toCapacityPolicyModel(policy types.CapacityPolicy) *models.CapacityPolicy {
+	if policy.IsEmpty() {
+		return nil
+	}
+	return &models.CapacityPolicy{
+		Priority:               int64(policy.Priority),
+		StopLowerPriorityTasks: policy.StopLowerPriorityTasks,
+	}
+}
+
+func toCapacityPolicy(policy *models.CapacityPolicy) types.CapacityPolicy {
+	if policy == nil {
+		return types.CapacityPolicy{}
+	}
+	return types.CapacityPolicy{
+		Priority:               int(policy.Priority),
+		StopLowerPriorityTasks: policy.StopLowerPriorityTasks,
+	}
+}
+
+func toTaskOverridesModel(overrides types.TaskOverrides) *models.TaskOverrides {
+	if overrides.IsEmpty() {
+		return nil
+	}
+	containerModels := make([]*models.ContainerOverride, 0, len(overrides.ContainerOverrides))
+	for _, container := range overrides.ContainerOverrides {
+		containerModels = append(containerModels, &models.ContainerOverride{
+			Name:        aws.String(container.Name),
+			Command:     container.Command,
+			Environment: container.Environment,
+		})
+	}
+	return &models.TaskOverrides{
+		ContainerOverrides: containerModels,
+		TaskRoleArn:        overrides.TaskRoleARN,
+		ExecutionRoleArn:   overrides.ExecutionRoleARN,
+		CPU:                overrides.CPU,
+		Memory:             overrides.Memory,
+		Tags:               overrides.Tags,
+	}
+}
+
+func toTaskOverrides(overrides *models.TaskOverrides) types.TaskOverrides {
+	if overrides == nil {
+		return types.TaskOverrides{}
+	}
+	containers := make([]types.ContainerOverride, 0, len(overrides.ContainerOverrides))
+	for _, container := range overrides.ContainerOverrides {
+		if container == nil {
+			continue
+		}
+		containers = append(containers, types.ContainerOverride{
+			Name:        aws.StringValue(container.Name),
+			Command:     container.Command,
+			Environment: container.Environment,
+		})
+	}
+	return types.TaskOverrides{
+		ContainerOverrides: containers,
+		TaskRoleARN:        overrides.TaskRoleArn,
+		ExecutionRoleARN:   overrides.ExecutionRoleArn,
+		CPU:                overrides.CPU,
+		Memory:             overrides.Memory,
+		Tags:               overrides.Tags,
+	}
+}
+
+func toMaintenanceWindowModels(windows []types.MaintenanceWindow) []*models.MaintenanceWindow {
+	windowModels := make([]*models.MaintenanceWindow, 0, len(windows))
+	for _, w := range windows {
+		windowModels = append(windowModels, &models.MaintenanceWindow{
+			Schedule:        aws.String(w.Schedule),
+			DurationSeconds: aws.Int64(int64(w.Duration / time.Second)),
+		})
+	}
+	return windowModels
+}
+
+// toMaintenanceWindows never returns nil, so that an update with no windows removes them
+func toMaintenanceWindows(windowModels []*models.MaintenanceWindow) []types.MaintenanceWindow {
+	windows := make([]types.MaintenanceWindow, 0, len(windowModels))
+	for _, w := range windowModels {
+		if w == nil {
+			continue
+		}
+		windows = append(windows, types.MaintenanceWindow{
+			Schedule: aws.StringValue(w.Schedule),
+			Duration: time.Duration(aws.Int64Value(w.DurationSeconds)) * time.Second,
+		})
+	}
+	return windows
+}
+
+func toPlacementConstraintsModel(constraints types.PlacementConstraints) *models.PlacementConstraints {
+	if constraints.IsEmpty() {
+		return nil
 	}
+	return &models.PlacementConstraints{
+		Expressions:       constraints.Expressions,
+		AvailabilityZones: constraints.AvailabilityZones,
+		IncludeInstances:  constraints.IncludeInstances,
+		ExcludeInstances:  constraints.ExcludeInstances,
+	}
+}
+
+func toPlacementConstraints(constraints *models.PlacementConstraints) types.PlacementConstraints {
+	if constraints == nil {
+		return types.PlacementConstraints{}
+	}
+	return types.PlacementConstraints{
+		Expressions:       constraints.Expressions,
+		AvailabilityZones: constraints.AvailabilityZones,
+		IncludeInstances:  constraints.IncludeInstances,
+		ExcludeInstances:  constraints.ExcludeInstances,
+	}
+}
+
+// toEnvironmentUpdate returns the settings set in the request. If replace is set, the settings
+// that are not set are reset to their defaults.
+func toEnvironmentUpdate(req models.UpdateEnvironmentRequest, replace bool) types.EnvironmentUpdate {
+	update := types.EnvironmentUpdate{}
+	if req.TaskDefinition != "" {
+		update.TaskDefinition = &req.TaskDefinition
+	}
+
+	instanceGroup := req.InstanceGroup
+	if instanceGroup == nil {
+		instanceGroup = &models.InstanceGroup{}
+	}
+	if instanceGroup.Cluster != "" {
+		update.Cluster = &instanceGroup.Cluster
+	}
+	if instanceGroup.ClusterSelector != nil {
+		selector := toClusterSelector(instanceGroup.ClusterSelector)
+		update.ClusterSelector = &selector
+	}
+	if instanceGroup.PlacementConstraints != nil || replace {
+		constraints := toPlacementConstraints(instanceGroup.PlacementConstraints)
+		update.PlacementConstraints = &constraints
+	}
+
+	if req.RolloutStrategy != nil || replace {
+		strategy := toRolloutStrategy(req.RolloutStrategy)
+		update.RolloutStrategy = &strategy
+	}
+	if req.SchedulingStrategy != nil || replace {
+		scheduling := toSchedulingStrategy(req.SchedulingStrategy)
+		update.SchedulingStrategy = &scheduling
+	}
+	if req.RollbackPolicy != nil || replace {
+		policy := toRollbackPolicy(req.RollbackPolicy)
+		update.RollbackPolicy = &policy
+	}
+	if req.RestartPolicy != nil || replace {
+		restart := toRestartPolicy(req.RestartPolicy)
+		update.RestartPolicy = &restart
+	}
+	if req.CapacityPolicy != nil || replace {
+		capacity := toCapacityPolicy(req.CapacityPolicy)
+		update.CapacityPolicy = &capacity
+	}
+	if req.TaskOverrides != nil || replace {
+		overrides := toTaskOverrides(req.TaskOverrides)
+		update.TaskOverrides = &overrides
+	}
+	if req.MaintenanceWindows != nil || replace {
+		update.MaintenanceWindows = toMaintenanceWindows(req.MaintenanceWindows)
+	}
+	if req.DrainingPolicy != "" || replace {
+		policy := types.DrainingPolicy(req.DrainingPolicy)
+		update.DrainingPolicy = &policy
+	}
+
+	return update
 }
 
 func toDeploymentModel(envName *string, depType types.Deployment) *models.Deployment {
+	clusters := []*models.ClusterDeployment{}
+	for _, cluster := range sortedClusters(depType.Clusters) {
+		clusters = append(clusters, toClusterDeploymentModel(cluster, depType.Clusters[cluster]))
+	}
+
+	return &models.Deployment{
+		EnvironmentName:      envName,
+		ID:                   &depType.ID,
+		Status:               aws.String(depType.Status.String()),
+		TaskDefinition:       aws.String(depType.TaskDefinition),
+		FailedInstances:      toFailedInstanceARNs(depType.FailedInstances),
+		InsufficientCapacity: toInsufficientCapacityModels(depType.InsufficientCapacity),
+		Batches:              toDeploymentBatchModels(depType.Batches),
+		RolloutCompleted:     depType.RolloutCompleted,
+		Clusters:             clusters,
+		RollbackReason:       depType.RollbackReason,
+		RolledBackBy:         depType.RolledBackBy,
+		RollbackOf:           depType.RollbackOf,
+		NotBefore:            toDateTime(depType.NotBefore),
+	}
+}
+
+func toClusterDeploymentModel(cluster string, clusterType types.ClusterDeployment) *models.ClusterDeployment {
+	return &models.ClusterDeployment{
+		Cluster:          aws.String(cluster),
+		Status:           aws.String(clusterType.Status.String()),
+		DesiredTaskCount: int64(clusterType.DesiredTaskCount),
+		FailedInstances:  toFailedInstanceARNs(clusterType.FailedInstances),
+		Batches:          toDeploymentBatchModels(clusterType.Batches),
+		RolloutCompleted: clusterType.RolloutCompleted,
+	}
+}
+
+// sortedClusters returns the clusters of the deployment in order, so that they are listed consistently
+func sortedClusters(clusters map[string]types.ClusterDeployment) []string {
+	sorted := make([]string, 0, len(clusters))
+	for cluster := range clusters {
+		sorted = append(sorted, cluster)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func toFailedInstanceARNs(failures []*ecs.Failure) []string {
 	instanceArns := []string{}
-	for _, failure := range depType.FailedInstances {
+	for _, failure := range failures {
 		instanceArns = append(instanceArns, aws.StringValue(failure.Arn))
 	}
+	return instanceArns
+}
 
-	return &models.Deployment{
-		EnvironmentName: envName,
-		ID:              &depType.ID,
-		Status:          aws.String(toDeploymentStatus(depType.Status)),
-		TaskDefinition:  aws.String(depType.TaskDefinition),
-		FailedInstances: instanceArns,
+func toInsufficientCapacityModels(failures []*ecs.Failure) []*models.InsufficientCapacity {
+	insufficient := []*models.InsufficientCapacity{}
+	for _, failure := range failures {
+		insufficient = append(insufficient, &models.InsufficientCapacity{
+			InstanceARN: failure.Arn,
+			Reason:      failure.Reason,
+		})
+	}
+	return insufficient
+}
+
+func toDeploymentBatchModels(batchTypes []types.DeploymentBatch) []*models.DeploymentBatch {
+	batches := []*models.DeploymentBatch{}
+	for _, batch := range batchTypes {
+		batches = append(batches, toDeploymentBatchModel(batch))
 	}
+	return batches
 }
 
-func toDeploymentsModel(envName *string, depTypes []types.Deployment) *models.Deployments {
+func toDeploymentBatchModel(batch types.DeploymentBatch) *models.DeploymentBatch {
+	return &models.DeploymentBatch{
+		Instances:    batch.Instances,
+		Status:       aws.String(toDeploymentBatchStatus(batch.Status)),
+		StartTime:    strfmt.DateTime(batch.StartTime),
+		HealthySince: toDateTime(batch.HealthySince),
+		EndTime:      toDateTime(batch.EndTime),
+	}
+}
+
+func toDeploymentBatchStatus(statusType types.DeploymentBatchStatus) string {
+	switch statusType {
+	case types.BatchInProgress:
+		return models.DeploymentBatchStatusRunning
+	case types.BatchSoaking:
+		return models.DeploymentBatchStatusSoaking
+	case types.BatchCompleted:
+		return models.DeploymentBatchStatusCompleted
+	default:
+		return "unknown"
+	}
+}
+
+// toDateTime converts t, leaving it unset in the model if it is zero
+func toDateTime(t time.Time) strfmt.DateTime {
+	if t.IsZero() {
+		return strfmt.DateTime{}
+	}
+	return strfmt.DateTime(t)
+}
+
+func toDeploymentsModel(envName *string, depTypes []types.Deployment, nextToken string) *models.Deployments {
 	depModels := []*models.Deployment{}
 	for _, depType := range depTypes {
 		depModel := toDeploymentModel(envName, depType)
 		depModels = append(depModels, depModel)
 	}
 	return &models.Deployments{
-		Items: depModels,
+		Items:     depModels,
+		NextToken: nextToken,
+	}
+}
+
+func toDeploymentInstancesModel(instanceTypes []types.DeploymentInstance) *models.DeploymentInstances {
+	instanceModels := []*models.DeploymentInstance{}
+	for _, instanceType := range instanceTypes {
+		taskModels := []*models.DeploymentTask{}
+		for _, taskType := range instanceType.Tasks {
+			taskModels = append(taskModels, &models.DeploymentTask{
+				TaskARN:       aws.String(taskType.TaskARN),
+				LastStatus:    taskType.LastStatus,
+				StartedAt:     toDateTime(taskType.StartedAt),
+				StoppedAt:     toDateTime(taskType.StoppedAt),
+				StoppedReason: taskType.StoppedReason,
+			})
+		}
+		instanceModels = append(instanceModels, &models.DeploymentInstance{
+			InstanceARN:   aws.String(instanceType.InstanceARN),
+			Cluster:       instanceType.Cluster,
+			Tasks:         taskModels,
+			FailureReason: instanceType.FailureReason,
+		})
+	}
+	return &models.DeploymentInstances{
+		Items: instanceModels,
+	}
+}
+
+func toWebhookEventTypes(events []string) []types.WebhookEventType {
+	var eventTypes []types.WebhookEventType
+	for _, event := range events {
+		eventTypes = append(eventTypes, types.WebhookEventType(event))
+	}
+	return eventTypes
+}
+
+// toWebhookModel leaves out the secret of the webhook
+func toWebhookModel(hook types.Webhook) *models.Webhook {
+	events := []string{}
+	for _, event := range hook.Events {
+		events = append(events, string(event))
+	}
+	return &models.Webhook{
+		ID:              aws.String(hook.ID),
+		EnvironmentName: aws.String(hook.EnvironmentName),
+		URL:             aws.String(hook.URL),
+		Events:          events,
+		CreatedAt:       toDateTime(hook.CreatedAt),
+	}
+}
+
+func toWebhooksModel(hooks []types.Webhook) *models.Webhooks {
+	hookModels := []*models.Webhook{}
+	for _, hook := range hooks {
+		hookModels = append(hookModels, toWebhookModel(hook))
+	}
+	return &models.Webhooks{
+		Items: hookModels,
+	}
+}
+
+func toWebhookDeliveriesModel(deliveries []types.WebhookDelivery) *models.WebhookDeliveries {
+	deliveryModels := []*models.WebhookDelivery{}
+	for _, delivery := range deliveries {
+		deliveryModels = append(deliveryModels, &models.WebhookDelivery{
+			ID:             aws.String(delivery.ID),
+			WebhookID:      aws.String(delivery.WebhookID),
+			Event:          aws.String(string(delivery.Event)),
+			DeploymentID:   delivery.DeploymentID,
+			Status:         aws.String(toWebhookDeliveryStatus(delivery.Status)),
+			Attempts:       aws.Int64(int64(delivery.Attempts)),
+			ResponseStatus: int64(delivery.ResponseStatus),
+			Error:          delivery.Error,
+			CreatedAt:      toDateTime(delivery.CreatedAt),
+			LastAttemptAt:  toDateTime(delivery.LastAttemptAt),
+		})
+	}
+	return &models.WebhookDeliveries{
+		Items: deliveryModels,
 	}
 }
 
-func toDeploymentStatus(statusType types.DeploymentStatus) string {
-	switch {
-	case types.DeploymentPending == statusType:
-		return "pending"
-	case types.DeploymentInProgress == statusType:
-		return "running"
-	case types.DeploymentCompleted == statusType:
-		return "completed"
+func toWebhookDeliveryStatus(statusType types.WebhookDeliveryStatus) string {
+	switch statusType {
+	case types.WebhookDeliveryPending:
+		return models.WebhookDeliveryStatusPending
+	case types.WebhookDeliverySucceeded:
+		return models.WebhookDeliveryStatusSucceeded
+	case types.WebhookDeliveryFailed:
+		return models.WebhookDeliveryStatusFailed
 	default:
 		return "unknown"
 	}
 }
+
+func toDeploymentPlanModel(planType types.DeploymentPlan) *models.DeploymentPlan {
+	clusterModels := []*models.ClusterPlan{}
+	for _, clusterType := range planType.Clusters {
+		clusterModels = append(clusterModels, toClusterPlanModel(clusterType))
+	}
+	return &models.DeploymentPlan{
+		TaskDefinition: aws.String(planType.TaskDefinition),
+		Clusters:       clusterModels,
+	}
+}
+
+func toClusterPlanModel(clusterType types.ClusterPlan) *models.ClusterPlan {
+	batches := [][]string{}
+	for _, batch := range clusterType.Batches {
+		batches = append(batches, append([]string{}, batch...))
+	}
+	stoppedTasks := []*models.PlannedTask{}
+	for _, taskType := range clusterType.StoppedTasks {
+		stoppedTasks = append(stoppedTasks, &models.PlannedTask{
+			TaskARN:      aws.String(taskType.TaskARN),
+			InstanceARN:  taskType.InstanceARN,
+			Environment:  taskType.Environment,
+			DeploymentID: taskType.DeploymentID,
+		})
+	}
+	skippedInstances := []*models.SkippedInstance{}
+	for _, instanceType := range clusterType.SkippedInstances {
+		skippedInstances = append(skippedInstances, &models.SkippedInstance{
+			InstanceARN: aws.String(instanceType.InstanceARN),
+			Reason:      aws.String(instanceType.Reason),
+		})
+	}
+	return &models.ClusterPlan{
+		Cluster:          clusterType.Cluster,
+		NewInstances:     append([]string{}, clusterType.NewInstances...),
+		Batches:          batches,
+		StoppedTasks:     stoppedTasks,
+		SkippedInstances: skippedInstances,
+	}
+}
@@ -45,6 +45,11 @@ func handleBackendError(w http.ResponseWriter, err error) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	_, ok = errors.Cause(err).(types.ConflictError)
+	if ok {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 
 	writeInternalServerError(w, err)
 }
@@ -0,0 +1,54 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auth
+
+import (
+	"time"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/json"
+	log "github.com/cihub/seelog"
+)
+
+// AuditEntry records a single mutating call against the API
+type AuditEntry struct {
+	Time        time.Time `json:"time"`
+	Principal   string    `json:"principal"`
+	Method      string    `json:"authMethod"`
+	Action      Action    `json:"action"`
+	Environment string    `json:"environment"`
+	StatusCode  int       `json:"statusCode"`
+	Allowed     bool      `json:"allowed"`
+	RemoteAddr  string    `json:"remoteAddr"`
+}
+
+// Auditor records audit entries
+type Auditor interface {
+	Record(entry AuditEntry)
+}
+
+type logAuditor struct{}
+
+// NewLogAuditor writes audit entries as JSON lines to the scheduler log
+func NewLogAuditor() Auditor {
+	return logAuditor{}
+}
+
+func (a logAuditor) Record(entry AuditEntry) {
+	line, err := json.MarshalJSON(entry)
+	if err != nil {
+		log.Errorf("Could not marshal audit entry %+v: %v", entry, err)
+		return
+	}
+	log.Infof("[audit] %s", line)
+}
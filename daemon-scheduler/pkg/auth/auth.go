@@ -0,0 +1,135 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Action identifies an operation exposed by the scheduler API
+type Action string
+
+const (
+	ActionCreateEnvironment       Action = "CreateEnvironment"
+	ActionGetEnvironment          Action = "GetEnvironment"
+	ActionListEnvironments        Action = "ListEnvironments"
+	ActionUpdateEnvironment       Action = "UpdateEnvironment"
+	ActionDeleteEnvironment       Action = "DeleteEnvironment"
+	ActionCreateDeployment        Action = "CreateDeployment"
+	ActionGetDeployment           Action = "GetDeployment"
+	ActionListDeployments         Action = "ListDeployments"
+	ActionPauseDeployment         Action = "PauseDeployment"
+	ActionResumeDeployment        Action = "ResumeDeployment"
+	ActionCancelDeployment        Action = "CancelDeployment"
+	ActionListDeploymentInstances Action = "ListDeploymentInstances"
+	ActionPlanDeployment          Action = "PlanDeployment"
+	ActionCreateWebhook           Action = "CreateWebhook"
+	ActionGetWebhook              Action = "GetWebhook"
+	ActionListWebhooks            Action = "ListWebhooks"
+	ActionDeleteWebhook           Action = "DeleteWebhook"
+	ActionListWebhookDeliveries   Action = "ListWebhookDeliveries"
+	// ActionGetMetrics reads the expvar variables of the scheduler, which are not scoped to an
+	// environment, so it is only allowed by rules matching every environment
+	ActionGetMetrics Action = "GetMetrics"
+
+	// ActionAll matches every action in a policy rule
+	ActionAll Action = "*"
+)
+
+var (
+	// ErrNoCredentials is returned by an authenticator when the request does not carry
+	// the credentials it understands
+	ErrNoCredentials = errors.New("No credentials provided")
+	// ErrInvalidCredentials is returned by an authenticator when the request carries
+	// credentials it understands but they do not identify a known principal
+	ErrInvalidCredentials = errors.New("Invalid credentials")
+
+	// mutatingActions are the actions recorded in the audit log
+	mutatingActions = map[Action]bool{
+		ActionCreateEnvironment: true,
+		ActionUpdateEnvironment: true,
+		ActionDeleteEnvironment: true,
+		ActionCreateDeployment:  true,
+		ActionPauseDeployment:   true,
+		ActionResumeDeployment:  true,
+		ActionCancelDeployment:  true,
+		ActionCreateWebhook:     true,
+		ActionDeleteWebhook:     true,
+	}
+
+	// listingActions return several environments rather than targeting one, and only return the
+	// environments the principal is allowed the action on
+	listingActions = map[Action]bool{
+		ActionListEnvironments: true,
+	}
+)
+
+// IsMutating returns true if the action changes the state of the scheduler
+func (a Action) IsMutating() bool {
+	return mutatingActions[a]
+}
+
+// IsListing returns true if the action returns several environments, which are filtered down to
+// the ones the principal is allowed the action on
+func (a Action) IsListing() bool {
+	return listingActions[a]
+}
+
+// Principal is the authenticated caller of an API
+type Principal struct {
+	Name string
+	// Method is the authentication method the principal used, for auditing
+	Method string
+}
+
+// Authenticator identifies the principal making a request
+type Authenticator interface {
+	// Authenticate returns the principal for the request. It returns ErrNoCredentials
+	// if the request does not carry credentials for this authenticator.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type principalKey struct{}
+
+type environmentFilterKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	if !ok {
+		return nil, false
+	}
+	return &principal, true
+}
+
+// WithEnvironmentFilter returns a copy of ctx carrying the filter of the environments a listing
+// action may return
+func WithEnvironmentFilter(ctx context.Context, allowed func(environment string) bool) context.Context {
+	return context.WithValue(ctx, environmentFilterKey{}, allowed)
+}
+
+// EnvironmentAllowed returns true if a listing action may return the environment. Every
+// environment is allowed when the request is not authenticated.
+func EnvironmentAllowed(ctx context.Context, environment string) bool {
+	allowed, ok := ctx.Value(environmentFilterKey{}).(func(string) bool)
+	return !ok || allowed(environment)
+}
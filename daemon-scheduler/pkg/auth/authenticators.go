@@ -0,0 +1,252 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	APIKeyHeader        = "X-Api-Key"
+	AuthorizationHeader = "Authorization"
+	DateHeader          = "X-Blox-Date"
+
+	bearerScheme = "Bearer"
+	// HMACScheme is the authorization scheme of signed requests, in the form
+	// "BLOX-HMAC-SHA256 Credential=<key id>, Signature=<hex signature>"
+	HMACScheme = "BLOX-HMAC-SHA256"
+
+	credentialParam = "Credential"
+	signatureParam  = "Signature"
+
+	methodAPIKey = "api-key"
+	methodBearer = "bearer"
+	methodHMAC   = "hmac"
+
+	// maxSignatureSkew is how far the signed date can be from the server clock
+	maxSignatureSkew = 5 * time.Minute
+)
+
+type apiKeyAuthenticator struct {
+	// key -> principal name
+	keys map[string]string
+}
+
+// NewAPIKeyAuthenticator authenticates requests carrying a static key in the X-Api-Key header
+func NewAPIKeyAuthenticator(keys map[string]string) Authenticator {
+	return apiKeyAuthenticator{
+		keys: keys,
+	}
+}
+
+func (a apiKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	name, ok := lookupSecret(a.keys, key)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{Name: name, Method: methodAPIKey}, nil
+}
+
+type bearerAuthenticator struct {
+	// token -> principal name
+	tokens map[string]string
+}
+
+// NewBearerAuthenticator authenticates requests carrying a static bearer token in the Authorization header
+func NewBearerAuthenticator(tokens map[string]string) Authenticator {
+	return bearerAuthenticator{
+		tokens: tokens,
+	}
+}
+
+func (a bearerAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, value := splitAuthorization(r)
+	if scheme != bearerScheme || value == "" {
+		return nil, ErrNoCredentials
+	}
+
+	name, ok := lookupSecret(a.tokens, value)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{Name: name, Method: methodBearer}, nil
+}
+
+// HMACKey is a shared secret used to sign requests
+type HMACKey struct {
+	Principal string
+	Secret    string
+}
+
+type hmacAuthenticator struct {
+	// key id -> key
+	keys map[string]HMACKey
+	now  func() time.Time
+}
+
+// NewHMACAuthenticator authenticates requests signed with a shared secret. The signature is the hex encoded
+// HMAC-SHA256 of the string returned by StringToSign.
+func NewHMACAuthenticator(keys map[string]HMACKey) Authenticator {
+	return hmacAuthenticator{
+		keys: keys,
+		now:  time.Now,
+	}
+}
+
+func (a hmacAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, value := splitAuthorization(r)
+	if scheme != HMACScheme {
+		return nil, ErrNoCredentials
+	}
+
+	params := parseAuthorizationParams(value)
+	keyID := params[credentialParam]
+	signature := params[signatureParam]
+	if keyID == "" || signature == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	key, ok := a.keys[keyID]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	date, err := time.Parse(time.RFC3339, r.Header.Get(DateHeader))
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidCredentials, "Missing or malformed %s header", DateHeader)
+	}
+
+	skew := a.now().Sub(date)
+	if skew > maxSignatureSkew || skew < -maxSignatureSkew {
+		return nil, errors.Wrapf(ErrInvalidCredentials, "Request date %v is outside of the allowed skew", date)
+	}
+
+	stringToSign, err := StringToSign(r)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := Sign(key.Secret, stringToSign)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Principal{Name: key.Principal, Method: methodHMAC}, nil
+}
+
+// StringToSign builds the canonical representation of a request that is signed by HMAC clients:
+// method, path, raw query, the X-Blox-Date header and the hex encoded SHA256 of the body, separated by newlines.
+// The request body is restored so that it can be read again by handlers.
+func StringToSign(r *http.Request) (string, error) {
+	body := []byte{}
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		if err != nil {
+			return "", errors.Wrap(err, "Could not read request body")
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		r.Method,
+		r.URL.Path,
+		r.URL.RawQuery,
+		r.Header.Get(DateHeader),
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n"), nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of stringToSign using secret
+func Sign(secret string, stringToSign string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(stringToSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type chainAuthenticator struct {
+	authenticators []Authenticator
+}
+
+// NewChainAuthenticator tries each authenticator in order and returns the first principal found.
+// A request with invalid credentials for any authenticator is rejected.
+func NewChainAuthenticator(authenticators ...Authenticator) Authenticator {
+	return chainAuthenticator{
+		authenticators: authenticators,
+	}
+}
+
+func (a chainAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	for _, authenticator := range a.authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if err == ErrNoCredentials {
+			continue
+		}
+		return principal, err
+	}
+	return nil, ErrNoCredentials
+}
+
+func splitAuthorization(r *http.Request) (string, string) {
+	header := strings.TrimSpace(r.Header.Get(AuthorizationHeader))
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 {
+		return header, ""
+	}
+	return parts[0], strings.TrimSpace(parts[1])
+}
+
+func parseAuthorizationParams(value string) map[string]string {
+	params := make(map[string]string)
+	for _, param := range strings.Split(value, ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		params[kv[0]] = kv[1]
+	}
+	return params
+}
+
+// lookupSecret compares the provided secret against every known one in constant time
+func lookupSecret(secrets map[string]string, provided string) (string, bool) {
+	name := ""
+	found := false
+	for secret, principal := range secrets {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(provided)) == 1 {
+			name = principal
+			found = true
+		}
+	}
+	return name, found
+}
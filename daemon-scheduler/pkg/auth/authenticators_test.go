@@ -0,0 +1,138 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	principalName = "deployer"
+	secret        = "s3cr3t"
+	keyID         = "key1"
+	requestURL    = "http://localhost:2000/v1/environments/env/deployments?deploymentToken=abc"
+)
+
+func newRequest(t *testing.T, body string) *http.Request {
+	r, err := http.NewRequest("POST", requestURL, bytes.NewBufferString(body))
+	assert.Nil(t, err, "Unexpected error creating request")
+	return r
+}
+
+func signRequest(t *testing.T, r *http.Request, date time.Time, secret string) {
+	r.Header.Set(DateHeader, date.Format(time.RFC3339))
+	stringToSign, err := StringToSign(r)
+	assert.Nil(t, err, "Unexpected error building the string to sign")
+	r.Header.Set(AuthorizationHeader, HMACScheme+" Credential="+keyID+", Signature="+Sign(secret, stringToSign))
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	authenticator := NewAPIKeyAuthenticator(map[string]string{secret: principalName})
+
+	r := newRequest(t, "")
+	_, err := authenticator.Authenticate(r)
+	assert.Equal(t, ErrNoCredentials, err, "Expected no credentials without an api key")
+
+	r.Header.Set(APIKeyHeader, "wrong")
+	_, err = authenticator.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err, "Expected invalid credentials with an unknown api key")
+
+	r.Header.Set(APIKeyHeader, secret)
+	principal, err := authenticator.Authenticate(r)
+	assert.Nil(t, err, "Unexpected error authenticating a valid api key")
+	assert.Equal(t, principalName, principal.Name, "Unexpected principal")
+}
+
+func TestBearerAuthenticator(t *testing.T) {
+	authenticator := NewBearerAuthenticator(map[string]string{secret: principalName})
+
+	r := newRequest(t, "")
+	r.Header.Set(AuthorizationHeader, "Basic abc")
+	_, err := authenticator.Authenticate(r)
+	assert.Equal(t, ErrNoCredentials, err, "Expected no credentials with another scheme")
+
+	r.Header.Set(AuthorizationHeader, "Bearer wrong")
+	_, err = authenticator.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err, "Expected invalid credentials with an unknown token")
+
+	r.Header.Set(AuthorizationHeader, "Bearer "+secret)
+	principal, err := authenticator.Authenticate(r)
+	assert.Nil(t, err, "Unexpected error authenticating a valid token")
+	assert.Equal(t, principalName, principal.Name, "Unexpected principal")
+}
+
+func TestHMACAuthenticator(t *testing.T) {
+	authenticator := NewHMACAuthenticator(map[string]HMACKey{keyID: {Principal: principalName, Secret: secret}})
+	body := `{"name":"env"}`
+
+	r := newRequest(t, body)
+	signRequest(t, r, time.Now(), secret)
+	principal, err := authenticator.Authenticate(r)
+	assert.Nil(t, err, "Unexpected error authenticating a signed request")
+	assert.Equal(t, principalName, principal.Name, "Unexpected principal")
+
+	b, err := ioutil.ReadAll(r.Body)
+	assert.Nil(t, err, "Unexpected error reading body")
+	assert.Equal(t, body, string(b), "Body should be readable after authentication")
+}
+
+func TestHMACAuthenticatorWrongSecret(t *testing.T) {
+	authenticator := NewHMACAuthenticator(map[string]HMACKey{keyID: {Principal: principalName, Secret: secret}})
+
+	r := newRequest(t, "")
+	signRequest(t, r, time.Now(), "wrong")
+	_, err := authenticator.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err, "Expected invalid credentials with a wrong signature")
+}
+
+func TestHMACAuthenticatorTamperedBody(t *testing.T) {
+	authenticator := NewHMACAuthenticator(map[string]HMACKey{keyID: {Principal: principalName, Secret: secret}})
+
+	r := newRequest(t, "original")
+	signRequest(t, r, time.Now(), secret)
+	r.Body = ioutil.NopCloser(bytes.NewBufferString("tampered"))
+	_, err := authenticator.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err, "Expected invalid credentials with a tampered body")
+}
+
+func TestHMACAuthenticatorExpiredDate(t *testing.T) {
+	authenticator := NewHMACAuthenticator(map[string]HMACKey{keyID: {Principal: principalName, Secret: secret}})
+
+	r := newRequest(t, "")
+	signRequest(t, r, time.Now().Add(-time.Hour), secret)
+	_, err := authenticator.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, errors.Cause(err), "Expected invalid credentials with an old date")
+}
+
+func TestChainAuthenticator(t *testing.T) {
+	authenticator := NewChainAuthenticator(
+		NewAPIKeyAuthenticator(map[string]string{"key": "ci"}),
+		NewBearerAuthenticator(map[string]string{"token": "ops"}))
+
+	r := newRequest(t, "")
+	_, err := authenticator.Authenticate(r)
+	assert.Equal(t, ErrNoCredentials, err, "Expected no credentials")
+
+	r.Header.Set(AuthorizationHeader, "Bearer token")
+	principal, err := authenticator.Authenticate(r)
+	assert.Nil(t, err, "Unexpected error authenticating with the second authenticator")
+	assert.Equal(t, "ops", principal.Name, "Unexpected principal")
+}
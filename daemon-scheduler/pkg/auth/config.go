@@ -0,0 +1,117 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auth
+
+import (
+	"io/ioutil"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/json"
+	"github.com/pkg/errors"
+)
+
+// Config is the content of the auth configuration file
+type Config struct {
+	APIKeys      []Credential    `json:"apiKeys"`
+	BearerTokens []Credential    `json:"bearerTokens"`
+	HMACKeys     []HMACKeyConfig `json:"hmacKeys"`
+	Policy       Policy          `json:"policy"`
+}
+
+// Credential maps a static secret to a principal
+type Credential struct {
+	Principal string `json:"principal"`
+	Secret    string `json:"secret"`
+}
+
+// HMACKeyConfig maps a key id and its shared secret to a principal
+type HMACKeyConfig struct {
+	Principal string `json:"principal"`
+	KeyID     string `json:"keyId"`
+	Secret    string `json:"secret"`
+}
+
+// LoadConfig reads and validates the auth configuration file at path
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not read auth config file %s", path)
+	}
+
+	var config Config
+	err = json.UnmarshalJSON(string(b), &config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not parse auth config file %s", path)
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Validate checks that credentials are complete and unique and that the policy is well formed
+func (c Config) Validate() error {
+	secrets := make(map[string]bool)
+	for _, credential := range append(append([]Credential{}, c.APIKeys...), c.BearerTokens...) {
+		if credential.Principal == "" || credential.Secret == "" {
+			return errors.New("Every api key and bearer token must have a principal and a secret")
+		}
+		if secrets[credential.Secret] {
+			return errors.Errorf("Secret for principal %s is already used by another credential", credential.Principal)
+		}
+		secrets[credential.Secret] = true
+	}
+
+	keyIDs := make(map[string]bool)
+	for _, key := range c.HMACKeys {
+		if key.Principal == "" || key.KeyID == "" || key.Secret == "" {
+			return errors.New("Every hmac key must have a principal, a key id and a secret")
+		}
+		if keyIDs[key.KeyID] {
+			return errors.Errorf("Hmac key id %s is defined more than once", key.KeyID)
+		}
+		keyIDs[key.KeyID] = true
+	}
+
+	return c.Policy.Validate()
+}
+
+// Authenticator returns an authenticator accepting every credential in the config
+func (c Config) Authenticator() Authenticator {
+	apiKeys := make(map[string]string)
+	for _, credential := range c.APIKeys {
+		apiKeys[credential.Secret] = credential.Principal
+	}
+
+	tokens := make(map[string]string)
+	for _, credential := range c.BearerTokens {
+		tokens[credential.Secret] = credential.Principal
+	}
+
+	hmacKeys := make(map[string]HMACKey)
+	for _, key := range c.HMACKeys {
+		hmacKeys[key.KeyID] = HMACKey{
+			Principal: key.Principal,
+			Secret:    key.Secret,
+		}
+	}
+
+	return NewChainAuthenticator(
+		NewAPIKeyAuthenticator(apiKeys),
+		NewBearerAuthenticator(tokens),
+		NewHMACAuthenticator(hmacKeys),
+	)
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/urfave/negroni"
+)
+
+const (
+	authenticateHeader = "WWW-Authenticate"
+)
+
+// ActionResolver maps a request to the action it performs and the environment it targets
+type ActionResolver interface {
+	// Resolve returns the action and the environment name for the request. public is true for
+	// requests that do not need to be authenticated, such as health checks. ok is false if the
+	// request does not match any known action.
+	Resolve(r *http.Request) (action Action, environment string, public bool, ok bool)
+}
+
+type middleware struct {
+	authenticator Authenticator
+	policy        Policy
+	auditor       Auditor
+	resolver      ActionResolver
+}
+
+// NewMiddleware returns a negroni handler that authenticates every request, authorizes it against the policy and
+// records mutating calls with the auditor
+func NewMiddleware(authenticator Authenticator, policy Policy, auditor Auditor, resolver ActionResolver) negroni.Handler {
+	return middleware{
+		authenticator: authenticator,
+		policy:        policy,
+		auditor:       auditor,
+		resolver:      resolver,
+	}
+}
+
+func (m middleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	action, environment, public, ok := m.resolver.Resolve(r)
+	if public {
+		next(w, r)
+		return
+	}
+
+	principal, err := m.authenticator.Authenticate(r)
+	if err != nil {
+		log.Infof("Rejecting unauthenticated request %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		m.audit(r, action, environment, Principal{}, false, http.StatusUnauthorized)
+		w.Header().Set(authenticateHeader, fmt.Sprintf("%s, %s, %s", APIKeyHeader, bearerScheme, HMACScheme))
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !ok {
+		// unknown route, let the router respond
+		next(w, r.WithContext(WithPrincipal(r.Context(), *principal)))
+		return
+	}
+
+	if !m.authorize(*principal, action, environment) {
+		log.Infof("Principal %s is not allowed to %s on environment '%s'", principal.Name, action, environment)
+		m.audit(r, action, environment, *principal, false, http.StatusForbidden)
+		http.Error(w, fmt.Sprintf("%s is not allowed to %s on environment '%s'", principal.Name, action, environment),
+			http.StatusForbidden)
+		return
+	}
+
+	rw, isNegroni := w.(negroni.ResponseWriter)
+	if !isNegroni {
+		rw = negroni.NewResponseWriter(w)
+	}
+
+	ctx := WithPrincipal(r.Context(), *principal)
+	if action.IsListing() {
+		policy, caller := m.policy, *principal
+		ctx = WithEnvironmentFilter(ctx, func(environment string) bool {
+			return policy.Authorize(caller, action, environment)
+		})
+	}
+	next(rw, r.WithContext(ctx))
+
+	status := rw.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	m.audit(r, action, environment, *principal, true, status)
+}
+
+// authorize checks a listing action against every environment, as its results are filtered per environment
+func (m middleware) authorize(principal Principal, action Action, environment string) bool {
+	if action.IsListing() {
+		return m.policy.AuthorizeAny(principal, action)
+	}
+	return m.policy.Authorize(principal, action, environment)
+}
+
+func (m middleware) audit(r *http.Request, action Action, environment string,
+	principal Principal, allowed bool, status int) {
+
+	if !action.IsMutating() {
+		return
+	}
+
+	m.auditor.Record(AuditEntry{
+		Time:        time.Now().UTC(),
+		Principal:   principal.Name,
+		Method:      principal.Method,
+		Action:      action,
+		Environment: environment,
+		StatusCode:  status,
+		Allowed:     allowed,
+		RemoteAddr:  r.RemoteAddr,
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticResolver struct {
+	action      Action
+	environment string
+	public      bool
+}
+
+func (s staticResolver) Resolve(r *http.Request) (Action, string, bool, bool) {
+	return s.action, s.environment, s.public, true
+}
+
+type recordingAuditor struct {
+	entries []AuditEntry
+}
+
+func (a *recordingAuditor) Record(entry AuditEntry) {
+	a.entries = append(a.entries, entry)
+}
+
+func serve(resolver ActionResolver, auditor Auditor, r *http.Request) (*httptest.ResponseRecorder, *Principal) {
+	m := NewMiddleware(NewBearerAuthenticator(map[string]string{"token": "ci"}), testPolicy, auditor, resolver)
+	recorder := httptest.NewRecorder()
+	var principal *Principal
+	m.ServeHTTP(recorder, r, func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusAccepted)
+	})
+	return recorder, principal
+}
+
+func TestMiddlewarePublicRoute(t *testing.T) {
+	auditor := &recordingAuditor{}
+	recorder, _ := serve(staticResolver{public: true}, auditor, newRequest(t, ""))
+	assert.Equal(t, http.StatusAccepted, recorder.Code, "Expected public route to be served without credentials")
+}
+
+func TestMiddlewareUnauthenticated(t *testing.T) {
+	auditor := &recordingAuditor{}
+	recorder, _ := serve(staticResolver{action: ActionCreateDeployment, environment: "prod-logs"}, auditor, newRequest(t, ""))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "Expected unauthenticated request to be rejected")
+	assert.Len(t, auditor.entries, 1, "Expected the rejected mutating call to be audited")
+	assert.False(t, auditor.entries[0].Allowed, "Expected audit entry to record the rejection")
+}
+
+func TestMiddlewareForbidden(t *testing.T) {
+	auditor := &recordingAuditor{}
+	r := newRequest(t, "")
+	r.Header.Set(AuthorizationHeader, "Bearer token")
+	recorder, _ := serve(staticResolver{action: ActionCreateDeployment, environment: "test-logs"}, auditor, r)
+	assert.Equal(t, http.StatusForbidden, recorder.Code, "Expected unauthorized request to be rejected")
+	assert.Len(t, auditor.entries, 1, "Expected the rejected mutating call to be audited")
+	assert.Equal(t, http.StatusForbidden, auditor.entries[0].StatusCode, "Unexpected audited status")
+}
+
+func TestMiddlewareAllowed(t *testing.T) {
+	auditor := &recordingAuditor{}
+	r := newRequest(t, "")
+	r.Header.Set(AuthorizationHeader, "Bearer token")
+	recorder, principal := serve(staticResolver{action: ActionCreateDeployment, environment: "prod-logs"}, auditor, r)
+	assert.Equal(t, http.StatusAccepted, recorder.Code, "Expected authorized request to be served")
+	assert.Equal(t, "ci", principal.Name, "Expected principal to be available to handlers")
+	assert.Len(t, auditor.entries, 1, "Expected the mutating call to be audited")
+	assert.Equal(t, AuditEntry{
+		Time:        auditor.entries[0].Time,
+		Principal:   "ci",
+		Method:      methodBearer,
+		Action:      ActionCreateDeployment,
+		Environment: "prod-logs",
+		StatusCode:  http.StatusAccepted,
+		Allowed:     true,
+		RemoteAddr:  r.RemoteAddr,
+	}, auditor.entries[0], "Unexpected audit entry")
+}
+
+func TestMiddlewareReadsAreNotAudited(t *testing.T) {
+	auditor := &recordingAuditor{}
+	r := newRequest(t, "")
+	r.Header.Set(AuthorizationHeader, "Bearer token")
+	recorder, _ := serve(staticResolver{action: ActionGetEnvironment, environment: "env"}, auditor, r)
+	assert.Equal(t, http.StatusAccepted, recorder.Code, "Expected authorized read to be served")
+	assert.Empty(t, auditor.entries, "Expected reads not to be audited")
+}
+
+func TestMiddlewareListingFiltersEnvironments(t *testing.T) {
+	policy := Policy{Rules: []Rule{{
+		Principals:   []string{"ci"},
+		Actions:      []Action{ActionListEnvironments},
+		Environments: []string{"prod-*"},
+	}}}
+	m := NewMiddleware(NewBearerAuthenticator(map[string]string{"token": "ci"}), policy, &recordingAuditor{},
+		staticResolver{action: ActionListEnvironments})
+	r := newRequest(t, "")
+	r.Header.Set(AuthorizationHeader, "Bearer token")
+
+	var prod, test bool
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, r, func(w http.ResponseWriter, r *http.Request) {
+		prod = EnvironmentAllowed(r.Context(), "prod-logs")
+		test = EnvironmentAllowed(r.Context(), "test-logs")
+	})
+	assert.Equal(t, http.StatusOK, recorder.Code, "Expected a principal scoped to some environments to list them")
+	assert.True(t, prod, "Expected environments matching the policy to be listed")
+	assert.False(t, test, "Expected other environments to be filtered out")
+}
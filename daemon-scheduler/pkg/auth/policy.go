@@ -0,0 +1,105 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auth
+
+import (
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	anyPrincipal = "*"
+)
+
+// Rule allows a set of principals to perform a set of actions on the environments matching any of the patterns.
+// Patterns use path.Match syntax, e.g. "prod-*". An action or principal of "*" matches everything.
+type Rule struct {
+	Principals   []string `json:"principals"`
+	Actions      []Action `json:"actions"`
+	Environments []string `json:"environments"`
+}
+
+// Policy is a list of rules. A request is allowed if at least one rule allows it.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Validate checks that every environment pattern in the policy is well formed
+func (p Policy) Validate() error {
+	for i, rule := range p.Rules {
+		if len(rule.Principals) == 0 || len(rule.Actions) == 0 || len(rule.Environments) == 0 {
+			return errors.Errorf("Policy rule %d must have principals, actions and environments", i)
+		}
+		for _, pattern := range rule.Environments {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return errors.Wrapf(err, "Invalid environment pattern '%s' in policy rule %d", pattern, i)
+			}
+		}
+	}
+	return nil
+}
+
+// Authorize returns true if the principal can perform the action on the environment
+func (p Policy) Authorize(principal Principal, action Action, environment string) bool {
+	for _, rule := range p.Rules {
+		if rule.allows(principal, action, environment) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizeAny returns true if the principal can perform the action on at least one environment. Listing actions
+// are authorized this way, and their results are then filtered with Authorize.
+func (p Policy) AuthorizeAny(principal Principal, action Action) bool {
+	for _, rule := range p.Rules {
+		if rule.matchesPrincipal(principal) && rule.matchesAction(action) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) allows(principal Principal, action Action, environment string) bool {
+	return r.matchesPrincipal(principal) && r.matchesAction(action) && r.matchesEnvironment(environment)
+}
+
+func (r Rule) matchesPrincipal(principal Principal) bool {
+	for _, p := range r.Principals {
+		if p == anyPrincipal || p == principal.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesAction(action Action) bool {
+	for _, a := range r.Actions {
+		if a == ActionAll || a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesEnvironment(environment string) bool {
+	for _, pattern := range r.Environments {
+		matched, err := path.Match(pattern, environment)
+		if err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
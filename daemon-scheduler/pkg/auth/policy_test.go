@@ -0,0 +1,77 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testPolicy = Policy{
+		Rules: []Rule{
+			{
+				Principals:   []string{"ci"},
+				Actions:      []Action{ActionCreateDeployment},
+				Environments: []string{"prod-*"},
+			},
+			{
+				Principals:   []string{"admin"},
+				Actions:      []Action{ActionAll},
+				Environments: []string{"*"},
+			},
+			{
+				Principals:   []string{"*"},
+				Actions:      []Action{ActionGetEnvironment, ActionListEnvironments},
+				Environments: []string{"*"},
+			},
+		},
+	}
+)
+
+func TestPolicyAuthorizeMatchingPattern(t *testing.T) {
+	assert.True(t, testPolicy.Authorize(Principal{Name: "ci"}, ActionCreateDeployment, "prod-logs"),
+		"Expected ci to deploy to prod environments")
+	assert.False(t, testPolicy.Authorize(Principal{Name: "ci"}, ActionCreateDeployment, "test-logs"),
+		"Expected ci not to deploy to test environments")
+	assert.False(t, testPolicy.Authorize(Principal{Name: "ci"}, ActionDeleteEnvironment, "prod-logs"),
+		"Expected ci not to delete prod environments")
+}
+
+func TestPolicyAuthorizeWildcards(t *testing.T) {
+	assert.True(t, testPolicy.Authorize(Principal{Name: "admin"}, ActionDeleteEnvironment, "anything"),
+		"Expected admin to perform any action")
+	assert.True(t, testPolicy.Authorize(Principal{Name: "someone"}, ActionListEnvironments, ""),
+		"Expected anyone to list environments")
+	assert.False(t, testPolicy.Authorize(Principal{Name: "someone"}, ActionCreateEnvironment, "env"),
+		"Expected no default permission to create environments")
+}
+
+func TestPolicyAuthorizeAny(t *testing.T) {
+	assert.True(t, testPolicy.AuthorizeAny(Principal{Name: "ci"}, ActionCreateDeployment),
+		"Expected ci to deploy to some environments")
+	assert.False(t, testPolicy.AuthorizeAny(Principal{Name: "ci"}, ActionDeleteEnvironment),
+		"Expected ci not to delete any environment")
+}
+
+func TestPolicyValidate(t *testing.T) {
+	assert.Nil(t, testPolicy.Validate(), "Unexpected error validating a valid policy")
+
+	invalid := Policy{Rules: []Rule{{Principals: []string{"ci"}, Actions: []Action{ActionAll}, Environments: []string{"[a-"}}}}
+	assert.Error(t, invalid.Validate(), "Expected error validating a malformed pattern")
+
+	incomplete := Policy{Rules: []Rule{{Principals: []string{"ci"}}}}
+	assert.Error(t, incomplete.Validate(), "Expected error validating an incomplete rule")
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/blox/blox/daemon-scheduler/pkg/httpclient"
+	"github.com/blox/blox/shared/ecsclient"
 	"github.com/pkg/errors"
 )
 
@@ -56,11 +57,14 @@ func newAWSSession() (*session.Session, error) {
 	return sess, err
 }
 
-// NewECSClient returns an ECS client.
-func NewECSClient() (*ecs.ECS, error) {
+// NewECSClient returns an ECS client whose requests are rate limited, retried and circuit broken
+// by the throttle.
+func NewECSClient(throttle *ecsclient.Throttle) (*ecs.ECS, error) {
 	sess, err := newAWSSession()
 	if err != nil {
 		return nil, err
 	}
-	return ecs.New(sess), nil
+	client := ecs.New(sess)
+	throttle.Apply(client)
+	return client, nil
 }
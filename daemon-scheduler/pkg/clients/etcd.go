@@ -14,9 +14,12 @@
 package clients
 
 import (
+	"crypto/tls"
+	"os"
 	"time"
 
 	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/pkg/tlsutil"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 )
@@ -48,18 +51,80 @@ type EtcdInterface interface {
 
 var _ EtcdInterface = (*etcd.Client)(nil)
 
+// EtcdTxnInterface defines the etcd transactions used in the project for writes that are
+// conditional on the revision of a key
+type EtcdTxnInterface interface {
+	EtcdInterface
+
+	// Txn creates a transaction.
+	Txn(ctx context.Context) etcd.Txn
+}
+
+var _ EtcdTxnInterface = (*etcd.Client)(nil)
+
 const (
-	dialTimeout = 5 * time.Second
+	// DefaultDialTimeout is the timeout for establishing a connection to etcd
+	DefaultDialTimeout = 5 * time.Second
+	// DefaultRequestTimeout is the timeout for a single etcd request
+	DefaultRequestTimeout = 30 * time.Second
+
+	etcdPasswordEnvVarName = "ETCD_PASSWORD"
 )
 
+// EtcdConfig holds the settings used to connect to etcd
+type EtcdConfig struct {
+	Endpoints []string
+
+	// CAFile, CertFile and KeyFile enable TLS. CertFile and KeyFile are only
+	// needed when etcd requires client certificates.
+	CAFile   string
+	CertFile string
+	KeyFile  string
+
+	// Username and Password enable etcd authentication. The password is read
+	// from the ETCD_PASSWORD environment variable if it is not set.
+	Username string
+	Password string
+
+	DialTimeout    time.Duration
+	RequestTimeout time.Duration
+
+	// KeyPrefix namespaces every key written by the scheduler so that several
+	// deployments can share an etcd cluster.
+	KeyPrefix string
+}
+
 // NewEtcdClient initializes an etcd client
-func NewEtcdClient(endpoints []string) (*etcd.Client, error) {
-	if len(endpoints) == 0 {
+func NewEtcdClient(config EtcdConfig) (*etcd.Client, error) {
+	if len(config.Endpoints) == 0 {
 		return nil, errors.New("Etcd endpoints should not be empty")
 	}
+
+	dialTimeout := config.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = DefaultDialTimeout
+	}
+
+	tlsConfig, err := newEtcdTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	password := config.Password
+	if password == "" {
+		password = os.Getenv(etcdPasswordEnvVarName)
+	}
+
+	if config.Username == "" && password != "" {
+		return nil, errors.New("Etcd username must be set when a password is provided")
+	}
+
 	etcd, err := etcd.New(etcd.Config{
-		Endpoints:   endpoints,
+		Endpoints:   config.Endpoints,
 		DialTimeout: dialTimeout,
+		TLS:         tlsConfig,
+		Username:    config.Username,
+		Password:    password,
 	})
 
 	if err != nil {
@@ -68,3 +133,35 @@ func NewEtcdClient(endpoints []string) (*etcd.Client, error) {
 
 	return etcd, nil
 }
+
+func newEtcdTLSConfig(config EtcdConfig) (*tls.Config, error) {
+	if config.CAFile == "" && config.CertFile == "" && config.KeyFile == "" {
+		return nil, nil
+	}
+
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, errors.New("Both the etcd client certificate and key must be provided")
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.CAFile != "" {
+		certPool, err := tlsutil.NewCertPool([]string{config.CAFile})
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not load etcd CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	if config.CertFile != "" {
+		cert, err := tlsutil.NewCert(config.CertFile, config.KeyFile, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not load etcd client certificate %s", config.CertFile)
+		}
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clients
+
+import (
+	"strings"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+type namespacedEtcd struct {
+	etcd           EtcdInterface
+	prefix         string
+	requestTimeout time.Duration
+}
+
+// NewNamespacedEtcd wraps an etcd client so that every key is stored under prefix and every
+// request is bounded by requestTimeout. Keys are returned without the prefix.
+func NewNamespacedEtcd(etcdInterface EtcdInterface, prefix string, requestTimeout time.Duration) EtcdInterface {
+	return namespacedEtcd{
+		etcd:           etcdInterface,
+		prefix:         prefix,
+		requestTimeout: requestTimeout,
+	}
+}
+
+func (n namespacedEtcd) Close() error {
+	return n.etcd.Close()
+}
+
+func (n namespacedEtcd) Put(ctx context.Context, key, val string, opts ...etcd.OpOption) (*etcd.PutResponse, error) {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := n.etcd.Put(ctx, n.prefix+key, val, opts...)
+	if resp != nil {
+		n.stripKeyValue(resp.PrevKv)
+	}
+	return resp, err
+}
+
+func (n namespacedEtcd) Get(ctx context.Context, key string, opts ...etcd.OpOption) (*etcd.GetResponse, error) {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := n.etcd.Get(ctx, n.prefix+key, opts...)
+	if resp != nil {
+		for _, kv := range resp.Kvs {
+			n.stripKeyValue(kv)
+		}
+	}
+	return resp, err
+}
+
+func (n namespacedEtcd) Delete(ctx context.Context, key string, opts ...etcd.OpOption) (*etcd.DeleteResponse, error) {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := n.etcd.Delete(ctx, n.prefix+key, opts...)
+	if resp != nil {
+		for _, kv := range resp.PrevKvs {
+			n.stripKeyValue(kv)
+		}
+	}
+	return resp, err
+}
+
+func (n namespacedEtcd) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if n.requestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, n.requestTimeout)
+}
+
+func (n namespacedEtcd) stripKeyValue(kv *mvccpb.KeyValue) {
+	if kv == nil || n.prefix == "" {
+		return
+	}
+	kv.Key = []byte(strings.TrimPrefix(string(kv.Key), n.prefix))
+}
+
+// PutIfModRevision puts a key-value pair into etcd if the key was last modified at modRevision,
+// or does not exist when modRevision is zero. It returns false without writing otherwise. Keys
+// are namespaced when etcdInterface was created with NewNamespacedEtcd.
+func PutIfModRevision(ctx context.Context, etcdInterface EtcdInterface, key, val string, modRevision int64) (bool, error) {
+	return WriteIfModRevision(ctx, etcdInterface, key, modRevision, func(namespace string) []etcd.Op {
+		return []etcd.Op{etcd.OpPut(namespace+key, val)}
+	})
+}
+
+// DeleteIfModRevision deletes a key from etcd if it was last modified at modRevision. It returns
+// false without deleting otherwise.
+func DeleteIfModRevision(ctx context.Context, etcdInterface EtcdInterface, key string, modRevision int64) (bool, error) {
+	return WriteIfModRevision(ctx, etcdInterface, key, modRevision, func(namespace string) []etcd.Op {
+		return []etcd.Op{etcd.OpDelete(namespace + key)}
+	})
+}
+
+// WriteIfModRevision applies the operations built by ops in a single transaction if key was last
+// modified at modRevision. It returns false without applying them otherwise. Operations are
+// opaque once built, so ops is given the namespace to add to the keys it writes.
+func WriteIfModRevision(ctx context.Context, etcdInterface EtcdInterface, key string, modRevision int64,
+	ops func(namespace string) []etcd.Op) (bool, error) {
+	namespace := ""
+	if n, ok := etcdInterface.(namespacedEtcd); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = n.withTimeout(ctx)
+		defer cancel()
+		etcdInterface = n.etcd
+		namespace = n.prefix
+	}
+
+	txnInterface, ok := etcdInterface.(EtcdTxnInterface)
+	if !ok {
+		return false, errors.New("Etcd client does not support transactions")
+	}
+
+	resp, err := txnInterface.Txn(ctx).
+		If(etcd.Compare(etcd.ModRevision(namespace+key), "=", modRevision)).
+		Then(ops(namespace)...).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+// GetAll reads the keys and the keys starting with any of the prefixes at the same revision, so
+// that values written together are read together. Keys are returned without the namespace.
+func GetAll(ctx context.Context, etcdInterface EtcdInterface, keys []string, keyPrefixes []string) ([]*mvccpb.KeyValue, error) {
+	namespace := ""
+	n, namespaced := etcdInterface.(namespacedEtcd)
+	if namespaced {
+		var cancel context.CancelFunc
+		ctx, cancel = n.withTimeout(ctx)
+		defer cancel()
+		etcdInterface = n.etcd
+		namespace = n.prefix
+	}
+
+	txnInterface, ok := etcdInterface.(EtcdTxnInterface)
+	if !ok {
+		return nil, errors.New("Etcd client does not support transactions")
+	}
+
+	ops := make([]etcd.Op, 0, len(keys)+len(keyPrefixes))
+	for _, key := range keys {
+		ops = append(ops, etcd.OpGet(namespace+key))
+	}
+	for _, keyPrefix := range keyPrefixes {
+		ops = append(ops, etcd.OpGet(namespace+keyPrefix, etcd.WithPrefix()))
+	}
+
+	resp, err := txnInterface.Txn(ctx).Then(ops...).Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	kvs := make([]*mvccpb.KeyValue, 0)
+	for _, r := range resp.Responses {
+		rangeResp := r.GetResponseRange()
+		if rangeResp == nil {
+			continue
+		}
+		for _, kv := range rangeResp.Kvs {
+			if namespaced {
+				n.stripKeyValue(kv)
+			}
+			kvs = append(kvs, kv)
+		}
+	}
+	return kvs, nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blox/blox/daemon-scheduler/logger"
+	"github.com/blox/blox/daemon-scheduler/pkg/config"
+	"github.com/blox/blox/shared/ecsclient"
+	log "github.com/cihub/seelog"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// LoadConfigFile applies the settings from the config file and the environment to the
+// flags that were not set on the command line, then validates the resulting configuration.
+func LoadConfigFile() error {
+	return loadConfigFile(RootCmd.PersistentFlags())
+}
+
+func loadConfigFile(flags *pflag.FlagSet) error {
+	if config.ConfigFile != "" {
+		viper.SetConfigFile(config.ConfigFile)
+		if err := viper.ReadInConfig(); err != nil {
+			return errors.Wrapf(err, "Could not read the config file %s", config.ConfigFile)
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Name == configFlag || !isSetOutsideCommandLine(flag) {
+			return
+		}
+		err = setFlagValue(flag, viper.Get(flag.Name))
+	})
+	if err != nil {
+		return err
+	}
+
+	// the rate limits of single APIs are parsed once every source of settings has been applied
+	config.ECSThrottle.APIRateLimits, err = ecsclient.ParseAPIRateLimits(config.ECSAPIRateLimits)
+	if err != nil {
+		return err
+	}
+
+	return validate()
+}
+
+// WatchConfigFile reloads the config file whenever it changes and calls onChange with the new
+// reloadable settings. Changes to other settings only take effect after a restart, invalid
+// changes are logged and ignored, and flags set on the command line take precedence.
+func WatchConfigFile(onChange func(config.Reloadable)) {
+	if config.ConfigFile == "" {
+		return
+	}
+
+	viper.OnConfigChange(func(fsnotify.Event) {
+		reloadable, err := reloadableSettings(RootCmd.PersistentFlags())
+		if err != nil {
+			log.Errorf("Ignoring changes to the config file %s: %+v", config.ConfigFile, err)
+			return
+		}
+		onChange(reloadable)
+	})
+	viper.WatchConfig()
+}
+
+func reloadableSettings(flags *pflag.FlagSet) (config.Reloadable, error) {
+	reloadable := currentReloadable()
+	if isSetOutsideCommandLine(flags.Lookup(logLevelFlag)) {
+		reloadable.LogLevel = viper.GetString(logLevelFlag)
+	}
+	if isSetOutsideCommandLine(flags.Lookup(schedulerIntervalFlag)) {
+		reloadable.SchedulerInterval = viper.GetDuration(schedulerIntervalFlag)
+	}
+	if isSetOutsideCommandLine(flags.Lookup(monitorIntervalFlag)) {
+		reloadable.MonitorInterval = viper.GetDuration(monitorIntervalFlag)
+	}
+	if isSetOutsideCommandLine(flags.Lookup(pendingMonitorFlag)) {
+		reloadable.PendingMonitorInterval = viper.GetDuration(pendingMonitorFlag)
+	}
+	if isSetOutsideCommandLine(flags.Lookup(trackingInfoTTLFlag)) {
+		reloadable.TrackingInfoTTL = viper.GetDuration(trackingInfoTTLFlag)
+	}
+	if isSetOutsideCommandLine(flags.Lookup(clusterFlag)) {
+		reloadable.Clusters = viper.GetStringSlice(clusterFlag)
+	}
+	return reloadable, validateReloadable(reloadable)
+}
+
+func currentReloadable() config.Reloadable {
+	return config.Reloadable{
+		LogLevel:               config.LogLevel,
+		SchedulerInterval:      config.SchedulerInterval,
+		MonitorInterval:        config.MonitorInterval,
+		PendingMonitorInterval: config.PendingMonitorInterval,
+		TrackingInfoTTL:        config.TrackingInfoTTL,
+		Clusters:               config.Clusters,
+	}
+}
+
+func isSetOutsideCommandLine(flag *pflag.Flag) bool {
+	return !flag.Changed && viper.IsSet(flag.Name)
+}
+
+func setFlagValue(flag *pflag.Flag, value interface{}) error {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	for _, v := range values {
+		if err := flag.Value.Set(fmt.Sprint(v)); err != nil {
+			return errors.Wrapf(err, "Invalid value for %s", flag.Name)
+		}
+	}
+	return nil
+}
+
+func validate() error {
+	if err := validateReloadable(currentReloadable()); err != nil {
+		return err
+	}
+	if config.ServerReadTimeout <= 0 {
+		return errors.Errorf("The %s must be positive", serverReadTimeoutFlag)
+	}
+	if config.ServerWriteTimeout <= 0 {
+		return errors.Errorf("The %s must be positive", serverWriteTimeoutFlag)
+	}
+	if config.LeaderSessionTTL < time.Second {
+		return errors.Errorf("The %s must be at least one second", leaderSessionTTLFlag)
+	}
+	if config.DeploymentRetention < 1 {
+		return errors.Errorf("The %s must be at least 1", deploymentRetentionFlag)
+	}
+	return config.ECSThrottle.Validate()
+}
+
+func validateReloadable(reloadable config.Reloadable) error {
+	if !logger.IsValidLogLevel(reloadable.LogLevel) {
+		return errors.Errorf("Unknown %s %s", logLevelFlag, reloadable.LogLevel)
+	}
+	if reloadable.SchedulerInterval <= 0 {
+		return errors.Errorf("The %s must be positive", schedulerIntervalFlag)
+	}
+	if reloadable.MonitorInterval <= 0 {
+		return errors.Errorf("The %s must be positive", monitorIntervalFlag)
+	}
+	if reloadable.PendingMonitorInterval <= 0 {
+		return errors.Errorf("The %s must be positive", pendingMonitorFlag)
+	}
+	if reloadable.TrackingInfoTTL <= 0 {
+		return errors.Errorf("The %s must be positive", trackingInfoTTLFlag)
+	}
+	for _, cluster := range reloadable.Clusters {
+		if cluster == "" {
+			return errors.Errorf("The %s list must not contain empty names", clusterFlag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/config"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "ds-config")
+	assert.Nil(t, err, "Could not create a temp dir")
+	path := filepath.Join(dir, "ds.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(contents), 0600), "Could not write the config file")
+	return path
+}
+
+func executeWithConfigFile(t *testing.T, path string, args string) (*pflag.FlagSet, error) {
+	viper.Reset()
+	rootCmd := createRootCommand()
+	rootCmd.SetArgs(strings.Split("--config "+path+" "+args, " "))
+	assert.NoError(t, rootCmd.Execute(), "Error processing the --config flag")
+	return rootCmd.PersistentFlags(), loadConfigFile(rootCmd.PersistentFlags())
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := writeConfigFile(t, `
+bind: :2000
+css-endpoint: localhost:3000
+scheduler-interval: 30s
+cluster:
+  - prod
+  - staging
+log-level: debug
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	_, err := executeWithConfigFile(t, path, "--log-level warn")
+	assert.Nil(t, err, "Unexpected error loading the config file")
+	assert.Equal(t, ":2000", config.SchedulerBindAddr, "Unexpected bind address set")
+	assert.Equal(t, "localhost:3000", config.ClusterStateServiceEndpoint, "Unexpected css endpoint set")
+	assert.Equal(t, 30*time.Second, config.SchedulerInterval, "Unexpected scheduler interval set")
+	assert.Equal(t, []string{"prod", "staging"}, config.Clusters, "Unexpected clusters set")
+	assert.Equal(t, "warn", config.LogLevel, "Expected the command line to take precedence over the config file")
+}
+
+func TestLoadConfigFileInvalidSettings(t *testing.T) {
+	path := writeConfigFile(t, "tracking-info-ttl: -1m\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	_, err := executeWithConfigFile(t, path, "--bind :2000")
+	assert.Error(t, err, "Expected error with a negative ttl")
+}
+
+func TestLoadConfigFileECSThrottle(t *testing.T) {
+	path := writeConfigFile(t, `
+ecs-rate-limit: 5
+ecs-api-rate-limit:
+  - DescribeTasks=20
+  - ListClusters=0
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	_, err := executeWithConfigFile(t, path, "--ecs-circuit-threshold 0")
+	assert.Nil(t, err, "Unexpected error loading the config file")
+	assert.Equal(t, 5.0, config.ECSThrottle.RateLimit, "Unexpected ECS rate limit set")
+	assert.Equal(t, map[string]float64{"DescribeTasks": 20, "ListClusters": 0}, config.ECSThrottle.APIRateLimits,
+		"Unexpected ECS API rate limits set")
+	assert.Equal(t, 0, config.ECSThrottle.CircuitThreshold, "Unexpected ECS circuit threshold set")
+
+	path = writeConfigFile(t, "ecs-api-rate-limit:\n  - DescribeTasks\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	_, err = executeWithConfigFile(t, path, "")
+	assert.Error(t, err, "Expected error with an API rate limit without a rate")
+}
+
+func TestReloadableSettings(t *testing.T) {
+	path := writeConfigFile(t, "monitor-interval: 1m\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	flags, err := executeWithConfigFile(t, path, "--scheduler-interval 5s")
+	assert.Nil(t, err, "Unexpected error loading the config file")
+
+	contents := "scheduler-interval: 1m\nmonitor-interval: 20s\npending-monitor-interval: 30s\ncluster:\n  - prod\n"
+	assert.Nil(t, ioutil.WriteFile(path, []byte(contents), 0600), "Could not update the config file")
+	assert.Nil(t, viper.ReadInConfig(), "Could not read the updated config file")
+
+	reloadable, err := reloadableSettings(flags)
+	assert.Nil(t, err, "Unexpected error reloading settings")
+	assert.Equal(t, 5*time.Second, reloadable.SchedulerInterval, "Expected the command line to take precedence over the config file")
+	assert.Equal(t, 20*time.Second, reloadable.MonitorInterval, "Unexpected monitor interval reloaded")
+	assert.Equal(t, 30*time.Second, reloadable.PendingMonitorInterval, "Unexpected pending monitor interval reloaded")
+	assert.Equal(t, []string{"prod"}, reloadable.Clusters, "Unexpected clusters reloaded")
+}
@@ -14,11 +14,43 @@
 package cmd
 
 import (
+	"strings"
+
+	"github.com/blox/blox/daemon-scheduler/logger"
+	"github.com/blox/blox/daemon-scheduler/pkg/clients"
 	"github.com/blox/blox/daemon-scheduler/pkg/config"
+	"github.com/blox/blox/daemon-scheduler/pkg/election"
+	"github.com/blox/blox/daemon-scheduler/pkg/engine"
+	"github.com/blox/blox/daemon-scheduler/pkg/scheduler"
+	"github.com/blox/blox/daemon-scheduler/pkg/store"
+	"github.com/blox/blox/shared/ecsclient"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+const (
+	configFlag              = "config"
+	logLevelFlag            = "log-level"
+	schedulerIntervalFlag   = "scheduler-interval"
+	monitorIntervalFlag     = "monitor-interval"
+	pendingMonitorFlag      = "pending-monitor-interval"
+	trackingInfoTTLFlag     = "tracking-info-ttl"
+	clusterFlag             = "cluster"
+	serverReadTimeoutFlag   = "server-read-timeout"
+	serverWriteTimeoutFlag  = "server-write-timeout"
+	advertiseAddressFlag    = "advertise-address"
+	leaderSessionTTLFlag    = "leader-session-ttl"
+	deploymentRetentionFlag = "deployment-retention"
+	ecsRateLimitFlag        = "ecs-rate-limit"
+	ecsBurstFlag            = "ecs-burst"
+	ecsAPIRateLimitFlag     = "ecs-api-rate-limit"
+	ecsMaxRetriesFlag       = "ecs-max-retries"
+	ecsCircuitThresholdFlag = "ecs-circuit-threshold"
+	ecsCircuitCooldownFlag  = "ecs-circuit-cooldown"
+
+	envPrefix = "DS"
+)
+
 var etcdAddrList string
 
 // RootCmd represents the base command when called without any subcommands
@@ -32,6 +64,9 @@ func init() {
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	// settings can be given as environment variables such as DS_LOG_LEVEL
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv() // read in environment variables that match
 }
 
@@ -46,8 +81,35 @@ it launches the task in those nodes as well.`,
 		},
 	}
 	rootCmd.PersistentFlags().StringArrayVar(&config.EtcdEndpoints, "etcd-endpoint", make([]string, 0), "Etcd node addresses")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdCAFile, "etcd-ca", "", "Path to the CA certificate used to verify etcd servers")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdCertFile, "etcd-cert", "", "Path to the client certificate used to connect to etcd")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdKeyFile, "etcd-key", "", "Path to the client key used to connect to etcd")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdUsername, "etcd-username", "", "Etcd username")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdPassword, "etcd-password", "", "Etcd password, read from ETCD_PASSWORD if not set")
+	rootCmd.PersistentFlags().DurationVar(&config.EtcdDialTimeout, "etcd-dial-timeout", clients.DefaultDialTimeout, "Timeout for connecting to etcd")
+	rootCmd.PersistentFlags().DurationVar(&config.EtcdRequestTimeout, "etcd-request-timeout", clients.DefaultRequestTimeout, "Timeout for a single etcd request")
+	rootCmd.PersistentFlags().StringVar(&config.EtcdKeyPrefix, "etcd-key-prefix", "", "Prefix of every key stored in etcd, to share an etcd cluster between deployments")
 	rootCmd.PersistentFlags().StringVar(&config.SchedulerBindAddr, "bind", "", "Scheduler bind address")
 	rootCmd.PersistentFlags().StringVar(&config.ClusterStateServiceEndpoint, "css-endpoint", "", "Cluster state service address")
+	rootCmd.PersistentFlags().StringVar(&config.AuthConfigFile, "auth-config", "", "Path to the API credentials and authorization policy file")
+	rootCmd.PersistentFlags().StringVar(&config.ConfigFile, configFlag, "", "Path to a YAML or TOML file with settings named after these flags")
+	rootCmd.PersistentFlags().StringVar(&config.LogLevel, logLevelFlag, logger.DefaultLogLevel, "Log level, one of debug, info, warn, error, crit or none")
+	rootCmd.PersistentFlags().DurationVar(&config.SchedulerInterval, schedulerIntervalFlag, engine.SchedulerTickerDuration, "Interval between full scheduler runs, which catch cluster state changes that were missed")
+	rootCmd.PersistentFlags().DurationVar(&config.MonitorInterval, monitorIntervalFlag, engine.InProgressMonitorTickerDuration, "Interval between checks of in-progress deployments")
+	rootCmd.PersistentFlags().DurationVar(&config.PendingMonitorInterval, pendingMonitorFlag, engine.PendingMonitorTickerDuration, "Interval between checks of pending deployments")
+	rootCmd.PersistentFlags().DurationVar(&config.TrackingInfoTTL, trackingInfoTTLFlag, engine.TrackingInfoTTL, "Time to wait for a started task to show up in the cluster state before starting it again")
+	rootCmd.PersistentFlags().StringArrayVar(&config.Clusters, clusterFlag, make([]string, 0), "Name or ARN of a cluster to schedule environments in, all clusters if not set")
+	rootCmd.PersistentFlags().DurationVar(&config.ServerReadTimeout, serverReadTimeoutFlag, scheduler.DefaultServerReadTimeout, "Maximum duration for reading a request")
+	rootCmd.PersistentFlags().DurationVar(&config.ServerWriteTimeout, serverWriteTimeoutFlag, scheduler.DefaultServerWriteTimeout, "Maximum duration for writing a response")
+	rootCmd.PersistentFlags().StringVar(&config.AdvertiseAddress, advertiseAddressFlag, "", "URL other replicas forward writes to while this replica leads, derived from the bind address and host name if not set")
+	rootCmd.PersistentFlags().DurationVar(&config.LeaderSessionTTL, leaderSessionTTLFlag, election.DefaultSessionTTL, "Time after which the leader loses its leadership if it cannot reach etcd")
+	rootCmd.PersistentFlags().IntVar(&config.DeploymentRetention, deploymentRetentionFlag, store.DefaultDeploymentRetention, "Number of latest deployments kept for each environment, besides the ones that have not finished")
+	rootCmd.PersistentFlags().Float64Var(&config.ECSThrottle.RateLimit, ecsRateLimitFlag, ecsclient.DefaultRateLimit, "Requests per second each ECS API is called at, 0 for no limit")
+	rootCmd.PersistentFlags().IntVar(&config.ECSThrottle.Burst, ecsBurstFlag, ecsclient.DefaultBurst, "Requests each ECS API may be called at once after being idle")
+	rootCmd.PersistentFlags().StringArrayVar(&config.ECSAPIRateLimits, ecsAPIRateLimitFlag, make([]string, 0), "Rate limit of a single ECS API as Operation=rate, such as DescribeTasks=20")
+	rootCmd.PersistentFlags().IntVar(&config.ECSThrottle.MaxRetries, ecsMaxRetriesFlag, ecsclient.DefaultMaxRetries, "Times a throttled or failed ECS request is retried")
+	rootCmd.PersistentFlags().IntVar(&config.ECSThrottle.CircuitThreshold, ecsCircuitThresholdFlag, ecsclient.DefaultCircuitThreshold, "Consecutive throttled or failed ECS requests after which ECS is not called for the circuit cooldown, 0 to never stop")
+	rootCmd.PersistentFlags().DurationVar(&config.ECSThrottle.CircuitCooldown, ecsCircuitCooldownFlag, ecsclient.DefaultCircuitCooldown, "Time ECS is not called for after too many throttled or failed requests")
 	rootCmd.PersistentFlags().BoolVar(&config.PrintVersion, "version", false, "Print version and exit")
 	return rootCmd
 }
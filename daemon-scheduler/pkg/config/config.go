@@ -13,9 +13,30 @@
 
 package config
 
+import (
+	"time"
+
+	"github.com/blox/blox/shared/ecsclient"
+)
+
 // EtcdEndpoints represents the etcd servers to connect to.
 var EtcdEndpoints []string
 
+// EtcdCAFile, EtcdCertFile and EtcdKeyFile represent the TLS files used to connect to etcd.
+var EtcdCAFile, EtcdCertFile, EtcdKeyFile string
+
+// EtcdUsername and EtcdPassword represent the credentials used to authenticate with etcd.
+var EtcdUsername, EtcdPassword string
+
+// EtcdDialTimeout represents the timeout for connecting to etcd.
+var EtcdDialTimeout time.Duration
+
+// EtcdRequestTimeout represents the timeout for a single etcd request.
+var EtcdRequestTimeout time.Duration
+
+// EtcdKeyPrefix represents the prefix of every key stored in etcd.
+var EtcdKeyPrefix string
+
 // SchedulerBindAddr represents the endpoint scheduler listens on.
 var SchedulerBindAddr string
 
@@ -24,3 +45,64 @@ var ClusterStateServiceEndpoint string
 
 // PrintVersion represents the flag to set when printing version information.
 var PrintVersion bool
+
+// AuthConfigFile represents the path of the file with the API credentials and authorization policy.
+// The API is not authenticated when it is empty.
+var AuthConfigFile string
+
+// ConfigFile represents the path of the YAML or TOML file to read settings from.
+var ConfigFile string
+
+// LogLevel represents the minimum level of the messages that are logged.
+var LogLevel string
+
+// SchedulerInterval represents the interval between full scheduler runs.
+var SchedulerInterval time.Duration
+
+// MonitorInterval represents the interval between checks of in-progress deployments.
+var MonitorInterval time.Duration
+
+// PendingMonitorInterval represents the interval between checks of pending deployments.
+var PendingMonitorInterval time.Duration
+
+// TrackingInfoTTL represents how long the scheduler waits for a started task to show up
+// in the cluster state before starting it again.
+var TrackingInfoTTL time.Duration
+
+// Clusters represents the clusters whose environments are scheduled. Environments in all
+// clusters are scheduled when it is empty.
+var Clusters []string
+
+// ServerReadTimeout and ServerWriteTimeout represent the maximum durations for reading
+// a request and writing a response.
+var ServerReadTimeout, ServerWriteTimeout time.Duration
+
+// AdvertiseAddress represents the URL other scheduler replicas use to forward writes to this
+// one while it is the leader.
+var AdvertiseAddress string
+
+// LeaderSessionTTL represents how long a leader keeps its leadership after it stops
+// refreshing its etcd session.
+var LeaderSessionTTL time.Duration
+
+// DeploymentRetention represents the number of latest deployments kept for each environment.
+var DeploymentRetention int
+
+// ECSThrottle represents the rate limits, retries and circuit breaking of ECS requests. Its
+// APIRateLimits are parsed from ECSAPIRateLimits.
+var ECSThrottle = ecsclient.DefaultConfig()
+
+// ECSAPIRateLimits represents the rate limits of single ECS APIs, given as Operation=rate.
+var ECSAPIRateLimits []string
+
+// Reloadable holds the settings that can change while the scheduler is running.
+type Reloadable struct {
+	LogLevel          string
+	SchedulerInterval time.Duration
+	MonitorInterval   time.Duration
+	// PendingMonitorInterval is the interval of the pending monitor, while MonitorInterval is
+	// the one of the in-progress monitor
+	PendingMonitorInterval time.Duration
+	TrackingInfoTTL        time.Duration
+	Clusters               []string
+}
@@ -0,0 +1,434 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// names of the remaining resources of instances in the cluster state
+const (
+	cpuResource      = "CPU"
+	memoryResource   = "MEMORY"
+	portsResource    = "PORTS"
+	udpPortsResource = "PORTS_UDP"
+
+	runningTaskStatus = "RUNNING"
+)
+
+const (
+	// environmentsCacheTTL is how long the environments listed for a capacity check are reused by
+	// the checks of the following starts
+	environmentsCacheTTL = 5 * time.Second
+	// maxCachedTaskDefinitions bounds the resources of task definitions kept across checks
+	maxCachedTaskDefinitions = 1024
+)
+
+// capacityCache keeps what capacity checks look up on every start: the resources of task
+// definitions, which never change once registered, and the environments for a few seconds
+type capacityCache struct {
+	lock          sync.Mutex
+	taskResources map[string]types.TaskResources
+	environments  []types.Environment
+	listedAt      time.Time
+}
+
+func newCapacityCache() *capacityCache {
+	return &capacityCache{taskResources: make(map[string]types.TaskResources)}
+}
+
+// capacityCheck holds what is needed to check the capacity of the instances of a cluster for the
+// tasks of a deployment
+type capacityCheck struct {
+	env          *types.Environment
+	cluster      string
+	required     types.TaskResources
+	remaining    map[string]types.InstanceResources
+	environments []types.Environment
+
+	// tasks of the cluster are only loaded when tasks of lower priority environments may have to
+	// be stopped
+	tasks []*models.Task
+
+	// dryRun checks record the tasks of lower priority environments in stopped instead of
+	// stopping them
+	dryRun  bool
+	stopped []types.PlannedTask
+}
+
+// checkCapacity splits the instances into those with the capacity to run a task of the deployment
+// and those without, which are returned as failures with the reason. Instances the cluster state
+// does not know the remaining resources of are left to ECS to check. If the capacity policy of the
+// environment allows it, tasks of lower priority environments are stopped to make room.
+func (d deployment) checkCapacity(ctx context.Context, env *types.Environment, cluster string,
+	deployment *types.Deployment, instanceARNs []*string) ([]*string, []*ecs.Failure, error) {
+
+	check, err := d.newCapacityCheck(ctx, env, cluster, deployment.TaskDefinition, deployment.TaskOverrides)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	available := make([]*string, 0, len(instanceARNs))
+	insufficient := make([]*ecs.Failure, 0)
+	for _, instanceARN := range instanceARNs {
+		reason, err := d.checkInstanceCapacity(check, aws.StringValue(instanceARN))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if reason == "" {
+			available = append(available, instanceARN)
+			continue
+		}
+
+		// the instance is reserved from now on, which the next checks have to see
+		if reason == types.StoppingTasksReason {
+			d.cache.forgetEnvironments()
+		}
+
+		log.Infof("Not starting a task of deployment %s on instance %s of environment %s: %s",
+			deployment.ID, aws.StringValue(instanceARN), env.Name, reason)
+		insufficient = append(insufficient, &ecs.Failure{
+			Arn:    instanceARN,
+			Reason: aws.String(reason),
+		})
+	}
+
+	return available, insufficient, nil
+}
+
+func (d deployment) PlanCapacity(ctx context.Context, env types.Environment, cluster string,
+	instanceARNs []string) (*types.CapacityPlan, error) {
+
+	check, err := d.newCapacityCheck(ctx, &env, cluster, env.DesiredTaskDefinition, env.TaskOverrides)
+	if err != nil {
+		return nil, err
+	}
+	check.dryRun = true
+
+	plan := &types.CapacityPlan{
+		Available:    make([]string, 0, len(instanceARNs)),
+		Insufficient: make(map[string]string),
+	}
+	for _, instanceARN := range instanceARNs {
+		reason, err := d.checkInstanceCapacity(check, instanceARN)
+		if err != nil {
+			return nil, err
+		}
+
+		if reason == "" {
+			plan.Available = append(plan.Available, instanceARN)
+		} else {
+			plan.Insufficient[instanceARN] = reason
+		}
+	}
+	plan.StoppedTasks = check.stopped
+	return plan, nil
+}
+
+// newCapacityCheck loads what is needed to check the capacity of the instances of the cluster for
+// tasks of the task definition started with the overrides
+func (d deployment) newCapacityCheck(ctx context.Context, env *types.Environment, cluster string,
+	taskDefinitionARN string, overrides types.TaskOverrides) (*capacityCheck, error) {
+
+	required, err := d.taskDefinitionResources(taskDefinitionARN)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := d.clusterState.ListInstances(cluster)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error listing instances of cluster %s", cluster)
+	}
+
+	environments, err := d.listEnvironments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &capacityCheck{
+		env:          env,
+		cluster:      cluster,
+		required:     overrides.ApplyTo(required),
+		remaining:    make(map[string]types.InstanceResources, len(instances)),
+		environments: environments,
+	}
+	for _, instance := range instances {
+		if resources, ok := instanceResources(instance); ok {
+			check.remaining[aws.StringValue(instance.ContainerInstanceARN)] = resources
+		}
+	}
+	return check, nil
+}
+
+// checkInstanceCapacity returns why the instance cannot run a task of the deployment, or an empty
+// string if it can
+func (d deployment) checkInstanceCapacity(check *capacityCheck, instanceARN string) (string, error) {
+	for _, e := range check.environments {
+		if e.Name != check.env.Name && e.CapacityPolicy.Priority > check.env.CapacityPolicy.Priority &&
+			e.ReservesInstance(instanceARN) {
+			return types.ReservedReason, nil
+		}
+	}
+
+	remaining, ok := check.remaining[instanceARN]
+	if !ok {
+		return "", nil
+	}
+
+	reason := remaining.InsufficientReason(check.required)
+	if reason == "" || !check.env.CapacityPolicy.StopLowerPriorityTasks {
+		return reason, nil
+	}
+
+	stopped, err := d.stopLowerPriorityTasks(check, instanceARN, remaining)
+	if err != nil {
+		return "", err
+	}
+	if stopped {
+		return types.StoppingTasksReason, nil
+	}
+	return reason, nil
+}
+
+// stopLowerPriorityTasks stops tasks of environments with a lower priority on the instance, lowest
+// priority first, if stopping them makes room for a task of the deployment. It returns whether
+// any task was stopped.
+func (d deployment) stopLowerPriorityTasks(check *capacityCheck, instanceARN string,
+	remaining types.InstanceResources) (bool, error) {
+
+	if check.tasks == nil {
+		tasks, err := d.clusterState.ListTasks(check.cluster)
+		if err != nil {
+			return false, errors.Wrapf(err, "Error listing tasks of cluster %s", check.cluster)
+		}
+		check.tasks = tasks
+	}
+
+	owners := make(map[string]types.Environment)
+	for _, e := range check.environments {
+		if e.Name == check.env.Name || e.CapacityPolicy.Priority >= check.env.CapacityPolicy.Priority {
+			continue
+		}
+		for id := range e.Deployments {
+			owners[id] = e
+		}
+	}
+
+	candidates := make(lowerPriorityTasks, 0)
+	for _, task := range check.tasks {
+		owner, ok := owners[task.StartedBy]
+		if ok && aws.StringValue(task.ContainerInstanceARN) == instanceARN &&
+			aws.StringValue(task.DesiredStatus) == runningTaskStatus {
+			candidates = append(candidates, lowerPriorityTask{
+				task:        task,
+				priority:    owner.CapacityPolicy.Priority,
+				environment: owner.Name,
+				overrides:   owner.Deployments[task.StartedBy].TaskOverrides,
+			})
+		}
+	}
+	sort.Sort(candidates)
+
+	toStop := make([]lowerPriorityTask, 0)
+	for _, candidate := range candidates {
+		if remaining.InsufficientReason(check.required) == "" {
+			break
+		}
+
+		resources, err := d.runningTaskResources(check, candidate.task, candidate.overrides)
+		if err != nil {
+			return false, err
+		}
+		remaining = remaining.Release(resources)
+		toStop = append(toStop, candidate)
+	}
+
+	if len(toStop) == 0 || remaining.InsufficientReason(check.required) != "" {
+		return false, nil
+	}
+
+	if check.dryRun {
+		for _, candidate := range toStop {
+			check.stopped = append(check.stopped, types.PlannedTask{
+				TaskARN:      aws.StringValue(candidate.task.TaskARN),
+				InstanceARN:  instanceARN,
+				Environment:  candidate.environment,
+				DeploymentID: candidate.task.StartedBy,
+			})
+		}
+		return true, nil
+	}
+
+	for _, candidate := range toStop {
+		task := candidate.task
+		log.Infof("Stopping task %s of deployment %s on instance %s to make room for environment %s",
+			aws.StringValue(task.TaskARN), task.StartedBy, instanceARN, check.env.Name)
+		err := d.ecs.StopTask(check.cluster, aws.StringValue(task.TaskARN), types.StopReasonEvicted)
+		if err != nil {
+			return false, errors.Wrapf(err, "Error stopping task %s", aws.StringValue(task.TaskARN))
+		}
+	}
+	return true, nil
+}
+
+// runningTaskResources returns the resources a task uses on its instance, given by its task
+// definition and the overrides it was started with except for the host ports, which are the ones
+// it is bound to
+func (d deployment) runningTaskResources(check *capacityCheck, task *models.Task,
+	overrides types.TaskOverrides) (types.TaskResources, error) {
+
+	resources, err := d.taskDefinitionResources(aws.StringValue(task.TaskDefinitionARN))
+	if err != nil {
+		return types.TaskResources{}, err
+	}
+	resources = overrides.ApplyTo(resources)
+
+	ports := make([]string, 0)
+	for _, container := range task.Containers {
+		for _, binding := range container.NetworkBindings {
+			ports = append(ports, types.HostPort(aws.Int64Value(binding.HostPort), binding.Protocol))
+		}
+	}
+	resources.Ports = ports
+	return resources, nil
+}
+
+// taskDefinitionResources returns the resources a task of the task definition requires. Task
+// definitions named by the ARN of their revision never change, so their resources are only
+// described once.
+func (d deployment) taskDefinitionResources(arn string) (types.TaskResources, error) {
+	d.cache.lock.Lock()
+	resources, ok := d.cache.taskResources[arn]
+	d.cache.lock.Unlock()
+	if ok {
+		return resources, nil
+	}
+
+	taskDefinition, err := d.ecs.DescribeTaskDefinition(aws.String(arn))
+	if err != nil {
+		return types.TaskResources{}, errors.Wrapf(err, "Error describing task definition %s", arn)
+	}
+	resources = types.NewTaskResources(taskDefinition)
+
+	// a family or a family without the revision may name a newer revision next time
+	if aws.StringValue(taskDefinition.TaskDefinitionArn) == arn {
+		d.cache.lock.Lock()
+		if len(d.cache.taskResources) >= maxCachedTaskDefinitions {
+			d.cache.taskResources = make(map[string]types.TaskResources)
+		}
+		d.cache.taskResources[arn] = resources
+		d.cache.lock.Unlock()
+	}
+	return resources, nil
+}
+
+// listEnvironments returns the environments, listing them at most once every
+// environmentsCacheTTL instead of on every start
+func (d deployment) listEnvironments(ctx context.Context) ([]types.Environment, error) {
+	d.cache.lock.Lock()
+	defer d.cache.lock.Unlock()
+
+	if d.cache.environments != nil && time.Since(d.cache.listedAt) < environmentsCacheTTL {
+		return d.cache.environments, nil
+	}
+
+	environments, err := d.environment.ListEnvironments(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error listing environments")
+	}
+	d.cache.environments = environments
+	d.cache.listedAt = time.Now()
+	return environments, nil
+}
+
+// forgetEnvironments makes the next capacity check list the environments again
+func (c *capacityCache) forgetEnvironments() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.environments = nil
+}
+
+// instanceResources returns the resources left on the instance, if the cluster state knows them
+func instanceResources(instance *models.ContainerInstance) (types.InstanceResources, bool) {
+	resources := types.InstanceResources{UsedPorts: make(map[string]bool)}
+	found := 0
+	for _, r := range instance.RemainingResources {
+		value := aws.StringValue(r.Value)
+		switch aws.StringValue(r.Name) {
+		case cpuResource, memoryResource:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return types.InstanceResources{}, false
+			}
+			if aws.StringValue(r.Name) == cpuResource {
+				resources.CPU = n
+			} else {
+				resources.Memory = n
+			}
+			found++
+		case portsResource, udpPortsResource:
+			protocol := "tcp"
+			if aws.StringValue(r.Name) == udpPortsResource {
+				protocol = "udp"
+			}
+			for _, port := range strings.Split(value, ",") {
+				n, err := strconv.ParseInt(port, 10, 64)
+				if err == nil {
+					resources.UsedPorts[types.HostPort(n, protocol)] = true
+				}
+			}
+		}
+	}
+	return resources, found == 2
+}
+
+// lowerPriorityTask is a task of an environment with a lower priority that may be stopped
+type lowerPriorityTask struct {
+	task        *models.Task
+	priority    int
+	environment string
+	// overrides are the ones the deployment that started the task started it with
+	overrides types.TaskOverrides
+}
+
+// lowerPriorityTasks are ordered by priority, then by ARN so that the same tasks are stopped first
+type lowerPriorityTasks []lowerPriorityTask
+
+func (t lowerPriorityTasks) Len() int {
+	return len(t)
+}
+
+func (t lowerPriorityTasks) Less(i, j int) bool {
+	if t[i].priority != t[j].priority {
+		return t[i].priority < t[j].priority
+	}
+	return aws.StringValue(t[i].task.TaskARN) < aws.StringValue(t[j].task.TaskARN)
+}
+
+func (t lowerPriorityTasks) Swap(i, j int) {
+	t[i], t[j] = t[j], t[i]
+}
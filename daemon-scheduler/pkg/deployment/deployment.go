@@ -15,7 +15,9 @@ package deployment
 
 import (
 	"context"
+	"time"
 
+	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/blox/blox/daemon-scheduler/pkg/facade"
 	"github.com/blox/blox/daemon-scheduler/pkg/types"
 	"github.com/pkg/errors"
@@ -26,11 +28,14 @@ import (
 type Deployment interface {
 	// CreateDeployment creates a new deployment in the provided environment and updates the
 	// environment's pending deployment ID to the ID of the deployment created. The environment
-	// token must match the provided token, otherwise the deployment creation will fail.
-	CreateDeployment(ctx context.Context, environmentName string, token string) (*types.Deployment, error)
+	// token must match the provided token, otherwise the deployment creation will fail. The
+	// deployment stays pending until notBefore, if set, and a maintenance window of the
+	// environment is open.
+	CreateDeployment(ctx context.Context, environmentName string, token string, notBefore time.Time) (*types.Deployment, error)
 	// CreateSubDeployment kicks off a deployment corresponding to the in progress deployment ID
-	// in the environment to start tasks on given instances
-	CreateSubDeployment(ctx context.Context, environmentName string, instanceARNs []*string) (*types.Deployment, error)
+	// in the environment to start tasks on given instances of the cluster, which has to be one of
+	// the clusters of the environment
+	CreateSubDeployment(ctx context.Context, environmentName string, cluster string, instanceARNs []*string) (*types.Deployment, error)
 
 	// GetDeployment returns the deployment with the provided id in the provided environment
 	GetDeployment(ctx context.Context, environmentName string, id string) (*types.Deployment, error)
@@ -43,12 +48,30 @@ type Deployment interface {
 	// ListDeploymentsSortedReverseChronologically returns a list of deployments reverse-ordered by start time,
 	// i.e. lastest deployment first
 	ListDeploymentsSortedReverseChronologically(ctx context.Context, environmentName string) ([]types.Deployment, error)
+
+	// PauseDeployment stops the latest deployment of the environment, which has to have the provided id,
+	// from being rolled out to further instances while keeping the tasks it started
+	PauseDeployment(ctx context.Context, environmentName string, id string) (*types.Deployment, error)
+	// ResumeDeployment carries on with the rollout of the paused deployment with the provided id
+	ResumeDeployment(ctx context.Context, environmentName string, id string) (*types.Deployment, error)
+	// CancelDeployment stops the latest deployment of the environment, which has to have the provided id,
+	// from being rolled out to further instances for good. If revert is set, the instances it updated are
+	// reverted by a new deployment of the latest healthy completed deployment.
+	CancelDeployment(ctx context.Context, environmentName string, id string, revert bool) (*types.Deployment, error)
+
+	// ListDeploymentInstances returns the state of the deployment with the provided id on every instance
+	// it started a task on or failed to, ordered by cluster and instance ARN
+	ListDeploymentInstances(ctx context.Context, environmentName string, id string) ([]types.DeploymentInstance, error)
+	// PlanCapacity checks which of the instances of the cluster have the capacity to run a task of the
+	// desired task definition of the environment, without stopping any task to make room
+	PlanCapacity(ctx context.Context, env types.Environment, cluster string, instanceARNs []string) (*types.CapacityPlan, error)
 }
 
 type deployment struct {
 	environment  Environment
 	clusterState facade.ClusterState
 	ecs          facade.ECS
+	cache        *capacityCache
 }
 
 func NewDeployment(
@@ -60,11 +83,12 @@ func NewDeployment(
 		environment:  environment,
 		clusterState: clusterState,
 		ecs:          ecs,
+		cache:        newCapacityCache(),
 	}
 }
 
 func (d deployment) CreateDeployment(ctx context.Context,
-	environmentName string, token string) (*types.Deployment, error) {
+	environmentName string, token string, notBefore time.Time) (*types.Deployment, error) {
 
 	if len(environmentName) == 0 {
 		return nil, types.NewBadRequestError(errors.New("Environment name is missing when creating a deployment"))
@@ -79,6 +103,11 @@ func (d deployment) CreateDeployment(ctx context.Context,
 		return nil, errors.Wrapf(err, "Error retrieving environment with name %s", environmentName)
 	}
 
+	err = env.VerifyNotDeleting()
+	if err != nil {
+		return nil, err
+	}
+
 	err = d.verifyToken(*env, token)
 	if err != nil {
 		return nil, err
@@ -89,11 +118,24 @@ func (d deployment) CreateDeployment(ctx context.Context,
 		return nil, err
 	}
 
+	latest := env.LatestDeployment()
+	if latest != nil && latest.Status == types.DeploymentPaused {
+		return nil, types.NewBadRequestError(errors.Errorf(
+			"Deployment %s is paused and has to be resumed or cancelled first", latest.ID))
+	}
+	// a pending deployment waiting to start is not in progress yet
+	if latest != nil && latest.Status == types.DeploymentPending {
+		return nil, types.NewBadRequestError(errors.Errorf(
+			"Deployment %s has not started yet and has to start or be cancelled first", latest.ID))
+	}
+
 	// create and add a pending deployment to the environment
 	deployment, err := types.NewDeployment(env.DesiredTaskDefinition, env.Token)
 	if err != nil {
 		return nil, err
 	}
+	deployment.TaskOverrides = env.TaskOverrides
+	deployment.NotBefore = notBefore
 
 	env, err = d.environment.AddPendingDeployment(ctx, *env, *deployment)
 	if err != nil {
@@ -130,7 +172,7 @@ func (d deployment) verifyNoInProgressDeploymentExists(ctx context.Context, envi
 	return nil
 }
 
-func (d deployment) CreateSubDeployment(ctx context.Context, environmentName string, instanceARNs []*string) (*types.Deployment, error) {
+func (d deployment) CreateSubDeployment(ctx context.Context, environmentName string, cluster string, instanceARNs []*string) (*types.Deployment, error) {
 	if environmentName == "" {
 		return nil, errors.New("Environment name is missing when creating a deployment")
 	}
@@ -140,6 +182,22 @@ func (d deployment) CreateSubDeployment(ctx context.Context, environmentName str
 		return nil, errors.Wrapf(err, "Error retrieving environment with name %s", environmentName)
 	}
 
+	// tasks started while the environment is being deleted would outlive it
+	if env.IsDeleting() {
+		return nil, errors.Errorf("Environment %s is being deleted, not starting tasks on %d instances",
+			environmentName, len(instanceARNs))
+	}
+
+	// the clusters of the environment may have changed since the instances were picked
+	targets, err := d.targetsCluster(env, cluster)
+	if err != nil {
+		return nil, err
+	}
+	if !targets {
+		return nil, errors.Errorf("Environment %s is not deployed to cluster %s, not starting tasks on %d instances",
+			environmentName, cluster, len(instanceARNs))
+	}
+
 	deployment, err := d.GetCurrentDeployment(ctx, environmentName)
 	if err != nil {
 		return nil, errors.Wrapf(err,
@@ -151,30 +209,80 @@ func (d deployment) CreateSubDeployment(ctx context.Context, environmentName str
 			"There is no deployment for environment with name '%s' to create a sub-deployment", environmentName)
 	}
 
-	return d.startDeployment(ctx, env, deployment, instanceARNs)
+	return d.startDeployment(ctx, env, cluster, deployment, instanceARNs)
+}
+
+// targetsCluster returns whether the environment is deployed to the cluster, describing the tags
+// of the cluster when the cluster selector picks clusters by tag
+func (d deployment) targetsCluster(env *types.Environment, cluster string) (bool, error) {
+	if env.TargetsCluster(cluster) {
+		return true, nil
+	}
+	if !env.HasClusterSelector() || !env.ClusterSelector.HasTags() {
+		return false, nil
+	}
+
+	tags, err := d.ecs.DescribeClusterTags(cluster)
+	if err != nil {
+		return false, errors.Wrapf(err, "Error getting the tags of cluster %s", cluster)
+	}
+	return env.ClusterSelector.MatchesTags(tags), nil
 }
 
 //TODO: wrap in a transaction so the environment and the deployment do not get modified in between being retrieved and starting tasks
-func (d deployment) startDeployment(ctx context.Context, env *types.Environment, deployment *types.Deployment, instanceARNs []*string) (*types.Deployment, error) {
-	resp, err := d.ecs.StartTask(env.Cluster, instanceARNs, deployment.ID, deployment.TaskDefinition)
+func (d deployment) startDeployment(ctx context.Context, env *types.Environment, cluster string, deployment *types.Deployment, instanceARNs []*string) (*types.Deployment, error) {
+	available, insufficient, err := d.checkCapacity(ctx, env, cluster, deployment, instanceARNs)
 	if err != nil {
-		return nil, errors.Wrapf(
-			err, "Error starting tasks for deployment with ID '%s' in environment with name '%s'", deployment.ID)
+		return nil, errors.Wrapf(err, "Error checking the capacity of instances for deployment with ID '%s'", deployment.ID)
+	}
+
+	startFailures := make([]*ecs.Failure, 0)
+	if len(available) > 0 {
+		resp, err := d.ecs.StartTask(cluster, available, deployment.ID, deployment.TaskDefinition,
+			deployment.TaskOverrides.ECSOverride(), deployment.TaskOverrides.ECSTags())
+		if err != nil {
+			return nil, errors.Wrapf(
+				err, "Error starting tasks for deployment with ID '%s' in environment with name '%s'", deployment.ID, env.Name)
+		}
+
+		// instances ECS found no room on are retried like the ones found short of capacity beforehand
+		for _, failure := range resp.Failures {
+			if types.IsCapacityFailure(failure) {
+				insufficient = append(insufficient, failure)
+			} else {
+				startFailures = append(startFailures, failure)
+			}
+		}
+	}
+	if env.HasClusterSelector() {
+		deployment = deployment.UpdateClusterInsufficientCapacity(cluster, instanceARNs, insufficient)
+	} else {
+		deployment = deployment.UpdateInsufficientCapacity(instanceARNs, insufficient)
 	}
 
-	// if deployment is already completed then we do not update
+	// if deployment is already completed then only the capacity of the instances is updated
 	// TODO: Figure out how we want to track failures in sub-deployments
 	if deployment.Status == types.DeploymentCompleted {
+		env, err = d.environment.UpdateDeployment(ctx, *env, *deployment)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error updating deployment with ID '%s'", deployment.ID)
+		}
 		return deployment, nil
 	}
 
-	failures := resp.Failures
-	if deployment.FailedInstances != nil {
-		failures = append(failures, deployment.FailedInstances...)
+	var updatedDeployment *types.Deployment
+	if env.HasClusterSelector() {
+		// the progress of the deployment is recorded per cluster
+		failures := append(startFailures, deployment.Clusters[cluster].FailedInstances...)
+		updatedDeployment, err = deployment.UpdateClusterInProgress(cluster, len(instanceARNs), failures)
+	} else {
+		failures := startFailures
+		if deployment.FailedInstances != nil {
+			failures = append(failures, deployment.FailedInstances...)
+		}
+		updatedDeployment, err = deployment.UpdateDeploymentInProgress(len(instanceARNs), failures)
 	}
 
-	updatedDeployment, err := deployment.UpdateDeploymentInProgress(len(instanceARNs), failures)
-
 	if err != nil {
 		return nil, errors.Wrapf(err, "Error updating deployment with ID '%s'", deployment.ID)
 	}
@@ -244,7 +352,15 @@ func (d deployment) GetCurrentDeployment(ctx context.Context, environmentName st
 		return nil, err
 	}
 
-	// if there is no in-progress deployment then we take the latest completed deployment
+	// a paused deployment keeps the instances as they are, so it is not replaced by the latest
+	// completed deployment
+	latest := env.LatestDeployment()
+	if latest != nil && latest.Status == types.DeploymentPaused {
+		return latest, nil
+	}
+
+	// if there is no in-progress deployment, including after the latest one was cancelled, then
+	// we take the latest completed deployment
 	deployments, err := env.SortDeploymentsReverseChronologically()
 	if err != nil {
 		return nil, err
@@ -286,8 +402,12 @@ func (d deployment) GetInProgressDeployment(ctx context.Context, environmentName
 
 	// If the status of the deployment is pending (this happens if we just set the
 	// in progress deployment id from the pending deployment id), update the status
-	// of the deployment to in progress
+	// of the deployment to in progress unless it is waiting for its start time or a
+	// maintenance window
 	if inProgress.Status == types.DeploymentPending {
+		if !env.CanStartDeployment(inProgress, time.Now()) {
+			return nil, nil
+		}
 		inProgress.Status = types.DeploymentInProgress
 	} else if inProgress.Status != types.DeploymentInProgress {
 		return nil, nil
@@ -308,3 +428,43 @@ func (d deployment) ListDeploymentsSortedReverseChronologically(ctx context.Cont
 
 	return env.SortDeploymentsReverseChronologically()
 }
+
+func (d deployment) PauseDeployment(ctx context.Context, environmentName string, id string) (*types.Deployment, error) {
+	return d.updateLatestDeployment(ctx, environmentName, id, func(env *types.Environment) error {
+		return env.PauseDeployment(id)
+	})
+}
+
+func (d deployment) ResumeDeployment(ctx context.Context, environmentName string, id string) (*types.Deployment, error) {
+	return d.updateLatestDeployment(ctx, environmentName, id, func(env *types.Environment) error {
+		return env.ResumeDeployment(id)
+	})
+}
+
+func (d deployment) CancelDeployment(ctx context.Context, environmentName string, id string, revert bool) (*types.Deployment, error) {
+	return d.updateLatestDeployment(ctx, environmentName, id, func(env *types.Environment) error {
+		_, err := env.CancelDeployment(id, revert)
+		return err
+	})
+}
+
+// updateLatestDeployment applies update to the environment and returns the updated deployment with the provided id
+func (d deployment) updateLatestDeployment(ctx context.Context, environmentName string, id string,
+	update func(env *types.Environment) error) (*types.Deployment, error) {
+
+	if len(environmentName) == 0 {
+		return nil, types.NewBadRequestError(errors.New("Environment name is missing"))
+	}
+
+	if len(id) == 0 {
+		return nil, types.NewBadRequestError(errors.New("Deployment ID is missing"))
+	}
+
+	env, err := d.environment.UpdateEnvironment(ctx, environmentName, update)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error updating deployment %s in environment %s", id, environmentName)
+	}
+
+	deployment := env.Deployments[id]
+	return &deployment, nil
+}
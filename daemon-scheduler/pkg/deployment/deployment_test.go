@@ -96,12 +96,12 @@ func (suite *DeploymentTestSuite) TestNewDeployment() {
 }
 
 func (suite *DeploymentTestSuite) TestCreateDeploymentEmptyEnvironmentName() {
-	_, err := suite.deployment.CreateDeployment(suite.ctx, "", suite.token)
+	_, err := suite.deployment.CreateDeployment(suite.ctx, "", suite.token, time.Time{})
 	assert.Error(suite.T(), err, "Expected an error when environment name is empty")
 }
 
 func (suite *DeploymentTestSuite) TestCreateDeploymentEmptyToken() {
-	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, "")
+	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, "", time.Time{})
 	assert.Error(suite.T(), err, "Expected an error when token is empty")
 }
 
@@ -109,7 +109,7 @@ func (suite *DeploymentTestSuite) TestCreateDeploymentGetEnvironmentFails() {
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).
 		Return(nil, errors.New("Get environment failed"))
 
-	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.token)
+	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.token, time.Time{})
 	assert.Error(suite.T(), err, "Expected an error when get environment fails")
 }
 
@@ -117,7 +117,7 @@ func (suite *DeploymentTestSuite) TestCreateDeploymentGetEnvironmentIsNil() {
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).
 		Return(nil, nil)
 
-	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.token)
+	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.token, time.Time{})
 	assert.Error(suite.T(), err, "Expected an error when get environment is nil")
 }
 
@@ -125,7 +125,7 @@ func (suite *DeploymentTestSuite) TestCreateDeploymentOutdatedToken() {
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).
 		Return(suite.environmentObject, nil)
 
-	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, "invalid")
+	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, "invalid", time.Time{})
 	assert.Error(suite.T(), err, "Expected an error when token is outdated")
 }
 
@@ -138,7 +138,7 @@ func (suite *DeploymentTestSuite) TestCreateDeploymentExistingToken() {
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).
 		Return(suite.environmentObject, nil)
 
-	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.token)
+	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.token, time.Time{})
 	assert.Error(suite.T(), err, "Expected an error when a deployment with the given token exists")
 }
 
@@ -148,7 +148,7 @@ func (suite *DeploymentTestSuite) TestCreateDeploymentGetInProgressDeploymentFai
 
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil).Times(2)
 
-	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.token)
+	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.token, time.Time{})
 	assert.Error(suite.T(), err, "Expected an error when getting in progress deployment fails")
 }
 
@@ -159,7 +159,7 @@ func (suite *DeploymentTestSuite) TestCreateDeploymentThereIsAnInProgressDeploym
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
 	suite.environment.EXPECT().AddPendingDeployment(suite.ctx, *suite.environmentObject, gomock.Any()).Times(0)
 
-	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.environmentObject.Token)
+	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.environmentObject.Token, time.Time{})
 	assert.Error(suite.T(), err, "Expected an error when there is an in-progress deployment")
 }
 
@@ -171,7 +171,7 @@ func (suite *DeploymentTestSuite) TestCreateDeploymentAddDeploymentFails() {
 			verifyDeployment(suite.T(), suite.deploymentObject, &d)
 		}).Return(nil, errors.New("Add deployment failed"))
 
-	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.environmentObject.Token)
+	_, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.environmentObject.Token, time.Time{})
 	assert.Error(suite.T(), err, "Expected an error when add deployment fails")
 }
 
@@ -183,7 +183,7 @@ func (suite *DeploymentTestSuite) TestCreateDeployment() {
 			verifyDeployment(suite.T(), suite.deploymentObject, &d)
 		}).Return(suite.environmentObject, nil)
 
-	d, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.environmentObject.Token)
+	d, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.environmentObject.Token, time.Time{})
 	assert.Nil(suite.T(), err, "Unexpected error when creating a deployment")
 	verifyDeployment(suite.T(), suite.deploymentObject, d)
 }
@@ -252,21 +252,21 @@ func (suite *DeploymentTestSuite) TestGetDeployment() {
 }
 
 func (suite *DeploymentTestSuite) TestCreateSubDeploymentEmptyEnvironmentName() {
-	_, err := suite.deployment.CreateSubDeployment(suite.ctx, "", suite.instanceARNs)
+	_, err := suite.deployment.CreateSubDeployment(suite.ctx, "", cluster1, suite.instanceARNs)
 	assert.NotNil(suite.T(), err, "Expected an error creating a sub-deployment without an environment name")
 }
 
 func (suite *DeploymentTestSuite) TestCreateSubDeploymentGetEnvironmentFails() {
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(nil, errors.New("Get environment failed"))
 
-	_, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, suite.instanceARNs)
+	_, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
 	assert.NotNil(suite.T(), err, "Expected an error creating a sub-deployment when get environment fails")
 }
 
 func (suite *DeploymentTestSuite) TestCreateSubDeploymentGetEnvironmentReturnsNil() {
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(nil, nil)
 
-	_, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, suite.instanceARNs)
+	_, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
 	assert.NotNil(suite.T(), err, "Expected an error creating a sub-deployment when get environment returns nil")
 }
 
@@ -276,7 +276,7 @@ func (suite *DeploymentTestSuite) TestCreateSubDeploymentGetCurrentDeploymentRet
 
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil).Times(2)
 
-	_, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, suite.instanceARNs)
+	_, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
 	assert.NotNil(suite.T(), err, "Expected an error creating a sub-deployment when get in progress deployment returns an error")
 }
 
@@ -287,7 +287,7 @@ func (suite *DeploymentTestSuite) TestCreateSubDeploymentGetCurrentDeploymentRet
 
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil).Times(3)
 
-	_, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, suite.instanceARNs)
+	_, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
 	assert.NotNil(suite.T(), err, "Expected an error creating a sub-deployment when get in progress deployment returns an error")
 }
 
@@ -300,13 +300,39 @@ func (suite *DeploymentTestSuite) TestCreateSubDeploymentStartTasksFails() {
 	env.Deployments[inprogressDeployment.ID] = *inprogressDeployment
 
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(2)
-	suite.ecs.EXPECT().StartTask(env.Cluster, suite.instanceARNs, inprogressDeployment.ID, inprogressDeployment.TaskDefinition).
+	suite.expectCapacityCheck(env.Cluster, nil, nil)
+	suite.ecs.EXPECT().StartTask(env.Cluster, suite.instanceARNs, inprogressDeployment.ID, inprogressDeployment.TaskDefinition, nil, nil).
 		Return(nil, errors.New("Error starting tasks"))
 
-	_, err = suite.deployment.CreateSubDeployment(suite.ctx, environmentName, suite.instanceARNs)
+	_, err = suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
 	assert.NotNil(suite.T(), err, "Expected an error creating a sub-deployment when start tasks fails")
 }
 
+func (suite *DeploymentTestSuite) TestCreateSubDeploymentWithTaskOverrides() {
+	inprogressDeployment, err := suite.deploymentObject.UpdateDeploymentInProgress(0, nil)
+	assert.Nil(suite.T(), err, "Unexpected error when moving deployment to in-progress")
+	inprogressDeployment.TaskOverrides = types.TaskOverrides{
+		ContainerOverrides: []types.ContainerOverride{{Name: "agent", Environment: map[string]string{"LEVEL": "debug"}}},
+		ExecutionRoleARN:   "arn:aws:iam::123456789012:role/execution",
+		Memory:             "1024",
+		Tags:               map[string]string{"team": "logs"},
+	}
+
+	env := suite.environmentObject
+	env.InProgressDeploymentID = inprogressDeployment.ID
+	env.Deployments[inprogressDeployment.ID] = *inprogressDeployment
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(2)
+	suite.expectCapacityCheck(env.Cluster, nil, nil)
+	suite.ecs.EXPECT().StartTask(env.Cluster, suite.instanceARNs, inprogressDeployment.ID, inprogressDeployment.TaskDefinition,
+		inprogressDeployment.TaskOverrides.ECSOverride(), []*ecs.Tag{{Key: aws.String("team"), Value: aws.String("logs")}}).
+		Return(&ecs.StartTaskOutput{}, nil)
+	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *env, gomock.Any()).Return(env, nil)
+
+	_, err = suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
+	assert.Nil(suite.T(), err, "Unexpected error creating a sub-deployment with task overrides")
+}
+
 func (suite *DeploymentTestSuite) TestCreateSubDeploymentUpdateDeploymentFails() {
 	inprogressDeployment, err := types.NewDeployment(suite.environmentObject.DesiredTaskDefinition, suite.environmentObject.Token)
 	assert.Nil(suite.T(), err, "Deployment creation failed")
@@ -318,7 +344,8 @@ func (suite *DeploymentTestSuite) TestCreateSubDeploymentUpdateDeploymentFails()
 	env.Deployments[inprogressDeployment.ID] = *inprogressDeployment
 
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(2)
-	suite.ecs.EXPECT().StartTask(env.Cluster, suite.instanceARNs, inprogressDeployment.ID, inprogressDeployment.TaskDefinition).Return(suite.startTaskOutput, nil)
+	suite.expectCapacityCheck(env.Cluster, nil, nil)
+	suite.ecs.EXPECT().StartTask(env.Cluster, suite.instanceARNs, inprogressDeployment.ID, inprogressDeployment.TaskDefinition, nil, nil).Return(suite.startTaskOutput, nil)
 
 	updatedDeployment := *inprogressDeployment
 	updatedDeployment.DesiredTaskCount = len(suite.instanceARNs)
@@ -331,7 +358,7 @@ func (suite *DeploymentTestSuite) TestCreateSubDeploymentUpdateDeploymentFails()
 			verifyDeployment(suite.T(), &updatedDeployment, &d)
 		}).Return(nil, errors.New("Error updating deployment"))
 
-	_, err = suite.deployment.CreateSubDeployment(suite.ctx, environmentName, suite.instanceARNs)
+	_, err = suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
 	assert.NotNil(suite.T(), err, "Expected an error creating a sub-deployment when update deployment fails")
 }
 
@@ -345,7 +372,8 @@ func (suite *DeploymentTestSuite) TestCreateSubDeployment() {
 	env.Deployments[inprogressDeployment.ID] = *inprogressDeployment
 
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(2)
-	suite.ecs.EXPECT().StartTask(env.Cluster, suite.instanceARNs, inprogressDeployment.ID, inprogressDeployment.TaskDefinition).Return(suite.startTaskOutput, nil)
+	suite.expectCapacityCheck(env.Cluster, nil, nil)
+	suite.ecs.EXPECT().StartTask(env.Cluster, suite.instanceARNs, inprogressDeployment.ID, inprogressDeployment.TaskDefinition, nil, nil).Return(suite.startTaskOutput, nil)
 
 	updatedDeployment := *inprogressDeployment
 	updatedDeployment.DesiredTaskCount = len(suite.instanceARNs)
@@ -358,7 +386,7 @@ func (suite *DeploymentTestSuite) TestCreateSubDeployment() {
 			verifyDeployment(suite.T(), &updatedDeployment, &d)
 		}).Return(env, nil)
 
-	d, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, suite.instanceARNs)
+	d, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
 	assert.Nil(suite.T(), err, "Unexpected error creating a sub-deployment")
 	verifyDeployment(suite.T(), &updatedDeployment, d)
 }
@@ -372,13 +400,282 @@ func (suite *DeploymentTestSuite) TestCreateSubDeploymentWithCompletedDeployment
 	env.Deployments[currentDeployment.ID] = *currentDeployment
 
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(3)
-	suite.ecs.EXPECT().StartTask(env.Cluster, suite.instanceARNs, currentDeployment.ID, currentDeployment.TaskDefinition).Return(suite.startTaskOutput, nil)
+	suite.expectCapacityCheck(env.Cluster, nil, nil)
+	suite.ecs.EXPECT().StartTask(env.Cluster, suite.instanceARNs, currentDeployment.ID, currentDeployment.TaskDefinition, nil, nil).Return(suite.startTaskOutput, nil)
+
+	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *env, gomock.Any()).Return(env, nil)
 
-	d, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, suite.instanceARNs)
+	d, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
 	assert.Nil(suite.T(), err, "Unexpected error creating a sub-deployment")
 	verifyDeployment(suite.T(), currentDeployment, d)
 }
 
+func (suite *DeploymentTestSuite) TestCreateSubDeploymentClusterNotTargeted() {
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+
+	_, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster2, suite.instanceARNs)
+	assert.Error(suite.T(), err, "Expected an error creating a sub-deployment in a cluster the environment is not deployed to")
+}
+
+func (suite *DeploymentTestSuite) TestCreateSubDeploymentClusterNotTagged() {
+	env, err := types.NewEnvironmentForClusters(environmentName, taskDefinition, types.ClusterSelector{Tags: map[string]string{"team": "blox"}})
+	assert.Nil(suite.T(), err, "Unexpected error creating an environment with a cluster selector")
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil)
+	suite.ecs.EXPECT().DescribeClusterTags(cluster2).Return(map[string]string{"team": "other"}, nil)
+
+	_, err = suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster2, suite.instanceARNs)
+	assert.Error(suite.T(), err, "Expected an error creating a sub-deployment in a cluster without the selected tags")
+}
+
+func (suite *DeploymentTestSuite) TestCreateSubDeploymentClusterSelector() {
+	env, err := types.NewEnvironmentForClusters(environmentName, taskDefinition, types.ClusterSelector{Clusters: []string{cluster1, cluster2}})
+	assert.Nil(suite.T(), err, "Unexpected error creating an environment with a cluster selector")
+
+	inprogressDeployment, err := types.NewDeployment(env.DesiredTaskDefinition, env.Token)
+	assert.Nil(suite.T(), err, "Deployment creation failed")
+	inprogressDeployment, err = inprogressDeployment.UpdateClusterInProgress(cluster1, 1, nil)
+	assert.Nil(suite.T(), err, "Unexpected error updating the deployment in a cluster")
+	env.InProgressDeploymentID = inprogressDeployment.ID
+	env.Deployments[inprogressDeployment.ID] = *inprogressDeployment
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(2)
+	suite.expectCapacityCheck(cluster2, nil, nil)
+	suite.ecs.EXPECT().StartTask(cluster2, suite.instanceARNs, inprogressDeployment.ID, inprogressDeployment.TaskDefinition, nil, nil).Return(suite.startTaskOutput, nil)
+	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *env, gomock.Any()).Return(env, nil)
+
+	d, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster2, suite.instanceARNs)
+	assert.Nil(suite.T(), err, "Unexpected error creating a sub-deployment")
+	assert.Exactly(suite.T(), 1+len(suite.instanceARNs), d.DesiredTaskCount, "Expected the desired tasks of every cluster")
+	assert.Exactly(suite.T(), types.DeploymentHealthy, d.Clusters[cluster1].Health, "Expected the first cluster to be healthy")
+	assert.Exactly(suite.T(), len(suite.instanceARNs), d.Clusters[cluster2].DesiredTaskCount, "Expected the desired tasks of the cluster")
+	assert.Exactly(suite.T(), suite.startTaskOutput.Failures, d.Clusters[cluster2].FailedInstances, "Expected the failures in the cluster")
+}
+
+func (suite *DeploymentTestSuite) TestCreateSubDeploymentInsufficientCapacity() {
+	env, inprogressDeployment := suite.inProgressEnvironment()
+	instances := []*models.ContainerInstance{
+		instanceWithResources(instanceARN1, "100", "2048", "22"),
+		instanceWithResources(instanceARN2, "1024", "2048", "22"),
+	}
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(2)
+	suite.expectCapacityCheck(cluster1, instances, []types.Environment{*env})
+	suite.ecs.EXPECT().StartTask(cluster1, []*string{aws.String(instanceARN2)}, inprogressDeployment.ID, inprogressDeployment.TaskDefinition, nil, nil).
+		Return(&ecs.StartTaskOutput{}, nil)
+	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *env, gomock.Any()).Return(env, nil)
+
+	d, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
+	assert.Nil(suite.T(), err, "Unexpected error creating a sub-deployment")
+	assert.Exactly(suite.T(), types.DeploymentHealthy, d.Health, "Expected instances short of capacity not to be failures")
+	assert.Empty(suite.T(), d.FailedInstances, "Expected instances short of capacity not to be failures")
+	assert.Equal(suite.T(), []*ecs.Failure{{Arn: aws.String(instanceARN1), Reason: aws.String(types.InsufficientCPUReason)}},
+		d.InsufficientCapacity, "Expected the instance short of CPU to be recorded")
+}
+
+func (suite *DeploymentTestSuite) TestCreateSubDeploymentInsufficientCapacityForOverriddenMemory() {
+	suite.deploymentObject.TaskOverrides = types.TaskOverrides{Memory: "2 GB"}
+	env, inprogressDeployment := suite.inProgressEnvironment()
+	instances := []*models.ContainerInstance{
+		instanceWithResources(instanceARN1, "1024", "1024", "22"),
+		instanceWithResources(instanceARN2, "1024", "4096", "22"),
+	}
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(2)
+	suite.expectCapacityCheck(cluster1, instances, []types.Environment{*env})
+	suite.ecs.EXPECT().StartTask(cluster1, []*string{aws.String(instanceARN2)}, inprogressDeployment.ID, inprogressDeployment.TaskDefinition,
+		&ecs.TaskOverride{Memory: aws.String("2 GB")}, nil).Return(&ecs.StartTaskOutput{}, nil)
+	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *env, gomock.Any()).Return(env, nil)
+
+	d, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
+	assert.Nil(suite.T(), err, "Unexpected error creating a sub-deployment")
+	assert.Equal(suite.T(), []*ecs.Failure{{Arn: aws.String(instanceARN1), Reason: aws.String(types.InsufficientMemoryReason)}},
+		d.InsufficientCapacity, "Expected the instance short of the overridden memory to be recorded")
+}
+
+func (suite *DeploymentTestSuite) TestCreateSubDeploymentStartTaskCapacityFailure() {
+	env, inprogressDeployment := suite.inProgressEnvironment()
+	failure := &ecs.Failure{Arn: aws.String(instanceARN1), Reason: aws.String(types.InsufficientMemoryReason)}
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(2)
+	suite.expectCapacityCheck(cluster1, nil, nil)
+	suite.ecs.EXPECT().StartTask(cluster1, suite.instanceARNs, inprogressDeployment.ID, inprogressDeployment.TaskDefinition, nil, nil).
+		Return(&ecs.StartTaskOutput{Failures: []*ecs.Failure{failure}}, nil)
+	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *env, gomock.Any()).Return(env, nil)
+
+	d, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
+	assert.Nil(suite.T(), err, "Unexpected error creating a sub-deployment")
+	assert.Empty(suite.T(), d.FailedInstances, "Expected ECS capacity failures not to be failures")
+	assert.Equal(suite.T(), []*ecs.Failure{failure}, d.InsufficientCapacity, "Expected the ECS capacity failure to be recorded")
+}
+
+func (suite *DeploymentTestSuite) TestCreateSubDeploymentStopsLowerPriorityTasks() {
+	env, inprogressDeployment := suite.inProgressEnvironment()
+	env.CapacityPolicy = types.CapacityPolicy{Priority: 10, StopLowerPriorityTasks: true}
+	lower, err := types.NewEnvironment(environmentName2, taskDefinition2, cluster1)
+	assert.Nil(suite.T(), err, "Unexpected error creating an environment")
+	lower.CapacityPolicy.Priority = 1
+	lower.Deployments[deploymentID] = types.Deployment{ID: deploymentID}
+
+	instances := []*models.ContainerInstance{instanceWithResources(instanceARN1, "1024", "0", "")}
+	tasks := []*models.Task{{
+		TaskARN:              aws.String(taskARN2),
+		TaskDefinitionARN:    aws.String(taskDefinition2),
+		ContainerInstanceARN: aws.String(instanceARN1),
+		StartedBy:            deploymentID,
+		DesiredStatus:        aws.String(runningTaskStatus),
+	}}
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(2)
+	suite.expectCapacityCheck(cluster1, instances, []types.Environment{*env, *lower})
+	suite.clusterState.EXPECT().ListTasks(cluster1).Return(tasks, nil)
+	suite.ecs.EXPECT().DescribeTaskDefinition(aws.String(taskDefinition2)).Return(taskDefinitionWithResources(256, 512), nil)
+	suite.ecs.EXPECT().StopTask(cluster1, taskARN2, types.StopReasonEvicted).Return(nil)
+	suite.ecs.EXPECT().StartTask(cluster1, []*string{aws.String(instanceARN2)}, inprogressDeployment.ID, inprogressDeployment.TaskDefinition, nil, nil).
+		Return(&ecs.StartTaskOutput{}, nil)
+	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *env, gomock.Any()).Return(env, nil)
+
+	d, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
+	assert.Nil(suite.T(), err, "Unexpected error creating a sub-deployment")
+	assert.Equal(suite.T(), []*ecs.Failure{{Arn: aws.String(instanceARN1), Reason: aws.String(types.StoppingTasksReason)}},
+		d.InsufficientCapacity, "Expected the instance to be reserved while the lower priority task stops")
+}
+
+func (suite *DeploymentTestSuite) TestCreateSubDeploymentInstanceReserved() {
+	env, inprogressDeployment := suite.inProgressEnvironment()
+	higher, err := types.NewEnvironment(environmentName2, taskDefinition2, cluster1)
+	assert.Nil(suite.T(), err, "Unexpected error creating an environment")
+	higher.CapacityPolicy = types.CapacityPolicy{Priority: 10, StopLowerPriorityTasks: true}
+	higher.InProgressDeploymentID = deploymentID
+	higher.Deployments[deploymentID] = types.Deployment{
+		ID:                   deploymentID,
+		InsufficientCapacity: []*ecs.Failure{{Arn: aws.String(instanceARN1), Reason: aws.String(types.StoppingTasksReason)}},
+	}
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(2)
+	suite.expectCapacityCheck(cluster1, nil, []types.Environment{*env, *higher})
+	suite.ecs.EXPECT().StartTask(cluster1, []*string{aws.String(instanceARN2)}, inprogressDeployment.ID, inprogressDeployment.TaskDefinition, nil, nil).
+		Return(&ecs.StartTaskOutput{}, nil)
+	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *env, gomock.Any()).Return(env, nil)
+
+	d, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
+	assert.Nil(suite.T(), err, "Unexpected error creating a sub-deployment")
+	assert.Equal(suite.T(), []*ecs.Failure{{Arn: aws.String(instanceARN1), Reason: aws.String(types.ReservedReason)}},
+		d.InsufficientCapacity, "Expected the instance reserved by a higher priority environment to be skipped")
+}
+
+func (suite *DeploymentTestSuite) TestCreateSubDeploymentReusesCapacityLookups() {
+	env, inprogressDeployment := suite.inProgressEnvironment()
+	taskDefinitionObject := taskDefinitionWithResources(256, 512)
+	taskDefinitionObject.TaskDefinitionArn = aws.String(taskDefinition)
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(env, nil).Times(4)
+	suite.ecs.EXPECT().DescribeTaskDefinition(aws.String(taskDefinition)).Return(taskDefinitionObject, nil).Times(1)
+	suite.environment.EXPECT().ListEnvironments(suite.ctx).Return([]types.Environment{*env}, nil).Times(1)
+	suite.clusterState.EXPECT().ListInstances(cluster1).Return(nil, nil).Times(2)
+	suite.ecs.EXPECT().StartTask(cluster1, suite.instanceARNs, inprogressDeployment.ID, inprogressDeployment.TaskDefinition, nil, nil).
+		Return(&ecs.StartTaskOutput{}, nil).Times(2)
+	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *env, gomock.Any()).Return(env, nil).Times(2)
+
+	for i := 0; i < 2; i++ {
+		_, err := suite.deployment.CreateSubDeployment(suite.ctx, environmentName, cluster1, suite.instanceARNs)
+		assert.Nil(suite.T(), err, "Unexpected error creating a sub-deployment")
+	}
+}
+
+func (suite *DeploymentTestSuite) TestListEnvironmentsAfterReservingInstance() {
+	d := suite.deployment.(deployment)
+	suite.environment.EXPECT().ListEnvironments(suite.ctx).Return([]types.Environment{}, nil).Times(2)
+
+	_, err := d.listEnvironments(suite.ctx)
+	assert.Nil(suite.T(), err, "Unexpected error listing environments")
+	_, err = d.listEnvironments(suite.ctx)
+	assert.Nil(suite.T(), err, "Unexpected error listing cached environments")
+
+	d.cache.forgetEnvironments()
+	_, err = d.listEnvironments(suite.ctx)
+	assert.Nil(suite.T(), err, "Unexpected error listing environments again")
+}
+
+func (suite *DeploymentTestSuite) TestPlanCapacityDoesNotStopTasks() {
+	env := suite.environmentObject
+	env.CapacityPolicy = types.CapacityPolicy{Priority: 10, StopLowerPriorityTasks: true}
+	lower, err := types.NewEnvironment(environmentName2, taskDefinition2, cluster1)
+	assert.Nil(suite.T(), err, "Unexpected error creating an environment")
+	lower.CapacityPolicy.Priority = 1
+	lower.Deployments[deploymentID] = types.Deployment{ID: deploymentID}
+
+	instances := []*models.ContainerInstance{
+		instanceWithResources(instanceARN1, "1024", "0", ""),
+		instanceWithResources(instanceARN2, "0", "1024", ""),
+	}
+	tasks := []*models.Task{{
+		TaskARN:              aws.String(taskARN2),
+		TaskDefinitionARN:    aws.String(taskDefinition2),
+		ContainerInstanceARN: aws.String(instanceARN1),
+		StartedBy:            deploymentID,
+		DesiredStatus:        aws.String(runningTaskStatus),
+	}}
+
+	suite.expectCapacityCheck(cluster1, instances, []types.Environment{*env, *lower})
+	suite.clusterState.EXPECT().ListTasks(cluster1).Return(tasks, nil)
+	suite.ecs.EXPECT().DescribeTaskDefinition(aws.String(taskDefinition2)).Return(taskDefinitionWithResources(256, 512), nil)
+
+	plan, err := suite.deployment.PlanCapacity(suite.ctx, *env, cluster1, []string{instanceARN1, instanceARN2})
+	assert.Nil(suite.T(), err, "Unexpected error planning capacity")
+	assert.Empty(suite.T(), plan.Available, "Expected no instance to have room without stopping tasks")
+	assert.Equal(suite.T(), types.StoppingTasksReason, plan.Insufficient[instanceARN1],
+		"Expected the instance to be freed by stopping the lower priority task")
+	assert.NotEmpty(suite.T(), plan.Insufficient[instanceARN2], "Expected the instance short of CPU to be skipped")
+	assert.Equal(suite.T(), []types.PlannedTask{{
+		TaskARN:      taskARN2,
+		InstanceARN:  instanceARN1,
+		Environment:  environmentName2,
+		DeploymentID: deploymentID,
+	}}, plan.StoppedTasks, "Expected the lower priority task to be planned to stop")
+}
+
+// inProgressEnvironment returns the environment of the suite with an in-progress deployment
+func (suite *DeploymentTestSuite) inProgressEnvironment() (*types.Environment, *types.Deployment) {
+	inprogressDeployment, err := suite.deploymentObject.UpdateDeploymentInProgress(0, nil)
+	assert.Nil(suite.T(), err, "Unexpected error when moving deployment to in-progress")
+
+	env := suite.environmentObject
+	env.InProgressDeploymentID = inprogressDeployment.ID
+	env.Deployments[inprogressDeployment.ID] = *inprogressDeployment
+	return env, inprogressDeployment
+}
+
+// expectCapacityCheck expects the capacity of the instances of the cluster to be checked for the
+// task definition of the suite
+func (suite *DeploymentTestSuite) expectCapacityCheck(cluster string, instances []*models.ContainerInstance,
+	environments []types.Environment) {
+
+	suite.ecs.EXPECT().DescribeTaskDefinition(aws.String(taskDefinition)).Return(taskDefinitionWithResources(256, 512), nil)
+	suite.clusterState.EXPECT().ListInstances(cluster).Return(instances, nil)
+	suite.environment.EXPECT().ListEnvironments(suite.ctx).Return(environments, nil)
+}
+
+func taskDefinitionWithResources(cpu int64, memory int64) *ecs.TaskDefinition {
+	return &ecs.TaskDefinition{
+		ContainerDefinitions: []*ecs.ContainerDefinition{{
+			Cpu:    aws.Int64(cpu),
+			Memory: aws.Int64(memory),
+		}},
+	}
+}
+
+func instanceWithResources(instanceARN string, cpu string, memory string, ports string) *models.ContainerInstance {
+	return &models.ContainerInstance{
+		ContainerInstanceARN: aws.String(instanceARN),
+		RemainingResources: []*models.ContainerInstanceResource{
+			{Name: aws.String(cpuResource), Value: aws.String(cpu)},
+			{Name: aws.String(memoryResource), Value: aws.String(memory)},
+			{Name: aws.String(portsResource), Value: aws.String(ports)},
+		},
+	}
+}
+
 func createContainerInstances(instanceARNs []*string) []*models.ContainerInstance {
 	containerInstances := make([]*models.ContainerInstance, 0, len(instanceARNs))
 	for _, i := range instanceARNs {
@@ -439,6 +736,27 @@ func (suite *DeploymentTestSuite) TestGetCurrentDeploymentCompleted() {
 	assert.Exactly(suite.T(), deployment.ID, d.ID, "Expected the deployment to match the completed deployment")
 }
 
+func (suite *DeploymentTestSuite) TestGetCurrentDeploymentCancelled() {
+	environment, err := types.NewEnvironment("TestGetCancelledDeployment", taskDefinition, cluster1)
+	assert.Nil(suite.T(), err, "Unexpected error when creating environment")
+	completed, err := types.NewDeployment(taskDefinition, uuid.NewRandom().String())
+	assert.Nil(suite.T(), err, "Unexpected error when creating deployment")
+	completed.Status = types.DeploymentCompleted
+	completed.StartTime = time.Now().Add(-time.Hour)
+	environment.Deployments[completed.ID] = *completed
+	cancelled, err := types.NewDeployment(taskDefinition, uuid.NewRandom().String())
+	assert.Nil(suite.T(), err, "Unexpected error when creating deployment")
+	cancelled.Status = types.DeploymentCancelled
+	environment.Deployments[cancelled.ID] = *cancelled
+	environment.PendingDeploymentID = cancelled.ID
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environment.Name).Return(environment, nil).Times(2)
+
+	d, err := suite.deployment.GetCurrentDeployment(suite.ctx, environment.Name)
+	assert.Nil(suite.T(), err, "Unexpected error when calling GetCurrentDeployment")
+	assert.Exactly(suite.T(), completed.ID, d.ID, "Expected the latest completed deployment after a cancel")
+}
+
 func (suite *DeploymentTestSuite) TestGetCurrentDeploymentEmpty() {
 	suite.environmentObject.PendingDeploymentID = ""
 	suite.environmentObject.InProgressDeploymentID = ""
@@ -609,3 +927,130 @@ func (suite *DeploymentTestSuite) TestListDeploymentsSortedReverseChronologicall
 	assert.Exactly(suite.T(), *deployment2, deployments[0], "Expected deployments to match")
 	assert.Exactly(suite.T(), *deployment1, deployments[1], "Expected deployments to match")
 }
+
+func (suite *DeploymentTestSuite) TestGetCurrentDeploymentPaused() {
+	suite.deploymentObject.Status = types.DeploymentPaused
+	suite.environmentObject.Deployments[suite.deploymentObject.ID] = *suite.deploymentObject
+	suite.environmentObject.PendingDeploymentID = suite.deploymentObject.ID
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, suite.environmentObject.Name).Return(suite.environmentObject, nil).Times(2)
+
+	d, err := suite.deployment.GetCurrentDeployment(suite.ctx, suite.environmentObject.Name)
+	assert.Nil(suite.T(), err, "Unexpected error when calling GetCurrentDeployment")
+	assert.Exactly(suite.T(), suite.deploymentObject.ID, d.ID, "Expected the paused deployment to stay the current deployment")
+}
+
+func (suite *DeploymentTestSuite) TestCreateDeploymentPausedDeployment() {
+	suite.deploymentObject.Status = types.DeploymentPaused
+	suite.deploymentObject.Token = "earlier-token"
+	suite.environmentObject.Deployments[suite.deploymentObject.ID] = *suite.deploymentObject
+	suite.environmentObject.PendingDeploymentID = suite.deploymentObject.ID
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, suite.environmentObject.Name).Return(suite.environmentObject, nil).Times(2)
+
+	_, err := suite.deployment.CreateDeployment(suite.ctx, suite.environmentObject.Name, suite.environmentObject.Token, time.Time{})
+	assert.Error(suite.T(), err, "Expected an error when the latest deployment is paused")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the latest deployment is paused")
+}
+
+func (suite *DeploymentTestSuite) TestCreateDeploymentEnvironmentDeleting() {
+	suite.environmentObject.StartDeleting(time.Now().Add(time.Minute))
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, suite.environmentObject.Name).Return(suite.environmentObject, nil)
+
+	_, err := suite.deployment.CreateDeployment(suite.ctx, suite.environmentObject.Name, suite.environmentObject.Token, time.Time{})
+	assert.Error(suite.T(), err, "Expected an error when the environment is being deleted")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the environment is being deleted")
+}
+
+func (suite *DeploymentTestSuite) TestCreateDeploymentNotBefore() {
+	notBefore := time.Now().Add(time.Hour).UTC()
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil).Times(2)
+	suite.environment.EXPECT().AddPendingDeployment(suite.ctx, *suite.environmentObject, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, d types.Deployment) {
+			assert.Exactly(suite.T(), notBefore, d.NotBefore, "Expected the deployment to wait for its start time")
+		}).Return(suite.environmentObject, nil)
+
+	d, err := suite.deployment.CreateDeployment(suite.ctx, environmentName, suite.environmentObject.Token, notBefore)
+	assert.Nil(suite.T(), err, "Unexpected error when creating a scheduled deployment")
+	assert.Exactly(suite.T(), types.DeploymentPending, d.Status, "Expected the deployment to be pending")
+}
+
+func (suite *DeploymentTestSuite) TestCreateDeploymentScheduledDeploymentWaiting() {
+	suite.deploymentObject.NotBefore = time.Now().Add(time.Hour)
+	suite.deploymentObject.Token = "earlier-token"
+	suite.environmentObject.Deployments[suite.deploymentObject.ID] = *suite.deploymentObject
+	suite.environmentObject.PendingDeploymentID = suite.deploymentObject.ID
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, suite.environmentObject.Name).Return(suite.environmentObject, nil).Times(2)
+
+	_, err := suite.deployment.CreateDeployment(suite.ctx, suite.environmentObject.Name, suite.environmentObject.Token, time.Time{})
+	assert.Error(suite.T(), err, "Expected an error when the latest deployment is waiting to start")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the latest deployment is waiting to start")
+}
+
+func (suite *DeploymentTestSuite) TestGetCurrentDeploymentScheduledDeploymentWaiting() {
+	completed, err := types.NewDeployment(taskDefinition, uuid.NewRandom().String())
+	assert.Nil(suite.T(), err, "Unexpected error when creating deployment")
+	completed.Status = types.DeploymentCompleted
+	completed.StartTime = time.Now().Add(-time.Hour)
+	suite.environmentObject.Deployments[completed.ID] = *completed
+
+	suite.deploymentObject.NotBefore = time.Now().Add(time.Hour)
+	suite.environmentObject.Deployments[suite.deploymentObject.ID] = *suite.deploymentObject
+	suite.environmentObject.PendingDeploymentID = suite.deploymentObject.ID
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, suite.environmentObject.Name).Return(suite.environmentObject, nil).Times(2)
+
+	d, err := suite.deployment.GetCurrentDeployment(suite.ctx, suite.environmentObject.Name)
+	assert.Nil(suite.T(), err, "Unexpected error when calling GetCurrentDeployment")
+	assert.Exactly(suite.T(), completed.ID, d.ID, "Expected the completed deployment until the scheduled one starts")
+}
+
+func (suite *DeploymentTestSuite) TestGetInProgressDeploymentOutsideMaintenanceWindow() {
+	suite.environmentObject.MaintenanceWindows = []types.MaintenanceWindow{{
+		// opens on the 1st of January only, and closes before the 2nd
+		Schedule: "0 0 1 1 *",
+		Duration: time.Minute,
+	}}
+	suite.environmentObject.Deployments[suite.deploymentObject.ID] = *suite.deploymentObject
+	suite.environmentObject.PendingDeploymentID = suite.deploymentObject.ID
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, suite.environmentObject.Name).Return(suite.environmentObject, nil)
+
+	d, err := suite.deployment.GetInProgressDeployment(suite.ctx, suite.environmentObject.Name)
+	assert.Nil(suite.T(), err, "Unexpected error when calling GetInProgressDeployment")
+	assert.Nil(suite.T(), d, "Expected the pending deployment to wait for a maintenance window")
+}
+
+func (suite *DeploymentTestSuite) TestPauseDeployment() {
+	suite.environmentObject.Deployments[suite.deploymentObject.ID] = *suite.deploymentObject
+	suite.environmentObject.PendingDeploymentID = suite.deploymentObject.ID
+
+	suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+			assert.Nil(suite.T(), update(suite.environmentObject), "Unexpected error pausing the deployment")
+		}).Return(suite.environmentObject, nil)
+
+	d, err := suite.deployment.PauseDeployment(suite.ctx, environmentName, suite.deploymentObject.ID)
+	assert.Nil(suite.T(), err, "Unexpected error when pausing the deployment")
+	assert.Exactly(suite.T(), types.DeploymentPaused, d.Status, "Expected the deployment to be paused")
+}
+
+func (suite *DeploymentTestSuite) TestCancelDeploymentUpdateEnvironmentFails() {
+	suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).
+		Return(nil, types.NewConflictError(errors.New("Deployment has already completed")))
+
+	_, err := suite.deployment.CancelDeployment(suite.ctx, environmentName, suite.deploymentObject.ID, false)
+	_, ok := errors.Cause(err).(types.ConflictError)
+	assert.True(suite.T(), ok, "Expected the conflict to be returned when cancelling the deployment fails")
+}
+
+func (suite *DeploymentTestSuite) TestCancelDeploymentMissingID() {
+	_, err := suite.deployment.CancelDeployment(suite.ctx, environmentName, "", false)
+	assert.Error(suite.T(), err, "Expected an error when the deployment ID is missing")
+}
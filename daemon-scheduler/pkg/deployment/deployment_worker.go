@@ -15,6 +15,9 @@ package deployment
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
@@ -28,10 +31,17 @@ const (
 	TaskPending = "PENDING"
 )
 
+var errDeploymentNotInProgress = errors.New("Deployment is no longer in progress")
+
 type DeploymentWorker interface {
 	// UpdateInProgressDeployment checks for in-progress deployments and moves them to complete when
-	// the tasks started by the deployment have moved out of pending status
+	// the tasks started by the deployment have moved out of pending status. Deployments that fail
+	// the rollback policy of their environment are marked failed and rolled back instead.
 	UpdateInProgressDeployment(ctx context.Context, environmentName string) (*types.Deployment, error)
+	// StartPendingDeployment moves the pending deployment of the environment to in-progress once
+	// its start time has passed and a maintenance window of the environment is open. It returns
+	// the started deployment, or nil if there is none.
+	StartPendingDeployment(ctx context.Context, environmentName string) (*types.Deployment, error)
 }
 
 type deploymentWorker struct {
@@ -75,17 +85,30 @@ func (d deploymentWorker) UpdateInProgressDeployment(ctx context.Context,
 		return nil, errors.Wrapf(err, "Error finding environment with name %s", environmentName)
 	}
 
-	if environment == nil {
+	// the tasks of an environment being deleted are being stopped, so its deployment is not
+	// checked or rolled back
+	if environment == nil || environment.IsDeleting() {
 		return nil, nil
 	}
 
-	taskProgress, err := d.checkDeploymentTaskProgress(environment, deployment)
+	taskProgress, clusterProgress, err := d.checkDeploymentTaskProgress(environment, deployment)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Error checking deployment %s progress in environment %s",
 			deployment.ID, environment.Name)
 	}
 
-	updatedDeployment, err := d.updateDeployment(ctx, environment, deployment, taskProgress)
+	if environment.RollbackPolicy.IsEnabled() {
+		reason, err := d.checkRollbackPolicy(environment, deployment, taskProgress, clusterProgress)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error checking the rollback policy of deployment %s in environment %s",
+				deployment.ID, environment.Name)
+		}
+		if reason != "" {
+			return d.rollBackDeployment(ctx, environment, deployment, reason)
+		}
+	}
+
+	updatedDeployment, err := d.updateDeployment(ctx, environment, deployment, taskProgress, clusterProgress)
 	if err != nil {
 		return nil, err
 	}
@@ -93,49 +116,222 @@ func (d deploymentWorker) UpdateInProgressDeployment(ctx context.Context,
 	return updatedDeployment, nil
 }
 
+func (d deploymentWorker) StartPendingDeployment(ctx context.Context,
+	environmentName string) (*types.Deployment, error) {
+
+	if environmentName == "" {
+		return nil, errors.New("Environment name is missing")
+	}
+
+	environment, err := d.environment.GetEnvironment(ctx, environmentName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error finding environment with name %s", environmentName)
+	}
+
+	if environment == nil || environment.IsDeleting() {
+		return nil, nil
+	}
+
+	// the environment is only updated when its pending deployment can start
+	pending := environment.LatestDeployment()
+	if pending == nil || pending.Status != types.DeploymentPending || !environment.CanStartDeployment(*pending, time.Now()) {
+		return nil, nil
+	}
+
+	var started *types.Deployment
+	_, err = d.environment.UpdateEnvironment(ctx, environmentName, func(latest *types.Environment) error {
+		started = latest.StartPendingDeployment(time.Now())
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error starting the pending deployment of environment %s", environmentName)
+	}
+
+	if started != nil {
+		log.Infof("Started pending deployment %s of environment %s", started.ID, environmentName)
+	}
+	return started, nil
+}
+
+// checkDeploymentTaskProgress returns the tasks started by the deployment across its clusters,
+// and in each of them
 func (d deploymentWorker) checkDeploymentTaskProgress(environment *types.Environment,
-	deployment *types.Deployment) (*ecs.DescribeTasksOutput, error) {
+	deployment *types.Deployment) (*ecs.DescribeTasksOutput, map[string]*ecs.DescribeTasksOutput, error) {
+
+	if environment.Cluster == "" && !environment.HasClusterSelector() {
+		return nil, nil, errors.New("Environment cluster should not be empty")
+	}
+
+	resp := &ecs.DescribeTasksOutput{}
+	clusterProgress := make(map[string]*ecs.DescribeTasksOutput)
+	for _, cluster := range deploymentClusters(environment, deployment) {
+		// TODO: replace with cluster state calls
+		tasks, err := d.ecs.ListTasks(cluster, deployment.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		clusterResp, err := d.ecs.DescribeTasks(cluster, tasks)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		clusterProgress[cluster] = clusterResp
+		resp.Tasks = append(resp.Tasks, clusterResp.Tasks...)
+		resp.Failures = append(resp.Failures, clusterResp.Failures...)
+	}
+
+	return resp, clusterProgress, nil
+}
+
+// deploymentClusters returns the clusters, in order, the deployment started tasks in
+func deploymentClusters(environment *types.Environment, deployment *types.Deployment) []string {
+	if !environment.HasClusterSelector() {
+		return []string{environment.Cluster}
+	}
+
+	clusters := make([]string, 0, len(deployment.Clusters))
+	for cluster := range deployment.Clusters {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	return clusters
+}
+
+// checkRollbackPolicy returns why the deployment failed the rollback policy of the environment, or
+// an empty string if it has not. A deployment to several clusters fails as soon as it fails in
+// any of them, so that a failing cluster is not hidden by the others.
+func (d deploymentWorker) checkRollbackPolicy(environment *types.Environment, deployment *types.Deployment,
+	resp *ecs.DescribeTasksOutput, clusterProgress map[string]*ecs.DescribeTasksOutput) (string, error) {
+
+	if !environment.HasClusterSelector() {
+		return d.checkClusterRollbackPolicy(environment, deployment, environment.Cluster, deployment.FailedInstances, resp)
+	}
+
+	for _, cluster := range deploymentClusters(environment, deployment) {
+		clusterResp, ok := clusterProgress[cluster]
+		if !ok {
+			clusterResp = &ecs.DescribeTasksOutput{}
+		}
+		reason, err := d.checkClusterRollbackPolicy(environment, deployment, cluster,
+			deployment.Clusters[cluster].FailedInstances, clusterResp)
+		if err != nil || reason != "" {
+			return reasonInCluster(reason, cluster), err
+		}
+	}
+	return "", nil
+}
+
+// checkClusterRollbackPolicy returns why the deployment failed the rollback policy of the
+// environment in the cluster given the instances starting its tasks failed on and its tasks there
+func (d deploymentWorker) checkClusterRollbackPolicy(environment *types.Environment, deployment *types.Deployment,
+	cluster string, failures []*ecs.Failure, resp *ecs.DescribeTasksOutput) (string, error) {
+
+	policy := environment.RollbackPolicy
+	stoppedTasks := []*ecs.Task{}
+	if policy.ChecksStoppedTasks() {
+		tasks, err := d.ecs.ListStoppedTasks(cluster, deployment.ID)
+		if err != nil {
+			return "", err
+		}
 
-	if environment.Cluster == "" {
-		return nil, errors.New("Environment cluster should not be empty")
+		if len(tasks) > 0 {
+			stopped, err := d.ecs.DescribeTasks(cluster, tasks)
+			if err != nil {
+				return "", err
+			}
+			stoppedTasks = stopped.Tasks
+		}
 	}
 
-	// TODO: replace with cluster state calls
-	tasks, err := d.ecs.ListTasks(environment.Cluster, deployment.ID)
+	return policy.FailureReason(failures, resp.Tasks, stoppedTasks, time.Now()), nil
+}
+
+// reasonInCluster adds the cluster to a non-empty failure reason
+func reasonInCluster(reason string, cluster string) string {
+	if reason == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s in cluster %s", reason, cluster)
+}
+
+// rollBackDeployment marks the deployment as failed and rolls the environment back to its latest
+// healthy deployment, if any, in a single update of the environment
+func (d deploymentWorker) rollBackDeployment(ctx context.Context,
+	environment *types.Environment, deployment *types.Deployment, reason string) (*types.Deployment, error) {
+
+	failedDeployment, err := deployment.UpdateDeploymentFailed(deployment.FailedInstances, reason)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := d.ecs.DescribeTasks(environment.Cluster, tasks)
+	var rollback *types.Deployment
+	_, err = d.environment.UpdateEnvironment(ctx, environment.Name, func(latest *types.Environment) error {
+		if !isInProgressDeployment(latest, failedDeployment.ID) {
+			return errDeploymentNotInProgress
+		}
+
+		var err error
+		rollback, err = latest.FailDeployment(*failedDeployment)
+		if err != nil {
+			return err
+		}
+
+		// the failed deployment records the deployment that rolled it back
+		*failedDeployment = latest.Deployments[failedDeployment.ID]
+		return nil
+	})
+	if errors.Cause(err) == errDeploymentNotInProgress {
+		log.Infof("Deployment %s is no longer the in-progress deployment", failedDeployment.ID)
+		return nil, nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "Error failing the deployment %s in the environment %s",
+			failedDeployment.ID, environment.Name)
+	}
+
+	if rollback == nil {
+		log.Infof("Deployment %s in environment %s failed without a deployment to roll back to: %s",
+			failedDeployment.ID, environment.Name, reason)
+	} else {
+		log.Infof("Deployment %s in environment %s failed and is rolled back by deployment %s to task definition %s: %s",
+			failedDeployment.ID, environment.Name, rollback.ID, rollback.TaskDefinition, reason)
 	}
 
-	return resp, nil
+	return failedDeployment, nil
 }
 
 func (d deploymentWorker) updateDeployment(ctx context.Context,
 	environment *types.Environment, deployment *types.Deployment,
-	resp *ecs.DescribeTasksOutput) (*types.Deployment, error) {
+	resp *ecs.DescribeTasksOutput, clusterProgress map[string]*ecs.DescribeTasksOutput) (*types.Deployment, error) {
 
-	updatedDeployment, err := d.updateDeploymentObject(deployment, resp)
+	updatedDeployment, err := d.updateDeploymentObject(environment, deployment, resp, clusterProgress)
 	if err != nil {
 		return nil, err
 	}
 
-	// retrieve in-progress again to make sure it has not been updated by another process
-	// TODO: wrap the in-progress check and updateDeployment in a transaction
-	deployment, err = d.deployment.GetInProgressDeployment(ctx, environment.Name)
-	if err != nil {
-		return nil, err
+	stopped := []string{}
+	if updatedDeployment.Status == types.DeploymentCompleted {
+		stopped, err = d.stoppedDeployments(environment, updatedDeployment.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error checking the tasks of earlier deployments in environment %s",
+				environment.Name)
+		}
 	}
 
-	if deployment == nil || deployment.ID != updatedDeployment.ID {
+	// the deployment may have been updated by another process since it was retrieved, so it is
+	// only updated if it is still the in-progress deployment when the environment is written
+	_, err = d.environment.UpdateEnvironment(ctx, environment.Name, func(latest *types.Environment) error {
+		if !isInProgressDeployment(latest, updatedDeployment.ID) {
+			return errDeploymentNotInProgress
+		}
+		latest.MarkTasksStopped(stopped)
+		return latest.UpdateDeployment(*updatedDeployment)
+	})
+	if errors.Cause(err) == errDeploymentNotInProgress {
 		log.Infof("Deployment %s is no longer the in-progress deployment", updatedDeployment.ID)
 		return nil, nil
 	}
-
-	_, err = d.environment.UpdateDeployment(ctx, *environment, *updatedDeployment)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Error updating the deployment %v in the environment %v",
 			*updatedDeployment, environment.Name)
@@ -144,22 +340,110 @@ func (d deploymentWorker) updateDeployment(ctx context.Context,
 	return updatedDeployment, nil
 }
 
-func (d deploymentWorker) updateDeploymentObject(deployment *types.Deployment,
-	resp *ecs.DescribeTasksOutput) (*types.Deployment, error) {
+// stoppedDeployments returns the IDs of the finished deployments of the environment without a task
+// left running, which can then be pruned. Once the deployment with the completed ID completes, none
+// of them starts tasks anymore, so they stay without tasks.
+func (d deploymentWorker) stoppedDeployments(environment *types.Environment, completed string) ([]string, error) {
+	stopped := make([]string, 0)
+	for id, deployment := range environment.Deployments {
+		if id == completed || !deployment.IsFinished() || deployment.TasksStopped {
+			continue
+		}
+
+		running := false
+		for _, cluster := range deploymentClusters(environment, &deployment) {
+			tasks, err := d.ecs.ListTasks(cluster, id)
+			if err != nil {
+				return nil, err
+			}
+			if len(tasks) > 0 {
+				running = true
+				break
+			}
+		}
+		if !running {
+			stopped = append(stopped, id)
+		}
+	}
+	sort.Strings(stopped)
+	return stopped, nil
+}
 
-	if d.deploymentCompleted(resp.Tasks, resp.Failures) {
-		return deployment.UpdateDeploymentCompleted(resp.Failures)
+// isInProgressDeployment returns whether the deployment with the provided ID is the in-progress
+// deployment of the environment, which is the pending deployment until it has been picked up
+func isInProgressDeployment(environment *types.Environment, id string) bool {
+	inProgressID := environment.InProgressDeploymentID
+	if inProgressID == "" {
+		inProgressID = environment.PendingDeploymentID
 	}
 
-	updatedDeployment, err := deployment.UpdateDeploymentInProgress(
-		deployment.DesiredTaskCount, resp.Failures)
+	deployment, ok := environment.Deployments[id]
+	if !ok || inProgressID != id {
+		return false
+	}
+
+	return deployment.Status == types.DeploymentPending || deployment.Status == types.DeploymentInProgress
+}
+
+func (d deploymentWorker) updateDeploymentObject(environment *types.Environment, deployment *types.Deployment,
+	resp *ecs.DescribeTasksOutput, clusterProgress map[string]*ecs.DescribeTasksOutput) (*types.Deployment, error) {
+
+	// a rolling deployment is only completed once the scheduler has updated every instance
+	rolledOut := deployment.RolloutCompleted
+	if environment.HasClusterSelector() {
+		rolledOut = deployment.ClustersRolledOut()
+	}
+	rolloutCompleted := !environment.RolloutStrategy.IsRolling() || rolledOut
+
+	var updatedDeployment *types.Deployment
+	var err error
+	if rolloutCompleted && d.deploymentCompleted(resp.Tasks, resp.Failures) {
+		updatedDeployment, err = deployment.UpdateDeploymentCompleted(resp.Failures)
+	} else {
+		updatedDeployment, err = deployment.UpdateDeploymentInProgress(
+			deployment.DesiredTaskCount, resp.Failures)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	if environment.HasClusterSelector() {
+		updatedDeployment.Clusters = d.updateClusterDeployments(environment, deployment, clusterProgress)
+	}
+
 	return updatedDeployment, nil
 }
 
+// updateClusterDeployments returns the progress of the deployment in each of its clusters given
+// the tasks it started in them
+func (d deploymentWorker) updateClusterDeployments(environment *types.Environment, deployment *types.Deployment,
+	clusterProgress map[string]*ecs.DescribeTasksOutput) map[string]types.ClusterDeployment {
+
+	clusters := make(map[string]types.ClusterDeployment, len(deployment.Clusters))
+	for cluster, c := range deployment.Clusters {
+		resp, ok := clusterProgress[cluster]
+		if !ok {
+			clusters[cluster] = c
+			continue
+		}
+
+		rolloutCompleted := !environment.RolloutStrategy.IsRolling() || c.RolloutCompleted
+		if rolloutCompleted && d.deploymentCompleted(resp.Tasks, resp.Failures) {
+			c.Status = types.DeploymentCompleted
+		} else {
+			c.Status = types.DeploymentInProgress
+		}
+
+		c.FailedInstances = resp.Failures
+		c.Health = types.DeploymentHealthy
+		if len(resp.Failures) > 0 {
+			c.Health = types.DeploymentUnhealthy
+		}
+		clusters[cluster] = c
+	}
+	return clusters
+}
+
 func (d deploymentWorker) deploymentCompleted(tasks []*ecs.Task, failures []*ecs.Failure) bool {
 	if len(tasks) == 0 {
 		return false
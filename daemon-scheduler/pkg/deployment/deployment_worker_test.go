@@ -16,6 +16,7 @@ package deployment
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
@@ -82,6 +83,39 @@ func (suite *DeploymentWorkerTestSuite) TestNewDeploymentWorker() {
 	assert.NotNil(suite.T(), w, "Worker should not be nil")
 }
 
+func (suite *DeploymentWorkerTestSuite) TestStartPendingDeployment() {
+	pending, err := suite.environmentObject.StartDeployment()
+	assert.Nil(suite.T(), err, "Unexpected error when starting a deployment")
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+	latest := *suite.environmentObject
+	latest.Deployments = map[string]types.Deployment{pending.ID: *pending}
+	suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+			assert.Nil(suite.T(), update(&latest), "Unexpected error updating the latest environment")
+		}).Return(&latest, nil)
+
+	d, err := suite.deploymentWorker.StartPendingDeployment(suite.ctx, environmentName)
+	assert.Nil(suite.T(), err, "Unexpected error when starting the pending deployment")
+	assert.Exactly(suite.T(), pending.ID, d.ID, "Expected the pending deployment to start")
+	assert.Exactly(suite.T(), types.DeploymentInProgress, latest.Deployments[pending.ID].Status,
+		"Expected the deployment to be in progress in the latest environment")
+}
+
+func (suite *DeploymentWorkerTestSuite) TestStartPendingDeploymentWaiting() {
+	pending, err := suite.environmentObject.StartDeployment()
+	assert.Nil(suite.T(), err, "Unexpected error when starting a deployment")
+	pending.NotBefore = time.Now().Add(time.Hour)
+	suite.environmentObject.Deployments[pending.ID] = *pending
+
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+	suite.environment.EXPECT().UpdateEnvironment(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	d, err := suite.deploymentWorker.StartPendingDeployment(suite.ctx, environmentName)
+	assert.Nil(suite.T(), err, "Unexpected error when the pending deployment waits for its start time")
+	assert.Nil(suite.T(), d, "Expected the pending deployment to wait for its start time")
+}
+
 func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentEmptyEnvironmentName() {
 	_, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, "")
 	assert.Error(suite.T(), err, "Expected an error when env name is missing")
@@ -143,7 +177,7 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentDescribeTa
 }
 
 func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentNoTasksStartedByTheDeployment() {
-	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil).Times(2)
+	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil)
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
 	suite.ecs.EXPECT().ListTasks(suite.environmentObject.Cluster, suite.inProgressDeploymentObject.ID).
 		Return(suite.clusterTaskARNs, nil)
@@ -153,8 +187,13 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentNoTasksSta
 	}
 
 	suite.ecs.EXPECT().DescribeTasks(suite.environmentObject.Cluster, suite.clusterTaskARNs).Return(noTasks, nil)
-	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *suite.environmentObject, *suite.inProgressDeploymentObject).
-		Return(suite.environmentObject, nil)
+	latest := suite.latestEnvironment(suite.inProgressDeploymentObject.ID)
+	suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+			assert.Nil(suite.T(), update(latest), "Unexpected error updating the latest environment")
+			assert.Equal(suite.T(), *suite.inProgressDeploymentObject, latest.Deployments[suite.inProgressDeploymentObject.ID],
+				"Expected the deployment to be updated in the latest environment")
+		}).Return(latest, nil)
 
 	d, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
 	assert.Nil(suite.T(), err, "Unexpected error when there are no tasks started by the deployment")
@@ -162,7 +201,7 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentNoTasksSta
 }
 
 func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentTasksArePending() {
-	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil).Times(2)
+	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil)
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
 	suite.ecs.EXPECT().ListTasks(suite.environmentObject.Cluster, suite.inProgressDeploymentObject.ID).
 		Return(suite.clusterTaskARNs, nil)
@@ -182,8 +221,13 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentTasksArePe
 	}
 
 	suite.ecs.EXPECT().DescribeTasks(suite.environmentObject.Cluster, suite.clusterTaskARNs).Return(tasks, nil)
-	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *suite.environmentObject, *suite.inProgressDeploymentObject).
-		Return(suite.environmentObject, nil)
+	latest := suite.latestEnvironment(suite.inProgressDeploymentObject.ID)
+	suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+			assert.Nil(suite.T(), update(latest), "Unexpected error updating the latest environment")
+			assert.Equal(suite.T(), types.DeploymentInProgress, latest.Deployments[suite.inProgressDeploymentObject.ID].Status,
+				"Expected the deployment to stay in progress")
+		}).Return(latest, nil)
 
 	d, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
 	assert.Nil(suite.T(), err, "Unexpected error when there is a pending task started by the deployment")
@@ -191,7 +235,7 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentTasksArePe
 }
 
 func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentDeploymentCompleted() {
-	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil).Times(2)
+	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil)
 	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
 	suite.ecs.EXPECT().ListTasks(suite.environmentObject.Cluster, suite.inProgressDeploymentObject.ID).
 		Return(suite.clusterTaskARNs, nil)
@@ -216,17 +260,232 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentDeployment
 
 	assert.Nil(suite.T(), err, "Unexpected error when moving deployment to completed")
 
-	suite.environment.EXPECT().UpdateDeployment(suite.ctx, *suite.environmentObject, gomock.Any()).Do(
-		func(_ interface{}, _ interface{}, d types.Deployment) {
+	latest := suite.latestEnvironment(suite.inProgressDeploymentObject.ID)
+	suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+			assert.Nil(suite.T(), update(latest), "Unexpected error updating the latest environment")
+			d := latest.Deployments[suite.inProgressDeploymentObject.ID]
 			verifyDeploymentCompleted(suite.T(), completedDeployment, &d)
-		}).Return(suite.environmentObject, nil)
+		}).Return(latest, nil)
 
 	d, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
 	assert.Nil(suite.T(), err, "Unexpected error when the deployment is completed")
 	verifyDeploymentCompleted(suite.T(), completedDeployment, d)
 }
 
-func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentInProgressDeploymentCheckFailsAfterUpdatingDeploymentObject() {
+func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentCompletedMarksStoppedDeployments() {
+	earlier := map[string]types.Deployment{
+		"stopped-dep": {ID: "stopped-dep", Status: types.DeploymentCompleted},
+		"running-dep": {ID: "running-dep", Status: types.DeploymentFailed},
+		"marked-dep":  {ID: "marked-dep", Status: types.DeploymentCompleted, TasksStopped: true},
+	}
+	for id, d := range earlier {
+		suite.environmentObject.Deployments[id] = d
+	}
+
+	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil)
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+	suite.ecs.EXPECT().ListTasks(suite.environmentObject.Cluster, suite.inProgressDeploymentObject.ID).
+		Return(suite.clusterTaskARNs[:1], nil)
+	suite.ecs.EXPECT().DescribeTasks(suite.environmentObject.Cluster, suite.clusterTaskARNs[:1]).Return(&ecs.DescribeTasksOutput{
+		Tasks: []*ecs.Task{{TaskArn: aws.String(taskARN1), LastStatus: aws.String(TaskRunning)}},
+	}, nil)
+	suite.ecs.EXPECT().ListTasks(suite.environmentObject.Cluster, "stopped-dep").Return([]*string{}, nil)
+	suite.ecs.EXPECT().ListTasks(suite.environmentObject.Cluster, "running-dep").Return(suite.clusterTaskARNs[1:], nil)
+
+	latest := suite.latestEnvironment(suite.inProgressDeploymentObject.ID)
+	for id, d := range earlier {
+		latest.Deployments[id] = d
+	}
+	suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+			assert.Nil(suite.T(), update(latest), "Unexpected error updating the latest environment")
+		}).Return(latest, nil)
+
+	d, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
+	assert.Nil(suite.T(), err, "Unexpected error when the deployment is completed")
+	assert.Exactly(suite.T(), types.DeploymentCompleted, d.Status, "Expected the deployment to be completed")
+	assert.True(suite.T(), latest.Deployments["stopped-dep"].TasksStopped,
+		"Expected the deployment without running tasks to be marked")
+	assert.False(suite.T(), latest.Deployments["running-dep"].TasksStopped,
+		"Expected the deployment with a running task not to be marked")
+}
+
+func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentRolloutInProgress() {
+	suite.environmentObject.RolloutStrategy = types.RolloutStrategy{BatchSize: 1}
+	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil)
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+	suite.ecs.EXPECT().ListTasks(suite.environmentObject.Cluster, suite.inProgressDeploymentObject.ID).
+		Return(suite.clusterTaskARNs, nil)
+
+	runningTask := &ecs.Task{
+		TaskArn:    aws.String(taskARN1),
+		LastStatus: aws.String(TaskRunning),
+	}
+	tasks := &ecs.DescribeTasksOutput{
+		Tasks: []*ecs.Task{runningTask},
+	}
+	suite.ecs.EXPECT().DescribeTasks(suite.environmentObject.Cluster, suite.clusterTaskARNs).Return(tasks, nil)
+
+	latest := suite.latestEnvironment(suite.inProgressDeploymentObject.ID)
+	suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+			assert.Nil(suite.T(), update(latest), "Unexpected error updating the latest environment")
+			assert.Equal(suite.T(), types.DeploymentInProgress, latest.Deployments[suite.inProgressDeploymentObject.ID].Status,
+				"Expected the deployment to stay in progress until the rollout completes")
+		}).Return(latest, nil)
+
+	d, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
+	assert.Nil(suite.T(), err, "Unexpected error when the rollout is in progress")
+	assert.Equal(suite.T(), types.DeploymentInProgress, d.Status, "Expected the deployment to stay in progress")
+}
+
+func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentPerCluster() {
+	suite.environmentObject.Cluster = ""
+	suite.environmentObject.ClusterSelector = types.ClusterSelector{Clusters: []string{cluster1, cluster2}}
+	deployment, err := suite.inProgressDeploymentObject.UpdateClusterInProgress(cluster1, 1, nil)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the deployment in a cluster")
+	deployment, err = deployment.UpdateClusterInProgress(cluster2, 1, nil)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the deployment in a cluster")
+	suite.inProgressDeploymentObject = deployment
+
+	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(deployment, nil)
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+	for cluster, status := range map[string]string{cluster1: TaskRunning, cluster2: TaskPending} {
+		taskARNs := []*string{aws.String(cluster + "/task")}
+		suite.ecs.EXPECT().ListTasks(cluster, deployment.ID).Return(taskARNs, nil)
+		suite.ecs.EXPECT().DescribeTasks(cluster, taskARNs).Return(&ecs.DescribeTasksOutput{
+			Tasks: []*ecs.Task{{TaskArn: taskARNs[0], LastStatus: aws.String(status)}},
+		}, nil)
+	}
+
+	latest := suite.latestEnvironment(deployment.ID)
+	suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+			assert.Nil(suite.T(), update(latest), "Unexpected error updating the latest environment")
+		}).Return(latest, nil)
+
+	d, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
+	assert.Nil(suite.T(), err, "Unexpected error when the deployment is in progress in a cluster")
+	assert.Equal(suite.T(), types.DeploymentInProgress, d.Status, "Expected the deployment to wait for every cluster")
+	assert.Equal(suite.T(), types.DeploymentCompleted, d.Clusters[cluster1].Status, "Expected the deployment to complete in the first cluster")
+	assert.Equal(suite.T(), types.DeploymentInProgress, d.Clusters[cluster2].Status, "Expected the deployment to be in progress in the second cluster")
+	assert.Equal(suite.T(), d.Clusters, latest.Deployments[deployment.ID].Clusters, "Expected the progress in each cluster to be stored")
+}
+
+func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentRollsBackCrashingDeployment() {
+	suite.environmentObject.RollbackPolicy = types.RollbackPolicy{CrashCount: 1, CrashWindow: time.Minute}
+	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil)
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+	suite.ecs.EXPECT().ListTasks(suite.environmentObject.Cluster, suite.inProgressDeploymentObject.ID).
+		Return(suite.clusterTaskARNs[:1], nil)
+	suite.ecs.EXPECT().DescribeTasks(suite.environmentObject.Cluster, suite.clusterTaskARNs[:1]).
+		Return(suite.emptyDescribeTasksOutput, nil)
+
+	stoppedTaskARNs := suite.clusterTaskARNs[1:]
+	crashedTask := &ecs.Task{
+		TaskArn:    aws.String(taskARN2),
+		LastStatus: aws.String("STOPPED"),
+		StartedAt:  aws.Time(time.Now().Add(-time.Minute)),
+		StoppedAt:  aws.Time(time.Now()),
+	}
+	suite.ecs.EXPECT().ListStoppedTasks(suite.environmentObject.Cluster, suite.inProgressDeploymentObject.ID).
+		Return(stoppedTaskARNs, nil)
+	suite.ecs.EXPECT().DescribeTasks(suite.environmentObject.Cluster, stoppedTaskARNs).
+		Return(&ecs.DescribeTasksOutput{Tasks: []*ecs.Task{crashedTask}}, nil)
+
+	healthyDeployment := types.Deployment{
+		ID:             "healthy-dep-id",
+		Status:         types.DeploymentCompleted,
+		Health:         types.DeploymentHealthy,
+		TaskDefinition: "healthy-task-definition",
+	}
+	latest := suite.latestEnvironment(suite.inProgressDeploymentObject.ID)
+	latest.Deployments[healthyDeployment.ID] = healthyDeployment
+	suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+			assert.Nil(suite.T(), update(latest), "Unexpected error updating the latest environment")
+		}).Return(latest, nil)
+
+	d, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
+	assert.Nil(suite.T(), err, "Unexpected error when the deployment fails")
+	assert.Exactly(suite.T(), types.DeploymentFailed, d.Status, "Expected the deployment to be failed")
+	assert.NotEmpty(suite.T(), d.RollbackReason, "Expected the rollback reason to be recorded")
+
+	rollback, ok := latest.Deployments[d.RolledBackBy]
+	assert.True(suite.T(), ok, "Expected a rollback deployment to be added")
+	assert.Exactly(suite.T(), healthyDeployment.TaskDefinition, rollback.TaskDefinition,
+		"Expected the rollback to redeploy the healthy task definition")
+	assert.Exactly(suite.T(), d.ID, rollback.RollbackOf, "Expected the rollback to refer to the failed deployment")
+	assert.Exactly(suite.T(), d.RollbackReason, rollback.RollbackReason, "Expected the rollback reason to be recorded")
+	assert.Exactly(suite.T(), rollback.ID, latest.PendingDeploymentID, "Expected the rollback to be pending")
+}
+
+func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentRollsBackDeploymentFailingInCluster() {
+	suite.environmentObject.Cluster = ""
+	suite.environmentObject.ClusterSelector = types.ClusterSelector{Clusters: []string{cluster1, cluster2}}
+	suite.environmentObject.RollbackPolicy = types.RollbackPolicy{FailedInstancePercent: 50}
+	failures := []*ecs.Failure{{Arn: aws.String(cluster2 + "/failed-instance"), Reason: aws.String("AGENT")}}
+	deployment, err := suite.inProgressDeploymentObject.UpdateClusterInProgress(cluster1, 2, nil)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the deployment in a cluster")
+	deployment, err = deployment.UpdateClusterInProgress(cluster2, 2, failures)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the deployment in a cluster")
+
+	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(deployment, nil)
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+	for cluster, instances := range map[string][]string{cluster1: {"instance1", "instance2"}, cluster2: {"instance3"}} {
+		taskARNs := []*string{}
+		tasks := []*ecs.Task{}
+		for _, instance := range instances {
+			taskARN := aws.String(cluster + "/task/" + instance)
+			taskARNs = append(taskARNs, taskARN)
+			tasks = append(tasks, &ecs.Task{
+				TaskArn:              taskARN,
+				ContainerInstanceArn: aws.String(cluster + "/" + instance),
+				LastStatus:           aws.String(TaskRunning),
+			})
+		}
+		suite.ecs.EXPECT().ListTasks(cluster, deployment.ID).Return(taskARNs, nil)
+		suite.ecs.EXPECT().DescribeTasks(cluster, taskARNs).Return(&ecs.DescribeTasksOutput{Tasks: tasks}, nil)
+	}
+
+	healthyDeployment := types.Deployment{
+		ID:             "healthy-dep-id",
+		Status:         types.DeploymentCompleted,
+		Health:         types.DeploymentHealthy,
+		TaskDefinition: "healthy-task-definition",
+	}
+	latest := suite.latestEnvironment(deployment.ID)
+	latest.Deployments[healthyDeployment.ID] = healthyDeployment
+	suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+			assert.Nil(suite.T(), update(latest), "Unexpected error updating the latest environment")
+		}).Return(latest, nil)
+
+	d, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
+	assert.Nil(suite.T(), err, "Unexpected error when the deployment fails in a cluster")
+	assert.Exactly(suite.T(), types.DeploymentFailed, d.Status,
+		"Expected the deployment to fail although most of its instances across clusters are healthy")
+	assert.Exactly(suite.T(), "Starting tasks failed on 1 of 2 instances in cluster "+cluster2, d.RollbackReason,
+		"Expected the rollback reason to name the failing cluster")
+}
+
+func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentListStoppedTasksFails() {
+	suite.environmentObject.RollbackPolicy = types.RollbackPolicy{CrashCount: 1, CrashWindow: time.Minute}
+	suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil)
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+	suite.ecs.EXPECT().ListTasks(suite.environmentObject.Cluster, suite.inProgressDeploymentObject.ID).
+		Return(suite.clusterTaskARNs, nil)
+	suite.ecs.EXPECT().DescribeTasks(suite.environmentObject.Cluster, suite.clusterTaskARNs).
+		Return(suite.emptyDescribeTasksOutput, nil)
+	suite.ecs.EXPECT().ListStoppedTasks(suite.environmentObject.Cluster, suite.inProgressDeploymentObject.ID).
+		Return(nil, errors.New("ListTasks failed"))
+
+	_, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
+	assert.Error(suite.T(), err, "Expected an error when listing stopped tasks fails")
+}
+
+func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentEnvironmentModifiedConcurrently() {
 	gomock.InOrder(
 		suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil),
 		suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil),
@@ -234,13 +493,15 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentInProgress
 			Return(suite.clusterTaskARNs, nil),
 		suite.ecs.EXPECT().DescribeTasks(suite.environmentObject.Cluster, suite.clusterTaskARNs).
 			Return(suite.emptyDescribeTasksOutput, nil),
-		suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(nil, errors.New("Second in-progress deployment check fails")),
+		suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).
+			Return(nil, types.NewConflictError(errors.New("Environment keeps being modified"))),
 	)
 	_, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
-	assert.Error(suite.T(), err, "Expected an error when in progress deployment check fails")
+	assert.Error(suite.T(), err, "Expected an error when the environment keeps being modified")
 }
 
 func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentNoInProgressDeploymentAfterUpdatingDeploymentObject() {
+	latest := suite.latestEnvironment("")
 	gomock.InOrder(
 		suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil),
 		suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil),
@@ -248,7 +509,11 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentNoInProgre
 			Return(suite.clusterTaskARNs, nil),
 		suite.ecs.EXPECT().DescribeTasks(suite.environmentObject.Cluster, suite.clusterTaskARNs).
 			Return(suite.emptyDescribeTasksOutput, nil),
-		suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(nil, nil),
+		suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+			func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+				assert.Exactly(suite.T(), errDeploymentNotInProgress, update(latest),
+					"Expected the update to be skipped when there is no in-progress deployment")
+			}).Return(nil, errDeploymentNotInProgress),
 	)
 	d, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
 	assert.Nil(suite.T(), err, "Unexpected error when there is no in-progress deployment")
@@ -259,6 +524,9 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentInProgress
 	newInProgressDeployment, err := types.NewDeployment(taskDefinition, suite.environmentObject.Token)
 	assert.Nil(suite.T(), err, "Could not create a new deployment")
 
+	latest := suite.latestEnvironment(newInProgressDeployment.ID)
+	latest.Deployments[newInProgressDeployment.ID] = *newInProgressDeployment
+
 	gomock.InOrder(
 		suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil),
 		suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil),
@@ -266,7 +534,11 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentInProgress
 			Return(suite.clusterTaskARNs, nil),
 		suite.ecs.EXPECT().DescribeTasks(suite.environmentObject.Cluster, suite.clusterTaskARNs).
 			Return(suite.emptyDescribeTasksOutput, nil),
-		suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(newInProgressDeployment, nil),
+		suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).Do(
+			func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+				assert.Exactly(suite.T(), errDeploymentNotInProgress, update(latest),
+					"Expected the update to be skipped when the in-progress deployment has changed")
+			}).Return(nil, errDeploymentNotInProgress),
 	)
 
 	d, err := suite.deploymentWorker.UpdateInProgressDeployment(suite.ctx, environmentName)
@@ -282,8 +554,7 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentUpdateDepl
 			Return(suite.clusterTaskARNs, nil),
 		suite.ecs.EXPECT().DescribeTasks(suite.environmentObject.Cluster, suite.clusterTaskARNs).
 			Return(suite.emptyDescribeTasksOutput, nil),
-		suite.deployment.EXPECT().GetInProgressDeployment(suite.ctx, environmentName).Return(suite.inProgressDeploymentObject, nil),
-		suite.environment.EXPECT().UpdateDeployment(suite.ctx, *suite.environmentObject, *suite.inProgressDeploymentObject).
+		suite.environment.EXPECT().UpdateEnvironment(suite.ctx, environmentName, gomock.Any()).
 			Return(nil, errors.New("Update deployment failed")),
 	)
 
@@ -291,6 +562,17 @@ func (suite *DeploymentWorkerTestSuite) TestUpdateInProgressDeploymentUpdateDepl
 	assert.Error(suite.T(), err, "Expected an error when update deployment fails")
 }
 
+// latestEnvironment returns the stored version of the environment with the in-progress
+// deployment and inProgressID as the in-progress deployment ID
+func (suite *DeploymentWorkerTestSuite) latestEnvironment(inProgressID string) *types.Environment {
+	latest := *suite.environmentObject
+	latest.Deployments = map[string]types.Deployment{
+		suite.inProgressDeploymentObject.ID: *suite.inProgressDeploymentObject,
+	}
+	latest.InProgressDeploymentID = inProgressID
+	return &latest
+}
+
 func verifyDeploymentCompleted(t *testing.T, expected *types.Deployment, actual *types.Deployment) {
 	assert.Exactly(t, expected.ID, actual.ID, "Deployment ids should match")
 	assert.Exactly(t, types.DeploymentCompleted, actual.Status, "Deployment status should be completed")
@@ -16,6 +16,7 @@ package deployment
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/blox/blox/daemon-scheduler/pkg/store"
 	"github.com/blox/blox/daemon-scheduler/pkg/types"
@@ -33,41 +34,79 @@ var (
 )
 
 type Environment interface {
-	// CreateEnvironment stores a new environment in the database
-	CreateEnvironment(ctx context.Context, name string, taskDefinition string, cluster string) (*types.Environment, error)
+	// CreateEnvironment stores a new environment in the database. The environment is deployed
+	// either to cluster or to the clusters picked by selector, and exactly one of them has to be set.
+	CreateEnvironment(ctx context.Context, name string, taskDefinition string, cluster string,
+		selector types.ClusterSelector, constraints types.PlacementConstraints, strategy types.RolloutStrategy,
+		policy types.RollbackPolicy, capacity types.CapacityPolicy, overrides types.TaskOverrides,
+		windows []types.MaintenanceWindow, draining types.DrainingPolicy,
+		scheduling types.SchedulingStrategy, restart types.RestartPolicy) (*types.Environment, error)
 	// GetEnvironment gets the environment with the provided name from the database
 	GetEnvironment(ctx context.Context, name string) (*types.Environment, error)
 	// DeleteEnvironment deletes the environment with the provided name from the database
 	DeleteEnvironment(ctx context.Context, name string) error
+	// DrainEnvironment marks the environment with the provided name as being deleted, so that the
+	// scheduler stops the tasks of its deployments and deletes it once they have stopped or timeout
+	// has passed. It returns nil if the environment does not exist.
+	DrainEnvironment(ctx context.Context, name string, timeout time.Duration) (*types.Environment, error)
+	// DeleteDrainedEnvironment deletes the provided version of an environment being deleted from the
+	// database. It returns a ConflictError if the environment was modified since.
+	DeleteDrainedEnvironment(ctx context.Context, environment types.Environment) error
 	// ListEnvironments returns a list with all the existing environments
 	ListEnvironments(ctx context.Context) ([]types.Environment, error)
 	// FilterEnvironments returns a list of all environments that match the filters
 	FilterEnvironments(ctx context.Context, filterKey string, filterVal string) ([]types.Environment, error)
+	// UpdateEnvironmentSettings applies update to the environment with the provided name if token is
+	// its current token, and rotates the token. If deploy is set, a deployment of the updated task
+	// definition is started in the same write and returned.
+	UpdateEnvironmentSettings(ctx context.Context, name string, token string, update types.EnvironmentUpdate,
+		deploy bool) (*types.Environment, *types.Deployment, error)
 
-	//TODO: reconsider how these methods are structured when adding transactions (do we need 2 separate methods? etc)
 	// AddPendingDeployment adds a deployment to the environment if a deployment with
-	// the provided ID does not exist
+	// the provided ID does not exist. It returns a ConflictError if the token or the pending or
+	// in-progress deployment of the stored environment no longer match the provided environment.
 	AddPendingDeployment(ctx context.Context, environment types.Environment, deployment types.Deployment) (*types.Environment, error)
 	// UpdateDeployment replaces an existing deployment in the environment with the
 	// provided one if a deployment with the provided ID already exists
 	UpdateDeployment(ctx context.Context, environment types.Environment, deployment types.Deployment) (*types.Environment, error)
+	// UpdateEnvironment applies update to the latest version of the environment with the provided
+	// name and stores the result, reapplying it if the environment is modified concurrently. The
+	// notifier is told about the stored change.
+	UpdateEnvironment(ctx context.Context, name string, update func(environment *types.Environment) error) (*types.Environment, error)
+}
+
+// Notifier is told about the changes of environments once they are stored
+type Notifier interface {
+	// EnvironmentUpdated is called with the version of an environment an update was applied to
+	// and the stored result of the update
+	EnvironmentUpdated(ctx context.Context, before types.Environment, after types.Environment)
+	// EnvironmentDeleted is called once the environment with the provided name is deleted
+	EnvironmentDeleted(ctx context.Context, name string)
 }
 
 type environment struct {
 	environmentStore store.EnvironmentStore
+	notifier         Notifier
 }
 
-func NewEnvironment(environmentStore store.EnvironmentStore) (Environment, error) {
+// NewEnvironment creates an environment service that tells notifier about the changes of
+// environments. notifier can be nil.
+func NewEnvironment(environmentStore store.EnvironmentStore, notifier Notifier) (Environment, error) {
 	if environmentStore == nil {
 		return nil, errors.New("Environment is not initialized")
 	}
 	return environment{
 		environmentStore: environmentStore,
+		notifier:         notifier,
 	}, nil
 }
 
 func (e environment) CreateEnvironment(ctx context.Context,
-	name string, taskDefinition string, cluster string) (*types.Environment, error) {
+	name string, taskDefinition string, cluster string, selector types.ClusterSelector,
+	constraints types.PlacementConstraints, strategy types.RolloutStrategy,
+	policy types.RollbackPolicy, capacity types.CapacityPolicy, overrides types.TaskOverrides,
+	windows []types.MaintenanceWindow, draining types.DrainingPolicy,
+	scheduling types.SchedulingStrategy, restart types.RestartPolicy) (*types.Environment, error) {
 
 	if len(name) == 0 {
 		return nil, errors.New("Environment name is missing")
@@ -77,10 +116,64 @@ func (e environment) CreateEnvironment(ctx context.Context,
 		return nil, errors.New("Environment task definition is missing")
 	}
 
-	if len(cluster) == 0 {
+	if len(cluster) == 0 && selector.IsEmpty() {
 		return nil, errors.New("Environment cluster is missing")
 	}
 
+	if len(cluster) > 0 && !selector.IsEmpty() {
+		return nil, types.NewBadRequestError(errors.New("Environment cannot have both a cluster and a cluster selector"))
+	}
+
+	err := selector.Validate()
+	if err != nil {
+		return nil, types.NewBadRequestError(errors.Wrapf(err, "Invalid cluster selector"))
+	}
+
+	err = constraints.Validate()
+	if err != nil {
+		return nil, types.NewBadRequestError(errors.Wrapf(err, "Invalid placement constraints"))
+	}
+
+	err = strategy.Validate()
+	if err != nil {
+		return nil, types.NewBadRequestError(errors.Wrapf(err, "Invalid rollout strategy"))
+	}
+
+	err = policy.Validate()
+	if err != nil {
+		return nil, types.NewBadRequestError(errors.Wrapf(err, "Invalid rollback policy"))
+	}
+
+	err = capacity.Validate()
+	if err != nil {
+		return nil, types.NewBadRequestError(errors.Wrapf(err, "Invalid capacity policy"))
+	}
+
+	err = overrides.Validate()
+	if err != nil {
+		return nil, types.NewBadRequestError(errors.Wrapf(err, "Invalid task overrides"))
+	}
+
+	err = types.ValidateMaintenanceWindows(windows)
+	if err != nil {
+		return nil, types.NewBadRequestError(err)
+	}
+
+	err = draining.Validate()
+	if err != nil {
+		return nil, types.NewBadRequestError(err)
+	}
+
+	err = scheduling.Validate()
+	if err != nil {
+		return nil, types.NewBadRequestError(errors.Wrapf(err, "Invalid scheduling strategy"))
+	}
+
+	err = restart.Validate()
+	if err != nil {
+		return nil, types.NewBadRequestError(errors.Wrapf(err, "Invalid restart policy"))
+	}
+
 	env, err := e.GetEnvironment(ctx, name)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Error getting environment with name %s", name)
@@ -91,10 +184,24 @@ func (e environment) CreateEnvironment(ctx context.Context,
 		return nil, types.NewBadRequestError(errors.Errorf("An environment with name %s already exists", name))
 	}
 
-	environment, err := types.NewEnvironment(name, taskDefinition, cluster)
+	var environment *types.Environment
+	if selector.IsEmpty() {
+		environment, err = types.NewEnvironment(name, taskDefinition, cluster)
+	} else {
+		environment, err = types.NewEnvironmentForClusters(name, taskDefinition, selector)
+	}
 	if err != nil {
 		return nil, err
 	}
+	environment.PlacementConstraints = constraints
+	environment.RolloutStrategy = strategy
+	environment.RollbackPolicy = policy
+	environment.CapacityPolicy = capacity
+	environment.TaskOverrides = overrides
+	environment.MaintenanceWindows = windows
+	environment.DrainingPolicy = draining
+	environment.SchedulingStrategy = scheduling
+	environment.RestartPolicy = restart
 
 	err = e.environmentStore.PutEnvironment(ctx, *environment)
 	if err != nil {
@@ -138,6 +245,50 @@ func (e environment) DeleteEnvironment(ctx context.Context, name string) error {
 		return errors.Wrapf(err, "Error deleting environment %s from store", name)
 	}
 
+	if e.notifier != nil {
+		e.notifier.EnvironmentDeleted(ctx, name)
+	}
+	return nil
+}
+
+func (e environment) DrainEnvironment(ctx context.Context, name string, timeout time.Duration) (*types.Environment, error) {
+	if len(name) == 0 {
+		return nil, types.NewBadRequestError(errors.New("Environment name is missing"))
+	}
+
+	if timeout < 0 {
+		return nil, types.NewBadRequestError(errors.Errorf("Drain timeout %s should not be negative", timeout))
+	}
+
+	deadline := time.Now().Add(timeout)
+	env, err := e.UpdateEnvironment(ctx, name, func(latest *types.Environment) error {
+		latest.StartDeleting(deadline)
+		return nil
+	})
+	if err != nil {
+		if _, ok := errors.Cause(err).(types.NotFoundError); ok {
+			log.Infof("Environment %s does not exist", name)
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "Error marking environment %s as deleting", name)
+	}
+
+	return env, nil
+}
+
+func (e environment) DeleteDrainedEnvironment(ctx context.Context, environment types.Environment) error {
+	if !environment.IsDeleting() {
+		return errors.Errorf("Environment %s is not being deleted", environment.Name)
+	}
+
+	err := e.environmentStore.DeleteEnvironment(ctx, environment)
+	if err != nil {
+		return errors.Wrapf(err, "Error deleting environment %s from store", environment.Name)
+	}
+
+	if e.notifier != nil {
+		e.notifier.EnvironmentDeleted(ctx, environment.Name)
+	}
 	return nil
 }
 
@@ -161,6 +312,35 @@ func (e environment) FilterEnvironments(ctx context.Context, filterKey string, f
 	}
 }
 
+func (e environment) UpdateEnvironmentSettings(ctx context.Context, name string, token string,
+	update types.EnvironmentUpdate, deploy bool) (*types.Environment, *types.Deployment, error) {
+
+	if len(token) == 0 {
+		return nil, nil, types.NewBadRequestError(errors.New("Environment token is missing"))
+	}
+
+	err := update.Validate()
+	if err != nil {
+		return nil, nil, types.NewBadRequestError(err)
+	}
+
+	var deployment *types.Deployment
+	env, err := e.UpdateEnvironment(ctx, name, func(latest *types.Environment) error {
+		err := latest.Update(token, update)
+		if err != nil || !deploy {
+			return err
+		}
+
+		deployment, err = latest.StartDeployment()
+		return err
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Error updating environment %s", name)
+	}
+
+	return env, deployment, nil
+}
+
 func (e environment) filterEnvironmentsByCluster(ctx context.Context, cluster string) ([]types.Environment, error) {
 	if validate.IsClusterARN(cluster) {
 		return e.filterEnvironmentsByClusterARN(ctx, cluster)
@@ -179,7 +359,7 @@ func (e environment) filterEnvironmentsByClusterARN(ctx context.Context, cluster
 
 	filteredEnvs := make([]types.Environment, 0, len(envs))
 	for _, env := range envs {
-		if clusterARN == env.Cluster {
+		if clusterARN == env.Cluster || env.ClusterSelector.Matches(clusterARN) || deployedByTags(env, clusterARN) {
 			filteredEnvs = append(filteredEnvs, env)
 		}
 	}
@@ -195,13 +375,21 @@ func (e environment) filterEnvironmentsByClusterName(ctx context.Context, cluste
 	filteredEnvs := make([]types.Environment, 0, len(envs))
 	for _, env := range envs {
 		clusterARNSuffix := "/" + clusterName
-		if strings.HasSuffix(env.Cluster, clusterARNSuffix) {
+		if strings.HasSuffix(env.Cluster, clusterARNSuffix) || env.ClusterSelector.Matches(clusterName) ||
+			deployedByTags(env, clusterName) {
 			filteredEnvs = append(filteredEnvs, env)
 		}
 	}
 	return filteredEnvs, nil
 }
 
+// deployedByTags returns whether the environment selects clusters by tag and was deployed to the
+// cluster. Filtering does not describe the tags of clusters, so a tagged cluster the environment
+// was never deployed to is not matched.
+func deployedByTags(env types.Environment, cluster string) bool {
+	return env.ClusterSelector.HasTags() && env.DeployedTo(cluster)
+}
+
 func (e environment) AddPendingDeployment(ctx context.Context, environment types.Environment,
 	deployment types.Deployment) (*types.Environment, error) {
 
@@ -218,17 +406,24 @@ func (e environment) AddPendingDeployment(ctx context.Context, environment types
 		return nil, errors.Errorf("Deployment status should be pending but is %v", deployment.Status)
 	}
 
-	err := environment.AddPendingDeployment(deployment)
-	if err != nil {
-		return nil, err
-	}
+	updated, err := e.UpdateEnvironment(ctx, environment.Name, func(latest *types.Environment) error {
+		// the deployment was validated against the provided environment, so it cannot be added
+		// if another deployment was created or the token was changed in the meantime
+		if latest.Token != environment.Token ||
+			latest.PendingDeploymentID != environment.PendingDeploymentID ||
+			latest.InProgressDeploymentID != environment.InProgressDeploymentID {
+			return types.NewConflictError(errors.Errorf(
+				"Environment %s was modified while adding deployment %s, retry with the latest environment",
+				environment.Name, deployment.ID))
+		}
 
-	err = e.environmentStore.PutEnvironment(ctx, environment)
+		return latest.AddPendingDeployment(deployment)
+	})
 	if err != nil {
 		return nil, errors.Wrapf(err, "Error saving environment %s to store", environment.Name)
 	}
 
-	return &environment, nil
+	return updated, nil
 }
 
 func (e environment) UpdateDeployment(ctx context.Context, environment types.Environment,
@@ -243,20 +438,29 @@ func (e environment) UpdateDeployment(ctx context.Context, environment types.Env
 		return nil, errors.Errorf("Deployment %s does not exist", deployment.ID)
 	}
 
-	// replace deployment with updated version
-	environment.Deployments[deployment.ID] = deployment
-	environment.DesiredTaskCount = deployment.DesiredTaskCount
+	return e.UpdateEnvironment(ctx, environment.Name, func(latest *types.Environment) error {
+		return latest.UpdateDeployment(deployment)
+	})
+}
 
-	if deployment.Health == types.DeploymentHealthy {
-		environment.Health = types.EnvironmentHealthy
-	} else {
-		environment.Health = types.EnvironmentUnhealthy
+func (e environment) UpdateEnvironment(ctx context.Context, name string,
+	update func(environment *types.Environment) error) (*types.Environment, error) {
+
+	if len(name) == 0 {
+		return nil, types.NewBadRequestError(errors.New("Environment name is missing"))
 	}
 
-	err := e.environmentStore.PutEnvironment(ctx, environment)
+	var before types.Environment
+	env, err := store.UpdateEnvironment(ctx, e.environmentStore, name, func(latest *types.Environment) error {
+		before = latest.Snapshot()
+		return update(latest)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &environment, nil
+	if e.notifier != nil {
+		e.notifier.EnvironmentUpdated(ctx, before, *env)
+	}
+	return env, nil
 }
@@ -16,6 +16,7 @@ package deployment
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
@@ -30,6 +31,7 @@ import (
 type EnvironmentTestSuite struct {
 	suite.Suite
 	environmentStore    *mocks.MockEnvironmentStore
+	notifier            *mocks.MockNotifier
 	environment         Environment
 	ctx                 context.Context
 	environment1        *types.Environment
@@ -45,10 +47,11 @@ type EnvironmentTestSuite struct {
 func (suite *EnvironmentTestSuite) SetupTest() {
 	mockCtrl := gomock.NewController(suite.T())
 	suite.environmentStore = mocks.NewMockEnvironmentStore(mockCtrl)
+	suite.notifier = mocks.NewMockNotifier(mockCtrl)
 	suite.ctx = context.TODO()
 
 	var err error
-	suite.environment, err = NewEnvironment(suite.environmentStore)
+	suite.environment, err = NewEnvironment(suite.environmentStore, nil)
 	assert.Nil(suite.T(), err, "Cannot initialize EnvironmentTestSuite")
 
 	task1 := ecs.Task{
@@ -98,28 +101,28 @@ func TestEnvironmentTestSuite(t *testing.T) {
 }
 
 func (suite *EnvironmentTestSuite) TestNewEnvironmentEmptyStore() {
-	_, err := NewEnvironment(nil)
+	_, err := NewEnvironment(nil, suite.notifier)
 	assert.Error(suite.T(), err, "Expected an error when store is nil")
 }
 
 func (suite *EnvironmentTestSuite) TestNewEnvironmentStore() {
-	e, err := NewEnvironment(suite.environmentStore)
+	e, err := NewEnvironment(suite.environmentStore, suite.notifier)
 	assert.Nil(suite.T(), err, "Unexpected error when store is not nil")
 	assert.NotNil(suite.T(), e, "Environment should not be nil")
 }
 
 func (suite *EnvironmentTestSuite) TestCreateEnvironmentEmptyName() {
-	_, err := suite.environment.CreateEnvironment(suite.ctx, "", taskDefinition, cluster1)
+	_, err := suite.environment.CreateEnvironment(suite.ctx, "", taskDefinition, cluster1, types.ClusterSelector{}, types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
 	assert.Error(suite.T(), err, "Expected an error when name is empty")
 }
 
 func (suite *EnvironmentTestSuite) TestCreateEnvironmentEmptyTaskDefinition() {
-	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, "", cluster1)
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, "", cluster1, types.ClusterSelector{}, types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
 	assert.Error(suite.T(), err, "Expected an error when taskDefinition is empty")
 }
 
 func (suite *EnvironmentTestSuite) TestCreateEnvironmentEmptyCluster() {
-	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, "")
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, "", types.ClusterSelector{}, types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
 	assert.Error(suite.T(), err, "Expected an error when cluster is empty")
 }
 
@@ -127,7 +130,7 @@ func (suite *EnvironmentTestSuite) TestCreateEnvironmentGetEnvironmentFails() {
 	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
 		Return(nil, errors.New("Get environment failed"))
 
-	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1)
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{}, types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
 	assert.Error(suite.T(), err, "Expected an error when get environment fails")
 }
 
@@ -135,7 +138,7 @@ func (suite *EnvironmentTestSuite) TestCreateEnvironmentEnvironmentExists() {
 	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
 		Return(suite.environment1, nil)
 
-	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1)
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{}, types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
 	assert.Error(suite.T(), err, "Expected an error when environment exists")
 }
 
@@ -147,7 +150,7 @@ func (suite *EnvironmentTestSuite) TestCreateEnvironmentPutEnvironmentFails() {
 		verifyEnvironment(suite.T(), suite.environment1, &e)
 	}).Return(errors.New("Put environment failed"))
 
-	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1)
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{}, types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
 	assert.Error(suite.T(), err, "Expected an error when put environment fails")
 }
 
@@ -158,11 +161,240 @@ func (suite *EnvironmentTestSuite) TestCreateEnvironment() {
 		verifyEnvironment(suite.T(), suite.environment1, &e)
 	}).Return(nil)
 
-	env, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1)
+	env, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{}, types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
 	assert.Nil(suite.T(), err, "Unexpected error when creating an environment")
 	verifyEnvironment(suite.T(), suite.environment1, env)
 }
 
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentInvalidPlacementConstraints() {
+	constraints := types.PlacementConstraints{Expressions: []string{"ecs.instance-type =~ m5.("}}
+
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{}, constraints, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Error(suite.T(), err, "Expected an error when placement constraints are invalid")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when placement constraints are invalid")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentWithPlacementConstraints() {
+	constraints := types.PlacementConstraints{
+		Expressions:       []string{"ecs.instance-type =~ m5.*"},
+		AvailabilityZones: []string{"us-east-1a"},
+	}
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
+		assert.Equal(suite.T(), constraints, e.PlacementConstraints, "Expected the placement constraints to be stored")
+	}).Return(nil)
+
+	env, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{}, constraints, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Nil(suite.T(), err, "Unexpected error when creating an environment with placement constraints")
+	assert.Equal(suite.T(), constraints, env.PlacementConstraints, "Expected the placement constraints to be set")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentInvalidRolloutStrategy() {
+	strategy := types.RolloutStrategy{BatchPercent: 150}
+
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, strategy, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Error(suite.T(), err, "Expected an error when the rollout strategy is invalid")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the rollout strategy is invalid")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentWithRolloutStrategy() {
+	strategy := types.RolloutStrategy{BatchSize: 2, MinHealthyPercent: 50, SoakTime: time.Minute}
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
+		assert.Equal(suite.T(), strategy, e.RolloutStrategy, "Expected the rollout strategy to be stored")
+	}).Return(nil)
+
+	env, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, strategy, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Nil(suite.T(), err, "Unexpected error when creating an environment with a rollout strategy")
+	assert.Equal(suite.T(), strategy, env.RolloutStrategy, "Expected the rollout strategy to be set")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentInvalidRollbackPolicy() {
+	policy := types.RollbackPolicy{CrashCount: 3}
+
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, policy, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Error(suite.T(), err, "Expected an error when the rollback policy is invalid")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the rollback policy is invalid")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentWithRollbackPolicy() {
+	policy := types.RollbackPolicy{FailedInstancePercent: 50, CrashCount: 3, CrashWindow: 5 * time.Minute}
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
+		assert.Equal(suite.T(), policy, e.RollbackPolicy, "Expected the rollback policy to be stored")
+	}).Return(nil)
+
+	env, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, policy, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Nil(suite.T(), err, "Unexpected error when creating an environment with a rollback policy")
+	assert.Equal(suite.T(), policy, env.RollbackPolicy, "Expected the rollback policy to be set")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentInvalidCapacityPolicy() {
+	capacity := types.CapacityPolicy{Priority: -1}
+
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, capacity, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Error(suite.T(), err, "Expected an error when the capacity policy is invalid")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the capacity policy is invalid")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentWithCapacityPolicy() {
+	capacity := types.CapacityPolicy{Priority: 10, StopLowerPriorityTasks: true}
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
+		assert.Equal(suite.T(), capacity, e.CapacityPolicy, "Expected the capacity policy to be stored")
+	}).Return(nil)
+
+	env, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, capacity, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Nil(suite.T(), err, "Unexpected error when creating an environment with a capacity policy")
+	assert.Equal(suite.T(), capacity, env.CapacityPolicy, "Expected the capacity policy to be set")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentInvalidTaskOverrides() {
+	overrides := types.TaskOverrides{ContainerOverrides: []types.ContainerOverride{{Name: "agent"}, {Name: "agent"}}}
+
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, overrides, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Error(suite.T(), err, "Expected an error when the task overrides are invalid")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the task overrides are invalid")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentWithTaskOverrides() {
+	overrides := types.TaskOverrides{
+		TaskRoleARN:        "arn:aws:iam::123456789012:role/agent",
+		ContainerOverrides: []types.ContainerOverride{{Name: "agent", Environment: map[string]string{"LEVEL": "debug"}}},
+	}
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
+		assert.Equal(suite.T(), overrides, e.TaskOverrides, "Expected the task overrides to be stored")
+	}).Return(nil)
+
+	env, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, overrides, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Nil(suite.T(), err, "Unexpected error when creating an environment with task overrides")
+	assert.Equal(suite.T(), overrides, env.TaskOverrides, "Expected the task overrides to be set")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentInvalidMaintenanceWindows() {
+	windows := []types.MaintenanceWindow{{Schedule: "0 2 * * mon", Duration: time.Hour}}
+
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, windows, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Error(suite.T(), err, "Expected an error when the maintenance windows are invalid")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the maintenance windows are invalid")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentWithMaintenanceWindows() {
+	windows := []types.MaintenanceWindow{{Schedule: "0 2 * * 6", Duration: time.Hour}}
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
+		assert.Equal(suite.T(), windows, e.MaintenanceWindows, "Expected the maintenance windows to be stored")
+	}).Return(nil)
+
+	env, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, windows, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Nil(suite.T(), err, "Unexpected error when creating an environment with maintenance windows")
+	assert.Equal(suite.T(), windows, env.MaintenanceWindows, "Expected the maintenance windows to be set")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentInvalidDrainingPolicy() {
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil,
+		types.DrainingPolicy("drain-whenever"), types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Error(suite.T(), err, "Expected an error when the draining policy is unknown")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the draining policy is unknown")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentInvalidSchedulingStrategy() {
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil,
+		"", types.SchedulingStrategy{Type: types.ReplicaStrategy, Replicas: -1}, types.RestartPolicy{})
+	assert.Error(suite.T(), err, "Expected an error when the replicas are negative")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the scheduling strategy is invalid")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentWithSchedulingStrategy() {
+	strategy := types.SchedulingStrategy{Type: types.ReplicaStrategy, Replicas: 3}
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
+		assert.Equal(suite.T(), strategy, e.SchedulingStrategy, "Expected the scheduling strategy to be stored")
+	}).Return(nil)
+
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil,
+		"", strategy, types.RestartPolicy{})
+	assert.Nil(suite.T(), err, "Unexpected error when creating an environment with a scheduling strategy")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentInvalidRestartPolicy() {
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil,
+		"", types.SchedulingStrategy{}, types.RestartPolicy{MaxRestarts: -1})
+	assert.Error(suite.T(), err, "Expected an error when the restart policy is invalid")
+	_, ok := err.(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the restart policy is invalid")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentWithRestartPolicy() {
+	policy := types.RestartPolicy{MaxRestarts: 3, StableTime: time.Minute}
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
+		assert.Equal(suite.T(), policy, e.RestartPolicy, "Expected the restart policy to be stored")
+	}).Return(nil)
+
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil,
+		"", types.SchedulingStrategy{}, policy)
+	assert.Nil(suite.T(), err, "Unexpected error when creating an environment with a restart policy")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentWithDrainingPolicy() {
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
+		assert.Equal(suite.T(), types.DrainingStopImmediately, e.DrainingPolicy, "Expected the draining policy to be stored")
+	}).Return(nil)
+
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1, types.ClusterSelector{},
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil,
+		types.DrainingStopImmediately, types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Nil(suite.T(), err, "Unexpected error when creating an environment with a draining policy")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentWithClusterSelector() {
+	selector := types.ClusterSelector{NamePattern: "test*"}
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
+		assert.Equal(suite.T(), selector, e.ClusterSelector, "Expected the cluster selector to be stored")
+	}).Return(nil)
+
+	env, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, "", selector,
+		types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Nil(suite.T(), err, "Unexpected error when creating an environment with a cluster selector")
+	assert.Empty(suite.T(), env.Cluster, "Expected no single cluster")
+	assert.Equal(suite.T(), selector, env.ClusterSelector, "Expected the cluster selector to be set")
+}
+
+func (suite *EnvironmentTestSuite) TestCreateEnvironmentWithClusterAndClusterSelector() {
+	_, err := suite.environment.CreateEnvironment(suite.ctx, environmentName1, taskDefinition, cluster1,
+		types.ClusterSelector{NamePattern: "test*"}, types.PlacementConstraints{}, types.RolloutStrategy{}, types.RollbackPolicy{}, types.CapacityPolicy{}, types.TaskOverrides{}, nil, "", types.SchedulingStrategy{}, types.RestartPolicy{})
+	assert.Error(suite.T(), err, "Expected an error when both a cluster and a cluster selector are set")
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when both a cluster and a cluster selector are set")
+}
+
 func (suite *EnvironmentTestSuite) TestGetEnvironmentEmptyName() {
 	_, err := suite.environment.GetEnvironment(suite.ctx, "")
 	assert.Error(suite.T(), err, "Expected an error when name is empty")
@@ -247,6 +479,42 @@ func (suite *EnvironmentTestSuite) TestDeleteEnvironmentGetEnvironmentReturnsEmp
 	assert.Nil(suite.T(), observedErr, "Unexpected error when deleting a missing environment")
 }
 
+func (suite *EnvironmentTestSuite) TestDrainEnvironment() {
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Return(nil)
+
+	env, err := suite.environment.DrainEnvironment(suite.ctx, environmentName1, time.Minute)
+	assert.Nil(suite.T(), err, "Unexpected error when draining the environment")
+	assert.True(suite.T(), env.IsDeleting(), "Expected the environment to be deleting")
+	assert.WithinDuration(suite.T(), time.Now().Add(time.Minute), env.DrainDeadline, time.Second,
+		"Expected the drain deadline to be a minute away")
+}
+
+func (suite *EnvironmentTestSuite) TestDrainEnvironmentDoesNotExist() {
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+
+	env, err := suite.environment.DrainEnvironment(suite.ctx, environmentName1, time.Minute)
+	assert.Nil(suite.T(), err, "Unexpected error when draining a missing environment")
+	assert.Nil(suite.T(), env, "Expected no environment")
+}
+
+func (suite *EnvironmentTestSuite) TestDrainEnvironmentNegativeTimeout() {
+	_, err := suite.environment.DrainEnvironment(suite.ctx, environmentName1, -time.Second)
+	assert.IsType(suite.T(), types.BadRequestError{}, err, "Expected a bad request when the timeout is negative")
+}
+
+func (suite *EnvironmentTestSuite) TestDeleteDrainedEnvironment() {
+	err := suite.environment.DeleteDrainedEnvironment(suite.ctx, *suite.environment1)
+	assert.Error(suite.T(), err, "Expected an error when the environment is not being deleted")
+
+	suite.environment1.StartDeleting(time.Now())
+	suite.environmentStore.EXPECT().DeleteEnvironment(suite.ctx, *suite.environment1).Return(nil)
+
+	err = suite.environment.DeleteDrainedEnvironment(suite.ctx, *suite.environment1)
+	assert.Nil(suite.T(), err, "Unexpected error when deleting a drained environment")
+}
+
 func (suite *EnvironmentTestSuite) TestListEnvironmentsListFromStoreFails() {
 	suite.environmentStore.EXPECT().ListEnvironments(suite.ctx).
 		Return(nil, errors.New("List failed"))
@@ -318,6 +586,22 @@ func (suite *EnvironmentTestSuite) filterEnvironmentsByCluster(cluster string) {
 	assert.Exactly(suite.T(), expectedEnvs, envs, "Returned filtered environments does not match expected environments")
 }
 
+func (suite *EnvironmentTestSuite) TestFilterEnvironmentsByClusterTags() {
+	tagged, err := types.NewEnvironmentForClusters(environmentName3, taskDefinition,
+		types.ClusterSelector{Tags: map[string]string{"team": "blox"}})
+	assert.Nil(suite.T(), err, "Unexpected error creating new environment")
+	notDeployed, err := types.NewEnvironmentForClusters(environmentName2, taskDefinition, tagged.ClusterSelector)
+	assert.Nil(suite.T(), err, "Unexpected error creating new environment")
+	tagged.Deployments["1"] = types.Deployment{ID: "1", Clusters: map[string]types.ClusterDeployment{cluster1: {}}}
+
+	suite.environmentStore.EXPECT().ListEnvironments(suite.ctx).Return([]types.Environment{*tagged, *notDeployed}, nil)
+
+	envs, err := suite.environment.FilterEnvironments(suite.ctx, clusterFilter, clusterName1)
+	assert.Nil(suite.T(), err, "Unexpected error when filtering environments")
+	assert.Exactly(suite.T(), []types.Environment{*tagged}, envs,
+		"Expected only the environment selecting clusters by tag that was deployed to the cluster")
+}
+
 func (suite *EnvironmentTestSuite) TestFilterEnvironmentsByClusterInvalidCluster() {
 	suite.environmentStore.EXPECT().ListEnvironments(gomock.Any()).Times(0)
 
@@ -341,8 +625,12 @@ func (suite *EnvironmentTestSuite) TestAddDeploymentDeploymentExists() {
 }
 
 func (suite *EnvironmentTestSuite) TestAddDeploymentPutEnvironmentFails() {
+	updatedEnv := storedEnvironment(suite.environment1)
+	updatedEnv.Deployments[suite.deployment.ID] = *suite.deployment
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
 	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
-		verifyEnvironment(suite.T(), suite.environment1, &e)
+		verifyEnvironment(suite.T(), updatedEnv, &e)
 	}).Return(errors.New("Put environment failed"))
 
 	_, err := suite.environment.AddPendingDeployment(suite.ctx, *suite.environment1, *suite.deployment)
@@ -350,15 +638,30 @@ func (suite *EnvironmentTestSuite) TestAddDeploymentPutEnvironmentFails() {
 }
 
 func (suite *EnvironmentTestSuite) TestAddDeployment() {
-	updatedEnv := suite.environment1
+	updatedEnv := storedEnvironment(suite.environment1)
 	updatedEnv.PendingDeploymentID = suite.deployment.ID
+	updatedEnv.Deployments[suite.deployment.ID] = *suite.deployment
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
 	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Eq(*updatedEnv)).Return(nil)
 
 	env, err := suite.environment.AddPendingDeployment(suite.ctx, *suite.environment1, *suite.deployment)
 	assert.Nil(suite.T(), err, "Unexpected error when adding a deployment")
 
-	suite.environment1.Deployments[suite.deployment.ID] = *suite.deployment
-	assert.Exactly(suite.T(), suite.environment1, env, "Environment does not match the expected environment")
+	assert.Exactly(suite.T(), updatedEnv, env, "Environment does not match the expected environment")
+}
+
+func (suite *EnvironmentTestSuite) TestAddDeploymentEnvironmentModified() {
+	latest := storedEnvironment(suite.environment1)
+	otherDeployment, err := types.NewDeployment(taskDefinition, latest.Token)
+	assert.Nil(suite.T(), err, "Could not create a new deployment")
+	latest.AddPendingDeployment(*otherDeployment)
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(latest, nil)
+
+	_, err = suite.environment.AddPendingDeployment(suite.ctx, *suite.environment1, *suite.deployment)
+	assert.Error(suite.T(), err, "Expected an error when the environment was modified")
+	_, ok := errors.Cause(err).(types.ConflictError)
+	assert.True(suite.T(), ok, "Expected a conflict error when the environment was modified")
 }
 
 func (suite *EnvironmentTestSuite) TestUpdateDeploymentEmptyDeploymentID() {
@@ -376,6 +679,8 @@ func (suite *EnvironmentTestSuite) TestUpdateDeploymentDeploymentDoesNotExist()
 func (suite *EnvironmentTestSuite) TestUpdateDeploymentPutEnvironmentFails() {
 	suite.environment1.Deployments[suite.deployment.ID] = *suite.deployment
 
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
 	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
 		verifyEnvironment(suite.T(), suite.updatedEnvironment, &e)
 	}).Return(errors.New("Put environment failed"))
@@ -390,6 +695,8 @@ func (suite *EnvironmentTestSuite) TestUpdateDeploymentUnhealthy() {
 	suite.updatedEnvironment.Deployments[suite.unhealthyDeployment.ID] = *suite.unhealthyDeployment
 	suite.updatedEnvironment.Health = types.EnvironmentUnhealthy
 
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
 	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
 		verifyEnvironment(suite.T(), suite.updatedEnvironment, &e)
 	}).Return(nil)
@@ -404,6 +711,8 @@ func (suite *EnvironmentTestSuite) TestUpdateDeploymentEnvironmentDoesNotHaveCur
 
 	suite.updatedEnvironment.Deployments[suite.deployment.ID] = *suite.updatedDeployment
 
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
 	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
 		verifyEnvironment(suite.T(), suite.updatedEnvironment, &e)
 	}).Return(nil)
@@ -418,6 +727,8 @@ func (suite *EnvironmentTestSuite) TestUpdateDeploymentEnvironmentCurrentTasksCo
 
 	suite.updatedEnvironment.Deployments[suite.deployment.ID] = *suite.updatedDeployment
 
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
 	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
 		verifyEnvironment(suite.T(), suite.updatedEnvironment, &e)
 	}).Return(nil)
@@ -435,6 +746,8 @@ func (suite *EnvironmentTestSuite) TestUpdateDeploymentEnvironmentCurrentTasksCo
 
 	suite.updatedEnvironment.Deployments[suite.deployment.ID] = *suite.updatedDeployment
 
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
 	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Do(func(_ interface{}, e types.Environment) {
 		verifyEnvironment(suite.T(), suite.updatedEnvironment, &e)
 	}).Return(nil)
@@ -444,6 +757,16 @@ func (suite *EnvironmentTestSuite) TestUpdateDeploymentEnvironmentCurrentTasksCo
 	verifyEnvironment(suite.T(), suite.updatedEnvironment, env)
 }
 
+// storedEnvironment returns a copy of environment as it would be read from the store
+func storedEnvironment(environment *types.Environment) *types.Environment {
+	stored := *environment
+	stored.Deployments = make(map[string]types.Deployment)
+	for id, d := range environment.Deployments {
+		stored.Deployments[id] = d
+	}
+	return &stored
+}
+
 func verifyEnvironment(t *testing.T, expected *types.Environment, actual *types.Environment) {
 	assert.NotNil(t, actual, "Environment should not be nil")
 	assert.Exactly(t, expected.Name, actual.Name, "Name should match")
@@ -453,3 +776,120 @@ func verifyEnvironment(t *testing.T, expected *types.Environment, actual *types.
 	assert.Exactly(t, expected.Health, actual.Health, "Health should match")
 	assert.Exactly(t, expected.Deployments, actual.Deployments, "Deployments should match")
 }
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentSettingsEmptyToken() {
+	_, _, err := suite.environment.UpdateEnvironmentSettings(suite.ctx, environmentName1, "",
+		types.EnvironmentUpdate{}, false)
+	assert.IsType(suite.T(), types.BadRequestError{}, err, "Expected a bad request when the token is missing")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentSettingsInvalidUpdate() {
+	_, _, err := suite.environment.UpdateEnvironmentSettings(suite.ctx, environmentName1, suite.environment1.Token,
+		types.EnvironmentUpdate{RolloutStrategy: &types.RolloutStrategy{BatchPercent: 101}}, false)
+	assert.IsType(suite.T(), types.BadRequestError{}, err, "Expected a bad request when the update is invalid")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentSettingsOutdatedToken() {
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
+
+	_, _, err := suite.environment.UpdateEnvironmentSettings(suite.ctx, environmentName1, "outdated-token",
+		types.EnvironmentUpdate{TaskDefinition: aws.String(taskDefinition2)}, false)
+	_, ok := errors.Cause(err).(types.ConflictError)
+	assert.True(suite.T(), ok, "Expected a conflict error when the token is outdated")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentSettings() {
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Return(nil)
+
+	env, d, err := suite.environment.UpdateEnvironmentSettings(suite.ctx, environmentName1, suite.environment1.Token,
+		types.EnvironmentUpdate{TaskDefinition: aws.String(taskDefinition2)}, false)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the environment")
+	assert.Nil(suite.T(), d, "Expected no deployment to be started")
+	assert.Exactly(suite.T(), taskDefinition2, env.DesiredTaskDefinition, "Expected the updated task definition")
+	assert.Exactly(suite.T(), cluster1, env.Cluster, "Expected the cluster to be left unchanged")
+	assert.NotEqual(suite.T(), suite.environment1.Token, env.Token, "Expected the token to be rotated")
+	assert.Empty(suite.T(), env.Deployments, "Expected no deployment to be added")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentSettingsWithDeployment() {
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Return(nil)
+
+	env, d, err := suite.environment.UpdateEnvironmentSettings(suite.ctx, environmentName1, suite.environment1.Token,
+		types.EnvironmentUpdate{TaskDefinition: aws.String(taskDefinition2)}, true)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the environment")
+	assert.NotNil(suite.T(), d, "Expected a deployment to be started")
+	assert.Exactly(suite.T(), taskDefinition2, d.TaskDefinition, "Expected the updated task definition to be deployed")
+	assert.Exactly(suite.T(), env.Token, d.Token, "Expected the deployment to be recorded with the rotated token")
+	assert.Exactly(suite.T(), d.ID, env.PendingDeploymentID, "Expected the deployment to be pending")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentSettingsWithDeploymentInProgress() {
+	stored := storedEnvironment(suite.environment1)
+	err := stored.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(stored, nil)
+
+	_, _, err = suite.environment.UpdateEnvironmentSettings(suite.ctx, environmentName1, suite.environment1.Token,
+		types.EnvironmentUpdate{TaskDefinition: aws.String(taskDefinition2)}, true)
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when a deployment is in progress")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentNotifies() {
+	e, err := NewEnvironment(suite.environmentStore, suite.notifier)
+	assert.Nil(suite.T(), err, "Unexpected error when creating the environment service")
+
+	stored := storedEnvironment(suite.environment1)
+	err = stored.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(stored, nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Return(nil)
+	suite.notifier.EXPECT().EnvironmentUpdated(suite.ctx, gomock.Any(), gomock.Any()).
+		Do(func(_ interface{}, before types.Environment, after types.Environment) {
+			assert.Exactly(suite.T(), types.DeploymentPending, before.Deployments[suite.deployment.ID].Status,
+				"Expected the environment before the update")
+			assert.Exactly(suite.T(), types.DeploymentInProgress, after.Deployments[suite.deployment.ID].Status,
+				"Expected the updated environment")
+		})
+
+	_, err = e.UpdateEnvironment(suite.ctx, environmentName1, func(latest *types.Environment) error {
+		d := latest.Deployments[suite.deployment.ID]
+		d.Status = types.DeploymentInProgress
+		latest.Deployments[d.ID] = d
+		return nil
+	})
+	assert.Nil(suite.T(), err, "Unexpected error when updating the environment")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentFailsDoesNotNotify() {
+	e, err := NewEnvironment(suite.environmentStore, suite.notifier)
+	assert.Nil(suite.T(), err, "Unexpected error when creating the environment service")
+
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).
+		Return(storedEnvironment(suite.environment1), nil)
+	suite.environmentStore.EXPECT().PutEnvironment(suite.ctx, gomock.Any()).Return(errors.New("Put environment failed"))
+	suite.notifier.EXPECT().EnvironmentUpdated(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	_, err = e.UpdateEnvironment(suite.ctx, environmentName1, func(latest *types.Environment) error {
+		return nil
+	})
+	assert.Error(suite.T(), err, "Expected an error when put fails")
+}
+
+func (suite *EnvironmentTestSuite) TestDeleteEnvironmentNotifies() {
+	e, err := NewEnvironment(suite.environmentStore, suite.notifier)
+	assert.Nil(suite.T(), err, "Unexpected error when creating the environment service")
+
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(suite.environment1, nil)
+	suite.environmentStore.EXPECT().DeleteEnvironment(suite.ctx, *suite.environment1).Return(nil)
+	suite.notifier.EXPECT().EnvironmentDeleted(suite.ctx, environmentName1)
+
+	err = e.DeleteEnvironment(suite.ctx, environmentName1)
+	assert.Nil(suite.T(), err, "Unexpected error when deleting environment")
+}
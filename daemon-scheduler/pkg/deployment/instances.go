@@ -0,0 +1,149 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/pkg/errors"
+)
+
+func (d deployment) ListDeploymentInstances(ctx context.Context, environmentName string, id string) ([]types.DeploymentInstance, error) {
+	if len(environmentName) == 0 {
+		return nil, types.NewBadRequestError(errors.New("Environment name is missing"))
+	}
+
+	env, err := d.getEnvironmentOrFailIfDoesNotExist(ctx, environmentName)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, ok := env.Deployments[id]
+	if !ok {
+		return nil, types.NewNotFoundError(errors.Errorf("Deployment %s does not exist for environment %s", id, environmentName))
+	}
+
+	instances := make(map[string]*types.DeploymentInstance)
+	instance := func(cluster string, instanceARN string) *types.DeploymentInstance {
+		i, ok := instances[instanceARN]
+		if !ok {
+			i = &types.DeploymentInstance{Cluster: cluster, InstanceARN: instanceARN}
+			instances[instanceARN] = i
+		}
+		return i
+	}
+
+	failures := make(map[string][]*ecs.Failure)
+	insufficientCapacity := make(map[string][]*ecs.Failure)
+	if len(deployment.Clusters) == 0 && env.Cluster != "" {
+		failures[env.Cluster] = deployment.FailedInstances
+		insufficientCapacity[env.Cluster] = deployment.InsufficientCapacity
+	}
+	for cluster, clusterDeployment := range deployment.Clusters {
+		failures[cluster] = clusterDeployment.FailedInstances
+		insufficientCapacity[cluster] = clusterDeployment.InsufficientCapacity
+	}
+
+	for cluster, clusterFailures := range failures {
+		tasks, err := d.clusterState.ListTasks(cluster)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error listing the tasks of cluster %s", cluster)
+		}
+		for _, task := range tasks {
+			if task.StartedBy != id {
+				continue
+			}
+			i := instance(cluster, aws.StringValue(task.ContainerInstanceARN))
+			i.Tasks = append(i.Tasks, toDeploymentTask(task))
+		}
+		for _, failure := range clusterFailures {
+			instance(cluster, aws.StringValue(failure.Arn)).FailureReason = aws.StringValue(failure.Reason)
+		}
+	}
+
+	// instances without the capacity for the task are retried by the scheduler, so their reason
+	// is only reported if the deployment did not otherwise fail on them
+	for cluster, clusterInsufficient := range insufficientCapacity {
+		for _, failure := range clusterInsufficient {
+			i := instance(cluster, aws.StringValue(failure.Arn))
+			if i.FailureReason == "" {
+				i.FailureReason = aws.StringValue(failure.Reason)
+			}
+		}
+	}
+
+	result := make([]types.DeploymentInstance, 0, len(instances))
+	for _, i := range instances {
+		sort.Sort(latestTasks(i.Tasks))
+		result = append(result, *i)
+	}
+	sort.Sort(instanceOrderedDeploymentInstances(result))
+	return result, nil
+}
+
+// toDeploymentTask returns the state of the task as recorded by the cluster state
+func toDeploymentTask(task *models.Task) types.DeploymentTask {
+	return types.DeploymentTask{
+		TaskARN:       aws.StringValue(task.TaskARN),
+		LastStatus:    aws.StringValue(task.LastStatus),
+		StartedAt:     parseTaskTime(task.StartedAt),
+		StoppedAt:     parseTaskTime(task.StoppedAt),
+		StoppedReason: task.StoppedReason,
+	}
+}
+
+// parseTaskTime returns the time recorded by the cluster state, or the zero time if it is not set
+func parseTaskTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+type instanceOrderedDeploymentInstances []types.DeploymentInstance
+
+func (p instanceOrderedDeploymentInstances) Len() int { return len(p) }
+
+func (p instanceOrderedDeploymentInstances) Less(i, j int) bool {
+	if p[i].Cluster != p[j].Cluster {
+		return p[i].Cluster < p[j].Cluster
+	}
+	return p[i].InstanceARN < p[j].InstanceARN
+}
+
+func (p instanceOrderedDeploymentInstances) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+// latestTasks orders tasks latest first. Tasks that have not started yet are the latest.
+type latestTasks []types.DeploymentTask
+
+func (p latestTasks) Len() int { return len(p) }
+
+func (p latestTasks) Less(i, j int) bool {
+	if p[i].StartedAt.Equal(p[j].StartedAt) {
+		return p[i].TaskARN < p[j].TaskARN
+	}
+	if p[i].StartedAt.IsZero() || p[j].StartedAt.IsZero() {
+		return p[i].StartedAt.IsZero()
+	}
+	return p[i].StartedAt.After(p[j].StartedAt)
+}
+
+func (p latestTasks) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
@@ -0,0 +1,156 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package deployment
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *DeploymentTestSuite) TestListDeploymentInstancesMissingDeployment() {
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+
+	_, err := suite.deployment.ListDeploymentInstances(suite.ctx, environmentName, suite.deploymentObject.ID)
+	_, ok := errors.Cause(err).(types.NotFoundError)
+	assert.True(suite.T(), ok, "Expected a not found error when the deployment does not exist")
+}
+
+func (suite *DeploymentTestSuite) TestListDeploymentInstancesListTasksFails() {
+	suite.environmentObject.Deployments[suite.deploymentObject.ID] = *suite.deploymentObject
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+	suite.clusterState.EXPECT().ListTasks(cluster1).Return(nil, errors.New("ListTasks failed"))
+
+	_, err := suite.deployment.ListDeploymentInstances(suite.ctx, environmentName, suite.deploymentObject.ID)
+	assert.Error(suite.T(), err, "Expected an error when listing the tasks fails")
+}
+
+func (suite *DeploymentTestSuite) TestListDeploymentInstances() {
+	startedAt := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+	suite.deploymentObject.FailedInstances = []*ecs.Failure{{Arn: aws.String(instanceARN2), Reason: aws.String("AGENT")}}
+	suite.environmentObject.Deployments[suite.deploymentObject.ID] = *suite.deploymentObject
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+
+	stopped := suite.deploymentTask(taskARN1, "STOPPED", startedAt)
+	stopped.StoppedAt = startedAt.Add(time.Minute).Format(time.RFC3339)
+	stopped.StoppedReason = "Essential container in task exited"
+	running := suite.deploymentTask(taskARN2, "RUNNING", startedAt.Add(time.Hour))
+	other := suite.deploymentTask(taskARN1, "RUNNING", startedAt)
+	other.StartedBy = "otherDeployment"
+	other.ContainerInstanceARN = aws.String(instanceARN2)
+	suite.clusterState.EXPECT().ListTasks(cluster1).Return([]*models.Task{stopped, other, running}, nil)
+
+	instances, err := suite.deployment.ListDeploymentInstances(suite.ctx, environmentName, suite.deploymentObject.ID)
+	assert.Nil(suite.T(), err, "Unexpected error when listing deployment instances")
+
+	expected := []types.DeploymentInstance{
+		{
+			Cluster:       cluster1,
+			InstanceARN:   instanceARN2,
+			FailureReason: "AGENT",
+		},
+		{
+			Cluster:     cluster1,
+			InstanceARN: instanceARN1,
+			Tasks: []types.DeploymentTask{
+				{TaskARN: taskARN2, LastStatus: "RUNNING", StartedAt: startedAt.Add(time.Hour)},
+				{
+					TaskARN:       taskARN1,
+					LastStatus:    "STOPPED",
+					StartedAt:     startedAt,
+					StoppedAt:     startedAt.Add(time.Minute),
+					StoppedReason: "Essential container in task exited",
+				},
+			},
+		},
+	}
+	assert.Equal(suite.T(), expected, instances, "Expected the tasks and failures of the deployment by instance")
+}
+
+func (suite *DeploymentTestSuite) TestListDeploymentInstancesInClusters() {
+	suite.deploymentObject.Clusters = map[string]types.ClusterDeployment{
+		cluster1: {},
+		cluster2: {FailedInstances: []*ecs.Failure{{Arn: aws.String(instanceARN2), Reason: aws.String("AGENT")}}},
+	}
+	suite.environmentObject.Cluster = ""
+	suite.environmentObject.Deployments[suite.deploymentObject.ID] = *suite.deploymentObject
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+
+	pending := suite.deploymentTask(taskARN1, "PENDING", time.Time{})
+	suite.clusterState.EXPECT().ListTasks(cluster1).Return([]*models.Task{pending}, nil)
+	suite.clusterState.EXPECT().ListTasks(cluster2).Return([]*models.Task{}, nil)
+
+	instances, err := suite.deployment.ListDeploymentInstances(suite.ctx, environmentName, suite.deploymentObject.ID)
+	assert.Nil(suite.T(), err, "Unexpected error when listing deployment instances")
+
+	expected := []types.DeploymentInstance{
+		{
+			Cluster:     cluster1,
+			InstanceARN: instanceARN1,
+			Tasks:       []types.DeploymentTask{{TaskARN: taskARN1, LastStatus: "PENDING"}},
+		},
+		{
+			Cluster:       cluster2,
+			InstanceARN:   instanceARN2,
+			FailureReason: "AGENT",
+		},
+	}
+	assert.Equal(suite.T(), expected, instances, "Expected the instances of every cluster of the deployment")
+}
+
+func (suite *DeploymentTestSuite) TestListDeploymentInstancesInsufficientCapacityInClusters() {
+	insufficient := []*ecs.Failure{{Arn: aws.String(instanceARN), Reason: aws.String("RESOURCE:MEMORY")}}
+	suite.deploymentObject.Clusters = map[string]types.ClusterDeployment{
+		cluster1: {},
+		cluster2: {InsufficientCapacity: insufficient},
+	}
+	suite.deploymentObject.InsufficientCapacity = insufficient
+	suite.environmentObject.Cluster = ""
+	suite.environmentObject.Deployments[suite.deploymentObject.ID] = *suite.deploymentObject
+	suite.environment.EXPECT().GetEnvironment(suite.ctx, environmentName).Return(suite.environmentObject, nil)
+	suite.clusterState.EXPECT().ListTasks(cluster1).Return([]*models.Task{}, nil)
+	suite.clusterState.EXPECT().ListTasks(cluster2).Return([]*models.Task{}, nil)
+
+	instances, err := suite.deployment.ListDeploymentInstances(suite.ctx, environmentName, suite.deploymentObject.ID)
+	assert.Nil(suite.T(), err, "Unexpected error when listing deployment instances")
+
+	expected := []types.DeploymentInstance{
+		{
+			Cluster:       cluster2,
+			InstanceARN:   instanceARN,
+			FailureReason: "RESOURCE:MEMORY",
+		},
+	}
+	assert.Equal(suite.T(), expected, instances, "Expected the instance short of capacity in its own cluster")
+}
+
+// deploymentTask returns a task of the deployment on instanceARN1, which is not running yet if startedAt is zero
+func (suite *DeploymentTestSuite) deploymentTask(taskARN string, status string, startedAt time.Time) *models.Task {
+	task := &models.Task{
+		ClusterARN:           aws.String(cluster1),
+		ContainerInstanceARN: aws.String(instanceARN1),
+		TaskARN:              aws.String(taskARN),
+		LastStatus:           aws.String(status),
+		StartedBy:            suite.deploymentObject.ID,
+	}
+	if !startedAt.IsZero() {
+		task.StartedAt = startedAt.Format(time.RFC3339)
+	}
+	return task
+}
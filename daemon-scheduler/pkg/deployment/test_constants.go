@@ -19,6 +19,7 @@ const (
 	environmentName2 = "environmentName2"
 	environmentName3 = "environmentName3"
 	taskDefinition   = "arn:aws:ecs:us-east-1:12345678912:task-definition/test"
+	taskDefinition2  = "arn:aws:ecs:us-east-1:12345678912:task-definition/test:2"
 	taskARN1         = "arn:aws:ecs:us-east-1:12345678912:task/c024d145-093b-499a-9b14-5baf273f5835"
 	taskARN2         = "arn:aws:ecs:us-east-1:12345678912:task/a1d71628-01e3-4013-b18c-6e14032a9522"
 	clusterName1     = "test1"
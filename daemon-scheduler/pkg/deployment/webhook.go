@@ -0,0 +1,159 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package deployment
+
+import (
+	"context"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/store"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// Webhook manages the webhooks environment changes are posted to
+type Webhook interface {
+	// CreateWebhook subscribes url to the events of the environment with the provided name, or to
+	// all of them if events is empty. Payloads are signed with secret.
+	CreateWebhook(ctx context.Context, environmentName string, url string, secret string,
+		events []types.WebhookEventType) (*types.Webhook, error)
+	// GetWebhook returns nil if the environment has no webhook with the provided ID
+	GetWebhook(ctx context.Context, environmentName string, id string) (*types.Webhook, error)
+	// ListWebhooks returns the webhooks of the environment ordered by creation time
+	ListWebhooks(ctx context.Context, environmentName string) ([]types.Webhook, error)
+	// DeleteWebhook deletes the webhook along with its deliveries. It returns nil if the webhook
+	// does not exist.
+	DeleteWebhook(ctx context.Context, environmentName string, id string) error
+	// ListWebhookDeliveries returns the latest deliveries to the webhook, latest first
+	ListWebhookDeliveries(ctx context.Context, environmentName string, id string) ([]types.WebhookDelivery, error)
+}
+
+type webhook struct {
+	environmentStore store.EnvironmentStore
+	webhookStore     store.WebhookStore
+}
+
+func NewWebhook(environmentStore store.EnvironmentStore, webhookStore store.WebhookStore) (Webhook, error) {
+	if environmentStore == nil {
+		return nil, errors.New("Environment store is not initialized")
+	}
+	if webhookStore == nil {
+		return nil, errors.New("Webhook store is not initialized")
+	}
+	return webhook{
+		environmentStore: environmentStore,
+		webhookStore:     webhookStore,
+	}, nil
+}
+
+func (w webhook) CreateWebhook(ctx context.Context, environmentName string, url string, secret string,
+	events []types.WebhookEventType) (*types.Webhook, error) {
+
+	hook, err := types.NewWebhook(environmentName, url, secret, events)
+	if err != nil {
+		return nil, types.NewBadRequestError(errors.Wrapf(err, "Invalid webhook"))
+	}
+
+	err = w.verifyEnvironmentExists(ctx, environmentName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.webhookStore.PutWebhook(ctx, *hook)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error saving webhook of environment %s to store", environmentName)
+	}
+
+	log.Infof("Created webhook %s of environment %s", hook.ID, environmentName)
+	return hook, nil
+}
+
+func (w webhook) GetWebhook(ctx context.Context, environmentName string, id string) (*types.Webhook, error) {
+	if len(environmentName) == 0 {
+		return nil, types.NewBadRequestError(errors.New("Environment name is missing"))
+	}
+	if len(id) == 0 {
+		return nil, types.NewBadRequestError(errors.New("Webhook ID is missing"))
+	}
+
+	hook, err := w.webhookStore.GetWebhook(ctx, environmentName, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error loading webhook %s of environment %s from store", id, environmentName)
+	}
+	return hook, nil
+}
+
+func (w webhook) ListWebhooks(ctx context.Context, environmentName string) ([]types.Webhook, error) {
+	err := w.verifyEnvironmentExists(ctx, environmentName)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := w.webhookStore.ListWebhooks(ctx, environmentName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error loading webhooks of environment %s from store", environmentName)
+	}
+	return hooks, nil
+}
+
+func (w webhook) DeleteWebhook(ctx context.Context, environmentName string, id string) error {
+	hook, err := w.GetWebhook(ctx, environmentName, id)
+	if err != nil {
+		return err
+	}
+
+	if hook == nil {
+		log.Infof("Webhook %s of environment %s does not exist", id, environmentName)
+		return nil
+	}
+
+	err = w.webhookStore.DeleteWebhook(ctx, *hook)
+	if err != nil {
+		return errors.Wrapf(err, "Error deleting webhook %s of environment %s from store", id, environmentName)
+	}
+	return nil
+}
+
+func (w webhook) ListWebhookDeliveries(ctx context.Context, environmentName string, id string) ([]types.WebhookDelivery, error) {
+	hook, err := w.GetWebhook(ctx, environmentName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if hook == nil {
+		return nil, types.NewNotFoundError(errors.Errorf("Webhook %s does not exist for environment %s", id, environmentName))
+	}
+
+	deliveries, err := w.webhookStore.ListDeliveries(ctx, environmentName, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error loading deliveries of webhook %s from store", id)
+	}
+	return deliveries, nil
+}
+
+func (w webhook) verifyEnvironmentExists(ctx context.Context, environmentName string) error {
+	if len(environmentName) == 0 {
+		return types.NewBadRequestError(errors.New("Environment name is missing"))
+	}
+
+	env, err := w.environmentStore.GetEnvironment(ctx, environmentName)
+	if err != nil {
+		return errors.Wrapf(err, "Error loading environment %s from store", environmentName)
+	}
+
+	if env == nil {
+		return types.NewNotFoundError(errors.Errorf("Environment %s does not exist", environmentName))
+	}
+	return nil
+}
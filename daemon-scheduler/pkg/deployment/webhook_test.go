@@ -0,0 +1,160 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/mocks"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	webhookURL    = "https://hooks.example.com/blox"
+	webhookSecret = "secret"
+	webhookID     = "webhookID"
+)
+
+type WebhookTestSuite struct {
+	suite.Suite
+	environmentStore *mocks.MockEnvironmentStore
+	webhookStore     *mocks.MockWebhookStore
+	webhook          Webhook
+	ctx              context.Context
+	environment      *types.Environment
+	hook             types.Webhook
+}
+
+func (suite *WebhookTestSuite) SetupTest() {
+	mockCtrl := gomock.NewController(suite.T())
+	suite.environmentStore = mocks.NewMockEnvironmentStore(mockCtrl)
+	suite.webhookStore = mocks.NewMockWebhookStore(mockCtrl)
+	suite.ctx = context.TODO()
+
+	var err error
+	suite.webhook, err = NewWebhook(suite.environmentStore, suite.webhookStore)
+	assert.Nil(suite.T(), err, "Cannot initialize WebhookTestSuite")
+
+	suite.environment, err = types.NewEnvironment(environmentName1, taskDefinition, cluster1)
+	assert.Nil(suite.T(), err, "Cannot initialize WebhookTestSuite")
+
+	suite.hook = types.Webhook{
+		ID:              webhookID,
+		EnvironmentName: environmentName1,
+		URL:             webhookURL,
+		Secret:          webhookSecret,
+	}
+}
+
+func TestWebhookTestSuite(t *testing.T) {
+	suite.Run(t, new(WebhookTestSuite))
+}
+
+func (suite *WebhookTestSuite) TestNewWebhookEmptyStores() {
+	_, err := NewWebhook(nil, suite.webhookStore)
+	assert.Error(suite.T(), err, "Expected an error when the environment store is nil")
+	_, err = NewWebhook(suite.environmentStore, nil)
+	assert.Error(suite.T(), err, "Expected an error when the webhook store is nil")
+}
+
+func (suite *WebhookTestSuite) TestCreateWebhookInvalidURL() {
+	_, err := suite.webhook.CreateWebhook(suite.ctx, environmentName1, "hooks.example.com", webhookSecret, nil)
+	_, ok := errors.Cause(err).(types.BadRequestError)
+	assert.True(suite.T(), ok, "Expected a bad request error when the URL is invalid")
+}
+
+func (suite *WebhookTestSuite) TestCreateWebhookEnvironmentDoesNotExist() {
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+
+	_, err := suite.webhook.CreateWebhook(suite.ctx, environmentName1, webhookURL, webhookSecret, nil)
+	_, ok := errors.Cause(err).(types.NotFoundError)
+	assert.True(suite.T(), ok, "Expected a not found error when the environment does not exist")
+}
+
+func (suite *WebhookTestSuite) TestCreateWebhookPutFails() {
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(suite.environment, nil)
+	suite.webhookStore.EXPECT().PutWebhook(suite.ctx, gomock.Any()).Return(errors.New("Put failed"))
+
+	_, err := suite.webhook.CreateWebhook(suite.ctx, environmentName1, webhookURL, webhookSecret, nil)
+	assert.Error(suite.T(), err, "Expected an error when put fails")
+}
+
+func (suite *WebhookTestSuite) TestCreateWebhook() {
+	events := []types.WebhookEventType{types.WebhookDeploymentCompleted}
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(suite.environment, nil)
+	suite.webhookStore.EXPECT().PutWebhook(suite.ctx, gomock.Any()).Do(func(_ interface{}, w types.Webhook) {
+		assert.Exactly(suite.T(), environmentName1, w.EnvironmentName, "Expected the webhook of the environment")
+		assert.Exactly(suite.T(), webhookSecret, w.Secret, "Expected the secret to be stored")
+		assert.Exactly(suite.T(), events, w.Events, "Expected the events to be stored")
+	}).Return(nil)
+
+	hook, err := suite.webhook.CreateWebhook(suite.ctx, environmentName1, webhookURL, webhookSecret, events)
+	assert.Nil(suite.T(), err, "Unexpected error when creating a webhook")
+	assert.NotEmpty(suite.T(), hook.ID, "Expected the webhook to have an ID")
+}
+
+func (suite *WebhookTestSuite) TestListWebhooksEnvironmentDoesNotExist() {
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(nil, nil)
+
+	_, err := suite.webhook.ListWebhooks(suite.ctx, environmentName1)
+	_, ok := errors.Cause(err).(types.NotFoundError)
+	assert.True(suite.T(), ok, "Expected a not found error when the environment does not exist")
+}
+
+func (suite *WebhookTestSuite) TestListWebhooks() {
+	suite.environmentStore.EXPECT().GetEnvironment(suite.ctx, environmentName1).Return(suite.environment, nil)
+	suite.webhookStore.EXPECT().ListWebhooks(suite.ctx, environmentName1).Return([]types.Webhook{suite.hook}, nil)
+
+	hooks, err := suite.webhook.ListWebhooks(suite.ctx, environmentName1)
+	assert.Nil(suite.T(), err, "Unexpected error when listing webhooks")
+	assert.Exactly(suite.T(), []types.Webhook{suite.hook}, hooks, "Expected the webhooks of the environment")
+}
+
+func (suite *WebhookTestSuite) TestDeleteWebhookDoesNotExist() {
+	suite.webhookStore.EXPECT().GetWebhook(suite.ctx, environmentName1, webhookID).Return(nil, nil)
+
+	err := suite.webhook.DeleteWebhook(suite.ctx, environmentName1, webhookID)
+	assert.Nil(suite.T(), err, "Unexpected error when the webhook does not exist")
+}
+
+func (suite *WebhookTestSuite) TestDeleteWebhook() {
+	suite.webhookStore.EXPECT().GetWebhook(suite.ctx, environmentName1, webhookID).Return(&suite.hook, nil)
+	suite.webhookStore.EXPECT().DeleteWebhook(suite.ctx, suite.hook).Return(nil)
+
+	err := suite.webhook.DeleteWebhook(suite.ctx, environmentName1, webhookID)
+	assert.Nil(suite.T(), err, "Unexpected error when deleting a webhook")
+}
+
+func (suite *WebhookTestSuite) TestListWebhookDeliveriesWebhookDoesNotExist() {
+	suite.webhookStore.EXPECT().GetWebhook(suite.ctx, environmentName1, webhookID).Return(nil, nil)
+
+	_, err := suite.webhook.ListWebhookDeliveries(suite.ctx, environmentName1, webhookID)
+	_, ok := errors.Cause(err).(types.NotFoundError)
+	assert.True(suite.T(), ok, "Expected a not found error when the webhook does not exist")
+}
+
+func (suite *WebhookTestSuite) TestListWebhookDeliveries() {
+	deliveries := []types.WebhookDelivery{{ID: "delivery", WebhookID: webhookID, EnvironmentName: environmentName1}}
+	suite.webhookStore.EXPECT().GetWebhook(suite.ctx, environmentName1, webhookID).Return(&suite.hook, nil)
+	suite.webhookStore.EXPECT().ListDeliveries(suite.ctx, environmentName1, webhookID).Return(deliveries, nil)
+
+	result, err := suite.webhook.ListWebhookDeliveries(suite.ctx, environmentName1, webhookID)
+	assert.Nil(suite.T(), err, "Unexpected error when listing deliveries")
+	assert.Exactly(suite.T(), deliveries, result, "Expected the deliveries of the webhook")
+}
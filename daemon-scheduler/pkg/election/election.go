@@ -0,0 +1,233 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package election
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/clientv3/concurrency"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ElectionKey is the etcd key prefix under which replicas campaign for leadership
+	ElectionKey = "ecs/scheduler/leader"
+	// DefaultSessionTTL is how long a leader keeps its leadership after it stops
+	// refreshing its session, for example because it lost its connection to etcd
+	DefaultSessionTTL = 10 * time.Second
+
+	campaignRetryInterval = 5 * time.Second
+)
+
+var (
+	// ErrNotLeader is returned when an action reserved for the leader is attempted by
+	// a replica that is not, or is no longer, the leader
+	ErrNotLeader = errors.New("This replica is not the leader")
+	// ErrNoLeader is returned when no replica currently holds the leadership
+	ErrNoLeader = errors.New("No leader is elected")
+)
+
+// Leadership reports which replica leads
+type Leadership interface {
+	// IsLeader returns whether this replica believes it is the leader
+	IsLeader() bool
+	// Leader returns the address advertised by the current leader
+	Leader(ctx context.Context) (string, error)
+}
+
+// Elector campaigns for leadership among the scheduler replicas sharing an etcd cluster
+type Elector struct {
+	client         *etcd.Client
+	key            string
+	address        string
+	sessionTTL     time.Duration
+	requestTimeout time.Duration
+
+	termLock sync.RWMutex
+	term     *term
+}
+
+// term describes the period during which this replica holds the leadership
+type term struct {
+	ctx context.Context
+	key string
+	rev int64
+}
+
+// NewElector creates an elector that campaigns under key, which must already include any
+// etcd key prefix, and advertises address to the other replicas while it leads
+func NewElector(client *etcd.Client, key string, address string, sessionTTL time.Duration,
+	requestTimeout time.Duration) (*Elector, error) {
+	if client == nil {
+		return nil, errors.New("Etcd client should not be nil")
+	}
+	if key == "" {
+		return nil, errors.New("Election key should not be empty")
+	}
+	if address == "" {
+		return nil, errors.New("Advertised address should not be empty")
+	}
+	if sessionTTL < time.Second {
+		return nil, errors.Errorf("Invalid leader session ttl %s, it should be at least one second", sessionTTL)
+	}
+	return &Elector{
+		client:         client,
+		key:            key,
+		address:        address,
+		sessionTTL:     sessionTTL,
+		requestTimeout: requestTimeout,
+	}, nil
+}
+
+// Run campaigns for leadership until ctx is cancelled. Each time this replica is elected,
+// lead is called with a context that is cancelled when the leadership is lost or ctx is
+// cancelled. lead should return once the work it started has stopped; the leadership is
+// only given up after that so that two leaders never work at the same time.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	for ctx.Err() == nil {
+		if err := e.runTerm(ctx, lead); err != nil && ctx.Err() == nil {
+			log.Errorf("Error campaigning for leadership: %+v", err)
+			select {
+			case <-time.After(campaignRetryInterval):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+func (e *Elector) runTerm(ctx context.Context, lead func(ctx context.Context)) error {
+	session, err := concurrency.NewSession(e.client, concurrency.WithTTL(int(e.sessionTTL/time.Second)))
+	if err != nil {
+		return errors.Wrapf(err, "Could not create a leader election session")
+	}
+	defer session.Close()
+
+	// the session is lost when its lease cannot be kept alive, after which another
+	// replica may be elected
+	termCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-session.Done():
+			log.Warnf("Lost the leader election session")
+			cancel()
+		case <-termCtx.Done():
+		}
+	}()
+
+	election := concurrency.NewElection(session, e.key)
+	if err := election.Campaign(termCtx, e.address); err != nil {
+		return errors.Wrapf(err, "Could not campaign for leadership")
+	}
+
+	rev, err := e.leaderKeyRevision(termCtx, election.Key())
+	if err != nil {
+		e.resign(election)
+		return err
+	}
+
+	log.Infof("Elected leader with address %s", e.address)
+	e.setTerm(&term{ctx: termCtx, key: election.Key(), rev: rev})
+	lead(termCtx)
+	e.setTerm(nil)
+	e.resign(election)
+	log.Infof("Gave up the leadership")
+	return nil
+}
+
+func (e *Elector) resign(election *concurrency.Election) {
+	ctx, cancel := e.withTimeout(context.Background())
+	defer cancel()
+	if err := election.Resign(ctx); err != nil {
+		log.Errorf("Could not resign the leadership: %+v", err)
+	}
+}
+
+// IsLeader returns whether this replica holds a leadership term that has not been lost
+func (e *Elector) IsLeader() bool {
+	t := e.getTerm()
+	return t != nil && t.ctx.Err() == nil
+}
+
+// Leader returns the address advertised by the current leader, or ErrNoLeader if there is none
+func (e *Elector) Leader(ctx context.Context) (string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+
+	// candidates are queued by creation revision and the oldest one leads
+	resp, err := e.client.Get(ctx, e.key+"/", etcd.WithFirstCreate()...)
+	if err != nil {
+		return "", errors.Wrapf(err, "Could not get the leader")
+	}
+	if len(resp.Kvs) == 0 {
+		return "", ErrNoLeader
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// Fence returns ErrNotLeader unless etcd confirms that this replica still holds the
+// leadership. It should be called right before any action that must not be taken by
+// a deposed leader, since the local view of the leadership can be out of date.
+func (e *Elector) Fence() error {
+	t := e.getTerm()
+	if t == nil || t.ctx.Err() != nil {
+		return ErrNotLeader
+	}
+
+	ctx, cancel := e.withTimeout(t.ctx)
+	defer cancel()
+	rev, err := e.leaderKeyRevision(ctx, t.key)
+	if err != nil {
+		return errors.Wrapf(ErrNotLeader, "Could not confirm the leadership: %v", err)
+	}
+	if rev != t.rev {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+// leaderKeyRevision returns the creation revision of key, which identifies the term
+func (e *Elector) leaderKeyRevision(ctx context.Context, key string) (int64, error) {
+	resp, err := e.client.Get(ctx, key)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Could not get the leader key")
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, ErrNotLeader
+	}
+	return resp.Kvs[0].CreateRevision, nil
+}
+
+func (e *Elector) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.requestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.requestTimeout)
+}
+
+func (e *Elector) getTerm() *term {
+	e.termLock.RLock()
+	defer e.termLock.RUnlock()
+	return e.term
+}
+
+func (e *Elector) setTerm(t *term) {
+	e.termLock.Lock()
+	defer e.termLock.Unlock()
+	e.term = t
+}
@@ -0,0 +1,82 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package election
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	log "github.com/cihub/seelog"
+)
+
+// ForwardedHeader marks requests forwarded to the leader so that they are never forwarded again
+const ForwardedHeader = "X-Blox-Forwarded-By"
+
+const noLeaderError = "No leader is available to handle the request, retry later"
+
+// Forwarder is a negroni middleware that serves reads on every replica and forwards
+// writes to the leader
+type Forwarder struct {
+	leadership Leadership
+	address    string
+}
+
+// NewForwarder creates a forwarder for the replica advertising address
+func NewForwarder(leadership Leadership, address string) *Forwarder {
+	return &Forwarder{
+		leadership: leadership,
+		address:    address,
+	}
+}
+
+func (f *Forwarder) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if isRead(r) || f.leadership.IsLeader() {
+		next(w, r)
+		return
+	}
+
+	if r.Header.Get(ForwardedHeader) != "" {
+		// the sender thinks we lead, which means leadership is changing hands
+		http.Error(w, noLeaderError, http.StatusServiceUnavailable)
+		return
+	}
+
+	leader, err := f.leadership.Leader(r.Context())
+	if err != nil {
+		log.Errorf("Could not find the leader to forward %s %s to: %+v", r.Method, r.URL.Path, err)
+		http.Error(w, noLeaderError, http.StatusServiceUnavailable)
+		return
+	}
+	if leader == f.address {
+		// our term has ended but the leader key has not expired yet
+		http.Error(w, noLeaderError, http.StatusServiceUnavailable)
+		return
+	}
+
+	target, err := url.Parse(leader)
+	if err != nil {
+		log.Errorf("Invalid leader address %s: %+v", leader, err)
+		http.Error(w, noLeaderError, http.StatusServiceUnavailable)
+		return
+	}
+
+	log.Debugf("Forwarding %s %s to the leader at %s", r.Method, r.URL.Path, leader)
+	r.Header.Set(ForwardedHeader, f.address)
+	httputil.NewSingleHostReverseProxy(target).ServeHTTP(w, r)
+}
+
+func isRead(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+}
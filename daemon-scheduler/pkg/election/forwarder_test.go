@@ -0,0 +1,97 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package election
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const replicaAddress = "http://replica:2000"
+
+type staticLeadership struct {
+	leader    bool
+	leaderURL string
+	err       error
+}
+
+func (l staticLeadership) IsLeader() bool {
+	return l.leader
+}
+
+func (l staticLeadership) Leader(ctx context.Context) (string, error) {
+	return l.leaderURL, l.err
+}
+
+func forward(leadership Leadership, r *http.Request) (*httptest.ResponseRecorder, bool) {
+	recorder := httptest.NewRecorder()
+	served := false
+	NewForwarder(leadership, replicaAddress).ServeHTTP(recorder, r, func(w http.ResponseWriter, r *http.Request) {
+		served = true
+		w.WriteHeader(http.StatusAccepted)
+	})
+	return recorder, served
+}
+
+func TestForwarderServesReadsLocally(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/v1/environments", nil)
+	_, served := forward(staticLeadership{err: ErrNoLeader}, r)
+	assert.True(t, served, "Expected reads to be served by every replica")
+}
+
+func TestForwarderServesWritesOnLeader(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/v1/environments", nil)
+	_, served := forward(staticLeadership{leader: true}, r)
+	assert.True(t, served, "Expected writes to be served by the leader")
+}
+
+func TestForwarderForwardsWritesToLeader(t *testing.T) {
+	var forwardedBy string
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedBy = r.Header.Get(ForwardedHeader)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer leader.Close()
+
+	r, _ := http.NewRequest("DELETE", "/v1/environments/env", nil)
+	recorder, served := forward(staticLeadership{leaderURL: leader.URL}, r)
+	assert.False(t, served, "Expected writes not to be served by a follower")
+	assert.Equal(t, http.StatusCreated, recorder.Code, "Expected the leader response to be returned")
+	assert.Equal(t, replicaAddress, forwardedBy, "Expected the forwarded request to be marked")
+}
+
+func TestForwarderNoLeader(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/v1/environments", nil)
+	recorder, served := forward(staticLeadership{err: ErrNoLeader}, r)
+	assert.False(t, served, "Expected writes not to be served by a follower")
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code, "Unexpected status without a leader")
+}
+
+func TestForwarderDoesNotForwardTwice(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/v1/environments", nil)
+	r.Header.Set(ForwardedHeader, "http://other:2000")
+	recorder, served := forward(staticLeadership{leaderURL: "http://other:2000"}, r)
+	assert.False(t, served, "Expected writes not to be served by a follower")
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code, "Expected forwarded requests not to be forwarded again")
+}
+
+func TestForwarderDeposedLeader(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/v1/environments", nil)
+	recorder, _ := forward(staticLeadership{leaderURL: replicaAddress}, r)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code, "Expected a deposed leader not to forward to itself")
+}
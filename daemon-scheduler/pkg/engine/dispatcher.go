@@ -15,6 +15,7 @@ package engine
 
 import (
 	"context"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
@@ -37,6 +38,7 @@ type dispatcher struct {
 	deploymentWorker deployment.DeploymentWorker
 	input            <-chan Event
 	output           chan<- Event
+	running          *sync.WaitGroup
 }
 
 func NewDispatcher(ctx context.Context,
@@ -56,16 +58,27 @@ func NewDispatcher(ctx context.Context,
 		deploymentWorker: deploymentWorker,
 		input:            input,
 		output:           output,
+		running:          &sync.WaitGroup{},
 	}
 }
 
-// Start starts dispatcher. dispatcher listens to events on channel and forwards them to workers
+// Start starts dispatcher. dispatcher listens to events on channel and forwards them to workers.
+// It stops listening when the context is cancelled or the input channel is closed. Closing the
+// input channel lets workers already handling events finish with an uncancelled context.
 func (dispatcher *dispatcher) Start() {
+	dispatcher.running.Add(1)
 	go func() {
+		defer dispatcher.running.Done()
 		for {
 			select {
-			case event := <-dispatcher.input:
+			case event, ok := <-dispatcher.input:
+				if !ok {
+					log.Info("Input closed, shutting down dispatcher")
+					return
+				}
+				dispatcher.running.Add(1)
 				go func(event Event) {
+					defer dispatcher.running.Done()
 					worker := worker{
 						environmentSvc:   dispatcher.environmentSvc,
 						deploymentSvc:    dispatcher.deploymentSvc,
@@ -73,12 +86,13 @@ func (dispatcher *dispatcher) Start() {
 						ecs:              dispatcher.ecs,
 						css:              dispatcher.css,
 						output:           dispatcher.output,
+						done:             dispatcher.ctx.Done(),
 					}
 					err := worker.handleEvent(dispatcher.ctx, event)
 					if err != nil {
-						dispatcher.output <- ErrorEvent{
+						sendEvent(dispatcher.ctx.Done(), dispatcher.output, ErrorEvent{
 							Error: err,
-						}
+						})
 					}
 				}(event)
 			case <-dispatcher.ctx.Done():
@@ -91,6 +105,11 @@ func (dispatcher *dispatcher) Start() {
 	log.Info("Started dispatcher")
 }
 
+// Wait blocks until the dispatcher has stopped listening and all workers have finished.
+func (dispatcher *dispatcher) Wait() {
+	dispatcher.running.Wait()
+}
+
 // Worker is actor which handles an event appropriately
 type worker struct {
 	environmentSvc   deployment.Environment
@@ -99,6 +118,7 @@ type worker struct {
 	ecs              facade.ECS
 	css              facade.ClusterState
 	output           chan<- Event
+	done             <-chan struct{}
 }
 
 func (w *worker) handleEvent(ctx context.Context, event Event) error {
@@ -109,6 +129,8 @@ func (w *worker) handleEvent(ctx context.Context, event Event) error {
 		return w.handleStopTasksEvent(ctx, event)
 	case UpdateInProgressDeploymentEventType:
 		return w.handleUpdateInProgressDeploymentEvent(ctx, event)
+	case UpdatePendingDeploymentEventType:
+		return w.handleUpdatePendingDeploymentEvent(ctx, event)
 	default:
 		return w.handleUnknownEvent(ctx, event)
 	}
@@ -134,13 +156,29 @@ func (w *worker) handleUpdateInProgressDeploymentEvent(ctx context.Context, even
 	return nil
 }
 
+func (w *worker) handleUpdatePendingDeploymentEvent(ctx context.Context, event Event) error {
+	deploymentEvent, ok := event.(UpdatePendingDeploymentEvent)
+	if !ok {
+		return errors.Errorf("Expected event with event-type %v to be of struct-type UpdatePendingDeploymentEvent",
+			event.GetType())
+	}
+
+	_, err := w.deploymentWorker.StartPendingDeployment(ctx, deploymentEvent.Environment.Name)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (w *worker) handleStartDeploymentEvent(ctx context.Context, event Event) error {
 	deploymentEvent, ok := event.(StartDeploymentEvent)
 	if !ok {
 		return errors.Errorf("Expected event with event-type %s to be of struct-type StartDeploymentEvent", event.GetType())
 	}
 
-	deployment, err := w.deploymentSvc.CreateSubDeployment(ctx, deploymentEvent.Environment.Name, deploymentEvent.Instances)
+	deployment, err := w.deploymentSvc.CreateSubDeployment(ctx, deploymentEvent.Environment.Name, deploymentEvent.Environment.Cluster,
+		deploymentEvent.Instances)
 	if err != nil {
 		return errors.Wrapf(err, "Error starting deployment using environment %s on %d instances",
 			deploymentEvent.Environment.Name, len(deploymentEvent.Instances))
@@ -149,9 +187,9 @@ func (w *worker) handleStartDeploymentEvent(ctx context.Context, event Event) er
 	log.Debugf("Succesfully created a deployment with %s on %d instances in environment %s",
 		deployment.ID, len(deploymentEvent.Instances), deploymentEvent.Environment.Name)
 
-	w.output <- StartDeploymentResult{
+	sendEvent(w.done, w.output, StartDeploymentResult{
 		Deployment: *deployment,
-	}
+	})
 	return nil
 }
 
@@ -180,7 +218,7 @@ func (w *worker) handleStopTasksEvent(ctx context.Context, event Event) error {
 			stoppedTasks = append(stoppedTasks, task)
 			continue
 		}
-		err := w.ecs.StopTask(stopTasksEvent.Cluster, task)
+		err := w.ecs.StopTask(stopTasksEvent.Cluster, task, stopTasksEvent.Reason)
 		if err != nil {
 			log.Errorf("Error stopping task %s in cluster %s: %v", task, stopTasksEvent.Cluster, err)
 			continue
@@ -193,9 +231,9 @@ func (w *worker) handleStopTasksEvent(ctx context.Context, event Event) error {
 	log.Debugf("Successfully stopped %d tasks out of %d tasks under environment %s",
 		len(stoppedTasks), len(stopTasksEvent.Tasks), stopTasksEvent.Environment.Name)
 
-	w.output <- StopTasksResult{
+	sendEvent(w.done, w.output, StopTasksResult{
 		StoppedTasks: stoppedTasks,
-	}
+	})
 
 	return nil
 }
@@ -149,6 +149,79 @@ func (suite *DispatcherTestSuite) TestUpdateInProgressDeploymentEvent() {
 	input <- event
 }
 
+func (suite *DispatcherTestSuite) TestUpdatePendingDeploymentEventReturnsError() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	input := make(chan Event)
+	output := make(chan Event)
+	dispatcher := NewDispatcher(ctx,
+		suite.environmentSvc,
+		suite.deploymentSvc,
+		suite.ecs, suite.css,
+		suite.deploymentWorker,
+		input, output,
+	)
+
+	event := UpdatePendingDeploymentEvent{
+		Environment: types.Environment{
+			Name:    environmentName,
+			Cluster: clusterARN,
+		},
+	}
+
+	err := errors.New("Error calling StartPendingDeployment")
+	suite.deploymentWorker.EXPECT().
+		StartPendingDeployment(ctx, event.Environment.Name).
+		Return(nil, err).
+		Times(1)
+
+	dispatcher.Start()
+	input <- event
+
+	observedErr := errors.Cause((<-output).(ErrorEvent).Error)
+	assert.Equal(suite.T(), err, observedErr)
+}
+
+func (suite *DispatcherTestSuite) TestClosingInputWaitsForWorkers() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	input := make(chan Event)
+	output := make(chan Event)
+	dispatcher := NewDispatcher(ctx,
+		suite.environmentSvc,
+		suite.deploymentSvc,
+		suite.ecs, suite.css,
+		suite.deploymentWorker,
+		input, output,
+	)
+
+	event := UpdateInProgressDeploymentEvent{
+		Environment: types.Environment{
+			Name:    environmentName,
+			Cluster: clusterARN,
+		},
+	}
+	handled := false
+	suite.deploymentWorker.EXPECT().
+		UpdateInProgressDeployment(ctx, event.Environment.Name).
+		Do(func(ctx context.Context, name string) {
+			time.Sleep(10 * time.Millisecond)
+			handled = true
+		}).
+		Return(nil, nil).
+		Times(1)
+
+	dispatcher.Start()
+	input <- event
+	close(input)
+	dispatcher.Wait()
+
+	assert.True(suite.T(), handled, "Expected the in-flight event to be handled before Wait returns")
+	assert.Nil(suite.T(), ctx.Err(), "Expected Wait to return without the context being cancelled")
+}
+
 func (suite *DispatcherTestSuite) TestStartDeploymentEventReturnsError() {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -178,7 +251,7 @@ func (suite *DispatcherTestSuite) TestStartDeploymentEventReturnsError() {
 
 	err := errors.New("Error creating sub-deployment")
 	suite.deploymentSvc.EXPECT().
-		CreateSubDeployment(ctx, event.Environment.Name, event.Instances).
+		CreateSubDeployment(ctx, event.Environment.Name, event.Environment.Cluster, event.Instances).
 		Return(nil, err)
 
 	dispatcher.Start()
@@ -219,7 +292,7 @@ func (suite *DispatcherTestSuite) TestStartDeploymentEvent() {
 		ID: uuid.NewRandom().String(),
 	}
 	suite.deploymentSvc.EXPECT().
-		CreateSubDeployment(ctx, event.Environment.Name, event.Instances).
+		CreateSubDeployment(ctx, event.Environment.Name, event.Environment.Cluster, event.Instances).
 		Return(&deployment, nil).
 		Times(1)
 
@@ -252,11 +325,12 @@ func (suite *DispatcherTestSuite) TestStopTasksEventListTasksReturnsError() {
 	event := StopTasksEvent{
 		Cluster: "cluster-arn",
 		Tasks:   tasksToStop,
+		Reason:  types.StopReasonReplaced,
 	}
 
 	err := errors.New("Error from css.ListTasks")
 	suite.css.EXPECT().ListTasks(event.Cluster).Return(nil, err).Times(1)
-	suite.ecs.EXPECT().StopTask(gomock.Any(), gomock.Any()).Times(0)
+	suite.ecs.EXPECT().StopTask(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
 	dispatcher.Start()
 	input <- event
@@ -285,6 +359,7 @@ func (suite *DispatcherTestSuite) TestStopTasksEventECSStopTaskReturnsError() {
 	event := StopTasksEvent{
 		Cluster: "cluster-arn",
 		Tasks:   tasksToStop,
+		Reason:  types.StopReasonReplaced,
 	}
 
 	tasksFromECS := []*models.Task{
@@ -297,7 +372,7 @@ func (suite *DispatcherTestSuite) TestStopTasksEventECSStopTaskReturnsError() {
 	suite.css.EXPECT().ListTasks(event.Cluster).Return(tasksFromECS, nil).Times(1)
 
 	err := errors.New("Error stopping task")
-	suite.ecs.EXPECT().StopTask(event.Cluster, "task-arn-1").Return(err).Times(1)
+	suite.ecs.EXPECT().StopTask(event.Cluster, "task-arn-1", event.Reason).Return(err).Times(1)
 
 	dispatcher.Start()
 	input <- event
@@ -328,6 +403,7 @@ func (suite *DispatcherTestSuite) TestStopTasksEvent() {
 	event := StopTasksEvent{
 		Cluster: "cluster-arn",
 		Tasks:   tasksToStop,
+		Reason:  types.StopReasonReplaced,
 	}
 
 	tasksFromECS := []*models.Task{
@@ -348,10 +424,10 @@ func (suite *DispatcherTestSuite) TestStopTasksEvent() {
 		},
 	}
 	suite.css.EXPECT().ListTasks(event.Cluster).Return(tasksFromECS, nil).Times(1)
-	suite.ecs.EXPECT().StopTask(event.Cluster, "task-arn-1").Return(nil).Times(1)
-	suite.ecs.EXPECT().StopTask(event.Cluster, "task-arn-2").Times(0)
-	suite.ecs.EXPECT().StopTask(event.Cluster, "unknown-task-arn-1").Times(0)
-	suite.ecs.EXPECT().StopTask(event.Cluster, "task-arn-3").Times(0)
+	suite.ecs.EXPECT().StopTask(event.Cluster, "task-arn-1", event.Reason).Return(nil).Times(1)
+	suite.ecs.EXPECT().StopTask(event.Cluster, "task-arn-2", event.Reason).Times(0)
+	suite.ecs.EXPECT().StopTask(event.Cluster, "unknown-task-arn-1", event.Reason).Times(0)
+	suite.ecs.EXPECT().StopTask(event.Cluster, "task-arn-3", event.Reason).Times(0)
 
 	dispatcher.Start()
 	input <- event
@@ -0,0 +1,116 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// DrainCheckInterval is how often an environment being deleted is scheduled while its tasks stop
+const DrainCheckInterval = 10 * time.Second
+
+// drainEnvironment stops the tasks of every deployment of an environment being deleted, and
+// deletes the environment once none of them is left or its drain deadline has passed
+func (s *scheduler) drainEnvironment(state *environmentExecutionState) error {
+	environment := state.environment
+
+	running, stopping, err := s.listEnvironmentTasks(environment)
+	if err != nil {
+		return errors.Wrapf(err, "Error listing the tasks of environment %s being deleted", environment.Name)
+	}
+
+	now := time.Now()
+	remaining := stopping
+	for _, tasks := range running {
+		remaining += len(tasks)
+	}
+	if remaining > 0 && now.Before(environment.DrainDeadline) {
+		for _, cluster := range environment.DeployedClusters() {
+			tasks := running[cluster]
+			if len(tasks) == 0 {
+				continue
+			}
+			log.Infof("[s:%s, e:%s] Stopping %d tasks of the environment being deleted in cluster %s",
+				s.id, environment.Name, len(tasks), cluster)
+			sendEvent(s.ctx.Done(), s.events, StopTasksEvent{
+				Cluster:     cluster,
+				Tasks:       tasks,
+				Environment: environment,
+				Reason:      types.StopReasonEnvironmentDeleted,
+			})
+		}
+
+		delay := DrainCheckInterval
+		if untilDeadline := environment.DrainDeadline.Sub(now); untilDeadline < delay {
+			delay = untilDeadline
+		}
+		s.recheckLater(state, delay)
+		return nil
+	}
+
+	if remaining > 0 {
+		log.Warnf("[s:%s, e:%s] Deleting the environment with %d tasks left after the drain deadline %s",
+			s.id, environment.Name, remaining, environment.DrainDeadline)
+	}
+
+	err = s.environmentSvc.DeleteDrainedEnvironment(s.ctx, environment)
+	if err != nil {
+		return errors.Wrapf(err, "Error deleting environment %s once its tasks stopped", environment.Name)
+	}
+
+	log.Infof("[s:%s, e:%s] Deleted the environment", s.id, environment.Name)
+	return nil
+}
+
+// listEnvironmentTasks returns the tasks of every deployment of the environment that are meant to
+// be running in each of its clusters, and the number of tasks that were asked to stop but have not
+// stopped yet
+func (s *scheduler) listEnvironmentTasks(environment types.Environment) (map[string][]string, int, error) {
+	running := make(map[string][]string)
+	stopping := 0
+	for _, cluster := range environment.DeployedClusters() {
+		for id := range environment.Deployments {
+			tasks, err := s.ecs.ListTasks(cluster, id)
+			if err != nil {
+				return nil, 0, err
+			}
+			running[cluster] = append(running[cluster], aws.StringValueSlice(tasks)...)
+
+			stoppedTasks, err := s.ecs.ListStoppedTasks(cluster, id)
+			if err != nil {
+				return nil, 0, err
+			}
+			if len(stoppedTasks) == 0 {
+				continue
+			}
+
+			resp, err := s.ecs.DescribeTasks(cluster, stoppedTasks)
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, task := range resp.Tasks {
+				if aws.StringValue(task.LastStatus) != stoppedTaskStatus {
+					stopping++
+				}
+			}
+		}
+	}
+
+	return running, stopping, nil
+}
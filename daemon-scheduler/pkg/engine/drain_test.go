@@ -0,0 +1,131 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *SchedulerTestSuite) TestDrainStopsRunningTasks() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment := drainingEnvironment(time.Now().Add(time.Minute))
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.ecs.EXPECT().ListTasks(environment.Cluster, "dep-id").Return([]*string{aws.String("task-1")}, nil)
+	suite.ecs.EXPECT().ListStoppedTasks(environment.Cluster, "dep-id").Return(nil, nil)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	stopTasksEvent := (<-events).(StopTasksEvent)
+	assert.Equal(suite.T(), []string{"task-1"}, stopTasksEvent.Tasks, "Expected the running task to stop")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+}
+
+func (suite *SchedulerTestSuite) TestDrainWaitsForStoppingTasks() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment := drainingEnvironment(time.Now().Add(time.Minute))
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.expectStoppedTasks(environment, "RUNNING")
+
+	events := suite.startRolloutScheduler(ctx)
+
+	_, ok := (<-events).(SchedulerEnvironmentEvent)
+	assert.True(suite.T(), ok, "Expected the environment not to be deleted while a task is stopping")
+}
+
+func (suite *SchedulerTestSuite) TestDrainDeletesEnvironmentOnceTasksStopped() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment := drainingEnvironment(time.Now().Add(time.Minute))
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.expectStoppedTasks(environment, stoppedTaskStatus)
+	suite.environmentSvc.EXPECT().DeleteDrainedEnvironment(ctx, environment).Return(nil)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	_, ok := (<-events).(SchedulerEnvironmentEvent)
+	assert.True(suite.T(), ok, "Expected the environment to be deleted")
+}
+
+func (suite *SchedulerTestSuite) TestDrainDeletesEnvironmentAfterDeadline() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment := drainingEnvironment(time.Now().Add(-time.Second))
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.ecs.EXPECT().ListTasks(environment.Cluster, "dep-id").Return([]*string{aws.String("task-1")}, nil)
+	suite.ecs.EXPECT().ListStoppedTasks(environment.Cluster, "dep-id").Return(nil, nil)
+	suite.environmentSvc.EXPECT().DeleteDrainedEnvironment(ctx, environment).Return(nil)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	_, ok := (<-events).(SchedulerEnvironmentEvent)
+	assert.True(suite.T(), ok, "Expected the environment to be deleted without stopping the task once the deadline passed")
+}
+
+func (suite *SchedulerTestSuite) TestDrainStopsTasksOfDeploymentsPastRetention() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment := drainingEnvironment(time.Now().Add(time.Minute))
+	startTime := time.Now()
+	environment.Deployments = map[string]types.Deployment{
+		"old-dep":     {ID: "old-dep", Status: types.DeploymentCompleted, StartTime: startTime.Add(-3 * time.Hour)},
+		"stopped-dep": {ID: "stopped-dep", Status: types.DeploymentFailed, StartTime: startTime.Add(-2 * time.Hour), TasksStopped: true},
+		"dep-id":      {ID: "dep-id", Status: types.DeploymentCompleted, StartTime: startTime.Add(-time.Hour)},
+	}
+	environment.Deployments = environment.RetainedDeployments(1)
+	_, ok := environment.Deployments["stopped-dep"]
+	assert.False(suite.T(), ok, "Expected the deployment without running tasks to be pruned")
+
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.ecs.EXPECT().ListTasks(environment.Cluster, "old-dep").Return([]*string{aws.String("task-1")}, nil)
+	suite.ecs.EXPECT().ListStoppedTasks(environment.Cluster, "old-dep").Return(nil, nil)
+	suite.ecs.EXPECT().ListTasks(environment.Cluster, "dep-id").Return(nil, nil)
+	suite.ecs.EXPECT().ListStoppedTasks(environment.Cluster, "dep-id").Return(nil, nil)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	stopTasksEvent := (<-events).(StopTasksEvent)
+	assert.Equal(suite.T(), []string{"task-1"}, stopTasksEvent.Tasks,
+		"Expected the task of the deployment past the retention to stop")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+}
+
+func (suite *SchedulerTestSuite) expectStoppedTasks(environment types.Environment, lastStatus string) {
+	suite.ecs.EXPECT().ListTasks(environment.Cluster, "dep-id").Return([]*string{}, nil)
+	suite.ecs.EXPECT().ListStoppedTasks(environment.Cluster, "dep-id").Return([]*string{aws.String("task-1")}, nil)
+	suite.ecs.EXPECT().DescribeTasks(environment.Cluster, []*string{aws.String("task-1")}).Return(&ecs.DescribeTasksOutput{
+		Tasks: []*ecs.Task{{TaskArn: aws.String("task-1"), LastStatus: aws.String(lastStatus)}},
+	}, nil)
+}
+
+func drainingEnvironment(deadline time.Time) types.Environment {
+	environment, _ := rolloutEnvironment(types.RolloutStrategy{})
+	environment.Deployments = map[string]types.Deployment{
+		"dep-id": {ID: "dep-id", Status: types.DeploymentCompleted},
+	}
+	environment.StartDeleting(deadline)
+	return environment
+}
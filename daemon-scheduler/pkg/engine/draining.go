@@ -0,0 +1,125 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sort"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// stopTasksOnDrainingInstances stops the tasks of the environment on the draining instances of
+// the cluster as the draining policy of the environment says. With the keep-until-last policy the
+// tasks on an instance are stopped once every other task left on it was started by an environment.
+func (s *scheduler) stopTasksOnDrainingInstances(state *environmentExecutionState, result *instanceLookupResult) error {
+	environment := state.environment
+	policy := environment.DrainingPolicy.OrDefault()
+	if policy == types.DrainingDoNotStartNew {
+		return nil
+	}
+
+	instanceARNs := make([]string, 0, len(result.drainingInstances))
+	for instanceARN, draining := range result.drainingInstances {
+		if len(draining.tasks) > 0 {
+			instanceARNs = append(instanceARNs, instanceARN)
+		}
+	}
+	sort.Strings(instanceARNs)
+
+	var deploymentIDs map[string]bool
+	tasks := make([]string, 0)
+	for _, instanceARN := range instanceARNs {
+		draining := result.drainingInstances[instanceARN]
+		if policy == types.DrainingKeepUntilLast {
+			if deploymentIDs == nil {
+				ids, err := s.environmentDeploymentIDs()
+				if err != nil {
+					return err
+				}
+				deploymentIDs = ids
+			}
+			if workload := countWorkloadTasks(draining, deploymentIDs); workload > 0 {
+				log.Debugf("[s:%s, e:%s] Keeping the tasks on draining instance %s until %d other tasks stop",
+					s.id, environment.Name, instanceARN, workload)
+				continue
+			}
+		}
+		log.Infof("[s:%s, e:%s] Stopping %d tasks on draining instance %s",
+			s.id, environment.Name, len(draining.tasks), instanceARN)
+		tasks = append(tasks, draining.tasks...)
+	}
+
+	if len(tasks) == 0 {
+		return nil
+	}
+	sendEvent(s.ctx.Done(), s.events, StopTasksEvent{
+		Cluster:     environment.Cluster,
+		Tasks:       tasks,
+		Environment: environment,
+		Reason:      types.StopReasonDraining,
+	})
+	return nil
+}
+
+// environmentDeploymentIDs returns the IDs of the deployments of every environment, which are
+// what the tasks started by an environment are started by
+func (s *scheduler) environmentDeploymentIDs() (map[string]bool, error) {
+	environments, err := s.environmentSvc.ListEnvironments(s.ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error listing environments")
+	}
+	ids := make(map[string]bool)
+	for _, e := range environments {
+		for id := range e.Deployments {
+			ids[id] = true
+		}
+	}
+	return ids, nil
+}
+
+// countWorkloadTasks returns how many of the other tasks on the draining instance were not
+// started by an environment
+func countWorkloadTasks(draining *drainingInstance, deploymentIDs map[string]bool) int {
+	count := 0
+	for _, task := range draining.otherTasks {
+		if !deploymentIDs[task.StartedBy] {
+			count++
+		}
+	}
+	return count
+}
+
+// recordDisconnectedInstances stores the instances of the cluster whose agent is disconnected
+// with the environment when they changed, so that they can be reported
+func (s *scheduler) recordDisconnectedInstances(state *environmentExecutionState, result *instanceLookupResult) error {
+	environment := state.environment
+	if !environment.UpdateDisconnectedInstances(environment.Cluster, result.disconnectedInstances) {
+		return nil
+	}
+	if len(result.disconnectedInstances) > 0 {
+		log.Warnf("[s:%s, e:%s] Not starting tasks on %d instances of cluster %s with a disconnected agent",
+			s.id, environment.Name, len(result.disconnectedInstances), environment.Cluster)
+	}
+
+	_, err := s.environmentSvc.UpdateEnvironment(s.ctx, environment.Name, func(latest *types.Environment) error {
+		latest.UpdateDisconnectedInstances(environment.Cluster, result.disconnectedInstances)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Error updating the disconnected instances of environment %s", environment.Name)
+	}
+	return nil
+}
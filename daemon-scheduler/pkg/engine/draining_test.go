@@ -0,0 +1,172 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	drainingInstance1 = "instance-arn-draining"
+	activeInstance1   = "instance-arn-active"
+)
+
+func (suite *SchedulerTestSuite) TestDrainingStopImmediately() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := drainingPolicyEnvironment(types.DrainingStopImmediately)
+	instances := []*models.ContainerInstance{
+		containerInstance("testCluster", activeInstance1, "ACTIVE"),
+		containerInstance("testCluster", drainingInstance1, drainingInstanceStatus),
+	}
+	tasks := []*models.Task{
+		rolloutTask(activeInstance1, currentDeployment.ID, runningTaskStatus),
+		rolloutTask(drainingInstance1, currentDeployment.ID, runningTaskStatus),
+		workloadTask(drainingInstance1, "non-scheduler"),
+	}
+	suite.expectDrainingLookup(ctx, environment, currentDeployment, instances, tasks)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	stopTasksEvent := (<-events).(StopTasksEvent)
+	assert.Exactly(suite.T(), environment.Cluster, stopTasksEvent.Cluster)
+	assert.Equal(suite.T(), []string{"task-" + drainingInstance1}, stopTasksEvent.Tasks,
+		"Expected the task on the draining instance to stop right away")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+}
+
+func (suite *SchedulerTestSuite) TestDrainingKeepUntilLastWaitsForOtherTasks() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := drainingPolicyEnvironment("")
+	instances := []*models.ContainerInstance{containerInstance("testCluster", drainingInstance1, drainingInstanceStatus)}
+	tasks := []*models.Task{
+		rolloutTask(drainingInstance1, currentDeployment.ID, runningTaskStatus),
+		workloadTask(drainingInstance1, "non-scheduler"),
+	}
+	suite.expectDrainingLookup(ctx, environment, currentDeployment, instances, tasks)
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	_, ok := (<-events).(SchedulerEnvironmentEvent)
+	assert.True(suite.T(), ok, "Expected the task to keep running while other tasks run on the instance")
+}
+
+func (suite *SchedulerTestSuite) TestDrainingKeepUntilLastStopsLastTasks() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := drainingPolicyEnvironment(types.DrainingKeepUntilLast)
+	otherEnvironment := types.Environment{
+		Name:        "TestDrainingOther",
+		Cluster:     environment.Cluster,
+		Deployments: map[string]types.Deployment{"other-dep-id": {ID: "other-dep-id"}},
+	}
+	instances := []*models.ContainerInstance{containerInstance("testCluster", drainingInstance1, drainingInstanceStatus)}
+	tasks := []*models.Task{
+		rolloutTask(drainingInstance1, currentDeployment.ID, runningTaskStatus),
+		workloadTask(drainingInstance1, "other-dep-id"),
+	}
+	suite.expectDrainingLookup(ctx, environment, currentDeployment, instances, tasks)
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment, otherEnvironment}, nil)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	stopTasksEvent := (<-events).(StopTasksEvent)
+	assert.Equal(suite.T(), []string{"task-" + drainingInstance1}, stopTasksEvent.Tasks,
+		"Expected the task to stop once only tasks of environments are left")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+}
+
+func (suite *SchedulerTestSuite) TestDrainingDoNotStartNew() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := drainingPolicyEnvironment(types.DrainingDoNotStartNew)
+	instances := []*models.ContainerInstance{
+		containerInstance("testCluster", drainingInstance1, drainingInstanceStatus),
+		containerInstance("testCluster", "instance-arn-draining-new", drainingInstanceStatus),
+	}
+	tasks := []*models.Task{rolloutTask(drainingInstance1, currentDeployment.ID, runningTaskStatus)}
+	suite.expectDrainingLookup(ctx, environment, currentDeployment, instances, tasks)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	_, ok := (<-events).(SchedulerEnvironmentEvent)
+	assert.True(suite.T(), ok, "Expected tasks to be neither started nor stopped on draining instances")
+}
+
+func (suite *SchedulerTestSuite) TestDisconnectedAgentInstancesRecorded() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := drainingPolicyEnvironment("")
+	disconnected := containerInstance("testCluster", "instance-arn-disconnected", "ACTIVE")
+	disconnected.AgentConnected = aws.Bool(false)
+	connected := containerInstance("testCluster", activeInstance1, "ACTIVE")
+	connected.AgentConnected = aws.Bool(true)
+	instances := []*models.ContainerInstance{disconnected, connected}
+	suite.expectDrainingLookup(ctx, environment, currentDeployment, instances, []*models.Task{})
+	recorded := suite.expectRecordRollout(ctx, environment, currentDeployment)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	startDeploymentEvent := (<-events).(StartDeploymentEvent)
+	assert.Equal(suite.T(), []*string{aws.String(activeInstance1)}, startDeploymentEvent.Instances,
+		"Expected no task to be started on the instance with a disconnected agent")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+
+	latest := <-recorded
+	assert.Equal(suite.T(), map[string]string{"instance-arn-disconnected": environment.Cluster}, latest.DisconnectedInstances,
+		"Expected the instance with a disconnected agent to be recorded")
+}
+
+func (suite *SchedulerTestSuite) expectDrainingLookup(ctx context.Context, environment types.Environment,
+	currentDeployment types.Deployment, instances []*models.ContainerInstance, tasks []*models.Task) {
+
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment.Name).Return(&currentDeployment, nil)
+	suite.css.EXPECT().ListInstances(environment.Cluster).Return(instances, nil)
+	suite.css.EXPECT().ListTasks(environment.Cluster).Return(tasks, nil)
+	suite.deploymentSvc.EXPECT().ListDeploymentsSortedReverseChronologically(ctx, environment.Name).
+		Return([]types.Deployment{currentDeployment}, nil)
+}
+
+func drainingPolicyEnvironment(policy types.DrainingPolicy) (types.Environment, types.Deployment) {
+	currentDeployment := types.Deployment{
+		ID:     "dep-id",
+		Status: types.DeploymentCompleted,
+	}
+	environment := types.Environment{
+		Name:           "TestDraining",
+		Cluster:        "testCluster",
+		DrainingPolicy: policy,
+		Deployments:    map[string]types.Deployment{currentDeployment.ID: currentDeployment},
+	}
+	return environment, currentDeployment
+}
+
+func workloadTask(instanceARN string, startedBy string) *models.Task {
+	task := rolloutTask(instanceARN, startedBy, runningTaskStatus)
+	task.TaskARN = aws.String("task-" + startedBy + "-" + instanceARN)
+	return task
+}
@@ -36,6 +36,17 @@ type Event interface {
 	GetType() EventType
 }
 
+// sendEvent sends event on events unless done is closed first, so that senders do not block
+// forever once the receiving side has shut down. It returns whether the event was sent.
+func sendEvent(done <-chan struct{}, events chan<- Event, event Event) bool {
+	select {
+	case events <- event:
+		return true
+	case <-done:
+		return false
+	}
+}
+
 // StartDeploymentEvent is message used to notify actors to perform a deployment using environment
 type StartDeploymentEvent struct {
 	Instances   []*string
@@ -51,6 +62,8 @@ type StopTasksEvent struct {
 	Cluster     string
 	Tasks       []string
 	Environment types.Environment
+	// Reason is one of the types.StopReason reasons the tasks are stopped for
+	Reason string
 }
 
 func (e StopTasksEvent) GetType() EventType {
@@ -15,6 +15,7 @@ package engine
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/blox/blox/daemon-scheduler/pkg/deployment"
@@ -23,6 +24,7 @@ import (
 )
 
 const (
+	// InProgressMonitorTickerDuration and PendingMonitorTickerDuration are the default intervals between monitor runs
 	InProgressMonitorTickerDuration = 10 * time.Second
 	PendingMonitorTickerDuration    = 10 * time.Second
 )
@@ -30,12 +32,25 @@ const (
 type Monitor interface {
 	PendingMonitorLoop(tickerDuration time.Duration)
 	InProgressMonitorLoop(tickerDuration time.Duration)
+	// SetTickerDuration changes the intervals of the running in-progress and pending monitor
+	// loops from their next tick
+	SetTickerDuration(inProgressDuration time.Duration, pendingDuration time.Duration) error
+	// Wait blocks until all monitor loops have stopped
+	Wait()
 }
 
 type monitor struct {
 	ctx         context.Context
 	environment deployment.Environment
 	events      chan<- Event
+	running     *sync.WaitGroup
+	tickers     *monitorTickers
+}
+
+type monitorTickers struct {
+	lock       sync.Mutex
+	inProgress *time.Ticker
+	pending    *time.Ticker
 }
 
 func NewMonitor(
@@ -47,20 +62,28 @@ func NewMonitor(
 		ctx:         ctx,
 		environment: environment,
 		events:      events,
+		running:     &sync.WaitGroup{},
+		tickers:     &monitorTickers{},
 	}
 }
 
 func (m monitor) InProgressMonitorLoop(tickerDuration time.Duration) {
-	ticker := time.NewTicker(tickerDuration)
+	ticker := m.newTicker(&m.tickers.inProgress, tickerDuration)
+	m.running.Add(1)
 	go func() {
+		defer m.running.Done()
 		for {
 			select {
 			case <-ticker.C:
+				// both cases can be ready at once, so don't start another run after cancellation
+				if m.ctx.Err() != nil {
+					continue
+				}
 				err := m.runInProgressOnce()
 				if err != nil {
-					m.events <- MonitorErrorEvent{
+					sendEvent(m.ctx.Done(), m.events, MonitorErrorEvent{
 						Error: err,
-					}
+					})
 				}
 			case <-m.ctx.Done():
 				log.Info("Shutting down the in-progress monitor")
@@ -72,16 +95,21 @@ func (m monitor) InProgressMonitorLoop(tickerDuration time.Duration) {
 }
 
 func (m monitor) PendingMonitorLoop(tickerDuration time.Duration) {
-	ticker := time.NewTicker(tickerDuration)
+	ticker := m.newTicker(&m.tickers.pending, tickerDuration)
+	m.running.Add(1)
 	go func() {
+		defer m.running.Done()
 		for {
 			select {
 			case <-ticker.C:
+				if m.ctx.Err() != nil {
+					continue
+				}
 				err := m.runPendingOnce()
 				if err != nil {
-					m.events <- MonitorErrorEvent{
+					sendEvent(m.ctx.Done(), m.events, MonitorErrorEvent{
 						Error: err,
-					}
+					})
 				}
 			case <-m.ctx.Done():
 				log.Info("Shutting down the pending monitor")
@@ -92,6 +120,38 @@ func (m monitor) PendingMonitorLoop(tickerDuration time.Duration) {
 	}()
 }
 
+func (m monitor) SetTickerDuration(inProgressDuration time.Duration, pendingDuration time.Duration) error {
+	if inProgressDuration <= 0 {
+		return errors.Errorf("Invalid in-progress monitor interval %s", inProgressDuration)
+	}
+	if pendingDuration <= 0 {
+		return errors.Errorf("Invalid pending monitor interval %s", pendingDuration)
+	}
+
+	m.tickers.lock.Lock()
+	defer m.tickers.lock.Unlock()
+
+	if m.tickers.inProgress != nil {
+		m.tickers.inProgress.Reset(inProgressDuration)
+	}
+	if m.tickers.pending != nil {
+		m.tickers.pending.Reset(pendingDuration)
+	}
+	return nil
+}
+
+func (m monitor) newTicker(ticker **time.Ticker, tickerDuration time.Duration) *time.Ticker {
+	m.tickers.lock.Lock()
+	defer m.tickers.lock.Unlock()
+
+	*ticker = time.NewTicker(tickerDuration)
+	return *ticker
+}
+
+func (m monitor) Wait() {
+	m.running.Wait()
+}
+
 func (m monitor) runInProgressOnce() error {
 	environments, err := m.environment.ListEnvironments(m.ctx)
 	if err != nil {
@@ -103,8 +163,10 @@ func (m monitor) runInProgressOnce() error {
 	}
 
 	for _, environment := range environments {
-		m.events <- UpdateInProgressDeploymentEvent{
+		if !sendEvent(m.ctx.Done(), m.events, UpdateInProgressDeploymentEvent{
 			Environment: environment,
+		}) {
+			return nil
 		}
 	}
 
@@ -122,8 +184,10 @@ func (m monitor) runPendingOnce() error {
 	}
 
 	for _, environment := range environments {
-		m.events <- UpdatePendingDeploymentEvent{
+		if !sendEvent(m.ctx.Done(), m.events, UpdatePendingDeploymentEvent{
 			Environment: environment,
+		}) {
+			return nil
 		}
 	}
 
@@ -135,3 +135,20 @@ func (suite *MonitorTestSuite) TestPendingListMultipleEnvironments() {
 		}
 	}
 }
+
+func (suite *MonitorTestSuite) TestSetTickerDurationPerLoop() {
+	ctx, cancel := context.WithCancel(suite.ctx)
+	defer cancel()
+	events := make(chan Event)
+
+	monitor := NewMonitor(ctx, suite.environment, events)
+	suite.environment.EXPECT().ListEnvironments(ctx).Return([]types.Environment{*suite.env1}, nil).AnyTimes()
+
+	monitor.InProgressMonitorLoop(time.Hour)
+	monitor.PendingMonitorLoop(time.Hour)
+	assert.Error(suite.T(), monitor.SetTickerDuration(time.Hour, 0), "Expected error setting an invalid interval")
+	assert.Nil(suite.T(), monitor.SetTickerDuration(time.Hour, time.Millisecond), "Unexpected error setting the intervals")
+
+	_, ok := (<-events).(UpdatePendingDeploymentEvent)
+	assert.True(suite.T(), ok, "Expected only the pending monitor to run at its own interval")
+}
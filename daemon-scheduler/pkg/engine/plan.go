@@ -0,0 +1,243 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/daemon-scheduler/pkg/deployment"
+	"github.com/blox/blox/daemon-scheduler/pkg/facade"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/pkg/errors"
+)
+
+// Planner works out what the scheduler would do to roll out a new deployment of an environment
+type Planner interface {
+	// PlanDeployment returns the instances a new deployment of the desired task definition of the
+	// environment would start tasks on, the tasks it would stop and the order of its batches,
+	// without starting or stopping any task
+	PlanDeployment(ctx context.Context, environmentName string) (*types.DeploymentPlan, error)
+}
+
+type planner struct {
+	environmentSvc deployment.Environment
+	deploymentSvc  deployment.Deployment
+	css            facade.ClusterState
+	ecs            facade.ECS
+}
+
+// NewPlanner creates a planner that looks up instances the way the scheduler does
+func NewPlanner(environmentSvc deployment.Environment, deploymentSvc deployment.Deployment,
+	css facade.ClusterState, ecs facade.ECS) Planner {
+
+	return planner{
+		environmentSvc: environmentSvc,
+		deploymentSvc:  deploymentSvc,
+		css:            css,
+		ecs:            ecs,
+	}
+}
+
+func (p planner) PlanDeployment(ctx context.Context, environmentName string) (*types.DeploymentPlan, error) {
+	environment, err := p.environmentSvc.GetEnvironment(ctx, environmentName)
+	if err != nil {
+		return nil, err
+	}
+	if environment == nil {
+		return nil, types.NewNotFoundError(errors.Errorf("Environment %s does not exist", environmentName))
+	}
+	if environment.IsDeleting() {
+		return nil, types.NewBadRequestError(errors.Errorf("Environment %s is being deleted", environmentName))
+	}
+
+	// the scheduler only looks up instances, it never starts or stops tasks itself
+	s := &scheduler{
+		ctx:            ctx,
+		environmentSvc: p.environmentSvc,
+		deploymentSvc:  p.deploymentSvc,
+		css:            p.css,
+	}
+
+	var clusters []string
+	if environment.HasClusterSelector() {
+		clusters, err = p.css.ListClusters()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error getting clusters to select")
+		}
+		sort.Strings(clusters)
+	}
+
+	plan := &types.DeploymentPlan{
+		TaskDefinition: environment.DesiredTaskDefinition,
+		Clusters:       make([]types.ClusterPlan, 0),
+	}
+	targets, err := clusterTargets(*environment, clusters, p.ecs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error selecting the clusters of environment %s", environmentName)
+	}
+	for _, target := range targets {
+		clusterPlan, err := p.planCluster(s, target)
+		if err != nil {
+			return nil, err
+		}
+		plan.Clusters = append(plan.Clusters, *clusterPlan)
+	}
+	return plan, nil
+}
+
+// planCluster returns what the scheduler would do in the cluster of the environment once a new
+// deployment is created
+func (p planner) planCluster(s *scheduler, environment types.Environment) (*types.ClusterPlan, error) {
+	state := &environmentExecutionState{
+		name:         environment.Name,
+		environment:  environment,
+		trackingInfo: make(map[string]time.Time),
+		latest:       environment,
+	}
+	result, err := s.lookupInstances(state)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error finding instances to deploy for environment %s", environment.Name)
+	}
+
+	plan := &types.ClusterPlan{
+		Cluster:          environment.Cluster,
+		NewInstances:     make([]string, 0),
+		Batches:          make([][]string, 0),
+		StoppedTasks:     make([]types.PlannedTask, 0),
+		SkippedInstances: make([]types.SkippedInstance, 0),
+	}
+	skipped := make(map[string]string, len(result.ineligibleInstances))
+	for instanceARN, reason := range result.ineligibleInstances {
+		skipped[instanceARN] = reason
+	}
+
+	// a new deployment starts with no restarts held back, so every new instance gets a task
+	// unless it is short of capacity
+	newInstances := make([]string, 0, len(result.newInstances))
+	for _, instanceARN := range result.newInstances {
+		newInstances = append(newInstances, aws.StringValue(instanceARN))
+	}
+	sort.Strings(newInstances)
+	shortOfCapacity := 0
+	if len(newInstances) > 0 {
+		capacity, err := p.deploymentSvc.PlanCapacity(s.ctx, environment, environment.Cluster, newInstances)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error checking the capacity of instances for environment %s", environment.Name)
+		}
+		plan.NewInstances = append(plan.NewInstances, capacity.Available...)
+		for instanceARN, reason := range capacity.Insufficient {
+			skipped[instanceARN] = reason
+		}
+		shortOfCapacity = len(capacity.Insufficient)
+		plan.StoppedTasks = append(plan.StoppedTasks, capacity.StoppedTasks...)
+	}
+
+	// every instance running tasks of the environment runs earlier deployments only
+	outdated := outdatedInstances(&types.Deployment{}, result)
+	batches, blocked := planBatches(environment.RolloutStrategy, result, outdated, shortOfCapacity)
+	plan.Batches = batches
+	for _, instanceARN := range blocked {
+		skipped[instanceARN] = types.RolloutBlockedReason
+	}
+
+	for _, batch := range batches {
+		for _, instanceARN := range batch {
+			for _, dt := range result.deployedInstances[instanceARN] {
+				plan.StoppedTasks = append(plan.StoppedTasks, types.PlannedTask{
+					TaskARN:      dt.taskARN,
+					InstanceARN:  instanceARN,
+					Environment:  environment.Name,
+					DeploymentID: dt.deploymentID,
+				})
+			}
+		}
+	}
+
+	// the tasks on the instances the strategy no longer places the environment on would stop too
+	excess := make([]string, 0, len(result.excessInstances))
+	for instanceARN := range result.excessInstances {
+		excess = append(excess, instanceARN)
+	}
+	sort.Strings(excess)
+	for _, instanceARN := range excess {
+		for _, dt := range result.excessInstances[instanceARN] {
+			if !dt.availableInClusterState {
+				continue
+			}
+			plan.StoppedTasks = append(plan.StoppedTasks, types.PlannedTask{
+				TaskARN:      dt.taskARN,
+				InstanceARN:  instanceARN,
+				Environment:  environment.Name,
+				DeploymentID: dt.deploymentID,
+			})
+		}
+	}
+
+	for instanceARN, reason := range skipped {
+		plan.SkippedInstances = append(plan.SkippedInstances, types.SkippedInstance{
+			InstanceARN: instanceARN,
+			Reason:      reason,
+		})
+	}
+	sort.Sort(instanceOrderedSkippedInstances(plan.SkippedInstances))
+	return plan, nil
+}
+
+// planBatches splits the outdated instances into the batches they would be updated in, assuming
+// the tasks of every batch become healthy, and returns the instances that would wait for more
+// instances to be healthy for good. shortOfCapacity instances would not get a task and stay
+// unavailable.
+func planBatches(strategy types.RolloutStrategy, result *instanceLookupResult, outdated []string,
+	shortOfCapacity int) ([][]string, []string) {
+
+	batches := make([][]string, 0)
+	if len(outdated) == 0 {
+		return batches, nil
+	}
+	if !strategy.IsRolling() {
+		return append(batches, outdated), nil
+	}
+
+	// the first batch waits for the tasks started on new instances if there are too few
+	// instances running tasks of the environment
+	size := strategy.NextBatchSize(result.totalInstanceCount, unavailableInstanceCount(result))
+	for len(outdated) > 0 {
+		if size == 0 {
+			size = strategy.NextBatchSize(result.totalInstanceCount, shortOfCapacity)
+			if size == 0 {
+				return batches, outdated
+			}
+		}
+		if size > len(outdated) {
+			size = len(outdated)
+		}
+		batches = append(batches, outdated[:size])
+		outdated = outdated[size:]
+		size = strategy.NextBatchSize(result.totalInstanceCount, shortOfCapacity)
+	}
+	return batches, nil
+}
+
+type instanceOrderedSkippedInstances []types.SkippedInstance
+
+func (p instanceOrderedSkippedInstances) Len() int { return len(p) }
+
+func (p instanceOrderedSkippedInstances) Less(i, j int) bool {
+	return p[i].InstanceARN < p[j].InstanceARN
+}
+
+func (p instanceOrderedSkippedInstances) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
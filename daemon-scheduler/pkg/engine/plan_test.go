@@ -0,0 +1,99 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *SchedulerTestSuite) TestPlanDeployment() {
+	ctx := context.Background()
+
+	environment, _ := rolloutEnvironment(types.RolloutStrategy{BatchSize: 1})
+	environment.DesiredTaskDefinition = "task-definition-arn"
+	suite.environmentSvc.EXPECT().GetEnvironment(ctx, environment.Name).Return(&environment, nil)
+
+	instances := []*models.ContainerInstance{
+		containerInstance("testCluster", rolloutInstance1, "ACTIVE"),
+		containerInstance("testCluster", rolloutInstance2, "ACTIVE"),
+		containerInstance("testCluster", rolloutInstance3, "ACTIVE"),
+		containerInstance("testCluster", "instance-arn-4", "ACTIVE"),
+		containerInstance("testCluster", "instance-arn-5", "INACTIVE"),
+	}
+	suite.css.EXPECT().ListInstances(environment.Cluster).Return(instances, nil)
+	tasks := []*models.Task{
+		rolloutTask(rolloutInstance1, "old-dep-id", runningTaskStatus),
+		rolloutTask(rolloutInstance2, "old-dep-id", runningTaskStatus),
+	}
+	suite.css.EXPECT().ListTasks(environment.Cluster).Return(tasks, nil)
+	deployments := []types.Deployment{{ID: "old-dep-id", Status: types.DeploymentCompleted}}
+	suite.deploymentSvc.EXPECT().ListDeploymentsSortedReverseChronologically(ctx, environment.Name).Return(deployments, nil)
+
+	capacity := &types.CapacityPlan{
+		Available:    []string{rolloutInstance3},
+		Insufficient: map[string]string{"instance-arn-4": "RESOURCE:MEMORY"},
+	}
+	suite.deploymentSvc.EXPECT().PlanCapacity(ctx, gomock.Any(), environment.Cluster,
+		[]string{rolloutInstance3, "instance-arn-4"}).Return(capacity, nil)
+
+	planner := NewPlanner(suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	plan, err := planner.PlanDeployment(ctx, environment.Name)
+	assert.Nil(suite.T(), err, "Unexpected error planning a deployment")
+	assert.Equal(suite.T(), environment.DesiredTaskDefinition, plan.TaskDefinition)
+	assert.Len(suite.T(), plan.Clusters, 1, "Expected a plan for the cluster of the environment")
+
+	clusterPlan := plan.Clusters[0]
+	assert.Equal(suite.T(), environment.Cluster, clusterPlan.Cluster)
+	assert.Equal(suite.T(), []string{rolloutInstance3}, clusterPlan.NewInstances,
+		"Expected the new instance with enough capacity to get a task")
+	assert.Equal(suite.T(), [][]string{{rolloutInstance1}, {rolloutInstance2}}, clusterPlan.Batches,
+		"Expected the outdated instances to be updated one at a time")
+	assert.Equal(suite.T(), []types.PlannedTask{
+		{TaskARN: "task-" + rolloutInstance1, InstanceARN: rolloutInstance1, Environment: environment.Name, DeploymentID: "old-dep-id"},
+		{TaskARN: "task-" + rolloutInstance2, InstanceARN: rolloutInstance2, Environment: environment.Name, DeploymentID: "old-dep-id"},
+	}, clusterPlan.StoppedTasks, "Expected the outdated tasks to be stopped")
+	assert.Equal(suite.T(), []types.SkippedInstance{
+		{InstanceARN: "instance-arn-4", Reason: "RESOURCE:MEMORY"},
+		{InstanceARN: "instance-arn-5", Reason: types.InactiveInstanceReason},
+	}, clusterPlan.SkippedInstances, "Expected the instances short of capacity and inactive to be skipped")
+}
+
+func (suite *SchedulerTestSuite) TestPlanDeploymentMissingEnvironment() {
+	ctx := context.Background()
+	suite.environmentSvc.EXPECT().GetEnvironment(ctx, "TestPlan").Return(nil, nil)
+
+	planner := NewPlanner(suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	_, err := planner.PlanDeployment(ctx, "TestPlan")
+	assert.Error(suite.T(), err, "Expected an error planning a deployment of a missing environment")
+	_, ok := err.(types.NotFoundError)
+	assert.True(suite.T(), ok, "Expected a not found error")
+}
+
+func (suite *SchedulerTestSuite) TestPlanBatchesBlockedByUnhealthyInstances() {
+	strategy := types.RolloutStrategy{BatchSize: 2, MinHealthyPercent: 75}
+	result := &instanceLookupResult{
+		totalInstanceCount: 4,
+		deployedInstances:  make(map[string][]*deployedTask),
+	}
+	outdated := []string{rolloutInstance1, rolloutInstance2, rolloutInstance3}
+
+	batches, blocked := planBatches(strategy, result, outdated, 1)
+	assert.Empty(suite.T(), batches, "Expected no batch while too few instances are healthy")
+	assert.Equal(suite.T(), outdated, blocked, "Expected every outdated instance to wait")
+}
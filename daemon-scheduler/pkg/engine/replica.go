@@ -0,0 +1,123 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+)
+
+// memoryResource is the name of the remaining memory of instances in the cluster state
+const memoryResource = "MEMORY"
+
+// replicaStrategy places the environment on a fixed number of instances. The instances that
+// already run a task are kept as long as there are not too many, so that tasks only move when the
+// replicas go down or their instance goes away. New tasks are spread over the availability zones
+// of the cluster first, and then packed onto the instances with the least memory left.
+type replicaStrategy struct {
+	replicas int
+}
+
+func (r replicaStrategy) Place(environment types.Environment, cluster ClusterState) []string {
+	deployed := make([]string, 0, len(cluster.Deployed))
+	available := make([]string, 0, len(cluster.Instances))
+	unavailable := make([]string, 0, len(cluster.Unavailable))
+	for instanceARN := range cluster.Instances {
+		switch {
+		case cluster.Deployed[instanceARN]:
+			deployed = append(deployed, instanceARN)
+		case cluster.Unavailable[instanceARN]:
+			unavailable = append(unavailable, instanceARN)
+		default:
+			available = append(available, instanceARN)
+		}
+	}
+
+	placed := make([]string, 0, r.replicas)
+	zones := make(map[string]int)
+	for _, group := range [][]string{deployed, available, unavailable} {
+		candidates := newReplicaCandidates(group, cluster.Instances)
+		for len(placed) < r.replicas && len(candidates) > 0 {
+			i := candidates.next(zones)
+			placed = append(placed, candidates[i].instanceARN)
+			zones[candidates[i].zone]++
+			candidates = append(candidates[:i], candidates[i+1:]...)
+		}
+	}
+	sort.Strings(placed)
+	return placed
+}
+
+// replicaCandidate is an instance a replica may be placed on
+type replicaCandidate struct {
+	instanceARN string
+	zone        string
+	// memory is the memory left on the instance, or the largest value if it is not known
+	memory int64
+}
+
+type replicaCandidates []replicaCandidate
+
+func newReplicaCandidates(instanceARNs []string, instances map[string]*models.ContainerInstance) replicaCandidates {
+	candidates := make(replicaCandidates, 0, len(instanceARNs))
+	for _, instanceARN := range instanceARNs {
+		instance := instances[instanceARN]
+		candidate := replicaCandidate{
+			instanceARN: instanceARN,
+			memory:      math.MaxInt64,
+		}
+		for _, attribute := range instance.Attributes {
+			if aws.StringValue(attribute.Name) == types.AvailabilityZoneAttribute {
+				candidate.zone = aws.StringValue(attribute.Value)
+			}
+		}
+		for _, resource := range instance.RemainingResources {
+			if aws.StringValue(resource.Name) != memoryResource {
+				continue
+			}
+			if memory, err := strconv.ParseInt(aws.StringValue(resource.Value), 10, 64); err == nil {
+				candidate.memory = memory
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// next returns the index of the candidate the next replica is placed on: the one in the zone with
+// the fewest replicas, then with the least memory left, then with the lowest ARN
+func (c replicaCandidates) next(zones map[string]int) int {
+	best := 0
+	for i := 1; i < len(c); i++ {
+		if c.before(i, best, zones) {
+			best = i
+		}
+	}
+	return best
+}
+
+func (c replicaCandidates) before(i int, j int, zones map[string]int) bool {
+	if zones[c[i].zone] != zones[c[j].zone] {
+		return zones[c[i].zone] < zones[c[j].zone]
+	}
+	if c[i].memory != c[j].memory {
+		return c[i].memory < c[j].memory
+	}
+	return c[i].instanceARN < c[j].instanceARN
+}
@@ -0,0 +1,194 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// checkRestarts records the tasks of the current deployment that crashed on the instances of the
+// cluster and holds back restarting them until their backoff is over, or for good once the
+// instance is crash-looping. Instances that are held are removed from the new instances and
+// added to the held instances of the result. The restarts are persisted with the environment so
+// that they survive the scheduler restarting.
+func (s *scheduler) checkRestarts(state *environmentExecutionState, currentDeployment *types.Deployment,
+	result *instanceLookupResult) error {
+
+	environment := state.environment
+	policy := environment.RestartPolicy.WithDefaults()
+	now := time.Now().UTC()
+	updates := make(map[string]*types.InstanceRestart)
+
+	eligible := make(map[string]bool, result.totalInstanceCount)
+	for instanceARN := range result.deployedInstances {
+		eligible[instanceARN] = true
+	}
+	for _, instanceARN := range result.newInstances {
+		eligible[aws.StringValue(instanceARN)] = true
+	}
+
+	// the restarts are reset once the instance is gone, a new deployment is rolled out or a task
+	// of the deployment has kept running
+	for instanceARN, restart := range environment.Restarts {
+		if restart.Cluster != environment.Cluster {
+			continue
+		}
+		if !eligible[instanceARN] || restart.DeploymentID != currentDeployment.ID ||
+			isStable(result.deployedInstances[instanceARN], currentDeployment.ID, policy, now) {
+			updates[instanceARN] = nil
+		}
+	}
+
+	for instanceARN, tasks := range result.stoppedTasks {
+		if isStarted(result.deployedInstances[instanceARN], currentDeployment.ID) {
+			continue
+		}
+		task := latestStoppedTask(tasks, currentDeployment.ID)
+		if task == nil {
+			continue
+		}
+
+		restart, ok := environment.Restarts[instanceARN]
+		if update, updated := updates[instanceARN]; updated {
+			ok = update != nil
+			if ok {
+				restart = *update
+			}
+		}
+
+		stopped := toStoppedTask(task, now)
+		if !policy.Crashed(stopped) {
+			if ok {
+				updates[instanceARN] = nil
+			}
+			continue
+		}
+
+		if !ok || restart.DeploymentID != currentDeployment.ID || restart.LastStoppedTask.TaskARN != stopped.TaskARN {
+			restart = restart.RecordCrash(environment.Cluster, currentDeployment.ID, stopped, policy, now)
+			updates[instanceARN] = &restart
+			if policy.IsCrashLooping(restart) {
+				log.Warnf("[s:%s, e:%s] Task of deployment %s crashed %d times on instance %s, not restarting it: %s",
+					s.id, environment.Name, currentDeployment.ID, restart.Count, instanceARN, stopped.StoppedReason)
+			}
+		}
+
+		if policy.IsCrashLooping(restart) {
+			result.heldInstances[instanceARN] = true
+		} else if now.Before(restart.NextRestart) {
+			log.Infof("[s:%s, e:%s] Task of deployment %s crashed on instance %s, restarting it in %s",
+				s.id, environment.Name, currentDeployment.ID, instanceARN, restart.NextRestart.Sub(now))
+			result.heldInstances[instanceARN] = true
+			s.recheckLater(state, restart.NextRestart.Sub(now))
+		}
+	}
+
+	newInstances := make([]*string, 0, len(result.newInstances))
+	for _, instanceARN := range result.newInstances {
+		if !result.heldInstances[aws.StringValue(instanceARN)] {
+			newInstances = append(newInstances, instanceARN)
+		}
+	}
+	result.newInstances = newInstances
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	_, err := s.environmentSvc.UpdateEnvironment(s.ctx, environment.Name, func(latest *types.Environment) error {
+		latest.UpdateRestarts(updates)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Error recording the restarts of deployment %s", currentDeployment.ID)
+	}
+	return nil
+}
+
+// isStarted returns whether any of the tasks was started by deploymentID and is meant to be running
+func isStarted(deployedTasks []*deployedTask, deploymentID string) bool {
+	for _, dt := range deployedTasks {
+		if dt.availableInClusterState && dt.deploymentID == deploymentID {
+			return true
+		}
+	}
+	return false
+}
+
+// isStable returns whether any of the tasks was started by deploymentID and has been running for
+// the stable time of the policy
+func isStable(deployedTasks []*deployedTask, deploymentID string, policy types.RestartPolicy, now time.Time) bool {
+	for _, dt := range deployedTasks {
+		if !dt.availableInClusterState || !dt.running || dt.deploymentID != deploymentID {
+			continue
+		}
+		startedAt, err := time.Parse(time.RFC3339, dt.startedAt)
+		if err == nil && now.Sub(startedAt) >= policy.StableTime {
+			return true
+		}
+	}
+	return false
+}
+
+// latestStoppedTask returns the task started by deploymentID that stopped last, or nil if there is
+// none. Tasks the scheduler stopped itself did not crash and are left out.
+func latestStoppedTask(tasks []*models.Task, deploymentID string) *models.Task {
+	var latest *models.Task
+	for _, task := range tasks {
+		if task.StartedBy != deploymentID || types.IsStoppedByScheduler(task.StoppedReason) {
+			continue
+		}
+		if latest == nil || stoppedAt(task).After(stoppedAt(latest)) ||
+			(stoppedAt(task).Equal(stoppedAt(latest)) && aws.StringValue(task.TaskARN) > aws.StringValue(latest.TaskARN)) {
+			latest = task
+		}
+	}
+	return latest
+}
+
+// stoppedAt returns when the task stopped, or the zero time if it is still stopping
+func stoppedAt(task *models.Task) time.Time {
+	t, err := time.Parse(time.RFC3339, task.StoppedAt)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// toStoppedTask returns what is recorded of the stopped task. A task that is still stopping is
+// taken to have stopped now.
+func toStoppedTask(task *models.Task, now time.Time) types.StoppedTask {
+	stopped := types.StoppedTask{
+		TaskARN:       aws.StringValue(task.TaskARN),
+		StoppedAt:     stoppedAt(task),
+		StoppedReason: task.StoppedReason,
+		ExitCodes:     make(map[string]int64, len(task.Containers)),
+	}
+	if stopped.StoppedAt.IsZero() {
+		stopped.StoppedAt = now
+	}
+	if startedAt, err := time.Parse(time.RFC3339, task.StartedAt); err == nil {
+		stopped.StartedAt = startedAt
+	}
+	for _, container := range task.Containers {
+		stopped.ExitCodes[aws.StringValue(container.Name)] = container.ExitCode
+	}
+	return stopped
+}
@@ -0,0 +1,182 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *SchedulerTestSuite) TestRestartBacksOffCrashedTask() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{})
+	crashed := crashedTask(rolloutInstance1, "task-crashed", currentDeployment.ID, time.Minute)
+	tasks := []*models.Task{
+		crashed,
+		rolloutTask(rolloutInstance2, currentDeployment.ID, runningTaskStatus),
+	}
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, tasks)
+	recorded := suite.expectRecordRollout(ctx, environment, currentDeployment)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	_, ok := (<-events).(SchedulerEnvironmentEvent)
+	assert.True(suite.T(), ok, "Expected the crashed task not to be restarted before its backoff")
+
+	restart := (<-recorded).Restarts[rolloutInstance1]
+	assert.Exactly(suite.T(), 1, restart.Count, "Expected the crash to be counted")
+	assert.Exactly(suite.T(), environment.Cluster, restart.Cluster, "Expected the cluster to be recorded")
+	assert.Exactly(suite.T(), currentDeployment.ID, restart.DeploymentID, "Expected the deployment to be recorded")
+	assert.Exactly(suite.T(), "task-crashed", restart.LastStoppedTask.TaskARN, "Expected the crashed task to be recorded")
+	assert.Exactly(suite.T(), crashed.StoppedReason, restart.LastStoppedTask.StoppedReason, "Expected the stopped reason to be recorded")
+	assert.Equal(suite.T(), map[string]int64{"app": 1}, restart.LastStoppedTask.ExitCodes, "Expected the exit codes to be recorded")
+	assert.True(suite.T(), restart.NextRestart.After(time.Now()), "Expected the restart to be delayed")
+}
+
+func (suite *SchedulerTestSuite) TestRestartAfterBackoff() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{})
+	environment.Restarts = map[string]types.InstanceRestart{
+		rolloutInstance1: {
+			Cluster:         environment.Cluster,
+			DeploymentID:    currentDeployment.ID,
+			Count:           1,
+			NextRestart:     time.Now().Add(-time.Second),
+			LastStoppedTask: types.StoppedTask{TaskARN: "task-crashed"},
+		},
+	}
+	tasks := []*models.Task{crashedTask(rolloutInstance1, "task-crashed", currentDeployment.ID, time.Minute)}
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, tasks)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	startDeploymentEvent := (<-events).(StartDeploymentEvent)
+	assert.Equal(suite.T(), []*string{aws.String(rolloutInstance1)}, startDeploymentEvent.Instances,
+		"Expected the task to be restarted once its backoff is over")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+}
+
+func (suite *SchedulerTestSuite) TestRestartStopsWhenCrashLooping() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{})
+	environment.RestartPolicy = types.RestartPolicy{MaxRestarts: 2}
+	environment.Restarts = map[string]types.InstanceRestart{
+		rolloutInstance1: {
+			Cluster:         environment.Cluster,
+			DeploymentID:    currentDeployment.ID,
+			Count:           2,
+			NextRestart:     time.Now().Add(-time.Second),
+			LastStoppedTask: types.StoppedTask{TaskARN: "task-crashed-before"},
+		},
+	}
+	tasks := []*models.Task{crashedTask(rolloutInstance1, "task-crashed", currentDeployment.ID, time.Minute)}
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, tasks)
+	recorded := suite.expectRecordRollout(ctx, environment, currentDeployment)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	_, ok := (<-events).(SchedulerEnvironmentEvent)
+	assert.True(suite.T(), ok, "Expected the task not to be restarted on a crash-looping instance")
+
+	latest := <-recorded
+	assert.Equal(suite.T(), []string{rolloutInstance1}, latest.CrashLoopingInstances(), "Expected the instance to be crash-looping")
+	assert.Exactly(suite.T(), types.EnvironmentUnhealthy, latest.Health, "Expected the environment to be unhealthy")
+}
+
+func (suite *SchedulerTestSuite) TestRestartDoesNotCountStoppedStableTask() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{})
+	tasks := []*models.Task{crashedTask(rolloutInstance1, "task-stopped", currentDeployment.ID, time.Hour)}
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, tasks)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	startDeploymentEvent := (<-events).(StartDeploymentEvent)
+	assert.Equal(suite.T(), []*string{aws.String(rolloutInstance1)}, startDeploymentEvent.Instances,
+		"Expected a task that ran for the stable time to be restarted right away")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+}
+
+func (suite *SchedulerTestSuite) TestRestartDoesNotCountTaskStoppedByScheduler() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{})
+	evicted := crashedTask(rolloutInstance1, "task-evicted", currentDeployment.ID, time.Minute)
+	evicted.StoppedReason = types.StopReasonEvicted
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, []*models.Task{evicted})
+
+	events := suite.startRolloutScheduler(ctx)
+
+	startDeploymentEvent := (<-events).(StartDeploymentEvent)
+	assert.Equal(suite.T(), []*string{aws.String(rolloutInstance1)}, startDeploymentEvent.Instances,
+		"Expected a task the scheduler stopped not to count as a crash")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+}
+
+func (suite *SchedulerTestSuite) TestRestartsResetOnceTaskIsStable() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{})
+	environment.Restarts = map[string]types.InstanceRestart{
+		rolloutInstance1: {
+			Cluster:      environment.Cluster,
+			DeploymentID: currentDeployment.ID,
+			Count:        3,
+		},
+	}
+	running := rolloutTask(rolloutInstance1, currentDeployment.ID, runningTaskStatus)
+	running.StartedAt = time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, []*models.Task{running})
+	recorded := suite.expectRecordRollout(ctx, environment, currentDeployment)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	_ = (<-events).(SchedulerEnvironmentEvent)
+	assert.Empty(suite.T(), (<-recorded).Restarts, "Expected the restarts to be reset")
+}
+
+// crashedTask returns a task of the deployment that stopped on the instance after running for ranFor
+func crashedTask(instanceARN string, taskARN string, deploymentID string, ranFor time.Duration) *models.Task {
+	stoppedAt := time.Now().UTC().Add(-time.Second)
+	return &models.Task{
+		ClusterARN:           aws.String("testCluster"),
+		ContainerInstanceARN: aws.String(instanceARN),
+		TaskARN:              aws.String(taskARN),
+		StartedBy:            deploymentID,
+		DesiredStatus:        aws.String(stoppedTaskStatus),
+		LastStatus:           aws.String(stoppedTaskStatus),
+		StartedAt:            stoppedAt.Add(-ranFor).Format(time.RFC3339),
+		StoppedAt:            stoppedAt.Format(time.RFC3339),
+		StoppedReason:        "Essential container in task exited",
+		Containers: []*models.TaskContainer{{
+			Name:     aws.String("app"),
+			ExitCode: 1,
+		}},
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// RolloutCheckInterval is how often an environment is scheduled while it waits for the tasks of
+// a rollout batch to be running, since tasks reaching RUNNING do not trigger scheduling
+const RolloutCheckInterval = 10 * time.Second
+
+// MaintenanceCheckInterval is how often an environment is scheduled while its deployment waits
+// for its start time or a maintenance window, which open on the minute
+const MaintenanceCheckInterval = time.Minute
+
+// rolloutInstances returns the outdated instances, which only run tasks of earlier deployments,
+// that can be updated in this run. Under a rolling strategy, a new batch of outdated instances
+// is only started once every task of the previous batch has been running for the soak time.
+// The batches are recorded in the deployment so that a new leader carries on with the rollout.
+func (s *scheduler) rolloutInstances(state *environmentExecutionState, currentDeployment *types.Deployment,
+	result *instanceLookupResult, outdated []string) ([]string, error) {
+
+	environment := state.environment
+	strategy := environment.RolloutStrategy
+	if !strategy.IsRolling() || currentDeployment.Status == types.DeploymentCompleted {
+		return outdated, nil
+	}
+
+	now := time.Now().UTC()
+	recordedBatches, rolloutCompleted := rolloutProgress(environment, currentDeployment)
+	batches := append([]types.DeploymentBatch(nil), recordedBatches...)
+	changed := false
+	if n := len(batches); n > 0 && batches[n-1].Status != types.BatchCompleted {
+		batch := &batches[n-1]
+		changed = batch.Update(isBatchRunning(*batch, currentDeployment.ID, result), strategy.SoakTime, now)
+		if batch.Status != types.BatchCompleted {
+			s.recheckRolloutLater(state, batch, strategy.SoakTime, now)
+			if changed {
+				err := s.recordRollout(environment, currentDeployment.ID, batches, false)
+				if err != nil {
+					return nil, err
+				}
+			}
+			// instances of the batch are updated again if replacing their tasks failed
+			return intersect(batch.Instances, outdated), nil
+		}
+		log.Infof("[s:%s, e:%s] Batch %d of deployment %s completed", s.id, environment.Name, n, currentDeployment.ID)
+	}
+
+	if len(outdated) == 0 {
+		if rolloutCompleted && !changed {
+			return nil, nil
+		}
+		log.Infof("[s:%s, e:%s] Rollout of deployment %s completed", s.id, environment.Name, currentDeployment.ID)
+		return nil, s.recordRollout(environment, currentDeployment.ID, batches, true)
+	}
+
+	size := strategy.NextBatchSize(result.totalInstanceCount, unavailableInstanceCount(result))
+	if size == 0 {
+		log.Infof("[s:%s, e:%s] Waiting for more instances to be healthy before updating %d outdated instances",
+			s.id, environment.Name, len(outdated))
+		s.recheckLater(state, RolloutCheckInterval)
+		if !changed {
+			return nil, nil
+		}
+		return nil, s.recordRollout(environment, currentDeployment.ID, batches, false)
+	}
+	if size > len(outdated) {
+		size = len(outdated)
+	}
+
+	batch := types.DeploymentBatch{
+		Instances: outdated[:size],
+		Status:    types.BatchInProgress,
+		StartTime: now,
+	}
+	batches = append(batches, batch)
+	// the batch is recorded before any task is replaced so that it is not started twice
+	err := s.recordRollout(environment, currentDeployment.ID, batches, false)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[s:%s, e:%s] Starting batch %d of deployment %s on %d of %d outdated instances",
+		s.id, environment.Name, len(batches), currentDeployment.ID, size, len(outdated))
+	s.recheckLater(state, RolloutCheckInterval)
+	return batch.Instances, nil
+}
+
+// rolloutProgress returns the batches of the deployment and whether its rollout completed, which
+// is recorded for each cluster of an environment with a cluster selector
+func rolloutProgress(environment types.Environment, d *types.Deployment) ([]types.DeploymentBatch, bool) {
+	if environment.HasClusterSelector() {
+		c := d.Clusters[environment.Cluster]
+		return c.Batches, c.RolloutCompleted
+	}
+	return d.Batches, d.RolloutCompleted
+}
+
+func (s *scheduler) recordRollout(environment types.Environment, deploymentID string,
+	batches []types.DeploymentBatch, completed bool) error {
+
+	_, err := s.environmentSvc.UpdateEnvironment(s.ctx, environment.Name, func(latest *types.Environment) error {
+		if environment.HasClusterSelector() {
+			return latest.UpdateClusterRollout(deploymentID, environment.Cluster, batches, completed)
+		}
+		return latest.UpdateRollout(deploymentID, batches, completed)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Error recording the rollout of deployment %s", deploymentID)
+	}
+	return nil
+}
+
+// recheckRolloutLater schedules the environment again when the batch may have moved forward
+func (s *scheduler) recheckRolloutLater(state *environmentExecutionState, batch *types.DeploymentBatch,
+	soakTime time.Duration, now time.Time) {
+
+	delay := RolloutCheckInterval
+	if batch.Status == types.BatchSoaking {
+		delay = batch.HealthySince.Add(soakTime).Sub(now)
+	}
+	s.recheckLater(state, delay)
+}
+
+// recheckLater schedules the environment again after delay, unless a recheck is already pending
+// or the scheduler shuts down first
+func (s *scheduler) recheckLater(state *environmentExecutionState, delay time.Duration) {
+	if !state.setRecheckPending() {
+		return
+	}
+
+	s.running.Add(1)
+	go func(s *scheduler, environment types.Environment) {
+		defer s.running.Done()
+		select {
+		case <-time.After(delay):
+			state.clearRecheckPending()
+			s.scheduleEnvironment(environment)
+		case <-s.ctx.Done():
+		}
+	}(s, state.environment)
+}
+
+// setRecheckPending marks a recheck of the environment as pending and returns false if one
+// already was
+func (state *environmentExecutionState) setRecheckPending() bool {
+	state.inProgressLock.Lock()
+	defer state.inProgressLock.Unlock()
+
+	if state.recheckPending {
+		return false
+	}
+	state.recheckPending = true
+	return true
+}
+
+func (state *environmentExecutionState) clearRecheckPending() {
+	state.inProgressLock.Lock()
+	defer state.inProgressLock.Unlock()
+
+	state.recheckPending = false
+}
+
+// outdatedInstances returns the instances, in order, that run tasks of earlier deployments only
+// and are not being deployed to
+func outdatedInstances(currentDeployment *types.Deployment, result *instanceLookupResult) []string {
+	outdated := make([]string, 0)
+	for instanceARN, deployedTasks := range result.deployedInstances {
+		if isOutdated(deployedTasks, currentDeployment.ID) {
+			outdated = append(outdated, instanceARN)
+		}
+	}
+	sort.Strings(outdated)
+	return outdated
+}
+
+// intersect returns the values that are in both a and b
+func intersect(a []string, b []string) []string {
+	values := make(map[string]bool, len(b))
+	for _, v := range b {
+		values[v] = true
+	}
+
+	intersection := make([]string, 0)
+	for _, v := range a {
+		if values[v] {
+			intersection = append(intersection, v)
+		}
+	}
+	return intersection
+}
+
+func isOutdated(deployedTasks []*deployedTask, deploymentID string) bool {
+	for _, dt := range deployedTasks {
+		if !dt.availableInClusterState || dt.deploymentID == deploymentID {
+			return false
+		}
+	}
+	return len(deployedTasks) > 0
+}
+
+// isBatchRunning returns whether every instance of the batch that is still in the cluster runs
+// a task of the deployment
+func isBatchRunning(batch types.DeploymentBatch, deploymentID string, result *instanceLookupResult) bool {
+	newInstances := make(map[string]bool, len(result.newInstances))
+	for _, instanceARN := range result.newInstances {
+		newInstances[aws.StringValue(instanceARN)] = true
+	}
+
+	for _, instanceARN := range batch.Instances {
+		deployedTasks, ok := result.deployedInstances[instanceARN]
+		if !ok {
+			// the tasks on the instance stopped, unless the instance left the cluster
+			if newInstances[instanceARN] {
+				return false
+			}
+			continue
+		}
+		if !isRunning(deployedTasks, deploymentID) {
+			return false
+		}
+	}
+	return true
+}
+
+// unavailableInstanceCount returns the number of instances that do not run any task of the environment
+func unavailableInstanceCount(result *instanceLookupResult) int {
+	available := 0
+	for _, deployedTasks := range result.deployedInstances {
+		if isRunning(deployedTasks, "") {
+			available++
+		}
+	}
+	return result.totalInstanceCount - available
+}
+
+// isRunning returns whether any of the tasks is running, and was started by deploymentID unless it is empty
+func isRunning(deployedTasks []*deployedTask, deploymentID string) bool {
+	for _, dt := range deployedTasks {
+		if dt.availableInClusterState && dt.running && (deploymentID == "" || dt.deploymentID == deploymentID) {
+			return true
+		}
+	}
+	return false
+}
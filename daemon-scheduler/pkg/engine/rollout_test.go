@@ -0,0 +1,304 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	rolloutInstance1 = "instance-arn-1"
+	rolloutInstance2 = "instance-arn-2"
+	rolloutInstance3 = "instance-arn-3"
+)
+
+func (suite *SchedulerTestSuite) TestRolloutStartsFirstBatch() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{BatchSize: 2})
+	tasks := []*models.Task{
+		rolloutTask(rolloutInstance1, "old-dep-id", runningTaskStatus),
+		rolloutTask(rolloutInstance2, "old-dep-id", runningTaskStatus),
+		rolloutTask(rolloutInstance3, "old-dep-id", runningTaskStatus),
+	}
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, tasks)
+	recorded := suite.expectRecordRollout(ctx, environment, currentDeployment)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	updated := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		stopTasksEvent := (<-events).(StopTasksEvent)
+		startDeploymentEvent := (<-events).(StartDeploymentEvent)
+		instanceARN := aws.StringValue(startDeploymentEvent.Instances[0])
+		assert.Equal(suite.T(), []string{"task-" + instanceARN}, stopTasksEvent.Tasks, "Expected the outdated task to stop")
+		updated[instanceARN] = true
+	}
+	assert.Equal(suite.T(), map[string]bool{rolloutInstance1: true, rolloutInstance2: true}, updated,
+		"Expected the deployment to start on the instances of the batch")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+
+	deployment := (<-recorded).Deployments[currentDeployment.ID]
+	assert.Len(suite.T(), deployment.Batches, 1, "Expected a batch to be recorded")
+	assert.Equal(suite.T(), []string{rolloutInstance1, rolloutInstance2}, deployment.Batches[0].Instances,
+		"Expected the batch to hold the first outdated instances")
+	assert.Exactly(suite.T(), types.BatchInProgress, deployment.Batches[0].Status, "Expected the batch to be in progress")
+	assert.False(suite.T(), deployment.RolloutCompleted, "Expected the rollout to be in progress")
+}
+
+func (suite *SchedulerTestSuite) TestRolloutWaitsForBatchToRun() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{BatchSize: 1})
+	currentDeployment.Batches = []types.DeploymentBatch{{
+		Instances: []string{rolloutInstance1},
+		Status:    types.BatchInProgress,
+		StartTime: time.Now(),
+	}}
+	tasks := []*models.Task{
+		rolloutTask(rolloutInstance1, currentDeployment.ID, "PENDING"),
+		rolloutTask(rolloutInstance2, "old-dep-id", runningTaskStatus),
+	}
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, tasks)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	_, ok := (<-events).(SchedulerEnvironmentEvent)
+	assert.True(suite.T(), ok, "Expected no task to be replaced while the batch is not running")
+}
+
+func (suite *SchedulerTestSuite) TestRolloutStartsNextBatchAfterSoaking() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{BatchSize: 1, SoakTime: time.Minute})
+	currentDeployment.Batches = []types.DeploymentBatch{{
+		Instances:    []string{rolloutInstance1},
+		Status:       types.BatchSoaking,
+		StartTime:    time.Now().Add(-3 * time.Minute),
+		HealthySince: time.Now().Add(-2 * time.Minute),
+	}}
+	tasks := []*models.Task{
+		rolloutTask(rolloutInstance1, currentDeployment.ID, runningTaskStatus),
+		rolloutTask(rolloutInstance2, "old-dep-id", runningTaskStatus),
+	}
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, tasks)
+	recorded := suite.expectRecordRollout(ctx, environment, currentDeployment)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	stopTasksEvent := (<-events).(StopTasksEvent)
+	assert.Equal(suite.T(), []string{"task-" + rolloutInstance2}, stopTasksEvent.Tasks, "Expected the outdated task to stop")
+	startDeploymentEvent := (<-events).(StartDeploymentEvent)
+	assert.Equal(suite.T(), []*string{aws.String(rolloutInstance2)}, startDeploymentEvent.Instances,
+		"Expected the deployment to start on the instance of the next batch")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+
+	deployment := (<-recorded).Deployments[currentDeployment.ID]
+	assert.Len(suite.T(), deployment.Batches, 2, "Expected the next batch to be recorded")
+	assert.Exactly(suite.T(), types.BatchCompleted, deployment.Batches[0].Status, "Expected the first batch to be completed")
+	assert.Equal(suite.T(), []string{rolloutInstance2}, deployment.Batches[1].Instances,
+		"Expected the next batch to hold the remaining outdated instance")
+}
+
+func (suite *SchedulerTestSuite) TestRolloutKeepsMinHealthyInstances() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{BatchSize: 3, MinHealthyPercent: 50})
+	tasks := []*models.Task{
+		rolloutTask(rolloutInstance1, "old-dep-id", runningTaskStatus),
+		rolloutTask(rolloutInstance2, "old-dep-id", runningTaskStatus),
+		rolloutTask(rolloutInstance3, "old-dep-id", runningTaskStatus),
+	}
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, tasks)
+	recorded := suite.expectRecordRollout(ctx, environment, currentDeployment)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	_ = (<-events).(StopTasksEvent)
+	startDeploymentEvent := (<-events).(StartDeploymentEvent)
+	assert.Equal(suite.T(), []*string{aws.String(rolloutInstance1)}, startDeploymentEvent.Instances,
+		"Expected a single instance to be updated to keep half of the instances healthy")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+
+	deployment := (<-recorded).Deployments[currentDeployment.ID]
+	assert.Equal(suite.T(), []string{rolloutInstance1}, deployment.Batches[0].Instances,
+		"Expected the batch to be limited by the min healthy percent")
+}
+
+func (suite *SchedulerTestSuite) TestRolloutCompleted() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{BatchSize: 1})
+	currentDeployment.Batches = []types.DeploymentBatch{{
+		Instances: []string{rolloutInstance1},
+		Status:    types.BatchSoaking,
+		StartTime: time.Now().Add(-time.Minute),
+		// soaking without a soak time only lasts until the next run
+		HealthySince: time.Now().Add(-time.Second),
+	}}
+	tasks := []*models.Task{
+		rolloutTask(rolloutInstance1, currentDeployment.ID, runningTaskStatus),
+		rolloutTask(rolloutInstance2, currentDeployment.ID, runningTaskStatus),
+	}
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, tasks)
+	recorded := suite.expectRecordRollout(ctx, environment, currentDeployment)
+
+	events := suite.startRolloutScheduler(ctx)
+	_ = (<-events).(SchedulerEnvironmentEvent)
+
+	deployment := (<-recorded).Deployments[currentDeployment.ID]
+	assert.Exactly(suite.T(), types.BatchCompleted, deployment.Batches[0].Status, "Expected the batch to be completed")
+	assert.True(suite.T(), deployment.RolloutCompleted, "Expected the rollout to be completed")
+}
+
+func (suite *SchedulerTestSuite) TestPausedDeploymentKeepsInstances() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{})
+	currentDeployment.Status = types.DeploymentPaused
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment.Name).Return(&currentDeployment, nil)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	_, ok := (<-events).(SchedulerEnvironmentEvent)
+	assert.True(suite.T(), ok, "Expected no task to be started or stopped while the deployment is paused")
+}
+
+func (suite *SchedulerTestSuite) TestCancelledDeploymentFallsBackToCompletedDeployment() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{})
+	currentDeployment.Status = types.DeploymentCompleted
+	cancelled := types.Deployment{ID: "cancelled-dep-id", Status: types.DeploymentCancelled}
+	environment.Deployments = map[string]types.Deployment{
+		currentDeployment.ID: currentDeployment,
+		cancelled.ID:         cancelled,
+	}
+	environment.PendingDeploymentID = cancelled.ID
+
+	// the deployment service falls back to the latest completed deployment after a cancel
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment.Name).Return(&currentDeployment, nil)
+	suite.css.EXPECT().ListInstances(environment.Cluster).Return([]*models.ContainerInstance{
+		containerInstance(environment.Cluster, rolloutInstance1, "ACTIVE"),
+		containerInstance(environment.Cluster, rolloutInstance2, "ACTIVE"),
+		containerInstance(environment.Cluster, rolloutInstance3, "ACTIVE"),
+	}, nil)
+	suite.css.EXPECT().ListTasks(environment.Cluster).Return([]*models.Task{
+		rolloutTask(rolloutInstance1, cancelled.ID, runningTaskStatus),
+		rolloutTask(rolloutInstance2, "old-dep-id", runningTaskStatus),
+	}, nil)
+	oldDeployment := types.Deployment{ID: "old-dep-id", Status: types.DeploymentCompleted}
+	suite.deploymentSvc.EXPECT().ListDeploymentsSortedReverseChronologically(ctx, environment.Name).
+		Return([]types.Deployment{cancelled, currentDeployment, oldDeployment}, nil)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	startDeploymentEvent := (<-events).(StartDeploymentEvent)
+	assert.Equal(suite.T(), []*string{aws.String(rolloutInstance3)}, startDeploymentEvent.Instances,
+		"Expected a new instance to get the latest completed deployment")
+	stopTasksEvent := (<-events).(StopTasksEvent)
+	assert.Equal(suite.T(), []string{"task-" + rolloutInstance2}, stopTasksEvent.Tasks,
+		"Expected only the outdated task to stop, not the one of the cancelled deployment")
+	startDeploymentEvent = (<-events).(StartDeploymentEvent)
+	assert.Equal(suite.T(), []*string{aws.String(rolloutInstance2)}, startDeploymentEvent.Instances,
+		"Expected the outdated instance to get the latest completed deployment")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+}
+
+func (suite *SchedulerTestSuite) expectRolloutLookup(ctx context.Context, environment types.Environment,
+	currentDeployment types.Deployment, tasks []*models.Task) {
+
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment.Name).Return(&currentDeployment, nil)
+
+	instances := make([]*models.ContainerInstance, 0, len(tasks))
+	for _, task := range tasks {
+		instances = append(instances, &models.ContainerInstance{
+			ClusterARN:           aws.String(environment.Cluster),
+			ContainerInstanceARN: task.ContainerInstanceARN,
+			Status:               aws.String("ACTIVE"),
+		})
+	}
+	suite.css.EXPECT().ListInstances(environment.Cluster).Return(instances, nil)
+	suite.css.EXPECT().ListTasks(environment.Cluster).Return(tasks, nil)
+
+	oldDeployment := types.Deployment{
+		ID:     "old-dep-id",
+		Status: types.DeploymentCompleted,
+	}
+	deployments := []types.Deployment{currentDeployment, oldDeployment}
+	suite.deploymentSvc.EXPECT().ListDeploymentsSortedReverseChronologically(ctx, environment.Name).Return(deployments, nil)
+}
+
+// expectRecordRollout expects the rollout to be recorded once and sends the updated environment
+func (suite *SchedulerTestSuite) expectRecordRollout(ctx context.Context, environment types.Environment,
+	currentDeployment types.Deployment) <-chan *types.Environment {
+
+	recorded := make(chan *types.Environment, 1)
+	latest := environment
+	latest.Deployments = map[string]types.Deployment{currentDeployment.ID: currentDeployment}
+	suite.environmentSvc.EXPECT().UpdateEnvironment(ctx, environment.Name, gomock.Any()).Do(
+		func(_ interface{}, _ interface{}, update func(*types.Environment) error) {
+			assert.Nil(suite.T(), update(&latest), "Unexpected error recording the rollout")
+			recorded <- &latest
+		}).Return(&latest, nil)
+	return recorded
+}
+
+func (suite *SchedulerTestSuite) startRolloutScheduler(ctx context.Context) <-chan Event {
+	events := make(chan Event)
+	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	scheduler.Start()
+	return events
+}
+
+func rolloutEnvironment(strategy types.RolloutStrategy) (types.Environment, types.Deployment) {
+	environment := types.Environment{
+		Name:            "TestRollout",
+		Cluster:         "testCluster",
+		RolloutStrategy: strategy,
+	}
+	currentDeployment := types.Deployment{
+		ID:     "dep-id",
+		Status: types.DeploymentInProgress,
+	}
+	return environment, currentDeployment
+}
+
+func rolloutTask(instanceARN string, deploymentID string, lastStatus string) *models.Task {
+	return &models.Task{
+		ClusterARN:           aws.String("testCluster"),
+		ContainerInstanceARN: aws.String(instanceARN),
+		TaskARN:              aws.String("task-" + instanceARN),
+		StartedBy:            deploymentID,
+		DesiredStatus:        aws.String(runningTaskStatus),
+		LastStatus:           aws.String(lastStatus),
+	}
+}
@@ -16,6 +16,7 @@ package engine
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,10 +31,15 @@ import (
 )
 
 const (
-	schedulerTickerDuration = 10 * time.Second
+	// SchedulerTickerDuration is the default interval between full scheduler runs. In between,
+	// environments are scheduled as soon as the cluster state changes.
+	SchedulerTickerDuration = 5 * time.Minute
 	inactiveInstanceStatus  = "INACTIVE"
+	drainingInstanceStatus  = "DRAINING"
 	runningTaskStatus       = "RUNNING"
-	trackingInfoTTL         = 1 * time.Minute
+	stoppedTaskStatus       = "STOPPED"
+	// TrackingInfoTTL is the default time to wait for a started task to show up in the cluster state before starting it again
+	TrackingInfoTTL = 1 * time.Minute
 )
 
 type scheduler struct {
@@ -44,15 +50,34 @@ type scheduler struct {
 	css            facade.ClusterState
 	ecs            facade.ECS
 	events         chan<- Event
-	executionState map[string]environmentExecutionState
 	inProgress     bool
 	inProgressLock sync.RWMutex
+	running        sync.WaitGroup
+
+	executionStateLock sync.Mutex
+	executionState     map[string]*environmentExecutionState
+
+	settingsLock    sync.RWMutex
+	ticker          *time.Ticker
+	tickerDuration  time.Duration
+	trackingInfoTTL time.Duration
+	clusters        map[string]bool
 }
 
 type environmentExecutionState struct {
-	environment    types.Environment
-	trackingInfo   map[string]time.Time
-	inProgress     bool
+	name string
+	// environment is the version of the environment the run in progress uses. It only changes
+	// when a run starts, so only the run in progress reads it.
+	environment  types.Environment
+	trackingInfo map[string]time.Time
+	// latest is the latest version of the environment the scheduler has seen, which the next
+	// run picks up
+	latest     types.Environment
+	inProgress bool
+	// rerun is set when the environment should be scheduled again once the run in progress ends
+	rerun bool
+	// recheckPending is set while the environment is due to be scheduled again during a rollout
+	recheckPending bool
 	inProgressLock sync.RWMutex
 }
 
@@ -60,6 +85,34 @@ type instanceLookupResult struct {
 	totalInstanceCount int
 	newInstances       []*string
 	deployedInstances  map[string][]*deployedTask
+	// stoppedTasks are the stopped tasks of the environment on each eligible instance
+	stoppedTasks map[string][]*models.Task
+	// heldInstances are the instances the task of the environment is not restarted on yet, or no
+	// longer, because its tasks keep crashing there
+	heldInstances map[string]bool
+	// ineligibleInstances are the instances of the cluster the environment is not deployed to,
+	// with the reason
+	ineligibleInstances map[string]string
+	// drainingInstances are the draining instances of the cluster with the tasks meant to be
+	// running on them
+	drainingInstances map[string]*drainingInstance
+	// disconnectedInstances are the instances of the cluster whose agent is disconnected
+	disconnectedInstances []string
+	// unavailableInstances are the instances the current deployment found short of capacity
+	unavailableInstances map[string]bool
+	// excessInstances are the instances running tasks of the environment that its strategy no
+	// longer places it on, with the tasks
+	excessInstances map[string][]*deployedTask
+	// missingReplicas are the replicas of a replica environment that could not be placed as the
+	// cluster has too few eligible instances
+	missingReplicas int
+}
+
+type drainingInstance struct {
+	// tasks are the tasks of the environment
+	tasks []string
+	// otherTasks are the tasks of other environments and the tasks not started by an environment
+	otherTasks []*models.Task
 }
 
 type deployedTask struct {
@@ -67,28 +120,38 @@ type deployedTask struct {
 	instanceARN             string
 	deploymentID            string
 	availableInClusterState bool
+	running                 bool
+	startedAt               string
 }
 
 // NewScheduler creates a scheduler instance with clean execution state. There should be only one instance of this running on a host.
 func NewScheduler(ctx context.Context, events chan<- Event, environmentSvc deployment.Environment,
 	deploymentSvc deployment.Deployment, css facade.ClusterState, ecs facade.ECS) *scheduler {
 	return &scheduler{
-		id:             uuid.NewRandom().String(),
-		ctx:            ctx,
-		environmentSvc: environmentSvc,
-		deploymentSvc:  deploymentSvc,
-		css:            css,
-		ecs:            ecs,
-		events:         events,
-		executionState: make(map[string]environmentExecutionState),
-		inProgress:     false,
+		id:              uuid.NewRandom().String(),
+		ctx:             ctx,
+		environmentSvc:  environmentSvc,
+		deploymentSvc:   deploymentSvc,
+		css:             css,
+		ecs:             ecs,
+		events:          events,
+		executionState:  make(map[string]*environmentExecutionState),
+		inProgress:      false,
+		tickerDuration:  SchedulerTickerDuration,
+		trackingInfoTTL: TrackingInfoTTL,
 	}
 }
 
 // Start makes scheduler loop through all the environments and makes sure they reach their eventual state.
 func (s *scheduler) Start() {
-	ticker := time.NewTicker(schedulerTickerDuration)
+	s.settingsLock.Lock()
+	s.ticker = time.NewTicker(s.tickerDuration)
+	ticker := s.ticker
+	s.settingsLock.Unlock()
+
+	s.running.Add(1)
 	go func(s *scheduler) {
+		defer s.running.Done()
 		s.runOnce()
 		for {
 			select {
@@ -103,23 +166,136 @@ func (s *scheduler) Start() {
 	}(s)
 }
 
+// Watch schedules the environments affected by changes to the cluster state until the
+// scheduler is shut down. Changes are expected to come from facade.ClusterStateCache.Watch.
+func (s *scheduler) Watch(changes <-chan facade.ClusterStateChange) {
+	s.running.Add(1)
+	go func(s *scheduler) {
+		defer s.running.Done()
+		for {
+			select {
+			case change := <-changes:
+				s.runForChange(change)
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}(s)
+}
+
+// Wait blocks until the scheduler loop has stopped and any iteration in progress has finished.
+func (s *scheduler) Wait() {
+	s.running.Wait()
+}
+
+// SetTickerDuration changes the interval between scheduler runs. It can be called while the
+// scheduler is running and takes effect from the next tick.
+func (s *scheduler) SetTickerDuration(tickerDuration time.Duration) error {
+	if tickerDuration <= 0 {
+		return errors.Errorf("Invalid scheduler interval %s", tickerDuration)
+	}
+
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+
+	s.tickerDuration = tickerDuration
+	if s.ticker != nil {
+		s.ticker.Reset(tickerDuration)
+	}
+	return nil
+}
+
+// SetTrackingInfoTTL changes how long the scheduler waits for a started task to show up in the
+// cluster state before starting it again.
+func (s *scheduler) SetTrackingInfoTTL(ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.Errorf("Invalid tracking info ttl %s", ttl)
+	}
+
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+
+	s.trackingInfoTTL = ttl
+	return nil
+}
+
+// SetClusters limits the scheduler to environments in the given clusters, named either by
+// name or ARN. All environments are scheduled when clusters is empty.
+func (s *scheduler) SetClusters(clusters []string) {
+	scope := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		scope[clusterShortName(cluster)] = true
+	}
+
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+
+	s.clusters = scope
+}
+
+func (s *scheduler) getTrackingInfoTTL() time.Duration {
+	s.settingsLock.RLock()
+	defer s.settingsLock.RUnlock()
+
+	return s.trackingInfoTTL
+}
+
+func (s *scheduler) isInScope(cluster string) bool {
+	s.settingsLock.RLock()
+	defer s.settingsLock.RUnlock()
+
+	return len(s.clusters) == 0 || s.clusters[clusterShortName(cluster)]
+}
+
+// isTargetInScope returns whether the cluster of the environment is in the scheduler's scope. An
+// environment with a cluster selector being deleted is in scope if any of its clusters is.
+func (s *scheduler) isTargetInScope(environment types.Environment) bool {
+	if environment.Cluster != "" {
+		return s.isInScope(environment.Cluster)
+	}
+
+	clusters := environment.DeployedClusters()
+	for _, cluster := range clusters {
+		if s.isInScope(cluster) {
+			return true
+		}
+	}
+	return len(clusters) == 0
+}
+
+// isTargetInCluster returns whether the environment is scheduled in the cluster. Each target of an
+// environment with a cluster selector is in its own cluster only, unless it is being deleted.
+func isTargetInCluster(environment types.Environment, cluster string) bool {
+	if environment.Cluster == "" {
+		return environment.TargetsCluster(cluster) || environment.DeployedTo(cluster)
+	}
+	return clusterShortName(environment.Cluster) == clusterShortName(cluster)
+}
+
+// clusterShortName returns the name of the cluster from either its name or its ARN
+func clusterShortName(cluster string) string {
+	return cluster[strings.LastIndex(cluster, "/")+1:]
+}
+
 func (s *scheduler) runOnce() {
 	if s.isInProgress() {
 		msg := fmt.Sprintf("[s:%s] Another instance of scheduler is already in progress, skipping", s.id)
 		log.Info(msg)
-		s.events <- SchedulerErrorEvent{
-			Error: errors.Errorf(msg),
-		}
+		sendEvent(s.ctx.Done(), s.events, SchedulerErrorEvent{
+			Error: errors.New(msg),
+		})
 		return
 	}
 
+	s.running.Add(1)
 	go func(s *scheduler) {
+		defer s.running.Done()
 		err := s.runOnceInternal()
 		if err != nil {
 			log.Errorf("[s:%s] Error running scheduler : %v", s.id, err)
-			s.events <- SchedulerErrorEvent{
+			sendEvent(s.ctx.Done(), s.events, SchedulerErrorEvent{
 				Error: err,
-			}
+			})
 		}
 	}(s)
 }
@@ -131,43 +307,226 @@ func (s *scheduler) runOnceInternal() error {
 
 	environments, err := s.environmentSvc.ListEnvironments(s.ctx)
 	if err != nil {
-		return errors.Wrapf(err, "Error getting environments", s.id)
+		return errors.Wrapf(err, "[s:%s] Error getting environments", s.id)
+	}
+
+	clusters, err := s.listClusters(environments, "")
+	if err != nil {
+		return err
 	}
 
 	for _, environment := range environments {
-		_, ok := s.executionState[environment.Name]
-		if !ok {
-			s.executionState[environment.Name] = environmentExecutionState{
-				environment:  environment,
-				trackingInfo: make(map[string]time.Time),
-				inProgress:   false,
+		targets, err := clusterTargets(environment, clusters, s.ecs)
+		if err != nil {
+			log.Errorf("[s:%s, e:%s] Error selecting the clusters of the environment: %v", s.id, environment.Name, err)
+			sendEvent(s.ctx.Done(), s.events, SchedulerErrorEvent{
+				Error: err,
+			})
+			continue
+		}
+
+		for _, target := range targets {
+			if !s.isTargetInScope(target) {
+				log.Debugf("[s:%s, e:%s] Skipping environment in cluster %s outside of the scheduler's scope",
+					s.id, target.Name, target.Cluster)
+				continue
 			}
+
+			s.scheduleEnvironment(target)
+		}
+	}
+
+	return nil
+}
+
+// listClusters returns the clusters in the cluster state if any of the environments has a
+// cluster selector that may pick clusters named name, or any cluster if name is empty
+func (s *scheduler) listClusters(environments []types.Environment, name string) ([]string, error) {
+	for _, environment := range environments {
+		selector := environment.ClusterSelector
+		if !environment.HasClusterSelector() || (name != "" && !selector.Matches(name) && !selector.HasTags()) {
+			continue
+		}
+
+		clusters, err := s.css.ListClusters()
+		if err != nil {
+			return nil, errors.Wrapf(err, "[s:%s] Error getting clusters to select", s.id)
 		}
+		return clusters, nil
+	}
+	return nil, nil
+}
 
-		state := s.executionState[environment.Name]
+// clusterTargets returns a copy of the environment for each of the clusters it is deployed to,
+// with Cluster set to that cluster, so that each of them is scheduled on its own. Environments
+// being deleted are returned as they are, as they are drained across all their clusters at once.
+// The tags of clusters not selected by name are described when the selector picks clusters by tag.
+func clusterTargets(environment types.Environment, clusters []string, ecs facade.ECS) ([]types.Environment, error) {
+	if !environment.HasClusterSelector() || environment.IsDeleting() {
+		return []types.Environment{environment}, nil
+	}
 
-		// processing for environments is independent, so we can do them concurrently
-		go func(s *scheduler, state environmentExecutionState) {
-			err := s.runForEnvironment(&state)
+	targets := make([]types.Environment, 0)
+	for _, cluster := range clusters {
+		selected := environment.ClusterSelector.Matches(cluster)
+		if !selected && environment.ClusterSelector.HasTags() {
+			tags, err := ecs.DescribeClusterTags(cluster)
 			if err != nil {
-				// TODO: we may want to report this for better ux
-				log.Errorf("[s:%s, e:%s] Error running this iteration of Scheduler for environment : %v", s.id, state.environment.Name, err)
-				s.events <- SchedulerErrorEvent{
-					Error:       errors.Wrapf(err, "Error running scheduler for environment %s", state.environment.Name),
-					Environment: state.environment,
-				}
-				return
+				return nil, errors.Wrapf(err, "Error getting the tags of cluster %s", cluster)
 			}
-			msg := fmt.Sprintf("[s:%s, e:%s] Done running this iteration of scheduler for environment", s.id, state.environment.Name)
-			log.Debug(msg)
-			s.events <- SchedulerEnvironmentEvent{
-				Message:     msg,
-				Environment: state.environment,
+			selected = environment.ClusterSelector.MatchesTags(tags)
+		}
+
+		if selected {
+			target := environment
+			target.Cluster = cluster
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// runForChange schedules the environments in the cluster that changed. When a task stopped,
+// only the environment that started it is scheduled.
+func (s *scheduler) runForChange(change facade.ClusterStateChange) {
+	if change.Cluster == "" {
+		log.Infof("[s:%s] Cluster state changes may have been missed, scheduling all environments", s.id)
+		s.runOnce()
+		return
+	}
+
+	if !s.isInScope(change.Cluster) {
+		return
+	}
+
+	environments, err := s.environmentSvc.ListEnvironments(s.ctx)
+	if err != nil {
+		log.Errorf("[s:%s] Error getting environments to schedule after a change in cluster %s: %v", s.id, change.Cluster, err)
+		sendEvent(s.ctx.Done(), s.events, SchedulerErrorEvent{
+			Error: errors.Wrapf(err, "[s:%s] Error getting environments", s.id),
+		})
+		return
+	}
+
+	clusters, err := s.listClusters(environments, change.Cluster)
+	if err != nil {
+		log.Errorf("[s:%s] Error getting clusters to schedule after a change in cluster %s: %v", s.id, change.Cluster, err)
+		sendEvent(s.ctx.Done(), s.events, SchedulerErrorEvent{
+			Error: err,
+		})
+		return
+	}
+
+	// only the cluster that changed is selected from, so that no other cluster's tags are described
+	changed := make([]string, 0, 1)
+	for _, cluster := range clusters {
+		if clusterShortName(cluster) == clusterShortName(change.Cluster) {
+			changed = append(changed, cluster)
+		}
+	}
+
+	for _, environment := range environments {
+		if _, ok := environment.Deployments[change.StartedBy]; change.StartedBy != "" && !ok {
+			continue
+		}
+
+		targets, err := clusterTargets(environment, changed, s.ecs)
+		if err != nil {
+			log.Errorf("[s:%s, e:%s] Error selecting the clusters of the environment after a change in cluster %s: %v",
+				s.id, environment.Name, change.Cluster, err)
+			sendEvent(s.ctx.Done(), s.events, SchedulerErrorEvent{
+				Error: err,
+			})
+			continue
+		}
+
+		for _, target := range targets {
+			if !isTargetInCluster(target, change.Cluster) {
+				continue
 			}
-		}(s, state)
+
+			log.Debugf("[s:%s, e:%s] Scheduling environment after a change in cluster %s", s.id, target.Name, change.Cluster)
+			s.scheduleEnvironment(target)
+		}
 	}
+}
 
-	return nil
+// scheduleEnvironment runs the scheduler for environment in the background
+func (s *scheduler) scheduleEnvironment(environment types.Environment) {
+	state := s.getExecutionState(environment)
+
+	// processing for environments is independent, so we can do them concurrently
+	s.running.Add(1)
+	go func(s *scheduler, state *environmentExecutionState) {
+		defer s.running.Done()
+		err := s.runForEnvironment(state)
+		if err != nil {
+			// TODO: we may want to report this for better ux
+			log.Errorf("[s:%s, e:%s] Error running this iteration of Scheduler for environment : %v", s.id, environment.Name, err)
+			sendEvent(s.ctx.Done(), s.events, SchedulerErrorEvent{
+				Error:       errors.Wrapf(err, "Error running scheduler for environment %s", environment.Name),
+				Environment: environment,
+			})
+			return
+		}
+		msg := fmt.Sprintf("[s:%s, e:%s] Done running this iteration of scheduler for environment", s.id, environment.Name)
+		log.Debug(msg)
+		sendEvent(s.ctx.Done(), s.events, SchedulerEnvironmentEvent{
+			Message:     msg,
+			Environment: environment,
+		})
+	}(s, state)
+}
+
+func (s *scheduler) getExecutionState(environment types.Environment) *environmentExecutionState {
+	s.executionStateLock.Lock()
+	defer s.executionStateLock.Unlock()
+
+	key := executionStateKey(environment)
+	state, ok := s.executionState[key]
+	if !ok {
+		state = &environmentExecutionState{
+			name:         environment.Name,
+			environment:  environment,
+			trackingInfo: make(map[string]time.Time),
+			latest:       environment,
+			inProgress:   false,
+		}
+		s.executionState[key] = state
+		return state
+	}
+
+	state.setLatest(environment)
+	return state
+}
+
+// executionStateKey identifies the execution state of the environment, which is kept for each
+// cluster of an environment with a cluster selector
+func executionStateKey(environment types.Environment) string {
+	if !environment.HasClusterSelector() || environment.IsDeleting() {
+		return environment.Name
+	}
+	return environment.Name + "/" + environment.Cluster
+}
+
+// setLatest records environment as the version the next run uses, unless a later version has
+// already been seen
+func (state *environmentExecutionState) setLatest(environment types.Environment) {
+	state.inProgressLock.Lock()
+	defer state.inProgressLock.Unlock()
+
+	if environment.ModRevision >= state.latest.ModRevision {
+		state.latest = environment
+	}
+}
+
+// refresh moves the run about to start to the latest version of the environment. The
+// instances tracked in the previous cluster are forgotten when the cluster changed.
+func (state *environmentExecutionState) refresh() {
+	if state.latest.Cluster != state.environment.Cluster {
+		state.trackingInfo = make(map[string]time.Time)
+	}
+	state.environment = state.latest
 }
 
 func (s *scheduler) setInProgress(val bool) {
@@ -182,41 +541,84 @@ func (s *scheduler) isInProgress() bool {
 	return s.inProgress
 }
 
-func (state *environmentExecutionState) setInProgress(val bool) {
-	lock := state.inProgressLock
-	lock.Lock()
-	defer lock.Unlock()
-	state.inProgress = val
+// start marks the environment as in progress. If it already is, it asks for the run in
+// progress to be repeated and returns false.
+func (state *environmentExecutionState) start() bool {
+	state.inProgressLock.Lock()
+	defer state.inProgressLock.Unlock()
+
+	if state.inProgress {
+		state.rerun = true
+		return false
+	}
+	state.inProgress = true
+	state.refresh()
+	return true
 }
 
-func (state *environmentExecutionState) isInProgress() bool {
-	lock := state.inProgressLock
-	lock.RLock()
-	defer lock.RUnlock()
-	return state.inProgress
+// finish ends the run in progress unless it succeeded and should be repeated, in which case
+// it returns true
+func (state *environmentExecutionState) finish(err error) bool {
+	state.inProgressLock.Lock()
+	defer state.inProgressLock.Unlock()
+
+	rerun := state.rerun && err == nil
+	state.rerun = false
+	state.inProgress = rerun
+	if rerun {
+		state.refresh()
+	}
+	return rerun
 }
 
 func (s *scheduler) runForEnvironment(state *environmentExecutionState) error {
-	environment := state.environment
-	if state.isInProgress() {
-		log.Debugf("[s:%s, e:%s] Execution for environment is already in progress", s.id, environment.Name)
+	if !state.start() {
+		log.Debugf("[s:%s, e:%s] Execution for environment is already in progress, it will run again once done",
+			s.id, state.name)
 		return nil
 	}
-	state.setInProgress(true)
-	defer state.setInProgress(false)
 
+	for {
+		err := s.runForEnvironmentOnce(state)
+		if !state.finish(err) {
+			return err
+		}
+		log.Debugf("[s:%s, e:%s] Running the scheduler again for environment after a change", s.id, state.environment.Name)
+	}
+}
+
+func (s *scheduler) runForEnvironmentOnce(state *environmentExecutionState) error {
+	environment := state.environment
 	log.Debugf("[s:%s, e:%s] Number of instances tracked under environment is %d", s.id, environment.Name, len(state.trackingInfo))
 
+	if environment.IsDeleting() {
+		return s.drainEnvironment(state)
+	}
+
 	currentDeployment, err := s.getCurrentDeployment(&environment)
 	if err != nil {
 		return err
 	}
 
+	// the instances keep the current deployment while the latest one waits to start
+	if latest := environment.LatestDeployment(); latest != nil && latest.Status == types.DeploymentPending &&
+		!environment.CanStartDeployment(*latest, time.Now()) {
+		log.Debugf("[s:%s, e:%s] Deployment %s is waiting for its start time or a maintenance window",
+			s.id, environment.Name, latest.ID)
+		s.recheckLater(state, MaintenanceCheckInterval)
+	}
+
 	if currentDeployment == nil {
 		log.Debugf("No deployment available for environment %s", environment.Name)
 		return nil
 	}
 
+	if currentDeployment.Status == types.DeploymentPaused {
+		log.Debugf("[s:%s, e:%s] Deployment %s is paused, keeping the instances as they are",
+			s.id, environment.Name, currentDeployment.ID)
+		return nil
+	}
+
 	lookupResult, err := s.lookupInstances(state)
 	if err != nil {
 		return errors.Wrapf(err, "Error finding instances to deploy for environment")
@@ -225,8 +627,31 @@ func (s *scheduler) runForEnvironment(state *environmentExecutionState) error {
 	log.Debugf("[s:%s, e:%s] Instance lookup result: new=%d, deployed=%d, total=%d",
 		s.id, environment.Name, len(lookupResult.newInstances), len(lookupResult.deployedInstances), lookupResult.totalInstanceCount)
 
+	err = s.recordDisconnectedInstances(state, lookupResult)
+	if err != nil {
+		return errors.Wrapf(err, "Error recording the instances of environment with a disconnected agent")
+	}
+
+	err = s.recordMissingReplicas(state, lookupResult)
+	if err != nil {
+		return errors.Wrapf(err, "Error recording the missing replicas of environment")
+	}
+
+	err = s.stopTasksOnDrainingInstances(state, lookupResult)
+	if err != nil {
+		return errors.Wrapf(err, "Error stopping the tasks of environment on draining instances")
+	}
+
+	s.stopExcessTasks(state, lookupResult)
+
+	err = s.checkRestarts(state, currentDeployment, lookupResult)
+	if err != nil {
+		return errors.Wrapf(err, "Error checking the restarts of tasks of environment")
+	}
+
 	s.deployToNewInstances(state, lookupResult)
 
+	keepCancelledDeployment(&environment, lookupResult)
 	err = s.updateDeployedInstances(state, currentDeployment, lookupResult)
 	if err != nil {
 		return errors.Wrapf(err, "Error updating deployed instances for environment")
@@ -247,8 +672,35 @@ func (s *scheduler) getCurrentDeployment(environment *types.Environment) (*types
 // updateDeployedInstances performs deployment on instances which already have some version of environment deployed
 func (s *scheduler) updateDeployedInstances(state *environmentExecutionState, currentDeployment *types.Deployment, result *instanceLookupResult) error {
 	environment := state.environment
+
+	// instances running earlier deployments only may have to wait for their turn in the rollout
+	outdated := outdatedInstances(currentDeployment, result)
+	var updatable []string
+	if len(outdated) > 0 && !environment.InMaintenanceWindow(time.Now()) {
+		// the rollout pauses until a maintenance window opens again
+		log.Infof("[s:%s, e:%s] Not updating %d outdated instances outside of the maintenance windows",
+			s.id, environment.Name, len(outdated))
+		s.recheckLater(state, MaintenanceCheckInterval)
+	} else {
+		var err error
+		updatable, err = s.rolloutInstances(state, currentDeployment, result, outdated)
+		if err != nil {
+			return err
+		}
+	}
+	waiting := make(map[string]bool, len(outdated))
+	for _, instanceARN := range outdated {
+		waiting[instanceARN] = true
+	}
+	for _, instanceARN := range updatable {
+		delete(waiting, instanceARN)
+	}
+
 	// go through already deployed instances and select the tasks which need to be replaced
 	for instanceARN, deployedTasks := range result.deployedInstances {
+		if waiting[instanceARN] {
+			continue
+		}
 		shouldDeploy := true
 		tasksToStop := make([]string, 0)
 		for _, dt := range deployedTasks {
@@ -271,7 +723,7 @@ func (s *scheduler) updateDeployedInstances(state *environmentExecutionState, cu
 					//if deployment happened a while ago and
 					//we haven't heard from cluster-state we
 					//ask ECS if the deployment succeeded
-					if time.Now().UTC().Sub(deployedAt) > trackingInfoTTL {
+					if time.Now().UTC().Sub(deployedAt) > s.getTrackingInfoTTL() {
 						deployed, err := s.isDeployedToInstance(state, currentDeployment, instanceARN)
 						if err != nil {
 							return err
@@ -287,21 +739,28 @@ func (s *scheduler) updateDeployedInstances(state *environmentExecutionState, cu
 		// order is to stop existing task(s) and start new one
 		if len(tasksToStop) > 0 {
 			log.Debugf("[s:%s, e:%s] Sending StopTasksEvent with %d tasks", s.id, environment.Name, len(tasksToStop))
-			s.events <- StopTasksEvent{
+			sendEvent(s.ctx.Done(), s.events, StopTasksEvent{
 				Cluster:     environment.Cluster,
 				Tasks:       tasksToStop,
 				Environment: environment,
-			}
+				Reason:      types.StopReasonReplaced,
+			})
+		}
+
+		if shouldDeploy && result.heldInstances[instanceARN] {
+			log.Debugf("[s:%s, e:%s] Not restarting the task of deployment %s on instance %s yet",
+				s.id, environment.Name, currentDeployment.ID, instanceARN)
+			shouldDeploy = false
 		}
 
 		if shouldDeploy {
 			log.Debugf("[s:%s, e:%s] Sending StartDeploymentEvent for deployment %s to instance %s",
 				s.id, environment.Name, currentDeployment.ID, instanceARN)
 			state.trackingInfo[instanceARN] = time.Now().UTC()
-			s.events <- StartDeploymentEvent{
+			sendEvent(s.ctx.Done(), s.events, StartDeploymentEvent{
 				Environment: environment,
 				Instances:   []*string{aws.String(instanceARN)},
-			}
+			})
 		}
 	}
 
@@ -330,6 +789,25 @@ func (s *scheduler) isDeployedToInstance(state *environmentExecutionState, curre
 	return false, nil
 }
 
+// keepCancelledDeployment leaves the instances running tasks of a cancelled latest deployment as
+// they are until the next deployment. The other instances are deployed to the current deployment,
+// which is then the latest completed deployment.
+func keepCancelledDeployment(environment *types.Environment, result *instanceLookupResult) {
+	latest := environment.LatestDeployment()
+	if latest == nil || latest.Status != types.DeploymentCancelled {
+		return
+	}
+
+	for instanceARN, deployedTasks := range result.deployedInstances {
+		for _, dt := range deployedTasks {
+			if dt.availableInClusterState && dt.deploymentID == latest.ID {
+				delete(result.deployedInstances, instanceARN)
+				break
+			}
+		}
+	}
+}
+
 // deployToNewInstances performs deployment on instances which never got any deployment for the given environment
 func (s *scheduler) deployToNewInstances(state *environmentExecutionState, result *instanceLookupResult) {
 	if len(result.newInstances) > 0 {
@@ -340,7 +818,9 @@ func (s *scheduler) deployToNewInstances(state *environmentExecutionState, resul
 			Environment: state.environment,
 			Instances:   result.newInstances,
 		}
-		s.events <- event
+		if !sendEvent(s.ctx.Done(), s.events, event) {
+			return
+		}
 		log.Infof("Sent event to start tasks on %d instances in environment %s", len(result.newInstances), state.environment)
 	}
 }
@@ -361,9 +841,16 @@ func (s *scheduler) lookupInstances(state *environmentExecutionState) (*instance
 	}
 
 	result := &instanceLookupResult{
-		totalInstanceCount: 0,
-		newInstances:       make([]*string, 0),
-		deployedInstances:  make(map[string][]*deployedTask),
+		totalInstanceCount:    0,
+		newInstances:          make([]*string, 0),
+		deployedInstances:     make(map[string][]*deployedTask),
+		stoppedTasks:          make(map[string][]*models.Task),
+		heldInstances:         make(map[string]bool),
+		ineligibleInstances:   make(map[string]string),
+		drainingInstances:     make(map[string]*drainingInstance),
+		disconnectedInstances: make([]string, 0),
+		unavailableInstances:  make(map[string]bool),
+		excessInstances:       make(map[string][]*deployedTask),
 	}
 
 	result, err = s.loadInstancesAlreadyDeployed(state, instanceARNToInstance, result)
@@ -371,22 +858,79 @@ func (s *scheduler) lookupInstances(state *environmentExecutionState) (*instance
 		return nil, errors.Wrapf(err, "Error finding instances where environment is already deployed")
 	}
 
-	// collect all the instances which do not have this environment installed
+	eligible := make(map[string]*models.ContainerInstance, len(instances))
 	for _, i := range instances {
 		instanceARN := aws.StringValue(i.ContainerInstanceARN)
-		if aws.StringValue(i.Status) == inactiveInstanceStatus {
+		if reason := ineligibleReason(environment, i); reason != "" {
 			delete(result.deployedInstances, instanceARN)
+			result.ineligibleInstances[instanceARN] = reason
+			if reason == types.AgentDisconnectedReason {
+				result.disconnectedInstances = append(result.disconnectedInstances, instanceARN)
+			}
+			continue
+		}
+		eligible[instanceARN] = i
+	}
+
+	// collect all the instances the strategy places the environment on which do not have it installed
+	placed := placeTasks(environment, eligible, result)
+	result.missingReplicas = missingReplicas(environment, placed)
+	for _, i := range instances {
+		instanceARN := aws.StringValue(i.ContainerInstanceARN)
+		if _, ok := eligible[instanceARN]; !ok {
+			continue
+		}
+		deployedTasks, deployed := result.deployedInstances[instanceARN]
+		if !placed[instanceARN] {
+			if deployed {
+				result.excessInstances[instanceARN] = deployedTasks
+				delete(result.deployedInstances, instanceARN)
+			}
+			result.ineligibleInstances[instanceARN] = types.NotPlacedReason
 			continue
 		}
 		result.totalInstanceCount++
-		_, ok := result.deployedInstances[instanceARN]
-		if !ok {
+		if !deployed {
 			result.newInstances = append(result.newInstances, i.ContainerInstanceARN)
 		}
 	}
 	return result, nil
 }
 
+// isEligible returns whether the environment should be deployed to instance, which has to be
+// active with a connected agent and satisfy the placement constraints of the environment
+func isEligible(environment types.Environment, instance *models.ContainerInstance) bool {
+	return ineligibleReason(environment, instance) == ""
+}
+
+// ineligibleReason returns why the environment should not be deployed to instance, or an empty
+// string if it should
+func ineligibleReason(environment types.Environment, instance *models.ContainerInstance) string {
+	if aws.StringValue(instance.Status) == inactiveInstanceStatus {
+		return types.InactiveInstanceReason
+	}
+	if instance.AgentConnected != nil && !aws.BoolValue(instance.AgentConnected) {
+		return types.AgentDisconnectedReason
+	}
+	if aws.StringValue(instance.Status) == drainingInstanceStatus {
+		return types.DrainingInstanceReason
+	}
+
+	attributes := make(map[string]string, len(instance.Attributes))
+	for _, attribute := range instance.Attributes {
+		attributes[aws.StringValue(attribute.Name)] = aws.StringValue(attribute.Value)
+	}
+	matches := environment.PlacementConstraints.Matches(types.PlacementInstance{
+		ContainerInstanceARN: aws.StringValue(instance.ContainerInstanceARN),
+		EC2InstanceID:        instance.EC2InstanceID,
+		Attributes:           attributes,
+	})
+	if !matches {
+		return types.PlacementConstraintsReason
+	}
+	return ""
+}
+
 // loadInstancesAlreadyDeployed populates instanceLookupResult struct with the state of instances derived from
 // state of environments, deployments and cluster
 func (s *scheduler) loadInstancesAlreadyDeployed(state *environmentExecutionState,
@@ -395,7 +939,7 @@ func (s *scheduler) loadInstancesAlreadyDeployed(state *environmentExecutionStat
 
 	environment := state.environment
 
-	tasks, err := s.getRunningTasks(environment.Cluster)
+	tasks, stoppedTasks, err := s.getTasks(environment.Cluster)
 	if err != nil {
 		return result, err
 	}
@@ -413,16 +957,37 @@ func (s *scheduler) loadInstancesAlreadyDeployed(state *environmentExecutionStat
 
 	}
 
-	// for each task find the deployment it corresponds to and tag the instance of the task as deployed
-	for _, task := range tasks {
-		// ignore if task does not belong to this environment
-		_, ok := deploymentsMap[task.StartedBy]
-		if !ok {
+	// strategies place tasks on the instances the current deployment found short of capacity last
+	for _, d := range deployments {
+		if d.Status == types.DeploymentPending {
 			continue
 		}
+		for _, failure := range d.InsufficientCapacity {
+			result.unavailableInstances[aws.StringValue(failure.Arn)] = true
+		}
+		break
+	}
+
+	// for each task find the deployment it corresponds to and tag the instance of the task as deployed
+	for _, task := range tasks {
+		_, ours := deploymentsMap[task.StartedBy]
 		instanceARN := aws.StringValue(task.ContainerInstanceARN)
 		instance, ok := instanceARNToInstance[instanceARN]
-		if !ok || aws.StringValue(instance.Status) == inactiveInstanceStatus {
+		if ok && ineligibleReason(environment, instance) == types.DrainingInstanceReason {
+			draining, ok := result.drainingInstances[instanceARN]
+			if !ok {
+				draining = &drainingInstance{}
+				result.drainingInstances[instanceARN] = draining
+			}
+			if ours {
+				draining.tasks = append(draining.tasks, aws.StringValue(task.TaskARN))
+			} else {
+				draining.otherTasks = append(draining.otherTasks, task)
+			}
+			continue
+		}
+		// ignore if task does not belong to this environment
+		if !ours || !ok || !isEligible(environment, instance) {
 			continue
 		}
 
@@ -436,11 +1001,25 @@ func (s *scheduler) loadInstancesAlreadyDeployed(state *environmentExecutionStat
 			taskARN:                 aws.StringValue(task.TaskARN),
 			deploymentID:            task.StartedBy,
 			availableInClusterState: true,
+			running:                 aws.StringValue(task.LastStatus) == runningTaskStatus,
+			startedAt:               task.StartedAt,
 		})
 
 		result.deployedInstances[instanceARN] = deployedTasks
 	}
 
+	for _, task := range stoppedTasks {
+		if _, ok := deploymentsMap[task.StartedBy]; !ok {
+			continue
+		}
+		instanceARN := aws.StringValue(task.ContainerInstanceARN)
+		instance, ok := instanceARNToInstance[instanceARN]
+		if !ok || !isEligible(environment, instance) {
+			continue
+		}
+		result.stoppedTasks[instanceARN] = append(result.stoppedTasks[instanceARN], task)
+	}
+
 	// Also add tasks which are not yet available in cluster-state, this happens when events are delayed.
 	for instanceARN, _ := range state.trackingInfo {
 		deployedTasks, ok := result.deployedInstances[instanceARN]
@@ -457,24 +1036,28 @@ func (s *scheduler) loadInstancesAlreadyDeployed(state *environmentExecutionStat
 	return result, nil
 }
 
-// getRunningTasks returns a map of taskARN -> task where task is -probably- running
-func (s *scheduler) getRunningTasks(cluster string) (map[string]*models.Task, error) {
+// getTasks returns a map of taskARN -> task where task is -probably- running, and the tasks that
+// are stopped or stopping
+func (s *scheduler) getTasks(cluster string) (map[string]*models.Task, []*models.Task, error) {
 	resp, err := s.css.ListTasks(cluster)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Error getting tasks for cluster %s", cluster)
+		return nil, nil, errors.Wrapf(err, "Error getting tasks for cluster %s", cluster)
 	}
 
 	tasks := make(map[string]*models.Task)
+	stopped := make([]*models.Task, 0)
 	for _, task := range resp {
 		if aws.StringValue(task.DesiredStatus) == runningTaskStatus {
 			tasks[aws.StringValue(task.TaskARN)] = task
+		} else {
+			stopped = append(stopped, task)
 		}
 	}
 
-	return tasks, nil
+	return tasks, stopped, nil
 }
 
 // setExecutionState provides a way for tests to set initial state of s. Not to be used by regular scheduler flow
-func (s *scheduler) setExecutionState(state map[string]environmentExecutionState) {
+func (s *scheduler) setExecutionState(state map[string]*environmentExecutionState) {
 	s.executionState = state
 }
@@ -21,6 +21,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/facade"
 	mocks "github.com/blox/blox/daemon-scheduler/pkg/mocks"
 	"github.com/blox/blox/daemon-scheduler/pkg/types"
 	"github.com/golang/mock/gomock"
@@ -50,7 +51,7 @@ func TestSchedulerTestSuite(t *testing.T) {
 }
 
 func (suite *SchedulerTestSuite) TestRunInProgress() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 	events := make(chan Event)
 	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
@@ -60,27 +61,233 @@ func (suite *SchedulerTestSuite) TestRunInProgress() {
 	assert.Error(suite.T(), schedulerErrorEvent.Error, "Expected error due to in-progress scheduler run")
 }
 
+func (suite *SchedulerTestSuite) TestClusterScope() {
+	scheduler := NewScheduler(context.Background(), make(chan Event), suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	assert.True(suite.T(), scheduler.isInScope(cluster1), "Expected every cluster to be in scope by default")
+
+	scheduler.SetClusters([]string{"test1"})
+	assert.True(suite.T(), scheduler.isInScope(cluster1), "Expected the cluster ARN to match the cluster name")
+	assert.False(suite.T(), scheduler.isInScope(clusterARN), "Expected other clusters to be out of scope")
+
+	scheduler.SetClusters([]string{clusterARN})
+	assert.True(suite.T(), scheduler.isInScope(clusterName), "Expected the cluster name to match the cluster ARN")
+}
+
+func (suite *SchedulerTestSuite) TestSetTickerDuration() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
+	defer cancel()
+	runs := make(chan struct{}, 100)
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Do(func(ctx context.Context) {
+		runs <- struct{}{}
+	}).Return(nil, nil).AnyTimes()
+	events := make(chan Event, 100)
+	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	assert.Error(suite.T(), scheduler.SetTickerDuration(0), "Expected error setting an invalid interval")
+	assert.Nil(suite.T(), scheduler.SetTickerDuration(time.Millisecond), "Unexpected error setting the interval")
+	scheduler.Start()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			assert.Fail(suite.T(), "Expected the scheduler to run at the new interval")
+			return
+		}
+	}
+}
+
+func (suite *SchedulerTestSuite) TestWatchInstanceRegistered() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
+	defer cancel()
+	environment1 := types.Environment{
+		Name:    environmentName1,
+		Cluster: cluster1,
+	}
+	environment2 := types.Environment{
+		Name:    environmentName2,
+		Cluster: cluster2,
+	}
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment1, environment2}, nil)
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment1.Name).Return(nil, nil)
+	events := make(chan Event)
+	changes := make(chan facade.ClusterStateChange)
+	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	scheduler.Watch(changes)
+
+	changes <- facade.ClusterStateChange{Cluster: "test1"}
+	schedulerEnvironmentEvent := (<-events).(SchedulerEnvironmentEvent)
+	assert.Equal(suite.T(), environment1.Name, schedulerEnvironmentEvent.Environment.Name,
+		"Expected only the environment in the changed cluster to be scheduled")
+}
+
+func (suite *SchedulerTestSuite) TestWatchTaskStopped() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
+	defer cancel()
+	environment1 := types.Environment{
+		Name:        environmentName1,
+		Cluster:     cluster1,
+		Deployments: map[string]types.Deployment{"dep-id": {ID: "dep-id"}},
+	}
+	environment2 := types.Environment{
+		Name:    environmentName2,
+		Cluster: cluster1,
+	}
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment1, environment2}, nil)
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment1.Name).Return(nil, nil)
+	events := make(chan Event)
+	changes := make(chan facade.ClusterStateChange)
+	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	scheduler.Watch(changes)
+
+	changes <- facade.ClusterStateChange{Cluster: "test1", StartedBy: "dep-id"}
+	schedulerEnvironmentEvent := (<-events).(SchedulerEnvironmentEvent)
+	assert.Equal(suite.T(), environment1.Name, schedulerEnvironmentEvent.Environment.Name,
+		"Expected only the environment that started the task to be scheduled")
+}
+
+func (suite *SchedulerTestSuite) TestWatchClusterSelected() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
+	defer cancel()
+	environment1 := types.Environment{
+		Name:            environmentName1,
+		ClusterSelector: types.ClusterSelector{NamePattern: "test*"},
+	}
+	environment2 := types.Environment{
+		Name:    environmentName2,
+		Cluster: cluster2,
+	}
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment1, environment2}, nil)
+	suite.css.EXPECT().ListClusters().Return([]string{cluster1, cluster2}, nil)
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment1.Name).Return(nil, nil)
+	events := make(chan Event)
+	changes := make(chan facade.ClusterStateChange)
+	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	scheduler.Watch(changes)
+
+	changes <- facade.ClusterStateChange{Cluster: "test1"}
+	schedulerEnvironmentEvent := (<-events).(SchedulerEnvironmentEvent)
+	assert.Equal(suite.T(), environment1.Name, schedulerEnvironmentEvent.Environment.Name,
+		"Expected the environment selecting the changed cluster to be scheduled")
+	assert.Equal(suite.T(), cluster1, schedulerEnvironmentEvent.Environment.Cluster,
+		"Expected the environment to be scheduled in the changed cluster")
+}
+
+func (suite *SchedulerTestSuite) TestClusterTargets() {
+	environment := types.Environment{
+		Name:            environmentName1,
+		ClusterSelector: types.ClusterSelector{Clusters: []string{"test2"}, NamePattern: "other*"},
+	}
+	targets, err := clusterTargets(environment, []string{cluster1, cluster2}, suite.ecs)
+	assert.Nil(suite.T(), err, "Unexpected error selecting the clusters")
+	assert.Equal(suite.T(), 1, len(targets), "Expected one target for the one selected cluster")
+	assert.Equal(suite.T(), cluster2, targets[0].Cluster, "Expected the target to be in the selected cluster")
+	assert.Equal(suite.T(), executionStateKey(targets[0]), environmentName1+"/"+cluster2,
+		"Expected each cluster of the environment to be scheduled on its own")
+
+	environment.Cluster = cluster1
+	environment.ClusterSelector = types.ClusterSelector{}
+	targets, err = clusterTargets(environment, []string{cluster1, cluster2}, suite.ecs)
+	assert.Nil(suite.T(), err, "Unexpected error selecting the clusters")
+	assert.Equal(suite.T(), []types.Environment{environment}, targets,
+		"Expected an environment without a cluster selector to be its own target")
+}
+
+func (suite *SchedulerTestSuite) TestClusterTargetsByTags() {
+	environment := types.Environment{
+		Name:            environmentName1,
+		ClusterSelector: types.ClusterSelector{Clusters: []string{"test1"}, Tags: map[string]string{"team": "blox"}},
+	}
+	suite.ecs.EXPECT().DescribeClusterTags(cluster2).Return(map[string]string{"team": "blox", "stage": "prod"}, nil)
+	targets, err := clusterTargets(environment, []string{cluster1, cluster2}, suite.ecs)
+	assert.Nil(suite.T(), err, "Unexpected error selecting the clusters")
+	assert.Equal(suite.T(), 2, len(targets), "Expected a target for the listed and the tagged cluster")
+	assert.Equal(suite.T(), cluster2, targets[1].Cluster, "Expected a target in the tagged cluster")
+
+	suite.ecs.EXPECT().DescribeClusterTags(cluster2).Return(nil, errors.New("Throttled"))
+	_, err = clusterTargets(environment, []string{cluster1, cluster2}, suite.ecs)
+	assert.Error(suite.T(), err, "Expected an error when the cluster tags cannot be described")
+}
+
+func (suite *SchedulerTestSuite) TestWatchClusterSelectedByTags() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
+	defer cancel()
+	environment := types.Environment{
+		Name:            environmentName1,
+		ClusterSelector: types.ClusterSelector{Tags: map[string]string{"team": "blox"}},
+	}
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.css.EXPECT().ListClusters().Return([]string{cluster1, cluster2}, nil)
+	suite.ecs.EXPECT().DescribeClusterTags(cluster1).Return(map[string]string{"team": "blox"}, nil)
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment.Name).Return(nil, nil)
+	events := make(chan Event)
+	changes := make(chan facade.ClusterStateChange)
+	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	scheduler.Watch(changes)
+
+	changes <- facade.ClusterStateChange{Cluster: "test1"}
+	schedulerEnvironmentEvent := (<-events).(SchedulerEnvironmentEvent)
+	assert.Equal(suite.T(), environment.Name, schedulerEnvironmentEvent.Environment.Name,
+		"Expected the environment selecting the changed cluster by its tags to be scheduled")
+	assert.Equal(suite.T(), cluster1, schedulerEnvironmentEvent.Environment.Cluster,
+		"Expected the environment to be scheduled in the changed cluster")
+}
+
+func (suite *SchedulerTestSuite) TestEnvironmentRunRepeatedAfterChange() {
+	state := &environmentExecutionState{}
+	assert.True(suite.T(), state.start(), "Expected the first run to start")
+	assert.False(suite.T(), state.start(), "Expected a run not to start while another one is in progress")
+	assert.True(suite.T(), state.finish(nil), "Expected the run to be repeated")
+	assert.False(suite.T(), state.finish(nil), "Expected the run to be repeated only once")
+	assert.True(suite.T(), state.start(), "Expected a run to start once the previous one finished")
+	state.start()
+	assert.False(suite.T(), state.finish(errors.New("Run failed")), "Expected a failed run not to be repeated")
+}
+
+func (suite *SchedulerTestSuite) TestEnvironmentRunUsesLatestEnvironment() {
+	scheduler := NewScheduler(context.Background(), make(chan Event), suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	environment := types.Environment{Name: "env", Cluster: cluster1, DesiredTaskDefinition: "td:1", ModRevision: 1}
+	state := scheduler.getExecutionState(environment)
+	state.trackingInfo["instance-arn"] = time.Now()
+
+	updated := environment
+	updated.DesiredTaskDefinition = "td:2"
+	updated.ModRevision = 2
+	scheduler.getExecutionState(updated)
+	scheduler.getExecutionState(environment)
+	assert.True(suite.T(), state.start(), "Expected the run to start")
+	assert.Equal(suite.T(), "td:2", state.environment.DesiredTaskDefinition,
+		"Expected the run to use the latest version of the environment")
+	assert.NotEmpty(suite.T(), state.trackingInfo, "Expected the tracked instances to be kept")
+
+	moved := updated
+	moved.Cluster = clusterARN
+	moved.ModRevision = 3
+	scheduler.getExecutionState(moved)
+	state.rerun = true
+	assert.True(suite.T(), state.finish(nil), "Expected the run to be repeated")
+	assert.Equal(suite.T(), clusterARN, state.environment.Cluster, "Expected the repeated run to use the new cluster")
+	assert.Empty(suite.T(), state.trackingInfo, "Expected the instances tracked in the old cluster to be forgotten")
+}
+
 func (suite *SchedulerTestSuite) TestRunListEnvironmentsReturnsError() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
-	var err error
-	err = errors.New("Error calling ListEnvironments")
-	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return(nil, err)
+	err := errors.New("Error calling ListEnvironments")
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return(nil, err).MinTimes(2)
 	events := make(chan Event)
 	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	assert.Nil(suite.T(), scheduler.SetTickerDuration(100*time.Millisecond), "Unexpected error setting the interval")
 	scheduler.Start()
 	schedulerErrorEvent := (<-events).(SchedulerErrorEvent)
 	assert.Equal(suite.T(), err, errors.Cause(schedulerErrorEvent.Error))
 
 	//next run of scheduler should occur after ticker and do the same thing
-	err = errors.New("Error calling ListEnvironments")
-	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return(nil, err)
 	schedulerErrorEvent = (<-events).(SchedulerErrorEvent)
 	assert.Equal(suite.T(), err, errors.Cause(schedulerErrorEvent.Error))
 }
 
 func (suite *SchedulerTestSuite) TestRunGetCurrentDeploymentReturnsError() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 	err := errors.New("Error calling GetCurrentDeployment")
 	environment := types.Environment{
@@ -97,7 +304,7 @@ func (suite *SchedulerTestSuite) TestRunGetCurrentDeploymentReturnsError() {
 }
 
 func (suite *SchedulerTestSuite) TestRunGetCurrentDeploymentReturnsNil() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 	environment := types.Environment{
 		Name: "TestRunGetCurrentDeploymentReturnsNil",
@@ -113,7 +320,7 @@ func (suite *SchedulerTestSuite) TestRunGetCurrentDeploymentReturnsNil() {
 }
 
 func (suite *SchedulerTestSuite) TestRunListInstancesReturnsError() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 	environment := types.Environment{
 		Name:    "TestRunListInstancesReturnsError",
@@ -139,7 +346,7 @@ func (suite *SchedulerTestSuite) TestRunListInstancesReturnsError() {
 }
 
 func (suite *SchedulerTestSuite) TestRunListTasksReturnsError() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 
 	environment := types.Environment{
@@ -175,7 +382,7 @@ func (suite *SchedulerTestSuite) TestRunListTasksReturnsError() {
 }
 
 func (suite *SchedulerTestSuite) TestRunListDeploymentsReturnsError() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 
 	environment := types.Environment{
@@ -219,7 +426,7 @@ func (suite *SchedulerTestSuite) TestRunListDeploymentsReturnsError() {
 }
 
 func (suite *SchedulerTestSuite) TestRunAllInstancesDeployed() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 
 	environment := types.Environment{
@@ -278,7 +485,7 @@ func (suite *SchedulerTestSuite) TestRunAllInstancesDeployed() {
 }
 
 func (suite *SchedulerTestSuite) TestRunEnvironmentStateInProgress() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 
 	environment := types.Environment{
@@ -291,8 +498,8 @@ func (suite *SchedulerTestSuite) TestRunEnvironmentStateInProgress() {
 	events := make(chan Event)
 	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
 	trackingInfo := make(map[string]time.Time)
-	previousState := make(map[string]environmentExecutionState)
-	previousState[environment.Name] = environmentExecutionState{
+	previousState := make(map[string]*environmentExecutionState)
+	previousState[environment.Name] = &environmentExecutionState{
 		environment:  environment,
 		trackingInfo: trackingInfo,
 		inProgress:   true,
@@ -306,7 +513,7 @@ func (suite *SchedulerTestSuite) TestRunEnvironmentStateInProgress() {
 }
 
 func (suite *SchedulerTestSuite) TestRunNewInstance() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 
 	environment := types.Environment{
@@ -383,8 +590,64 @@ func (suite *SchedulerTestSuite) TestRunNewInstance() {
 	assert.Equal(suite.T(), environment.Name, schedulerEnvironmentEvent.Environment.Name)
 }
 
+func (suite *SchedulerTestSuite) TestRunPlacementConstraints() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
+	defer cancel()
+
+	environment := types.Environment{
+		Name:    "TestRunPlacementConstraints",
+		Cluster: "testCluster",
+		PlacementConstraints: types.PlacementConstraints{
+			Expressions:       []string{"ecs.instance-type =~ m5.*"},
+			AvailabilityZones: []string{"us-east-1a"},
+			ExcludeInstances:  []string{"i-excluded"},
+		},
+	}
+	environments := []types.Environment{environment}
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return(environments, nil)
+
+	currentDeployment := types.Deployment{
+		ID:     "dep-id",
+		Status: types.DeploymentInProgress,
+		Health: types.DeploymentHealthy,
+	}
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment.Name).Return(&currentDeployment, nil)
+
+	placedInstance := placementInstance(environment.Cluster, "instance-arn-placed", "i-placed", "m5.large", "us-east-1a")
+	otherZoneInstance := placementInstance(environment.Cluster, "instance-arn-zone", "i-zone", "m5.large", "us-east-1b")
+	otherTypeInstance := placementInstance(environment.Cluster, "instance-arn-type", "i-type", "c5.large", "us-east-1a")
+	excludedInstance := placementInstance(environment.Cluster, "instance-arn-excluded", "i-excluded", "m5.large", "us-east-1a")
+	instances := []*models.ContainerInstance{placedInstance, otherZoneInstance, otherTypeInstance, excludedInstance}
+	suite.css.EXPECT().ListInstances(environment.Cluster).Return(instances, nil)
+
+	// a task deployed before the instance stopped matching is not tracked
+	task := &models.Task{
+		ClusterARN:           otherZoneInstance.ClusterARN,
+		ContainerInstanceARN: otherZoneInstance.ContainerInstanceARN,
+		TaskARN:              aws.String("task-arn-1"),
+		StartedBy:            currentDeployment.ID,
+		DesiredStatus:        aws.String(runningTaskStatus),
+	}
+	suite.css.EXPECT().ListTasks(environment.Cluster).Return([]*models.Task{task}, nil)
+
+	deployments := []types.Deployment{currentDeployment}
+	suite.deploymentSvc.EXPECT().ListDeploymentsSortedReverseChronologically(ctx, environment.Name).Return(deployments, nil)
+
+	events := make(chan Event)
+	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	scheduler.Start()
+
+	startDeploymentEvent := (<-events).(StartDeploymentEvent)
+	assert.Equal(suite.T(), environment.Name, startDeploymentEvent.Environment.Name)
+	assert.Equal(suite.T(), []*string{placedInstance.ContainerInstanceARN}, startDeploymentEvent.Instances,
+		"Expected only the instance matching the placement constraints")
+
+	schedulerEnvironmentEvent := (<-events).(SchedulerEnvironmentEvent)
+	assert.Equal(suite.T(), environment.Name, schedulerEnvironmentEvent.Environment.Name)
+}
+
 func (suite *SchedulerTestSuite) TestRunInstancesWithOldDeployments() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 
 	environment := types.Environment{
@@ -455,8 +718,58 @@ func (suite *SchedulerTestSuite) TestRunInstancesWithOldDeployments() {
 	assert.Equal(suite.T(), environment.Name, schedulerEnvironmentEvent.Environment.Name)
 }
 
+func (suite *SchedulerTestSuite) TestRunInstancesWithOldDeploymentsOutsideMaintenanceWindow() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
+	defer cancel()
+
+	environment := types.Environment{
+		Name:    "TestRunInstancesWithOldDeploymentsOutsideMaintenanceWindow",
+		Cluster: "testCluster",
+		// open for the first minute of the year only
+		MaintenanceWindows: []types.MaintenanceWindow{{Schedule: "0 0 1 1 *", Duration: time.Minute}},
+	}
+	environments := []types.Environment{environment}
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return(environments, nil)
+
+	currentDeployment := types.Deployment{
+		ID:     "dep-id",
+		Status: types.DeploymentInProgress,
+	}
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment.Name).Return(&currentDeployment, nil)
+
+	instance := &models.ContainerInstance{
+		ClusterARN:           aws.String(environment.Cluster),
+		ContainerInstanceARN: aws.String("instance-arn-1"),
+		Status:               aws.String("ACTIVE"),
+	}
+	suite.css.EXPECT().ListInstances(environment.Cluster).Return([]*models.ContainerInstance{instance}, nil)
+
+	oldDeployment := types.Deployment{
+		ID:     "old-dep-id",
+		Status: types.DeploymentCompleted,
+	}
+	task := &models.Task{
+		ClusterARN:           instance.ClusterARN,
+		ContainerInstanceARN: instance.ContainerInstanceARN,
+		TaskARN:              aws.String("task-arn-1"),
+		StartedBy:            oldDeployment.ID,
+		DesiredStatus:        aws.String(runningTaskStatus),
+	}
+	suite.css.EXPECT().ListTasks(environment.Cluster).Return([]*models.Task{task}, nil)
+	suite.deploymentSvc.EXPECT().ListDeploymentsSortedReverseChronologically(ctx, environment.Name).
+		Return([]types.Deployment{currentDeployment, oldDeployment}, nil)
+
+	events := make(chan Event)
+	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
+	scheduler.Start()
+
+	// the task of the old deployment is neither stopped nor replaced
+	schedulerEnvironmentEvent := (<-events).(SchedulerEnvironmentEvent)
+	assert.Equal(suite.T(), environment.Name, schedulerEnvironmentEvent.Environment.Name)
+}
+
 func (suite *SchedulerTestSuite) TestRunTrackedInstance() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 
 	environment := types.Environment{
@@ -494,8 +807,8 @@ func (suite *SchedulerTestSuite) TestRunTrackedInstance() {
 	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
 	trackingInfo := make(map[string]time.Time)
 	trackingInfo[aws.StringValue(instance.ContainerInstanceARN)] = time.Now().UTC()
-	previousState := make(map[string]environmentExecutionState)
-	previousState[environment.Name] = environmentExecutionState{
+	previousState := make(map[string]*environmentExecutionState)
+	previousState[environment.Name] = &environmentExecutionState{
 		environment:  environment,
 		trackingInfo: trackingInfo,
 		inProgress:   false,
@@ -508,7 +821,7 @@ func (suite *SchedulerTestSuite) TestRunTrackedInstance() {
 }
 
 func (suite *SchedulerTestSuite) TestRunTrackedInstanceTTLExpired() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 
 	environment := types.Environment{
@@ -558,9 +871,9 @@ func (suite *SchedulerTestSuite) TestRunTrackedInstanceTTLExpired() {
 	events := make(chan Event)
 	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
 	trackingInfo := make(map[string]time.Time)
-	trackingInfo[aws.StringValue(instance.ContainerInstanceARN)] = time.Now().UTC().Add(-2 * trackingInfoTTL)
-	previousState := make(map[string]environmentExecutionState)
-	previousState[environment.Name] = environmentExecutionState{
+	trackingInfo[aws.StringValue(instance.ContainerInstanceARN)] = time.Now().UTC().Add(-2 * TrackingInfoTTL)
+	previousState := make(map[string]*environmentExecutionState)
+	previousState[environment.Name] = &environmentExecutionState{
 		environment:  environment,
 		trackingInfo: trackingInfo,
 		inProgress:   false,
@@ -573,7 +886,7 @@ func (suite *SchedulerTestSuite) TestRunTrackedInstanceTTLExpired() {
 }
 
 func (suite *SchedulerTestSuite) TestRunTrackedInstanceDescribeTasksReturnsError() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 
 	environment := types.Environment{
@@ -614,9 +927,9 @@ func (suite *SchedulerTestSuite) TestRunTrackedInstanceDescribeTasksReturnsError
 	events := make(chan Event)
 	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
 	trackingInfo := make(map[string]time.Time)
-	trackingInfo[aws.StringValue(instance.ContainerInstanceARN)] = time.Now().UTC().Add(-2 * trackingInfoTTL)
-	previousState := make(map[string]environmentExecutionState)
-	previousState[environment.Name] = environmentExecutionState{
+	trackingInfo[aws.StringValue(instance.ContainerInstanceARN)] = time.Now().UTC().Add(-2 * TrackingInfoTTL)
+	previousState := make(map[string]*environmentExecutionState)
+	previousState[environment.Name] = &environmentExecutionState{
 		environment:  environment,
 		trackingInfo: trackingInfo,
 		inProgress:   false,
@@ -628,7 +941,7 @@ func (suite *SchedulerTestSuite) TestRunTrackedInstanceDescribeTasksReturnsError
 }
 
 func (suite *SchedulerTestSuite) TestRunTrackedInstanceListTasksReturnsError() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 
 	environment := types.Environment{
@@ -666,9 +979,9 @@ func (suite *SchedulerTestSuite) TestRunTrackedInstanceListTasksReturnsError() {
 	events := make(chan Event)
 	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
 	trackingInfo := make(map[string]time.Time)
-	trackingInfo[aws.StringValue(instance.ContainerInstanceARN)] = time.Now().UTC().Add(-2 * trackingInfoTTL)
-	previousState := make(map[string]environmentExecutionState)
-	previousState[environment.Name] = environmentExecutionState{
+	trackingInfo[aws.StringValue(instance.ContainerInstanceARN)] = time.Now().UTC().Add(-2 * TrackingInfoTTL)
+	previousState := make(map[string]*environmentExecutionState)
+	previousState[environment.Name] = &environmentExecutionState{
 		environment:  environment,
 		trackingInfo: trackingInfo,
 		inProgress:   false,
@@ -680,7 +993,7 @@ func (suite *SchedulerTestSuite) TestRunTrackedInstanceListTasksReturnsError() {
 }
 
 func (suite *SchedulerTestSuite) TestRunTrackedInstanceListTasksReturnsEmpty() {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*schedulerTickerDuration)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*SchedulerTickerDuration)
 	defer cancel()
 
 	environment := types.Environment{
@@ -718,9 +1031,9 @@ func (suite *SchedulerTestSuite) TestRunTrackedInstanceListTasksReturnsEmpty() {
 	events := make(chan Event)
 	scheduler := NewScheduler(ctx, events, suite.environmentSvc, suite.deploymentSvc, suite.css, suite.ecs)
 	trackingInfo := make(map[string]time.Time)
-	trackingInfo[aws.StringValue(instance.ContainerInstanceARN)] = time.Now().UTC().Add(-2 * trackingInfoTTL)
-	previousState := make(map[string]environmentExecutionState)
-	previousState[environment.Name] = environmentExecutionState{
+	trackingInfo[aws.StringValue(instance.ContainerInstanceARN)] = time.Now().UTC().Add(-2 * TrackingInfoTTL)
+	previousState := make(map[string]*environmentExecutionState)
+	previousState[environment.Name] = &environmentExecutionState{
 		environment:  environment,
 		trackingInfo: trackingInfo,
 		inProgress:   false,
@@ -736,3 +1049,24 @@ func (suite *SchedulerTestSuite) TestRunTrackedInstanceListTasksReturnsEmpty() {
 	schedulerEnvironmentEvent := (<-events).(SchedulerEnvironmentEvent)
 	assert.Equal(suite.T(), environment.Name, schedulerEnvironmentEvent.Environment.Name)
 }
+
+func containerInstance(cluster string, arn string, status string) *models.ContainerInstance {
+	return &models.ContainerInstance{
+		ClusterARN:           aws.String(cluster),
+		ContainerInstanceARN: aws.String(arn),
+		Status:               aws.String(status),
+	}
+}
+
+func placementInstance(cluster string, arn string, ec2InstanceID string, instanceType string, zone string) *models.ContainerInstance {
+	return &models.ContainerInstance{
+		ClusterARN:           aws.String(cluster),
+		ContainerInstanceARN: aws.String(arn),
+		EC2InstanceID:        ec2InstanceID,
+		Status:               aws.String("ACTIVE"),
+		Attributes: []*models.ContainerInstanceAttribute{
+			{Name: aws.String("ecs.instance-type"), Value: aws.String(instanceType)},
+			{Name: aws.String(types.AvailabilityZoneAttribute), Value: aws.String(zone)},
+		},
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sort"
+
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// Strategy decides which instances of a cluster run a task of an environment. The scheduler
+// starts a task of the current deployment on every instance the strategy places the environment
+// on that does not run one yet, replaces the tasks of earlier deployments there as the rollout
+// strategy of the environment says, and stops the tasks on the instances it is no longer placed
+// on. Each instance runs at most one task of an environment.
+type Strategy interface {
+	// Place returns the instances of the cluster that should run a task of the environment
+	Place(environment types.Environment, cluster ClusterState) []string
+}
+
+// ClusterState is what a strategy places the tasks of an environment from
+type ClusterState struct {
+	// Instances are the instances of the cluster the environment may run on, by ARN. Instances
+	// that are inactive, draining, have a disconnected agent or do not satisfy the placement
+	// constraints of the environment are left out.
+	Instances map[string]*models.ContainerInstance
+	// Deployed are the instances that already run a task of the environment
+	Deployed map[string]bool
+	// Unavailable are the instances the current deployment found short of capacity
+	Unavailable map[string]bool
+}
+
+// strategies create the strategy of each type of scheduling strategy
+var strategies = map[types.SchedulingStrategyType]func(types.SchedulingStrategy) Strategy{
+	types.DaemonStrategy: func(types.SchedulingStrategy) Strategy {
+		return daemonStrategy{}
+	},
+	types.ReplicaStrategy: func(strategy types.SchedulingStrategy) Strategy {
+		return replicaStrategy{replicas: strategy.Replicas}
+	},
+}
+
+// strategyFor returns the strategy of the environment
+func strategyFor(environment types.Environment) Strategy {
+	newStrategy, ok := strategies[environment.SchedulingStrategy.TypeOrDefault()]
+	if !ok {
+		log.Warnf("Unknown scheduling strategy %s of environment %s, using the daemon strategy",
+			environment.SchedulingStrategy.Type, environment.Name)
+		return daemonStrategy{}
+	}
+	return newStrategy(environment.SchedulingStrategy)
+}
+
+// daemonStrategy places the environment on every instance it may run on
+type daemonStrategy struct{}
+
+func (daemonStrategy) Place(environment types.Environment, cluster ClusterState) []string {
+	placed := make([]string, 0, len(cluster.Instances))
+	for instanceARN := range cluster.Instances {
+		placed = append(placed, instanceARN)
+	}
+	sort.Strings(placed)
+	return placed
+}
+
+// placeTasks returns the instances the strategy of the environment places it on among the
+// eligible instances
+func placeTasks(environment types.Environment, eligible map[string]*models.ContainerInstance,
+	result *instanceLookupResult) map[string]bool {
+
+	deployed := make(map[string]bool, len(result.deployedInstances))
+	for instanceARN := range result.deployedInstances {
+		if _, ok := eligible[instanceARN]; ok {
+			deployed[instanceARN] = true
+		}
+	}
+	cluster := ClusterState{
+		Instances:   eligible,
+		Deployed:    deployed,
+		Unavailable: result.unavailableInstances,
+	}
+
+	placed := make(map[string]bool, len(eligible))
+	for _, instanceARN := range strategyFor(environment).Place(environment, cluster) {
+		placed[instanceARN] = true
+	}
+	return placed
+}
+
+// missingReplicas returns the number of replicas of a replica environment the placed instances
+// fall short of
+func missingReplicas(environment types.Environment, placed map[string]bool) int {
+	if !environment.SchedulingStrategy.IsReplica() || len(placed) >= environment.SchedulingStrategy.Replicas {
+		return 0
+	}
+	return environment.SchedulingStrategy.Replicas - len(placed)
+}
+
+// recordMissingReplicas stores the replicas of the environment that could not be placed in the
+// cluster with the environment when they changed, so that they can be reported
+func (s *scheduler) recordMissingReplicas(state *environmentExecutionState, result *instanceLookupResult) error {
+	environment := state.environment
+	if !environment.UpdateMissingReplicas(environment.Cluster, result.missingReplicas) {
+		return nil
+	}
+	if result.missingReplicas > 0 {
+		log.Warnf("[s:%s, e:%s] Placing %d of %d replicas, as cluster %s has too few eligible instances",
+			s.id, environment.Name, environment.SchedulingStrategy.Replicas-result.missingReplicas,
+			environment.SchedulingStrategy.Replicas, environment.Cluster)
+	}
+
+	_, err := s.environmentSvc.UpdateEnvironment(s.ctx, environment.Name, func(latest *types.Environment) error {
+		latest.UpdateMissingReplicas(environment.Cluster, result.missingReplicas)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Error updating the missing replicas of environment %s", environment.Name)
+	}
+	return nil
+}
+
+// stopExcessTasks stops the tasks of the environment on the instances its strategy no longer
+// places it on
+func (s *scheduler) stopExcessTasks(state *environmentExecutionState, result *instanceLookupResult) {
+	environment := state.environment
+	tasks := make([]string, 0)
+	for instanceARN, deployedTasks := range result.excessInstances {
+		// a task started there but not known to the cluster state yet is not waited for anymore
+		delete(state.trackingInfo, instanceARN)
+		for _, dt := range deployedTasks {
+			if dt.availableInClusterState {
+				tasks = append(tasks, dt.taskARN)
+			}
+		}
+	}
+	if len(tasks) == 0 {
+		return
+	}
+	sort.Strings(tasks)
+
+	log.Infof("[s:%s, e:%s] Stopping %d tasks on instances the environment is no longer placed on",
+		s.id, environment.Name, len(tasks))
+	sendEvent(s.ctx.Done(), s.events, StopTasksEvent{
+		Cluster:     environment.Cluster,
+		Tasks:       tasks,
+		Environment: environment,
+		Reason:      types.StopReasonExcess,
+	})
+}
@@ -0,0 +1,178 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *SchedulerTestSuite) TestDaemonStrategyPlacesEveryInstance() {
+	cluster := replicaCluster()
+	placed := daemonStrategy{}.Place(types.Environment{}, cluster)
+	assert.Equal(suite.T(), []string{"instance-a1", "instance-a2", "instance-b1"}, placed,
+		"Expected every instance to get a task")
+}
+
+func (suite *SchedulerTestSuite) TestReplicaStrategySpreadsOverZones() {
+	cluster := replicaCluster()
+	placed := replicaStrategy{replicas: 2}.Place(types.Environment{}, cluster)
+	assert.Equal(suite.T(), []string{"instance-a2", "instance-b1"}, placed,
+		"Expected a replica in each zone, on the instance with the least memory left in the zone")
+}
+
+func (suite *SchedulerTestSuite) TestReplicaStrategyBinpacksWithinZone() {
+	cluster := replicaCluster()
+	placed := replicaStrategy{replicas: 3}.Place(types.Environment{}, cluster)
+	assert.Equal(suite.T(), []string{"instance-a1", "instance-a2", "instance-b1"}, placed,
+		"Expected every instance once there are as many replicas as instances")
+
+	placed = replicaStrategy{replicas: 5}.Place(types.Environment{}, cluster)
+	assert.Len(suite.T(), placed, 3, "Expected at most one replica on each instance")
+}
+
+func (suite *SchedulerTestSuite) TestReplicaStrategyKeepsDeployedInstances() {
+	cluster := replicaCluster()
+	cluster.Deployed = map[string]bool{"instance-a1": true, "instance-b1": true}
+	placed := replicaStrategy{replicas: 1}.Place(types.Environment{}, cluster)
+	assert.Equal(suite.T(), []string{"instance-a1"}, placed,
+		"Expected a deployed instance to be kept over the instance with the least memory left")
+
+	placed = replicaStrategy{replicas: 0}.Place(types.Environment{}, cluster)
+	assert.Empty(suite.T(), placed, "Expected no instance without replicas")
+}
+
+func (suite *SchedulerTestSuite) TestReplicaStrategyPlacesUnavailableInstancesLast() {
+	cluster := replicaCluster()
+	cluster.Unavailable = map[string]bool{"instance-a2": true}
+	placed := replicaStrategy{replicas: 2}.Place(types.Environment{}, cluster)
+	assert.Equal(suite.T(), []string{"instance-a1", "instance-b1"}, placed,
+		"Expected the instance short of capacity to be skipped while others are left")
+}
+
+func (suite *SchedulerTestSuite) TestRunReplicaStrategy() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{})
+	environment.SchedulingStrategy = types.SchedulingStrategy{Type: types.ReplicaStrategy, Replicas: 2}
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment.Name).Return(&currentDeployment, nil)
+
+	instances := []*models.ContainerInstance{
+		replicaInstance("instance-a1", "us-east-1a", 1024),
+		replicaInstance("instance-a2", "us-east-1a", 512),
+		replicaInstance("instance-b1", "us-east-1b", 2048),
+	}
+	suite.css.EXPECT().ListInstances(environment.Cluster).Return(instances, nil)
+	tasks := []*models.Task{rolloutTask("instance-a1", currentDeployment.ID, runningTaskStatus)}
+	suite.css.EXPECT().ListTasks(environment.Cluster).Return(tasks, nil)
+	suite.deploymentSvc.EXPECT().ListDeploymentsSortedReverseChronologically(ctx, environment.Name).
+		Return([]types.Deployment{currentDeployment}, nil)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	startDeploymentEvent := (<-events).(StartDeploymentEvent)
+	assert.Equal(suite.T(), []*string{aws.String("instance-b1")}, startDeploymentEvent.Instances,
+		"Expected the second replica in the other zone")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+}
+
+func (suite *SchedulerTestSuite) TestRunReplicaStrategyStopsExcessTasks() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{})
+	environment.SchedulingStrategy = types.SchedulingStrategy{Type: types.ReplicaStrategy, Replicas: 1}
+	tasks := []*models.Task{
+		rolloutTask(rolloutInstance1, currentDeployment.ID, runningTaskStatus),
+		rolloutTask(rolloutInstance2, currentDeployment.ID, runningTaskStatus),
+	}
+	suite.expectRolloutLookup(ctx, environment, currentDeployment, tasks)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	stopTasksEvent := (<-events).(StopTasksEvent)
+	assert.Equal(suite.T(), []string{"task-" + rolloutInstance2}, stopTasksEvent.Tasks,
+		"Expected the task over the replicas to be stopped")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+}
+
+func (suite *SchedulerTestSuite) TestRunReplicaStrategyRecordsMissingReplicas() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	environment, currentDeployment := rolloutEnvironment(types.RolloutStrategy{})
+	environment.SchedulingStrategy = types.SchedulingStrategy{Type: types.ReplicaStrategy, Replicas: 5}
+	suite.environmentSvc.EXPECT().ListEnvironments(ctx).Return([]types.Environment{environment}, nil)
+	suite.deploymentSvc.EXPECT().GetCurrentDeployment(ctx, environment.Name).Return(&currentDeployment, nil)
+
+	instances := []*models.ContainerInstance{
+		replicaInstance("instance-a1", "us-east-1a", 1024),
+		replicaInstance("instance-a2", "us-east-1a", 512),
+		replicaInstance("instance-b1", "us-east-1b", 2048),
+	}
+	suite.css.EXPECT().ListInstances(environment.Cluster).Return(instances, nil)
+	tasks := []*models.Task{rolloutTask("instance-a1", currentDeployment.ID, runningTaskStatus)}
+	suite.css.EXPECT().ListTasks(environment.Cluster).Return(tasks, nil)
+	suite.deploymentSvc.EXPECT().ListDeploymentsSortedReverseChronologically(ctx, environment.Name).
+		Return([]types.Deployment{currentDeployment}, nil)
+	recorded := suite.expectRecordRollout(ctx, environment, currentDeployment)
+
+	events := suite.startRolloutScheduler(ctx)
+
+	startDeploymentEvent := (<-events).(StartDeploymentEvent)
+	assert.Equal(suite.T(), []*string{aws.String("instance-a2"), aws.String("instance-b1")}, startDeploymentEvent.Instances,
+		"Expected a replica on every instance")
+	_ = (<-events).(SchedulerEnvironmentEvent)
+
+	latest := <-recorded
+	assert.Equal(suite.T(), map[string]int{environment.Cluster: 2}, latest.MissingReplicas,
+		"Expected the replicas the cluster has no instance for to be recorded")
+}
+
+// replicaCluster has two instances in us-east-1a, the second with less memory left, and one with
+// the most memory left in us-east-1b
+func replicaCluster() ClusterState {
+	instances := []*models.ContainerInstance{
+		replicaInstance("instance-a1", "us-east-1a", 1024),
+		replicaInstance("instance-a2", "us-east-1a", 512),
+		replicaInstance("instance-b1", "us-east-1b", 2048),
+	}
+	cluster := ClusterState{
+		Instances:   make(map[string]*models.ContainerInstance),
+		Deployed:    make(map[string]bool),
+		Unavailable: make(map[string]bool),
+	}
+	for _, instance := range instances {
+		cluster.Instances[aws.StringValue(instance.ContainerInstanceARN)] = instance
+	}
+	return cluster
+}
+
+func replicaInstance(instanceARN string, zone string, memory int) *models.ContainerInstance {
+	instance := containerInstance("testCluster", instanceARN, "ACTIVE")
+	instance.Attributes = []*models.ContainerInstanceAttribute{
+		{Name: aws.String(types.AvailabilityZoneAttribute), Value: aws.String(zone)},
+	}
+	instance.RemainingResources = []*models.ContainerInstanceResource{
+		{Name: aws.String(memoryResource), Type: aws.String("INTEGER"), Value: aws.String(strconv.Itoa(memory))},
+	}
+	return instance
+}
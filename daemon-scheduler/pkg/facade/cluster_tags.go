@@ -0,0 +1,78 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package facade
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type clusterTagCache struct {
+	ECS
+	maxAge time.Duration
+	now    func() time.Time
+
+	lock     sync.Mutex
+	clusters map[string]cachedClusterTags
+}
+
+// cachedClusterTags holds the tags of a cluster and when they were described
+type cachedClusterTags struct {
+	tags        map[string]string
+	describedAt time.Time
+}
+
+// NewClusterTagCache wraps an ECS facade so that the tags of each cluster are only described
+// again once they are older than maxAge, as selecting clusters by tag looks them up on every
+// scheduler run. Other calls are passed through.
+func NewClusterTagCache(ecs ECS, maxAge time.Duration) (ECS, error) {
+	if ecs == nil {
+		return nil, errors.New("ECS should not be nil")
+	}
+	if maxAge <= 0 {
+		return nil, errors.Errorf("Invalid cluster tag cache max age %s", maxAge)
+	}
+	return &clusterTagCache{
+		ECS:      ecs,
+		maxAge:   maxAge,
+		now:      time.Now,
+		clusters: make(map[string]cachedClusterTags),
+	}, nil
+}
+
+func (c *clusterTagCache) DescribeClusterTags(cluster string) (map[string]string, error) {
+	name := clusterName(cluster)
+
+	c.lock.Lock()
+	cached, ok := c.clusters[name]
+	c.lock.Unlock()
+	if ok && c.now().Sub(cached.describedAt) < c.maxAge {
+		return cached.tags, nil
+	}
+
+	tags, err := c.ECS.DescribeClusterTags(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.clusters[name] = cachedClusterTags{
+		tags:        tags,
+		describedAt: c.now(),
+	}
+	return tags, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package facade
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClusterTagCacheTestSuite struct {
+	suite.Suite
+	ecs   *mocks.MockECS
+	cache *clusterTagCache
+	clock time.Time
+}
+
+func (suite *ClusterTagCacheTestSuite) SetupTest() {
+	mockCtrl := gomock.NewController(suite.T())
+	suite.ecs = mocks.NewMockECS(mockCtrl)
+
+	cache, err := NewClusterTagCache(suite.ecs, time.Minute)
+	assert.Nil(suite.T(), err, "Cannot initialize ClusterTagCacheTestSuite")
+	suite.cache = cache.(*clusterTagCache)
+	suite.clock = time.Unix(1000, 0)
+	suite.cache.now = func() time.Time {
+		return suite.clock
+	}
+}
+
+func TestClusterTagCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(ClusterTagCacheTestSuite))
+}
+
+func (suite *ClusterTagCacheTestSuite) TestNewClusterTagCacheInvalidArguments() {
+	_, err := NewClusterTagCache(nil, time.Minute)
+	assert.Error(suite.T(), err, "Expected an error when ECS is nil")
+
+	_, err = NewClusterTagCache(suite.ecs, 0)
+	assert.Error(suite.T(), err, "Expected an error when the max age is not positive")
+}
+
+func (suite *ClusterTagCacheTestSuite) TestDescribeClusterTagsCached() {
+	tags := map[string]string{"team": "blox"}
+	suite.ecs.EXPECT().DescribeClusterTags(clusterARN).Return(tags, nil).Times(1)
+
+	for _, cluster := range []string{clusterARN, testClusterName} {
+		cached, err := suite.cache.DescribeClusterTags(cluster)
+		assert.Nil(suite.T(), err, "Unexpected error describing the cluster tags")
+		assert.Equal(suite.T(), tags, cached, "Expected the tags of the cluster")
+	}
+}
+
+func (suite *ClusterTagCacheTestSuite) TestDescribeClusterTagsExpires() {
+	suite.ecs.EXPECT().DescribeClusterTags(testClusterName).Return(map[string]string{"team": "blox"}, nil)
+	suite.ecs.EXPECT().DescribeClusterTags(testClusterName).Return(map[string]string{"team": "ecs"}, nil)
+
+	_, err := suite.cache.DescribeClusterTags(testClusterName)
+	assert.Nil(suite.T(), err, "Unexpected error describing the cluster tags")
+
+	suite.clock = suite.clock.Add(time.Minute)
+	tags, err := suite.cache.DescribeClusterTags(testClusterName)
+	assert.Nil(suite.T(), err, "Unexpected error describing the cluster tags")
+	assert.Equal(suite.T(), map[string]string{"team": "ecs"}, tags, "Expected the tags to be described again")
+}
+
+func (suite *ClusterTagCacheTestSuite) TestDescribeClusterTagsErrorNotCached() {
+	suite.ecs.EXPECT().DescribeClusterTags(testClusterName).Return(nil, errors.New("Throttled"))
+	suite.ecs.EXPECT().DescribeClusterTags(testClusterName).Return(map[string]string{"team": "blox"}, nil)
+
+	_, err := suite.cache.DescribeClusterTags(testClusterName)
+	assert.Error(suite.T(), err, "Expected an error when the tags cannot be described")
+
+	tags, err := suite.cache.DescribeClusterTags(testClusterName)
+	assert.Nil(suite.T(), err, "Unexpected error describing the cluster tags")
+	assert.Equal(suite.T(), map[string]string{"team": "blox"}, tags, "Expected the tags to be described again")
+}
@@ -14,6 +14,12 @@
 package facade
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/client"
 	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/client/operations"
 	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
@@ -24,6 +30,14 @@ import (
 type ClusterState interface {
 	ListInstances(cluster string) ([]*models.ContainerInstance, error)
 	ListTasks(cluster string) ([]*models.Task, error)
+	// ListClusters returns the ARNs, in order, of the clusters with instances in the cluster state
+	ListClusters() ([]string, error)
+	// StreamInstances sends instances that change across all clusters on instances until the
+	// stream ends or ctx is cancelled
+	StreamInstances(ctx context.Context, instances chan<- *models.ContainerInstance) error
+	// StreamTasks sends tasks that change across all clusters on tasks until the stream ends
+	// or ctx is cancelled
+	StreamTasks(ctx context.Context, tasks chan<- *models.Task) error
 }
 
 type clusterState struct {
@@ -61,3 +75,93 @@ func (c clusterState) ListTasks(cluster string) ([]*models.Task, error) {
 	}
 	return resp.Payload.Items, nil
 }
+
+func (c clusterState) ListClusters() ([]string, error) {
+	resp, err := c.client.Operations.ListInstances(operations.NewListInstancesParams())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error calling ListInstances to list clusters")
+	}
+
+	seen := make(map[string]bool)
+	clusters := make([]string, 0)
+	for _, instance := range resp.Payload.Items {
+		cluster := aws.StringValue(instance.ClusterARN)
+		if cluster != "" && !seen[cluster] {
+			seen[cluster] = true
+			clusters = append(clusters, cluster)
+		}
+	}
+	sort.Strings(clusters)
+	return clusters, nil
+}
+
+func (c clusterState) StreamInstances(ctx context.Context, instances chan<- *models.ContainerInstance) error {
+	err := stream(ctx, func(w io.Writer) error {
+		_, err := c.client.Operations.StreamInstances(operations.NewStreamInstancesParamsWithContext(ctx), w)
+		return err
+	}, func(decoder *json.Decoder) error {
+		instance := &models.ContainerInstance{}
+		if err := decoder.Decode(instance); err != nil {
+			return err
+		}
+		select {
+		case instances <- instance:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	return errors.Wrapf(err, "Error streaming instances")
+}
+
+func (c clusterState) StreamTasks(ctx context.Context, tasks chan<- *models.Task) error {
+	err := stream(ctx, func(w io.Writer) error {
+		_, err := c.client.Operations.StreamTasks(operations.NewStreamTasksParamsWithContext(ctx), w)
+		return err
+	}, func(decoder *json.Decoder) error {
+		task := &models.Task{}
+		if err := decoder.Decode(task); err != nil {
+			return err
+		}
+		select {
+		case tasks <- task:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	return errors.Wrapf(err, "Error streaming tasks")
+}
+
+// stream calls open with a writer that receives the streamed response body and calls next
+// to decode each JSON document written to it, until either of them fails or the stream ends
+func stream(ctx context.Context, open func(w io.Writer) error, next func(decoder *json.Decoder) error) error {
+	reader, writer := io.Pipe()
+	opened := make(chan error, 1)
+	go func() {
+		err := open(writer)
+		// the reader sees io.EOF when the stream ended without an error
+		writer.CloseWithError(err)
+		opened <- err
+	}()
+
+	decoder := json.NewDecoder(reader)
+	var err error
+	for err == nil {
+		err = next(decoder)
+	}
+	// unblock open if it is still writing
+	reader.CloseWithError(err)
+	openErr := <-opened
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if openErr != nil {
+		return openErr
+	}
+	if err == io.EOF {
+		return errors.New("The stream was closed by the cluster state service")
+	}
+	return err
+}
@@ -0,0 +1,331 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package facade
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	streamRetryInterval  = 5 * time.Second
+	activeInstanceStatus = "ACTIVE"
+	stoppedTaskStatus    = "STOPPED"
+)
+
+// ClusterStateChange describes a change in the cluster state that may require scheduling
+type ClusterStateChange struct {
+	// Cluster is the name of the cluster that changed. It is empty when changes may have been
+	// missed, in which case every cluster should be checked.
+	Cluster string
+	// StartedBy is the startedBy of the task that stopped. It is empty when an instance registered.
+	StartedBy string
+}
+
+// ClusterStateCache is a ClusterState that serves instances and tasks from a local cache kept
+// up to date by streaming changes from the cluster state service
+type ClusterStateCache interface {
+	ClusterState
+	// Watch streams changes into the cache until ctx is cancelled and sends the changes that
+	// may require scheduling on changes. The cache is bypassed while Watch is not running.
+	Watch(ctx context.Context, changes chan<- ClusterStateChange)
+}
+
+type clusterStateCache struct {
+	css    ClusterState
+	maxAge time.Duration
+
+	lock     sync.RWMutex
+	clusters map[string]*cachedCluster
+	// streaming is set while both streams are connected, and epoch changes every time they
+	// connect or disconnect so that listings spanning a reconnection are not cached
+	streaming bool
+	epoch     int
+}
+
+// cachedCluster holds the instances and tasks of a cluster keyed by ARN
+type cachedCluster struct {
+	instances         map[string]*models.ContainerInstance
+	instancesSyncedAt time.Time
+	// instanceChanges counts the streamed instance changes, so that a listing that raced with
+	// a change is not cached
+	instanceChanges int
+
+	tasks         map[string]*models.Task
+	tasksSyncedAt time.Time
+	taskChanges   int
+}
+
+// NewClusterStateCache creates a cache in front of css. Cached clusters are listed again
+// from css once they are older than maxAge, which bounds the effect of a missed change.
+func NewClusterStateCache(css ClusterState, maxAge time.Duration) (ClusterStateCache, error) {
+	if css == nil {
+		return nil, errors.New("Cluster state should not be nil")
+	}
+	if maxAge <= 0 {
+		return nil, errors.Errorf("Invalid cluster state cache max age %s", maxAge)
+	}
+	return &clusterStateCache{
+		css:      css,
+		maxAge:   maxAge,
+		clusters: make(map[string]*cachedCluster),
+	}, nil
+}
+
+func (c *clusterStateCache) ListInstances(cluster string) ([]*models.ContainerInstance, error) {
+	name := clusterName(cluster)
+
+	c.lock.RLock()
+	cached, ok := c.clusters[name]
+	if ok && c.streaming && time.Since(cached.instancesSyncedAt) < c.maxAge {
+		instances := make([]*models.ContainerInstance, 0, len(cached.instances))
+		for _, instance := range cached.instances {
+			instances = append(instances, instance)
+		}
+		c.lock.RUnlock()
+		return instances, nil
+	}
+	streaming, epoch, changes := c.streaming, c.epoch, 0
+	if ok {
+		changes = cached.instanceChanges
+	}
+	c.lock.RUnlock()
+
+	instances, err := c.css.ListInstances(cluster)
+	if err != nil || !streaming {
+		return instances, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	cached = c.cluster(name)
+	if c.epoch == epoch && cached.instanceChanges == changes {
+		cached.instances = make(map[string]*models.ContainerInstance, len(instances))
+		for _, instance := range instances {
+			cached.instances[aws.StringValue(instance.ContainerInstanceARN)] = instance
+		}
+		cached.instancesSyncedAt = time.Now()
+	}
+	return instances, nil
+}
+
+func (c *clusterStateCache) ListTasks(cluster string) ([]*models.Task, error) {
+	name := clusterName(cluster)
+
+	c.lock.RLock()
+	cached, ok := c.clusters[name]
+	if ok && c.streaming && time.Since(cached.tasksSyncedAt) < c.maxAge {
+		tasks := make([]*models.Task, 0, len(cached.tasks))
+		for _, task := range cached.tasks {
+			tasks = append(tasks, task)
+		}
+		c.lock.RUnlock()
+		return tasks, nil
+	}
+	streaming, epoch, changes := c.streaming, c.epoch, 0
+	if ok {
+		changes = cached.taskChanges
+	}
+	c.lock.RUnlock()
+
+	tasks, err := c.css.ListTasks(cluster)
+	if err != nil || !streaming {
+		return tasks, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	cached = c.cluster(name)
+	if c.epoch == epoch && cached.taskChanges == changes {
+		cached.tasks = make(map[string]*models.Task, len(tasks))
+		for _, task := range tasks {
+			cached.tasks[aws.StringValue(task.TaskARN)] = task
+		}
+		cached.tasksSyncedAt = time.Now()
+	}
+	return tasks, nil
+}
+
+// ListClusters is not cached, as clusters are only listed once per scheduler run
+func (c *clusterStateCache) ListClusters() ([]string, error) {
+	return c.css.ListClusters()
+}
+
+func (c *clusterStateCache) StreamInstances(ctx context.Context, instances chan<- *models.ContainerInstance) error {
+	return c.css.StreamInstances(ctx, instances)
+}
+
+func (c *clusterStateCache) StreamTasks(ctx context.Context, tasks chan<- *models.Task) error {
+	return c.css.StreamTasks(ctx, tasks)
+}
+
+func (c *clusterStateCache) Watch(ctx context.Context, changes chan<- ClusterStateChange) {
+	for connected := false; ctx.Err() == nil; connected = true {
+		if connected {
+			// changes may have been missed while the streams were down
+			select {
+			case changes <- ClusterStateChange{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		err := c.watchOnce(ctx, changes)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Errorf("Error streaming the cluster state, retrying in %s: %+v", streamRetryInterval, err)
+		select {
+		case <-time.After(streamRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchOnce applies streamed changes to the cache until either stream fails
+func (c *clusterStateCache) watchOnce(ctx context.Context, changes chan<- ClusterStateChange) error {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c.setStreaming(true)
+	defer c.setStreaming(false)
+
+	instances := make(chan *models.ContainerInstance)
+	tasks := make(chan *models.Task)
+	errs := make(chan error, 2)
+	go func() {
+		errs <- c.css.StreamInstances(streamCtx, instances)
+	}()
+	go func() {
+		errs <- c.css.StreamTasks(streamCtx, tasks)
+	}()
+
+	var err error
+	for pending := 2; pending > 0; {
+		var change *ClusterStateChange
+		select {
+		case instance := <-instances:
+			change = c.applyInstance(instance)
+		case task := <-tasks:
+			change = c.applyTask(task)
+		case streamErr := <-errs:
+			if err == nil {
+				err = streamErr
+			}
+			// stop the other stream as the cache cannot be kept up to date without both
+			cancel()
+			pending--
+			continue
+		}
+		if change == nil {
+			continue
+		}
+		select {
+		case changes <- *change:
+		case <-ctx.Done():
+		}
+	}
+	return err
+}
+
+func (c *clusterStateCache) setStreaming(streaming bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.streaming = streaming
+	c.epoch++
+	if !streaming {
+		c.clusters = make(map[string]*cachedCluster)
+	}
+}
+
+// applyInstance updates the cache with instance and returns a change if the instance became active
+func (c *clusterStateCache) applyInstance(instance *models.ContainerInstance) *ClusterStateChange {
+	name := clusterName(aws.StringValue(instance.ClusterARN))
+	arn := aws.StringValue(instance.ContainerInstanceARN)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, seen := c.clusters[name]
+	cached := c.cluster(name)
+	cached.instanceChanges++
+	if cached.instances == nil {
+		// the cluster is not being scheduled yet, but environments with a cluster selector may
+		// have to be deployed to it if it just appeared
+		if !seen && aws.StringValue(instance.Status) == activeInstanceStatus {
+			return &ClusterStateChange{Cluster: name}
+		}
+		return nil
+	}
+
+	previous, ok := cached.instances[arn]
+	cached.instances[arn] = instance
+	if aws.StringValue(instance.Status) != activeInstanceStatus ||
+		(ok && aws.StringValue(previous.Status) == activeInstanceStatus) {
+		return nil
+	}
+	return &ClusterStateChange{Cluster: name}
+}
+
+// applyTask updates the cache with task and returns a change if a started task is stopping
+func (c *clusterStateCache) applyTask(task *models.Task) *ClusterStateChange {
+	name := clusterName(aws.StringValue(task.ClusterARN))
+	arn := aws.StringValue(task.TaskARN)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cached := c.cluster(name)
+	cached.taskChanges++
+	if cached.tasks == nil {
+		return nil
+	}
+
+	if aws.StringValue(task.DesiredStatus) != stoppedTaskStatus && aws.StringValue(task.LastStatus) != stoppedTaskStatus {
+		cached.tasks[arn] = task
+		return nil
+	}
+
+	// stopped tasks are dropped since the cluster state service does not stream deletions
+	_, ok := cached.tasks[arn]
+	delete(cached.tasks, arn)
+	if !ok || task.StartedBy == "" {
+		return nil
+	}
+	return &ClusterStateChange{Cluster: name, StartedBy: task.StartedBy}
+}
+
+// cluster returns the cache entry for the cluster named name, creating it if needed
+func (c *clusterStateCache) cluster(name string) *cachedCluster {
+	cached, ok := c.clusters[name]
+	if !ok {
+		cached = &cachedCluster{}
+		c.clusters[name] = cached
+	}
+	return cached
+}
+
+// clusterName returns the name of the cluster from either its name or its ARN
+func clusterName(cluster string) string {
+	return cluster[strings.LastIndex(cluster, "/")+1:]
+}
@@ -0,0 +1,181 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package facade
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
+	"github.com/blox/blox/daemon-scheduler/pkg/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testClusterName = "cluster1"
+	clusterARN      = "arn:aws:ecs:us-east-1:123456789123:cluster/" + testClusterName
+	instanceARN1    = "arn:aws:ecs:us-east-1:123456789123:container-instance/instance1"
+	instanceARN2    = "arn:aws:ecs:us-east-1:123456789123:container-instance/instance2"
+	taskARN         = "arn:aws:ecs:us-east-1:123456789123:task/task1"
+	deploymentID    = "deployment1"
+)
+
+type ClusterStateCacheTestSuite struct {
+	suite.Suite
+	css       *mocks.MockClusterState
+	cache     ClusterStateCache
+	ctx       context.Context
+	cancel    context.CancelFunc
+	instances chan *models.ContainerInstance
+	tasks     chan *models.Task
+	changes   chan ClusterStateChange
+}
+
+func (suite *ClusterStateCacheTestSuite) SetupTest() {
+	mockCtrl := gomock.NewController(suite.T())
+	suite.css = mocks.NewMockClusterState(mockCtrl)
+
+	var err error
+	suite.cache, err = NewClusterStateCache(suite.css, time.Minute)
+	assert.Nil(suite.T(), err, "Cannot initialize ClusterStateCacheTestSuite")
+
+	suite.ctx, suite.cancel = context.WithCancel(context.Background())
+	suite.instances = make(chan *models.ContainerInstance)
+	suite.tasks = make(chan *models.Task)
+	suite.changes = make(chan ClusterStateChange)
+}
+
+func (suite *ClusterStateCacheTestSuite) TearDownTest() {
+	suite.cancel()
+}
+
+func TestClusterStateCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(ClusterStateCacheTestSuite))
+}
+
+func (suite *ClusterStateCacheTestSuite) TestNewClusterStateCacheInvalidMaxAge() {
+	_, err := NewClusterStateCache(suite.css, 0)
+	assert.Error(suite.T(), err, "Expected an error when max age is not positive")
+}
+
+func (suite *ClusterStateCacheTestSuite) TestListInstancesNotWatching() {
+	instances := []*models.ContainerInstance{instance(instanceARN1, activeInstanceStatus)}
+	suite.css.EXPECT().ListInstances(testClusterName).Return(instances, nil).Times(2)
+
+	for i := 0; i < 2; i++ {
+		listed, err := suite.cache.ListInstances(testClusterName)
+		assert.Nil(suite.T(), err, "Unexpected error listing instances")
+		assert.Equal(suite.T(), instances, listed, "Expected the instances from the cluster state")
+	}
+}
+
+func (suite *ClusterStateCacheTestSuite) TestInstanceRegistered() {
+	suite.watch()
+	suite.css.EXPECT().ListInstances(clusterARN).
+		Return([]*models.ContainerInstance{instance(instanceARN1, activeInstanceStatus)}, nil)
+
+	listed, err := suite.cache.ListInstances(clusterARN)
+	assert.Nil(suite.T(), err, "Unexpected error listing instances")
+	assert.Len(suite.T(), listed, 1, "Expected the instances from the cluster state")
+
+	suite.instances <- instance(instanceARN2, activeInstanceStatus)
+	change := <-suite.changes
+	assert.Equal(suite.T(), ClusterStateChange{Cluster: testClusterName}, change, "Expected a change in the cluster")
+
+	listed, err = suite.cache.ListInstances(testClusterName)
+	assert.Nil(suite.T(), err, "Unexpected error listing cached instances")
+	assert.Len(suite.T(), listed, 2, "Expected the registered instance to be cached")
+}
+
+func (suite *ClusterStateCacheTestSuite) TestClusterAppeared() {
+	suite.watch()
+
+	suite.instances <- instance(instanceARN1, activeInstanceStatus)
+	change := <-suite.changes
+	assert.Equal(suite.T(), ClusterStateChange{Cluster: testClusterName}, change,
+		"Expected a change when an instance registers in a cluster that is not scheduled yet")
+}
+
+func (suite *ClusterStateCacheTestSuite) TestTaskStopped() {
+	suite.watch()
+	suite.css.EXPECT().ListTasks(testClusterName).Return([]*models.Task{task("RUNNING")}, nil)
+
+	listed, err := suite.cache.ListTasks(testClusterName)
+	assert.Nil(suite.T(), err, "Unexpected error listing tasks")
+	assert.Len(suite.T(), listed, 1, "Expected the tasks from the cluster state")
+
+	suite.tasks <- task(stoppedTaskStatus)
+	change := <-suite.changes
+	assert.Equal(suite.T(), ClusterStateChange{Cluster: testClusterName, StartedBy: deploymentID}, change,
+		"Expected a change in the cluster")
+
+	listed, err = suite.cache.ListTasks(testClusterName)
+	assert.Nil(suite.T(), err, "Unexpected error listing cached tasks")
+	assert.Empty(suite.T(), listed, "Expected the stopped task to be removed from the cache")
+}
+
+// watch starts watching streams that forward the instances and tasks sent by the test
+func (suite *ClusterStateCacheTestSuite) watch() {
+	started := make(chan struct{}, 2)
+	streamedInstances, streamedTasks := suite.instances, suite.tasks
+	suite.css.EXPECT().StreamInstances(gomock.Any(), gomock.Any()).Do(
+		func(ctx context.Context, instances chan<- *models.ContainerInstance) {
+			started <- struct{}{}
+			for {
+				select {
+				case instance := <-streamedInstances:
+					instances <- instance
+				case <-ctx.Done():
+					return
+				}
+			}
+		}).Return(context.Canceled)
+	suite.css.EXPECT().StreamTasks(gomock.Any(), gomock.Any()).Do(
+		func(ctx context.Context, tasks chan<- *models.Task) {
+			started <- struct{}{}
+			for {
+				select {
+				case task := <-streamedTasks:
+					tasks <- task
+				case <-ctx.Done():
+					return
+				}
+			}
+		}).Return(context.Canceled)
+
+	go suite.cache.Watch(suite.ctx, suite.changes)
+	<-started
+	<-started
+}
+
+func instance(arn string, status string) *models.ContainerInstance {
+	return &models.ContainerInstance{
+		ClusterARN:           aws.String(clusterARN),
+		ContainerInstanceARN: aws.String(arn),
+		Status:               aws.String(status),
+	}
+}
+
+func task(desiredStatus string) *models.Task {
+	return &models.Task{
+		ClusterARN:    aws.String(clusterARN),
+		TaskARN:       aws.String(taskARN),
+		DesiredStatus: aws.String(desiredStatus),
+		StartedBy:     deploymentID,
+	}
+}
@@ -30,15 +30,21 @@ type ECS interface {
 		clusterArn string,
 		containerInstances []*string,
 		startedBy string,
-		taskDefinition string) (*ecs.StartTaskOutput, error)
+		taskDefinition string,
+		overrides *ecs.TaskOverride,
+		tags []*ecs.Tag) (*ecs.StartTaskOutput, error)
 
 	ListClusters() ([]*string, error)
 	DescribeCluster(cluster *string) (*ecs.Cluster, error)
+	// DescribeClusterTags returns the ECS tags of the cluster keyed by tag key
+	DescribeClusterTags(cluster string) (map[string]string, error)
 	DescribeTaskDefinition(taskDefinition *string) (*ecs.TaskDefinition, error)
 	ListTasks(cluster string, startedBy string) ([]*string, error)
+	ListStoppedTasks(cluster string, startedBy string) ([]*string, error)
 	ListTasksByInstance(cluster string, instanceARN string) ([]*string, error)
 	DescribeTasks(cluster string, tasks []*string) (*ecs.DescribeTasksOutput, error)
-	StopTask(clusterArn string, taskArn string) error
+	// StopTask stops the task, giving ECS the reason it was stopped for
+	StopTask(clusterArn string, taskArn string, reason string) error
 }
 
 type ecsClient struct {
@@ -55,7 +61,9 @@ func (c ecsClient) StartTask(
 	clusterArn string,
 	containerInstances []*string,
 	startedBy string,
-	taskDefinition string) (*ecs.StartTaskOutput, error) {
+	taskDefinition string,
+	overrides *ecs.TaskOverride,
+	tags []*ecs.Tag) (*ecs.StartTaskOutput, error) {
 	output := &ecs.StartTaskOutput{
 		Failures: []*ecs.Failure{},
 		Tasks:    []*ecs.Task{},
@@ -73,6 +81,8 @@ func (c ecsClient) StartTask(
 			ContainerInstances: partition,
 			StartedBy:          aws.String(startedBy),
 			TaskDefinition:     aws.String(taskDefinition),
+			Overrides:          overrides,
+			Tags:               tags,
 		}
 
 		resp, err := c.ecs.StartTask(input)
@@ -98,11 +108,32 @@ func (c ecsClient) DescribeCluster(cluster *string) (*ecs.Cluster, error) {
 	}
 
 	if len(resp.Clusters) == 0 {
-		return nil, errors.Wrapf(err, "Cluster with name %s is missing", *cluster)
+		return nil, errors.Errorf("Cluster with name %s is missing", *cluster)
 	}
 	return resp.Clusters[0], nil
 }
 
+func (c ecsClient) DescribeClusterTags(cluster string) (map[string]string, error) {
+	input := &ecs.DescribeClustersInput{
+		Clusters: []*string{aws.String(cluster)},
+		Include:  []*string{aws.String(ecs.ClusterFieldTags)},
+	}
+	resp, err := c.ecs.DescribeClusters(input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error calling DescribeClusters for the tags of cluster %s", cluster)
+	}
+
+	if len(resp.Clusters) == 0 {
+		return nil, errors.Errorf("Cluster with name %s is missing", cluster)
+	}
+
+	tags := make(map[string]string, len(resp.Clusters[0].Tags))
+	for _, tag := range resp.Clusters[0].Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
 func (c ecsClient) DescribeTaskDefinition(td *string) (*ecs.TaskDefinition, error) {
 	input := &ecs.DescribeTaskDefinitionInput{
 		TaskDefinition: td,
@@ -185,6 +216,30 @@ func (c ecsClient) ListTasks(cluster string, startedBy string) ([]*string, error
 	return resp.TaskArns, nil
 }
 
+// ListStoppedTasks returns every stopped task ECS still knows of, which can take more than one
+// page as stopped tasks are kept for a while
+func (c ecsClient) ListStoppedTasks(cluster string, startedBy string) ([]*string, error) {
+	input := &ecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		StartedBy:     aws.String(startedBy),
+		DesiredStatus: aws.String(ecs.DesiredStatusStopped),
+	}
+
+	tasks := []*string{}
+	for {
+		resp, err := c.ecs.ListTasks(input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to list stopped ECS tasks in cluster %v startedBy %v", cluster, startedBy)
+		}
+		tasks = append(tasks, resp.TaskArns...)
+
+		if aws.StringValue(resp.NextToken) == "" {
+			return tasks, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
 func (c ecsClient) ListTasksByInstance(cluster string, instanceARN string) ([]*string, error) {
 	input := &ecs.ListTasksInput{
 		Cluster:           aws.String(cluster),
@@ -199,10 +254,11 @@ func (c ecsClient) ListTasksByInstance(cluster string, instanceARN string) ([]*s
 	return resp.TaskArns, nil
 }
 
-func (c ecsClient) StopTask(clusterArn string, taskArn string) error {
+func (c ecsClient) StopTask(clusterArn string, taskArn string, reason string) error {
 	input := &ecs.StopTaskInput{
 		Cluster: aws.String(clusterArn),
 		Task:    aws.String(taskArn),
+		Reason:  aws.String(reason),
 	}
 	_, err := c.ecs.StopTask(input)
 	if err != nil {
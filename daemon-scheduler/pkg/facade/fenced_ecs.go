@@ -0,0 +1,53 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package facade
+
+import (
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+type fencedECS struct {
+	ECS
+	fence func() error
+}
+
+// NewFencedECS wraps an ECS facade so that tasks are only started or stopped while fence
+// returns no error. Other calls are passed through.
+func NewFencedECS(ecs ECS, fence func() error) ECS {
+	return fencedECS{
+		ECS:   ecs,
+		fence: fence,
+	}
+}
+
+func (c fencedECS) StartTask(
+	clusterArn string,
+	containerInstances []*string,
+	startedBy string,
+	taskDefinition string,
+	overrides *ecs.TaskOverride,
+	tags []*ecs.Tag) (*ecs.StartTaskOutput, error) {
+	if err := c.fence(); err != nil {
+		return nil, errors.Wrapf(err, "Refusing to start taskDefinition %v on cluster %v", taskDefinition, clusterArn)
+	}
+	return c.ECS.StartTask(clusterArn, containerInstances, startedBy, taskDefinition, overrides, tags)
+}
+
+func (c fencedECS) StopTask(clusterArn string, taskArn string, reason string) error {
+	if err := c.fence(); err != nil {
+		return errors.Wrapf(err, "Refusing to stop task %s in cluster %s", taskArn, clusterArn)
+	}
+	return c.ECS.StopTask(clusterArn, taskArn, reason)
+}
@@ -17,6 +17,8 @@
 package mocks
 
 import (
+	context "context"
+
 	models "github.com/blox/blox/cluster-state-service/swagger/v1/generated/models"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -63,3 +65,34 @@ func (_m *MockClusterState) ListTasks(cluster string) ([]*models.Task, error) {
 func (_mr *_MockClusterStateRecorder) ListTasks(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListTasks", arg0)
 }
+
+func (_m *MockClusterState) ListClusters() ([]string, error) {
+	ret := _m.ctrl.Call(_m, "ListClusters")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockClusterStateRecorder) ListClusters() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListClusters")
+}
+
+func (_m *MockClusterState) StreamInstances(ctx context.Context, instances chan<- *models.ContainerInstance) error {
+	ret := _m.ctrl.Call(_m, "StreamInstances", ctx, instances)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClusterStateRecorder) StreamInstances(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "StreamInstances", arg0, arg1)
+}
+
+func (_m *MockClusterState) StreamTasks(ctx context.Context, tasks chan<- *models.Task) error {
+	ret := _m.ctrl.Call(_m, "StreamTasks", ctx, tasks)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClusterStateRecorder) StreamTasks(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "StreamTasks", arg0, arg1)
+}
@@ -20,6 +20,7 @@ import (
 	context "context"
 	types "github.com/blox/blox/daemon-scheduler/pkg/types"
 	gomock "github.com/golang/mock/gomock"
+	time "time"
 )
 
 // Mock of Deployment interface
@@ -43,26 +44,26 @@ func (_m *MockDeployment) EXPECT() *_MockDeploymentRecorder {
 	return _m.recorder
 }
 
-func (_m *MockDeployment) CreateDeployment(ctx context.Context, environmentName string, token string) (*types.Deployment, error) {
-	ret := _m.ctrl.Call(_m, "CreateDeployment", ctx, environmentName, token)
+func (_m *MockDeployment) CreateDeployment(ctx context.Context, environmentName string, token string, notBefore time.Time) (*types.Deployment, error) {
+	ret := _m.ctrl.Call(_m, "CreateDeployment", ctx, environmentName, token, notBefore)
 	ret0, _ := ret[0].(*types.Deployment)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (_mr *_MockDeploymentRecorder) CreateDeployment(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateDeployment", arg0, arg1, arg2)
+func (_mr *_MockDeploymentRecorder) CreateDeployment(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateDeployment", arg0, arg1, arg2, arg3)
 }
 
-func (_m *MockDeployment) CreateSubDeployment(ctx context.Context, environmentName string, instanceARNs []*string) (*types.Deployment, error) {
-	ret := _m.ctrl.Call(_m, "CreateSubDeployment", ctx, environmentName, instanceARNs)
+func (_m *MockDeployment) CreateSubDeployment(ctx context.Context, environmentName string, cluster string, instanceARNs []*string) (*types.Deployment, error) {
+	ret := _m.ctrl.Call(_m, "CreateSubDeployment", ctx, environmentName, cluster, instanceARNs)
 	ret0, _ := ret[0].(*types.Deployment)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (_mr *_MockDeploymentRecorder) CreateSubDeployment(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateSubDeployment", arg0, arg1, arg2)
+func (_mr *_MockDeploymentRecorder) CreateSubDeployment(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateSubDeployment", arg0, arg1, arg2, arg3)
 }
 
 func (_m *MockDeployment) GetDeployment(ctx context.Context, environmentName string, id string) (*types.Deployment, error) {
@@ -108,3 +109,58 @@ func (_m *MockDeployment) ListDeploymentsSortedReverseChronologically(ctx contex
 func (_mr *_MockDeploymentRecorder) ListDeploymentsSortedReverseChronologically(arg0, arg1 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListDeploymentsSortedReverseChronologically", arg0, arg1)
 }
+
+func (_m *MockDeployment) ListDeploymentInstances(ctx context.Context, environmentName string, id string) ([]types.DeploymentInstance, error) {
+	ret := _m.ctrl.Call(_m, "ListDeploymentInstances", ctx, environmentName, id)
+	ret0, _ := ret[0].([]types.DeploymentInstance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDeploymentRecorder) ListDeploymentInstances(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListDeploymentInstances", arg0, arg1, arg2)
+}
+
+func (_m *MockDeployment) PlanCapacity(ctx context.Context, env types.Environment, cluster string, instanceARNs []string) (*types.CapacityPlan, error) {
+	ret := _m.ctrl.Call(_m, "PlanCapacity", ctx, env, cluster, instanceARNs)
+	ret0, _ := ret[0].(*types.CapacityPlan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDeploymentRecorder) PlanCapacity(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PlanCapacity", arg0, arg1, arg2, arg3)
+}
+
+func (_m *MockDeployment) PauseDeployment(ctx context.Context, environmentName string, id string) (*types.Deployment, error) {
+	ret := _m.ctrl.Call(_m, "PauseDeployment", ctx, environmentName, id)
+	ret0, _ := ret[0].(*types.Deployment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDeploymentRecorder) PauseDeployment(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PauseDeployment", arg0, arg1, arg2)
+}
+
+func (_m *MockDeployment) ResumeDeployment(ctx context.Context, environmentName string, id string) (*types.Deployment, error) {
+	ret := _m.ctrl.Call(_m, "ResumeDeployment", ctx, environmentName, id)
+	ret0, _ := ret[0].(*types.Deployment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDeploymentRecorder) ResumeDeployment(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ResumeDeployment", arg0, arg1, arg2)
+}
+
+func (_m *MockDeployment) CancelDeployment(ctx context.Context, environmentName string, id string, revert bool) (*types.Deployment, error) {
+	ret := _m.ctrl.Call(_m, "CancelDeployment", ctx, environmentName, id, revert)
+	ret0, _ := ret[0].(*types.Deployment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDeploymentRecorder) CancelDeployment(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CancelDeployment", arg0, arg1, arg2, arg3)
+}
@@ -53,3 +53,14 @@ func (_m *MockDeploymentWorker) UpdateInProgressDeployment(ctx context.Context,
 func (_mr *_MockDeploymentWorkerRecorder) UpdateInProgressDeployment(arg0, arg1 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "UpdateInProgressDeployment", arg0, arg1)
 }
+
+func (_m *MockDeploymentWorker) StartPendingDeployment(ctx context.Context, environmentName string) (*types.Deployment, error) {
+	ret := _m.ctrl.Call(_m, "StartPendingDeployment", ctx, environmentName)
+	ret0, _ := ret[0].(*types.Deployment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDeploymentWorkerRecorder) StartPendingDeployment(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "StartPendingDeployment", arg0, arg1)
+}
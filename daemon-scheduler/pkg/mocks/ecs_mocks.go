@@ -42,15 +42,15 @@ func (_m *MockECS) EXPECT() *_MockECSRecorder {
 	return _m.recorder
 }
 
-func (_m *MockECS) StartTask(clusterArn string, containerInstances []*string, startedBy string, taskDefinition string) (*ecs.StartTaskOutput, error) {
-	ret := _m.ctrl.Call(_m, "StartTask", clusterArn, containerInstances, startedBy, taskDefinition)
+func (_m *MockECS) StartTask(clusterArn string, containerInstances []*string, startedBy string, taskDefinition string, overrides *ecs.TaskOverride, tags []*ecs.Tag) (*ecs.StartTaskOutput, error) {
+	ret := _m.ctrl.Call(_m, "StartTask", clusterArn, containerInstances, startedBy, taskDefinition, overrides, tags)
 	ret0, _ := ret[0].(*ecs.StartTaskOutput)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (_mr *_MockECSRecorder) StartTask(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "StartTask", arg0, arg1, arg2, arg3)
+func (_mr *_MockECSRecorder) StartTask(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "StartTask", arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
 func (_m *MockECS) ListClusters() ([]*string, error) {
@@ -75,6 +75,17 @@ func (_mr *_MockECSRecorder) DescribeCluster(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DescribeCluster", arg0)
 }
 
+func (_m *MockECS) DescribeClusterTags(cluster string) (map[string]string, error) {
+	ret := _m.ctrl.Call(_m, "DescribeClusterTags", cluster)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockECSRecorder) DescribeClusterTags(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DescribeClusterTags", arg0)
+}
+
 func (_m *MockECS) DescribeTaskDefinition(taskDefinition *string) (*ecs.TaskDefinition, error) {
 	ret := _m.ctrl.Call(_m, "DescribeTaskDefinition", taskDefinition)
 	ret0, _ := ret[0].(*ecs.TaskDefinition)
@@ -97,6 +108,17 @@ func (_mr *_MockECSRecorder) ListTasks(arg0, arg1 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListTasks", arg0, arg1)
 }
 
+func (_m *MockECS) ListStoppedTasks(cluster string, startedBy string) ([]*string, error) {
+	ret := _m.ctrl.Call(_m, "ListStoppedTasks", cluster, startedBy)
+	ret0, _ := ret[0].([]*string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockECSRecorder) ListStoppedTasks(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListStoppedTasks", arg0, arg1)
+}
+
 func (_m *MockECS) ListTasksByInstance(cluster string, instanceARN string) ([]*string, error) {
 	ret := _m.ctrl.Call(_m, "ListTasksByInstance", cluster, instanceARN)
 	ret0, _ := ret[0].([]*string)
@@ -119,12 +141,12 @@ func (_mr *_MockECSRecorder) DescribeTasks(arg0, arg1 interface{}) *gomock.Call
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DescribeTasks", arg0, arg1)
 }
 
-func (_m *MockECS) StopTask(clusterArn string, taskArn string) error {
-	ret := _m.ctrl.Call(_m, "StopTask", clusterArn, taskArn)
+func (_m *MockECS) StopTask(clusterArn string, taskArn string, reason string) error {
+	ret := _m.ctrl.Call(_m, "StopTask", clusterArn, taskArn, reason)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-func (_mr *_MockECSRecorder) StopTask(arg0, arg1 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "StopTask", arg0, arg1)
+func (_mr *_MockECSRecorder) StopTask(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "StopTask", arg0, arg1, arg2)
 }
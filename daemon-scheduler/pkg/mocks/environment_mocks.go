@@ -20,6 +20,7 @@ import (
 	context "context"
 	types "github.com/blox/blox/daemon-scheduler/pkg/types"
 	gomock "github.com/golang/mock/gomock"
+	time "time"
 )
 
 // Mock of Environment interface
@@ -43,15 +44,15 @@ func (_m *MockEnvironment) EXPECT() *_MockEnvironmentRecorder {
 	return _m.recorder
 }
 
-func (_m *MockEnvironment) CreateEnvironment(ctx context.Context, name string, taskDefinition string, cluster string) (*types.Environment, error) {
-	ret := _m.ctrl.Call(_m, "CreateEnvironment", ctx, name, taskDefinition, cluster)
+func (_m *MockEnvironment) CreateEnvironment(ctx context.Context, name string, taskDefinition string, cluster string, selector types.ClusterSelector, constraints types.PlacementConstraints, strategy types.RolloutStrategy, policy types.RollbackPolicy, capacity types.CapacityPolicy, overrides types.TaskOverrides, windows []types.MaintenanceWindow, draining types.DrainingPolicy, scheduling types.SchedulingStrategy, restart types.RestartPolicy) (*types.Environment, error) {
+	ret := _m.ctrl.Call(_m, "CreateEnvironment", ctx, name, taskDefinition, cluster, selector, constraints, strategy, policy, capacity, overrides, windows, draining, scheduling, restart)
 	ret0, _ := ret[0].(*types.Environment)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (_mr *_MockEnvironmentRecorder) CreateEnvironment(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateEnvironment", arg0, arg1, arg2, arg3)
+func (_mr *_MockEnvironmentRecorder) CreateEnvironment(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12, arg13 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateEnvironment", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12, arg13)
 }
 
 func (_m *MockEnvironment) GetEnvironment(ctx context.Context, name string) (*types.Environment, error) {
@@ -75,6 +76,27 @@ func (_mr *_MockEnvironmentRecorder) DeleteEnvironment(arg0, arg1 interface{}) *
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteEnvironment", arg0, arg1)
 }
 
+func (_m *MockEnvironment) DrainEnvironment(ctx context.Context, name string, timeout time.Duration) (*types.Environment, error) {
+	ret := _m.ctrl.Call(_m, "DrainEnvironment", ctx, name, timeout)
+	ret0, _ := ret[0].(*types.Environment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockEnvironmentRecorder) DrainEnvironment(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DrainEnvironment", arg0, arg1, arg2)
+}
+
+func (_m *MockEnvironment) DeleteDrainedEnvironment(ctx context.Context, environment types.Environment) error {
+	ret := _m.ctrl.Call(_m, "DeleteDrainedEnvironment", ctx, environment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockEnvironmentRecorder) DeleteDrainedEnvironment(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteDrainedEnvironment", arg0, arg1)
+}
+
 func (_m *MockEnvironment) ListEnvironments(ctx context.Context) ([]types.Environment, error) {
 	ret := _m.ctrl.Call(_m, "ListEnvironments", ctx)
 	ret0, _ := ret[0].([]types.Environment)
@@ -97,6 +119,18 @@ func (_mr *_MockEnvironmentRecorder) FilterEnvironments(arg0, arg1, arg2 interfa
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "FilterEnvironments", arg0, arg1, arg2)
 }
 
+func (_m *MockEnvironment) UpdateEnvironmentSettings(ctx context.Context, name string, token string, update types.EnvironmentUpdate, deploy bool) (*types.Environment, *types.Deployment, error) {
+	ret := _m.ctrl.Call(_m, "UpdateEnvironmentSettings", ctx, name, token, update, deploy)
+	ret0, _ := ret[0].(*types.Environment)
+	ret1, _ := ret[1].(*types.Deployment)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+func (_mr *_MockEnvironmentRecorder) UpdateEnvironmentSettings(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "UpdateEnvironmentSettings", arg0, arg1, arg2, arg3, arg4)
+}
+
 func (_m *MockEnvironment) AddPendingDeployment(ctx context.Context, environment types.Environment, deployment types.Deployment) (*types.Environment, error) {
 	ret := _m.ctrl.Call(_m, "AddPendingDeployment", ctx, environment, deployment)
 	ret0, _ := ret[0].(*types.Environment)
@@ -118,3 +152,51 @@ func (_m *MockEnvironment) UpdateDeployment(ctx context.Context, environment typ
 func (_mr *_MockEnvironmentRecorder) UpdateDeployment(arg0, arg1, arg2 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "UpdateDeployment", arg0, arg1, arg2)
 }
+
+func (_m *MockEnvironment) UpdateEnvironment(ctx context.Context, name string, update func(*types.Environment) error) (*types.Environment, error) {
+	ret := _m.ctrl.Call(_m, "UpdateEnvironment", ctx, name, update)
+	ret0, _ := ret[0].(*types.Environment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockEnvironmentRecorder) UpdateEnvironment(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "UpdateEnvironment", arg0, arg1, arg2)
+}
+
+// Mock of Notifier interface
+type MockNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *_MockNotifierRecorder
+}
+
+// Recorder for MockNotifier (not exported)
+type _MockNotifierRecorder struct {
+	mock *MockNotifier
+}
+
+func NewMockNotifier(ctrl *gomock.Controller) *MockNotifier {
+	mock := &MockNotifier{ctrl: ctrl}
+	mock.recorder = &_MockNotifierRecorder{mock}
+	return mock
+}
+
+func (_m *MockNotifier) EXPECT() *_MockNotifierRecorder {
+	return _m.recorder
+}
+
+func (_m *MockNotifier) EnvironmentUpdated(ctx context.Context, before types.Environment, after types.Environment) {
+	_m.ctrl.Call(_m, "EnvironmentUpdated", ctx, before, after)
+}
+
+func (_mr *_MockNotifierRecorder) EnvironmentUpdated(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "EnvironmentUpdated", arg0, arg1, arg2)
+}
+
+func (_m *MockNotifier) EnvironmentDeleted(ctx context.Context, name string) {
+	_m.ctrl.Call(_m, "EnvironmentDeleted", ctx, name)
+}
+
+func (_mr *_MockNotifierRecorder) EnvironmentDeleted(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "EnvironmentDeleted", arg0, arg1)
+}
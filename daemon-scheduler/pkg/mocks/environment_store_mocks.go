@@ -84,3 +84,13 @@ func (_m *MockEnvironmentStore) ListEnvironments(ctx context.Context) ([]types.E
 func (_mr *_MockEnvironmentStoreRecorder) ListEnvironments(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListEnvironments", arg0)
 }
+
+func (_m *MockEnvironmentStore) MigrateEnvironments(ctx context.Context) error {
+	ret := _m.ctrl.Call(_m, "MigrateEnvironments", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockEnvironmentStoreRecorder) MigrateEnvironments(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "MigrateEnvironments", arg0)
+}
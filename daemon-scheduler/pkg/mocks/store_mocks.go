@@ -18,6 +18,7 @@ package mocks
 
 import (
 	context "context"
+	types "github.com/blox/blox/daemon-scheduler/pkg/types"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -83,3 +84,66 @@ func (_m *MockDataStore) GetWithPrefix(ctx context.Context, keyPrefix string) (m
 func (_mr *_MockDataStoreRecorder) GetWithPrefix(arg0, arg1 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "GetWithPrefix", arg0, arg1)
 }
+
+func (_m *MockDataStore) GetWithModRevision(ctx context.Context, key string) (map[string]types.RevisionedValue, error) {
+	ret := _m.ctrl.Call(_m, "GetWithModRevision", ctx, key)
+	ret0, _ := ret[0].(map[string]types.RevisionedValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDataStoreRecorder) GetWithModRevision(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "GetWithModRevision", arg0, arg1)
+}
+
+func (_m *MockDataStore) GetWithPrefixAndModRevision(ctx context.Context, keyPrefix string) (map[string]types.RevisionedValue, error) {
+	ret := _m.ctrl.Call(_m, "GetWithPrefixAndModRevision", ctx, keyPrefix)
+	ret0, _ := ret[0].(map[string]types.RevisionedValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDataStoreRecorder) GetWithPrefixAndModRevision(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "GetWithPrefixAndModRevision", arg0, arg1)
+}
+
+func (_m *MockDataStore) PutIfModRevision(ctx context.Context, key string, value string, modRevision int64) error {
+	ret := _m.ctrl.Call(_m, "PutIfModRevision", ctx, key, value, modRevision)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDataStoreRecorder) PutIfModRevision(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PutIfModRevision", arg0, arg1, arg2, arg3)
+}
+
+func (_m *MockDataStore) DeleteIfModRevision(ctx context.Context, key string, modRevision int64) error {
+	ret := _m.ctrl.Call(_m, "DeleteIfModRevision", ctx, key, modRevision)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDataStoreRecorder) DeleteIfModRevision(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteIfModRevision", arg0, arg1, arg2)
+}
+
+func (_m *MockDataStore) GetAllWithModRevision(ctx context.Context, keys []string, keyPrefixes []string) (map[string]types.RevisionedValue, error) {
+	ret := _m.ctrl.Call(_m, "GetAllWithModRevision", ctx, keys, keyPrefixes)
+	ret0, _ := ret[0].(map[string]types.RevisionedValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDataStoreRecorder) GetAllWithModRevision(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "GetAllWithModRevision", arg0, arg1, arg2)
+}
+
+func (_m *MockDataStore) WriteIfModRevision(ctx context.Context, key string, modRevision int64, puts map[string]string, deletes []string) error {
+	ret := _m.ctrl.Call(_m, "WriteIfModRevision", ctx, key, modRevision, puts, deletes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDataStoreRecorder) WriteIfModRevision(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "WriteIfModRevision", arg0, arg1, arg2, arg3, arg4)
+}
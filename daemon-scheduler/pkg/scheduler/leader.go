@@ -0,0 +1,133 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/config"
+	"github.com/blox/blox/daemon-scheduler/pkg/deployment"
+	"github.com/blox/blox/daemon-scheduler/pkg/engine"
+	"github.com/blox/blox/daemon-scheduler/pkg/facade"
+	log "github.com/cihub/seelog"
+)
+
+// tunableScheduler holds the scheduler settings that can change while it is running
+type tunableScheduler interface {
+	SetTickerDuration(tickerDuration time.Duration) error
+	SetTrackingInfoTTL(ttl time.Duration) error
+	SetClusters(clusters []string)
+}
+
+// leaderEngine runs the scheduler, monitor and dispatcher while this replica is the leader
+type leaderEngine struct {
+	environment      deployment.Environment
+	deploymentSvc    deployment.Deployment
+	ecs              facade.ECS
+	css              facade.ClusterStateCache
+	deploymentWorker deployment.DeploymentWorker
+
+	lock      sync.Mutex
+	settings  config.Reloadable
+	scheduler tunableScheduler
+	monitor   engine.Monitor
+}
+
+func newLeaderEngine(environment deployment.Environment, deploymentSvc deployment.Deployment,
+	ecs facade.ECS, css facade.ClusterStateCache, deploymentWorker deployment.DeploymentWorker,
+	settings config.Reloadable) *leaderEngine {
+	return &leaderEngine{
+		environment:      environment,
+		deploymentSvc:    deploymentSvc,
+		ecs:              ecs,
+		css:              css,
+		deploymentWorker: deploymentWorker,
+		settings:         settings,
+	}
+}
+
+// lead starts the engine and blocks until ctx is cancelled and the engine has stopped. The
+// scheduler, monitor and cluster state streams stop as soon as ctx is cancelled, while the
+// dispatcher keeps handling the events it has already received until its input is closed.
+func (l *leaderEngine) lead(ctx context.Context) {
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+
+	input := make(chan engine.Event)
+	output := make(chan engine.Event)
+	dispatcher := engine.NewDispatcher(workCtx, l.environment, l.deploymentSvc, l.ecs, l.css, l.deploymentWorker, input, output)
+	dispatcher.Start()
+	go drainEvents(workCtx, output)
+
+	scheduler := engine.NewScheduler(ctx, input, l.environment, l.deploymentSvc, l.css, l.ecs)
+	monitor := engine.NewMonitor(ctx, l.environment, input)
+
+	// the scheduler reacts to cluster state changes between its periodic runs
+	changes := make(chan facade.ClusterStateChange)
+	var watching sync.WaitGroup
+	watching.Add(1)
+	go func() {
+		defer watching.Done()
+		l.css.Watch(ctx, changes)
+	}()
+
+	l.lock.Lock()
+	applySchedulerSettings(scheduler, l.settings)
+	scheduler.Start()
+	scheduler.Watch(changes)
+	monitor.InProgressMonitorLoop(l.settings.MonitorInterval)
+	monitor.PendingMonitorLoop(l.settings.PendingMonitorInterval)
+	l.scheduler, l.monitor = scheduler, monitor
+	l.lock.Unlock()
+
+	<-ctx.Done()
+
+	l.lock.Lock()
+	l.scheduler, l.monitor = nil, nil
+	l.lock.Unlock()
+
+	watching.Wait()
+	scheduler.Wait()
+	monitor.Wait()
+	// nothing sends on input once the scheduler and monitor have stopped
+	close(input)
+	dispatcher.Wait()
+}
+
+// apply records reloaded settings and applies them to the running engine, if any
+func (l *leaderEngine) apply(settings config.Reloadable) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.settings = settings
+	if l.scheduler == nil {
+		return
+	}
+	applySchedulerSettings(l.scheduler, settings)
+	if err := l.monitor.SetTickerDuration(settings.MonitorInterval, settings.PendingMonitorInterval); err != nil {
+		log.Errorf("Could not update the monitor intervals: %+v", err)
+	}
+}
+
+func applySchedulerSettings(scheduler tunableScheduler, settings config.Reloadable) {
+	if err := scheduler.SetTickerDuration(settings.SchedulerInterval); err != nil {
+		log.Errorf("Could not set the scheduler interval: %+v", err)
+	}
+	if err := scheduler.SetTrackingInfoTTL(settings.TrackingInfoTTL); err != nil {
+		log.Errorf("Could not set the tracking info ttl: %+v", err)
+	}
+	scheduler.SetClusters(settings.Clusters)
+}
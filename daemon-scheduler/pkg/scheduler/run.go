@@ -17,28 +17,48 @@ import (
 	"context"
 
 	"github.com/blox/blox/daemon-scheduler/pkg/api/v1"
+	"github.com/blox/blox/daemon-scheduler/pkg/auth"
 	"github.com/blox/blox/daemon-scheduler/pkg/clients"
 	"github.com/blox/blox/daemon-scheduler/pkg/config"
 	"github.com/blox/blox/daemon-scheduler/pkg/deployment"
+	"github.com/blox/blox/daemon-scheduler/pkg/election"
 	"github.com/blox/blox/daemon-scheduler/pkg/engine"
 	"github.com/blox/blox/daemon-scheduler/pkg/facade"
+	"github.com/blox/blox/daemon-scheduler/pkg/httpclient"
 	"github.com/blox/blox/daemon-scheduler/pkg/store"
+	"github.com/blox/blox/daemon-scheduler/pkg/webhook"
+	"github.com/blox/blox/shared/ecsclient"
+	"github.com/blox/blox/shared/shutdown"
 	log "github.com/cihub/seelog"
 	httptransport "github.com/go-openapi/runtime/client"
 	"github.com/pkg/errors"
 	"github.com/urfave/negroni"
 
+	"net"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 )
 
 const (
-	serverReadTimeout  = 10 * time.Second
-	serverWriteTimeout = 10 * time.Second
+	// DefaultServerReadTimeout and DefaultServerWriteTimeout are the default maximum durations
+	// for reading a request and writing a response
+	DefaultServerReadTimeout  = 10 * time.Second
+	DefaultServerWriteTimeout = 10 * time.Second
+
+	// clusterTagsMaxAge is how long the tags of a cluster are used to select it before they are
+	// described again, so that a tag change is picked up without describing clusters every run
+	clusterTagsMaxAge = 5 * time.Minute
 )
 
-// Run kickstarts the daemon scheduler service.
-func Run(schedulerBindAddr string, clusterStateServiceEndpoint string) error {
+// Run kickstarts the daemon scheduler service. Replicas sharing an etcd cluster elect a leader
+// that runs the scheduler, monitors and dispatcher, while every replica serves reads and
+// forwards writes to the leader. On SIGINT or SIGTERM it drains in-flight requests, stops the
+// scheduler and monitors and waits for events already dispatched to be handled before giving
+// up the leadership, then waits for the webhook deliveries in progress. Settings received on
+// reloads are applied while the scheduler is running.
+func Run(schedulerBindAddr string, clusterStateServiceEndpoint string, reloads <-chan config.Reloadable) error {
 	if schedulerBindAddr == "" {
 		return errors.Errorf("The address for scheduler endpoint is not set")
 	}
@@ -46,7 +66,17 @@ func Run(schedulerBindAddr string, clusterStateServiceEndpoint string) error {
 		return errors.Errorf("The address for cluster state service endpoint is not set")
 	}
 
-	etcdClient, err := clients.NewEtcdClient(config.EtcdEndpoints)
+	etcdClient, err := clients.NewEtcdClient(clients.EtcdConfig{
+		Endpoints:      config.EtcdEndpoints,
+		CAFile:         config.EtcdCAFile,
+		CertFile:       config.EtcdCertFile,
+		KeyFile:        config.EtcdKeyFile,
+		Username:       config.EtcdUsername,
+		Password:       config.EtcdPassword,
+		DialTimeout:    config.EtcdDialTimeout,
+		RequestTimeout: config.EtcdRequestTimeout,
+		KeyPrefix:      config.EtcdKeyPrefix,
+	})
 	if err != nil {
 		log.Criticalf("Could not start etcd: %+v", err)
 		return err
@@ -54,19 +84,34 @@ func Run(schedulerBindAddr string, clusterStateServiceEndpoint string) error {
 	defer etcdClient.Close()
 
 	// initialize the datastore
-	datastore, err := store.NewDataStore(etcdClient)
+	datastore, err := store.NewDataStore(clients.NewNamespacedEtcd(etcdClient, config.EtcdKeyPrefix, config.EtcdRequestTimeout))
 	if err != nil {
 		log.Criticalf("Could not initialize the datastore: %+v", err)
 		return err
 	}
 
-	environmentStore, err := store.NewEnvironmentStore(datastore)
+	environmentStore, err := store.NewEnvironmentStore(datastore, config.DeploymentRetention)
 	if err != nil {
 		log.Criticalf("Could not initialize the environment store: %+v", err)
 		return err
 	}
 
-	ecsClient, err := clients.NewECSClient()
+	err = environmentStore.MigrateEnvironments(context.Background())
+	if err != nil {
+		log.Criticalf("Could not migrate the stored environments: %+v", err)
+		return err
+	}
+
+	// the ECS requests are throttled to stay within the ECS rate limits, and their metrics are
+	// served with the other expvar variables
+	ecsThrottle, err := ecsclient.NewThrottle(config.ECSThrottle)
+	if err != nil {
+		log.Criticalf("Could not initialize the ecs throttle: %+v", err)
+		return err
+	}
+	ecsclient.PublishMetrics(ecsThrottle)
+
+	ecsClient, err := clients.NewECSClient(ecsThrottle)
 	if err != nil {
 		log.Criticalf("Could not initialize ecs client: %+v", err)
 		return err
@@ -76,52 +121,179 @@ func Run(schedulerBindAddr string, clusterStateServiceEndpoint string) error {
 	cssTransport := httptransport.New(clusterStateServiceEndpoint, "/v1", []string{"http"})
 	cssClient.SetTransport(cssTransport)
 
-	ecs := facade.NewECS(ecsClient)
-	css, err := facade.NewClusterState(cssClient)
+	ecs, err := facade.NewClusterTagCache(facade.NewECS(ecsClient), clusterTagsMaxAge)
+	if err != nil {
+		log.Criticalf("Could not initialize the cluster tag cache: %+v", err)
+		return err
+	}
+	clusterState, err := facade.NewClusterState(cssClient)
 	if err != nil {
 		log.Criticalf("Could not initialize cluster state: %+v", err)
 		return err
 	}
 
-	environment, err := deployment.NewEnvironment(environmentStore)
+	// the leader streams cluster state changes into the cache, which is refreshed at the
+	// interval between full scheduler runs
+	css, err := facade.NewClusterStateCache(clusterState, config.SchedulerInterval)
+	if err != nil {
+		log.Criticalf("Could not initialize cluster state cache: %+v", err)
+		return err
+	}
+
+	webhookStore, err := store.NewWebhookStore(datastore, store.DefaultWebhookDeliveryRetention)
+	if err != nil {
+		log.Criticalf("Could not initialize the webhook store: %+v", err)
+		return err
+	}
+
+	// changes to environments are posted to their webhooks by whichever replica makes them
+	notifier, err := webhook.NewNotifier(webhookStore, httpclient.New())
+	if err != nil {
+		log.Criticalf("Could not initialize the webhook notifier: %+v", err)
+		return err
+	}
+
+	environment, err := deployment.NewEnvironment(environmentStore, notifier)
 	if err != nil {
 		log.Criticalf("Could not initialize environment: %+v", err)
 		return err
 	}
 
+	webhookSvc, err := deployment.NewWebhook(environmentStore, webhookStore)
+	if err != nil {
+		log.Criticalf("Could not initialize webhook: %+v", err)
+		return err
+	}
+
+	address, err := advertiseAddress(schedulerBindAddr)
+	if err != nil {
+		log.Criticalf("Could not determine the advertised address: %+v", err)
+		return err
+	}
+	elector, err := election.NewElector(etcdClient, config.EtcdKeyPrefix+election.ElectionKey, address,
+		config.LeaderSessionTTL, config.EtcdRequestTimeout)
+	if err != nil {
+		log.Criticalf("Could not initialize the leader election: %+v", err)
+		return err
+	}
+
+	// a replica that lost the leadership must not start or stop tasks even if it has not
+	// noticed yet
+	ecs = facade.NewFencedECS(ecs, elector.Fence)
+
 	deploymentSvc := deployment.NewDeployment(environment, css, ecs)
 	deploymentWorker := deployment.NewDeploymentWorker(environment, deploymentSvc, ecs, css)
 
-	ctx := context.Background()
-	input := make(chan engine.Event)
-	output := make(chan engine.Event)
-	dispatcher := engine.NewDispatcher(ctx, environment, deploymentSvc, ecs, css, deploymentWorker, input, output)
-	dispatcher.Start()
-	scheduler := engine.NewScheduler(ctx, input, environment, deploymentSvc, css, ecs)
-	scheduler.Start()
+	leader := newLeaderEngine(environment, deploymentSvc, ecs, css, deploymentWorker, config.Reloadable{
+		SchedulerInterval:      config.SchedulerInterval,
+		MonitorInterval:        config.MonitorInterval,
+		PendingMonitorInterval: config.PendingMonitorInterval,
+		TrackingInfoTTL:        config.TrackingInfoTTL,
+		Clusters:               config.Clusters,
+	})
 
-	monitor := engine.NewMonitor(ctx, environment, input)
-	monitor.InProgressMonitorLoop(engine.InProgressMonitorTickerDuration)
+	// only the leader runs the engine, every replica serves the API
+	electionCtx, cancelElection := context.WithCancel(context.Background())
+	defer cancelElection()
+	var campaigning sync.WaitGroup
+	campaigning.Add(1)
+	go func() {
+		defer campaigning.Done()
+		elector.Run(electionCtx, leader.lead)
+	}()
 
-	api := v1.NewAPI(environment, deploymentSvc, ecs)
+	go func() {
+		for {
+			select {
+			case reloadable := <-reloads:
+				leader.apply(reloadable)
+				log.Infof("Applied reloaded settings: %+v", reloadable)
+			case <-electionCtx.Done():
+				return
+			}
+		}
+	}()
+
+	api := v1.NewAPI(environment, deploymentSvc, webhookSvc, ecs, engine.NewPlanner(environment, deploymentSvc, css, ecs))
 
 	// start server
 	router := v1.NewRouter(api)
 
+	// Requests are authorized before being forwarded to the leader, so a
+	// follower never proxies a request it would have rejected.
 	n := negroni.Classic()
+	if config.AuthConfigFile != "" {
+		authConfig, err := auth.LoadConfig(config.AuthConfigFile)
+		if err != nil {
+			log.Criticalf("Could not load the auth config: %+v", err)
+			return err
+		}
+		n.Use(auth.NewMiddleware(authConfig.Authenticator(), authConfig.Policy, auth.NewLogAuditor(), v1.NewActionResolver(router)))
+	} else {
+		log.Warn("No auth config provided, the scheduler API is not authenticated")
+	}
+	n.Use(election.NewForwarder(elector, address))
 	n.UseHandler(router)
 
 	s := &http.Server{
 		Addr:         schedulerBindAddr,
 		Handler:      n,
-		ReadTimeout:  serverReadTimeout,
-		WriteTimeout: serverWriteTimeout,
+		ReadTimeout:  config.ServerReadTimeout,
+		WriteTimeout: config.ServerWriteTimeout,
 	}
 
-	err = s.ListenAndServe()
+	err = shutdown.ServeUntilSignal(s, func(ctx context.Context) error {
+		// the leadership is given up once the engine has stopped, which ECS requests waiting for
+		// the rate limit do not hold up
+		cancelElection()
+		ecsThrottle.Stop()
+		err := shutdown.WaitFor(ctx, campaigning.Wait)
+		// webhook deliveries still being retried are abandoned once ctx is done
+		stopErr := notifier.Stop(ctx)
+		if err != nil {
+			return err
+		}
+		return stopErr
+	})
 	if err != nil {
-		log.Criticalf("Could not start the server: %+v", err)
+		log.Criticalf("Error serving requests: %+v", err)
 	}
 
 	return err
 }
+
+// advertiseAddress returns the URL other replicas forward writes to, derived from the bind
+// address and host name unless it is configured
+func advertiseAddress(bindAddr string) (string, error) {
+	if config.AdvertiseAddress != "" {
+		return config.AdvertiseAddress, nil
+	}
+
+	host, port, err := net.SplitHostPort(bindAddr)
+	if err != nil {
+		return "", errors.Wrapf(err, "Invalid bind address %s", bindAddr)
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host, err = os.Hostname()
+		if err != nil {
+			return "", errors.Wrapf(err, "Could not get the host name")
+		}
+	}
+	return "http://" + net.JoinHostPort(host, port), nil
+}
+
+// drainEvents consumes the results published by the dispatcher so that its workers never block
+func drainEvents(ctx context.Context, events <-chan engine.Event) {
+	for {
+		select {
+		case event := <-events:
+			if errorEvent, ok := event.(engine.ErrorEvent); ok {
+				log.Errorf("Error handling event: %+v", errorEvent.Error)
+				continue
+			}
+			log.Debugf("Dispatcher published event: %s", event.GetType())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scheduler
+
+import (
+	"os"
+	"testing"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvertiseAddressConfigured(t *testing.T) {
+	defer func(address string) { config.AdvertiseAddress = address }(config.AdvertiseAddress)
+	config.AdvertiseAddress = "https://scheduler-1:2000"
+
+	address, err := advertiseAddress(":2000")
+	assert.Nil(t, err, "Unexpected error getting the advertised address")
+	assert.Equal(t, "https://scheduler-1:2000", address, "Expected the configured address to be used")
+}
+
+func TestAdvertiseAddressFromBindAddress(t *testing.T) {
+	address, err := advertiseAddress("10.0.0.1:2000")
+	assert.Nil(t, err, "Unexpected error getting the advertised address")
+	assert.Equal(t, "http://10.0.0.1:2000", address, "Expected the bind address to be used")
+}
+
+func TestAdvertiseAddressUnspecifiedHost(t *testing.T) {
+	hostname, _ := os.Hostname()
+	for _, bindAddr := range []string{":2000", "0.0.0.0:2000"} {
+		address, err := advertiseAddress(bindAddr)
+		assert.Nil(t, err, "Unexpected error getting the advertised address")
+		assert.Equal(t, "http://"+hostname+":2000", address, "Expected the host name to be used")
+	}
+}
+
+func TestAdvertiseAddressInvalidBindAddress(t *testing.T) {
+	_, err := advertiseAddress("localhost")
+	assert.Error(t, err, "Expected an error for a bind address without a port")
+}
@@ -15,34 +15,68 @@ package store
 
 import (
 	"context"
+	"sort"
+	"strings"
 
 	"github.com/blox/blox/daemon-scheduler/pkg/json"
 	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	log "github.com/cihub/seelog"
 	"github.com/pkg/errors"
 )
 
 const (
 	environmentKeyPrefix = "ecs/environment/"
+	// deployments are stored under ecs/deployment/<environment name>/<deployment ID>
+	deploymentKeyPrefix = "ecs/deployment/"
 )
 
+const (
+	// MaxUpdateAttempts is the number of times UpdateEnvironment reads and writes an environment
+	// that keeps being modified concurrently before giving up
+	MaxUpdateAttempts = 5
+
+	// DefaultDeploymentRetention is the number of latest deployments kept for each environment
+	DefaultDeploymentRetention = 20
+)
+
+// EnvironmentStore stores environments. Writes only succeed if the stored environment has not been
+// modified since it was read, as recorded in its ModRevision, and return a ConflictError otherwise.
+// The deployments of an environment are stored under their own keys and written along with it,
+// only when they changed. Deployments beyond the retention of the store are deleted.
 type EnvironmentStore interface {
 	PutEnvironment(ctx context.Context, environment types.Environment) error
 	GetEnvironment(ctx context.Context, name string) (*types.Environment, error)
 	DeleteEnvironment(ctx context.Context, environment types.Environment) error
 	ListEnvironments(ctx context.Context) ([]types.Environment, error)
+	// MigrateEnvironments moves the deployments of environments stored by earlier versions, which
+	// kept them in the environment record, to their own keys
+	MigrateEnvironments(ctx context.Context) error
 }
 
 type environmentStore struct {
 	datastore DataStore
+	retention int
 }
 
-func NewEnvironmentStore(ds DataStore) (EnvironmentStore, error) {
+// legacyEnvironment holds the deployments of an environment stored by earlier versions
+type legacyEnvironment struct {
+	Deployments map[string]types.Deployment
+}
+
+// NewEnvironmentStore creates a store that keeps the latest retention deployments of each
+// environment
+func NewEnvironmentStore(ds DataStore, retention int) (EnvironmentStore, error) {
 	if ds == nil {
 		return nil, errors.New("The datastore cannot be nil")
 	}
 
+	if retention < 1 {
+		return nil, errors.Errorf("The deployment retention %d should be at least 1", retention)
+	}
+
 	return environmentStore{
 		datastore: ds,
+		retention: retention,
 	}, nil
 }
 
@@ -53,6 +87,29 @@ func generateEnvironmentKey(environment types.Environment) (string, error) {
 	return environmentKeyPrefix + environment.Name, nil
 }
 
+func generateDeploymentKeyPrefix(environmentName string) string {
+	return deploymentKeyPrefix + environmentName + "/"
+}
+
+func generateDeploymentKey(environmentName string, id string) string {
+	return generateDeploymentKeyPrefix(environmentName) + id
+}
+
+// parseDeploymentKey returns the name of the environment and the ID of the deployment stored
+// under key, and false if key is not a deployment key
+func parseDeploymentKey(key string) (string, string, bool) {
+	if !strings.HasPrefix(key, deploymentKeyPrefix) {
+		return "", "", false
+	}
+
+	key = strings.TrimPrefix(key, deploymentKeyPrefix)
+	i := strings.LastIndex(key, "/")
+	if i <= 0 || i == len(key)-1 {
+		return "", "", false
+	}
+	return key[:i], key[i+1:], true
+}
+
 func (e environmentStore) PutEnvironment(ctx context.Context, environment types.Environment) error {
 	key, err := generateEnvironmentKey(environment)
 	if err != nil {
@@ -64,12 +121,37 @@ func (e environmentStore) PutEnvironment(ctx context.Context, environment types.
 		return err
 	}
 
-	err = e.datastore.Put(ctx, key, dataJSON)
+	// the stored deployments cannot change without the environment changing, so they are the
+	// ones the environment was read with as long as the write succeeds
+	stored, err := e.getDeployments(ctx, environment.Name)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	puts := map[string]string{key: dataJSON}
+	retained := environment.RetainedDeployments(e.retention)
+	for id, d := range retained {
+		deploymentJSON, err := json.MarshalJSON(d)
+		if err != nil {
+			return err
+		}
+
+		deploymentKey := generateDeploymentKey(environment.Name, id)
+		if current, ok := stored[deploymentKey]; !ok || current.Value != deploymentJSON {
+			puts[deploymentKey] = deploymentJSON
+		}
+	}
+
+	deletes := make([]string, 0)
+	for deploymentKey := range stored {
+		_, id, _ := parseDeploymentKey(deploymentKey)
+		if _, ok := retained[id]; !ok {
+			deletes = append(deletes, deploymentKey)
+		}
+	}
+	sort.Strings(deletes)
+
+	return e.datastore.WriteIfModRevision(ctx, key, environment.ModRevision, puts, deletes)
 }
 
 func (e environmentStore) GetEnvironment(ctx context.Context, name string) (*types.Environment, error) {
@@ -77,70 +159,204 @@ func (e environmentStore) GetEnvironment(ctx context.Context, name string) (*typ
 		return nil, errors.New("Environment name is missing")
 	}
 
-	var environment types.Environment
-	environment.Name = name
+	key, err := generateEnvironmentKey(types.Environment{Name: name})
+	if err != nil {
+		return nil, err
+	}
 
-	key, err := generateEnvironmentKey(environment)
+	resp, err := e.datastore.GetAllWithModRevision(ctx, []string{key}, []string{generateDeploymentKeyPrefix(name)})
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := e.datastore.Get(ctx, key)
+	environments, err := decodeEnvironments(resp)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(resp) == 0 {
+	environment, ok := environments[name]
+	if !ok {
 		return nil, nil
 	}
+	return environment, nil
+}
 
-	if len(resp) > 1 {
-		return nil, errors.Errorf("Multiple entries exist with the key %v", key)
+func (e environmentStore) DeleteEnvironment(ctx context.Context, environment types.Environment) error {
+	key, err := generateEnvironmentKey(environment)
+	if err != nil {
+		return err
 	}
 
-	for _, v := range resp {
-		err = json.UnmarshalJSON(v, &environment)
-		if err != nil {
-			return nil, err
-		}
-		break
+	stored, err := e.getDeployments(ctx, environment.Name)
+	if err != nil {
+		return err
+	}
+
+	deletes := []string{key}
+	for deploymentKey := range stored {
+		deletes = append(deletes, deploymentKey)
 	}
+	sort.Strings(deletes[1:])
 
-	return &environment, nil
+	return e.datastore.WriteIfModRevision(ctx, key, environment.ModRevision, nil, deletes)
 }
 
-func (e environmentStore) DeleteEnvironment(ctx context.Context, environment types.Environment) error {
-	key, err := generateEnvironmentKey(environment)
+func (e environmentStore) ListEnvironments(ctx context.Context) ([]types.Environment, error) {
+	resp, err := e.datastore.GetAllWithModRevision(ctx, nil, []string{environmentKeyPrefix, deploymentKeyPrefix})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	decoded, err := decodeEnvironments(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(decoded))
+	for name := range decoded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	environments := make([]types.Environment, 0, len(decoded))
+	for _, name := range names {
+		environments = append(environments, *decoded[name])
 	}
 
-	err = e.datastore.Delete(ctx, key)
+	return environments, nil
+}
+
+func (e environmentStore) MigrateEnvironments(ctx context.Context) error {
+	resp, err := e.datastore.GetWithPrefixAndModRevision(ctx, environmentKeyPrefix)
 	if err != nil {
 		return err
 	}
 
+	for key, v := range resp {
+		legacy := legacyEnvironment{}
+		err = json.UnmarshalJSON(v.Value, &legacy)
+		if err != nil {
+			return err
+		}
+		if len(legacy.Deployments) == 0 {
+			continue
+		}
+
+		name := strings.TrimPrefix(key, environmentKeyPrefix)
+		log.Infof("Moving the %d deployments of environment %s to their own keys", len(legacy.Deployments), name)
+		_, err = UpdateEnvironment(ctx, e, name, func(environment *types.Environment) error {
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Error moving the deployments of environment %s", name)
+		}
+	}
+
 	return nil
 }
 
-func (e environmentStore) ListEnvironments(ctx context.Context) ([]types.Environment, error) {
-	resp, err := e.datastore.GetWithPrefix(ctx, environmentKeyPrefix)
+// getDeployments returns the stored deployments of the environment with the provided name by key
+func (e environmentStore) getDeployments(ctx context.Context, name string) (map[string]types.RevisionedValue, error) {
+	resp, err := e.datastore.GetWithPrefixAndModRevision(ctx, generateDeploymentKeyPrefix(name))
 	if err != nil {
 		return nil, err
 	}
 
-	environments := make([]types.Environment, 0, len(resp))
+	// the prefix also matches the deployments of environments whose name starts with name/
+	for key := range resp {
+		if environmentName, _, ok := parseDeploymentKey(key); !ok || environmentName != name {
+			delete(resp, key)
+		}
+	}
+	return resp, nil
+}
+
+// decodeEnvironments returns the environments by name along with their deployments, which are
+// read from the environment record for environments stored by earlier versions
+func decodeEnvironments(resp map[string]types.RevisionedValue) (map[string]*types.Environment, error) {
+	environments := make(map[string]*types.Environment)
+	for key, v := range resp {
+		if !strings.HasPrefix(key, environmentKeyPrefix) {
+			continue
+		}
 
-	for _, v := range resp {
 		environment := types.Environment{}
+		err := json.UnmarshalJSON(v.Value, &environment)
+		if err != nil {
+			return nil, err
+		}
+		environment.ModRevision = v.ModRevision
 
-		err = json.UnmarshalJSON(v, &environment)
+		legacy := legacyEnvironment{}
+		err = json.UnmarshalJSON(v.Value, &legacy)
 		if err != nil {
 			return nil, err
 		}
+		environment.Deployments = legacy.Deployments
+		if environment.Deployments == nil {
+			environment.Deployments = make(map[string]types.Deployment)
+		}
 
-		environments = append(environments, environment)
+		environments[environment.Name] = &environment
+	}
+
+	for key, v := range resp {
+		name, _, ok := parseDeploymentKey(key)
+		if !ok {
+			continue
+		}
+
+		environment, ok := environments[name]
+		if !ok {
+			continue
+		}
+
+		d := types.Deployment{}
+		err := json.UnmarshalJSON(v.Value, &d)
+		if err != nil {
+			return nil, err
+		}
+		environment.Deployments[d.ID] = d
 	}
 
 	return environments, nil
 }
+
+// UpdateEnvironment reads the environment with the provided name, applies update to it and stores
+// the result. If the environment is modified concurrently, it is read again and update is applied
+// to the new version, up to MaxUpdateAttempts times. update must therefore only depend on the
+// environment it is given, and can return a ConflictError if that version invalidates the change.
+func UpdateEnvironment(ctx context.Context, environmentStore EnvironmentStore, name string,
+	update func(environment *types.Environment) error) (*types.Environment, error) {
+
+	var err error
+	for attempt := 1; attempt <= MaxUpdateAttempts; attempt++ {
+		var environment *types.Environment
+		environment, err = environmentStore.GetEnvironment(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if environment == nil {
+			return nil, types.NewNotFoundError(errors.Errorf("Environment %s does not exist", name))
+		}
+
+		err = update(environment)
+		if err != nil {
+			return nil, err
+		}
+
+		err = environmentStore.PutEnvironment(ctx, *environment)
+		if err == nil {
+			return environment, nil
+		}
+
+		if _, ok := errors.Cause(err).(types.ConflictError); !ok {
+			return nil, err
+		}
+
+		log.Debugf("Environment %s was modified concurrently, retrying the update (attempt %d): %v", name, attempt, err)
+	}
+
+	return nil, errors.Wrapf(err, "Giving up updating environment %s after %d attempts", name, MaxUpdateAttempts)
+}
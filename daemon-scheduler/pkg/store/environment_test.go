@@ -16,6 +16,7 @@ package store
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/blox/blox/daemon-scheduler/pkg/json"
 	"github.com/blox/blox/daemon-scheduler/pkg/mocks"
@@ -33,6 +34,11 @@ const (
 	environmentKey2  = environmentKeyPrefix + environmentName2
 	taskDefinition   = "arn:aws:ecs:us-east-1:12345678912:task-definition/test"
 	cluster          = "arn:aws:ecs:us-east-1:123456789123:cluster/test"
+	modRevision1     = int64(7)
+	modRevision2     = int64(8)
+	retention        = 2
+
+	deploymentKeyPrefix1 = deploymentKeyPrefix + environmentName1 + "/"
 )
 
 type EnvironmentTestSuite struct {
@@ -60,7 +66,7 @@ func (suite *EnvironmentTestSuite) SetupTest() {
 	assert.Nil(suite.T(), err, "Cannot initialize EnvironmentTestSuite")
 	suite.environment2JSON, err = json.MarshalJSON(suite.environment2)
 	assert.Nil(suite.T(), err, "Cannot initialize EnvironmentTestSuite")
-	suite.environmentStore, err = NewEnvironmentStore(suite.datastore)
+	suite.environmentStore, err = NewEnvironmentStore(suite.datastore, retention)
 	assert.Nil(suite.T(), err, "Cannot initialize EnvironmentTestSuite")
 }
 
@@ -69,12 +75,17 @@ func TestEnvironmentTestSuite(t *testing.T) {
 }
 
 func (suite *EnvironmentTestSuite) TestNewEnvironmentStoreEmptyDataStore() {
-	_, err := NewEnvironmentStore(nil)
+	_, err := NewEnvironmentStore(nil, retention)
 	assert.Error(suite.T(), err, "Expected an error when datastore is nil")
 }
 
+func (suite *EnvironmentTestSuite) TestNewEnvironmentStoreInvalidRetention() {
+	_, err := NewEnvironmentStore(suite.datastore, 0)
+	assert.Error(suite.T(), err, "Expected an error when the retention is not positive")
+}
+
 func (suite *EnvironmentTestSuite) TestNewEnvironmentStore() {
-	es, err := NewEnvironmentStore(suite.datastore)
+	es, err := NewEnvironmentStore(suite.datastore, retention)
 	assert.Nil(suite.T(), err, "Unexpected error when datastore is not nil")
 	assert.NotNil(suite.T(), es, "Environment store should not be nil")
 }
@@ -85,28 +96,130 @@ func (suite *EnvironmentTestSuite) TestPutWithMissingEnvironmentName() {
 }
 
 func (suite *EnvironmentTestSuite) TestPutDataStorePutFails() {
-	suite.datastore.EXPECT().Put(suite.ctx, environmentKey1, suite.environment1JSON).
+	suite.expectStoredDeployments(map[string]types.RevisionedValue{})
+	suite.datastore.EXPECT().WriteIfModRevision(suite.ctx, environmentKey1, int64(0),
+		map[string]string{environmentKey1: suite.environment1JSON}, []string{}).
 		Return(errors.New("Put failed"))
 
 	err := suite.environmentStore.PutEnvironment(suite.ctx, *suite.environment1)
 	assert.Error(suite.T(), err, "Expected an error when datastore put fails")
 }
 
+func (suite *EnvironmentTestSuite) TestPutGetDeploymentsFails() {
+	suite.datastore.EXPECT().GetWithPrefixAndModRevision(suite.ctx, deploymentKeyPrefix1).
+		Return(nil, errors.New("GetWithPrefix failed"))
+
+	err := suite.environmentStore.PutEnvironment(suite.ctx, *suite.environment1)
+	assert.Error(suite.T(), err, "Expected an error when getting the stored deployments fails")
+}
+
 func (suite *EnvironmentTestSuite) TestPut() {
-	suite.datastore.EXPECT().Put(suite.ctx, environmentKey1, suite.environment1JSON).
+	suite.expectStoredDeployments(map[string]types.RevisionedValue{})
+	suite.datastore.EXPECT().WriteIfModRevision(suite.ctx, environmentKey1, int64(0),
+		map[string]string{environmentKey1: suite.environment1JSON}, []string{}).
 		Return(nil)
 
 	err := suite.environmentStore.PutEnvironment(suite.ctx, *suite.environment1)
 	assert.Nil(suite.T(), err, "Unexpected error when datastore put succeeds")
 }
 
+func (suite *EnvironmentTestSuite) TestPutWritesChangedDeploymentsOnly() {
+	// the latest healthy deployment is retained even though it is older than the others
+	unchanged := suite.deployment("unchanged", types.DeploymentCompleted, 2)
+	unchanged.Health = types.DeploymentHealthy
+	changed := suite.deployment("changed", types.DeploymentInProgress, 1)
+	added := suite.deployment("added", types.DeploymentPending, 0)
+	suite.environment1.Deployments = map[string]types.Deployment{
+		unchanged.ID: unchanged,
+		changed.ID:   changed,
+		added.ID:     added,
+	}
+	suite.environment1.ModRevision = modRevision1
+
+	stale := changed
+	stale.Status = types.DeploymentPending
+	suite.expectStoredDeployments(map[string]types.RevisionedValue{
+		deploymentKeyPrefix1 + unchanged.ID: {Value: suite.toJSON(unchanged)},
+		deploymentKeyPrefix1 + changed.ID:   {Value: suite.toJSON(stale)},
+	})
+	suite.datastore.EXPECT().WriteIfModRevision(suite.ctx, environmentKey1, modRevision1,
+		map[string]string{
+			environmentKey1:                   suite.environment1JSON,
+			deploymentKeyPrefix1 + changed.ID: suite.toJSON(changed),
+			deploymentKeyPrefix1 + added.ID:   suite.toJSON(added),
+		}, []string{}).Return(nil)
+
+	err := suite.environmentStore.PutEnvironment(suite.ctx, *suite.environment1)
+	assert.Nil(suite.T(), err, "Unexpected error when datastore put succeeds")
+}
+
+func (suite *EnvironmentTestSuite) TestPutDeletesDeploymentsBeyondRetention() {
+	oldest := suite.deployment("oldest", types.DeploymentCompleted, 3)
+	oldest.TasksStopped = true
+	old := suite.deployment("old", types.DeploymentFailed, 2)
+	old.TasksStopped = true
+	previous := suite.deployment("previous", types.DeploymentCompleted, 1)
+	latest := suite.deployment("latest", types.DeploymentInProgress, 0)
+	suite.environment1.Deployments = map[string]types.Deployment{
+		oldest.ID:   oldest,
+		old.ID:      old,
+		previous.ID: previous,
+		latest.ID:   latest,
+	}
+	suite.environment1.InProgressDeploymentID = latest.ID
+	suite.environment1.ModRevision = modRevision1
+	environmentJSON := suite.toJSON(suite.environment1)
+
+	stored := make(map[string]types.RevisionedValue)
+	for id, d := range suite.environment1.Deployments {
+		stored[deploymentKeyPrefix1+id] = types.RevisionedValue{Value: suite.toJSON(d)}
+	}
+	// deployments of an environment whose name starts with the name of this one are left alone
+	stored[deploymentKeyPrefix1+"other/dep"] = types.RevisionedValue{Value: suite.toJSON(old)}
+	suite.datastore.EXPECT().GetWithPrefixAndModRevision(suite.ctx, deploymentKeyPrefix1).Return(stored, nil)
+	suite.datastore.EXPECT().WriteIfModRevision(suite.ctx, environmentKey1, modRevision1,
+		map[string]string{environmentKey1: environmentJSON},
+		[]string{deploymentKeyPrefix1 + old.ID, deploymentKeyPrefix1 + oldest.ID}).Return(nil)
+
+	err := suite.environmentStore.PutEnvironment(suite.ctx, *suite.environment1)
+	assert.Nil(suite.T(), err, "Unexpected error when datastore put succeeds")
+}
+
+func (suite *EnvironmentTestSuite) TestPutKeepsDeploymentsWithRunningTasks() {
+	running := suite.deployment("running", types.DeploymentFailed, 3)
+	old := suite.deployment("old", types.DeploymentFailed, 2)
+	old.TasksStopped = true
+	failed := suite.deployment("failed", types.DeploymentFailed, 1)
+	latest := suite.deployment("latest", types.DeploymentInProgress, 0)
+	suite.environment1.Deployments = map[string]types.Deployment{
+		running.ID: running,
+		old.ID:     old,
+		failed.ID:  failed,
+		latest.ID:  latest,
+	}
+	suite.environment1.InProgressDeploymentID = latest.ID
+	suite.environment1.ModRevision = modRevision1
+
+	stored := make(map[string]types.RevisionedValue)
+	for id, d := range suite.environment1.Deployments {
+		stored[deploymentKeyPrefix1+id] = types.RevisionedValue{Value: suite.toJSON(d)}
+	}
+	suite.datastore.EXPECT().GetWithPrefixAndModRevision(suite.ctx, deploymentKeyPrefix1).Return(stored, nil)
+	suite.datastore.EXPECT().WriteIfModRevision(suite.ctx, environmentKey1, modRevision1,
+		map[string]string{environmentKey1: suite.toJSON(suite.environment1)},
+		[]string{deploymentKeyPrefix1 + old.ID}).Return(nil)
+
+	err := suite.environmentStore.PutEnvironment(suite.ctx, *suite.environment1)
+	assert.Nil(suite.T(), err, "Unexpected error when datastore put succeeds")
+}
+
 func (suite *EnvironmentTestSuite) TestGetWithMissingEnvironmentName() {
 	_, err := suite.environmentStore.GetEnvironment(suite.ctx, "")
 	assert.Error(suite.T(), err, "Expected an error when environment name is missing")
 }
 
 func (suite *EnvironmentTestSuite) TestGetDataStoreGetFails() {
-	suite.datastore.EXPECT().Get(suite.ctx, environmentKey1).
+	suite.datastore.EXPECT().GetAllWithModRevision(suite.ctx, []string{environmentKey1}, []string{deploymentKeyPrefix1}).
 		Return(nil, errors.New("Get failed"))
 
 	_, err := suite.environmentStore.GetEnvironment(suite.ctx, suite.environment1.Name)
@@ -114,31 +227,17 @@ func (suite *EnvironmentTestSuite) TestGetDataStoreGetFails() {
 }
 
 func (suite *EnvironmentTestSuite) TestGetDataStoreGetEmpty() {
-	resp := make(map[string]string)
-	suite.datastore.EXPECT().Get(suite.ctx, environmentKey1).Return(resp, nil)
+	suite.expectGet(map[string]types.RevisionedValue{})
 
 	env, err := suite.environmentStore.GetEnvironment(suite.ctx, suite.environment1.Name)
 	assert.Nil(suite.T(), err, "Unexpected error when datastore get is empty")
 	assert.Nil(suite.T(), env, "Expected nil when datastore get is empty")
 }
 
-func (suite *EnvironmentTestSuite) TestGetDataStoreGetMultipleResults() {
-	resp := map[string]string{
-		environmentKey1: suite.environment1JSON,
-		environmentKey2: suite.environment1JSON,
-	}
-	suite.datastore.EXPECT().Get(suite.ctx, environmentKey1).Return(resp, nil)
-
-	env, err := suite.environmentStore.GetEnvironment(suite.ctx, suite.environment1.Name)
-	assert.Error(suite.T(), err, "Expected an error when multiple results are returned from the datastore")
-	assert.Nil(suite.T(), env, "Expected nil when multiple results are returned from the datastore")
-}
-
 func (suite *EnvironmentTestSuite) TestGetDataStoreInvalidJson() {
-	resp := map[string]string{
-		environmentKey1: "invalidJSON",
-	}
-	suite.datastore.EXPECT().Get(suite.ctx, environmentKey1).Return(resp, nil)
+	suite.expectGet(map[string]types.RevisionedValue{
+		environmentKey1: {Value: "invalidJSON", ModRevision: modRevision1},
+	})
 
 	env, err := suite.environmentStore.GetEnvironment(suite.ctx, suite.environment1.Name)
 	assert.Error(suite.T(), err, "Expected an error when get returns invalid json")
@@ -146,19 +245,44 @@ func (suite *EnvironmentTestSuite) TestGetDataStoreInvalidJson() {
 }
 
 func (suite *EnvironmentTestSuite) TestGetDataStore() {
-	resp := map[string]string{
-		environmentKey1: suite.environment1JSON,
-	}
-	suite.datastore.EXPECT().Get(suite.ctx, environmentKey1).Return(resp, nil)
+	suite.expectGet(map[string]types.RevisionedValue{
+		environmentKey1: {Value: suite.environment1JSON, ModRevision: modRevision1},
+	})
 
 	env, err := suite.environmentStore.GetEnvironment(suite.ctx, suite.environment1.Name)
 	assert.Nil(suite.T(), err, "Unexpected error when retrieving results")
+	suite.environment1.ModRevision = modRevision1
 	assert.Exactly(suite.T(), suite.environment1, env,
 		"Expected the returned environment to be the same as the one returned by get")
 }
 
+func (suite *EnvironmentTestSuite) TestGetWithDeployments() {
+	d := suite.deployment("dep", types.DeploymentCompleted, 0)
+	suite.expectGet(map[string]types.RevisionedValue{
+		environmentKey1:             {Value: suite.environment1JSON, ModRevision: modRevision1},
+		deploymentKeyPrefix1 + d.ID: {Value: suite.toJSON(d)},
+	})
+
+	env, err := suite.environmentStore.GetEnvironment(suite.ctx, suite.environment1.Name)
+	assert.Nil(suite.T(), err, "Unexpected error when retrieving results")
+	assert.Len(suite.T(), env.Deployments, 1, "Expected the deployments to be read along with the environment")
+	assert.Exactly(suite.T(), d.TaskDefinition, env.Deployments[d.ID].TaskDefinition, "Expected the stored deployment")
+}
+
+func (suite *EnvironmentTestSuite) TestGetLegacyEnvironment() {
+	d := suite.deployment("dep", types.DeploymentCompleted, 0)
+	suite.expectGet(map[string]types.RevisionedValue{
+		environmentKey1: {Value: suite.legacyJSON(suite.environment1, d), ModRevision: modRevision1},
+	})
+
+	env, err := suite.environmentStore.GetEnvironment(suite.ctx, suite.environment1.Name)
+	assert.Nil(suite.T(), err, "Unexpected error when retrieving results")
+	assert.Len(suite.T(), env.Deployments, 1, "Expected the deployments stored in the environment to be read")
+	assert.Exactly(suite.T(), d.ID, env.Deployments[d.ID].ID, "Expected the deployment stored in the environment")
+}
+
 func (suite *EnvironmentTestSuite) TestListGetWithPrefixFails() {
-	suite.datastore.EXPECT().GetWithPrefix(suite.ctx, environmentKeyPrefix).
+	suite.datastore.EXPECT().GetAllWithModRevision(suite.ctx, nil, []string{environmentKeyPrefix, deploymentKeyPrefix}).
 		Return(nil, errors.New("GetWithPrefix failed"))
 
 	_, err := suite.environmentStore.ListEnvironments(suite.ctx)
@@ -166,10 +290,11 @@ func (suite *EnvironmentTestSuite) TestListGetWithPrefixFails() {
 }
 
 func (suite *EnvironmentTestSuite) TestListGetWithPrefixInvalidJson() {
-	resp := map[string]string{
-		environmentKey1: "invalidJSON",
+	resp := map[string]types.RevisionedValue{
+		environmentKey1: {Value: "invalidJSON", ModRevision: modRevision1},
 	}
-	suite.datastore.EXPECT().GetWithPrefix(suite.ctx, environmentKeyPrefix).Return(resp, nil)
+	suite.datastore.EXPECT().GetAllWithModRevision(suite.ctx, nil, []string{environmentKeyPrefix, deploymentKeyPrefix}).
+		Return(resp, nil)
 
 	envs, err := suite.environmentStore.ListEnvironments(suite.ctx)
 	assert.Error(suite.T(), err, "Expected an error when getwithprefix returns invalid json")
@@ -177,18 +302,164 @@ func (suite *EnvironmentTestSuite) TestListGetWithPrefixInvalidJson() {
 }
 
 func (suite *EnvironmentTestSuite) TestList() {
-	resp := map[string]string{
-		environmentKey1: suite.environment1JSON,
-		environmentKey2: suite.environment2JSON,
+	d := suite.deployment("dep", types.DeploymentCompleted, 0)
+	resp := map[string]types.RevisionedValue{
+		environmentKey2:             {Value: suite.environment2JSON, ModRevision: modRevision2},
+		environmentKey1:             {Value: suite.environment1JSON, ModRevision: modRevision1},
+		deploymentKeyPrefix1 + d.ID: {Value: suite.toJSON(d)},
 	}
-	suite.datastore.EXPECT().GetWithPrefix(suite.ctx, environmentKeyPrefix).Return(resp, nil)
+	suite.datastore.EXPECT().GetAllWithModRevision(suite.ctx, nil, []string{environmentKeyPrefix, deploymentKeyPrefix}).
+		Return(resp, nil)
 
 	envs, err := suite.environmentStore.ListEnvironments(suite.ctx)
 	assert.Nil(suite.T(), err, "Unexpected error when listing environments")
+	suite.environment1.ModRevision = modRevision1
+	suite.environment1.Deployments[d.ID] = d
+	suite.environment2.ModRevision = modRevision2
 
 	expectedEnvs := []types.Environment{*suite.environment1, *suite.environment2}
-	assert.Equal(suite.T(), len(expectedEnvs), len(envs), "Expected listed environments(len=%d) to be of same count as what's returned from the store(len=%d)", len(envs), len(expectedEnvs))
-	for _, expectedEnv := range expectedEnvs {
-		assert.Contains(suite.T(), envs, expectedEnv, "Expected %s to be returned by ListEnvironments", expectedEnv)
+	assert.Exactly(suite.T(), expectedEnvs, envs, "Expected the environments ordered by name with their deployments")
+}
+
+func (suite *EnvironmentTestSuite) TestPutStoredEnvironment() {
+	suite.environment1.ModRevision = modRevision1
+	suite.expectStoredDeployments(map[string]types.RevisionedValue{})
+	suite.datastore.EXPECT().WriteIfModRevision(suite.ctx, environmentKey1, modRevision1,
+		map[string]string{environmentKey1: suite.environment1JSON}, []string{}).
+		Return(nil)
+
+	err := suite.environmentStore.PutEnvironment(suite.ctx, *suite.environment1)
+	assert.Nil(suite.T(), err, "Unexpected error when datastore put succeeds")
+}
+
+func (suite *EnvironmentTestSuite) TestDelete() {
+	suite.environment1.ModRevision = modRevision1
+	suite.expectStoredDeployments(map[string]types.RevisionedValue{
+		deploymentKeyPrefix1 + "dep-2": {Value: "{}"},
+		deploymentKeyPrefix1 + "dep-1": {Value: "{}"},
+	})
+	suite.datastore.EXPECT().WriteIfModRevision(suite.ctx, environmentKey1, modRevision1, nil,
+		[]string{environmentKey1, deploymentKeyPrefix1 + "dep-1", deploymentKeyPrefix1 + "dep-2"}).Return(nil)
+
+	err := suite.environmentStore.DeleteEnvironment(suite.ctx, *suite.environment1)
+	assert.Nil(suite.T(), err, "Unexpected error when datastore delete succeeds")
+}
+
+func (suite *EnvironmentTestSuite) TestMigrateEnvironments() {
+	d := suite.deployment("dep", types.DeploymentCompleted, 0)
+	legacy := map[string]types.RevisionedValue{
+		environmentKey1: {Value: suite.legacyJSON(suite.environment1, d), ModRevision: modRevision1},
+		environmentKey2: {Value: suite.environment2JSON, ModRevision: modRevision2},
+	}
+	suite.datastore.EXPECT().GetWithPrefixAndModRevision(suite.ctx, environmentKeyPrefix).Return(legacy, nil)
+	suite.expectGet(map[string]types.RevisionedValue{environmentKey1: legacy[environmentKey1]})
+	suite.expectStoredDeployments(map[string]types.RevisionedValue{})
+	suite.datastore.EXPECT().WriteIfModRevision(suite.ctx, environmentKey1, modRevision1,
+		map[string]string{
+			environmentKey1:             suite.environment1JSON,
+			deploymentKeyPrefix1 + d.ID: suite.toJSON(d),
+		}, []string{}).Return(nil)
+
+	err := suite.environmentStore.MigrateEnvironments(suite.ctx)
+	assert.Nil(suite.T(), err, "Unexpected error when migrating environments")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentDoesNotExist() {
+	suite.expectGet(map[string]types.RevisionedValue{})
+
+	_, err := UpdateEnvironment(suite.ctx, suite.environmentStore, environmentName1, func(*types.Environment) error {
+		assert.Fail(suite.T(), "Expected update not to be called for a missing environment")
+		return nil
+	})
+	_, ok := errors.Cause(err).(types.NotFoundError)
+	assert.True(suite.T(), ok, "Expected a not found error when the environment does not exist")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentUpdateFails() {
+	suite.expectGet(map[string]types.RevisionedValue{environmentKey1: {Value: suite.environment1JSON, ModRevision: modRevision1}})
+	suite.datastore.EXPECT().WriteIfModRevision(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := UpdateEnvironment(suite.ctx, suite.environmentStore, environmentName1, func(*types.Environment) error {
+		return errors.New("Update failed")
+	})
+	assert.Error(suite.T(), err, "Expected an error when update fails")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentRetriesOnConflict() {
+	stale := map[string]types.RevisionedValue{environmentKey1: {Value: suite.environment1JSON, ModRevision: modRevision1}}
+	latest := map[string]types.RevisionedValue{environmentKey1: {Value: suite.environment1JSON, ModRevision: modRevision2}}
+	conflict := types.NewConflictError(errors.New("Conflict"))
+
+	gomock.InOrder(
+		suite.expectGet(stale),
+		suite.expectStoredDeployments(map[string]types.RevisionedValue{}),
+		suite.datastore.EXPECT().WriteIfModRevision(suite.ctx, environmentKey1, modRevision1, gomock.Any(), gomock.Any()).Return(conflict),
+		suite.expectGet(latest),
+		suite.expectStoredDeployments(map[string]types.RevisionedValue{}),
+		suite.datastore.EXPECT().WriteIfModRevision(suite.ctx, environmentKey1, modRevision2, gomock.Any(), gomock.Any()).Return(nil),
+	)
+
+	updates := 0
+	env, err := UpdateEnvironment(suite.ctx, suite.environmentStore, environmentName1, func(env *types.Environment) error {
+		updates++
+		env.DesiredTaskCount = 3
+		return nil
+	})
+	assert.Nil(suite.T(), err, "Unexpected error when the update succeeds after a conflict")
+	assert.Equal(suite.T(), 2, updates, "Expected the update to be reapplied after a conflict")
+	assert.Equal(suite.T(), 3, env.DesiredTaskCount, "Expected the updated environment to be returned")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateEnvironmentGivesUpAfterConflicts() {
+	resp := map[string]types.RevisionedValue{environmentKey1: {Value: suite.environment1JSON, ModRevision: modRevision1}}
+	suite.expectGet(resp).Times(MaxUpdateAttempts)
+	suite.expectStoredDeployments(map[string]types.RevisionedValue{}).Times(MaxUpdateAttempts)
+	suite.datastore.EXPECT().WriteIfModRevision(suite.ctx, environmentKey1, modRevision1, gomock.Any(), gomock.Any()).
+		Return(types.NewConflictError(errors.New("Conflict"))).Times(MaxUpdateAttempts)
+
+	_, err := UpdateEnvironment(suite.ctx, suite.environmentStore, environmentName1, func(*types.Environment) error {
+		return nil
+	})
+	_, ok := errors.Cause(err).(types.ConflictError)
+	assert.True(suite.T(), ok, "Expected a conflict error after too many conflicts")
+}
+
+func (suite *EnvironmentTestSuite) expectGet(resp map[string]types.RevisionedValue) *gomock.Call {
+	return suite.datastore.EXPECT().GetAllWithModRevision(suite.ctx, []string{environmentKey1}, []string{deploymentKeyPrefix1}).
+		Return(resp, nil)
+}
+
+func (suite *EnvironmentTestSuite) expectStoredDeployments(resp map[string]types.RevisionedValue) *gomock.Call {
+	return suite.datastore.EXPECT().GetWithPrefixAndModRevision(suite.ctx, deploymentKeyPrefix1).Return(resp, nil)
+}
+
+// deployment returns a deployment that started age hours ago
+func (suite *EnvironmentTestSuite) deployment(id string, status types.DeploymentStatus, age int) types.Deployment {
+	return types.Deployment{
+		ID:             id,
+		Status:         status,
+		Health:         types.DeploymentUnhealthy,
+		TaskDefinition: taskDefinition,
+		StartTime:      time.Date(2017, 1, 1, 12-age, 0, 0, 0, time.UTC),
+	}
+}
+
+func (suite *EnvironmentTestSuite) toJSON(v interface{}) string {
+	s, err := json.MarshalJSON(v)
+	assert.Nil(suite.T(), err, "Unexpected error marshalling %v", v)
+	return s
+}
+
+// legacyJSON returns the environment as stored by earlier versions, along with its deployments
+func (suite *EnvironmentTestSuite) legacyJSON(environment *types.Environment, deployments ...types.Deployment) string {
+	fields := make(map[string]interface{})
+	err := json.UnmarshalJSON(suite.toJSON(environment), &fields)
+	assert.Nil(suite.T(), err, "Unexpected error unmarshalling the environment")
+
+	legacy := make(map[string]types.Deployment)
+	for _, d := range deployments {
+		legacy[d.ID] = d
 	}
+	fields["Deployments"] = legacy
+	return suite.toJSON(fields)
 }
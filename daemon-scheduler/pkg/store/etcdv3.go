@@ -17,6 +17,7 @@ import (
 	"context"
 
 	"github.com/blox/blox/daemon-scheduler/pkg/clients"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
 	etcd "github.com/coreos/etcd/clientv3"
 	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
 	"github.com/pkg/errors"
@@ -100,6 +101,156 @@ func handleGetResponse(resp *etcd.GetResponse) (map[string]string, error) {
 	return kv, nil
 }
 
+// GetWithModRevision returns a map with one key-value pair where the key matches the provided key
+func (datastore etcdDataStore) GetWithModRevision(ctx context.Context, key string) (map[string]types.RevisionedValue, error) {
+	if len(key) == 0 {
+		return nil, errors.New("Key cannot be empty")
+	}
+
+	resp, err := datastore.etcdInterface.Get(ctx, key)
+
+	if err != nil {
+		return nil, handleEtcdError(err)
+	}
+
+	return handleRevisionedGetResponse(resp), nil
+}
+
+// GetWithPrefixAndModRevision returns a map of key-value pairs where the key starts with keyPrefix
+func (datastore etcdDataStore) GetWithPrefixAndModRevision(ctx context.Context, keyPrefix string) (map[string]types.RevisionedValue, error) {
+	if len(keyPrefix) == 0 {
+		return nil, errors.New("Key prefix cannot be empty while getting data from datastore by prefix")
+	}
+
+	resp, err := datastore.etcdInterface.Get(ctx, keyPrefix, etcd.WithPrefix())
+
+	if err != nil {
+		return nil, handleEtcdError(err)
+	}
+
+	return handleRevisionedGetResponse(resp), nil
+}
+
+func handleRevisionedGetResponse(resp *etcd.GetResponse) map[string]types.RevisionedValue {
+	kv := make(map[string]types.RevisionedValue)
+
+	if resp == nil || resp.Kvs == nil {
+		return kv
+	}
+
+	for _, response := range resp.Kvs {
+		kv[string(response.Key)] = types.RevisionedValue{
+			Value:       string(response.Value),
+			ModRevision: response.ModRevision,
+		}
+	}
+
+	return kv
+}
+
+// PutIfModRevision puts the provided key-value pair into the datastore if the key was last
+// modified at modRevision, or does not exist when modRevision is zero
+func (datastore etcdDataStore) PutIfModRevision(ctx context.Context, key string, value string, modRevision int64) error {
+	if len(key) == 0 {
+		return errors.Errorf("Key cannot be empty")
+	}
+
+	if len(value) == 0 {
+		return errors.Errorf("Value cannot be empty")
+	}
+
+	ok, err := clients.PutIfModRevision(ctx, datastore.etcdInterface, key, value, modRevision)
+	if err != nil {
+		return handleEtcdError(err)
+	}
+
+	if !ok {
+		return types.NewConflictError(errors.Errorf("Key %s was modified since revision %d", key, modRevision))
+	}
+
+	return nil
+}
+
+// DeleteIfModRevision deletes the record with the matching key from the database if it was
+// last modified at modRevision
+func (datastore etcdDataStore) DeleteIfModRevision(ctx context.Context, key string, modRevision int64) error {
+	if len(key) == 0 {
+		return errors.New("Key cannot be empty")
+	}
+
+	ok, err := clients.DeleteIfModRevision(ctx, datastore.etcdInterface, key, modRevision)
+	if err != nil {
+		return handleEtcdError(err)
+	}
+
+	if !ok {
+		return types.NewConflictError(errors.Errorf("Key %s was modified since revision %d", key, modRevision))
+	}
+
+	return nil
+}
+
+// GetAllWithModRevision returns a map of key-value pairs where the key is one of keys or starts
+// with one of keyPrefixes, read at the same revision
+func (datastore etcdDataStore) GetAllWithModRevision(ctx context.Context, keys []string,
+	keyPrefixes []string) (map[string]types.RevisionedValue, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errors.New("Key cannot be empty")
+		}
+	}
+	for _, keyPrefix := range keyPrefixes {
+		if len(keyPrefix) == 0 {
+			return nil, errors.New("Key prefix cannot be empty while getting data from datastore by prefix")
+		}
+	}
+
+	kvs, err := clients.GetAll(ctx, datastore.etcdInterface, keys, keyPrefixes)
+	if err != nil {
+		return nil, handleEtcdError(err)
+	}
+
+	return handleRevisionedGetResponse(&etcd.GetResponse{Kvs: kvs}), nil
+}
+
+// WriteIfModRevision puts and deletes the provided keys together if key was last modified at
+// modRevision, or does not exist when modRevision is zero
+func (datastore etcdDataStore) WriteIfModRevision(ctx context.Context, key string, modRevision int64,
+	puts map[string]string, deletes []string) error {
+	if len(key) == 0 {
+		return errors.Errorf("Key cannot be empty")
+	}
+
+	for k, v := range puts {
+		if len(k) == 0 {
+			return errors.Errorf("Key cannot be empty")
+		}
+		if len(v) == 0 {
+			return errors.Errorf("Value cannot be empty")
+		}
+	}
+
+	ok, err := clients.WriteIfModRevision(ctx, datastore.etcdInterface, key, modRevision, func(namespace string) []etcd.Op {
+		ops := make([]etcd.Op, 0, len(puts)+len(deletes))
+		for k, v := range puts {
+			ops = append(ops, etcd.OpPut(namespace+k, v))
+		}
+		for _, k := range deletes {
+			ops = append(ops, etcd.OpDelete(namespace+k))
+		}
+		return ops
+	})
+	if err != nil {
+		return handleEtcdError(err)
+	}
+
+	if !ok {
+		return types.NewConflictError(errors.Errorf("Key %s was modified since revision %d", key, modRevision))
+	}
+
+	return nil
+}
+
 // Delete deletes the record with the matching key from the database
 func (datastore etcdDataStore) Delete(ctx context.Context, key string) error {
 	if len(key) == 0 {
@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/blox/blox/daemon-scheduler/pkg/mocks"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
 	etcd "github.com/coreos/etcd/clientv3"
 	mvccpb "github.com/coreos/etcd/mvcc/mvccpb"
 	"github.com/golang/mock/gomock"
@@ -219,3 +220,28 @@ func (testSuite *DataStoreTestSuite) TestGetEtcd() {
 		assert.Exactly(testSuite.T(), string(getResp.Kvs[0].Value), value, "Expected value does not match the received response")
 	}
 }
+
+func (testSuite *DataStoreTestSuite) TestGetWithModRevisionEtcd() {
+	var getResp etcd.GetResponse
+	getResp.Kvs = []*mvccpb.KeyValue{{
+		Key:         []byte(key),
+		Value:       []byte(value),
+		ModRevision: 5,
+	}}
+	testSuite.etcdInterface.EXPECT().Get(testSuite.ctx, key).Return(&getResp, nil)
+
+	resp, err := testSuite.datastore.GetWithModRevision(testSuite.ctx, key)
+	assert.Nil(testSuite.T(), err, "Unexpected error when etcd get returns results")
+	assert.Equal(testSuite.T(), map[string]types.RevisionedValue{key: {Value: value, ModRevision: 5}}, resp,
+		"Expected the value to be returned with its mod revision")
+}
+
+func (testSuite *DataStoreTestSuite) TestPutIfModRevisionEmptyKey() {
+	err := testSuite.datastore.PutIfModRevision(testSuite.ctx, "", value, 1)
+	assert.Error(testSuite.T(), err, "Expected an error when key is nil")
+}
+
+func (testSuite *DataStoreTestSuite) TestDeleteIfModRevisionEmptyKey() {
+	err := testSuite.datastore.DeleteIfModRevision(testSuite.ctx, "", 1)
+	assert.Error(testSuite.T(), err, "Expected an error when key is nil")
+}
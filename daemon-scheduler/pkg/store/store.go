@@ -13,11 +13,31 @@
 
 package store
 
-import "context"
+import (
+	"context"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+)
 
 type DataStore interface {
 	Put(ctx context.Context, key string, value string) error
 	Get(ctx context.Context, key string) (map[string]string, error)
 	Delete(ctx context.Context, key string) error
 	GetWithPrefix(ctx context.Context, keyPrefix string) (map[string]string, error)
+
+	// GetWithModRevision and GetWithPrefixAndModRevision return values along with the revision
+	// they were last modified at, to be passed to the conditional writes below
+	GetWithModRevision(ctx context.Context, key string) (map[string]types.RevisionedValue, error)
+	GetWithPrefixAndModRevision(ctx context.Context, keyPrefix string) (map[string]types.RevisionedValue, error)
+	// PutIfModRevision and DeleteIfModRevision only write if the key was last modified at
+	// modRevision, or does not exist when modRevision is zero, and return a ConflictError otherwise
+	PutIfModRevision(ctx context.Context, key string, value string, modRevision int64) error
+	DeleteIfModRevision(ctx context.Context, key string, modRevision int64) error
+
+	// GetAllWithModRevision returns the values of the keys and of the keys starting with any of
+	// the prefixes, read at the same revision
+	GetAllWithModRevision(ctx context.Context, keys []string, keyPrefixes []string) (map[string]types.RevisionedValue, error)
+	// WriteIfModRevision puts and deletes keys in a single write if key was last modified at
+	// modRevision, or does not exist when modRevision is zero, and returns a ConflictError otherwise
+	WriteIfModRevision(ctx context.Context, key string, modRevision int64, puts map[string]string, deletes []string) error
 }
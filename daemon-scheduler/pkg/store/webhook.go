@@ -0,0 +1,393 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/json"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// webhooks are stored under ecs/webhook/<environment name>/<webhook ID>
+	webhookKeyPrefix = "ecs/webhook/"
+	// deliveries are stored under ecs/webhook-delivery/<environment name>/<webhook ID>/<delivery ID>
+	webhookDeliveryKeyPrefix = "ecs/webhook-delivery/"
+)
+
+// DefaultWebhookDeliveryRetention is the number of latest deliveries kept for each webhook
+const DefaultWebhookDeliveryRetention = 50
+
+// WebhookStore stores the webhooks of environments and the log of their deliveries. Deliveries
+// beyond the retention of the store are deleted every few deliveries of a webhook, and are never
+// listed.
+type WebhookStore interface {
+	PutWebhook(ctx context.Context, webhook types.Webhook) error
+	// GetWebhook returns nil if the environment has no webhook with the provided ID
+	GetWebhook(ctx context.Context, environmentName string, id string) (*types.Webhook, error)
+	// ListWebhooks returns the webhooks of the environment ordered by creation time
+	ListWebhooks(ctx context.Context, environmentName string) ([]types.Webhook, error)
+	// DeleteWebhook deletes the webhook along with its deliveries
+	DeleteWebhook(ctx context.Context, webhook types.Webhook) error
+	// DeleteWebhooks deletes all the webhooks of the environment along with their deliveries
+	DeleteWebhooks(ctx context.Context, environmentName string) error
+
+	// PutDelivery stores the delivery, unless its webhook has been deleted
+	PutDelivery(ctx context.Context, delivery types.WebhookDelivery) error
+	// ListDeliveries returns the retained deliveries of the webhook, latest first
+	ListDeliveries(ctx context.Context, environmentName string, webhookID string) ([]types.WebhookDelivery, error)
+}
+
+type webhookStore struct {
+	datastore DataStore
+	retention int
+	// trimInterval is the number of deliveries of a webhook put between deleting the ones
+	// beyond the retention
+	trimInterval int
+	puts         *deliveryCounter
+}
+
+// deliveryCounter counts the deliveries put for each webhook, by delivery key prefix, since its
+// deliveries were last trimmed
+type deliveryCounter struct {
+	lock   sync.Mutex
+	counts map[string]int
+}
+
+// NewWebhookStore creates a store that keeps the latest retention deliveries of each webhook
+func NewWebhookStore(ds DataStore, retention int) (WebhookStore, error) {
+	if ds == nil {
+		return nil, errors.New("The datastore cannot be nil")
+	}
+
+	if retention < 1 {
+		return nil, errors.Errorf("The delivery retention %d should be at least 1", retention)
+	}
+
+	trimInterval := retention / 5
+	if trimInterval < 1 {
+		trimInterval = 1
+	}
+
+	return webhookStore{
+		datastore:    ds,
+		retention:    retention,
+		trimInterval: trimInterval,
+		puts:         &deliveryCounter{counts: make(map[string]int)},
+	}, nil
+}
+
+func generateWebhookKeyPrefix(environmentName string) string {
+	return webhookKeyPrefix + environmentName + "/"
+}
+
+func generateWebhookKey(environmentName string, id string) string {
+	return generateWebhookKeyPrefix(environmentName) + id
+}
+
+func generateDeliveryKeyPrefix(environmentName string, webhookID string) string {
+	return webhookDeliveryKeyPrefix + environmentName + "/" + webhookID + "/"
+}
+
+func validateWebhookKey(environmentName string, id string) error {
+	if len(environmentName) == 0 {
+		return errors.New("Environment name is missing")
+	}
+	if len(id) == 0 {
+		return errors.New("Webhook ID is missing")
+	}
+	return nil
+}
+
+func (w webhookStore) PutWebhook(ctx context.Context, webhook types.Webhook) error {
+	err := validateWebhookKey(webhook.EnvironmentName, webhook.ID)
+	if err != nil {
+		return err
+	}
+
+	webhookJSON, err := json.MarshalJSON(webhook)
+	if err != nil {
+		return err
+	}
+
+	return w.datastore.Put(ctx, generateWebhookKey(webhook.EnvironmentName, webhook.ID), webhookJSON)
+}
+
+func (w webhookStore) GetWebhook(ctx context.Context, environmentName string, id string) (*types.Webhook, error) {
+	err := validateWebhookKey(environmentName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := w.datastore.Get(ctx, generateWebhookKey(environmentName, id))
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := decodeWebhooks(resp, environmentName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(webhooks) == 0 {
+		return nil, nil
+	}
+	return &webhooks[0], nil
+}
+
+func (w webhookStore) ListWebhooks(ctx context.Context, environmentName string) ([]types.Webhook, error) {
+	if len(environmentName) == 0 {
+		return nil, errors.New("Environment name is missing")
+	}
+
+	resp, err := w.datastore.GetWithPrefix(ctx, generateWebhookKeyPrefix(environmentName))
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeWebhooks(resp, environmentName)
+}
+
+func (w webhookStore) DeleteWebhook(ctx context.Context, webhook types.Webhook) error {
+	err := validateWebhookKey(webhook.EnvironmentName, webhook.ID)
+	if err != nil {
+		return err
+	}
+
+	err = w.datastore.Delete(ctx, generateWebhookKey(webhook.EnvironmentName, webhook.ID))
+	if err != nil {
+		return err
+	}
+
+	prefix := generateDeliveryKeyPrefix(webhook.EnvironmentName, webhook.ID)
+	w.puts.reset(prefix)
+
+	// the keys are deleted rather than the decoded deliveries, so that deliveries that cannot be
+	// decoded are deleted too
+	resp, err := w.datastore.GetWithPrefix(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for key := range resp {
+		// the prefix also matches the deliveries of environments whose name starts with
+		// environmentName/webhookID/
+		if strings.Contains(key[len(prefix):], "/") {
+			continue
+		}
+		err = w.datastore.Delete(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w webhookStore) DeleteWebhooks(ctx context.Context, environmentName string) error {
+	webhooks, err := w.ListWebhooks(ctx, environmentName)
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		err = w.DeleteWebhook(ctx, webhook)
+		if err != nil {
+			return errors.Wrapf(err, "Error deleting webhook %s of environment %s", webhook.ID, environmentName)
+		}
+	}
+	return nil
+}
+
+func (w webhookStore) PutDelivery(ctx context.Context, delivery types.WebhookDelivery) error {
+	err := validateWebhookKey(delivery.EnvironmentName, delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+	if len(delivery.ID) == 0 {
+		return errors.New("Delivery ID is missing")
+	}
+
+	deliveryJSON, err := json.MarshalJSON(delivery)
+	if err != nil {
+		return err
+	}
+
+	prefix := generateDeliveryKeyPrefix(delivery.EnvironmentName, delivery.WebhookID)
+	err = w.datastore.Put(ctx, prefix+delivery.ID, deliveryJSON)
+	if err != nil {
+		return err
+	}
+
+	// the webhook is checked after the delivery is put, so that a delivery racing with
+	// DeleteWebhook is deleted either here or there
+	webhook, err := w.GetWebhook(ctx, delivery.EnvironmentName, delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+	if webhook == nil {
+		return w.datastore.Delete(ctx, prefix+delivery.ID)
+	}
+
+	if !w.puts.increment(prefix, w.trimInterval) {
+		return nil
+	}
+	deliveries, err := w.listAllDeliveries(ctx, delivery.EnvironmentName, delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) <= w.retention {
+		return nil
+	}
+	return w.deleteDeliveries(ctx, deliveries[w.retention:])
+}
+
+func (w webhookStore) ListDeliveries(ctx context.Context, environmentName string, webhookID string) ([]types.WebhookDelivery, error) {
+	deliveries, err := w.listAllDeliveries(ctx, environmentName, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) > w.retention {
+		deliveries = deliveries[:w.retention]
+	}
+	return deliveries, nil
+}
+
+// listAllDeliveries returns the deliveries of the webhook, latest first, including the ones
+// beyond the retention that have not been deleted yet
+func (w webhookStore) listAllDeliveries(ctx context.Context, environmentName string,
+	webhookID string) ([]types.WebhookDelivery, error) {
+
+	err := validateWebhookKey(environmentName, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := w.datastore.GetWithPrefix(ctx, generateDeliveryKeyPrefix(environmentName, webhookID))
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]types.WebhookDelivery, 0, len(resp))
+	for _, v := range resp {
+		delivery := types.WebhookDelivery{}
+		err = json.UnmarshalJSON(v, &delivery)
+		if err != nil {
+			return nil, err
+		}
+
+		// the prefix also matches the deliveries of environments whose name starts with
+		// environmentName/
+		if delivery.EnvironmentName == environmentName && delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	sort.Sort(timeOrderedDeliveries(deliveries))
+	return deliveries, nil
+}
+
+func (w webhookStore) deleteDeliveries(ctx context.Context, deliveries []types.WebhookDelivery) error {
+	for _, delivery := range deliveries {
+		key := generateDeliveryKeyPrefix(delivery.EnvironmentName, delivery.WebhookID) + delivery.ID
+		err := w.datastore.Delete(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// increment counts a delivery put for the webhook with the delivery key prefix, and returns
+// whether interval deliveries have been put since the last time it returned true
+func (c *deliveryCounter) increment(prefix string, interval int) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.counts[prefix]++
+	if c.counts[prefix] < interval {
+		return false
+	}
+	delete(c.counts, prefix)
+	return true
+}
+
+// reset forgets the deliveries put for the webhook with the delivery key prefix
+func (c *deliveryCounter) reset(prefix string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.counts, prefix)
+}
+
+// decodeWebhooks returns the webhooks of the environment with the provided name ordered by
+// creation time
+func decodeWebhooks(resp map[string]string, environmentName string) ([]types.Webhook, error) {
+	webhooks := make([]types.Webhook, 0, len(resp))
+	for _, v := range resp {
+		webhook := types.Webhook{}
+		err := json.UnmarshalJSON(v, &webhook)
+		if err != nil {
+			return nil, err
+		}
+
+		// the prefix also matches the webhooks of environments whose name starts with
+		// environmentName/
+		if webhook.EnvironmentName == environmentName {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	sort.Sort(timeOrderedWebhooks(webhooks))
+	return webhooks, nil
+}
+
+type timeOrderedWebhooks []types.Webhook
+
+func (p timeOrderedWebhooks) Len() int {
+	return len(p)
+}
+
+// Less orders webhooks by creation time, and webhooks created at the same time by ID
+func (p timeOrderedWebhooks) Less(i, j int) bool {
+	if p[i].CreatedAt.Equal(p[j].CreatedAt) {
+		return p[i].ID < p[j].ID
+	}
+	return p[i].CreatedAt.Before(p[j].CreatedAt)
+}
+
+func (p timeOrderedWebhooks) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+
+type timeOrderedDeliveries []types.WebhookDelivery
+
+func (p timeOrderedDeliveries) Len() int {
+	return len(p)
+}
+
+// Less orders deliveries reverse-chronologically: latest creation time first, and deliveries
+// created at the same time by reverse ID
+func (p timeOrderedDeliveries) Less(i, j int) bool {
+	if p[i].CreatedAt.Equal(p[j].CreatedAt) {
+		return p[i].ID > p[j].ID
+	}
+	return p[i].CreatedAt.After(p[j].CreatedAt)
+}
+
+func (p timeOrderedDeliveries) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
@@ -0,0 +1,294 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/json"
+	"github.com/blox/blox/daemon-scheduler/pkg/mocks"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	webhookID1         = "webhookID1"
+	webhookKey1        = webhookKeyPrefix + environmentName1 + "/" + webhookID1
+	webhookKeyPrefix1  = webhookKeyPrefix + environmentName1 + "/"
+	deliveryKeyPrefix1 = webhookDeliveryKeyPrefix + environmentName1 + "/" + webhookID1 + "/"
+	deliveryRetention  = 2
+	webhookURL         = "https://hooks.example.com/blox"
+	webhookSecret      = "secret"
+	nestedEnvironment  = environmentName1 + "/nested"
+	nestedWebhookID    = "nestedWebhookID"
+	nestedWebhookKey   = webhookKeyPrefix + nestedEnvironment + "/" + nestedWebhookID
+	deliveryKey1       = deliveryKeyPrefix1 + "delivery"
+)
+
+type WebhookStoreTestSuite struct {
+	suite.Suite
+	datastore    *mocks.MockDataStore
+	webhookStore WebhookStore
+	ctx          context.Context
+	webhook      types.Webhook
+	webhookJSON  string
+}
+
+func (suite *WebhookStoreTestSuite) SetupTest() {
+	mockCtrl := gomock.NewController(suite.T())
+	var err error
+
+	suite.datastore = mocks.NewMockDataStore(mockCtrl)
+	suite.ctx = context.TODO()
+	suite.webhook = types.Webhook{
+		ID:              webhookID1,
+		EnvironmentName: environmentName1,
+		URL:             webhookURL,
+		Secret:          webhookSecret,
+		CreatedAt:       time.Now(),
+	}
+	suite.webhookJSON, err = json.MarshalJSON(suite.webhook)
+	assert.Nil(suite.T(), err, "Cannot initialize WebhookStoreTestSuite")
+	suite.webhookStore, err = NewWebhookStore(suite.datastore, deliveryRetention)
+	assert.Nil(suite.T(), err, "Cannot initialize WebhookStoreTestSuite")
+}
+
+func TestWebhookStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(WebhookStoreTestSuite))
+}
+
+func (suite *WebhookStoreTestSuite) TestNewWebhookStoreEmptyDataStore() {
+	_, err := NewWebhookStore(nil, deliveryRetention)
+	assert.Error(suite.T(), err, "Expected an error when datastore is nil")
+}
+
+func (suite *WebhookStoreTestSuite) TestNewWebhookStoreInvalidRetention() {
+	_, err := NewWebhookStore(suite.datastore, 0)
+	assert.Error(suite.T(), err, "Expected an error when the retention is not positive")
+}
+
+func (suite *WebhookStoreTestSuite) TestPutWebhookMissingID() {
+	err := suite.webhookStore.PutWebhook(suite.ctx, types.Webhook{EnvironmentName: environmentName1})
+	assert.Error(suite.T(), err, "Expected an error when the webhook ID is missing")
+}
+
+func (suite *WebhookStoreTestSuite) TestPutWebhook() {
+	suite.datastore.EXPECT().Put(suite.ctx, webhookKey1, suite.webhookJSON).Return(nil)
+
+	err := suite.webhookStore.PutWebhook(suite.ctx, suite.webhook)
+	assert.Nil(suite.T(), err, "Unexpected error when putting a webhook")
+}
+
+func (suite *WebhookStoreTestSuite) TestGetWebhookDataStoreGetFails() {
+	suite.datastore.EXPECT().Get(suite.ctx, webhookKey1).Return(nil, errors.New("Get failed"))
+
+	_, err := suite.webhookStore.GetWebhook(suite.ctx, environmentName1, webhookID1)
+	assert.Error(suite.T(), err, "Expected an error when datastore get fails")
+}
+
+func (suite *WebhookStoreTestSuite) TestGetWebhookDoesNotExist() {
+	suite.datastore.EXPECT().Get(suite.ctx, webhookKey1).Return(map[string]string{}, nil)
+
+	webhook, err := suite.webhookStore.GetWebhook(suite.ctx, environmentName1, webhookID1)
+	assert.Nil(suite.T(), err, "Unexpected error when the webhook does not exist")
+	assert.Nil(suite.T(), webhook, "Expected no webhook when it does not exist")
+}
+
+func (suite *WebhookStoreTestSuite) TestGetWebhook() {
+	suite.datastore.EXPECT().Get(suite.ctx, webhookKey1).Return(map[string]string{webhookKey1: suite.webhookJSON}, nil)
+
+	webhook, err := suite.webhookStore.GetWebhook(suite.ctx, environmentName1, webhookID1)
+	assert.Nil(suite.T(), err, "Unexpected error when getting a webhook")
+	assert.Exactly(suite.T(), suite.webhook.URL, webhook.URL, "Expected the stored webhook")
+	assert.Exactly(suite.T(), suite.webhook.Secret, webhook.Secret, "Expected the secret to be stored")
+}
+
+func (suite *WebhookStoreTestSuite) TestListWebhooksSkipsNestedEnvironments() {
+	nested := suite.webhook
+	nested.ID = nestedWebhookID
+	nested.EnvironmentName = nestedEnvironment
+	nestedJSON, err := json.MarshalJSON(nested)
+	assert.Nil(suite.T(), err, "Unexpected error when marshalling a webhook")
+
+	older := suite.webhook
+	older.ID = "older"
+	older.CreatedAt = suite.webhook.CreatedAt.Add(-time.Minute)
+	olderJSON, err := json.MarshalJSON(older)
+	assert.Nil(suite.T(), err, "Unexpected error when marshalling a webhook")
+
+	suite.datastore.EXPECT().GetWithPrefix(suite.ctx, webhookKeyPrefix1).Return(map[string]string{
+		webhookKey1:                 suite.webhookJSON,
+		webhookKeyPrefix1 + "older": olderJSON,
+		nestedWebhookKey:            nestedJSON,
+	}, nil)
+
+	webhooks, err := suite.webhookStore.ListWebhooks(suite.ctx, environmentName1)
+	assert.Nil(suite.T(), err, "Unexpected error when listing webhooks")
+	assert.Len(suite.T(), webhooks, 2, "Expected only the webhooks of the environment")
+	assert.Exactly(suite.T(), "older", webhooks[0].ID, "Expected the webhooks to be ordered by creation time")
+	assert.Exactly(suite.T(), webhookID1, webhooks[1].ID, "Expected the webhooks to be ordered by creation time")
+}
+
+func (suite *WebhookStoreTestSuite) TestDeleteWebhookDeletesDeliveries() {
+	delivery := suite.delivery("delivery", time.Now())
+	deliveryJSON, err := json.MarshalJSON(delivery)
+	assert.Nil(suite.T(), err, "Unexpected error when marshalling a delivery")
+
+	gomock.InOrder(
+		suite.datastore.EXPECT().Delete(suite.ctx, webhookKey1).Return(nil),
+		suite.datastore.EXPECT().GetWithPrefix(suite.ctx, deliveryKeyPrefix1).Return(map[string]string{
+			deliveryKey1: deliveryJSON,
+			deliveryKeyPrefix1 + "nested/webhook/delivery": deliveryJSON,
+		}, nil),
+		suite.datastore.EXPECT().Delete(suite.ctx, deliveryKey1).Return(nil),
+	)
+
+	err = suite.webhookStore.DeleteWebhook(suite.ctx, suite.webhook)
+	assert.Nil(suite.T(), err, "Unexpected error when deleting a webhook")
+}
+
+func (suite *WebhookStoreTestSuite) TestDeleteWebhookDeletesUndecodableDeliveries() {
+	gomock.InOrder(
+		suite.datastore.EXPECT().Delete(suite.ctx, webhookKey1).Return(nil),
+		suite.datastore.EXPECT().GetWithPrefix(suite.ctx, deliveryKeyPrefix1).
+			Return(map[string]string{deliveryKey1: "{"}, nil),
+		suite.datastore.EXPECT().Delete(suite.ctx, deliveryKey1).Return(nil),
+	)
+
+	err := suite.webhookStore.DeleteWebhook(suite.ctx, suite.webhook)
+	assert.Nil(suite.T(), err, "Unexpected error when deleting a webhook")
+}
+
+func (suite *WebhookStoreTestSuite) TestDeleteWebhooks() {
+	gomock.InOrder(
+		suite.datastore.EXPECT().GetWithPrefix(suite.ctx, webhookKeyPrefix1).
+			Return(map[string]string{webhookKey1: suite.webhookJSON}, nil),
+		suite.datastore.EXPECT().Delete(suite.ctx, webhookKey1).Return(nil),
+		suite.datastore.EXPECT().GetWithPrefix(suite.ctx, deliveryKeyPrefix1).Return(map[string]string{}, nil),
+	)
+
+	err := suite.webhookStore.DeleteWebhooks(suite.ctx, environmentName1)
+	assert.Nil(suite.T(), err, "Unexpected error when deleting the webhooks of an environment")
+}
+
+func (suite *WebhookStoreTestSuite) TestPutDeliveryDeletesDeliveriesBeyondRetention() {
+	now := time.Now()
+	stored := map[string]string{}
+	var latest types.WebhookDelivery
+	for i, id := range []string{"oldest", "older", "latest"} {
+		delivery := suite.delivery(id, now.Add(time.Duration(i)*time.Second))
+		deliveryJSON, err := json.MarshalJSON(delivery)
+		assert.Nil(suite.T(), err, "Unexpected error when marshalling a delivery")
+		stored[deliveryKeyPrefix1+id] = deliveryJSON
+		latest = delivery
+	}
+
+	gomock.InOrder(
+		suite.datastore.EXPECT().Put(suite.ctx, deliveryKeyPrefix1+"latest", stored[deliveryKeyPrefix1+"latest"]).Return(nil),
+		suite.datastore.EXPECT().Get(suite.ctx, webhookKey1).Return(map[string]string{webhookKey1: suite.webhookJSON}, nil),
+		suite.datastore.EXPECT().GetWithPrefix(suite.ctx, deliveryKeyPrefix1).Return(stored, nil),
+		suite.datastore.EXPECT().Delete(suite.ctx, deliveryKeyPrefix1+"oldest").Return(nil),
+	)
+
+	err := suite.webhookStore.PutDelivery(suite.ctx, latest)
+	assert.Nil(suite.T(), err, "Unexpected error when putting a delivery")
+}
+
+func (suite *WebhookStoreTestSuite) TestPutDeliveryTrimsEveryFewDeliveries() {
+	webhookStore, err := NewWebhookStore(suite.datastore, 10)
+	assert.Nil(suite.T(), err, "Unexpected error when creating a webhook store")
+
+	first, second := suite.delivery("first", time.Now()), suite.delivery("second", time.Now())
+	gomock.InOrder(
+		suite.datastore.EXPECT().Put(suite.ctx, deliveryKeyPrefix1+"first", gomock.Any()).Return(nil),
+		suite.datastore.EXPECT().Get(suite.ctx, webhookKey1).Return(map[string]string{webhookKey1: suite.webhookJSON}, nil),
+		suite.datastore.EXPECT().Put(suite.ctx, deliveryKeyPrefix1+"second", gomock.Any()).Return(nil),
+		suite.datastore.EXPECT().Get(suite.ctx, webhookKey1).Return(map[string]string{webhookKey1: suite.webhookJSON}, nil),
+		suite.datastore.EXPECT().GetWithPrefix(suite.ctx, deliveryKeyPrefix1).Return(map[string]string{}, nil),
+	)
+
+	err = webhookStore.PutDelivery(suite.ctx, first)
+	assert.Nil(suite.T(), err, "Unexpected error when putting a delivery")
+	err = webhookStore.PutDelivery(suite.ctx, second)
+	assert.Nil(suite.T(), err, "Unexpected error when putting a delivery")
+}
+
+func (suite *WebhookStoreTestSuite) TestPutDeliveryOfDeletedWebhook() {
+	gomock.InOrder(
+		suite.datastore.EXPECT().Put(suite.ctx, deliveryKey1, gomock.Any()).Return(nil),
+		suite.datastore.EXPECT().Get(suite.ctx, webhookKey1).Return(map[string]string{}, nil),
+		suite.datastore.EXPECT().Delete(suite.ctx, deliveryKey1).Return(nil),
+	)
+
+	err := suite.webhookStore.PutDelivery(suite.ctx, suite.delivery("delivery", time.Now()))
+	assert.Nil(suite.T(), err, "Unexpected error when putting a delivery of a deleted webhook")
+}
+
+func (suite *WebhookStoreTestSuite) TestPutDeliveryMissingID() {
+	err := suite.webhookStore.PutDelivery(suite.ctx, suite.delivery("", time.Now()))
+	assert.Error(suite.T(), err, "Expected an error when the delivery ID is missing")
+}
+
+func (suite *WebhookStoreTestSuite) TestListDeliveries() {
+	now := time.Now()
+	older, err := json.MarshalJSON(suite.delivery("older", now))
+	assert.Nil(suite.T(), err, "Unexpected error when marshalling a delivery")
+	latest, err := json.MarshalJSON(suite.delivery("latest", now.Add(time.Second)))
+	assert.Nil(suite.T(), err, "Unexpected error when marshalling a delivery")
+
+	suite.datastore.EXPECT().GetWithPrefix(suite.ctx, deliveryKeyPrefix1).Return(map[string]string{
+		deliveryKeyPrefix1 + "older":  older,
+		deliveryKeyPrefix1 + "latest": latest,
+	}, nil)
+
+	deliveries, err := suite.webhookStore.ListDeliveries(suite.ctx, environmentName1, webhookID1)
+	assert.Nil(suite.T(), err, "Unexpected error when listing deliveries")
+	assert.Len(suite.T(), deliveries, 2, "Expected both deliveries")
+	assert.Exactly(suite.T(), "latest", deliveries[0].ID, "Expected the latest delivery first")
+}
+
+func (suite *WebhookStoreTestSuite) delivery(id string, createdAt time.Time) types.WebhookDelivery {
+	return types.WebhookDelivery{
+		ID:              id,
+		WebhookID:       webhookID1,
+		EnvironmentName: environmentName1,
+		Event:           types.WebhookDeploymentStarted,
+		Status:          types.WebhookDeliverySucceeded,
+		Attempts:        1,
+		CreatedAt:       createdAt,
+	}
+}
+
+func (suite *WebhookStoreTestSuite) TestListDeliveriesBeyondRetention() {
+	now := time.Now()
+	stored := map[string]string{}
+	for i, id := range []string{"oldest", "older", "latest"} {
+		deliveryJSON, err := json.MarshalJSON(suite.delivery(id, now.Add(time.Duration(i)*time.Second)))
+		assert.Nil(suite.T(), err, "Unexpected error when marshalling a delivery")
+		stored[deliveryKeyPrefix1+id] = deliveryJSON
+	}
+
+	suite.datastore.EXPECT().GetWithPrefix(suite.ctx, deliveryKeyPrefix1).Return(stored, nil)
+
+	deliveries, err := suite.webhookStore.ListDeliveries(suite.ctx, environmentName1, webhookID1)
+	assert.Nil(suite.T(), err, "Unexpected error when listing deliveries")
+	assert.Len(suite.T(), deliveries, deliveryRetention, "Expected only the retained deliveries")
+	assert.Exactly(suite.T(), "latest", deliveries[0].ID, "Expected the latest delivery first")
+	assert.Exactly(suite.T(), "older", deliveries[1].ID, "Expected the oldest delivery to be left out")
+}
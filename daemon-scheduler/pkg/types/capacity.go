@@ -0,0 +1,171 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// Reasons an instance does not have the capacity to run a task. The first three are also the
+// reasons ECS fails to start a task for.
+const (
+	InsufficientCPUReason    = "RESOURCE:CPU"
+	InsufficientMemoryReason = "RESOURCE:MEMORY"
+	PortsInUseReason         = "RESOURCE:PORTS"
+	// StoppingTasksReason is recorded while the tasks of lower priority environments are being
+	// stopped to make room on the instance, which is reserved for the environment until then
+	StoppingTasksReason = "RESOURCE:STOPPING_LOWER_PRIORITY_TASKS"
+	// ReservedReason is recorded when the instance is reserved by a higher priority environment
+	ReservedReason = "RESOURCE:RESERVED"
+
+	resourceReasonPrefix = "RESOURCE:"
+	hostNetworkMode      = "host"
+	udpProtocol          = "udp"
+)
+
+// CapacityPolicy decides whether an environment makes room for its tasks on instances without
+// enough capacity by stopping the tasks of environments with a lower priority
+type CapacityPolicy struct {
+	// Priority ranks the environment against the environments whose tasks it may stop and that
+	// may stop its tasks
+	Priority int
+	// StopLowerPriorityTasks allows the environment to stop tasks of environments with a lower
+	// priority
+	StopLowerPriorityTasks bool
+}
+
+// Validate returns an error if the priority is negative
+func (p CapacityPolicy) Validate() error {
+	if p.Priority < 0 {
+		return errors.Errorf("Priority %d should not be negative", p.Priority)
+	}
+	return nil
+}
+
+// IsEmpty returns whether the policy has the default settings
+func (p CapacityPolicy) IsEmpty() bool {
+	return p == CapacityPolicy{}
+}
+
+// IsCapacityFailure returns whether the failure to start a task is due to a lack of capacity on
+// the instance rather than to the task
+func IsCapacityFailure(failure *ecs.Failure) bool {
+	return strings.HasPrefix(aws.StringValue(failure.Reason), resourceReasonPrefix)
+}
+
+// TaskResources are the resources a task uses on an instance
+type TaskResources struct {
+	CPU    int64
+	Memory int64
+	// Ports are the host ports of the task, e.g. "80/tcp"
+	Ports []string
+}
+
+// NewTaskResources returns the resources tasks of the task definition need. The memory
+// reservation of a container counts instead of its memory limit when set, as ECS does, and
+// dynamic host ports need no particular port.
+func NewTaskResources(taskDefinition *ecs.TaskDefinition) TaskResources {
+	resources := TaskResources{Ports: make([]string, 0)}
+	hostNetwork := aws.StringValue(taskDefinition.NetworkMode) == hostNetworkMode
+	for _, container := range taskDefinition.ContainerDefinitions {
+		resources.CPU += aws.Int64Value(container.Cpu)
+		if container.MemoryReservation != nil {
+			resources.Memory += aws.Int64Value(container.MemoryReservation)
+		} else {
+			resources.Memory += aws.Int64Value(container.Memory)
+		}
+
+		for _, mapping := range container.PortMappings {
+			port := aws.Int64Value(mapping.HostPort)
+			if hostNetwork && port == 0 {
+				port = aws.Int64Value(mapping.ContainerPort)
+			}
+			if port != 0 {
+				resources.Ports = append(resources.Ports, HostPort(port, aws.StringValue(mapping.Protocol)))
+			}
+		}
+	}
+	return resources
+}
+
+// HostPort returns how a host port is named in TaskResources and InstanceResources
+func HostPort(port int64, protocol string) string {
+	if protocol != udpProtocol {
+		protocol = "tcp"
+	}
+	return fmt.Sprintf("%d/%s", port, protocol)
+}
+
+// InstanceResources are the resources left on an instance
+type InstanceResources struct {
+	CPU    int64
+	Memory int64
+	// UsedPorts are the host ports in use, e.g. "80/tcp"
+	UsedPorts map[string]bool
+}
+
+// InsufficientReason returns why the task does not fit in the resources, or an empty string if
+// it does
+func (r InstanceResources) InsufficientReason(task TaskResources) string {
+	if task.CPU > r.CPU {
+		return InsufficientCPUReason
+	}
+	if task.Memory > r.Memory {
+		return InsufficientMemoryReason
+	}
+	for _, port := range task.Ports {
+		if r.UsedPorts[port] {
+			return PortsInUseReason
+		}
+	}
+	return ""
+}
+
+// Release returns the resources left once the task has stopped
+func (r InstanceResources) Release(task TaskResources) InstanceResources {
+	usedPorts := make(map[string]bool, len(r.UsedPorts))
+	for port := range r.UsedPorts {
+		usedPorts[port] = true
+	}
+	for _, port := range task.Ports {
+		delete(usedPorts, port)
+	}
+
+	return InstanceResources{
+		CPU:       r.CPU + task.CPU,
+		Memory:    r.Memory + task.Memory,
+		UsedPorts: usedPorts,
+	}
+}
+
+// ReservesInstance returns whether the latest deployment of the environment is stopping tasks of
+// lower priority environments to make room on the instance
+func (e *Environment) ReservesInstance(instanceARN string) bool {
+	latest := e.LatestDeployment()
+	if latest == nil {
+		return false
+	}
+
+	for _, f := range latest.InsufficientCapacity {
+		if aws.StringValue(f.Arn) == instanceARN && aws.StringValue(f.Reason) == StoppingTasksReason {
+			return true
+		}
+	}
+	return false
+}
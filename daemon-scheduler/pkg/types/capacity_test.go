@@ -0,0 +1,108 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapacityPolicyValidate(t *testing.T) {
+	assert.Nil(t, CapacityPolicy{}.Validate(), "Unexpected error validating the default policy")
+	assert.Nil(t, CapacityPolicy{Priority: 10, StopLowerPriorityTasks: true}.Validate(), "Unexpected error validating a valid policy")
+	assert.Error(t, CapacityPolicy{Priority: -1}.Validate(), "Expected an error validating a negative priority")
+}
+
+func TestNewTaskResources(t *testing.T) {
+	taskDefinition := &ecs.TaskDefinition{
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{
+				Cpu:    aws.Int64(256),
+				Memory: aws.Int64(512),
+				PortMappings: []*ecs.PortMapping{
+					{ContainerPort: aws.Int64(80), HostPort: aws.Int64(8080)},
+					{ContainerPort: aws.Int64(81), HostPort: aws.Int64(0)},
+				},
+			},
+			{
+				Cpu:               aws.Int64(128),
+				Memory:            aws.Int64(1024),
+				MemoryReservation: aws.Int64(256),
+				PortMappings:      []*ecs.PortMapping{{ContainerPort: aws.Int64(53), HostPort: aws.Int64(53), Protocol: aws.String("udp")}},
+			},
+		},
+	}
+
+	resources := NewTaskResources(taskDefinition)
+	assert.Exactly(t, int64(384), resources.CPU, "Expected the CPU of every container")
+	assert.Exactly(t, int64(768), resources.Memory, "Expected the memory reservation to count instead of the memory limit")
+	assert.Equal(t, []string{"8080/tcp", "53/udp"}, resources.Ports, "Expected the static host ports only")
+
+	taskDefinition.NetworkMode = aws.String("host")
+	assert.Equal(t, []string{"8080/tcp", "81/tcp", "53/udp"}, NewTaskResources(taskDefinition).Ports,
+		"Expected the container ports to be host ports in host network mode")
+}
+
+func TestInstanceResourcesInsufficientReason(t *testing.T) {
+	remaining := InstanceResources{CPU: 512, Memory: 1024, UsedPorts: map[string]bool{"80/tcp": true}}
+
+	assert.Empty(t, remaining.InsufficientReason(TaskResources{CPU: 512, Memory: 1024, Ports: []string{"80/udp"}}),
+		"Expected a task using every resource left to fit")
+	assert.Exactly(t, InsufficientCPUReason, remaining.InsufficientReason(TaskResources{CPU: 1024}), "Expected the task to be short of CPU")
+	assert.Exactly(t, InsufficientMemoryReason, remaining.InsufficientReason(TaskResources{Memory: 2048}), "Expected the task to be short of memory")
+	assert.Exactly(t, PortsInUseReason, remaining.InsufficientReason(TaskResources{Ports: []string{"80/tcp"}}), "Expected the port to be in use")
+}
+
+func TestInstanceResourcesRelease(t *testing.T) {
+	remaining := InstanceResources{CPU: 0, Memory: 0, UsedPorts: map[string]bool{"80/tcp": true, "22/tcp": true}}
+	released := remaining.Release(TaskResources{CPU: 256, Memory: 512, Ports: []string{"80/tcp"}})
+
+	assert.Equal(t, InstanceResources{CPU: 256, Memory: 512, UsedPorts: map[string]bool{"22/tcp": true}}, released,
+		"Expected the resources of the task to be released")
+	assert.True(t, remaining.UsedPorts["80/tcp"], "Expected the resources released from to be unchanged")
+}
+
+func TestUpdateInsufficientCapacity(t *testing.T) {
+	deployment := Deployment{InsufficientCapacity: []*ecs.Failure{
+		{Arn: aws.String("instance-1"), Reason: aws.String(InsufficientCPUReason)},
+		{Arn: aws.String("instance-2"), Reason: aws.String(InsufficientCPUReason)},
+	}}
+	insufficient := []*ecs.Failure{{Arn: aws.String("instance-3"), Reason: aws.String(PortsInUseReason)}}
+
+	updated := deployment.UpdateInsufficientCapacity([]*string{aws.String("instance-1"), aws.String("instance-3")}, insufficient)
+	assert.Equal(t, []*ecs.Failure{deployment.InsufficientCapacity[1], insufficient[0]}, updated.InsufficientCapacity,
+		"Expected the instances tasks were started on to be replaced")
+}
+
+func TestIsCapacityFailure(t *testing.T) {
+	assert.True(t, IsCapacityFailure(&ecs.Failure{Reason: aws.String(InsufficientMemoryReason)}), "Expected memory to be a capacity failure")
+	assert.False(t, IsCapacityFailure(&ecs.Failure{Reason: aws.String("AGENT")}), "Expected a disconnected agent not to be a capacity failure")
+}
+
+func TestReservesInstance(t *testing.T) {
+	environment, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error creating an environment")
+	assert.False(t, environment.ReservesInstance("instance-1"), "Expected no instance to be reserved without deployments")
+
+	environment.InProgressDeploymentID = "deployment-1"
+	environment.Deployments["deployment-1"] = Deployment{ID: "deployment-1", InsufficientCapacity: []*ecs.Failure{
+		{Arn: aws.String("instance-1"), Reason: aws.String(StoppingTasksReason)},
+		{Arn: aws.String("instance-2"), Reason: aws.String(InsufficientCPUReason)},
+	}}
+	assert.True(t, environment.ReservesInstance("instance-1"), "Expected the instance tasks are stopped on to be reserved")
+	assert.False(t, environment.ReservesInstance("instance-2"), "Expected an instance short of capacity not to be reserved")
+}
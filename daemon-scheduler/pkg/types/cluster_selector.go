@@ -0,0 +1,164 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxClusterTagKeyLength   = 128
+	maxClusterTagValueLength = 256
+)
+
+// ClusterSelector picks the clusters an environment is deployed to. A cluster is selected if it
+// is one of Clusters, if its name matches NamePattern or if it has all of Tags, so an empty
+// selector selects nothing.
+type ClusterSelector struct {
+	// Clusters lists the names or ARNs of clusters to deploy to
+	Clusters []string
+	// NamePattern is a shell pattern, e.g. "prod-*", matched against the names of the clusters
+	// in the cluster state
+	NamePattern string
+	// Tags are the ECS tags, keyed by tag key, a cluster needs to have all of to be selected
+	Tags map[string]string
+}
+
+// IsEmpty returns whether the selector selects no cluster
+func (s ClusterSelector) IsEmpty() bool {
+	return len(s.Clusters) == 0 && s.NamePattern == "" && len(s.Tags) == 0
+}
+
+// HasTags returns whether the selector picks clusters by their tags, which are not known from
+// the cluster name
+func (s ClusterSelector) HasTags() bool {
+	return len(s.Tags) > 0
+}
+
+// Validate returns an error if a listed cluster is empty, the name pattern is malformed or a
+// tag is not a valid ECS tag
+func (s ClusterSelector) Validate() error {
+	for _, cluster := range s.Clusters {
+		if cluster == "" {
+			return errors.New("Clusters should not be empty")
+		}
+	}
+
+	if s.NamePattern != "" {
+		if _, err := path.Match(s.NamePattern, ""); err != nil {
+			return errors.Wrapf(err, "Invalid cluster name pattern %s", s.NamePattern)
+		}
+	}
+
+	for key, value := range s.Tags {
+		if key == "" {
+			return errors.New("Cluster tag keys should not be empty")
+		}
+		if len(key) > maxClusterTagKeyLength {
+			return errors.Errorf("Cluster tag key %s is longer than %d characters", key, maxClusterTagKeyLength)
+		}
+		if len(value) > maxClusterTagValueLength {
+			return errors.Errorf("Value of cluster tag %s is longer than %d characters", key, maxClusterTagValueLength)
+		}
+	}
+	return nil
+}
+
+// Matches returns whether the cluster, named either by name or ARN, is selected by its name.
+// Clusters selected by their tags are matched with MatchesTags.
+func (s ClusterSelector) Matches(cluster string) bool {
+	name := clusterName(cluster)
+	for _, c := range s.Clusters {
+		if c == cluster || clusterName(c) == name {
+			return true
+		}
+	}
+
+	if s.NamePattern == "" {
+		return false
+	}
+	matched, err := path.Match(s.NamePattern, name)
+	return err == nil && matched
+}
+
+// MatchesTags returns whether a cluster with the tags is selected by its tags
+func (s ClusterSelector) MatchesTags(tags map[string]string) bool {
+	if len(s.Tags) == 0 {
+		return false
+	}
+	for key, value := range s.Tags {
+		if v, ok := tags[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// HasClusterSelector returns whether the environment is deployed to the clusters picked by its
+// cluster selector rather than to a single cluster
+func (e *Environment) HasClusterSelector() bool {
+	return !e.ClusterSelector.IsEmpty()
+}
+
+// TargetsCluster returns whether the environment is deployed to the cluster, named either by
+// name or ARN. Clusters picked by the tags of the cluster selector are not known from their name
+// and have to be checked with ClusterSelector.MatchesTags.
+func (e *Environment) TargetsCluster(cluster string) bool {
+	if e.HasClusterSelector() {
+		return e.ClusterSelector.Matches(cluster)
+	}
+	return e.Cluster == cluster || clusterName(e.Cluster) == clusterName(cluster)
+}
+
+// DeployedClusters returns the clusters, in order, any deployment of the environment started
+// tasks in
+func (e *Environment) DeployedClusters() []string {
+	if !e.HasClusterSelector() {
+		return []string{e.Cluster}
+	}
+
+	seen := make(map[string]bool)
+	clusters := make([]string, 0)
+	for _, d := range e.Deployments {
+		for cluster := range d.Clusters {
+			if !seen[cluster] {
+				seen[cluster] = true
+				clusters = append(clusters, cluster)
+			}
+		}
+	}
+	sort.Strings(clusters)
+	return clusters
+}
+
+// DeployedTo returns whether any deployment of the environment started tasks in the cluster,
+// named either by name or ARN
+func (e *Environment) DeployedTo(cluster string) bool {
+	name := clusterName(cluster)
+	for _, c := range e.DeployedClusters() {
+		if c == cluster || clusterName(c) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterName returns the name of the cluster from either its name or its ARN
+func clusterName(cluster string) string {
+	return cluster[strings.LastIndex(cluster, "/")+1:]
+}
@@ -0,0 +1,94 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const otherCluster = "arn:aws:ecs:us-east-1:123456789123:cluster/other"
+
+func TestClusterSelectorValidate(t *testing.T) {
+	assert.Nil(t, ClusterSelector{Clusters: []string{cluster}, NamePattern: "prod-*"}.Validate(),
+		"Unexpected error validating a cluster selector")
+	assert.Error(t, ClusterSelector{Clusters: []string{""}}.Validate(), "Expected an error when a cluster is empty")
+	assert.Error(t, ClusterSelector{NamePattern: "prod-["}.Validate(), "Expected an error when the pattern is malformed")
+	assert.Nil(t, ClusterSelector{Tags: map[string]string{"team": "blox", "shared": ""}}.Validate(),
+		"Unexpected error validating a cluster selector with tags")
+	assert.Error(t, ClusterSelector{Tags: map[string]string{"": "blox"}}.Validate(),
+		"Expected an error when a tag key is empty")
+	assert.Error(t, ClusterSelector{Tags: map[string]string{strings.Repeat("k", 129): "blox"}}.Validate(),
+		"Expected an error when a tag key is too long")
+	assert.Error(t, ClusterSelector{Tags: map[string]string{"team": strings.Repeat("v", 257)}}.Validate(),
+		"Expected an error when a tag value is too long")
+}
+
+func TestClusterSelectorMatches(t *testing.T) {
+	byName := ClusterSelector{Clusters: []string{"test"}}
+	assert.True(t, byName.Matches(cluster), "Expected a listed cluster to match by ARN")
+	assert.True(t, byName.Matches("test"), "Expected a listed cluster to match by name")
+	assert.False(t, byName.Matches(otherCluster), "Expected a cluster that is not listed not to match")
+
+	byPattern := ClusterSelector{NamePattern: "te*"}
+	assert.True(t, byPattern.Matches(cluster), "Expected a cluster whose name matches the pattern to match")
+	assert.False(t, byPattern.Matches(otherCluster), "Expected a cluster whose name does not match not to match")
+
+	assert.False(t, ClusterSelector{}.Matches(cluster), "Expected an empty selector to match no cluster")
+}
+
+func TestClusterSelectorMatchesTags(t *testing.T) {
+	byTags := ClusterSelector{Tags: map[string]string{"team": "blox", "stage": "prod"}}
+	assert.False(t, byTags.IsEmpty(), "Expected a selector with tags not to be empty")
+	assert.False(t, byTags.Matches(cluster), "Expected tags not to be matched by cluster name")
+	assert.True(t, byTags.MatchesTags(map[string]string{"team": "blox", "stage": "prod", "owner": "ops"}),
+		"Expected a cluster with all the tags to match")
+	assert.False(t, byTags.MatchesTags(map[string]string{"team": "blox"}),
+		"Expected a cluster missing a tag not to match")
+	assert.False(t, byTags.MatchesTags(map[string]string{"team": "blox", "stage": "test"}),
+		"Expected a cluster with another tag value not to match")
+
+	assert.False(t, ClusterSelector{}.MatchesTags(map[string]string{"team": "blox"}),
+		"Expected a selector without tags to match no cluster by its tags")
+}
+
+func TestNewEnvironmentForClusters(t *testing.T) {
+	_, err := NewEnvironmentForClusters(environmentName, taskDefinition, ClusterSelector{})
+	assert.Error(t, err, "Expected an error when the selector is empty")
+
+	environment, err := NewEnvironmentForClusters(environmentName, taskDefinition, ClusterSelector{NamePattern: "te*"})
+	assert.Nil(t, err, "Unexpected error when creating an environment with a cluster selector")
+	assert.Empty(t, environment.Cluster, "Expected no single cluster")
+	assert.True(t, environment.TargetsCluster(cluster), "Expected the environment to target a selected cluster")
+	assert.False(t, environment.TargetsCluster(otherCluster), "Expected the environment not to target other clusters")
+}
+
+func TestDeployedClusters(t *testing.T) {
+	environment, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error when creating an environment")
+	assert.Exactly(t, []string{cluster}, environment.DeployedClusters(), "Expected the cluster of the environment")
+
+	environment, err = NewEnvironmentForClusters(environmentName, taskDefinition, ClusterSelector{NamePattern: "*"})
+	assert.Nil(t, err, "Unexpected error when creating an environment with a cluster selector")
+	environment.Deployments = map[string]Deployment{
+		"1": {ID: "1", Clusters: map[string]ClusterDeployment{otherCluster: {}}},
+		"2": {ID: "2", Clusters: map[string]ClusterDeployment{cluster: {}, otherCluster: {}}},
+	}
+	assert.Exactly(t, []string{otherCluster, cluster}, environment.DeployedClusters(),
+		"Expected every cluster a deployment started tasks in")
+	assert.True(t, environment.DeployedTo("test"), "Expected the environment to be deployed to a cluster by name")
+	assert.False(t, environment.DeployedTo("missing"), "Expected the environment not to be deployed to other clusters")
+}
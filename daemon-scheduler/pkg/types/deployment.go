@@ -16,6 +16,7 @@ package types
 import (
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/pkg/errors"
 	"github.com/satori/go.uuid"
@@ -27,8 +28,34 @@ const (
 	DeploymentPending DeploymentStatus = iota
 	DeploymentInProgress
 	DeploymentCompleted
+	// DeploymentFailed deployments were stopped by the rollback policy of their environment
+	DeploymentFailed
+	// DeploymentPaused deployments are not rolled out to further instances until they are resumed
+	DeploymentPaused
+	// DeploymentCancelled deployments are never rolled out to further instances
+	DeploymentCancelled
 )
 
+// String returns the name of the status in the API and webhook payloads
+func (s DeploymentStatus) String() string {
+	switch s {
+	case DeploymentPending:
+		return "pending"
+	case DeploymentInProgress:
+		return "running"
+	case DeploymentCompleted:
+		return "completed"
+	case DeploymentFailed:
+		return "failed"
+	case DeploymentPaused:
+		return "paused"
+	case DeploymentCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
 type DeploymentHealth uint8
 
 const (
@@ -36,6 +63,14 @@ const (
 	DeploymentUnhealthy
 )
 
+// String returns the name of the health in the API and webhook payloads
+func (h DeploymentHealth) String() string {
+	if h == DeploymentUnhealthy {
+		return "unhealthy"
+	}
+	return "healthy"
+}
+
 type Deployment struct {
 	ID               string
 	Status           DeploymentStatus
@@ -43,10 +78,68 @@ type Deployment struct {
 	TaskDefinition   string
 	DesiredTaskCount int
 	Token            string
+	// TaskOverrides are the overrides of the environment when the deployment was created, which
+	// its tasks are started with
+	TaskOverrides TaskOverrides
+	// NotBefore is the earliest time the deployment starts. It stays pending until then.
+	NotBefore time.Time
 
 	FailedInstances []*ecs.Failure
 	StartTime       time.Time
 	EndTime         time.Time
+
+	// InsufficientCapacity records the instances tasks were not started on for lack of CPU,
+	// memory or ports, which are retried by the scheduler and do not count as failures
+	InsufficientCapacity []*ecs.Failure
+
+	// Batches record the progress of a rollout of the deployment to instances running tasks of
+	// earlier deployments. They are only used when the environment has a rolling strategy.
+	Batches []DeploymentBatch
+	// RolloutCompleted is set once no instance runs tasks of earlier deployments only
+	RolloutCompleted bool
+
+	// Clusters records the progress of the deployment in each cluster it started tasks in, keyed
+	// by cluster ARN. It is only used when the environment has a cluster selector, in which case
+	// DesiredTaskCount and FailedInstances add up every cluster.
+	Clusters map[string]ClusterDeployment
+
+	// RollbackReason records why the deployment failed or was cancelled, or why the deployment it
+	// rolled back did
+	RollbackReason string
+	// RolledBackBy is the ID of the deployment that rolled back this failed or cancelled deployment
+	RolledBackBy string
+	// RollbackOf is the ID of the failed or cancelled deployment this deployment rolled back
+	RollbackOf string
+
+	// PausedStatus is the status of a paused deployment before it was paused, which it gets
+	// back when it is resumed
+	PausedStatus DeploymentStatus
+
+	// TasksStopped is set once no task of the finished deployment is left running. Tasks are
+	// recognised as tasks of the environment by the deployment that started them, so deployments
+	// are only pruned once it is set.
+	TasksStopped bool
+}
+
+// IsFinished returns whether the deployment completed, failed or was cancelled
+func (d Deployment) IsFinished() bool {
+	return d.Status == DeploymentCompleted || d.Status == DeploymentFailed || d.Status == DeploymentCancelled
+}
+
+// ClusterDeployment is the progress of a deployment in one of the clusters of its environment
+type ClusterDeployment struct {
+	// Status is DeploymentInProgress until the tasks started in the cluster have left pending
+	Status           DeploymentStatus
+	Health           DeploymentHealth
+	DesiredTaskCount int
+	FailedInstances  []*ecs.Failure
+	// InsufficientCapacity records the instances of the cluster found short of capacity
+	InsufficientCapacity []*ecs.Failure
+
+	// Batches and RolloutCompleted record the progress of the rollout in the cluster when the
+	// environment has a rolling strategy
+	Batches          []DeploymentBatch
+	RolloutCompleted bool
 }
 
 func NewDeployment(taskDefinition string, token string) (*Deployment, error) {
@@ -76,6 +169,10 @@ func (d Deployment) UpdateDeploymentInProgress(
 		return nil, errors.New("Deployment cannot move from completed to in-progress")
 	}
 
+	if d.Status == DeploymentFailed {
+		return nil, errors.New("Deployment cannot move from failed to in-progress")
+	}
+
 	if failedInstances == nil || len(failedInstances) == 0 {
 		d.Health = DeploymentHealthy
 	} else {
@@ -89,6 +186,48 @@ func (d Deployment) UpdateDeploymentInProgress(
 	return &d, nil
 }
 
+// UpdateInsufficientCapacity records which of the instances tasks were just started on lacked
+// capacity, replacing what was recorded for them before
+func (d Deployment) UpdateInsufficientCapacity(instanceARNs []*string, insufficient []*ecs.Failure) *Deployment {
+	d.InsufficientCapacity = mergeInsufficientCapacity(d.InsufficientCapacity, instanceARNs, insufficient)
+	return &d
+}
+
+// UpdateClusterInsufficientCapacity records which of the instances of the cluster tasks were
+// just started on lacked capacity, both for the cluster and across clusters
+func (d Deployment) UpdateClusterInsufficientCapacity(cluster string, instanceARNs []*string,
+	insufficient []*ecs.Failure) *Deployment {
+
+	clusters := make(map[string]ClusterDeployment, len(d.Clusters)+1)
+	for name, c := range d.Clusters {
+		clusters[name] = c
+	}
+	c := clusters[cluster]
+	c.InsufficientCapacity = mergeInsufficientCapacity(c.InsufficientCapacity, instanceARNs, insufficient)
+	clusters[cluster] = c
+
+	updated := d.UpdateInsufficientCapacity(instanceARNs, insufficient)
+	updated.Clusters = clusters
+	return updated
+}
+
+// mergeInsufficientCapacity returns the recorded failures of the instances that were not just
+// attempted, followed by the insufficient ones of the attempt
+func mergeInsufficientCapacity(recorded []*ecs.Failure, instanceARNs []*string, insufficient []*ecs.Failure) []*ecs.Failure {
+	attempted := make(map[string]bool, len(instanceARNs))
+	for _, arn := range instanceARNs {
+		attempted[aws.StringValue(arn)] = true
+	}
+
+	capacity := make([]*ecs.Failure, 0, len(recorded)+len(insufficient))
+	for _, f := range recorded {
+		if !attempted[aws.StringValue(f.Arn)] {
+			capacity = append(capacity, f)
+		}
+	}
+	return append(capacity, insufficient...)
+}
+
 func (d Deployment) UpdateDeploymentCompleted(failedInstances []*ecs.Failure) (*Deployment, error) {
 	d.Status = DeploymentCompleted
 
@@ -103,3 +242,68 @@ func (d Deployment) UpdateDeploymentCompleted(failedInstances []*ecs.Failure) (*
 
 	return &d, nil
 }
+
+// UpdateDeploymentFailed marks the deployment as failed for the provided reason
+func (d Deployment) UpdateDeploymentFailed(failedInstances []*ecs.Failure, reason string) (*Deployment, error) {
+	if d.Status == DeploymentCompleted {
+		return nil, errors.New("Deployment cannot move from completed to failed")
+	}
+
+	d.Status = DeploymentFailed
+	d.Health = DeploymentUnhealthy
+	d.FailedInstances = failedInstances
+	d.RollbackReason = reason
+	d.EndTime = time.Now()
+
+	return &d, nil
+}
+
+// UpdateClusterInProgress records that the deployment started tasks in the cluster, and moves
+// the deployment in progress with the desired task count and failures of every cluster
+func (d Deployment) UpdateClusterInProgress(cluster string,
+	desiredTaskCount int, failedInstances []*ecs.Failure) (*Deployment, error) {
+
+	clusters := make(map[string]ClusterDeployment, len(d.Clusters)+1)
+	for name, c := range d.Clusters {
+		clusters[name] = c
+	}
+
+	c := clusters[cluster]
+	c.Status = DeploymentInProgress
+	c.DesiredTaskCount = desiredTaskCount
+	c.FailedInstances = failedInstances
+	c.Health = DeploymentHealthy
+	if len(failedInstances) > 0 {
+		c.Health = DeploymentUnhealthy
+	}
+	clusters[cluster] = c
+
+	total := 0
+	var failures []*ecs.Failure
+	for _, c := range clusters {
+		total += c.DesiredTaskCount
+		failures = append(failures, c.FailedInstances...)
+	}
+
+	updated, err := d.UpdateDeploymentInProgress(total, failures)
+	if err != nil {
+		return nil, err
+	}
+	updated.Clusters = clusters
+
+	return updated, nil
+}
+
+// ClustersRolledOut returns whether the rollout completed in every cluster the deployment
+// started tasks in
+func (d Deployment) ClustersRolledOut() bool {
+	if len(d.Clusters) == 0 {
+		return false
+	}
+	for _, c := range d.Clusters {
+		if !c.RolloutCompleted {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"time"
+)
+
+// DeploymentInstance is the state of a deployment on one of the instances it targeted
+type DeploymentInstance struct {
+	Cluster     string
+	InstanceARN string
+	// Tasks are the tasks the deployment started on the instance, latest first, including the
+	// ones that have stopped since
+	Tasks []DeploymentTask
+	// FailureReason is why ECS did not start the task of the deployment on the instance, if it did not
+	FailureReason string
+}
+
+// DeploymentTask is a task started by a deployment
+type DeploymentTask struct {
+	TaskARN    string
+	LastStatus string
+	// StartedAt is zero until the task is running
+	StartedAt time.Time
+	// StoppedAt is zero until the task has stopped
+	StoppedAt     time.Time
+	StoppedReason string
+}
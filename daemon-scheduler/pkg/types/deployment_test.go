@@ -138,3 +138,56 @@ func (suite *DeploymentTestSuite) TestUpdateDeploymentCompletedHealthy() {
 	assert.Exactly(suite.T(), taskDefinition, d.TaskDefinition, "Deployment taskDefintion does not match expected")
 	assert.Empty(suite.T(), d.FailedInstances, "Deployment failed instances does not match expected")
 }
+
+func (suite *DeploymentTestSuite) TestUpdateClusterInProgress() {
+	d, err := suite.deployment.UpdateClusterInProgress(cluster, 2, nil)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the deployment in a cluster")
+	d, err = d.UpdateClusterInProgress(otherCluster, 3, suite.failures)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the deployment in another cluster")
+
+	assert.Exactly(suite.T(), DeploymentInProgress, d.Status, "Expected the deployment to be in progress")
+	assert.Exactly(suite.T(), 5, d.DesiredTaskCount, "Expected the desired tasks of every cluster")
+	assert.Exactly(suite.T(), suite.failures, d.FailedInstances, "Expected the failures of every cluster")
+	assert.Exactly(suite.T(), DeploymentUnhealthy, d.Health, "Expected the deployment to be unhealthy")
+	assert.Exactly(suite.T(), DeploymentHealthy, d.Clusters[cluster].Health, "Expected the first cluster to be healthy")
+	assert.Exactly(suite.T(), 3, d.Clusters[otherCluster].DesiredTaskCount, "Expected the desired tasks of the cluster")
+	assert.Empty(suite.T(), suite.deployment.Clusters, "Expected the original deployment to be left unchanged")
+}
+
+func (suite *DeploymentTestSuite) TestUpdateClusterInsufficientCapacity() {
+	instanceARNs := []*string{aws.String(instanceArn)}
+	insufficient := []*ecs.Failure{{Arn: aws.String(instanceArn), Reason: aws.String(InsufficientMemoryReason)}}
+
+	d := suite.deployment.UpdateClusterInsufficientCapacity(cluster, instanceARNs, insufficient)
+	assert.Exactly(suite.T(), insufficient, d.Clusters[cluster].InsufficientCapacity,
+		"Expected the instances short of capacity in the cluster")
+	assert.Exactly(suite.T(), insufficient, d.InsufficientCapacity, "Expected the instances short of capacity in every cluster")
+
+	d = d.UpdateClusterInsufficientCapacity(cluster, instanceARNs, nil)
+	assert.Empty(suite.T(), d.Clusters[cluster].InsufficientCapacity, "Expected the instance to no longer be short of capacity")
+	assert.Empty(suite.T(), suite.deployment.Clusters, "Expected the original deployment to be left unchanged")
+}
+
+func (suite *DeploymentTestSuite) TestClustersRolledOut() {
+	assert.False(suite.T(), suite.deployment.ClustersRolledOut(), "Expected no rollout without clusters")
+
+	suite.deployment.Clusters = map[string]ClusterDeployment{
+		cluster:      {RolloutCompleted: true},
+		otherCluster: {},
+	}
+	assert.False(suite.T(), suite.deployment.ClustersRolledOut(), "Expected the rollout to wait for every cluster")
+
+	suite.deployment.Clusters[otherCluster] = ClusterDeployment{RolloutCompleted: true}
+	assert.True(suite.T(), suite.deployment.ClustersRolledOut(), "Expected the rollout to be completed")
+}
+
+func (suite *DeploymentTestSuite) TestStatusAndHealthStrings() {
+	assert.Exactly(suite.T(), "pending", DeploymentPending.String(), "")
+	assert.Exactly(suite.T(), "running", DeploymentInProgress.String(), "")
+	assert.Exactly(suite.T(), "cancelled", DeploymentCancelled.String(), "")
+	assert.Exactly(suite.T(), "unknown", DeploymentStatus(255).String(), "")
+	assert.Exactly(suite.T(), "unhealthy", DeploymentUnhealthy.String(), "")
+	assert.Exactly(suite.T(), "healthy", DeploymentHealthy.String(), "")
+	assert.Exactly(suite.T(), "unhealthy", EnvironmentUnhealthy.String(), "")
+	assert.Exactly(suite.T(), "healthy", EnvironmentHealthy.String(), "")
+}
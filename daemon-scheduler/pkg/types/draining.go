@@ -0,0 +1,97 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// DrainingPolicy decides what happens to the tasks of an environment on instances that are being
+// drained. No new task of the environment is started on a draining instance whatever the policy.
+type DrainingPolicy string
+
+const (
+	// DrainingKeepUntilLast keeps the tasks of the environment running on a draining instance until
+	// every task left on it was started by an environment, and then stops them
+	DrainingKeepUntilLast DrainingPolicy = "keep-until-last"
+	// DrainingStopImmediately stops the tasks of the environment as soon as the instance drains
+	DrainingStopImmediately DrainingPolicy = "stop-immediately"
+	// DrainingDoNotStartNew leaves the tasks of the environment running on a draining instance
+	DrainingDoNotStartNew DrainingPolicy = "do-not-start-new"
+
+	// DefaultDrainingPolicy applies to environments without a draining policy
+	DefaultDrainingPolicy = DrainingKeepUntilLast
+)
+
+// Validate returns an error if the policy is not one of the known policies. An empty policy is
+// the default policy.
+func (p DrainingPolicy) Validate() error {
+	switch p {
+	case "", DrainingKeepUntilLast, DrainingStopImmediately, DrainingDoNotStartNew:
+		return nil
+	default:
+		return errors.Errorf("Unknown draining policy %s", p)
+	}
+}
+
+// OrDefault returns the policy, or the default policy if it is empty
+func (p DrainingPolicy) OrDefault() DrainingPolicy {
+	if p == "" {
+		return DefaultDrainingPolicy
+	}
+	return p
+}
+
+// UpdateDisconnectedInstances replaces the instances of cluster whose agent is disconnected with
+// instanceARNs, and returns whether they changed. The instances are replaced with a new map so that
+// copies of the environment keep the instances they had.
+func (e *Environment) UpdateDisconnectedInstances(cluster string, instanceARNs []string) bool {
+	updated := make(map[string]string, len(e.DisconnectedInstances)+len(instanceARNs))
+	for instanceARN, c := range e.DisconnectedInstances {
+		if c != cluster {
+			updated[instanceARN] = c
+		}
+	}
+	for _, instanceARN := range instanceARNs {
+		updated[instanceARN] = cluster
+	}
+
+	changed := len(updated) != len(e.DisconnectedInstances)
+	for instanceARN, c := range updated {
+		if e.DisconnectedInstances[instanceARN] != c {
+			changed = true
+		}
+	}
+	if !changed {
+		return false
+	}
+	if len(updated) == 0 {
+		updated = nil
+	}
+	e.DisconnectedInstances = updated
+	return true
+}
+
+// DisconnectedInstanceARNs returns, in order, the instances whose agent was disconnected when the
+// environment was last scheduled in their cluster
+func (e *Environment) DisconnectedInstanceARNs() []string {
+	instances := make([]string, 0, len(e.DisconnectedInstances))
+	for instanceARN := range e.DisconnectedInstances {
+		instances = append(instances, instanceARN)
+	}
+	sort.Strings(instances)
+	return instances
+}
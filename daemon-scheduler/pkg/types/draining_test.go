@@ -0,0 +1,62 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainingPolicyValidate(t *testing.T) {
+	for _, policy := range []DrainingPolicy{"", DrainingKeepUntilLast, DrainingStopImmediately, DrainingDoNotStartNew} {
+		assert.Nil(t, policy.Validate(), "Unexpected error validating draining policy %s", policy)
+	}
+	assert.Error(t, DrainingPolicy("drain-whenever").Validate(), "Expected an error validating an unknown policy")
+}
+
+func TestDrainingPolicyOrDefault(t *testing.T) {
+	assert.Exactly(t, DefaultDrainingPolicy, DrainingPolicy("").OrDefault(), "Expected the default policy")
+	assert.Exactly(t, DrainingStopImmediately, DrainingStopImmediately.OrDefault(), "Expected the policy to be kept")
+}
+
+func TestUpdateDisconnectedInstances(t *testing.T) {
+	environment, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error when creating an environment")
+
+	assert.False(t, environment.UpdateDisconnectedInstances(cluster, nil), "Expected no change without disconnected instances")
+
+	assert.True(t, environment.UpdateDisconnectedInstances(cluster, []string{"instance-2", "instance-1"}),
+		"Expected the disconnected instances to be recorded")
+	assert.True(t, environment.UpdateDisconnectedInstances(otherCluster, []string{"instance-3"}),
+		"Expected the disconnected instances of another cluster to be recorded")
+	assert.Exactly(t, []string{"instance-1", "instance-2", "instance-3"}, environment.DisconnectedInstanceARNs(),
+		"Expected the disconnected instances of every cluster in order")
+
+	assert.False(t, environment.UpdateDisconnectedInstances(cluster, []string{"instance-1", "instance-2"}),
+		"Expected no change when the same instances are disconnected")
+
+	assert.True(t, environment.UpdateDisconnectedInstances(cluster, []string{"instance-2"}),
+		"Expected the reconnected instance to be removed")
+	assert.Exactly(t, []string{"instance-2", "instance-3"}, environment.DisconnectedInstanceARNs(),
+		"Expected the instances of the other cluster to be left as they are")
+
+	updated := *environment
+	assert.True(t, updated.UpdateDisconnectedInstances(otherCluster, nil),
+		"Expected the reconnected instance to be removed")
+	assert.Exactly(t, []string{"instance-2"}, updated.DisconnectedInstanceARNs(),
+		"Expected only the instance of the cluster to be left")
+	assert.Exactly(t, []string{"instance-2", "instance-3"}, environment.DisconnectedInstanceARNs(),
+		"Expected the copy of the environment to keep its instances")
+}
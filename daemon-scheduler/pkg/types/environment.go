@@ -14,7 +14,9 @@
 package types
 
 import (
+	"fmt"
 	"sort"
+	"time"
 
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
@@ -27,6 +29,35 @@ const (
 	EnvironmentUnhealthy
 )
 
+// String returns the name of the health in the API and webhook payloads
+func (h EnvironmentHealth) String() string {
+	if h == EnvironmentUnhealthy {
+		return "unhealthy"
+	}
+	return "healthy"
+}
+
+type EnvironmentStatus uint8
+
+const (
+	EnvironmentActive EnvironmentStatus = iota
+	// EnvironmentDeleting environments are deleted once the tasks of their deployments have stopped
+	EnvironmentDeleting
+)
+
+// DefaultDrainTimeout is how long the tasks of an environment being deleted are given to stop
+// when no timeout is requested
+const DefaultDrainTimeout = 5 * time.Minute
+
+// frozenDeploymentStatuses maps the statuses UpdateDeployment cannot move a deployment out of to
+// how they are described in errors
+var frozenDeploymentStatuses = map[DeploymentStatus]string{
+	DeploymentCompleted: "has already completed",
+	DeploymentFailed:    "has already failed",
+	DeploymentPaused:    "is paused",
+	DeploymentCancelled: "has been cancelled",
+}
+
 type Environment struct {
 	Token                 string
 	Name                  string
@@ -35,6 +66,40 @@ type Environment struct {
 	Cluster               string
 	Health                EnvironmentHealth
 
+	// ClusterSelector picks the clusters the environment is deployed to instead of Cluster,
+	// which is empty when the selector is set
+	ClusterSelector ClusterSelector
+
+	// Status is EnvironmentDeleting while the tasks of the environment are being stopped
+	Status EnvironmentStatus
+	// DrainDeadline is when an environment being deleted is deleted even if some of its tasks
+	// have not stopped
+	DrainDeadline time.Time
+
+	// PlacementConstraints limit the instances of the cluster the environment is deployed to
+	PlacementConstraints PlacementConstraints
+	// RolloutStrategy controls how deployments replace the tasks of earlier deployments
+	RolloutStrategy RolloutStrategy
+	// SchedulingStrategy decides how many tasks of the environment run and on which instances
+	SchedulingStrategy SchedulingStrategy
+	// RollbackPolicy decides when an in-progress deployment has failed and is rolled back
+	RollbackPolicy RollbackPolicy
+	// CapacityPolicy decides whether the environment stops tasks of lower priority environments
+	// to make room for its own
+	CapacityPolicy CapacityPolicy
+	// TaskOverrides are the settings the tasks of the environment are started with on top of
+	// the task definition
+	TaskOverrides TaskOverrides
+	// MaintenanceWindows are when deployments may change the tasks of the environment. Pending
+	// deployments wait for a window to open and rollouts pause while all of them are closed. An
+	// environment without windows can be changed at any time.
+	MaintenanceWindows []MaintenanceWindow
+	// DrainingPolicy decides what happens to the tasks of the environment on draining instances
+	DrainingPolicy DrainingPolicy
+	// RestartPolicy limits how often the task of the environment is restarted on an instance
+	// where it keeps crashing
+	RestartPolicy RestartPolicy
+
 	// ID of the deployment created by the latest create-deployment call.
 	PendingDeploymentID string
 	// ID of the deployment that is currently in-progress. Background workers will pick
@@ -42,8 +107,23 @@ type Environment struct {
 	// Otherwise, if no in-progress deployment exists, background workers pick up the latest completed deployment.
 	InProgressDeploymentID string
 
-	// deploymentID -> deployment
-	Deployments map[string]Deployment
+	// deploymentID -> deployment. Deployments are stored under their own keys rather than with
+	// the environment.
+	Deployments map[string]Deployment `json:"-"`
+
+	// Restarts tracks, by instance ARN, the restarts of tasks that keep stopping shortly after
+	// starting on the instance
+	Restarts map[string]InstanceRestart
+	// DisconnectedInstances are, by instance ARN, the clusters of the instances whose agent was
+	// disconnected when the environment was last scheduled there. No task is started on them.
+	DisconnectedInstances map[string]string
+	// MissingReplicas are, by cluster, the replicas of a replica environment its strategy could
+	// not place when the environment was last scheduled there, as too few instances were eligible
+	MissingReplicas map[string]int
+
+	// ModRevision is the revision of the stored environment when it was read. It is used to
+	// detect concurrent modifications and is zero for an environment that was never stored.
+	ModRevision int64 `json:"-"`
 }
 
 func NewEnvironment(name string, taskDefinition string, cluster string) (*Environment, error) {
@@ -69,6 +149,35 @@ func NewEnvironment(name string, taskDefinition string, cluster string) (*Enviro
 	}, nil
 }
 
+// NewEnvironmentForClusters creates an environment deployed to the clusters picked by selector
+func NewEnvironmentForClusters(name string, taskDefinition string, selector ClusterSelector) (*Environment, error) {
+	if len(name) == 0 {
+		return nil, errors.New("Name should not be empty")
+	}
+
+	if len(taskDefinition) == 0 {
+		return nil, errors.New("TaskDefinition should not be empty")
+	}
+
+	if selector.IsEmpty() {
+		return nil, errors.New("ClusterSelector should not be empty")
+	}
+
+	err := selector.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Environment{
+		Token: uuid.NewRandom().String(),
+		Name:  name,
+		DesiredTaskDefinition: taskDefinition,
+		ClusterSelector:       selector,
+		Health:                EnvironmentHealthy,
+		Deployments:           make(map[string]Deployment),
+	}, nil
+}
+
 func (e *Environment) AddPendingDeployment(d Deployment) error {
 	if d.Status != DeploymentPending {
 		return errors.Errorf("Cannot add deployment %v to environment %v as a pending deployment because its status is %v and not pending",
@@ -81,6 +190,290 @@ func (e *Environment) AddPendingDeployment(d Deployment) error {
 	return nil
 }
 
+// UpdateDeployment replaces the deployment with the same ID and updates the environment health
+// and desired task count to match it. A completed, failed, paused or cancelled deployment cannot
+// change its status.
+func (e *Environment) UpdateDeployment(d Deployment) error {
+	current, ok := e.Deployments[d.ID]
+	if !ok {
+		return errors.Errorf("Deployment %s does not exist", d.ID)
+	}
+
+	if frozen, ok := frozenDeploymentStatuses[current.Status]; ok && d.Status != current.Status {
+		return NewConflictError(errors.Errorf("Deployment %s in environment %s %s", d.ID, e.Name, frozen))
+	}
+
+	// the rollout progress is only recorded through UpdateRollout
+	d.Batches = current.Batches
+	d.RolloutCompleted = current.RolloutCompleted
+	d.Clusters = mergeClusterDeployments(current.Clusters, d.Clusters)
+
+	e.Deployments[d.ID] = d
+	e.DesiredTaskCount = d.DesiredTaskCount
+
+	if d.Health == DeploymentHealthy && len(e.CrashLoopingInstances()) == 0 {
+		e.Health = EnvironmentHealthy
+	} else {
+		e.Health = EnvironmentUnhealthy
+	}
+
+	return nil
+}
+
+// UpdateRollout records the rollout progress of the deployment with the provided ID, which
+// must still be rolled out
+func (e *Environment) UpdateRollout(id string, batches []DeploymentBatch, completed bool) error {
+	d, ok := e.Deployments[id]
+	if !ok {
+		return errors.Errorf("Deployment %s does not exist", id)
+	}
+
+	if frozen, ok := frozenDeploymentStatuses[d.Status]; ok {
+		return NewConflictError(errors.Errorf("Deployment %s in environment %s %s", id, e.Name, frozen))
+	}
+
+	d.Batches = batches
+	d.RolloutCompleted = completed
+	e.Deployments[id] = d
+
+	return nil
+}
+
+// UpdateClusterRollout records the rollout progress in the cluster of the deployment with the
+// provided ID, which must still be rolled out
+func (e *Environment) UpdateClusterRollout(id string, cluster string, batches []DeploymentBatch, completed bool) error {
+	d, ok := e.Deployments[id]
+	if !ok {
+		return errors.Errorf("Deployment %s does not exist", id)
+	}
+
+	if frozen, ok := frozenDeploymentStatuses[d.Status]; ok {
+		return NewConflictError(errors.Errorf("Deployment %s in environment %s %s", id, e.Name, frozen))
+	}
+
+	clusters := make(map[string]ClusterDeployment, len(d.Clusters)+1)
+	for name, c := range d.Clusters {
+		clusters[name] = c
+	}
+	c := clusters[cluster]
+	c.Batches = batches
+	c.RolloutCompleted = completed
+	clusters[cluster] = c
+
+	d.Clusters = clusters
+	e.Deployments[id] = d
+
+	return nil
+}
+
+// mergeClusterDeployments returns the progress of updated in each cluster, keeping the clusters
+// updated does not know about yet and the rollout progress recorded in current
+func mergeClusterDeployments(current map[string]ClusterDeployment,
+	updated map[string]ClusterDeployment) map[string]ClusterDeployment {
+
+	if len(current) == 0 && len(updated) == 0 {
+		return updated
+	}
+
+	merged := make(map[string]ClusterDeployment, len(current)+len(updated))
+	for name, c := range current {
+		merged[name] = c
+	}
+	for name, c := range updated {
+		c.Batches = current[name].Batches
+		c.RolloutCompleted = current[name].RolloutCompleted
+		merged[name] = c
+	}
+	return merged
+}
+
+// FailDeployment replaces the deployment with the same ID by the provided failed deployment and
+// rolls back to the task definition of the latest healthy completed deployment by adding a pending
+// deployment for it. It returns the rollback deployment, or nil if there is no deployment to roll
+// back to or the failed deployment was itself a rollback, so that rollbacks do not repeat.
+func (e *Environment) FailDeployment(d Deployment) (*Deployment, error) {
+	if d.Status != DeploymentFailed {
+		return nil, errors.Errorf("Deployment %s in environment %s has not failed", d.ID, e.Name)
+	}
+
+	err := e.UpdateDeployment(d)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.RollbackOf != "" {
+		return nil, nil
+	}
+
+	return e.rollBack(d)
+}
+
+// LatestDeployment returns the deployment created by the latest create-deployment call or
+// rollback, if any
+func (e *Environment) LatestDeployment() *Deployment {
+	id := e.InProgressDeploymentID
+	if id == "" {
+		id = e.PendingDeploymentID
+	}
+
+	d, ok := e.Deployments[id]
+	if !ok {
+		return nil
+	}
+	return &d
+}
+
+// PauseDeployment stops the latest deployment, which has to have the provided ID, from being
+// rolled out to further instances. Pausing a paused deployment has no effect.
+func (e *Environment) PauseDeployment(id string) error {
+	d, err := e.getLatestDeployment(id)
+	if err != nil {
+		return err
+	}
+
+	switch d.Status {
+	case DeploymentPaused:
+		return nil
+	case DeploymentPending, DeploymentInProgress:
+		d.PausedStatus = d.Status
+		d.Status = DeploymentPaused
+		e.Deployments[id] = *d
+		return nil
+	default:
+		return NewConflictError(errors.Errorf("Deployment %s in environment %s %s and cannot be paused",
+			id, e.Name, frozenDeploymentStatuses[d.Status]))
+	}
+}
+
+// ResumeDeployment carries on with the rollout of the latest deployment, which has to have the
+// provided ID. A deployment paused before it started is pending again, so that it still waits
+// for its start time and maintenance windows. Resuming a deployment that is not paused has no
+// effect.
+func (e *Environment) ResumeDeployment(id string) error {
+	d, err := e.getLatestDeployment(id)
+	if err != nil {
+		return err
+	}
+
+	switch d.Status {
+	case DeploymentPending, DeploymentInProgress:
+		return nil
+	case DeploymentPaused:
+		d.Status = d.PausedStatus
+		e.Deployments[id] = *d
+		return nil
+	default:
+		return NewConflictError(errors.Errorf("Deployment %s in environment %s %s and cannot be resumed",
+			id, e.Name, frozenDeploymentStatuses[d.Status]))
+	}
+}
+
+// CancelDeployment stops the latest deployment, which has to have the provided ID, from being
+// rolled out to further instances for good. If revert is set, the instances it updated are
+// reverted by a pending deployment of the latest healthy completed deployment, which is returned.
+func (e *Environment) CancelDeployment(id string, revert bool) (*Deployment, error) {
+	d, err := e.getLatestDeployment(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if frozen, ok := frozenDeploymentStatuses[d.Status]; ok && d.Status != DeploymentPaused {
+		return nil, NewConflictError(errors.Errorf("Deployment %s in environment %s %s and cannot be cancelled",
+			id, e.Name, frozen))
+	}
+
+	if revert && e.latestHealthyDeployment() == nil {
+		return nil, NewBadRequestError(errors.Errorf(
+			"There is no healthy completed deployment in environment %s to revert deployment %s to", e.Name, id))
+	}
+
+	d.Status = DeploymentCancelled
+	d.EndTime = time.Now()
+	e.Deployments[id] = *d
+
+	if !revert {
+		return nil, nil
+	}
+
+	d.RollbackReason = fmt.Sprintf("Deployment %s was cancelled", id)
+	return e.rollBack(*d)
+}
+
+// getLatestDeployment returns the latest deployment if it has the provided ID
+func (e *Environment) getLatestDeployment(id string) (*Deployment, error) {
+	if _, ok := e.Deployments[id]; !ok {
+		return nil, NewNotFoundError(errors.Errorf("Deployment %s does not exist in environment %s", id, e.Name))
+	}
+
+	d := e.LatestDeployment()
+	if d == nil || d.ID != id {
+		return nil, NewConflictError(errors.Errorf("Deployment %s is not the latest deployment of environment %s", id, e.Name))
+	}
+
+	return d, nil
+}
+
+// rollBack adds a pending deployment of the task definition and overrides of the latest healthy
+// completed deployment that rolls back d, records it in d, and makes them the desired ones of the
+// environment. It returns nil if there is no deployment to roll back to.
+func (e *Environment) rollBack(d Deployment) (*Deployment, error) {
+	healthy := e.latestHealthyDeployment()
+	if healthy == nil {
+		return nil, nil
+	}
+
+	// the rollback deployment gets its own token since the environment token may already be used
+	rollback, err := NewDeployment(healthy.TaskDefinition, uuid.NewRandom().String())
+	if err != nil {
+		return nil, err
+	}
+	rollback.TaskOverrides = healthy.TaskOverrides
+	rollback.RollbackOf = d.ID
+	rollback.RollbackReason = d.RollbackReason
+
+	err = e.AddPendingDeployment(*rollback)
+	if err != nil {
+		return nil, err
+	}
+
+	d.RolledBackBy = rollback.ID
+	e.Deployments[d.ID] = d
+	e.DesiredTaskDefinition = rollback.TaskDefinition
+	e.TaskOverrides = rollback.TaskOverrides
+
+	return rollback, nil
+}
+
+// latestHealthyDeployment returns the latest completed deployment that was healthy, if any
+func (e *Environment) latestHealthyDeployment() *Deployment {
+	var latest *Deployment
+	for _, d := range e.Deployments {
+		if d.Status != DeploymentCompleted || d.Health != DeploymentHealthy {
+			continue
+		}
+		if latest == nil || d.StartTime.After(latest.StartTime) {
+			d := d
+			latest = &d
+		}
+	}
+	return latest
+}
+
+// latestCompletedDeployment returns the completed deployment started last, regardless of its health
+func (e *Environment) latestCompletedDeployment() *Deployment {
+	var latest *Deployment
+	for _, d := range e.Deployments {
+		if d.Status != DeploymentCompleted {
+			continue
+		}
+		if latest == nil || d.StartTime.After(latest.StartTime) {
+			d := d
+			latest = &d
+		}
+	}
+	return latest
+}
+
 func (e *Environment) UpdatePendingDeploymentToInProgress() error {
 	d, err := e.getPendingDeployment()
 	if err != nil {
@@ -122,8 +515,12 @@ func (p timeOrderedDeployments) Len() int {
 	return len(p)
 }
 
-// Less orders deployments reverse-chronologically: latest startTime first
+// Less orders deployments reverse-chronologically: latest startTime first, and deployments that
+// started at the same time by reverse ID so that the order is stable across calls
 func (p timeOrderedDeployments) Less(i, j int) bool {
+	if p[i].StartTime.Equal(p[j].StartTime) {
+		return p[i].ID > p[j].ID
+	}
 	return p[i].StartTime.After(p[j].StartTime)
 }
 
@@ -141,3 +538,37 @@ func (e *Environment) SortDeploymentsReverseChronologically() ([]Deployment, err
 	sort.Sort(timeOrderedDeployments(deployments))
 	return deployments, nil
 }
+
+// RetainedDeployments returns the deployments to keep when only the latest retention deployments
+// of the environment are kept. Deployments that have not finished or still have tasks running,
+// the latest completed deployment, which tasks are started with, and the latest healthy completed
+// deployment, which failed deployments are rolled back to, are kept regardless.
+func (e *Environment) RetainedDeployments(retention int) map[string]Deployment {
+	retained := make(map[string]Deployment, len(e.Deployments))
+	if healthy := e.latestHealthyDeployment(); healthy != nil {
+		retained[healthy.ID] = *healthy
+	}
+	if completed := e.latestCompletedDeployment(); completed != nil {
+		retained[completed.ID] = *completed
+	}
+
+	deployments, _ := e.SortDeploymentsReverseChronologically()
+	for i, d := range deployments {
+		if i < retention || !d.IsFinished() || !d.TasksStopped ||
+			d.ID == e.PendingDeploymentID || d.ID == e.InProgressDeploymentID {
+			retained[d.ID] = d
+		}
+	}
+	return retained
+}
+
+// MarkTasksStopped records that no task of the finished deployments with the IDs is left running
+func (e *Environment) MarkTasksStopped(ids []string) {
+	for _, id := range ids {
+		d, ok := e.Deployments[id]
+		if ok && d.IsFinished() {
+			d.TasksStopped = true
+			e.Deployments[id] = d
+		}
+	}
+}
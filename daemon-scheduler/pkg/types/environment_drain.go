@@ -0,0 +1,44 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StartDeleting marks the environment as being deleted, giving the tasks of its deployments
+// until deadline to stop. An environment that is already being deleted keeps its deadline.
+func (e *Environment) StartDeleting(deadline time.Time) {
+	if e.IsDeleting() {
+		return
+	}
+	e.Status = EnvironmentDeleting
+	e.DrainDeadline = deadline
+}
+
+// IsDeleting returns whether the tasks of the environment are being stopped before it is deleted
+func (e *Environment) IsDeleting() bool {
+	return e.Status == EnvironmentDeleting
+}
+
+// VerifyNotDeleting returns a BadRequestError if the environment is being deleted, so that it
+// does not get new deployments or settings
+func (e *Environment) VerifyNotDeleting() error {
+	if e.IsDeleting() {
+		return NewBadRequestError(errors.Errorf("Environment %s is being deleted", e.Name))
+	}
+	return nil
+}
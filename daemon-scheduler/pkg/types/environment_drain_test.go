@@ -0,0 +1,41 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvironmentStartDeleting(t *testing.T) {
+	environment, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error when creating an environment")
+	assert.False(t, environment.IsDeleting(), "Expected a new environment to be active")
+	assert.Nil(t, environment.VerifyNotDeleting(), "Unexpected error verifying an active environment")
+
+	deadline := time.Now().Add(time.Minute)
+	environment.StartDeleting(deadline)
+	assert.True(t, environment.IsDeleting(), "Expected the environment to be deleting")
+	assert.Equal(t, deadline, environment.DrainDeadline, "Expected the drain deadline to be set")
+
+	environment.StartDeleting(deadline.Add(time.Hour))
+	assert.Equal(t, deadline, environment.DrainDeadline, "Expected the drain deadline to be kept")
+
+	assert.IsType(t, BadRequestError{}, environment.VerifyNotDeleting(), "Expected a bad request while deleting")
+	err = environment.Update(environment.Token, EnvironmentUpdate{TaskDefinition: aws.String(updatedTaskDefinition)})
+	assert.IsType(t, BadRequestError{}, err, "Expected an environment being deleted not to be updated")
+}
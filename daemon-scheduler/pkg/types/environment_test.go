@@ -89,6 +89,61 @@ func (suite *EnvironmentTestSuite) TestSortDeploymentsReverseChronologically() {
 	assert.Exactly(suite.T(), *deployment1, deployments[1], "Expected the deployments to match")
 }
 
+func (suite *EnvironmentTestSuite) TestSortDeploymentsReverseChronologicallySameStartTime() {
+	startTime := time.Now()
+	for _, id := range []string{"dep-1", "dep-3", "dep-2"} {
+		suite.environment.Deployments[id] = Deployment{ID: id, StartTime: startTime}
+	}
+
+	deployments, err := suite.environment.SortDeploymentsReverseChronologically()
+	assert.Nil(suite.T(), err, "Unexpected error when sorting deployments")
+	assert.Exactly(suite.T(), "dep-3", deployments[0].ID, "Expected deployments started together ordered by ID")
+	assert.Exactly(suite.T(), "dep-2", deployments[1].ID, "Expected deployments started together ordered by ID")
+	assert.Exactly(suite.T(), "dep-1", deployments[2].ID, "Expected deployments started together ordered by ID")
+}
+
+func (suite *EnvironmentTestSuite) TestRetainedDeployments() {
+	startTime := time.Now()
+	deployment := func(id string, status DeploymentStatus, health DeploymentHealth, age int) {
+		suite.environment.Deployments[id] = Deployment{
+			ID:           id,
+			Status:       status,
+			Health:       health,
+			StartTime:    startTime.Add(-time.Duration(age) * time.Hour),
+			TasksStopped: true,
+		}
+	}
+	deployment("healthy", DeploymentCompleted, DeploymentHealthy, 7)
+	deployment("completed", DeploymentCompleted, DeploymentUnhealthy, 6)
+	deployment("running", DeploymentFailed, DeploymentUnhealthy, 5)
+	deployment("paused", DeploymentPaused, DeploymentUnhealthy, 4)
+	deployment("expired", DeploymentFailed, DeploymentUnhealthy, 3)
+	deployment("failed", DeploymentFailed, DeploymentUnhealthy, 2)
+	deployment("latest", DeploymentInProgress, DeploymentUnhealthy, 1)
+	suite.environment.InProgressDeploymentID = "latest"
+	running := suite.environment.Deployments["running"]
+	running.TasksStopped = false
+	suite.environment.Deployments["running"] = running
+
+	retained := suite.environment.RetainedDeployments(2)
+	assert.Len(suite.T(), retained, 6, "Expected only the expired deployment to be dropped")
+	for _, id := range []string{"healthy", "completed", "running", "paused", "failed", "latest"} {
+		_, ok := retained[id]
+		assert.True(suite.T(), ok, "Expected deployment %s to be retained", id)
+	}
+}
+
+func (suite *EnvironmentTestSuite) TestMarkTasksStopped() {
+	suite.environment.Deployments["finished"] = Deployment{ID: "finished", Status: DeploymentCompleted}
+	suite.environment.Deployments["unfinished"] = Deployment{ID: "unfinished", Status: DeploymentInProgress}
+
+	suite.environment.MarkTasksStopped([]string{"finished", "unfinished", "missing"})
+	assert.True(suite.T(), suite.environment.Deployments["finished"].TasksStopped, "Expected the finished deployment to be marked")
+	assert.False(suite.T(), suite.environment.Deployments["unfinished"].TasksStopped, "Expected an unfinished deployment not to be marked")
+	_, ok := suite.environment.Deployments["missing"]
+	assert.False(suite.T(), ok, "Expected no deployment to be added")
+}
+
 func (suite *EnvironmentTestSuite) TestAddPendingDeploymentStatusNotPending() {
 	suite.deployment.Status = DeploymentInProgress
 
@@ -138,6 +193,230 @@ func (suite *EnvironmentTestSuite) TestUpdatePendingDeploymentToInProgress() {
 	assert.Exactly(suite.T(), *suite.deployment, suite.environment.Deployments[suite.environment.InProgressDeploymentID], "")
 }
 
+func (suite *EnvironmentTestSuite) TestUpdateRollout() {
+	err := suite.environment.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+
+	batches := []DeploymentBatch{{Instances: []string{instanceArn}, StartTime: time.Now()}}
+	err = suite.environment.UpdateRollout(suite.deployment.ID, batches, false)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the rollout")
+	assert.Exactly(suite.T(), batches, suite.environment.Deployments[suite.deployment.ID].Batches, "")
+
+	// updating the deployment does not overwrite the rollout progress
+	updated, err := suite.deployment.UpdateDeploymentInProgress(desiredTaskCount, nil)
+	assert.Nil(suite.T(), err, "Unexpected error when setting deployment in progress")
+	err = suite.environment.UpdateDeployment(*updated)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the deployment")
+	assert.Exactly(suite.T(), batches, suite.environment.Deployments[suite.deployment.ID].Batches, "")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateRolloutDeploymentCompleted() {
+	suite.deployment.Status = DeploymentCompleted
+	suite.environment.Deployments[suite.deployment.ID] = *suite.deployment
+
+	err := suite.environment.UpdateRollout(suite.deployment.ID, nil, true)
+	assert.IsType(suite.T(), ConflictError{}, err, "Expected a conflict when the deployment has completed")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateClusterRollout() {
+	err := suite.environment.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+
+	batches := []DeploymentBatch{{Instances: []string{instanceArn}, StartTime: time.Now()}}
+	err = suite.environment.UpdateClusterRollout(suite.deployment.ID, cluster, batches, true)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the rollout in a cluster")
+
+	// updating the deployment in a cluster keeps the rollout progress of every cluster
+	updated, err := suite.deployment.UpdateClusterInProgress(otherCluster, desiredTaskCount, nil)
+	assert.Nil(suite.T(), err, "Unexpected error when setting deployment in progress in a cluster")
+	err = suite.environment.UpdateDeployment(*updated)
+	assert.Nil(suite.T(), err, "Unexpected error when updating the deployment")
+
+	clusters := suite.environment.Deployments[suite.deployment.ID].Clusters
+	assert.Exactly(suite.T(), batches, clusters[cluster].Batches, "Expected the rollout progress to be kept")
+	assert.True(suite.T(), clusters[cluster].RolloutCompleted, "Expected the rollout to be completed in the cluster")
+	assert.Exactly(suite.T(), desiredTaskCount, clusters[otherCluster].DesiredTaskCount, "Expected the updated cluster")
+}
+
+func (suite *EnvironmentTestSuite) TestFailDeploymentRollsBackToLatestHealthyDeployment() {
+	older := Deployment{ID: "older", Status: DeploymentCompleted, Health: DeploymentHealthy,
+		TaskDefinition: "older-td", StartTime: time.Now().Add(-2 * time.Hour)}
+	healthy := Deployment{ID: "healthy", Status: DeploymentCompleted, Health: DeploymentHealthy,
+		TaskDefinition: "healthy-td", StartTime: time.Now().Add(-time.Hour),
+		TaskOverrides: TaskOverrides{Memory: "512"}}
+	unhealthy := Deployment{ID: "unhealthy", Status: DeploymentCompleted, Health: DeploymentUnhealthy,
+		TaskDefinition: "unhealthy-td", StartTime: time.Now().Add(-time.Minute)}
+	for _, d := range []Deployment{older, healthy, unhealthy} {
+		suite.environment.Deployments[d.ID] = d
+	}
+	suite.environment.TaskOverrides = TaskOverrides{Memory: "4096"}
+	suite.deployment.TaskOverrides = suite.environment.TaskOverrides
+	err := suite.environment.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+
+	failed, err := suite.deployment.UpdateDeploymentFailed(nil, "Tasks keep crashing")
+	assert.Nil(suite.T(), err, "Unexpected error when failing the deployment")
+	rollback, err := suite.environment.FailDeployment(*failed)
+	assert.Nil(suite.T(), err, "Unexpected error when rolling back the deployment")
+
+	assert.NotNil(suite.T(), rollback, "Expected a rollback deployment")
+	assert.Exactly(suite.T(), healthy.TaskDefinition, rollback.TaskDefinition,
+		"Expected the task definition of the latest healthy deployment")
+	assert.Exactly(suite.T(), DeploymentPending, rollback.Status, "Expected the rollback deployment to be pending")
+	assert.Exactly(suite.T(), suite.deployment.ID, rollback.RollbackOf, "")
+	assert.Exactly(suite.T(), "Tasks keep crashing", rollback.RollbackReason, "")
+	assert.Exactly(suite.T(), rollback.ID, suite.environment.PendingDeploymentID, "")
+	assert.Exactly(suite.T(), healthy.TaskDefinition, suite.environment.DesiredTaskDefinition, "")
+	assert.Exactly(suite.T(), healthy.TaskOverrides, rollback.TaskOverrides,
+		"Expected the overrides of the latest healthy deployment")
+	assert.Exactly(suite.T(), healthy.TaskOverrides, suite.environment.TaskOverrides,
+		"Expected the environment to start tasks with the overrides of the latest healthy deployment")
+
+	stored := suite.environment.Deployments[suite.deployment.ID]
+	assert.Exactly(suite.T(), DeploymentFailed, stored.Status, "Expected the deployment to be failed")
+	assert.Exactly(suite.T(), rollback.ID, stored.RolledBackBy, "")
+	assert.Exactly(suite.T(), "Tasks keep crashing", stored.RollbackReason, "")
+	assert.Exactly(suite.T(), EnvironmentUnhealthy, suite.environment.Health, "")
+}
+
+func (suite *EnvironmentTestSuite) TestFailDeploymentWithoutHealthyDeployment() {
+	err := suite.environment.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+
+	failed, err := suite.deployment.UpdateDeploymentFailed(nil, "Tasks keep crashing")
+	assert.Nil(suite.T(), err, "Unexpected error when failing the deployment")
+	rollback, err := suite.environment.FailDeployment(*failed)
+	assert.Nil(suite.T(), err, "Unexpected error when failing the deployment")
+	assert.Nil(suite.T(), rollback, "Expected no rollback without a healthy deployment")
+	assert.Exactly(suite.T(), suite.deployment.ID, suite.environment.PendingDeploymentID, "")
+	assert.Exactly(suite.T(), DeploymentFailed, suite.environment.Deployments[suite.deployment.ID].Status, "")
+}
+
+func (suite *EnvironmentTestSuite) TestFailDeploymentDoesNotRollBackRollback() {
+	suite.environment.Deployments["healthy"] = Deployment{ID: "healthy", Status: DeploymentCompleted,
+		Health: DeploymentHealthy, TaskDefinition: "healthy-td"}
+	suite.deployment.RollbackOf = "failed"
+	err := suite.environment.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+
+	failed, err := suite.deployment.UpdateDeploymentFailed(nil, "Tasks keep crashing")
+	assert.Nil(suite.T(), err, "Unexpected error when failing the deployment")
+	rollback, err := suite.environment.FailDeployment(*failed)
+	assert.Nil(suite.T(), err, "Unexpected error when failing the deployment")
+	assert.Nil(suite.T(), rollback, "Expected a failed rollback not to be rolled back")
+}
+
+func (suite *EnvironmentTestSuite) TestUpdateDeploymentFailed() {
+	suite.deployment.Status = DeploymentFailed
+	suite.environment.Deployments[suite.deployment.ID] = *suite.deployment
+
+	updated, err := suite.deployment.UpdateDeploymentCompleted(nil)
+	assert.Nil(suite.T(), err, "Unexpected error when setting deployment completed")
+	err = suite.environment.UpdateDeployment(*updated)
+	assert.IsType(suite.T(), ConflictError{}, err, "Expected a conflict when the deployment has failed")
+}
+
+func (suite *EnvironmentTestSuite) TestPauseAndResumeDeployment() {
+	err := suite.environment.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+
+	err = suite.environment.PauseDeployment(suite.deployment.ID)
+	assert.Nil(suite.T(), err, "Unexpected error when pausing the deployment")
+	assert.Exactly(suite.T(), DeploymentPaused, suite.environment.Deployments[suite.deployment.ID].Status, "")
+
+	// the deployment worker cannot move a paused deployment forward
+	updated, err := suite.deployment.UpdateDeploymentInProgress(desiredTaskCount, nil)
+	assert.Nil(suite.T(), err, "Unexpected error when setting deployment in progress")
+	err = suite.environment.UpdateDeployment(*updated)
+	assert.IsType(suite.T(), ConflictError{}, err, "Expected a conflict when the deployment is paused")
+
+	err = suite.environment.ResumeDeployment(suite.deployment.ID)
+	assert.Nil(suite.T(), err, "Unexpected error when resuming the deployment")
+	assert.Exactly(suite.T(), DeploymentPending, suite.environment.Deployments[suite.deployment.ID].Status,
+		"Expected the deployment to get back the status it was paused in")
+}
+
+func (suite *EnvironmentTestSuite) TestResumePendingDeployment() {
+	suite.deployment.NotBefore = time.Now().Add(time.Hour)
+	err := suite.environment.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+
+	err = suite.environment.PauseDeployment(suite.deployment.ID)
+	assert.Nil(suite.T(), err, "Unexpected error when pausing the deployment")
+	err = suite.environment.ResumeDeployment(suite.deployment.ID)
+	assert.Nil(suite.T(), err, "Unexpected error when resuming the deployment")
+
+	resumed := suite.environment.Deployments[suite.deployment.ID]
+	assert.Exactly(suite.T(), DeploymentPending, resumed.Status, "Expected a deployment paused before it started to be pending again")
+	assert.False(suite.T(), suite.environment.CanStartDeployment(resumed, time.Now()),
+		"Expected the resumed deployment to still wait for its start time")
+}
+
+func (suite *EnvironmentTestSuite) TestPauseDeploymentNotLatest() {
+	suite.environment.Deployments[suite.deployment.ID] = *suite.deployment
+
+	err := suite.environment.PauseDeployment(suite.deployment.ID)
+	assert.IsType(suite.T(), ConflictError{}, err, "Expected a conflict when the deployment is not the latest")
+
+	err = suite.environment.PauseDeployment("missing")
+	assert.IsType(suite.T(), NotFoundError{}, err, "Expected not found when the deployment does not exist")
+}
+
+func (suite *EnvironmentTestSuite) TestResumeDeploymentCompleted() {
+	err := suite.environment.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+	completed, err := suite.deployment.UpdateDeploymentCompleted(nil)
+	assert.Nil(suite.T(), err, "Unexpected error when setting deployment completed")
+	suite.environment.Deployments[suite.deployment.ID] = *completed
+
+	err = suite.environment.ResumeDeployment(suite.deployment.ID)
+	assert.IsType(suite.T(), ConflictError{}, err, "Expected a conflict when the deployment has completed")
+}
+
+func (suite *EnvironmentTestSuite) TestCancelDeployment() {
+	err := suite.environment.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+
+	rollback, err := suite.environment.CancelDeployment(suite.deployment.ID, false)
+	assert.Nil(suite.T(), err, "Unexpected error when cancelling the deployment")
+	assert.Nil(suite.T(), rollback, "Expected no deployment to revert the cancelled one")
+	assert.Exactly(suite.T(), DeploymentCancelled, suite.environment.Deployments[suite.deployment.ID].Status, "")
+
+	_, err = suite.environment.CancelDeployment(suite.deployment.ID, false)
+	assert.IsType(suite.T(), ConflictError{}, err, "Expected a conflict when the deployment has been cancelled")
+}
+
+func (suite *EnvironmentTestSuite) TestCancelDeploymentWithRevert() {
+	healthy := Deployment{ID: "healthy", Status: DeploymentCompleted, Health: DeploymentHealthy,
+		TaskDefinition: "healthy-td", StartTime: time.Now().Add(-time.Hour)}
+	suite.environment.Deployments[healthy.ID] = healthy
+	err := suite.environment.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+	err = suite.environment.PauseDeployment(suite.deployment.ID)
+	assert.Nil(suite.T(), err, "Unexpected error when pausing the deployment")
+
+	rollback, err := suite.environment.CancelDeployment(suite.deployment.ID, true)
+	assert.Nil(suite.T(), err, "Unexpected error when cancelling the deployment")
+	assert.Exactly(suite.T(), healthy.TaskDefinition, rollback.TaskDefinition, "Expected the healthy task definition")
+	assert.Exactly(suite.T(), suite.deployment.ID, rollback.RollbackOf, "")
+	assert.Exactly(suite.T(), rollback.ID, suite.environment.PendingDeploymentID, "")
+
+	cancelled := suite.environment.Deployments[suite.deployment.ID]
+	assert.Exactly(suite.T(), DeploymentCancelled, cancelled.Status, "")
+	assert.Exactly(suite.T(), rollback.ID, cancelled.RolledBackBy, "")
+	assert.NotEmpty(suite.T(), cancelled.RollbackReason, "")
+}
+
+func (suite *EnvironmentTestSuite) TestCancelDeploymentWithRevertWithoutHealthyDeployment() {
+	err := suite.environment.AddPendingDeployment(*suite.deployment)
+	assert.Nil(suite.T(), err, "Unexpected error when adding a pending deployment")
+
+	_, err = suite.environment.CancelDeployment(suite.deployment.ID, true)
+	assert.IsType(suite.T(), BadRequestError{}, err, "Expected a bad request when there is nothing to revert to")
+	assert.Exactly(suite.T(), DeploymentPending, suite.environment.Deployments[suite.deployment.ID].Status,
+		"Expected the deployment to be left as it is")
+}
+
 func generateToken() string {
 	return uuid.NewRandom().String()
 }
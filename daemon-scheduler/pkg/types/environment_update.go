@@ -0,0 +1,185 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+// EnvironmentUpdate holds the settings an update-environment call changes. Settings that are
+// nil are left unchanged. Setting either Cluster or ClusterSelector replaces the other.
+type EnvironmentUpdate struct {
+	TaskDefinition       *string
+	Cluster              *string
+	ClusterSelector      *ClusterSelector
+	PlacementConstraints *PlacementConstraints
+	RolloutStrategy      *RolloutStrategy
+	SchedulingStrategy   *SchedulingStrategy
+	RollbackPolicy       *RollbackPolicy
+	CapacityPolicy       *CapacityPolicy
+	TaskOverrides        *TaskOverrides
+	// MaintenanceWindows replace the windows of the environment unless nil, so an empty
+	// slice removes them
+	MaintenanceWindows []MaintenanceWindow
+	DrainingPolicy     *DrainingPolicy
+	RestartPolicy      *RestartPolicy
+}
+
+// Validate returns an error if any of the settings that are set is invalid
+func (u EnvironmentUpdate) Validate() error {
+	if u.TaskDefinition != nil && len(*u.TaskDefinition) == 0 {
+		return errors.New("TaskDefinition should not be empty")
+	}
+	if u.Cluster != nil && len(*u.Cluster) == 0 {
+		return errors.New("Cluster should not be empty")
+	}
+	if u.ClusterSelector != nil {
+		if u.Cluster != nil {
+			return errors.New("Cluster and ClusterSelector should not both be set")
+		}
+		if u.ClusterSelector.IsEmpty() {
+			return errors.New("ClusterSelector should not be empty")
+		}
+		if err := u.ClusterSelector.Validate(); err != nil {
+			return errors.Wrapf(err, "Invalid cluster selector")
+		}
+	}
+	if u.PlacementConstraints != nil {
+		if err := u.PlacementConstraints.Validate(); err != nil {
+			return errors.Wrapf(err, "Invalid placement constraints")
+		}
+	}
+	if u.RolloutStrategy != nil {
+		if err := u.RolloutStrategy.Validate(); err != nil {
+			return errors.Wrapf(err, "Invalid rollout strategy")
+		}
+	}
+	if u.SchedulingStrategy != nil {
+		if err := u.SchedulingStrategy.Validate(); err != nil {
+			return errors.Wrapf(err, "Invalid scheduling strategy")
+		}
+	}
+	if u.RollbackPolicy != nil {
+		if err := u.RollbackPolicy.Validate(); err != nil {
+			return errors.Wrapf(err, "Invalid rollback policy")
+		}
+	}
+	if u.CapacityPolicy != nil {
+		if err := u.CapacityPolicy.Validate(); err != nil {
+			return errors.Wrapf(err, "Invalid capacity policy")
+		}
+	}
+	if u.TaskOverrides != nil {
+		if err := u.TaskOverrides.Validate(); err != nil {
+			return errors.Wrapf(err, "Invalid task overrides")
+		}
+	}
+	if err := ValidateMaintenanceWindows(u.MaintenanceWindows); err != nil {
+		return err
+	}
+	if u.DrainingPolicy != nil {
+		if err := u.DrainingPolicy.Validate(); err != nil {
+			return err
+		}
+	}
+	if u.RestartPolicy != nil {
+		if err := u.RestartPolicy.Validate(); err != nil {
+			return errors.Wrapf(err, "Invalid restart policy")
+		}
+	}
+	return nil
+}
+
+// Update applies the update if token is the current token of the environment, and rotates the
+// token so that any other request based on the same version of the environment is rejected. An
+// environment being deleted cannot be updated.
+func (e *Environment) Update(token string, u EnvironmentUpdate) error {
+	if err := e.VerifyNotDeleting(); err != nil {
+		return err
+	}
+
+	if token != e.Token {
+		return NewConflictError(errors.Errorf(
+			"Token %s is not the current token of environment %s, retry with the latest environment", token, e.Name))
+	}
+
+	if u.TaskDefinition != nil {
+		e.DesiredTaskDefinition = *u.TaskDefinition
+	}
+	if u.Cluster != nil {
+		e.Cluster = *u.Cluster
+		e.ClusterSelector = ClusterSelector{}
+	}
+	if u.ClusterSelector != nil {
+		e.ClusterSelector = *u.ClusterSelector
+		e.Cluster = ""
+	}
+	if u.PlacementConstraints != nil {
+		e.PlacementConstraints = *u.PlacementConstraints
+	}
+	if u.RolloutStrategy != nil {
+		e.RolloutStrategy = *u.RolloutStrategy
+	}
+	if u.SchedulingStrategy != nil {
+		e.SchedulingStrategy = *u.SchedulingStrategy
+	}
+	if u.RollbackPolicy != nil {
+		e.RollbackPolicy = *u.RollbackPolicy
+	}
+	if u.CapacityPolicy != nil {
+		e.CapacityPolicy = *u.CapacityPolicy
+	}
+	if u.TaskOverrides != nil {
+		e.TaskOverrides = *u.TaskOverrides
+	}
+	if u.MaintenanceWindows != nil {
+		e.MaintenanceWindows = u.MaintenanceWindows
+	}
+	if u.DrainingPolicy != nil {
+		e.DrainingPolicy = *u.DrainingPolicy
+	}
+	if u.RestartPolicy != nil {
+		e.RestartPolicy = *u.RestartPolicy
+	}
+
+	e.Token = uuid.NewRandom().String()
+	return nil
+}
+
+// StartDeployment adds a pending deployment of the desired task definition, recorded with the
+// current token, unless the latest deployment is still being rolled out or is paused
+func (e *Environment) StartDeployment() (*Deployment, error) {
+	if latest := e.LatestDeployment(); latest != nil {
+		switch latest.Status {
+		case DeploymentPending, DeploymentInProgress, DeploymentPaused:
+			return nil, NewBadRequestError(errors.Errorf(
+				"Deployment %s of environment %s has not finished and has to complete or be cancelled first",
+				latest.ID, e.Name))
+		}
+	}
+
+	d, err := NewDeployment(e.DesiredTaskDefinition, e.Token)
+	if err != nil {
+		return nil, err
+	}
+	d.TaskOverrides = e.TaskOverrides
+
+	err = e.AddPendingDeployment(*d)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
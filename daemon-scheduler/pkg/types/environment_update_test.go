@@ -0,0 +1,136 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+const updatedTaskDefinition = "arn:aws:ecs:us-east-1:12345678912:task-definition/test:2"
+
+func TestEnvironmentUpdateValidate(t *testing.T) {
+	assert.Nil(t, EnvironmentUpdate{}.Validate(), "Unexpected error validating an empty update")
+
+	invalid := []EnvironmentUpdate{
+		{TaskDefinition: aws.String("")},
+		{Cluster: aws.String("")},
+		{PlacementConstraints: &PlacementConstraints{Expressions: []string{"ecs.instance-type =~ ("}}},
+		{RolloutStrategy: &RolloutStrategy{BatchSize: -1}},
+		{RollbackPolicy: &RollbackPolicy{CrashCount: 1}},
+		{CapacityPolicy: &CapacityPolicy{Priority: -1}},
+		{TaskOverrides: &TaskOverrides{ContainerOverrides: []ContainerOverride{{}}}},
+		{MaintenanceWindows: []MaintenanceWindow{{Schedule: "0 2 * *", Duration: time.Hour}}},
+		{DrainingPolicy: drainingPolicy("drain-whenever")},
+		{SchedulingStrategy: &SchedulingStrategy{Type: "singleton"}},
+		{ClusterSelector: &ClusterSelector{}},
+		{Cluster: aws.String(cluster), ClusterSelector: &ClusterSelector{NamePattern: "*"}},
+	}
+	for _, update := range invalid {
+		assert.Error(t, update.Validate(), "Expected an error validating %+v", update)
+	}
+}
+
+func TestEnvironmentUpdate(t *testing.T) {
+	environment, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error when creating an environment")
+	environment.RollbackPolicy = RollbackPolicy{CrashCount: 3, CrashWindow: time.Minute}
+	token := environment.Token
+
+	strategy := RolloutStrategy{BatchSize: 2}
+	capacity := CapacityPolicy{Priority: 5}
+	scheduling := SchedulingStrategy{Type: ReplicaStrategy, Replicas: 3}
+	err = environment.Update(token, EnvironmentUpdate{
+		TaskDefinition:     aws.String(updatedTaskDefinition),
+		RolloutStrategy:    &strategy,
+		SchedulingStrategy: &scheduling,
+		CapacityPolicy:     &capacity,
+	})
+	assert.Nil(t, err, "Unexpected error when updating the environment")
+	assert.Exactly(t, updatedTaskDefinition, environment.DesiredTaskDefinition, "Expected the updated task definition")
+	assert.Exactly(t, strategy, environment.RolloutStrategy, "Expected the updated rollout strategy")
+	assert.Exactly(t, scheduling, environment.SchedulingStrategy, "Expected the updated scheduling strategy")
+	assert.Exactly(t, capacity, environment.CapacityPolicy, "Expected the updated capacity policy")
+	assert.Exactly(t, cluster, environment.Cluster, "Expected the cluster to be left unchanged")
+	assert.Exactly(t, 3, environment.RollbackPolicy.CrashCount, "Expected the rollback policy to be left unchanged")
+	assert.NotEqual(t, token, environment.Token, "Expected the token to be rotated")
+
+	err = environment.Update(token, EnvironmentUpdate{Cluster: aws.String("other")})
+	assert.IsType(t, ConflictError{}, err, "Expected a conflict when the token is outdated")
+	assert.Exactly(t, cluster, environment.Cluster, "Expected the environment to be left unchanged")
+}
+
+func TestEnvironmentUpdateClusterSelector(t *testing.T) {
+	environment, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error when creating an environment")
+
+	selector := ClusterSelector{NamePattern: "prod-*"}
+	err = environment.Update(environment.Token, EnvironmentUpdate{ClusterSelector: &selector})
+	assert.Nil(t, err, "Unexpected error when updating the cluster selector")
+	assert.Exactly(t, selector, environment.ClusterSelector, "Expected the updated cluster selector")
+	assert.Empty(t, environment.Cluster, "Expected the cluster to be replaced by the selector")
+
+	err = environment.Update(environment.Token, EnvironmentUpdate{Cluster: aws.String(cluster)})
+	assert.Nil(t, err, "Unexpected error when updating the cluster")
+	assert.True(t, environment.ClusterSelector.IsEmpty(), "Expected the selector to be replaced by the cluster")
+}
+
+func TestEnvironmentUpdateMaintenanceWindows(t *testing.T) {
+	environment, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error when creating an environment")
+
+	windows := []MaintenanceWindow{{Schedule: "0 2 * * 6", Duration: time.Hour}}
+	err = environment.Update(environment.Token, EnvironmentUpdate{MaintenanceWindows: windows})
+	assert.Nil(t, err, "Unexpected error when updating the maintenance windows")
+	assert.Equal(t, windows, environment.MaintenanceWindows, "Expected the updated maintenance windows")
+
+	err = environment.Update(environment.Token, EnvironmentUpdate{RolloutStrategy: &RolloutStrategy{BatchSize: 1}})
+	assert.Nil(t, err, "Unexpected error when updating the rollout strategy")
+	assert.Equal(t, windows, environment.MaintenanceWindows, "Expected the maintenance windows to be left unchanged")
+
+	err = environment.Update(environment.Token, EnvironmentUpdate{MaintenanceWindows: []MaintenanceWindow{}})
+	assert.Nil(t, err, "Unexpected error when removing the maintenance windows")
+	assert.Empty(t, environment.MaintenanceWindows, "Expected the maintenance windows to be removed")
+}
+
+func TestEnvironmentStartDeployment(t *testing.T) {
+	environment, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error when creating an environment")
+
+	environment.TaskOverrides = TaskOverrides{TaskRoleARN: "arn:aws:iam::123456789012:role/agent"}
+
+	d, err := environment.StartDeployment()
+	assert.Nil(t, err, "Unexpected error when starting a deployment")
+	assert.Exactly(t, taskDefinition, d.TaskDefinition, "Expected the desired task definition")
+	assert.Equal(t, environment.TaskOverrides, d.TaskOverrides, "Expected the task overrides of the environment")
+	assert.Exactly(t, environment.Token, d.Token, "Expected the environment token")
+	assert.Exactly(t, d.ID, environment.PendingDeploymentID, "Expected the deployment to be pending")
+
+	_, err = environment.StartDeployment()
+	assert.IsType(t, BadRequestError{}, err, "Expected a bad request while the latest deployment is pending")
+
+	completed, err := d.UpdateDeploymentCompleted(nil)
+	assert.Nil(t, err, "Unexpected error when setting deployment completed")
+	environment.Deployments[d.ID] = *completed
+
+	_, err = environment.StartDeployment()
+	assert.Nil(t, err, "Unexpected error when starting a deployment after the latest one completed")
+}
+
+func drainingPolicy(policy DrainingPolicy) *DrainingPolicy {
+	return &policy
+}
@@ -32,3 +32,15 @@ func NewNotFoundError(err error) NotFoundError {
 		err,
 	}
 }
+
+// ConflictError is returned when a write is based on a version of the data that has
+// since been modified by another writer
+type ConflictError struct {
+	error
+}
+
+func NewConflictError(err error) ConflictError {
+	return ConflictError{
+		err,
+	}
+}
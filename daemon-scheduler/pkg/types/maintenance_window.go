@@ -0,0 +1,294 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	minMaintenanceWindowDuration = time.Minute
+	maxMaintenanceWindowDuration = 7 * 24 * time.Hour
+	// maxCachedCronSchedules bounds the parsed schedules kept, which is cleared when it is reached
+	maxCachedCronSchedules = 1024
+)
+
+// cronSchedules caches the parsed schedules of maintenance windows by expression, as windows are
+// checked on every scheduler run
+var cronSchedules = struct {
+	sync.RWMutex
+	parsed map[string]*cronSchedule
+}{parsed: make(map[string]*cronSchedule)}
+
+// MaintenanceWindow is a recurring period during which deployments may change the tasks of an
+// environment. The window opens every minute Schedule, a cron expression evaluated in UTC,
+// matches and stays open for Duration.
+type MaintenanceWindow struct {
+	// Schedule has the five fields minute, hour, day of month, month and day of week, each
+	// either *, a value, a range or a list of them, optionally with a /step
+	Schedule string
+	Duration time.Duration
+}
+
+// Validate returns an error if the schedule cannot be parsed or the duration is out of range
+func (w MaintenanceWindow) Validate() error {
+	if _, err := cachedCronSchedule(w.Schedule); err != nil {
+		return errors.Wrapf(err, "Invalid schedule '%s'", w.Schedule)
+	}
+	if w.Duration < minMaintenanceWindowDuration || w.Duration > maxMaintenanceWindowDuration {
+		return errors.Errorf("Duration should be between %s and %s but is %s",
+			minMaintenanceWindowDuration, maxMaintenanceWindowDuration, w.Duration)
+	}
+	return nil
+}
+
+// IsOpen returns whether the schedule matched during the Duration before now
+func (w MaintenanceWindow) IsOpen(now time.Time) bool {
+	schedule, err := cachedCronSchedule(w.Schedule)
+	if err != nil {
+		return false
+	}
+
+	now = now.UTC()
+	opened, ok := schedule.latest(now, now.Add(-w.Duration))
+	return ok && now.Sub(opened) < w.Duration
+}
+
+// ValidateMaintenanceWindows returns an error if any of the windows is invalid
+func ValidateMaintenanceWindows(windows []MaintenanceWindow) error {
+	for i, w := range windows {
+		if err := w.Validate(); err != nil {
+			return errors.Wrapf(err, "Invalid maintenance window %d", i)
+		}
+	}
+	return nil
+}
+
+// InMaintenanceWindow returns whether any maintenance window of the environment is open at now.
+// An environment without maintenance windows can be changed at any time.
+func (e *Environment) InMaintenanceWindow(now time.Time) bool {
+	if len(e.MaintenanceWindows) == 0 {
+		return true
+	}
+	for _, w := range e.MaintenanceWindows {
+		if w.IsOpen(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanStartDeployment returns whether the pending deployment d is allowed to start at now, which is
+// once its NotBefore time has passed and while a maintenance window of the environment is open
+func (e *Environment) CanStartDeployment(d Deployment, now time.Time) bool {
+	return !now.Before(d.NotBefore) && e.InMaintenanceWindow(now)
+}
+
+// StartPendingDeployment moves the latest deployment to in-progress if it is pending and allowed
+// to start at now, and returns it. It returns nil if there is no deployment to start.
+func (e *Environment) StartPendingDeployment(now time.Time) *Deployment {
+	d := e.LatestDeployment()
+	if d == nil || d.Status != DeploymentPending || !e.CanStartDeployment(*d, now) {
+		return nil
+	}
+
+	d.Status = DeploymentInProgress
+	e.Deployments[d.ID] = *d
+	return d
+}
+
+// cronFields are the names and ranges of the fields of a cron expression, in order. A day of
+// week of 7 is Sunday, like 0.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// cronSchedule holds the values each field of a cron expression matches as bit sets
+type cronSchedule struct {
+	fields [5]uint64
+	// anyDay and anyWeekday are set when the day of month or day of week field starts with *.
+	// As in vixie cron, a day matches if both fields do when either is set, and if either field
+	// does otherwise.
+	anyDay     bool
+	anyWeekday bool
+}
+
+// cachedCronSchedule returns the parsed schedule of the expression, parsing it only the first time
+func cachedCronSchedule(expr string) (*cronSchedule, error) {
+	cronSchedules.RLock()
+	schedule, ok := cronSchedules.parsed[expr]
+	cronSchedules.RUnlock()
+	if ok {
+		return schedule, nil
+	}
+
+	schedule, err := parseCronSchedule(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	cronSchedules.Lock()
+	defer cronSchedules.Unlock()
+	if len(cronSchedules.parsed) >= maxCachedCronSchedules {
+		cronSchedules.parsed = make(map[string]*cronSchedule)
+	}
+	cronSchedules.parsed[expr] = schedule
+	return schedule, nil
+}
+
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, errors.Errorf("Expected %d fields but found %d", len(cronFields), len(fields))
+	}
+
+	schedule := &cronSchedule{
+		anyDay:     strings.HasPrefix(fields[2], "*"),
+		anyWeekday: strings.HasPrefix(fields[4], "*"),
+	}
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid %s field '%s'", cronFields[i].name, field)
+		}
+		schedule.fields[i] = set
+	}
+
+	// Sunday is matched as 0 only
+	if schedule.fields[4]&(1<<7) != 0 {
+		schedule.fields[4] |= 1
+	}
+
+	return schedule, nil
+}
+
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, errors.Errorf("Invalid step in '%s'", part)
+			}
+		}
+
+		from, to := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			from, err = parseCronValue(bounds[0], min, max)
+			if err != nil {
+				return 0, err
+			}
+			to = from
+			if len(bounds) == 2 {
+				to, err = parseCronValue(bounds[1], min, max)
+				if err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				to = max
+			}
+			if to < from {
+				return 0, errors.Errorf("Invalid range '%s'", rangePart)
+			}
+		}
+
+		for v := from; v <= to; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func parseCronValue(value string, min int, max int) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil || v < min || v > max {
+		return 0, errors.Errorf("Value '%s' should be a number between %d and %d", value, min, max)
+	}
+	return v, nil
+}
+
+// matchesDay returns whether the schedule matches the day of t
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	if !s.has(3, int(t.Month())) {
+		return false
+	}
+
+	day, weekday := s.has(2, t.Day()), s.has(4, int(t.Weekday()))
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// latest returns the latest minute at or before t, in the location of t, that the schedule
+// matches. It returns false if there is none after earliest.
+func (s *cronSchedule) latest(t time.Time, earliest time.Time) (time.Time, bool) {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	for t.After(earliest) {
+		dayStart := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		if !s.matchesDay(t) {
+			t = dayStart.Add(-time.Minute)
+			continue
+		}
+
+		hour := s.latestValue(1, t.Hour())
+		if hour < 0 {
+			t = dayStart.Add(-time.Minute)
+			continue
+		}
+		if hour < t.Hour() {
+			t = dayStart.Add(time.Duration(hour)*time.Hour + 59*time.Minute)
+		}
+
+		minute := s.latestValue(0, t.Minute())
+		if minute < 0 {
+			t = dayStart.Add(time.Duration(hour)*time.Hour - time.Minute)
+			continue
+		}
+		t = dayStart.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		return t, t.After(earliest)
+	}
+	return time.Time{}, false
+}
+
+// latestValue returns the largest value of the field at most value, or -1 if there is none
+func (s *cronSchedule) latestValue(field int, value int) int {
+	for v := value; v >= 0; v-- {
+		if s.has(field, v) {
+			return v
+		}
+	}
+	return -1
+}
+
+func (s *cronSchedule) has(field int, value int) bool {
+	return s.fields[field]&(1<<uint(value)) != 0
+}
@@ -0,0 +1,129 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// saturday is 2017-03-04 02:30 UTC
+var saturday = time.Date(2017, time.March, 4, 2, 30, 0, 0, time.UTC)
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+	valid := []string{"0 2 * * 6", "*/15 0-6 * * 1-5", "0 0 1,15 * *", "30 22 * 1-3/2 7"}
+	for _, schedule := range valid {
+		assert.Nil(t, MaintenanceWindow{Schedule: schedule, Duration: time.Hour}.Validate(),
+			"Unexpected error validating schedule %s", schedule)
+	}
+
+	invalid := []MaintenanceWindow{
+		{Schedule: "", Duration: time.Hour},
+		{Schedule: "0 2 * *", Duration: time.Hour},
+		{Schedule: "60 2 * * *", Duration: time.Hour},
+		{Schedule: "0 5-2 * * *", Duration: time.Hour},
+		{Schedule: "*/0 * * * *", Duration: time.Hour},
+		{Schedule: "0 2 * * sat", Duration: time.Hour},
+		{Schedule: "0 2 * * *", Duration: time.Second},
+		{Schedule: "0 2 * * *", Duration: 8 * 24 * time.Hour},
+	}
+	for _, w := range invalid {
+		assert.Error(t, w.Validate(), "Expected an error validating %+v", w)
+	}
+}
+
+func TestMaintenanceWindowIsOpen(t *testing.T) {
+	w := MaintenanceWindow{Schedule: "0 2 * * 6", Duration: time.Hour}
+	assert.True(t, w.IsOpen(saturday), "Expected the window to be open half an hour after it opened")
+	assert.True(t, w.IsOpen(saturday.Add(-30*time.Minute)), "Expected the window to be open when it opens")
+	assert.False(t, w.IsOpen(saturday.Add(30*time.Minute)), "Expected the window to be closed once its duration passed")
+	assert.False(t, w.IsOpen(saturday.Add(-31*time.Minute)), "Expected the window to be closed before it opens")
+	assert.False(t, w.IsOpen(saturday.Add(24*time.Hour)), "Expected the window to be closed on other days of the week")
+
+	local := saturday.In(time.FixedZone("UTC+5", 5*60*60))
+	assert.True(t, w.IsOpen(local), "Expected the schedule to be evaluated in UTC")
+}
+
+func TestMaintenanceWindowDayOfMonthOrDayOfWeek(t *testing.T) {
+	// like cron, a day matches if either the day of month or the day of week does
+	w := MaintenanceWindow{Schedule: "0 2 1 * 0", Duration: time.Hour}
+	assert.False(t, w.IsOpen(saturday), "Expected the window to be closed on a Saturday that is not the 1st")
+	assert.True(t, w.IsOpen(saturday.Add(24*time.Hour)), "Expected the window to be open on Sunday")
+	assert.True(t, w.IsOpen(time.Date(2017, time.March, 1, 2, 0, 0, 0, time.UTC)), "Expected the window to be open on the 1st")
+
+	w = MaintenanceWindow{Schedule: "0 2 * * 7", Duration: time.Hour}
+	assert.True(t, w.IsOpen(saturday.Add(24*time.Hour)), "Expected 7 to match Sunday")
+}
+
+func TestMaintenanceWindowDayOfMonthStep(t *testing.T) {
+	w := MaintenanceWindow{Schedule: "0 2 */2 * *", Duration: time.Hour}
+	assert.False(t, w.IsOpen(saturday), "Expected the window to be closed on an even day")
+	assert.True(t, w.IsOpen(saturday.Add(24*time.Hour)), "Expected the window to be open on an odd day")
+}
+
+func TestMaintenanceWindowDayOfMonthStepAndDayOfWeek(t *testing.T) {
+	// like cron, a day has to match both fields when either starts with *
+	w := MaintenanceWindow{Schedule: "0 2 */2 * 0", Duration: time.Hour}
+	assert.True(t, w.IsOpen(saturday.Add(24*time.Hour)), "Expected the window to be open on Sunday the 5th")
+	assert.False(t, w.IsOpen(saturday.Add(8*24*time.Hour)), "Expected the window to be closed on Sunday the 12th")
+	assert.False(t, w.IsOpen(saturday.Add(-24*time.Hour)), "Expected the window to be closed on Friday the 3rd")
+}
+
+func TestMaintenanceWindowIsOpenSinceEarlierHourOrDay(t *testing.T) {
+	w := MaintenanceWindow{Schedule: "45 1-3 * * *", Duration: 30 * time.Minute}
+	assert.False(t, w.IsOpen(saturday), "Expected the window opened at 01:45 to be closed at 02:30")
+	assert.True(t, w.IsOpen(saturday.Add(-20*time.Minute)), "Expected the window opened at 01:45 to be open at 02:10")
+
+	w = MaintenanceWindow{Schedule: "0 0 1 * *", Duration: 7 * 24 * time.Hour}
+	assert.True(t, w.IsOpen(saturday), "Expected the window opened on the 1st to be open on the 4th")
+	assert.False(t, w.IsOpen(saturday.Add(5*24*time.Hour)), "Expected the window opened on the 1st to be closed on the 9th")
+}
+
+func TestEnvironmentCanStartDeployment(t *testing.T) {
+	environment, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error when creating an environment")
+
+	d := Deployment{ID: "deployment", Status: DeploymentPending}
+	assert.True(t, environment.CanStartDeployment(d, saturday), "Expected deployments to start at any time without windows")
+
+	d.NotBefore = saturday.Add(time.Minute)
+	assert.False(t, environment.CanStartDeployment(d, saturday), "Expected the deployment to wait for its start time")
+	assert.True(t, environment.CanStartDeployment(d, d.NotBefore), "Expected the deployment to start at its start time")
+
+	environment.MaintenanceWindows = []MaintenanceWindow{{Schedule: "0 4 * * *", Duration: time.Hour}}
+	assert.False(t, environment.CanStartDeployment(d, d.NotBefore), "Expected the deployment to wait for a window to open")
+	environment.MaintenanceWindows = append(environment.MaintenanceWindows, MaintenanceWindow{Schedule: "0 2 * * 6", Duration: time.Hour})
+	assert.True(t, environment.CanStartDeployment(d, d.NotBefore), "Expected the deployment to start once any window is open")
+}
+
+func TestEnvironmentStartPendingDeployment(t *testing.T) {
+	environment, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error when creating an environment")
+	assert.Nil(t, environment.StartPendingDeployment(saturday), "Expected no deployment to start without a deployment")
+
+	d, err := environment.StartDeployment()
+	assert.Nil(t, err, "Unexpected error when starting a deployment")
+	d.NotBefore = saturday.Add(time.Hour)
+	environment.Deployments[d.ID] = *d
+
+	assert.Nil(t, environment.StartPendingDeployment(saturday), "Expected the deployment to wait for its start time")
+	assert.Exactly(t, DeploymentPending, environment.Deployments[d.ID].Status, "Expected the deployment to stay pending")
+
+	started := environment.StartPendingDeployment(d.NotBefore)
+	assert.NotNil(t, started, "Expected the deployment to start")
+	assert.Exactly(t, DeploymentInProgress, environment.Deployments[d.ID].Status, "Expected the deployment to be in progress")
+	assert.Nil(t, environment.StartPendingDeployment(d.NotBefore), "Expected an in-progress deployment not to start again")
+}
@@ -0,0 +1,231 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// AvailabilityZoneAttribute is the instance attribute holding the availability zone of the instance
+const AvailabilityZoneAttribute = "ecs.availability-zone"
+
+// Placement expression operators. An expression without an operator only requires the
+// attribute to be set.
+const (
+	OperatorEquals       = "=="
+	OperatorNotEquals    = "!="
+	OperatorMatches      = "=~"
+	OperatorDoesNotMatch = "!~"
+)
+
+var placementOperators = []string{OperatorEquals, OperatorNotEquals, OperatorMatches, OperatorDoesNotMatch}
+
+// maxParsedExpressions bounds the number of parsed expressions kept around
+const maxParsedExpressions = 1024
+
+// parsedExpressions holds the expressions that have been validated or matched against instances,
+// so that their patterns are only compiled once rather than on every scheduler pass
+var parsedExpressions = struct {
+	lock        sync.RWMutex
+	expressions map[string]placementExpression
+}{expressions: make(map[string]placementExpression)}
+
+// PlacementConstraints limit the instances of its cluster an environment is deployed to. An
+// instance has to satisfy every constraint, so empty constraints match every instance.
+type PlacementConstraints struct {
+	// Expressions are evaluated against the instance attributes, e.g. "ecs.instance-type =~ m5.*"
+	Expressions []string
+	// AvailabilityZones lists the availability zones instances can be in
+	AvailabilityZones []string
+	// IncludeInstances lists the container instance ARNs or EC2 instance IDs of the only
+	// instances the environment is deployed to
+	IncludeInstances []string
+	// ExcludeInstances lists the container instance ARNs or EC2 instance IDs of instances the
+	// environment is never deployed to
+	ExcludeInstances []string
+}
+
+// PlacementInstance holds the instance details placement constraints are evaluated against
+type PlacementInstance struct {
+	ContainerInstanceARN string
+	EC2InstanceID        string
+	// attribute name -> value
+	Attributes map[string]string
+}
+
+type placementExpression struct {
+	attribute string
+	operator  string
+	value     string
+	pattern   *regexp.Regexp
+}
+
+// Validate returns an error if any of the expressions cannot be parsed
+func (c PlacementConstraints) Validate() error {
+	_, err := parseExpressions(c.Expressions)
+	return err
+}
+
+// IsEmpty returns whether there are no constraints
+func (c PlacementConstraints) IsEmpty() bool {
+	return len(c.Expressions) == 0 && len(c.AvailabilityZones) == 0 &&
+		len(c.IncludeInstances) == 0 && len(c.ExcludeInstances) == 0
+}
+
+// Matches returns whether the instance satisfies every constraint. Invalid expressions
+// match no instance.
+func (c PlacementConstraints) Matches(instance PlacementInstance) bool {
+	if len(c.IncludeInstances) > 0 && !identifies(c.IncludeInstances, instance) {
+		return false
+	}
+
+	if identifies(c.ExcludeInstances, instance) {
+		return false
+	}
+
+	if len(c.AvailabilityZones) > 0 && !contains(c.AvailabilityZones, instance.Attributes[AvailabilityZoneAttribute]) {
+		return false
+	}
+
+	expressions, err := parseExpressions(c.Expressions)
+	if err != nil {
+		return false
+	}
+
+	for _, expression := range expressions {
+		if !expression.matches(instance.Attributes) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e placementExpression) matches(attributes map[string]string) bool {
+	value, ok := attributes[e.attribute]
+	switch e.operator {
+	case "":
+		return ok
+	case OperatorEquals:
+		return ok && value == e.value
+	case OperatorNotEquals:
+		return !ok || value != e.value
+	case OperatorMatches:
+		return ok && e.pattern.MatchString(value)
+	case OperatorDoesNotMatch:
+		return !ok || !e.pattern.MatchString(value)
+	default:
+		return false
+	}
+}
+
+func parseExpressions(expressions []string) ([]placementExpression, error) {
+	parsed := make([]placementExpression, 0, len(expressions))
+	for _, expression := range expressions {
+		p, err := parseCachedExpression(expression)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, p)
+	}
+	return parsed, nil
+}
+
+// parseCachedExpression returns the parsed expression, parsing it only if it has not been
+// parsed before. Invalid expressions are not kept.
+func parseCachedExpression(expression string) (placementExpression, error) {
+	parsedExpressions.lock.RLock()
+	p, ok := parsedExpressions.expressions[expression]
+	parsedExpressions.lock.RUnlock()
+	if ok {
+		return p, nil
+	}
+
+	p, err := parseExpression(expression)
+	if err != nil {
+		return placementExpression{}, err
+	}
+
+	parsedExpressions.lock.Lock()
+	defer parsedExpressions.lock.Unlock()
+	// expressions of environments that changed are not tracked, so start over once there are
+	// too many
+	if len(parsedExpressions.expressions) >= maxParsedExpressions {
+		parsedExpressions.expressions = make(map[string]placementExpression)
+	}
+	parsedExpressions.expressions[expression] = p
+	return p, nil
+}
+
+// parseExpression parses expressions of the form "attribute", "attribute == value",
+// "attribute != value", "attribute =~ pattern" or "attribute !~ pattern". Patterns are
+// regular expressions that have to match the whole value.
+func parseExpression(expression string) (placementExpression, error) {
+	index, operator := -1, ""
+	for _, op := range placementOperators {
+		i := strings.Index(expression, op)
+		if i >= 0 && (index < 0 || i < index) {
+			index, operator = i, op
+		}
+	}
+
+	if index < 0 {
+		attribute := strings.TrimSpace(expression)
+		if attribute == "" {
+			return placementExpression{}, errors.New("Placement expression should not be empty")
+		}
+		return placementExpression{attribute: attribute}, nil
+	}
+
+	p := placementExpression{
+		attribute: strings.TrimSpace(expression[:index]),
+		operator:  operator,
+		value:     strings.TrimSpace(expression[index+len(operator):]),
+	}
+	if p.attribute == "" {
+		return placementExpression{}, errors.Errorf("Placement expression '%s' is missing an attribute", expression)
+	}
+
+	if operator == OperatorMatches || operator == OperatorDoesNotMatch {
+		pattern, err := regexp.Compile("^(?:" + p.value + ")$")
+		if err != nil {
+			return placementExpression{}, errors.Wrapf(err, "Invalid pattern in placement expression '%s'", expression)
+		}
+		p.pattern = pattern
+	}
+
+	return p, nil
+}
+
+func identifies(ids []string, instance PlacementInstance) bool {
+	for _, id := range ids {
+		if id == instance.ContainerInstanceARN || (id != "" && id == instance.EC2InstanceID) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	instanceARN   = "arn:aws:ecs:us-east-1:123456789123:container-instance/instance1"
+	ec2InstanceID = "i-0123456789abcdef0"
+)
+
+var placementInstance = PlacementInstance{
+	ContainerInstanceARN: instanceARN,
+	EC2InstanceID:        ec2InstanceID,
+	Attributes: map[string]string{
+		"ecs.instance-type":       "m5.large",
+		AvailabilityZoneAttribute: "us-east-1a",
+		"gpu":                     "",
+	},
+}
+
+func TestPlacementConstraintsValidate(t *testing.T) {
+	valid := PlacementConstraints{Expressions: []string{"gpu", "ecs.instance-type =~ m5.*", "ecs.os-type != windows"}}
+	assert.Nil(t, valid.Validate(), "Unexpected error validating valid expressions")
+
+	for _, expression := range []string{"", " == m5.large", "ecs.instance-type =~ m5.("} {
+		invalid := PlacementConstraints{Expressions: []string{expression}}
+		assert.Error(t, invalid.Validate(), "Expected an error validating '%s'", expression)
+	}
+}
+
+func TestPlacementConstraintsMatchesEmpty(t *testing.T) {
+	assert.True(t, PlacementConstraints{}.Matches(placementInstance), "Expected empty constraints to match every instance")
+}
+
+func TestPlacementConstraintsMatchesExpressions(t *testing.T) {
+	matches := map[string]bool{
+		"gpu":                            true,
+		"ecs.cpu-architecture":           false,
+		"ecs.instance-type == m5.large":  true,
+		"ecs.instance-type==m5.xlarge":   false,
+		"ecs.instance-type != m5.xlarge": true,
+		"ecs.os-type != windows":         true,
+		"ecs.instance-type =~ m5.*":      true,
+		"ecs.instance-type =~ m5":        false,
+		"ecs.instance-type !~ (c5|m5).*": false,
+		"ecs.os-type !~ windows.*":       true,
+		"ecs.availability-zone =~ .*-1a": true,
+	}
+	for expression, expected := range matches {
+		constraints := PlacementConstraints{Expressions: []string{expression}}
+		assert.Equal(t, expected, constraints.Matches(placementInstance), "Unexpected result for '%s'", expression)
+	}
+}
+
+func TestPlacementConstraintsCompilesPatternsOnce(t *testing.T) {
+	expression := "ecs.instance-type =~ (m5|c5).large"
+	constraints := PlacementConstraints{Expressions: []string{expression}}
+	assert.Nil(t, constraints.Validate(), "Unexpected error validating the expression")
+
+	validated, err := parseCachedExpression(expression)
+	assert.Nil(t, err, "Unexpected error parsing the expression")
+	assert.True(t, constraints.Matches(placementInstance), "Expected the instance to match")
+	matched, err := parseCachedExpression(expression)
+	assert.Nil(t, err, "Unexpected error parsing the expression")
+	assert.True(t, validated.pattern == matched.pattern, "Expected the pattern compiled on validation to be reused")
+}
+
+func TestPlacementConstraintsMatchesAvailabilityZones(t *testing.T) {
+	assert.True(t, PlacementConstraints{AvailabilityZones: []string{"us-east-1b", "us-east-1a"}}.Matches(placementInstance),
+		"Expected an instance in one of the availability zones to match")
+	assert.False(t, PlacementConstraints{AvailabilityZones: []string{"us-east-1b"}}.Matches(placementInstance),
+		"Expected an instance in another availability zone not to match")
+}
+
+func TestPlacementConstraintsMatchesInstances(t *testing.T) {
+	assert.True(t, PlacementConstraints{IncludeInstances: []string{ec2InstanceID}}.Matches(placementInstance),
+		"Expected an included EC2 instance to match")
+	assert.False(t, PlacementConstraints{IncludeInstances: []string{"i-other"}}.Matches(placementInstance),
+		"Expected an instance that is not included not to match")
+	assert.False(t, PlacementConstraints{ExcludeInstances: []string{instanceARN}}.Matches(placementInstance),
+		"Expected an excluded container instance not to match")
+	assert.False(t, PlacementConstraints{
+		IncludeInstances: []string{instanceARN},
+		ExcludeInstances: []string{ec2InstanceID},
+	}.Matches(placementInstance), "Expected exclusions to take precedence over inclusions")
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+// DeploymentPlan is what the scheduler would do to roll out a new deployment of an environment
+type DeploymentPlan struct {
+	TaskDefinition string
+	// Clusters are the plans for each cluster the environment is deployed to, ordered by cluster
+	Clusters []ClusterPlan
+}
+
+// ClusterPlan is what the scheduler would do in one of the clusters of an environment
+type ClusterPlan struct {
+	Cluster string
+	// NewInstances run no task of the environment and would get a task of the deployment right away
+	NewInstances []string
+	// Batches are the instances running tasks of earlier deployments, in the order the tasks would
+	// be replaced. There is a single batch unless the environment has a rolling strategy.
+	Batches [][]string
+	// StoppedTasks are the tasks that would be stopped, both the tasks of earlier deployments on
+	// the instances of the batches and the tasks of lower priority environments stopped to make room
+	StoppedTasks []PlannedTask
+	// SkippedInstances are the instances that would not get a task of the deployment
+	SkippedInstances []SkippedInstance
+}
+
+// PlannedTask is a task that would be stopped
+type PlannedTask struct {
+	TaskARN      string
+	InstanceARN  string
+	Environment  string
+	DeploymentID string
+}
+
+// SkippedInstance is an instance that would not get a task of the deployment, and why
+type SkippedInstance struct {
+	InstanceARN string
+	Reason      string
+}
+
+// Reasons instances are skipped that are not capacity failures
+const (
+	InactiveInstanceReason     = "INACTIVE"
+	DrainingInstanceReason     = "DRAINING"
+	AgentDisconnectedReason    = "AGENT_DISCONNECTED"
+	PlacementConstraintsReason = "PLACEMENT_CONSTRAINTS"
+	// NotPlacedReason is given to the instances the scheduling strategy of the environment does
+	// not place a task on, such as the instances left over once every replica is placed
+	NotPlacedReason = "STRATEGY:NOT_PLACED"
+	// RolloutBlockedReason is given to the instances of a rolling update that would wait for
+	// more instances to be healthy
+	RolloutBlockedReason = "ROLLOUT:TOO_FEW_HEALTHY_INSTANCES"
+)
+
+// CapacityPlan is the outcome of checking the capacity of instances for a task of an environment
+// without acting on it
+type CapacityPlan struct {
+	// Available are the instances with the capacity to run the task
+	Available []string
+	// Insufficient are the reasons the other instances cannot run the task, by instance ARN
+	Insufficient map[string]string
+	// StoppedTasks are the tasks of lower priority environments that would be stopped to make room
+	StoppedTasks []PlannedTask
+}
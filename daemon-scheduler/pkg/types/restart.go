@@ -0,0 +1,185 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Settings of the restart policy of environments that leave them unset
+const (
+	DefaultRestartInitialBackoff = 10 * time.Second
+	DefaultRestartMaxBackoff     = 5 * time.Minute
+	DefaultMaxRestarts           = 10
+	DefaultRestartStableTime     = 10 * time.Minute
+)
+
+// RestartPolicy limits how often the task of an environment is restarted on an instance where it
+// keeps stopping shortly after starting. Settings left at zero take their default value.
+type RestartPolicy struct {
+	// InitialBackoff is how long the first restart waits. The wait doubles with every further
+	// restart up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// MaxRestarts is how many times the task is restarted before the instance is crash-looping
+	// and the task is no longer restarted
+	MaxRestarts int
+	// StableTime is how long a task has to run for its stopping not to count as a crash
+	StableTime time.Duration
+}
+
+// Validate returns an error if a setting is negative or the initial backoff is longer than the
+// maximum backoff
+func (p RestartPolicy) Validate() error {
+	if p.InitialBackoff < 0 {
+		return errors.Errorf("Initial backoff %s should not be negative", p.InitialBackoff)
+	}
+	if p.MaxBackoff < 0 {
+		return errors.Errorf("Max backoff %s should not be negative", p.MaxBackoff)
+	}
+	if p.MaxRestarts < 0 {
+		return errors.Errorf("Max restarts %d should not be negative", p.MaxRestarts)
+	}
+	if p.StableTime < 0 {
+		return errors.Errorf("Stable time %s should not be negative", p.StableTime)
+	}
+
+	policy := p.WithDefaults()
+	if policy.InitialBackoff > policy.MaxBackoff {
+		return errors.Errorf("Initial backoff %s should not be longer than the max backoff %s",
+			policy.InitialBackoff, policy.MaxBackoff)
+	}
+	return nil
+}
+
+// WithDefaults returns the policy with the settings left at zero set to their default value
+func (p RestartPolicy) WithDefaults() RestartPolicy {
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = DefaultRestartInitialBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = DefaultRestartMaxBackoff
+	}
+	if p.MaxRestarts == 0 {
+		p.MaxRestarts = DefaultMaxRestarts
+	}
+	if p.StableTime == 0 {
+		p.StableTime = DefaultRestartStableTime
+	}
+	return p
+}
+
+// Backoff returns how long to wait before restarting a task that crashed count times in a row
+func (p RestartPolicy) Backoff(count int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < count && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return backoff
+}
+
+// Crashed returns whether the task stopped before running for StableTime
+func (p RestartPolicy) Crashed(task StoppedTask) bool {
+	return task.StartedAt.IsZero() || task.StoppedAt.Sub(task.StartedAt) < p.StableTime
+}
+
+// IsCrashLooping returns whether the task is no longer restarted on the instance
+func (p RestartPolicy) IsCrashLooping(restart InstanceRestart) bool {
+	return restart.Count > p.MaxRestarts
+}
+
+// StoppedTask describes a task of an environment that stopped on an instance
+type StoppedTask struct {
+	TaskARN string
+	// StartedAt is zero if the task never ran
+	StartedAt     time.Time
+	StoppedAt     time.Time
+	StoppedReason string
+	// ExitCodes are the exit codes of the containers of the task by container name
+	ExitCodes map[string]int64
+}
+
+// InstanceRestart tracks the restarts of the task of an environment on an instance where the
+// tasks of a deployment keep crashing
+type InstanceRestart struct {
+	Cluster      string
+	DeploymentID string
+	// Count is how many tasks of the deployment crashed on the instance in a row
+	Count int
+	// NextRestart is when the task can be restarted
+	NextRestart time.Time
+	// LastStoppedTask is the latest task that crashed
+	LastStoppedTask StoppedTask
+}
+
+// RecordCrash returns the restarts once the task of the deployment crashed. Crashes of tasks of
+// earlier deployments are not counted.
+func (r InstanceRestart) RecordCrash(cluster string, deploymentID string, task StoppedTask,
+	policy RestartPolicy, now time.Time) InstanceRestart {
+
+	if r.DeploymentID != deploymentID {
+		r = InstanceRestart{}
+	}
+
+	r.Cluster = cluster
+	r.DeploymentID = deploymentID
+	r.Count++
+	r.LastStoppedTask = task
+	r.NextRestart = now.Add(policy.Backoff(r.Count))
+	return r
+}
+
+// UpdateRestarts applies the updated restarts of instances, removing the ones that are nil, and
+// marks the environment unhealthy while an instance is crash-looping
+func (e *Environment) UpdateRestarts(updates map[string]*InstanceRestart) {
+	if e.Restarts == nil {
+		e.Restarts = make(map[string]InstanceRestart, len(updates))
+	}
+
+	for instanceARN, restart := range updates {
+		if restart == nil {
+			delete(e.Restarts, instanceARN)
+		} else {
+			e.Restarts[instanceARN] = *restart
+		}
+	}
+
+	e.Health = EnvironmentHealthy
+	if latest := e.LatestDeployment(); latest != nil && latest.Health == DeploymentUnhealthy {
+		e.Health = EnvironmentUnhealthy
+	}
+	if len(e.CrashLoopingInstances()) > 0 {
+		e.Health = EnvironmentUnhealthy
+	}
+}
+
+// CrashLoopingInstances returns, in order, the instances the task of the environment is no longer
+// restarted on under its restart policy
+func (e *Environment) CrashLoopingInstances() []string {
+	policy := e.RestartPolicy.WithDefaults()
+	instances := make([]string, 0)
+	for instanceARN, restart := range e.Restarts {
+		if policy.IsCrashLooping(restart) {
+			instances = append(instances, instanceARN)
+		}
+	}
+	sort.Strings(instances)
+	return instances
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartPolicyBackoff(t *testing.T) {
+	policy := RestartPolicy{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute, MaxRestarts: 3}
+	assert.Exactly(t, 10*time.Second, policy.Backoff(1), "Expected the initial backoff after the first crash")
+	assert.Exactly(t, 20*time.Second, policy.Backoff(2), "Expected the backoff to double")
+	assert.Exactly(t, 40*time.Second, policy.Backoff(3), "Expected the backoff to double")
+	assert.Exactly(t, time.Minute, policy.Backoff(4), "Expected the backoff to be capped")
+	assert.Exactly(t, time.Minute, policy.Backoff(100), "Expected the backoff to be capped")
+}
+
+func TestRestartPolicyCrashed(t *testing.T) {
+	policy := RestartPolicy{StableTime: 10 * time.Minute}
+	now := time.Now()
+	assert.True(t, policy.Crashed(StoppedTask{StoppedAt: now}), "Expected a task that never ran to have crashed")
+	assert.True(t, policy.Crashed(StoppedTask{StartedAt: now.Add(-time.Minute), StoppedAt: now}),
+		"Expected a task that stopped shortly after starting to have crashed")
+	assert.False(t, policy.Crashed(StoppedTask{StartedAt: now.Add(-time.Hour), StoppedAt: now}),
+		"Expected a task that ran for the stable time not to have crashed")
+}
+
+func TestRestartPolicyValidate(t *testing.T) {
+	assert.Nil(t, RestartPolicy{}.Validate(), "Unexpected error validating the default restart policy")
+	assert.Nil(t, RestartPolicy{InitialBackoff: time.Second, MaxBackoff: time.Minute, MaxRestarts: 3}.Validate(),
+		"Unexpected error validating a restart policy")
+	assert.Error(t, RestartPolicy{InitialBackoff: -time.Second}.Validate(), "Expected an error when the initial backoff is negative")
+	assert.Error(t, RestartPolicy{MaxBackoff: -time.Second}.Validate(), "Expected an error when the max backoff is negative")
+	assert.Error(t, RestartPolicy{MaxRestarts: -1}.Validate(), "Expected an error when the max restarts are negative")
+	assert.Error(t, RestartPolicy{StableTime: -time.Second}.Validate(), "Expected an error when the stable time is negative")
+	assert.Error(t, RestartPolicy{InitialBackoff: time.Hour}.Validate(),
+		"Expected an error when the initial backoff is longer than the default max backoff")
+}
+
+func TestRestartPolicyWithDefaults(t *testing.T) {
+	assert.Exactly(t, RestartPolicy{
+		InitialBackoff: DefaultRestartInitialBackoff,
+		MaxBackoff:     DefaultRestartMaxBackoff,
+		MaxRestarts:    DefaultMaxRestarts,
+		StableTime:     DefaultRestartStableTime,
+	}, RestartPolicy{}.WithDefaults(), "Expected the default settings")
+
+	policy := RestartPolicy{MaxRestarts: 3}.WithDefaults()
+	assert.Exactly(t, 3, policy.MaxRestarts, "Expected the max restarts that are set to be kept")
+	assert.Exactly(t, DefaultRestartStableTime, policy.StableTime, "Expected the default stable time")
+}
+
+func TestInstanceRestartRecordCrash(t *testing.T) {
+	policy := RestartPolicy{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute, MaxRestarts: 1}
+	now := time.Now()
+	task := StoppedTask{TaskARN: "task-1", StoppedReason: "Essential container in task exited"}
+
+	restart := InstanceRestart{}.RecordCrash("cluster", "deployment-1", task, policy, now)
+	assert.Exactly(t, 1, restart.Count, "Expected the crash to be counted")
+	assert.Exactly(t, "cluster", restart.Cluster, "Expected the cluster to be recorded")
+	assert.Exactly(t, task, restart.LastStoppedTask, "Expected the stopped task to be recorded")
+	assert.Exactly(t, now.Add(10*time.Second), restart.NextRestart, "Expected the restart after the initial backoff")
+	assert.False(t, policy.IsCrashLooping(restart), "Expected the task to be restarted")
+
+	restart = restart.RecordCrash("cluster", "deployment-1", task, policy, now)
+	assert.Exactly(t, 2, restart.Count, "Expected the crash to be counted")
+	assert.Exactly(t, now.Add(20*time.Second), restart.NextRestart, "Expected the backoff to double")
+	assert.True(t, policy.IsCrashLooping(restart), "Expected the instance to be crash-looping")
+
+	restart = restart.RecordCrash("cluster", "deployment-2", task, policy, now)
+	assert.Exactly(t, 1, restart.Count, "Expected the crashes of earlier deployments not to count")
+	assert.Exactly(t, "deployment-2", restart.DeploymentID, "Expected the deployment to be recorded")
+}
+
+func TestEnvironmentUpdateRestarts(t *testing.T) {
+	environment, err := NewEnvironment("test", "taskDefinition", "cluster")
+	assert.Nil(t, err, "Unexpected error creating an environment")
+
+	crashLooping := InstanceRestart{Count: DefaultMaxRestarts + 1}
+	environment.UpdateRestarts(map[string]*InstanceRestart{
+		"instance-2": &crashLooping,
+		"instance-1": &crashLooping,
+		"instance-3": {Count: 2},
+	})
+	assert.Equal(t, []string{"instance-1", "instance-2"}, environment.CrashLoopingInstances(),
+		"Expected the crash-looping instances in order")
+	assert.Exactly(t, EnvironmentUnhealthy, environment.Health, "Expected the environment to be unhealthy")
+
+	environment.UpdateRestarts(map[string]*InstanceRestart{
+		"instance-1": nil,
+		"instance-2": nil,
+	})
+	assert.Empty(t, environment.CrashLoopingInstances(), "Expected no crash-looping instances")
+	assert.Len(t, environment.Restarts, 1, "Expected the restarts of the other instances to be kept")
+	assert.Exactly(t, EnvironmentHealthy, environment.Health, "Expected the environment to be healthy again")
+
+	environment.RestartPolicy = RestartPolicy{MaxRestarts: 1}
+	assert.Equal(t, []string{"instance-3"}, environment.CrashLoopingInstances(),
+		"Expected the instances to be crash-looping under the restart policy of the environment")
+}
@@ -0,0 +1,135 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// RollbackPolicy decides when an in-progress deployment has failed and is rolled back to the
+// task definition of the latest healthy completed deployment. A check is disabled while its
+// threshold is zero, so the zero value never rolls back.
+type RollbackPolicy struct {
+	// FailedInstancePercent is the percentage of the instances targeted by the deployment on
+	// which starting a task failed that fails the deployment
+	FailedInstancePercent int
+	// PendingTaskCount is the number of tasks that fails the deployment by staying PENDING for
+	// longer than PendingTimeout, or by stopping without ever running
+	PendingTaskCount int
+	PendingTimeout   time.Duration
+	// CrashCount is the number of tasks that fails the deployment by stopping after running
+	// within the last CrashWindow
+	CrashCount  int
+	CrashWindow time.Duration
+}
+
+// Validate returns an error if any of the settings is out of range
+func (p RollbackPolicy) Validate() error {
+	if p.FailedInstancePercent < 0 || p.FailedInstancePercent > 100 {
+		return errors.Errorf("Failed instance percent %d should be between 0 and 100", p.FailedInstancePercent)
+	}
+	if p.PendingTaskCount < 0 {
+		return errors.Errorf("Pending task count %d should not be negative", p.PendingTaskCount)
+	}
+	if p.PendingTaskCount > 0 && p.PendingTimeout <= 0 {
+		return errors.New("Pending timeout should be positive when the pending task count is set")
+	}
+	if p.CrashCount < 0 {
+		return errors.Errorf("Crash count %d should not be negative", p.CrashCount)
+	}
+	if p.CrashCount > 0 && p.CrashWindow <= 0 {
+		return errors.New("Crash window should be positive when the crash count is set")
+	}
+	return nil
+}
+
+// IsEnabled returns whether any of the checks is enabled
+func (p RollbackPolicy) IsEnabled() bool {
+	return p.FailedInstancePercent > 0 || p.PendingTaskCount > 0 || p.CrashCount > 0
+}
+
+// ChecksStoppedTasks returns whether any of the enabled checks looks at stopped tasks
+func (p RollbackPolicy) ChecksStoppedTasks() bool {
+	return p.PendingTaskCount > 0 || p.CrashCount > 0
+}
+
+// FailureReason returns why a deployment has failed given the instances on which starting its
+// tasks failed and its active and stopped tasks at now, or an empty string if it has not. Tasks
+// the scheduler stopped itself, such as evicted or drained ones, do not count against it.
+func (p RollbackPolicy) FailureReason(failures []*ecs.Failure, tasks []*ecs.Task,
+	stoppedTasks []*ecs.Task, now time.Time) string {
+
+	failedTasks := make([]*ecs.Task, 0, len(stoppedTasks))
+	for _, t := range stoppedTasks {
+		if !IsStoppedByScheduler(aws.StringValue(t.StoppedReason)) {
+			failedTasks = append(failedTasks, t)
+		}
+	}
+	stoppedTasks = failedTasks
+
+	if p.FailedInstancePercent > 0 {
+		failed := make(map[string]bool)
+		for _, f := range failures {
+			failed[aws.StringValue(f.Arn)] = true
+		}
+		targeted := make(map[string]bool, len(failed))
+		for arn := range failed {
+			targeted[arn] = true
+		}
+		for _, t := range tasks {
+			targeted[aws.StringValue(t.ContainerInstanceArn)] = true
+		}
+		if len(failed) > 0 && len(failed)*100 >= p.FailedInstancePercent*len(targeted) {
+			return fmt.Sprintf("Starting tasks failed on %d of %d instances", len(failed), len(targeted))
+		}
+	}
+
+	if p.PendingTaskCount > 0 {
+		pending := 0
+		for _, t := range tasks {
+			if aws.StringValue(t.LastStatus) == "PENDING" && t.CreatedAt != nil &&
+				now.Sub(aws.TimeValue(t.CreatedAt)) > p.PendingTimeout {
+				pending++
+			}
+		}
+		for _, t := range stoppedTasks {
+			if t.StartedAt == nil {
+				pending++
+			}
+		}
+		if pending >= p.PendingTaskCount {
+			return fmt.Sprintf("%d tasks stayed pending for longer than %s or stopped without running",
+				pending, p.PendingTimeout)
+		}
+	}
+
+	if p.CrashCount > 0 {
+		crashes := 0
+		for _, t := range stoppedTasks {
+			if t.StartedAt != nil && t.StoppedAt != nil && now.Sub(aws.TimeValue(t.StoppedAt)) <= p.CrashWindow {
+				crashes++
+			}
+		}
+		if crashes >= p.CrashCount {
+			return fmt.Sprintf("%d tasks stopped after running within %s", crashes, p.CrashWindow)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackPolicyValidate(t *testing.T) {
+	assert.Nil(t, RollbackPolicy{}.Validate(), "Unexpected error validating the default policy")
+	assert.Nil(t, RollbackPolicy{FailedInstancePercent: 50, PendingTaskCount: 2, PendingTimeout: time.Minute,
+		CrashCount: 3, CrashWindow: time.Minute}.Validate(), "Unexpected error validating a valid policy")
+
+	invalid := []RollbackPolicy{
+		{FailedInstancePercent: -1},
+		{FailedInstancePercent: 101},
+		{PendingTaskCount: -1},
+		{PendingTaskCount: 1},
+		{CrashCount: -1},
+		{CrashCount: 1, CrashWindow: -time.Second},
+	}
+	for _, policy := range invalid {
+		assert.Error(t, policy.Validate(), "Expected an error validating %+v", policy)
+	}
+}
+
+func TestRollbackPolicyFailedInstances(t *testing.T) {
+	policy := RollbackPolicy{FailedInstancePercent: 50}
+	failures := []*ecs.Failure{{Arn: aws.String("instance-1")}}
+	tasks := []*ecs.Task{{ContainerInstanceArn: aws.String("instance-2")}, {ContainerInstanceArn: aws.String("instance-3")}}
+
+	assert.Empty(t, policy.FailureReason(failures, tasks, nil, time.Now()), "Expected a third of the instances to be tolerated")
+	assert.NotEmpty(t, policy.FailureReason(failures, tasks[:1], nil, time.Now()), "Expected half of the instances to fail the deployment")
+	assert.Empty(t, policy.FailureReason(nil, nil, nil, time.Now()), "Expected no failure without instances")
+}
+
+func TestRollbackPolicyPendingTasks(t *testing.T) {
+	now := time.Now()
+	policy := RollbackPolicy{PendingTaskCount: 2, PendingTimeout: time.Minute}
+	stuck := &ecs.Task{LastStatus: aws.String("PENDING"), CreatedAt: aws.Time(now.Add(-2 * time.Minute))}
+	recent := &ecs.Task{LastStatus: aws.String("PENDING"), CreatedAt: aws.Time(now.Add(-time.Second))}
+	neverStarted := &ecs.Task{LastStatus: aws.String("STOPPED"), StoppedAt: aws.Time(now)}
+
+	assert.Empty(t, policy.FailureReason(nil, []*ecs.Task{stuck, recent}, nil, now), "Expected recent pending tasks to be tolerated")
+	assert.NotEmpty(t, policy.FailureReason(nil, []*ecs.Task{stuck, recent}, []*ecs.Task{neverStarted}, now),
+		"Expected tasks that never ran to fail the deployment")
+}
+
+func TestRollbackPolicyCrashLoop(t *testing.T) {
+	now := time.Now()
+	policy := RollbackPolicy{CrashCount: 2, CrashWindow: time.Minute}
+	crashed := func(stoppedAgo time.Duration) *ecs.Task {
+		return &ecs.Task{
+			LastStatus: aws.String("STOPPED"),
+			StartedAt:  aws.Time(now.Add(-stoppedAgo - time.Second)),
+			StoppedAt:  aws.Time(now.Add(-stoppedAgo)),
+		}
+	}
+
+	assert.Empty(t, policy.FailureReason(nil, nil, []*ecs.Task{crashed(time.Second), crashed(time.Hour)}, now),
+		"Expected crashes outside of the window to be ignored")
+	assert.NotEmpty(t, policy.FailureReason(nil, nil, []*ecs.Task{crashed(time.Second), crashed(10 * time.Second)}, now),
+		"Expected crashes within the window to fail the deployment")
+}
+
+func TestRollbackPolicyIgnoresTasksStoppedByScheduler(t *testing.T) {
+	now := time.Now()
+	policy := RollbackPolicy{PendingTaskCount: 1, PendingTimeout: time.Minute, CrashCount: 1, CrashWindow: time.Minute}
+	drained := &ecs.Task{
+		LastStatus:    aws.String("STOPPED"),
+		StoppedReason: aws.String(StopReasonDraining),
+		StartedAt:     aws.Time(now.Add(-2 * time.Second)),
+		StoppedAt:     aws.Time(now.Add(-time.Second)),
+	}
+	evicted := &ecs.Task{
+		LastStatus:    aws.String("STOPPED"),
+		StoppedReason: aws.String(StopReasonEvicted),
+		StoppedAt:     aws.Time(now),
+	}
+
+	assert.Empty(t, policy.FailureReason(nil, nil, []*ecs.Task{drained, evicted}, now),
+		"Expected tasks stopped by the scheduler not to fail the deployment")
+}
@@ -0,0 +1,141 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RolloutStrategy controls how a deployment replaces the tasks of earlier deployments. The zero
+// value replaces them on every instance at once.
+type RolloutStrategy struct {
+	// BatchSize is the number of instances updated at a time
+	BatchSize int
+	// BatchPercent is the percentage of the instances updated at a time, used when BatchSize is not set
+	BatchPercent int
+	// MinHealthyPercent is the percentage of the instances that have to keep a running task during
+	// the rollout. At least one instance is updated at a time regardless.
+	MinHealthyPercent int
+	// SoakTime is how long every task of a batch has to keep running before the next batch starts
+	SoakTime time.Duration
+}
+
+type DeploymentBatchStatus uint8
+
+const (
+	// BatchInProgress batches wait for their tasks to be running
+	BatchInProgress DeploymentBatchStatus = iota
+	// BatchSoaking batches wait for their tasks to keep running for the soak time
+	BatchSoaking
+	BatchCompleted
+)
+
+// DeploymentBatch is a set of instances updated together during a rollout
+type DeploymentBatch struct {
+	Instances []string
+	Status    DeploymentBatchStatus
+	StartTime time.Time
+	// HealthySince is when every task of the batch was first found running since any of them
+	// last was not. It is zero while the batch is in progress.
+	HealthySince time.Time
+	EndTime      time.Time
+}
+
+// Validate returns an error if any of the settings is out of range
+func (s RolloutStrategy) Validate() error {
+	if s.BatchSize < 0 {
+		return errors.Errorf("Batch size %d should not be negative", s.BatchSize)
+	}
+	if s.BatchPercent < 0 || s.BatchPercent > 100 {
+		return errors.Errorf("Batch percent %d should be between 0 and 100", s.BatchPercent)
+	}
+	if s.BatchSize > 0 && s.BatchPercent > 0 {
+		return errors.New("Only one of batch size and batch percent should be set")
+	}
+	if s.MinHealthyPercent < 0 || s.MinHealthyPercent > 100 {
+		return errors.Errorf("Min healthy percent %d should be between 0 and 100", s.MinHealthyPercent)
+	}
+	if s.SoakTime < 0 {
+		return errors.Errorf("Soak time %s should not be negative", s.SoakTime)
+	}
+	return nil
+}
+
+// IsRolling returns whether instances are updated in batches rather than all at once
+func (s RolloutStrategy) IsRolling() bool {
+	return s != RolloutStrategy{}
+}
+
+// NextBatchSize returns the number of instances to update in the next batch out of total
+// instances, unavailable of which are not running any task of the environment
+func (s RolloutStrategy) NextBatchSize(total int, unavailable int) int {
+	size := total
+	if s.BatchSize > 0 {
+		size = s.BatchSize
+	} else if s.BatchPercent > 0 {
+		size = percentOf(total, s.BatchPercent)
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	maxUnavailable := total - percentOf(total, s.MinHealthyPercent)
+	if maxUnavailable < 1 {
+		maxUnavailable = 1
+	}
+	if size > maxUnavailable-unavailable {
+		size = maxUnavailable - unavailable
+	}
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+// percentOf returns percent of total, rounded up
+func percentOf(total int, percent int) int {
+	return (total*percent + 99) / 100
+}
+
+// Update moves the batch forward given whether all of its tasks are running at now, and
+// returns whether the batch changed
+func (b *DeploymentBatch) Update(healthy bool, soakTime time.Duration, now time.Time) bool {
+	if b.Status == BatchCompleted {
+		return false
+	}
+
+	if !healthy {
+		if b.Status != BatchSoaking {
+			return false
+		}
+		b.Status = BatchInProgress
+		b.HealthySince = time.Time{}
+		return true
+	}
+
+	changed := false
+	if b.Status == BatchInProgress {
+		b.Status = BatchSoaking
+		b.HealthySince = now
+		changed = true
+	}
+	if now.Sub(b.HealthySince) >= soakTime {
+		b.Status = BatchCompleted
+		b.EndTime = now
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,80 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolloutStrategyValidate(t *testing.T) {
+	assert.Nil(t, RolloutStrategy{}.Validate(), "Unexpected error validating the default strategy")
+	assert.Nil(t, RolloutStrategy{BatchPercent: 25, MinHealthyPercent: 75, SoakTime: time.Minute}.Validate(),
+		"Unexpected error validating a valid strategy")
+
+	invalid := []RolloutStrategy{
+		{BatchSize: -1},
+		{BatchPercent: 101},
+		{BatchSize: 1, BatchPercent: 10},
+		{MinHealthyPercent: -1},
+		{SoakTime: -time.Second},
+	}
+	for _, strategy := range invalid {
+		assert.Error(t, strategy.Validate(), "Expected an error validating %+v", strategy)
+	}
+}
+
+func TestRolloutStrategyNextBatchSize(t *testing.T) {
+	assert.Equal(t, 3, RolloutStrategy{BatchSize: 3}.NextBatchSize(10, 0), "Expected the batch size")
+	assert.Equal(t, 3, RolloutStrategy{BatchPercent: 25}.NextBatchSize(10, 0), "Expected the percentage rounded up")
+	assert.Equal(t, 1, RolloutStrategy{BatchPercent: 1}.NextBatchSize(10, 0), "Expected at least one instance")
+	assert.Equal(t, 10, RolloutStrategy{SoakTime: time.Minute}.NextBatchSize(10, 0), "Expected every instance")
+
+	strategy := RolloutStrategy{BatchSize: 5, MinHealthyPercent: 75}
+	assert.Equal(t, 2, strategy.NextBatchSize(10, 0), "Expected the batch to keep 75% of the instances healthy")
+	assert.Equal(t, 1, strategy.NextBatchSize(10, 1), "Expected unavailable instances to count against the batch")
+	assert.Equal(t, 0, strategy.NextBatchSize(10, 3), "Expected no batch while too few instances are healthy")
+	assert.Equal(t, 1, RolloutStrategy{MinHealthyPercent: 100}.NextBatchSize(10, 0),
+		"Expected one instance to be updated at a time when every instance has to stay healthy")
+}
+
+func TestDeploymentBatchUpdate(t *testing.T) {
+	start := time.Now()
+	batch := DeploymentBatch{Instances: []string{instanceARN}, StartTime: start}
+
+	assert.False(t, batch.Update(false, time.Minute, start), "Expected no change while tasks are not running")
+	assert.Exactly(t, BatchInProgress, batch.Status, "Expected the batch to be in progress")
+
+	assert.True(t, batch.Update(true, time.Minute, start.Add(time.Second)), "Expected a change once tasks are running")
+	assert.Exactly(t, BatchSoaking, batch.Status, "Expected the batch to be soaking")
+	assert.Equal(t, start.Add(time.Second), batch.HealthySince, "Expected the time the tasks were found running")
+
+	assert.True(t, batch.Update(false, time.Minute, start.Add(2*time.Second)), "Expected a change once a task stopped")
+	assert.Exactly(t, BatchInProgress, batch.Status, "Expected the batch to be in progress again")
+	assert.True(t, batch.HealthySince.IsZero(), "Expected the soak to start over")
+
+	assert.True(t, batch.Update(true, time.Minute, start.Add(3*time.Second)), "Expected a change once tasks are running")
+	assert.False(t, batch.Update(true, time.Minute, start.Add(time.Minute)), "Expected no change while soaking")
+	assert.True(t, batch.Update(true, time.Minute, start.Add(time.Minute+3*time.Second)), "Expected a change after soaking")
+	assert.Exactly(t, BatchCompleted, batch.Status, "Expected the batch to be completed")
+	assert.False(t, batch.Update(false, time.Minute, start.Add(2*time.Minute)), "Expected a completed batch not to change")
+}
+
+func TestDeploymentBatchUpdateWithoutSoakTime(t *testing.T) {
+	batch := DeploymentBatch{Instances: []string{instanceARN}, StartTime: time.Now()}
+	assert.True(t, batch.Update(true, 0, time.Now()), "Expected a change once tasks are running")
+	assert.Exactly(t, BatchCompleted, batch.Status, "Expected the batch to complete without soaking")
+}
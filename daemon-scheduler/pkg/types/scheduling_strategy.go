@@ -0,0 +1,92 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"github.com/pkg/errors"
+)
+
+// SchedulingStrategyType decides how many tasks of an environment run in each of its clusters
+type SchedulingStrategyType string
+
+const (
+	// DaemonStrategy runs a task of the environment on every instance it may run on
+	DaemonStrategy SchedulingStrategyType = "daemon"
+	// ReplicaStrategy runs a fixed number of tasks of the environment in each of its clusters,
+	// spread over the availability zones and packed onto the instances with the least memory left
+	ReplicaStrategy SchedulingStrategyType = "replica"
+)
+
+// SchedulingStrategy decides how many tasks of an environment run and on which instances. The
+// zero value is the daemon strategy.
+type SchedulingStrategy struct {
+	Type SchedulingStrategyType
+	// Replicas is the number of tasks of a replica environment in each of its clusters
+	Replicas int
+}
+
+// Validate returns an error if the type is unknown or the replicas do not go with it
+func (s SchedulingStrategy) Validate() error {
+	switch s.Type {
+	case "", DaemonStrategy:
+		if s.Replicas != 0 {
+			return errors.New("Replicas should only be set with the replica strategy")
+		}
+	case ReplicaStrategy:
+		if s.Replicas < 0 {
+			return errors.Errorf("Replicas %d should not be negative", s.Replicas)
+		}
+	default:
+		return errors.Errorf("Unknown scheduling strategy %s", s.Type)
+	}
+	return nil
+}
+
+// IsReplica returns whether the environment runs a fixed number of tasks rather than a task on
+// every instance
+func (s SchedulingStrategy) IsReplica() bool {
+	return s.Type == ReplicaStrategy
+}
+
+// TypeOrDefault returns the type of the strategy, or the daemon strategy if it is empty
+func (s SchedulingStrategy) TypeOrDefault() SchedulingStrategyType {
+	if s.Type == "" {
+		return DaemonStrategy
+	}
+	return s.Type
+}
+
+// UpdateMissingReplicas records that missing replicas of the environment could not be placed in
+// cluster, and returns whether that changed. The replicas are replaced with a new map so that
+// copies of the environment keep the ones they had.
+func (e *Environment) UpdateMissingReplicas(cluster string, missing int) bool {
+	if e.MissingReplicas[cluster] == missing {
+		return false
+	}
+
+	updated := make(map[string]int, len(e.MissingReplicas)+1)
+	for c, m := range e.MissingReplicas {
+		if c != cluster {
+			updated[c] = m
+		}
+	}
+	if missing > 0 {
+		updated[cluster] = missing
+	}
+	if len(updated) == 0 {
+		updated = nil
+	}
+	e.MissingReplicas = updated
+	return true
+}
@@ -0,0 +1,68 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulingStrategyValidate(t *testing.T) {
+	valid := []SchedulingStrategy{
+		{},
+		{Type: DaemonStrategy},
+		{Type: ReplicaStrategy},
+		{Type: ReplicaStrategy, Replicas: 3},
+	}
+	for _, strategy := range valid {
+		assert.Nil(t, strategy.Validate(), "Unexpected error validating %+v", strategy)
+	}
+
+	invalid := []SchedulingStrategy{
+		{Replicas: 3},
+		{Type: DaemonStrategy, Replicas: 1},
+		{Type: ReplicaStrategy, Replicas: -1},
+		{Type: "singleton"},
+	}
+	for _, strategy := range invalid {
+		assert.Error(t, strategy.Validate(), "Expected an error validating %+v", strategy)
+	}
+}
+
+func TestSchedulingStrategyTypeOrDefault(t *testing.T) {
+	assert.Exactly(t, DaemonStrategy, SchedulingStrategy{}.TypeOrDefault(), "Expected the daemon strategy by default")
+	assert.Exactly(t, ReplicaStrategy, SchedulingStrategy{Type: ReplicaStrategy}.TypeOrDefault(),
+		"Expected the type to be kept")
+	assert.False(t, SchedulingStrategy{}.IsReplica(), "Expected the default strategy not to be a replica strategy")
+}
+
+func TestUpdateMissingReplicas(t *testing.T) {
+	environment := Environment{}
+	assert.False(t, environment.UpdateMissingReplicas("cluster1", 0), "Expected no change without missing replicas")
+
+	assert.True(t, environment.UpdateMissingReplicas("cluster1", 2), "Expected the missing replicas to change")
+	assert.True(t, environment.UpdateMissingReplicas("cluster2", 1), "Expected the missing replicas to change")
+	copied := environment
+	assert.False(t, environment.UpdateMissingReplicas("cluster1", 2), "Expected no change for the same replicas")
+	assert.Equal(t, map[string]int{"cluster1": 2, "cluster2": 1}, environment.MissingReplicas, "")
+
+	assert.True(t, environment.UpdateMissingReplicas("cluster1", 0), "Expected the missing replicas to change")
+	assert.Equal(t, map[string]int{"cluster2": 1}, environment.MissingReplicas, "")
+	assert.Equal(t, map[string]int{"cluster1": 2, "cluster2": 1}, copied.MissingReplicas,
+		"Expected copies of the environment to keep their missing replicas")
+
+	assert.True(t, environment.UpdateMissingReplicas("cluster2", 0), "Expected the missing replicas to change")
+	assert.Nil(t, environment.MissingReplicas, "Expected no missing replicas left")
+}
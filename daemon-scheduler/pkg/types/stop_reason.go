@@ -0,0 +1,40 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import "strings"
+
+// Reasons the scheduler gives ECS for stopping tasks, which ECS reports as the stopped reason of
+// the task. They all start with StopReasonPrefix so that tasks stopped by the scheduler are not
+// mistaken for tasks that failed.
+const (
+	StopReasonPrefix = "Stopped by the daemon scheduler: "
+
+	// StopReasonReplaced is given for tasks replaced by a task of the current deployment
+	StopReasonReplaced = StopReasonPrefix + "replaced by a newer deployment"
+	// StopReasonExcess is given for tasks on instances the environment is no longer placed on
+	StopReasonExcess = StopReasonPrefix + "instance no longer selected for the environment"
+	// StopReasonDraining is given for tasks on draining instances
+	StopReasonDraining = StopReasonPrefix + "instance is draining"
+	// StopReasonEnvironmentDeleted is given for tasks of an environment being deleted
+	StopReasonEnvironmentDeleted = StopReasonPrefix + "environment deleted"
+	// StopReasonEvicted is given for tasks stopped to make room for an environment of higher priority
+	StopReasonEvicted = StopReasonPrefix + "evicted for an environment of higher priority"
+)
+
+// IsStoppedByScheduler returns whether a task that stopped for stoppedReason was stopped by the
+// scheduler
+func IsStoppedByScheduler(stoppedReason string) bool {
+	return strings.HasPrefix(stoppedReason, StopReasonPrefix)
+}
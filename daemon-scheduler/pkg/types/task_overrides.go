@@ -0,0 +1,223 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// TaskOverrides are the settings tasks of an environment are started with on top of its task
+// definition, so that one task definition can be run with different settings per environment
+type TaskOverrides struct {
+	ContainerOverrides []ContainerOverride
+	// TaskRoleARN is the IAM role the containers of the task assume
+	TaskRoleARN string
+	// ExecutionRoleARN is the IAM role the container agent pulls images and sends logs with
+	ExecutionRoleARN string
+	// CPU and Memory replace the CPU units and the MiB of memory reserved for the task, e.g.
+	// "512" or "0.5 vCPU" and "1024" or "1 GB"
+	CPU    string
+	Memory string
+	// Tags are added to the tasks
+	Tags map[string]string
+}
+
+// maxTaskTags is the number of tags ECS allows on a task
+const maxTaskTags = 50
+
+// ContainerOverride overrides the settings of a container of the task definition
+type ContainerOverride struct {
+	// Name is the name of the container in the task definition
+	Name string
+	// Command replaces the command of the container if set
+	Command []string
+	// Environment variables are added to the ones of the container, replacing those with the same name
+	Environment map[string]string
+}
+
+// Validate returns an error if a container override has no name, names the same container as
+// another override or sets an environment variable without a name, if the CPU or memory is not a
+// positive amount, or if a tag has no key or a key reserved by AWS
+func (o TaskOverrides) Validate() error {
+	if _, err := parseResource(o.CPU, "vCPU"); err != nil {
+		return errors.Wrapf(err, "CPU override %s is invalid", o.CPU)
+	}
+	if _, err := parseResource(o.Memory, "GB"); err != nil {
+		return errors.Wrapf(err, "Memory override %s is invalid", o.Memory)
+	}
+
+	if len(o.Tags) > maxTaskTags {
+		return errors.Errorf("Tasks should have at most %d tags", maxTaskTags)
+	}
+	for key := range o.Tags {
+		if key == "" {
+			return errors.New("Tag keys should not be empty")
+		}
+		if strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return errors.Errorf("Tag key %s should not start with aws:", key)
+		}
+	}
+
+	names := make(map[string]bool, len(o.ContainerOverrides))
+	for _, container := range o.ContainerOverrides {
+		if container.Name == "" {
+			return errors.New("Container override name should not be empty")
+		}
+		if names[container.Name] {
+			return errors.Errorf("Container %s should only be overridden once", container.Name)
+		}
+		names[container.Name] = true
+
+		for name := range container.Environment {
+			if name == "" {
+				return errors.Errorf("Environment variable names of container %s should not be empty", container.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateFor returns an error if a container override names a container that is not in the
+// task definition
+func (o TaskOverrides) ValidateFor(taskDefinition *ecs.TaskDefinition) error {
+	containers := make(map[string]bool, len(taskDefinition.ContainerDefinitions))
+	for _, container := range taskDefinition.ContainerDefinitions {
+		containers[aws.StringValue(container.Name)] = true
+	}
+	for _, container := range o.ContainerOverrides {
+		if !containers[container.Name] {
+			return errors.Errorf("Task definition %s has no container %s",
+				aws.StringValue(taskDefinition.TaskDefinitionArn), container.Name)
+		}
+	}
+	return nil
+}
+
+// IsEmpty returns whether tasks are started with the settings of the task definition
+func (o TaskOverrides) IsEmpty() bool {
+	return !o.overridesTask() && len(o.Tags) == 0
+}
+
+// overridesTask returns whether there are overrides other than tags, which ECS takes separately
+func (o TaskOverrides) overridesTask() bool {
+	return len(o.ContainerOverrides) > 0 || o.TaskRoleARN != "" || o.ExecutionRoleARN != "" ||
+		o.CPU != "" || o.Memory != ""
+}
+
+// ECSOverride returns the overrides to start tasks with, or nil if there are none. Environment
+// variables are ordered by name.
+func (o TaskOverrides) ECSOverride() *ecs.TaskOverride {
+	if !o.overridesTask() {
+		return nil
+	}
+
+	override := &ecs.TaskOverride{}
+	if o.TaskRoleARN != "" {
+		override.TaskRoleArn = aws.String(o.TaskRoleARN)
+	}
+	if o.ExecutionRoleARN != "" {
+		override.ExecutionRoleArn = aws.String(o.ExecutionRoleARN)
+	}
+	if o.CPU != "" {
+		override.Cpu = aws.String(o.CPU)
+	}
+	if o.Memory != "" {
+		override.Memory = aws.String(o.Memory)
+	}
+	for _, container := range o.ContainerOverrides {
+		containerOverride := &ecs.ContainerOverride{
+			Name: aws.String(container.Name),
+		}
+		if len(container.Command) > 0 {
+			containerOverride.Command = aws.StringSlice(container.Command)
+		}
+
+		names := make([]string, 0, len(container.Environment))
+		for name := range container.Environment {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			containerOverride.Environment = append(containerOverride.Environment, &ecs.KeyValuePair{
+				Name:  aws.String(name),
+				Value: aws.String(container.Environment[name]),
+			})
+		}
+		override.ContainerOverrides = append(override.ContainerOverrides, containerOverride)
+	}
+	return override
+}
+
+// ECSTags returns the tags to start tasks with ordered by key, or nil if there are none
+func (o TaskOverrides) ECSTags() []*ecs.Tag {
+	if len(o.Tags) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(o.Tags))
+	for key := range o.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tags := make([]*ecs.Tag, 0, len(keys))
+	for _, key := range keys {
+		tags = append(tags, &ecs.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(o.Tags[key]),
+		})
+	}
+	return tags
+}
+
+// ApplyTo returns the resources of the task definition with the CPU and memory reserved for the
+// task replaced by the overridden ones
+func (o TaskOverrides) ApplyTo(resources TaskResources) TaskResources {
+	if cpu, err := parseResource(o.CPU, "vCPU"); err == nil && cpu > 0 {
+		resources.CPU = cpu
+	}
+	if memory, err := parseResource(o.Memory, "GB"); err == nil && memory > 0 {
+		resources.Memory = memory
+	}
+	return resources
+}
+
+// parseResource returns the CPU units or MiB of memory, given either as a number of them or as a
+// number of vCPUs or GB, the unit, which are 1024 each. It returns 0 for an empty value.
+func parseResource(value string, unit string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	if strings.HasSuffix(strings.ToLower(value), strings.ToLower(unit)) {
+		amount, err := strconv.ParseFloat(strings.TrimSpace(value[:len(value)-len(unit)]), 64)
+		if err != nil || amount <= 0 {
+			return 0, errors.Errorf("Expected a positive number of %s", unit)
+		}
+		return int64(amount * 1024), nil
+	}
+
+	amount, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || amount <= 0 {
+		return 0, errors.Errorf("Expected a positive number, or a positive number of %s", unit)
+	}
+	return amount, nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskOverridesValidate(t *testing.T) {
+	assert.Nil(t, TaskOverrides{}.Validate(), "Unexpected error validating empty overrides")
+	assert.Nil(t, TaskOverrides{
+		ContainerOverrides: []ContainerOverride{{Name: "agent", Environment: map[string]string{"LEVEL": "debug"}}},
+	}.Validate(), "Unexpected error validating valid overrides")
+	assert.Error(t, TaskOverrides{ContainerOverrides: []ContainerOverride{{Command: []string{"run"}}}}.Validate(),
+		"Expected an error validating an override without a container name")
+	assert.Error(t, TaskOverrides{ContainerOverrides: []ContainerOverride{{Name: "agent"}, {Name: "agent"}}}.Validate(),
+		"Expected an error validating two overrides of the same container")
+	assert.Error(t, TaskOverrides{
+		ContainerOverrides: []ContainerOverride{{Name: "agent", Environment: map[string]string{"": "debug"}}},
+	}.Validate(), "Expected an error validating an environment variable without a name")
+	assert.Nil(t, TaskOverrides{CPU: "0.5 vCPU", Memory: "2048", Tags: map[string]string{"team": "logs"}}.Validate(),
+		"Unexpected error validating CPU, memory and tag overrides")
+	assert.Error(t, TaskOverrides{CPU: "half"}.Validate(), "Expected an error validating an invalid CPU")
+	assert.Error(t, TaskOverrides{Memory: "-1 GB"}.Validate(), "Expected an error validating a negative memory")
+	assert.Error(t, TaskOverrides{Tags: map[string]string{"aws:team": "logs"}}.Validate(),
+		"Expected an error validating a tag with the aws: prefix")
+	assert.Error(t, TaskOverrides{Tags: map[string]string{"": "logs"}}.Validate(),
+		"Expected an error validating a tag without a key")
+}
+
+func TestTaskOverridesValidateFor(t *testing.T) {
+	taskDefinition := &ecs.TaskDefinition{
+		TaskDefinitionArn:    aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/agent:1"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{{Name: aws.String("agent")}},
+	}
+	overrides := TaskOverrides{ContainerOverrides: []ContainerOverride{{Name: "agent"}}}
+	assert.Nil(t, overrides.ValidateFor(taskDefinition), "Unexpected error validating overrides of a container of the task definition")
+
+	overrides.ContainerOverrides = append(overrides.ContainerOverrides, ContainerOverride{Name: "sidecar"})
+	assert.Error(t, overrides.ValidateFor(taskDefinition), "Expected an error validating overrides of a missing container")
+}
+
+func TestTaskOverridesECSOverride(t *testing.T) {
+	assert.Nil(t, TaskOverrides{}.ECSOverride(), "Expected no override when tasks run the task definition as is")
+
+	overrides := TaskOverrides{
+		TaskRoleARN: "arn:aws:iam::123456789012:role/agent",
+		ContainerOverrides: []ContainerOverride{{
+			Name:        "agent",
+			Command:     []string{"agent", "--verbose"},
+			Environment: map[string]string{"REGION": "us-east-1", "LEVEL": "debug"},
+		}},
+	}
+	assert.Equal(t, &ecs.TaskOverride{
+		TaskRoleArn: aws.String("arn:aws:iam::123456789012:role/agent"),
+		ContainerOverrides: []*ecs.ContainerOverride{{
+			Name:    aws.String("agent"),
+			Command: aws.StringSlice([]string{"agent", "--verbose"}),
+			Environment: []*ecs.KeyValuePair{
+				{Name: aws.String("LEVEL"), Value: aws.String("debug")},
+				{Name: aws.String("REGION"), Value: aws.String("us-east-1")},
+			},
+		}},
+	}, overrides.ECSOverride(), "Expected the environment variables ordered by name")
+}
+
+func TestTaskOverridesECSOverrideOfTask(t *testing.T) {
+	overrides := TaskOverrides{
+		ExecutionRoleARN: "arn:aws:iam::123456789012:role/execution",
+		CPU:              "1 vCPU",
+		Memory:           "512",
+		Tags:             map[string]string{"team": "logs"},
+	}
+	assert.Equal(t, &ecs.TaskOverride{
+		ExecutionRoleArn: aws.String("arn:aws:iam::123456789012:role/execution"),
+		Cpu:              aws.String("1 vCPU"),
+		Memory:           aws.String("512"),
+	}, overrides.ECSOverride(), "Expected the execution role, CPU and memory in the override")
+
+	assert.Nil(t, TaskOverrides{Tags: map[string]string{"team": "logs"}}.ECSOverride(),
+		"Expected no override when only tags are set")
+}
+
+func TestTaskOverridesECSTags(t *testing.T) {
+	assert.Nil(t, TaskOverrides{}.ECSTags(), "Expected no tags")
+	assert.Equal(t, []*ecs.Tag{
+		{Key: aws.String("env"), Value: aws.String("prod")},
+		{Key: aws.String("team"), Value: aws.String("logs")},
+	}, TaskOverrides{Tags: map[string]string{"team": "logs", "env": "prod"}}.ECSTags(),
+		"Expected the tags ordered by key")
+}
+
+func TestTaskOverridesApplyTo(t *testing.T) {
+	resources := TaskResources{CPU: 256, Memory: 512, Ports: []string{"80/tcp"}}
+	assert.Equal(t, resources, TaskOverrides{}.ApplyTo(resources), "Expected the resources of the task definition")
+	assert.Equal(t, TaskResources{CPU: 512, Memory: 2048, Ports: []string{"80/tcp"}},
+		TaskOverrides{CPU: "0.5 vCPU", Memory: "2 GB"}.ApplyTo(resources), "Expected the overridden CPU and memory")
+	assert.Equal(t, TaskResources{CPU: 1024, Memory: 512, Ports: []string{"80/tcp"}},
+		TaskOverrides{CPU: "1024"}.ApplyTo(resources), "Expected only the CPU to be overridden")
+}
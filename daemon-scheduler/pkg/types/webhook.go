@@ -0,0 +1,242 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+)
+
+// WebhookEventType identifies a change of an environment webhooks can subscribe to
+type WebhookEventType string
+
+const (
+	// WebhookDeploymentStarted is sent when a deployment moves to in-progress
+	WebhookDeploymentStarted WebhookEventType = "deployment.started"
+	// WebhookDeploymentCompleted is sent when a deployment completes
+	WebhookDeploymentCompleted WebhookEventType = "deployment.completed"
+	// WebhookDeploymentUnhealthy is sent when a deployment becomes unhealthy
+	WebhookDeploymentUnhealthy WebhookEventType = "deployment.unhealthy"
+	// WebhookDeploymentFailed is sent when the rollback policy of the environment fails a deployment
+	WebhookDeploymentFailed WebhookEventType = "deployment.failed"
+	// WebhookDeploymentRolledBack is sent when a failed or cancelled deployment is rolled back
+	WebhookDeploymentRolledBack WebhookEventType = "deployment.rolledback"
+	// WebhookEnvironmentHealth is sent when the health of the environment changes
+	WebhookEnvironmentHealth WebhookEventType = "environment.health"
+)
+
+// WebhookEventTypes are the events webhooks can subscribe to
+var WebhookEventTypes = []WebhookEventType{
+	WebhookDeploymentStarted,
+	WebhookDeploymentCompleted,
+	WebhookDeploymentUnhealthy,
+	WebhookDeploymentFailed,
+	WebhookDeploymentRolledBack,
+	WebhookEnvironmentHealth,
+}
+
+// Webhook is a subscription to the changes of an environment. Events are posted to URL and
+// signed with Secret.
+type Webhook struct {
+	ID              string
+	EnvironmentName string
+	URL             string
+	Secret          string
+	// Events are the events posted to the webhook. A webhook without events receives all of them.
+	Events    []WebhookEventType
+	CreatedAt time.Time
+}
+
+// NewWebhook creates a webhook subscribing to the events of the environment with the provided name
+func NewWebhook(environmentName string, webhookURL string, secret string, events []WebhookEventType) (*Webhook, error) {
+	w := &Webhook{
+		ID:              uuid.NewV4().String(),
+		EnvironmentName: environmentName,
+		URL:             webhookURL,
+		Secret:          secret,
+		Events:          events,
+		CreatedAt:       time.Now(),
+	}
+
+	err := w.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Validate returns an error if the URL is not an absolute http or https URL, the secret is missing
+// or an event is unknown
+func (w Webhook) Validate() error {
+	if len(w.EnvironmentName) == 0 {
+		return errors.New("Environment name is missing")
+	}
+
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid webhook URL '%s'", w.URL)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return errors.Errorf("Webhook URL '%s' should be an absolute http or https URL", w.URL)
+	}
+
+	if len(w.Secret) == 0 {
+		return errors.New("Webhook secret is missing")
+	}
+
+	for _, event := range w.Events {
+		if !isWebhookEventType(event) {
+			return errors.Errorf("Unknown webhook event '%s'. Supported events are %v", event, WebhookEventTypes)
+		}
+	}
+	return nil
+}
+
+// Subscribes returns whether the event is posted to the webhook
+func (w Webhook) Subscribes(event WebhookEventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func isWebhookEventType(event WebhookEventType) bool {
+	for _, e := range WebhookEventTypes {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent is a change of an environment posted to its webhooks
+type WebhookEvent struct {
+	Type        WebhookEventType
+	Environment Environment
+	// Deployment is the deployment that changed, and nil for events of the environment
+	Deployment *Deployment
+}
+
+// Snapshot returns a copy of the environment that changes to its deployments do not affect, to be
+// compared with the environment once changed by WebhookEvents
+func (e Environment) Snapshot() Environment {
+	deployments := make(map[string]Deployment, len(e.Deployments))
+	for id, d := range e.Deployments {
+		deployments[id] = d
+	}
+	e.Deployments = deployments
+	return e
+}
+
+// WebhookEvents returns the events for the changes between two versions of an environment. The
+// events of deployments come first, ordered by deployment start time, earliest first.
+func WebhookEvents(before Environment, after Environment) []WebhookEvent {
+	deployments := make([]Deployment, 0, len(after.Deployments))
+	for _, d := range after.Deployments {
+		deployments = append(deployments, d)
+	}
+	sort.Sort(sort.Reverse(timeOrderedDeployments(deployments)))
+
+	events := make([]WebhookEvent, 0)
+	for i := range deployments {
+		d := &deployments[i]
+		previous, existed := before.Deployments[d.ID]
+		for _, eventType := range deploymentWebhookEvents(previous, existed, *d) {
+			events = append(events, WebhookEvent{Type: eventType, Environment: after, Deployment: d})
+		}
+	}
+
+	if before.Health != after.Health {
+		events = append(events, WebhookEvent{Type: WebhookEnvironmentHealth, Environment: after})
+	}
+	return events
+}
+
+// deploymentWebhookEvents returns the events for the changes of a deployment from previous, which
+// is only set if existed is
+func deploymentWebhookEvents(previous Deployment, existed bool, d Deployment) []WebhookEventType {
+	events := make([]WebhookEventType, 0)
+	if d.Status == DeploymentInProgress && (!existed || previous.Status == DeploymentPending) {
+		events = append(events, WebhookDeploymentStarted)
+	}
+	if d.Health == DeploymentUnhealthy && (!existed || previous.Health != DeploymentUnhealthy) {
+		events = append(events, WebhookDeploymentUnhealthy)
+	}
+	if d.Status == DeploymentCompleted && (!existed || previous.Status != DeploymentCompleted) {
+		events = append(events, WebhookDeploymentCompleted)
+	}
+	if d.Status == DeploymentFailed && (!existed || previous.Status != DeploymentFailed) {
+		events = append(events, WebhookDeploymentFailed)
+	}
+	if len(d.RolledBackBy) > 0 && (!existed || len(previous.RolledBackBy) == 0) {
+		events = append(events, WebhookDeploymentRolledBack)
+	}
+	return events
+}
+
+// WebhookDeliveryStatus is the outcome of posting an event to a webhook
+type WebhookDeliveryStatus uint8
+
+const (
+	// WebhookDeliveryPending deliveries are being attempted
+	WebhookDeliveryPending WebhookDeliveryStatus = iota
+	// WebhookDeliverySucceeded deliveries were acknowledged with a 2xx response
+	WebhookDeliverySucceeded
+	// WebhookDeliveryFailed deliveries were given up on
+	WebhookDeliveryFailed
+)
+
+// WebhookDelivery records the attempts to post an event to a webhook
+type WebhookDelivery struct {
+	ID              string
+	WebhookID       string
+	EnvironmentName string
+	Event           WebhookEventType
+	// DeploymentID is the deployment the event is about, if any
+	DeploymentID string
+	Status       WebhookDeliveryStatus
+	Attempts     int
+	// ResponseStatus is the HTTP status of the response to the last attempt, and zero if the
+	// attempt got no response
+	ResponseStatus int
+	// Error describes why the last attempt failed
+	Error         string
+	CreatedAt     time.Time
+	LastAttemptAt time.Time
+}
+
+// NewWebhookDelivery creates a pending delivery of the event to the webhook
+func NewWebhookDelivery(webhook Webhook, event WebhookEvent) *WebhookDelivery {
+	delivery := &WebhookDelivery{
+		ID:              uuid.NewV4().String(),
+		WebhookID:       webhook.ID,
+		EnvironmentName: webhook.EnvironmentName,
+		Event:           event.Type,
+		Status:          WebhookDeliveryPending,
+		CreatedAt:       time.Now(),
+	}
+	if event.Deployment != nil {
+		delivery.DeploymentID = event.Deployment.ID
+	}
+	return delivery
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	webhookURL    = "https://hooks.example.com/blox"
+	webhookSecret = "secret"
+)
+
+func TestNewWebhook(t *testing.T) {
+	w, err := NewWebhook(environmentName, webhookURL, webhookSecret, []WebhookEventType{WebhookDeploymentCompleted})
+	assert.Nil(t, err, "Unexpected error when creating a webhook")
+	assert.NotEmpty(t, w.ID, "Expected the webhook to have an ID")
+	assert.Exactly(t, environmentName, w.EnvironmentName, "Expected the webhook to belong to the environment")
+	assert.False(t, w.CreatedAt.IsZero(), "Expected the creation time to be set")
+}
+
+func TestWebhookValidate(t *testing.T) {
+	invalid := []Webhook{
+		{URL: webhookURL, Secret: webhookSecret},
+		{EnvironmentName: environmentName, URL: "hooks.example.com/blox", Secret: webhookSecret},
+		{EnvironmentName: environmentName, URL: "ftp://hooks.example.com/blox", Secret: webhookSecret},
+		{EnvironmentName: environmentName, URL: "https://", Secret: webhookSecret},
+		{EnvironmentName: environmentName, URL: webhookURL},
+		{EnvironmentName: environmentName, URL: webhookURL, Secret: webhookSecret, Events: []WebhookEventType{"deployment.deleted"}},
+	}
+	for _, w := range invalid {
+		assert.Error(t, w.Validate(), "Expected an error validating %+v", w)
+	}
+
+	w := Webhook{EnvironmentName: environmentName, URL: "http://localhost:8080/hook", Secret: webhookSecret,
+		Events: WebhookEventTypes}
+	assert.Nil(t, w.Validate(), "Unexpected error validating a webhook")
+}
+
+func TestWebhookSubscribes(t *testing.T) {
+	w := Webhook{}
+	assert.True(t, w.Subscribes(WebhookEnvironmentHealth), "Expected a webhook without events to receive all of them")
+
+	w.Events = []WebhookEventType{WebhookDeploymentStarted}
+	assert.True(t, w.Subscribes(WebhookDeploymentStarted), "Expected the webhook to receive the events it subscribes to")
+	assert.False(t, w.Subscribes(WebhookEnvironmentHealth), "Expected the webhook not to receive other events")
+}
+
+func TestWebhookEventsDeploymentStarted(t *testing.T) {
+	before, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error when creating an environment")
+	d, err := before.StartDeployment()
+	assert.Nil(t, err, "Unexpected error when starting a deployment")
+
+	after := before.Snapshot()
+	inProgress := *d
+	inProgress.Status = DeploymentInProgress
+	after.Deployments[d.ID] = inProgress
+
+	events := WebhookEvents(*before, after)
+	assert.Len(t, events, 1, "Expected one event")
+	assert.Exactly(t, WebhookDeploymentStarted, events[0].Type, "Expected the deployment to have started")
+	assert.Exactly(t, d.ID, events[0].Deployment.ID, "Expected the event to be about the deployment")
+
+	assert.Empty(t, WebhookEvents(after, after), "Expected no events without changes")
+}
+
+func TestWebhookEventsRollback(t *testing.T) {
+	before, err := NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(t, err, "Unexpected error when creating an environment")
+	failed := Deployment{ID: "failed", Status: DeploymentInProgress, Health: DeploymentHealthy, StartTime: time.Now()}
+	before.Deployments[failed.ID] = failed
+
+	after := before.Snapshot()
+	failed.Status = DeploymentFailed
+	failed.Health = DeploymentUnhealthy
+	failed.RolledBackBy = "rollback"
+	after.Deployments[failed.ID] = failed
+	after.Deployments["rollback"] = Deployment{ID: "rollback", Status: DeploymentInProgress,
+		Health: DeploymentHealthy, StartTime: failed.StartTime.Add(time.Second)}
+	after.Health = EnvironmentUnhealthy
+
+	var eventTypes []WebhookEventType
+	for _, event := range WebhookEvents(*before, after) {
+		eventTypes = append(eventTypes, event.Type)
+	}
+	assert.Exactly(t, []WebhookEventType{WebhookDeploymentUnhealthy, WebhookDeploymentFailed,
+		WebhookDeploymentRolledBack, WebhookDeploymentStarted, WebhookEnvironmentHealth}, eventTypes,
+		"Expected the events of the failed deployment, then of its rollback and of the environment")
+}
@@ -0,0 +1,250 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package webhook posts the changes of environments to the webhooks subscribed to them
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/store"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SignatureHeader carries sha256=<signature>, the HMAC-SHA256 of the body keyed with the
+	// secret of the webhook
+	SignatureHeader = "X-Blox-Signature"
+	// EventHeader carries the type of the event
+	EventHeader = "X-Blox-Event"
+	// DeliveryHeader carries the ID of the delivery, which is the same across attempts
+	DeliveryHeader = "X-Blox-Delivery"
+
+	// MaxAttempts is the number of times a delivery is attempted before giving up
+	MaxAttempts = 5
+
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+	requestTimeout = 10 * time.Second
+)
+
+// Notifier posts the events of environment changes to the webhooks subscribed to them. Each
+// delivery is attempted in the background up to MaxAttempts times with exponential backoff,
+// and every attempt is recorded in the delivery log of the webhook.
+type Notifier struct {
+	webhookStore store.WebhookStore
+	client       *http.Client
+	backoff      time.Duration
+
+	// ctx is cancelled to abandon deliveries that are still being retried when stopping
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	stopped    bool
+	deliveries sync.WaitGroup
+}
+
+// NewNotifier creates a notifier that posts events with client
+func NewNotifier(webhookStore store.WebhookStore, client *http.Client) (*Notifier, error) {
+	if webhookStore == nil {
+		return nil, errors.New("Webhook store is not initialized")
+	}
+	if client == nil {
+		return nil, errors.New("HTTP client is not initialized")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Notifier{
+		webhookStore: webhookStore,
+		client:       client,
+		backoff:      initialBackoff,
+		ctx:          ctx,
+		cancel:       cancel,
+	}, nil
+}
+
+// EnvironmentUpdated starts delivering the events of the changes between the two versions of
+// the environment to its webhooks
+func (n *Notifier) EnvironmentUpdated(ctx context.Context, before types.Environment, after types.Environment) {
+	events := types.WebhookEvents(before, after)
+	if len(events) == 0 {
+		return
+	}
+
+	hooks, err := n.webhookStore.ListWebhooks(ctx, after.Name)
+	if err != nil {
+		log.Errorf("Could not load the webhooks of environment %s, dropping %d events: %+v", after.Name, len(events), err)
+		return
+	}
+
+	for _, event := range events {
+		for _, hook := range hooks {
+			if !hook.Subscribes(event.Type) {
+				continue
+			}
+
+			delivery := types.NewWebhookDelivery(hook, event)
+			body, err := json.Marshal(newPayload(*delivery, event))
+			if err != nil {
+				log.Errorf("Could not encode event %s of environment %s: %+v", event.Type, after.Name, err)
+				continue
+			}
+			n.start(hook, *delivery, body)
+		}
+	}
+}
+
+// EnvironmentDeleted deletes the webhooks of the environment
+func (n *Notifier) EnvironmentDeleted(ctx context.Context, name string) {
+	err := n.webhookStore.DeleteWebhooks(ctx, name)
+	if err != nil {
+		log.Errorf("Could not delete the webhooks of environment %s: %+v", name, err)
+	}
+}
+
+// Stop waits for the deliveries in progress to finish, and abandons them once ctx is done.
+// Events are no longer delivered once Stop is called.
+func (n *Notifier) Stop(ctx context.Context) error {
+	n.mu.Lock()
+	n.stopped = true
+	n.mu.Unlock()
+	defer n.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		n.deliveries.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("Timed out waiting for webhook deliveries to finish")
+	}
+}
+
+func (n *Notifier) start(hook types.Webhook, delivery types.WebhookDelivery, body []byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped {
+		log.Warnf("Not delivering event %s to webhook %s while stopping", delivery.Event, hook.ID)
+		return
+	}
+
+	n.deliveries.Add(1)
+	go func() {
+		defer n.deliveries.Done()
+		n.deliver(hook, delivery, body)
+	}()
+}
+
+// deliver posts body to the webhook until it succeeds, fails with a response that is not worth
+// retrying or runs out of attempts
+func (n *Notifier) deliver(hook types.Webhook, delivery types.WebhookDelivery, body []byte) {
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		status, retry, err := n.post(hook, delivery, body)
+
+		delivery.Attempts = attempt
+		delivery.LastAttemptAt = time.Now()
+		delivery.ResponseStatus = status
+		delivery.Error = ""
+		switch {
+		case err == nil:
+			delivery.Status = types.WebhookDeliverySucceeded
+		case retry && attempt < MaxAttempts:
+			delivery.Status = types.WebhookDeliveryPending
+			delivery.Error = err.Error()
+		default:
+			delivery.Status = types.WebhookDeliveryFailed
+			delivery.Error = err.Error()
+		}
+
+		n.record(delivery)
+		if delivery.Status != types.WebhookDeliveryPending {
+			if delivery.Status == types.WebhookDeliveryFailed {
+				log.Warnf("Giving up delivering event %s of environment %s to webhook %s after %d attempts: %s",
+					delivery.Event, delivery.EnvironmentName, hook.ID, attempt, delivery.Error)
+			}
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-n.ctx.Done():
+			delivery.Status = types.WebhookDeliveryFailed
+			delivery.Error = "Abandoned while stopping: " + delivery.Error
+			n.record(delivery)
+			return
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// post posts body to the webhook once and returns the status of the response, if any, and
+// whether a failed attempt is worth retrying
+func (n *Notifier) post(hook types.Webhook, delivery types.WebhookDelivery, body []byte) (int, bool, error) {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "Could not create the request")
+	}
+
+	ctx, cancel := context.WithTimeout(n.ctx, requestTimeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(delivery.Event))
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(hook.Secret, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+	// drain the body so that the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp.StatusCode, false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return resp.StatusCode, true, errors.Errorf("Unexpected response status %d", resp.StatusCode)
+	default:
+		return resp.StatusCode, false, errors.Errorf("Unexpected response status %d", resp.StatusCode)
+	}
+}
+
+func (n *Notifier) record(delivery types.WebhookDelivery) {
+	// deliveries are recorded even while stopping
+	err := n.webhookStore.PutDelivery(context.Background(), delivery)
+	if err != nil {
+		log.Errorf("Could not record delivery %s to webhook %s: %+v", delivery.ID, delivery.WebhookID, err)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blox/blox/daemon-scheduler/pkg/mocks"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	environmentName = "environmentName"
+	taskDefinition  = "arn:aws:ecs:us-east-1:12345678912:task-definition/test"
+	cluster         = "arn:aws:ecs:us-east-1:123456789123:cluster/test"
+	webhookSecret   = "secret"
+)
+
+type NotifierTestSuite struct {
+	suite.Suite
+	webhookStore *mocks.MockWebhookStore
+	notifier     *Notifier
+	ctx          context.Context
+	before       types.Environment
+	after        types.Environment
+	deployment   types.Deployment
+
+	// requests records the requests received by the webhook server
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (suite *NotifierTestSuite) SetupTest() {
+	mockCtrl := gomock.NewController(suite.T())
+	suite.webhookStore = mocks.NewMockWebhookStore(mockCtrl)
+	suite.ctx = context.TODO()
+	suite.requests = nil
+	suite.bodies = nil
+
+	var err error
+	suite.notifier, err = NewNotifier(suite.webhookStore, http.DefaultClient)
+	assert.Nil(suite.T(), err, "Cannot initialize NotifierTestSuite")
+	suite.notifier.backoff = time.Millisecond
+
+	environment, err := types.NewEnvironment(environmentName, taskDefinition, cluster)
+	assert.Nil(suite.T(), err, "Cannot initialize NotifierTestSuite")
+	d, err := environment.StartDeployment()
+	assert.Nil(suite.T(), err, "Cannot initialize NotifierTestSuite")
+
+	suite.before = environment.Snapshot()
+	d.Status = types.DeploymentCompleted
+	environment.Deployments[d.ID] = *d
+	suite.after = *environment
+	suite.deployment = *d
+}
+
+func TestNotifierTestSuite(t *testing.T) {
+	suite.Run(t, new(NotifierTestSuite))
+}
+
+func (suite *NotifierTestSuite) TestNewNotifierEmptyStore() {
+	_, err := NewNotifier(nil, http.DefaultClient)
+	assert.Error(suite.T(), err, "Expected an error when the webhook store is nil")
+}
+
+func (suite *NotifierTestSuite) TestEnvironmentUpdatedWithoutEvents() {
+	suite.notifier.EnvironmentUpdated(suite.ctx, suite.after, suite.after)
+	assert.Nil(suite.T(), suite.notifier.Stop(suite.ctx), "Unexpected error when stopping")
+}
+
+func (suite *NotifierTestSuite) TestEnvironmentUpdatedListWebhooksFails() {
+	suite.webhookStore.EXPECT().ListWebhooks(suite.ctx, environmentName).Return(nil, errors.New("List failed"))
+
+	suite.notifier.EnvironmentUpdated(suite.ctx, suite.before, suite.after)
+	assert.Nil(suite.T(), suite.notifier.Stop(suite.ctx), "Unexpected error when stopping")
+}
+
+func (suite *NotifierTestSuite) TestEnvironmentUpdatedDelivers() {
+	server := suite.server(http.StatusOK)
+	defer server.Close()
+
+	hook := suite.webhook(server.URL, nil)
+	other := suite.webhook(server.URL, []types.WebhookEventType{types.WebhookDeploymentStarted})
+	suite.webhookStore.EXPECT().ListWebhooks(suite.ctx, environmentName).Return([]types.Webhook{hook, other}, nil)
+	suite.webhookStore.EXPECT().PutDelivery(gomock.Any(), gomock.Any()).Do(func(_ interface{}, delivery types.WebhookDelivery) {
+		assert.Exactly(suite.T(), hook.ID, delivery.WebhookID, "Expected only the subscribed webhook to be delivered to")
+		assert.Exactly(suite.T(), types.WebhookDeliverySucceeded, delivery.Status, "Expected the delivery to succeed")
+		assert.Exactly(suite.T(), 1, delivery.Attempts, "Expected a single attempt")
+		assert.Exactly(suite.T(), http.StatusOK, delivery.ResponseStatus, "Expected the response status to be recorded")
+		assert.Exactly(suite.T(), suite.deployment.ID, delivery.DeploymentID, "Expected the deployment to be recorded")
+	}).Return(nil)
+
+	suite.notifier.EnvironmentUpdated(suite.ctx, suite.before, suite.after)
+	assert.Nil(suite.T(), suite.notifier.Stop(suite.ctx), "Unexpected error when stopping")
+
+	assert.Len(suite.T(), suite.requests, 1, "Expected one request")
+	request, body := suite.requests[0], suite.bodies[0]
+	assert.Exactly(suite.T(), string(types.WebhookDeploymentCompleted), request.Header.Get(EventHeader), "Expected the event header")
+	assert.Exactly(suite.T(), "sha256="+Sign(webhookSecret, body), request.Header.Get(SignatureHeader),
+		"Expected the body to be signed with the secret of the webhook")
+
+	payload := Payload{}
+	err := json.Unmarshal(body, &payload)
+	assert.Nil(suite.T(), err, "Unexpected error decoding the payload")
+	assert.Exactly(suite.T(), request.Header.Get(DeliveryHeader), payload.DeliveryID, "Expected the delivery ID in the payload")
+	assert.Exactly(suite.T(), environmentName, payload.Environment.Name, "Expected the environment in the payload")
+	assert.Exactly(suite.T(), suite.deployment.ID, payload.Deployment.ID, "Expected the deployment in the payload")
+	assert.Exactly(suite.T(), "completed", payload.Deployment.Status, "Expected the deployment status in the payload")
+}
+
+func (suite *NotifierTestSuite) TestEnvironmentUpdatedRetriesServerErrors() {
+	server := suite.server(http.StatusServiceUnavailable)
+	defer server.Close()
+
+	hook := suite.webhook(server.URL, nil)
+	suite.webhookStore.EXPECT().ListWebhooks(suite.ctx, environmentName).Return([]types.Webhook{hook}, nil)
+	attempts := 0
+	suite.webhookStore.EXPECT().PutDelivery(gomock.Any(), gomock.Any()).Do(func(_ interface{}, delivery types.WebhookDelivery) {
+		attempts++
+		assert.Exactly(suite.T(), attempts, delivery.Attempts, "Expected every attempt to be recorded")
+		assert.Exactly(suite.T(), http.StatusServiceUnavailable, delivery.ResponseStatus, "Expected the response status to be recorded")
+		if attempts < MaxAttempts {
+			assert.Exactly(suite.T(), types.WebhookDeliveryPending, delivery.Status, "Expected the delivery to be retried")
+		} else {
+			assert.Exactly(suite.T(), types.WebhookDeliveryFailed, delivery.Status, "Expected the delivery to fail")
+			assert.NotEmpty(suite.T(), delivery.Error, "Expected the failure to be recorded")
+		}
+	}).Return(nil).Times(MaxAttempts)
+
+	suite.notifier.EnvironmentUpdated(suite.ctx, suite.before, suite.after)
+	assert.Nil(suite.T(), suite.notifier.Stop(suite.ctx), "Unexpected error when stopping")
+	assert.Len(suite.T(), suite.requests, MaxAttempts, "Expected the delivery to be attempted MaxAttempts times")
+}
+
+func (suite *NotifierTestSuite) TestEnvironmentUpdatedDoesNotRetryClientErrors() {
+	server := suite.server(http.StatusBadRequest)
+	defer server.Close()
+
+	hook := suite.webhook(server.URL, nil)
+	suite.webhookStore.EXPECT().ListWebhooks(suite.ctx, environmentName).Return([]types.Webhook{hook}, nil)
+	suite.webhookStore.EXPECT().PutDelivery(gomock.Any(), gomock.Any()).Do(func(_ interface{}, delivery types.WebhookDelivery) {
+		assert.Exactly(suite.T(), types.WebhookDeliveryFailed, delivery.Status, "Expected the delivery to fail")
+	}).Return(nil)
+
+	suite.notifier.EnvironmentUpdated(suite.ctx, suite.before, suite.after)
+	assert.Nil(suite.T(), suite.notifier.Stop(suite.ctx), "Unexpected error when stopping")
+	assert.Len(suite.T(), suite.requests, 1, "Expected client errors not to be retried")
+}
+
+func (suite *NotifierTestSuite) TestEnvironmentUpdatedAfterStop() {
+	assert.Nil(suite.T(), suite.notifier.Stop(suite.ctx), "Unexpected error when stopping")
+
+	hook := suite.webhook("http://localhost:1/hook", nil)
+	suite.webhookStore.EXPECT().ListWebhooks(suite.ctx, environmentName).Return([]types.Webhook{hook}, nil)
+
+	// no delivery is expected to be recorded
+	suite.notifier.EnvironmentUpdated(suite.ctx, suite.before, suite.after)
+}
+
+func (suite *NotifierTestSuite) TestEnvironmentDeleted() {
+	suite.webhookStore.EXPECT().DeleteWebhooks(suite.ctx, environmentName).Return(nil)
+	suite.notifier.EnvironmentDeleted(suite.ctx, environmentName)
+}
+
+func (suite *NotifierTestSuite) webhook(url string, events []types.WebhookEventType) types.Webhook {
+	hook, err := types.NewWebhook(environmentName, url, webhookSecret, events)
+	assert.Nil(suite.T(), err, "Unexpected error when creating a webhook")
+	return *hook
+}
+
+// server returns a webhook server that records requests and responds with status
+func (suite *NotifierTestSuite) server(status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(suite.T(), err, "Unexpected error reading the request body")
+
+		suite.mu.Lock()
+		suite.requests = append(suite.requests, r)
+		suite.bodies = append(suite.bodies, body)
+		suite.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+}
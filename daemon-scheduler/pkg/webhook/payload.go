@@ -0,0 +1,96 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/blox/blox/daemon-scheduler/pkg/types"
+)
+
+// Payload is the body posted to webhooks
+type Payload struct {
+	// DeliveryID identifies the delivery, and is the same across the attempts to post it
+	DeliveryID  string                 `json:"deliveryId"`
+	Event       types.WebhookEventType `json:"event"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Environment EnvironmentPayload     `json:"environment"`
+	// Deployment is the deployment the event is about, and is omitted for environment events
+	Deployment *DeploymentPayload `json:"deployment,omitempty"`
+}
+
+// EnvironmentPayload describes the environment an event is about
+type EnvironmentPayload struct {
+	Name           string `json:"name"`
+	Health         string `json:"health"`
+	TaskDefinition string `json:"taskDefinition"`
+}
+
+// DeploymentPayload describes the deployment an event is about
+type DeploymentPayload struct {
+	ID               string   `json:"id"`
+	Status           string   `json:"status"`
+	Health           string   `json:"health"`
+	TaskDefinition   string   `json:"taskDefinition"`
+	DesiredTaskCount int      `json:"desiredTaskCount"`
+	FailedInstances  []string `json:"failedInstances,omitempty"`
+	RollbackReason   string   `json:"rollbackReason,omitempty"`
+	RolledBackBy     string   `json:"rolledBackBy,omitempty"`
+	RollbackOf       string   `json:"rollbackOf,omitempty"`
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body keyed with secret, which is sent in the
+// signature header as sha256=<signature>
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newPayload(delivery types.WebhookDelivery, event types.WebhookEvent) Payload {
+	payload := Payload{
+		DeliveryID: delivery.ID,
+		Event:      event.Type,
+		Timestamp:  delivery.CreatedAt.UTC(),
+		Environment: EnvironmentPayload{
+			Name:           event.Environment.Name,
+			Health:         event.Environment.Health.String(),
+			TaskDefinition: event.Environment.DesiredTaskDefinition,
+		},
+	}
+
+	if d := event.Deployment; d != nil {
+		failedInstances := make([]string, 0, len(d.FailedInstances))
+		for _, failure := range d.FailedInstances {
+			failedInstances = append(failedInstances, aws.StringValue(failure.Arn))
+		}
+
+		payload.Deployment = &DeploymentPayload{
+			ID:               d.ID,
+			Status:           d.Status.String(),
+			Health:           d.Health.String(),
+			TaskDefinition:   d.TaskDefinition,
+			DesiredTaskCount: d.DesiredTaskCount,
+			FailedInstances:  failedInstances,
+			RollbackReason:   d.RollbackReason,
+			RolledBackBy:     d.RolledBackBy,
+			RollbackOf:       d.RollbackOf,
+		}
+	}
+	return payload
+}
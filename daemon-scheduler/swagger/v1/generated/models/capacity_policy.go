@@ -0,0 +1,65 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// CapacityPolicy Decides whether an environment makes room for its tasks on instances without enough capacity by stopping the tasks of environments with a lower priority
+// swagger:model CapacityPolicy
+type CapacityPolicy struct {
+
+	// Priority of the environment against the environments whose tasks it may stop and that may stop its tasks
+	// Minimum: 0
+	Priority int64 `json:"priority,omitempty"`
+
+	// Whether tasks of environments with a lower priority are stopped to make room for the tasks of the environment
+	StopLowerPriorityTasks bool `json:"stopLowerPriorityTasks,omitempty"`
+}
+
+// Validate validates this capacity policy
+func (m *CapacityPolicy) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validatePriority(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *CapacityPolicy) validatePriority(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Priority) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("priority", "body", int64(m.Priority), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	"encoding/json"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// ClusterDeployment Progress of a deployment in one of the clusters of its environment
+// swagger:model ClusterDeployment
+type ClusterDeployment struct {
+
+	// Progress of the rollout in the cluster, one batch at a time
+	Batches []*DeploymentBatch `json:"batches"`
+
+	// ARN of the cluster
+	// Required: true
+	Cluster *string `json:"cluster"`
+
+	// desired task count
+	DesiredTaskCount int64 `json:"desiredTaskCount,omitempty"`
+
+	// List of ECS container-instance ARNs of the cluster where deployment failed
+	FailedInstances []string `json:"failedInstances"`
+
+	// Whether every instance of the cluster has been updated to the deployment
+	RolloutCompleted bool `json:"rolloutCompleted,omitempty"`
+
+	// status
+	// Required: true
+	Status *string `json:"status"`
+}
+
+// Validate validates this cluster deployment
+func (m *ClusterDeployment) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateBatches(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateCluster(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateFailedInstances(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateStatus(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *ClusterDeployment) validateBatches(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Batches) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Batches); i++ {
+
+		if swag.IsZero(m.Batches[i]) { // not required
+			continue
+		}
+
+		if m.Batches[i] != nil {
+
+			if err := m.Batches[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func (m *ClusterDeployment) validateCluster(formats strfmt.Registry) error {
+
+	if err := validate.Required("cluster", "body", m.Cluster); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *ClusterDeployment) validateFailedInstances(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.FailedInstances) { // not required
+		return nil
+	}
+
+	return nil
+}
+
+var clusterDeploymentTypeStatusPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["running","completed"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		clusterDeploymentTypeStatusPropEnum = append(clusterDeploymentTypeStatusPropEnum, v)
+	}
+}
+
+const (
+	// ClusterDeploymentStatusRunning captures enum value "running"
+	ClusterDeploymentStatusRunning string = "running"
+	// ClusterDeploymentStatusCompleted captures enum value "completed"
+	ClusterDeploymentStatusCompleted string = "completed"
+)
+
+// prop value enum
+func (m *ClusterDeployment) validateStatusEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, clusterDeploymentTypeStatusPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *ClusterDeployment) validateStatus(formats strfmt.Registry) error {
+
+	if err := validate.Required("status", "body", m.Status); err != nil {
+		return err
+	}
+
+	// value enum
+	if err := m.validateStatusEnum("status", "body", *m.Status); err != nil {
+		return err
+	}
+
+	return nil
+}
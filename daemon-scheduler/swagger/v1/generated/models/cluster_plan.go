@@ -0,0 +1,112 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+)
+
+// ClusterPlan What the scheduler would do in one of the clusters of an environment
+// swagger:model ClusterPlan
+type ClusterPlan struct {
+
+	// Instances running tasks of earlier deployments, in the order their tasks would be replaced
+	Batches [][]string `json:"batches"`
+
+	// ECS cluster ARN or name
+	Cluster string `json:"cluster,omitempty"`
+
+	// Instances running no task of the environment that would get a task right away
+	NewInstances []string `json:"newInstances"`
+
+	// Instances that would not get a task of the deployment
+	SkippedInstances []*SkippedInstance `json:"skippedInstances"`
+
+	// Tasks that would be stopped
+	StoppedTasks []*PlannedTask `json:"stoppedTasks"`
+}
+
+// Validate validates this cluster plan
+func (m *ClusterPlan) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateSkippedInstances(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateStoppedTasks(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *ClusterPlan) validateSkippedInstances(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.SkippedInstances) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.SkippedInstances); i++ {
+
+		if swag.IsZero(m.SkippedInstances[i]) { // not required
+			continue
+		}
+
+		if m.SkippedInstances[i] != nil {
+
+			if err := m.SkippedInstances[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func (m *ClusterPlan) validateStoppedTasks(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.StoppedTasks) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.StoppedTasks); i++ {
+
+		if swag.IsZero(m.StoppedTasks[i]) { // not required
+			continue
+		}
+
+		if m.StoppedTasks[i] != nil {
+
+			if err := m.StoppedTasks[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
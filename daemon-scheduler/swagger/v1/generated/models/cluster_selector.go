@@ -0,0 +1,62 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+)
+
+// ClusterSelector Selects the clusters an environment is deployed to. Clusters appearing in the cluster state that match the selector are picked up automatically
+// swagger:model ClusterSelector
+type ClusterSelector struct {
+
+	// Names or ARNs of clusters to deploy to
+	Clusters []string `json:"clusters"`
+
+	// Shell pattern matched against cluster names, e.g. prod-*
+	NamePattern string `json:"namePattern,omitempty"`
+
+	// ECS tags a cluster needs to have all of to be selected
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// Validate validates this cluster selector
+func (m *ClusterSelector) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateClusters(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *ClusterSelector) validateClusters(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Clusters) { // not required
+		return nil
+	}
+
+	return nil
+}
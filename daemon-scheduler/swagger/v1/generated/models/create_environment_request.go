@@ -17,7 +17,10 @@ package models
 // Editing this file might prove futile when you re-run the swagger generate command
 
 import (
+	"encoding/json"
+
 	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 
 	"github.com/go-openapi/errors"
 	"github.com/go-openapi/validate"
@@ -27,45 +30,168 @@ import (
 // swagger:model CreateEnvironmentRequest
 type CreateEnvironmentRequest struct {
 
+	// capacity policy
+	CapacityPolicy *CapacityPolicy `json:"capacityPolicy,omitempty"`
+
+	// What happens to the tasks of the environment on DRAINING instances, which never get new tasks. keep-until-last, the default, stops them once every other task left on the instance was started by an environment, stop-immediately stops them right away and do-not-start-new leaves them running.
+	DrainingPolicy string `json:"drainingPolicy,omitempty"`
+
 	// instance group
 	// Required: true
 	InstanceGroup *InstanceGroup `json:"instanceGroup"`
 
+	// When deployments may change the tasks of the environment. Pending deployments wait for a window to open and rollouts pause while all windows are closed. Without windows, the environment can be changed at any time.
+	MaintenanceWindows []*MaintenanceWindow `json:"maintenanceWindows"`
+
 	// name
 	// Required: true
 	// Pattern: ^[a-zA-Z0-9-_]{1,30}$
 	Name *string `json:"name"`
 
+	// restart policy
+	RestartPolicy *RestartPolicy `json:"restartPolicy,omitempty"`
+
+	// rollback policy
+	RollbackPolicy *RollbackPolicy `json:"rollbackPolicy,omitempty"`
+
+	// rollout strategy
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// scheduling strategy
+	SchedulingStrategy *SchedulingStrategy `json:"schedulingStrategy,omitempty"`
+
 	// task definition
 	// Required: true
 	TaskDefinition *string `json:"taskDefinition"`
+
+	// task overrides
+	TaskOverrides *TaskOverrides `json:"taskOverrides,omitempty"`
 }
 
 // Validate validates this create environment request
 func (m *CreateEnvironmentRequest) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateCapacityPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateDrainingPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateInstanceGroup(formats); err != nil {
 		// prop
 		res = append(res, err)
 	}
 
+	if err := m.validateMaintenanceWindows(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateName(formats); err != nil {
 		// prop
 		res = append(res, err)
 	}
 
+	if err := m.validateRestartPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRollbackPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRolloutStrategy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateSchedulingStrategy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateTaskDefinition(formats); err != nil {
 		// prop
 		res = append(res, err)
 	}
 
+	if err := m.validateTaskOverrides(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
 	return nil
 }
 
+func (m *CreateEnvironmentRequest) validateCapacityPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.CapacityPolicy) { // not required
+		return nil
+	}
+
+	if m.CapacityPolicy != nil {
+
+		if err := m.CapacityPolicy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var createEnvironmentRequestTypeDrainingPolicyPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["keep-until-last","stop-immediately","do-not-start-new"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		createEnvironmentRequestTypeDrainingPolicyPropEnum = append(createEnvironmentRequestTypeDrainingPolicyPropEnum, v)
+	}
+}
+
+const (
+	// CreateEnvironmentRequestDrainingPolicyKeepUntilLast captures enum value "keep-until-last"
+	CreateEnvironmentRequestDrainingPolicyKeepUntilLast string = "keep-until-last"
+	// CreateEnvironmentRequestDrainingPolicyStopImmediately captures enum value "stop-immediately"
+	CreateEnvironmentRequestDrainingPolicyStopImmediately string = "stop-immediately"
+	// CreateEnvironmentRequestDrainingPolicyDoNotStartNew captures enum value "do-not-start-new"
+	CreateEnvironmentRequestDrainingPolicyDoNotStartNew string = "do-not-start-new"
+)
+
+// prop value enum
+func (m *CreateEnvironmentRequest) validateDrainingPolicyEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, createEnvironmentRequestTypeDrainingPolicyPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *CreateEnvironmentRequest) validateDrainingPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.DrainingPolicy) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateDrainingPolicyEnum("drainingPolicy", "body", m.DrainingPolicy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *CreateEnvironmentRequest) validateInstanceGroup(formats strfmt.Registry) error {
 
 	if err := validate.Required("instanceGroup", "body", m.InstanceGroup); err != nil {
@@ -82,6 +208,30 @@ func (m *CreateEnvironmentRequest) validateInstanceGroup(formats strfmt.Registry
 	return nil
 }
 
+func (m *CreateEnvironmentRequest) validateMaintenanceWindows(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.MaintenanceWindows) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.MaintenanceWindows); i++ {
+
+		if swag.IsZero(m.MaintenanceWindows[i]) { // not required
+			continue
+		}
+
+		if m.MaintenanceWindows[i] != nil {
+
+			if err := m.MaintenanceWindows[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
 func (m *CreateEnvironmentRequest) validateName(formats strfmt.Registry) error {
 
 	if err := validate.Required("name", "body", m.Name); err != nil {
@@ -95,6 +245,70 @@ func (m *CreateEnvironmentRequest) validateName(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *CreateEnvironmentRequest) validateRestartPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RestartPolicy) { // not required
+		return nil
+	}
+
+	if m.RestartPolicy != nil {
+
+		if err := m.RestartPolicy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *CreateEnvironmentRequest) validateRollbackPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RollbackPolicy) { // not required
+		return nil
+	}
+
+	if m.RollbackPolicy != nil {
+
+		if err := m.RollbackPolicy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *CreateEnvironmentRequest) validateRolloutStrategy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RolloutStrategy) { // not required
+		return nil
+	}
+
+	if m.RolloutStrategy != nil {
+
+		if err := m.RolloutStrategy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *CreateEnvironmentRequest) validateSchedulingStrategy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.SchedulingStrategy) { // not required
+		return nil
+	}
+
+	if m.SchedulingStrategy != nil {
+
+		if err := m.SchedulingStrategy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (m *CreateEnvironmentRequest) validateTaskDefinition(formats strfmt.Registry) error {
 
 	if err := validate.Required("taskDefinition", "body", m.TaskDefinition); err != nil {
@@ -103,3 +317,19 @@ func (m *CreateEnvironmentRequest) validateTaskDefinition(formats strfmt.Registr
 
 	return nil
 }
+
+func (m *CreateEnvironmentRequest) validateTaskOverrides(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.TaskOverrides) { // not required
+		return nil
+	}
+
+	if m.TaskOverrides != nil {
+
+		if err := m.TaskOverrides.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// CreateWebhookRequest create webhook request
+// swagger:model CreateWebhookRequest
+type CreateWebhookRequest struct {
+
+	// Events posted to the webhook, any of deployment.started, deployment.completed, deployment.unhealthy, deployment.failed, deployment.rolledback and environment.health. A webhook without events receives all of them.
+	Events []string `json:"events"`
+
+	// Key of the HMAC-SHA256 signature of the payloads, sent in the X-Blox-Signature header
+	// Required: true
+	Secret *string `json:"secret"`
+
+	// Absolute http or https URL events are posted to
+	// Required: true
+	URL *string `json:"url"`
+}
+
+// Validate validates this create webhook request
+func (m *CreateWebhookRequest) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateSecret(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateURL(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *CreateWebhookRequest) validateSecret(formats strfmt.Registry) error {
+
+	if err := validate.Required("secret", "body", m.Secret); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *CreateWebhookRequest) validateURL(formats strfmt.Registry) error {
+
+	if err := validate.Required("url", "body", m.URL); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -30,6 +30,12 @@ import (
 // swagger:model Deployment
 type Deployment struct {
 
+	// Progress of the rollout of the deployment to instances running earlier deployments, one batch at a time
+	Batches []*DeploymentBatch `json:"batches"`
+
+	// Progress of the deployment in each cluster it started tasks in, for environments with a cluster selector
+	Clusters []*ClusterDeployment `json:"clusters"`
+
 	// environment name
 	// Required: true
 	EnvironmentName *string `json:"environmentName"`
@@ -41,6 +47,24 @@ type Deployment struct {
 	// Required: true
 	ID *string `json:"id"`
 
+	// Instances tasks were not started on for lack of CPU, memory or ports. They are retried and do not count as failures.
+	InsufficientCapacity []*InsufficientCapacity `json:"insufficientCapacity"`
+
+	// Earliest time the deployment starts. It stays pending until then and until a maintenance window of the environment is open.
+	NotBefore strfmt.DateTime `json:"notBefore,omitempty"`
+
+	// ID of the failed or cancelled deployment this deployment rolled back
+	RollbackOf string `json:"rollbackOf,omitempty"`
+
+	// Why the deployment failed or was cancelled, or why the deployment it rolled back did
+	RollbackReason string `json:"rollbackReason,omitempty"`
+
+	// ID of the deployment that rolled back this failed or cancelled deployment
+	RolledBackBy string `json:"rolledBackBy,omitempty"`
+
+	// Whether every instance has been updated to the deployment
+	RolloutCompleted bool `json:"rolloutCompleted,omitempty"`
+
 	// status
 	// Required: true
 	Status *string `json:"status"`
@@ -54,6 +78,16 @@ type Deployment struct {
 func (m *Deployment) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateBatches(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateClusters(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateEnvironmentName(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -69,6 +103,11 @@ func (m *Deployment) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateInsufficientCapacity(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateStatus(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -85,6 +124,54 @@ func (m *Deployment) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Deployment) validateBatches(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Batches) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Batches); i++ {
+
+		if swag.IsZero(m.Batches[i]) { // not required
+			continue
+		}
+
+		if m.Batches[i] != nil {
+
+			if err := m.Batches[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func (m *Deployment) validateClusters(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Clusters) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Clusters); i++ {
+
+		if swag.IsZero(m.Clusters[i]) { // not required
+			continue
+		}
+
+		if m.Clusters[i] != nil {
+
+			if err := m.Clusters[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
 func (m *Deployment) validateEnvironmentName(formats strfmt.Registry) error {
 
 	if err := validate.Required("environmentName", "body", m.EnvironmentName); err != nil {
@@ -112,11 +199,35 @@ func (m *Deployment) validateID(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Deployment) validateInsufficientCapacity(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.InsufficientCapacity) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.InsufficientCapacity); i++ {
+
+		if swag.IsZero(m.InsufficientCapacity[i]) { // not required
+			continue
+		}
+
+		if m.InsufficientCapacity[i] != nil {
+
+			if err := m.InsufficientCapacity[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
 var deploymentTypeStatusPropEnum []interface{}
 
 func init() {
 	var res []string
-	if err := json.Unmarshal([]byte(`["pending","running","completed"]`), &res); err != nil {
+	if err := json.Unmarshal([]byte(`["pending","running","completed","failed","paused","cancelled"]`), &res); err != nil {
 		panic(err)
 	}
 	for _, v := range res {
@@ -131,6 +242,12 @@ const (
 	DeploymentStatusRunning string = "running"
 	// DeploymentStatusCompleted captures enum value "completed"
 	DeploymentStatusCompleted string = "completed"
+	// DeploymentStatusFailed captures enum value "failed"
+	DeploymentStatusFailed string = "failed"
+	// DeploymentStatusPaused captures enum value "paused"
+	DeploymentStatusPaused string = "paused"
+	// DeploymentStatusCancelled captures enum value "cancelled"
+	DeploymentStatusCancelled string = "cancelled"
 )
 
 // prop value enum
@@ -0,0 +1,135 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	"encoding/json"
+
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// DeploymentBatch A set of instances updated together during a rollout
+// swagger:model DeploymentBatch
+type DeploymentBatch struct {
+
+	// end time
+	EndTime strfmt.DateTime `json:"endTime,omitempty"`
+
+	// Time since which every task of the batch has been running
+	HealthySince strfmt.DateTime `json:"healthySince,omitempty"`
+
+	// ECS container-instance ARNs updated in the batch
+	// Required: true
+	Instances []string `json:"instances"`
+
+	// start time
+	// Required: true
+	StartTime strfmt.DateTime `json:"startTime"`
+
+	// status
+	// Required: true
+	Status *string `json:"status"`
+}
+
+// Validate validates this deployment batch
+func (m *DeploymentBatch) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateInstances(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateStartTime(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateStatus(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *DeploymentBatch) validateInstances(formats strfmt.Registry) error {
+
+	if err := validate.Required("instances", "body", m.Instances); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *DeploymentBatch) validateStartTime(formats strfmt.Registry) error {
+
+	if err := validate.Required("startTime", "body", strfmt.DateTime(m.StartTime)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var deploymentBatchTypeStatusPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["running","soaking","completed"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		deploymentBatchTypeStatusPropEnum = append(deploymentBatchTypeStatusPropEnum, v)
+	}
+}
+
+const (
+	// DeploymentBatchStatusRunning captures enum value "running"
+	DeploymentBatchStatusRunning string = "running"
+	// DeploymentBatchStatusSoaking captures enum value "soaking"
+	DeploymentBatchStatusSoaking string = "soaking"
+	// DeploymentBatchStatusCompleted captures enum value "completed"
+	DeploymentBatchStatusCompleted string = "completed"
+)
+
+// prop value enum
+func (m *DeploymentBatch) validateStatusEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, deploymentBatchTypeStatusPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *DeploymentBatch) validateStatus(formats strfmt.Registry) error {
+
+	if err := validate.Required("status", "body", m.Status); err != nil {
+		return err
+	}
+
+	// value enum
+	if err := m.validateStatusEnum("status", "body", *m.Status); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// DeploymentInstance The state of a deployment on an instance it targeted
+// swagger:model DeploymentInstance
+type DeploymentInstance struct {
+
+	// ECS cluster ARN or name of the instance
+	Cluster string `json:"cluster,omitempty"`
+
+	// Why ECS did not start the task of the deployment on the instance
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// ECS container-instance ARN
+	// Required: true
+	InstanceARN *string `json:"instanceARN"`
+
+	// Tasks the deployment started on the instance, latest first, including the ones that stopped since
+	Tasks []*DeploymentTask `json:"tasks"`
+}
+
+// Validate validates this deployment instance
+func (m *DeploymentInstance) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateInstanceARN(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateTasks(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *DeploymentInstance) validateInstanceARN(formats strfmt.Registry) error {
+
+	if err := validate.Required("instanceARN", "body", m.InstanceARN); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *DeploymentInstance) validateTasks(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Tasks) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Tasks); i++ {
+
+		if swag.IsZero(m.Tasks[i]) { // not required
+			continue
+		}
+
+		if m.Tasks[i] != nil {
+
+			if err := m.Tasks[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
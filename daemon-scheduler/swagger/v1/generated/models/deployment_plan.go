@@ -0,0 +1,91 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// DeploymentPlan What the scheduler would do to roll out a new deployment of an environment
+// swagger:model DeploymentPlan
+type DeploymentPlan struct {
+
+	// Plans for each cluster the environment is deployed to
+	// Required: true
+	Clusters []*ClusterPlan `json:"clusters"`
+
+	// Task definition the deployment would run
+	// Required: true
+	TaskDefinition *string `json:"taskDefinition"`
+}
+
+// Validate validates this deployment plan
+func (m *DeploymentPlan) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateClusters(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateTaskDefinition(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *DeploymentPlan) validateClusters(formats strfmt.Registry) error {
+
+	if err := validate.Required("clusters", "body", m.Clusters); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(m.Clusters); i++ {
+
+		if swag.IsZero(m.Clusters[i]) { // not required
+			continue
+		}
+
+		if m.Clusters[i] != nil {
+
+			if err := m.Clusters[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func (m *DeploymentPlan) validateTaskDefinition(formats strfmt.Registry) error {
+
+	if err := validate.Required("taskDefinition", "body", m.TaskDefinition); err != nil {
+		return err
+	}
+
+	return nil
+}
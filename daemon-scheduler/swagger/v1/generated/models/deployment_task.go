@@ -0,0 +1,69 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// DeploymentTask A task started by a deployment
+// swagger:model DeploymentTask
+type DeploymentTask struct {
+
+	// Status of the task when the cluster state last saw it
+	LastStatus string `json:"lastStatus,omitempty"`
+
+	// When the task started running
+	StartedAt strfmt.DateTime `json:"startedAt,omitempty"`
+
+	// When the task stopped
+	StoppedAt strfmt.DateTime `json:"stoppedAt,omitempty"`
+
+	// Why the task stopped
+	StoppedReason string `json:"stoppedReason,omitempty"`
+
+	// ECS task ARN
+	// Required: true
+	TaskARN *string `json:"taskARN"`
+}
+
+// Validate validates this deployment task
+func (m *DeploymentTask) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateTaskARN(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *DeploymentTask) validateTaskARN(formats strfmt.Registry) error {
+
+	if err := validate.Required("taskARN", "body", m.TaskARN); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -17,7 +17,10 @@ package models
 // Editing this file might prove futile when you re-run the swagger generate command
 
 import (
+	"encoding/json"
+
 	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 
 	"github.com/go-openapi/errors"
 	"github.com/go-openapi/validate"
@@ -27,9 +30,24 @@ import (
 // swagger:model Environment
 type Environment struct {
 
+	// capacity policy
+	CapacityPolicy *CapacityPolicy `json:"capacityPolicy,omitempty"`
+
+	// Instances the task of the environment is no longer restarted on because it kept stopping shortly after starting
+	CrashLoopingInstances []*CrashLoopingInstance `json:"crashLoopingInstances"`
+
 	// The token used to verify that the deployment is being kicked off on the correct version of the environment
 	DeploymentToken string `json:"deploymentToken,omitempty"`
 
+	// Instances whose ECS agent was disconnected when the environment was last scheduled on them. No task of the environment is started on them until the agent reconnects.
+	DisconnectedInstances []*DisconnectedInstance `json:"disconnectedInstances"`
+
+	// When an environment being deleted is deleted even if some of its tasks have not stopped
+	DrainDeadline strfmt.DateTime `json:"drainDeadline,omitempty"`
+
+	// What happens to the tasks of the environment on DRAINING instances, which never get new tasks. keep-until-last, the default, stops them once every other task left on the instance was started by an environment, stop-immediately stops them right away and do-not-start-new leaves them running.
+	DrainingPolicy string `json:"drainingPolicy,omitempty"`
+
 	// health
 	// Required: true
 	Health HealthStatus `json:"health"`
@@ -38,18 +56,62 @@ type Environment struct {
 	// Required: true
 	InstanceGroup *InstanceGroup `json:"instanceGroup"`
 
+	// When deployments may change the tasks of the environment. Pending deployments wait for a window to open and rollouts pause while all windows are closed. Without windows, the environment can be changed at any time.
+	MaintenanceWindows []*MaintenanceWindow `json:"maintenanceWindows"`
+
+	// Replicas of a replica environment that could not be placed in each cluster when the environment was last scheduled there, as the cluster has too few eligible instances
+	MissingReplicas map[string]int64 `json:"missingReplicas,omitempty"`
+
 	// Name of the environment
 	// Required: true
 	Name *string `json:"name"`
 
+	// restart policy
+	RestartPolicy *RestartPolicy `json:"restartPolicy,omitempty"`
+
+	// rollback policy
+	RollbackPolicy *RollbackPolicy `json:"rollbackPolicy,omitempty"`
+
+	// rollout strategy
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// scheduling strategy
+	SchedulingStrategy *SchedulingStrategy `json:"schedulingStrategy,omitempty"`
+
+	// Environments being deleted stay deleting until the tasks of their deployments have stopped
+	Status string `json:"status,omitempty"`
+
 	// TaskDefinition used to start tasks under this environment
 	TaskDefinition string `json:"taskDefinition,omitempty"`
+
+	// task overrides
+	TaskOverrides *TaskOverrides `json:"taskOverrides,omitempty"`
 }
 
 // Validate validates this environment
 func (m *Environment) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateCapacityPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateCrashLoopingInstances(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateDisconnectedInstances(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateDrainingPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateHealth(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -60,17 +122,159 @@ func (m *Environment) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateMaintenanceWindows(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateName(formats); err != nil {
 		// prop
 		res = append(res, err)
 	}
 
+	if err := m.validateRestartPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRollbackPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRolloutStrategy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateSchedulingStrategy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateStatus(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateTaskOverrides(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
 	return nil
 }
 
+func (m *Environment) validateCapacityPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.CapacityPolicy) { // not required
+		return nil
+	}
+
+	if m.CapacityPolicy != nil {
+
+		if err := m.CapacityPolicy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Environment) validateCrashLoopingInstances(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.CrashLoopingInstances) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.CrashLoopingInstances); i++ {
+
+		if swag.IsZero(m.CrashLoopingInstances[i]) { // not required
+			continue
+		}
+
+		if m.CrashLoopingInstances[i] != nil {
+
+			if err := m.CrashLoopingInstances[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func (m *Environment) validateDisconnectedInstances(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.DisconnectedInstances) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.DisconnectedInstances); i++ {
+
+		if swag.IsZero(m.DisconnectedInstances[i]) { // not required
+			continue
+		}
+
+		if m.DisconnectedInstances[i] != nil {
+
+			if err := m.DisconnectedInstances[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+var environmentTypeDrainingPolicyPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["keep-until-last","stop-immediately","do-not-start-new"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		environmentTypeDrainingPolicyPropEnum = append(environmentTypeDrainingPolicyPropEnum, v)
+	}
+}
+
+const (
+	// EnvironmentDrainingPolicyKeepUntilLast captures enum value "keep-until-last"
+	EnvironmentDrainingPolicyKeepUntilLast string = "keep-until-last"
+	// EnvironmentDrainingPolicyStopImmediately captures enum value "stop-immediately"
+	EnvironmentDrainingPolicyStopImmediately string = "stop-immediately"
+	// EnvironmentDrainingPolicyDoNotStartNew captures enum value "do-not-start-new"
+	EnvironmentDrainingPolicyDoNotStartNew string = "do-not-start-new"
+)
+
+// prop value enum
+func (m *Environment) validateDrainingPolicyEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, environmentTypeDrainingPolicyPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Environment) validateDrainingPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.DrainingPolicy) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateDrainingPolicyEnum("drainingPolicy", "body", m.DrainingPolicy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *Environment) validateHealth(formats strfmt.Registry) error {
 
 	if err := m.Health.Validate(formats); err != nil {
@@ -96,6 +300,30 @@ func (m *Environment) validateInstanceGroup(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Environment) validateMaintenanceWindows(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.MaintenanceWindows) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.MaintenanceWindows); i++ {
+
+		if swag.IsZero(m.MaintenanceWindows[i]) { // not required
+			continue
+		}
+
+		if m.MaintenanceWindows[i] != nil {
+
+			if err := m.MaintenanceWindows[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
 func (m *Environment) validateName(formats strfmt.Registry) error {
 
 	if err := validate.Required("name", "body", m.Name); err != nil {
@@ -104,3 +332,124 @@ func (m *Environment) validateName(formats strfmt.Registry) error {
 
 	return nil
 }
+
+func (m *Environment) validateRestartPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RestartPolicy) { // not required
+		return nil
+	}
+
+	if m.RestartPolicy != nil {
+
+		if err := m.RestartPolicy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Environment) validateRollbackPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RollbackPolicy) { // not required
+		return nil
+	}
+
+	if m.RollbackPolicy != nil {
+
+		if err := m.RollbackPolicy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Environment) validateRolloutStrategy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RolloutStrategy) { // not required
+		return nil
+	}
+
+	if m.RolloutStrategy != nil {
+
+		if err := m.RolloutStrategy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Environment) validateSchedulingStrategy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.SchedulingStrategy) { // not required
+		return nil
+	}
+
+	if m.SchedulingStrategy != nil {
+
+		if err := m.SchedulingStrategy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var environmentTypeStatusPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["active","deleting"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		environmentTypeStatusPropEnum = append(environmentTypeStatusPropEnum, v)
+	}
+}
+
+const (
+	// EnvironmentStatusActive captures enum value "active"
+	EnvironmentStatusActive string = "active"
+	// EnvironmentStatusDeleting captures enum value "deleting"
+	EnvironmentStatusDeleting string = "deleting"
+)
+
+// prop value enum
+func (m *Environment) validateStatusEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, environmentTypeStatusPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Environment) validateStatus(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Status) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateStatusEnum("status", "body", m.Status); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Environment) validateTaskOverrides(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.TaskOverrides) { // not required
+		return nil
+	}
+
+	if m.TaskOverrides != nil {
+
+		if err := m.TaskOverrides.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -18,6 +18,7 @@ package models
 
 import (
 	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 
 	"github.com/go-openapi/errors"
 )
@@ -26,16 +27,64 @@ import (
 // swagger:model InstanceGroup
 type InstanceGroup struct {
 
-	// Cluster of instances, e.g. ECS Cluster
+	// Cluster of instances, e.g. ECS Cluster. Not set when the instance group has a cluster selector
 	Cluster string `json:"cluster,omitempty"`
+
+	// cluster selector
+	ClusterSelector *ClusterSelector `json:"clusterSelector,omitempty"`
+
+	// placement constraints
+	PlacementConstraints *PlacementConstraints `json:"placementConstraints,omitempty"`
 }
 
 // Validate validates this instance group
 func (m *InstanceGroup) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateClusterSelector(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validatePlacementConstraints(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
 	return nil
 }
+
+func (m *InstanceGroup) validateClusterSelector(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ClusterSelector) { // not required
+		return nil
+	}
+
+	if m.ClusterSelector != nil {
+
+		if err := m.ClusterSelector.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *InstanceGroup) validatePlacementConstraints(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.PlacementConstraints) { // not required
+		return nil
+	}
+
+	if m.PlacementConstraints != nil {
+
+		if err := m.PlacementConstraints.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// InsufficientCapacity An instance without the capacity to run a task of a deployment
+// swagger:model InsufficientCapacity
+type InsufficientCapacity struct {
+
+	// ECS container-instance ARN
+	// Required: true
+	InstanceARN *string `json:"instanceARN"`
+
+	// The resource the instance is short of, e.g. RESOURCE:MEMORY, or RESOURCE:RESERVED if it is reserved for a higher priority environment
+	// Required: true
+	Reason *string `json:"reason"`
+}
+
+// Validate validates this insufficient capacity
+func (m *InsufficientCapacity) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateInstanceARN(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateReason(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *InsufficientCapacity) validateInstanceARN(formats strfmt.Registry) error {
+
+	if err := validate.Required("instanceARN", "body", m.InstanceARN); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *InsufficientCapacity) validateReason(formats strfmt.Registry) error {
+
+	if err := validate.Required("reason", "body", m.Reason); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// MaintenanceWindow Recurring period during which deployments may change the tasks of an environment
+// swagger:model MaintenanceWindow
+type MaintenanceWindow struct {
+
+	// Number of seconds the window stays open, between 60 and 604800
+	// Required: true
+	// Maximum: 604800
+	// Minimum: 60
+	DurationSeconds *int64 `json:"durationSeconds"`
+
+	// Cron expression with the fields minute, hour, day of month, month and day of week, evaluated in UTC, that matches when the window opens
+	// Required: true
+	Schedule *string `json:"schedule"`
+}
+
+// Validate validates this maintenance window
+func (m *MaintenanceWindow) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateDurationSeconds(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateSchedule(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *MaintenanceWindow) validateDurationSeconds(formats strfmt.Registry) error {
+
+	if err := validate.Required("durationSeconds", "body", m.DurationSeconds); err != nil {
+		return err
+	}
+
+	if err := validate.MinimumInt("durationSeconds", "body", int64(*m.DurationSeconds), 60, false); err != nil {
+		return err
+	}
+
+	if err := validate.MaximumInt("durationSeconds", "body", int64(*m.DurationSeconds), 604800, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *MaintenanceWindow) validateSchedule(formats strfmt.Registry) error {
+
+	if err := validate.Required("schedule", "body", m.Schedule); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+)
+
+// PlacementConstraints Constraints limiting the instances of the cluster an environment is deployed to. An instance has to satisfy every constraint.
+// swagger:model PlacementConstraints
+type PlacementConstraints struct {
+
+	// Availability zones instances can be in
+	AvailabilityZones []string `json:"availabilityZones"`
+
+	// Container instance ARNs or EC2 instance IDs of instances the environment is never deployed to
+	ExcludeInstances []string `json:"excludeInstances"`
+
+	// Expressions evaluated against the instance attributes, of the form 'attribute', 'attribute == value', 'attribute != value', 'attribute =~ pattern' or 'attribute !~ pattern', e.g. 'ecs.instance-type =~ m5.*'
+	Expressions []string `json:"expressions"`
+
+	// Container instance ARNs or EC2 instance IDs of the only instances the environment is deployed to
+	IncludeInstances []string `json:"includeInstances"`
+}
+
+// Validate validates this placement constraints
+func (m *PlacementConstraints) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateAvailabilityZones(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateExcludeInstances(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateExpressions(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateIncludeInstances(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *PlacementConstraints) validateAvailabilityZones(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.AvailabilityZones) { // not required
+		return nil
+	}
+
+	return nil
+}
+
+func (m *PlacementConstraints) validateExcludeInstances(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ExcludeInstances) { // not required
+		return nil
+	}
+
+	return nil
+}
+
+func (m *PlacementConstraints) validateExpressions(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Expressions) { // not required
+		return nil
+	}
+
+	return nil
+}
+
+func (m *PlacementConstraints) validateIncludeInstances(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.IncludeInstances) { // not required
+		return nil
+	}
+
+	return nil
+}
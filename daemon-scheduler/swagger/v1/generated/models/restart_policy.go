@@ -0,0 +1,128 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// RestartPolicy Limits how often the task of the environment is restarted on an instance where it keeps stopping shortly after starting. Settings that are not set take their default value.
+// swagger:model RestartPolicy
+type RestartPolicy struct {
+
+	// Number of seconds the first restart waits, 10 by default. The wait doubles with every further restart up to maxBackoffSeconds.
+	// Minimum: 0
+	InitialBackoffSeconds int64 `json:"initialBackoffSeconds,omitempty"`
+
+	// Number of seconds a restart waits at most, 300 by default
+	// Minimum: 0
+	MaxBackoffSeconds int64 `json:"maxBackoffSeconds,omitempty"`
+
+	// Number of times the task is restarted before the instance is crash-looping and the task is no longer restarted there, 10 by default
+	// Minimum: 0
+	MaxRestarts int64 `json:"maxRestarts,omitempty"`
+
+	// Number of seconds a task has to run for its stopping not to count as a crash, 600 by default
+	// Minimum: 0
+	StableSeconds int64 `json:"stableSeconds,omitempty"`
+}
+
+// Validate validates this restart policy
+func (m *RestartPolicy) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateInitialBackoffSeconds(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateMaxBackoffSeconds(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateMaxRestarts(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateStableSeconds(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *RestartPolicy) validateInitialBackoffSeconds(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.InitialBackoffSeconds) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("initialBackoffSeconds", "body", int64(m.InitialBackoffSeconds), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RestartPolicy) validateMaxBackoffSeconds(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.MaxBackoffSeconds) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("maxBackoffSeconds", "body", int64(m.MaxBackoffSeconds), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RestartPolicy) validateMaxRestarts(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.MaxRestarts) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("maxRestarts", "body", int64(m.MaxRestarts), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RestartPolicy) validateStableSeconds(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.StableSeconds) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("stableSeconds", "body", int64(m.StableSeconds), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// RollbackPolicy Decides when an in-progress deployment has failed and is rolled back to the task definition of the latest healthy completed deployment. A check is disabled while its threshold is not set.
+// swagger:model RollbackPolicy
+type RollbackPolicy struct {
+
+	// Number of tasks that fails the deployment by stopping after running within the last crashWindowSeconds
+	// Minimum: 0
+	CrashCount int64 `json:"crashCount,omitempty"`
+
+	// Number of seconds crashing tasks are counted over
+	// Minimum: 0
+	CrashWindowSeconds int64 `json:"crashWindowSeconds,omitempty"`
+
+	// Percentage of the instances targeted by the deployment on which starting a task failed that fails the deployment
+	// Maximum: 100
+	// Minimum: 0
+	FailedInstancePercent int64 `json:"failedInstancePercent,omitempty"`
+
+	// Number of tasks that fails the deployment by staying pending for longer than pendingTimeoutSeconds, or by stopping without ever running
+	// Minimum: 0
+	PendingTaskCount int64 `json:"pendingTaskCount,omitempty"`
+
+	// Number of seconds a task can stay pending
+	// Minimum: 0
+	PendingTimeoutSeconds int64 `json:"pendingTimeoutSeconds,omitempty"`
+}
+
+// Validate validates this rollback policy
+func (m *RollbackPolicy) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateCrashCount(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateCrashWindowSeconds(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateFailedInstancePercent(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validatePendingTaskCount(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validatePendingTimeoutSeconds(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *RollbackPolicy) validateCrashCount(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.CrashCount) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("crashCount", "body", int64(m.CrashCount), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RollbackPolicy) validateCrashWindowSeconds(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.CrashWindowSeconds) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("crashWindowSeconds", "body", int64(m.CrashWindowSeconds), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RollbackPolicy) validateFailedInstancePercent(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.FailedInstancePercent) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("failedInstancePercent", "body", int64(m.FailedInstancePercent), 0, false); err != nil {
+		return err
+	}
+
+	if err := validate.MaximumInt("failedInstancePercent", "body", int64(m.FailedInstancePercent), 100, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RollbackPolicy) validatePendingTaskCount(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.PendingTaskCount) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("pendingTaskCount", "body", int64(m.PendingTaskCount), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RollbackPolicy) validatePendingTimeoutSeconds(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.PendingTimeoutSeconds) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("pendingTimeoutSeconds", "body", int64(m.PendingTimeoutSeconds), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
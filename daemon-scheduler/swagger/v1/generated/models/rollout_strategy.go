@@ -0,0 +1,138 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// RolloutStrategy Controls how a deployment replaces the tasks of earlier deployments. Tasks are replaced on every instance at once unless any setting is set.
+// swagger:model RolloutStrategy
+type RolloutStrategy struct {
+
+	// Percentage of the instances updated at a time, used when batchSize is not set
+	// Maximum: 100
+	// Minimum: 0
+	BatchPercent int64 `json:"batchPercent,omitempty"`
+
+	// Number of instances updated at a time
+	// Minimum: 0
+	BatchSize int64 `json:"batchSize,omitempty"`
+
+	// Percentage of the instances that have to keep a running task during the rollout. At least one instance is updated at a time regardless.
+	// Maximum: 100
+	// Minimum: 0
+	MinHealthyPercent int64 `json:"minHealthyPercent,omitempty"`
+
+	// Number of seconds every task of a batch has to keep running before the next batch starts
+	// Minimum: 0
+	SoakSeconds int64 `json:"soakSeconds,omitempty"`
+}
+
+// Validate validates this rollout strategy
+func (m *RolloutStrategy) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateBatchPercent(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateBatchSize(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateMinHealthyPercent(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateSoakSeconds(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *RolloutStrategy) validateBatchPercent(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.BatchPercent) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("batchPercent", "body", int64(m.BatchPercent), 0, false); err != nil {
+		return err
+	}
+
+	if err := validate.MaximumInt("batchPercent", "body", int64(m.BatchPercent), 100, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RolloutStrategy) validateBatchSize(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.BatchSize) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("batchSize", "body", int64(m.BatchSize), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RolloutStrategy) validateMinHealthyPercent(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.MinHealthyPercent) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("minHealthyPercent", "body", int64(m.MinHealthyPercent), 0, false); err != nil {
+		return err
+	}
+
+	if err := validate.MaximumInt("minHealthyPercent", "body", int64(m.MinHealthyPercent), 100, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RolloutStrategy) validateSoakSeconds(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.SoakSeconds) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("soakSeconds", "body", int64(m.SoakSeconds), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
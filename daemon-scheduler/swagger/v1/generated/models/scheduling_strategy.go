@@ -0,0 +1,113 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	"encoding/json"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// SchedulingStrategy Decides how many tasks of the environment run and on which instances. Environments without a strategy use the daemon strategy.
+// swagger:model SchedulingStrategy
+type SchedulingStrategy struct {
+
+	// Number of tasks of a replica environment in each of its clusters
+	// Minimum: 0
+	Replicas int64 `json:"replicas,omitempty"`
+
+	// daemon runs a task on every instance the environment may run on. replica runs replicas tasks in each cluster, spread over the availability zones and packed onto the instances with the least memory left.
+	Type string `json:"type,omitempty"`
+}
+
+// Validate validates this scheduling strategy
+func (m *SchedulingStrategy) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateReplicas(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateType(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *SchedulingStrategy) validateReplicas(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Replicas) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("replicas", "body", int64(m.Replicas), 0, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var schedulingStrategyTypeTypePropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["daemon","replica"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		schedulingStrategyTypeTypePropEnum = append(schedulingStrategyTypeTypePropEnum, v)
+	}
+}
+
+const (
+	// SchedulingStrategyTypeDaemon captures enum value "daemon"
+	SchedulingStrategyTypeDaemon string = "daemon"
+	// SchedulingStrategyTypeReplica captures enum value "replica"
+	SchedulingStrategyTypeReplica string = "replica"
+)
+
+// prop value enum
+func (m *SchedulingStrategy) validateTypeEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, schedulingStrategyTypeTypePropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *SchedulingStrategy) validateType(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Type) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateTypeEnum("type", "body", m.Type); err != nil {
+		return err
+	}
+
+	return nil
+}
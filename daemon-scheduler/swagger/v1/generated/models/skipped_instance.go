@@ -0,0 +1,75 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// SkippedInstance An instance that would not get a task of the deployment
+// swagger:model SkippedInstance
+type SkippedInstance struct {
+
+	// ECS container-instance ARN
+	// Required: true
+	InstanceARN *string `json:"instanceARN"`
+
+	// Why the instance would be skipped, such as RESOURCE:MEMORY or PLACEMENT_CONSTRAINTS
+	// Required: true
+	Reason *string `json:"reason"`
+}
+
+// Validate validates this skipped instance
+func (m *SkippedInstance) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateInstanceARN(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateReason(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *SkippedInstance) validateInstanceARN(formats strfmt.Registry) error {
+
+	if err := validate.Required("instanceARN", "body", m.InstanceARN); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *SkippedInstance) validateReason(formats strfmt.Registry) error {
+
+	if err := validate.Required("reason", "body", m.Reason); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+)
+
+// TaskOverrides Settings the tasks of an environment are started with on top of its task definition
+// swagger:model TaskOverrides
+type TaskOverrides struct {
+
+	// Overrides of the containers of the task definition
+	ContainerOverrides []*ContainerOverride `json:"containerOverrides"`
+
+	// CPU units reserved for the tasks, replacing the one of the task definition, either as an integer or as vCPUs, e.g. 0.5 vCPU
+	CPU string `json:"cpu,omitempty"`
+
+	// ARN of the IAM role the container agent assumes to start the tasks
+	ExecutionRoleArn string `json:"executionRoleArn,omitempty"`
+
+	// Memory in MiB reserved for the tasks, replacing the one of the task definition, either as an integer or in GB, e.g. 2 GB
+	Memory string `json:"memory,omitempty"`
+
+	// Tags the tasks are started with
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// ARN of the IAM role the containers of the tasks assume
+	TaskRoleArn string `json:"taskRoleArn,omitempty"`
+}
+
+// Validate validates this task overrides
+func (m *TaskOverrides) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateContainerOverrides(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *TaskOverrides) validateContainerOverrides(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ContainerOverrides) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.ContainerOverrides); i++ {
+
+		if swag.IsZero(m.ContainerOverrides[i]) { // not required
+			continue
+		}
+
+		if m.ContainerOverrides[i] != nil {
+
+			if err := m.ContainerOverrides[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
@@ -0,0 +1,296 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	"encoding/json"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// UpdateEnvironmentRequest Request object to UpdateEnvironment api
+// swagger:model UpdateEnvironmentRequest
+type UpdateEnvironmentRequest struct {
+
+	// capacity policy
+	CapacityPolicy *CapacityPolicy `json:"capacityPolicy,omitempty"`
+
+	// What happens to the tasks of the environment on DRAINING instances, which never get new tasks. keep-until-last, the default, stops them once every other task left on the instance was started by an environment, stop-immediately stops them right away and do-not-start-new leaves them running.
+	DrainingPolicy string `json:"drainingPolicy,omitempty"`
+
+	// instance group
+	InstanceGroup *InstanceGroup `json:"instanceGroup,omitempty"`
+
+	// When deployments may change the tasks of the environment. Pending deployments wait for a window to open and rollouts pause while all windows are closed. Without windows, the environment can be changed at any time.
+	MaintenanceWindows []*MaintenanceWindow `json:"maintenanceWindows"`
+
+	// restart policy
+	RestartPolicy *RestartPolicy `json:"restartPolicy,omitempty"`
+
+	// rollback policy
+	RollbackPolicy *RollbackPolicy `json:"rollbackPolicy,omitempty"`
+
+	// rollout strategy
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// scheduling strategy
+	SchedulingStrategy *SchedulingStrategy `json:"schedulingStrategy,omitempty"`
+
+	// task definition
+	TaskDefinition string `json:"taskDefinition,omitempty"`
+
+	// task overrides
+	TaskOverrides *TaskOverrides `json:"taskOverrides,omitempty"`
+}
+
+// Validate validates this update environment request
+func (m *UpdateEnvironmentRequest) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateCapacityPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateDrainingPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateInstanceGroup(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateMaintenanceWindows(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRestartPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRollbackPolicy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateRolloutStrategy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateSchedulingStrategy(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateTaskOverrides(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *UpdateEnvironmentRequest) validateCapacityPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.CapacityPolicy) { // not required
+		return nil
+	}
+
+	if m.CapacityPolicy != nil {
+
+		if err := m.CapacityPolicy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var updateEnvironmentRequestTypeDrainingPolicyPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["keep-until-last","stop-immediately","do-not-start-new"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		updateEnvironmentRequestTypeDrainingPolicyPropEnum = append(updateEnvironmentRequestTypeDrainingPolicyPropEnum, v)
+	}
+}
+
+const (
+	// UpdateEnvironmentRequestDrainingPolicyKeepUntilLast captures enum value "keep-until-last"
+	UpdateEnvironmentRequestDrainingPolicyKeepUntilLast string = "keep-until-last"
+	// UpdateEnvironmentRequestDrainingPolicyStopImmediately captures enum value "stop-immediately"
+	UpdateEnvironmentRequestDrainingPolicyStopImmediately string = "stop-immediately"
+	// UpdateEnvironmentRequestDrainingPolicyDoNotStartNew captures enum value "do-not-start-new"
+	UpdateEnvironmentRequestDrainingPolicyDoNotStartNew string = "do-not-start-new"
+)
+
+// prop value enum
+func (m *UpdateEnvironmentRequest) validateDrainingPolicyEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, updateEnvironmentRequestTypeDrainingPolicyPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *UpdateEnvironmentRequest) validateDrainingPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.DrainingPolicy) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateDrainingPolicyEnum("drainingPolicy", "body", m.DrainingPolicy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *UpdateEnvironmentRequest) validateInstanceGroup(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.InstanceGroup) { // not required
+		return nil
+	}
+
+	if m.InstanceGroup != nil {
+
+		if err := m.InstanceGroup.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *UpdateEnvironmentRequest) validateMaintenanceWindows(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.MaintenanceWindows) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.MaintenanceWindows); i++ {
+
+		if swag.IsZero(m.MaintenanceWindows[i]) { // not required
+			continue
+		}
+
+		if m.MaintenanceWindows[i] != nil {
+
+			if err := m.MaintenanceWindows[i].Validate(formats); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func (m *UpdateEnvironmentRequest) validateRestartPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RestartPolicy) { // not required
+		return nil
+	}
+
+	if m.RestartPolicy != nil {
+
+		if err := m.RestartPolicy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *UpdateEnvironmentRequest) validateRollbackPolicy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RollbackPolicy) { // not required
+		return nil
+	}
+
+	if m.RollbackPolicy != nil {
+
+		if err := m.RollbackPolicy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *UpdateEnvironmentRequest) validateRolloutStrategy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RolloutStrategy) { // not required
+		return nil
+	}
+
+	if m.RolloutStrategy != nil {
+
+		if err := m.RolloutStrategy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *UpdateEnvironmentRequest) validateSchedulingStrategy(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.SchedulingStrategy) { // not required
+		return nil
+	}
+
+	if m.SchedulingStrategy != nil {
+
+		if err := m.SchedulingStrategy.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *UpdateEnvironmentRequest) validateTaskOverrides(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.TaskOverrides) { // not required
+		return nil
+	}
+
+	if m.TaskOverrides != nil {
+
+		if err := m.TaskOverrides.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// UpdateEnvironmentResponse Response object of UpdateEnvironment api
+// swagger:model UpdateEnvironmentResponse
+type UpdateEnvironmentResponse struct {
+
+	// deployment
+	Deployment *Deployment `json:"deployment,omitempty"`
+
+	// environment
+	// Required: true
+	Environment *Environment `json:"environment"`
+}
+
+// Validate validates this update environment response
+func (m *UpdateEnvironmentResponse) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateDeployment(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateEnvironment(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *UpdateEnvironmentResponse) validateDeployment(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Deployment) { // not required
+		return nil
+	}
+
+	if m.Deployment != nil {
+
+		if err := m.Deployment.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *UpdateEnvironmentResponse) validateEnvironment(formats strfmt.Registry) error {
+
+	if err := validate.Required("environment", "body", m.Environment); err != nil {
+		return err
+	}
+
+	if m.Environment != nil {
+
+		if err := m.Environment.Validate(formats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
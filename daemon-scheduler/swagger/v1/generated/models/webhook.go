@@ -0,0 +1,99 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// Webhook Subscription of a URL to the changes of an environment. The secret is never returned.
+// swagger:model Webhook
+type Webhook struct {
+
+	// created at
+	CreatedAt strfmt.DateTime `json:"createdAt,omitempty"`
+
+	// environment name
+	// Required: true
+	EnvironmentName *string `json:"environmentName"`
+
+	// Events posted to the webhook, any of deployment.started, deployment.completed, deployment.unhealthy, deployment.failed, deployment.rolledback and environment.health. A webhook without events receives all of them.
+	Events []string `json:"events"`
+
+	// ID of the webhook
+	// Required: true
+	ID *string `json:"id"`
+
+	// URL events are posted to
+	// Required: true
+	URL *string `json:"url"`
+}
+
+// Validate validates this webhook
+func (m *Webhook) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateEnvironmentName(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateID(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateURL(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *Webhook) validateEnvironmentName(formats strfmt.Registry) error {
+
+	if err := validate.Required("environmentName", "body", m.EnvironmentName); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Webhook) validateID(formats strfmt.Registry) error {
+
+	if err := validate.Required("id", "body", m.ID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Webhook) validateURL(formats strfmt.Registry) error {
+
+	if err := validate.Required("url", "body", m.URL); err != nil {
+		return err
+	}
+
+	return nil
+}
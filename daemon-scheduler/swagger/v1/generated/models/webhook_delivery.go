@@ -0,0 +1,180 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package models
+
+// This file was generated by the swagger tool.
+// Editing this file might prove futile when you re-run the swagger generate command
+
+import (
+	"encoding/json"
+
+	strfmt "github.com/go-openapi/strfmt"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/validate"
+)
+
+// WebhookDelivery The attempts to post an event to a webhook
+// swagger:model WebhookDelivery
+type WebhookDelivery struct {
+
+	// Number of times the event was posted
+	// Required: true
+	Attempts *int64 `json:"attempts"`
+
+	// created at
+	CreatedAt strfmt.DateTime `json:"createdAt,omitempty"`
+
+	// ID of the deployment the event is about, if any
+	DeploymentID string `json:"deploymentId,omitempty"`
+
+	// Why the last attempt failed
+	Error string `json:"error,omitempty"`
+
+	// event
+	// Required: true
+	Event *string `json:"event"`
+
+	// ID of the delivery, sent in the X-Blox-Delivery header
+	// Required: true
+	ID *string `json:"id"`
+
+	// last attempt at
+	LastAttemptAt strfmt.DateTime `json:"lastAttemptAt,omitempty"`
+
+	// HTTP status of the response to the last attempt, if any
+	ResponseStatus int64 `json:"responseStatus,omitempty"`
+
+	// status
+	// Required: true
+	Status *string `json:"status"`
+
+	// webhook Id
+	// Required: true
+	WebhookID *string `json:"webhookId"`
+}
+
+// Validate validates this webhook delivery
+func (m *WebhookDelivery) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateAttempts(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateEvent(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateID(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateStatus(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if err := m.validateWebhookID(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *WebhookDelivery) validateAttempts(formats strfmt.Registry) error {
+
+	if err := validate.Required("attempts", "body", m.Attempts); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *WebhookDelivery) validateEvent(formats strfmt.Registry) error {
+
+	if err := validate.Required("event", "body", m.Event); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *WebhookDelivery) validateID(formats strfmt.Registry) error {
+
+	if err := validate.Required("id", "body", m.ID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var webhookDeliveryTypeStatusPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["pending","succeeded","failed"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		webhookDeliveryTypeStatusPropEnum = append(webhookDeliveryTypeStatusPropEnum, v)
+	}
+}
+
+const (
+	// WebhookDeliveryStatusPending captures enum value "pending"
+	WebhookDeliveryStatusPending string = "pending"
+	// WebhookDeliveryStatusSucceeded captures enum value "succeeded"
+	WebhookDeliveryStatusSucceeded string = "succeeded"
+	// WebhookDeliveryStatusFailed captures enum value "failed"
+	WebhookDeliveryStatusFailed string = "failed"
+)
+
+// prop value enum
+func (m *WebhookDelivery) validateStatusEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, webhookDeliveryTypeStatusPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *WebhookDelivery) validateStatus(formats strfmt.Registry) error {
+
+	if err := validate.Required("status", "body", m.Status); err != nil {
+		return err
+	}
+
+	// value enum
+	if err := m.validateStatusEnum("status", "body", *m.Status); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *WebhookDelivery) validateWebhookID(formats strfmt.Registry) error {
+
+	if err := validate.Required("webhookId", "body", m.WebhookID); err != nil {
+		return err
+	}
+
+	return nil
+}
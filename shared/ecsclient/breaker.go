@@ -0,0 +1,100 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ecsclient
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// CircuitClosed, CircuitOpen and CircuitHalfOpen are the states of the circuit breaker
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// circuitBreaker stops calling ECS after threshold consecutive requests were throttled or
+// failed. Once the cooldown has passed a single request is let through, which closes the circuit
+// again if it succeeds and opens it for another cooldown if it does not. Another request is let
+// through if the outcome of that one is not known after a cooldown.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	opened   int64
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     CircuitClosed,
+	}
+}
+
+// allow returns whether a request may be sent
+func (c *circuitBreaker) allow(now time.Time) bool {
+	if c.threshold == 0 {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == CircuitClosed {
+		return true
+	}
+	// the request probing ECS after the cooldown may not have come back yet
+	if now.Sub(c.openedAt) < c.cooldown {
+		return false
+	}
+	c.state = CircuitHalfOpen
+	c.openedAt = now
+	return true
+}
+
+// success records a request ECS answered, even with an error of the caller
+func (c *circuitBreaker) success() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = 0
+	c.state = CircuitClosed
+}
+
+// failure records a request that was throttled or failed
+func (c *circuitBreaker) failure(now time.Time) {
+	if c.threshold == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures++
+	if c.state == CircuitHalfOpen || (c.state == CircuitClosed && c.failures >= c.threshold) {
+		c.state = CircuitOpen
+		c.openedAt = now
+		c.opened++
+	}
+}
+
+// status returns the state of the circuit and how many times it has been opened
+func (c *circuitBreaker) status() (string, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state, c.opened
+}
@@ -0,0 +1,59 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ecsclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerLetsOneProbeThrough(t *testing.T) {
+	now := time.Unix(1000, 0)
+	breaker := newCircuitBreaker(1, time.Minute)
+	breaker.failure(now)
+	assert.False(t, breaker.allow(now), "Expected the circuit to be open")
+
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.allow(now), "Expected a probe after the cooldown")
+	assert.False(t, breaker.allow(now), "Expected a single probe at a time")
+
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.allow(now), "Expected another probe when the first one did not come back")
+	breaker.success()
+	assert.True(t, breaker.allow(now), "Expected the circuit to close after a successful probe")
+}
+
+func TestCircuitBreakerCountsConsecutiveFailures(t *testing.T) {
+	now := time.Unix(1000, 0)
+	breaker := newCircuitBreaker(2, time.Minute)
+	breaker.failure(now)
+	breaker.success()
+	breaker.failure(now)
+	assert.True(t, breaker.allow(now), "Expected failures to be reset by a success")
+	breaker.failure(now)
+	assert.False(t, breaker.allow(now), "Expected the circuit to open after consecutive failures")
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	now := time.Unix(1000, 0)
+	breaker := newCircuitBreaker(0, 0)
+	for i := 0; i < 10; i++ {
+		breaker.failure(now)
+	}
+	assert.True(t, breaker.allow(now), "Expected a disabled circuit breaker never to open")
+	state, _ := breaker.status()
+	assert.Equal(t, CircuitClosed, state)
+}
@@ -0,0 +1,59 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ecsclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	now := time.Unix(1000, 0)
+	bucket := newTokenBucket(10, 3, now)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, time.Duration(0), bucket.reserve(now), "Expected the burst not to wait")
+	}
+	assert.Equal(t, 100*time.Millisecond, bucket.reserve(now), "Expected a request over the burst to wait")
+	assert.Equal(t, 200*time.Millisecond, bucket.reserve(now), "Expected waiting requests to queue up")
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	now := time.Unix(1000, 0)
+	bucket := newTokenBucket(10, 2, now)
+	bucket.reserve(now)
+	bucket.reserve(now)
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, time.Duration(0), bucket.reserve(now), "Expected tokens to be refilled")
+	assert.Equal(t, time.Duration(0), bucket.reserve(now), "Expected tokens to be refilled")
+	assert.Equal(t, 100*time.Millisecond, bucket.reserve(now), "Expected the refill to stop at the burst")
+}
+
+func TestTokenBucketRefund(t *testing.T) {
+	now := time.Unix(1000, 0)
+	bucket := newTokenBucket(10, 1, now)
+	bucket.reserve(now)
+	assert.Equal(t, 100*time.Millisecond, bucket.reserve(now), "Expected a request over the burst to wait")
+
+	bucket.refund()
+	assert.Equal(t, 100*time.Millisecond, bucket.reserve(now), "Expected the refunded token to be reserved again")
+
+	bucket.refund()
+	bucket.refund()
+	bucket.refund()
+	assert.Equal(t, time.Duration(0), bucket.reserve(now), "Expected refunds to stop at the burst")
+	assert.Equal(t, 100*time.Millisecond, bucket.reserve(now), "Expected refunds to stop at the burst")
+}
@@ -0,0 +1,425 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ecsclient keeps the ECS clients of the daemon scheduler and the cluster state service
+// within the ECS request rate limits, so that a large reconcile or deployment slows down rather
+// than failing on throttling errors.
+package ecsclient
+
+import (
+	"encoding/json"
+	"expvar"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultRateLimit and DefaultBurst are the default number of requests per second each ECS
+	// API is called at, and the number of requests each API may be called at once after being idle
+	DefaultRateLimit = 10
+	DefaultBurst     = 20
+	// DefaultMaxRetries is the default number of times a throttled or failed request is retried
+	DefaultMaxRetries = 5
+	// DefaultRetryBaseDelay and DefaultRetryMaxDelay bound the jittered delay before a retry,
+	// which doubles with every retry
+	DefaultRetryBaseDelay = 100 * time.Millisecond
+	DefaultRetryMaxDelay  = 10 * time.Second
+	// DefaultCircuitThreshold is the default number of consecutive throttled or failed requests
+	// after which ECS is not called for DefaultCircuitCooldown
+	DefaultCircuitThreshold = 20
+	DefaultCircuitCooldown  = 30 * time.Second
+
+	// MetricsName is the name the metrics of the throttle are published under with expvar, and
+	// MetricsPath the path the expvar variables are served on
+	MetricsName = "ecs"
+	MetricsPath = "/debug/vars"
+
+	// ErrCodeCircuitOpen is the code of the error returned for calls made while the circuit is open
+	ErrCodeCircuitOpen = "CircuitOpen"
+	// ErrCodeThrottleStopped is the code of the error returned for calls waiting for the rate
+	// limit when the throttle is stopped
+	ErrCodeThrottleStopped = "ThrottleStopped"
+)
+
+// Config holds the rate limits, retries and circuit breaking of the ECS clients
+type Config struct {
+	// RateLimit is the number of requests per second each ECS API is called at. Requests over
+	// the limit wait for their turn. Zero disables the rate limits.
+	RateLimit float64
+	// Burst is the number of requests each API may be called at once after being idle
+	Burst int
+	// APIRateLimits override the rate limit of single APIs by operation name, such as DescribeTasks
+	APIRateLimits map[string]float64
+	// MaxRetries is the number of times a request is retried after being throttled, failing with
+	// a 5xx response or not reaching ECS
+	MaxRetries int
+	// RetryBaseDelay and RetryMaxDelay bound the delay before a retry. The delay is picked at
+	// random up to the base delay doubled with every retry, and no longer than the max delay.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// CircuitThreshold is the number of consecutive throttled or failed requests after which calls
+	// fail straight away for CircuitCooldown. Zero disables the circuit breaker.
+	CircuitThreshold int
+	CircuitCooldown  time.Duration
+}
+
+// DefaultConfig returns the default rate limits, retries and circuit breaking
+func DefaultConfig() Config {
+	return Config{
+		RateLimit:        DefaultRateLimit,
+		Burst:            DefaultBurst,
+		MaxRetries:       DefaultMaxRetries,
+		RetryBaseDelay:   DefaultRetryBaseDelay,
+		RetryMaxDelay:    DefaultRetryMaxDelay,
+		CircuitThreshold: DefaultCircuitThreshold,
+		CircuitCooldown:  DefaultCircuitCooldown,
+	}
+}
+
+// Validate returns an error if a setting is out of range
+func (c Config) Validate() error {
+	if c.RateLimit < 0 {
+		return errors.New("The ECS rate limit must not be negative")
+	}
+	for api, rate := range c.APIRateLimits {
+		if api == "" || rate < 0 {
+			return errors.Errorf("Invalid rate limit %v of ECS API '%s'", rate, api)
+		}
+	}
+	if c.Burst < 1 {
+		return errors.New("The ECS burst must be at least 1")
+	}
+	if c.MaxRetries < 0 {
+		return errors.New("The ECS max retries must not be negative")
+	}
+	if c.RetryBaseDelay <= 0 || c.RetryMaxDelay < c.RetryBaseDelay {
+		return errors.New("The ECS retry delays must be positive, the max delay no shorter than the base delay")
+	}
+	if c.CircuitThreshold < 0 {
+		return errors.New("The ECS circuit threshold must not be negative")
+	}
+	if c.CircuitThreshold > 0 && c.CircuitCooldown <= 0 {
+		return errors.New("The ECS circuit cooldown must be positive")
+	}
+	return nil
+}
+
+// ParseAPIRateLimits parses rate limits of single APIs given as Operation=rate, such as
+// DescribeTasks=20
+func ParseAPIRateLimits(values []string) (map[string]float64, error) {
+	limits := make(map[string]float64, len(values))
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("Invalid ECS API rate limit '%s', expected Operation=rate", value)
+		}
+		rate, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || rate < 0 {
+			return nil, errors.Errorf("Invalid ECS API rate limit '%s', the rate must be a number of requests per second", value)
+		}
+		limits[parts[0]] = rate
+	}
+	return limits, nil
+}
+
+// APIMetrics counts the requests made to one ECS API
+type APIMetrics struct {
+	// Requests is the number of requests sent, retries included
+	Requests int64 `json:"requests"`
+	// Throttled and ServerErrors are the number of requests ECS throttled or answered with a
+	// 5xx response, and RequestErrors the number of requests that did not reach it
+	Throttled     int64 `json:"throttled"`
+	ServerErrors  int64 `json:"serverErrors"`
+	RequestErrors int64 `json:"requestErrors"`
+	// Retries is the number of requests retried
+	Retries int64 `json:"retries"`
+	// Rejected is the number of requests not sent because the circuit was open
+	Rejected int64 `json:"rejected"`
+	// RateLimitedSeconds is the total time requests waited for the rate limit
+	RateLimitedSeconds float64 `json:"rateLimitedSeconds"`
+}
+
+// Metrics counts the requests made by the ECS clients
+type Metrics struct {
+	// APIs are the metrics of each API called, by operation name
+	APIs map[string]APIMetrics `json:"apis"`
+	// Circuit is the state of the circuit breaker and CircuitOpened the number of times it opened
+	Circuit       string `json:"circuit"`
+	CircuitOpened int64  `json:"circuitOpened"`
+}
+
+// published is the throttle whose metrics are published under MetricsName. The expvar variable is
+// only published once, as publishing a name twice panics, and reads the latest throttle.
+var published = struct {
+	sync.Mutex
+	once     sync.Once
+	throttle *Throttle
+}{}
+
+// PublishMetrics publishes the metrics of the throttle with expvar under MetricsName, replacing
+// the throttle published before
+func PublishMetrics(throttle *Throttle) {
+	published.Lock()
+	published.throttle = throttle
+	published.Unlock()
+
+	published.once.Do(func() {
+		expvar.Publish(MetricsName, expvar.Func(func() interface{} {
+			published.Lock()
+			defer published.Unlock()
+			return published.throttle.Metrics()
+		}))
+	})
+}
+
+// Throttle applies the rate limits, retries and circuit breaking of a config to ECS clients.
+// Every ECS client of a service should share one so that they are limited together. It is an
+// expvar.Var, so that its metrics can be published.
+type Throttle struct {
+	config  Config
+	breaker *circuitBreaker
+
+	// now, sleep and jitter are replaced in tests
+	now    func() time.Time
+	sleep  func(ctx aws.Context, d time.Duration) error
+	jitter func(n int64) int64
+
+	// stopped is closed by Stop, which ends the waits for the rate limit
+	stopped  chan struct{}
+	stopOnce sync.Once
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	metrics map[string]*APIMetrics
+}
+
+// NewThrottle returns a throttle with the rate limits, retries and circuit breaking of the config
+func NewThrottle(config Config) (*Throttle, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var randomMu sync.Mutex
+	throttle := &Throttle{
+		config:  config,
+		breaker: newCircuitBreaker(config.CircuitThreshold, config.CircuitCooldown),
+		now:     time.Now,
+		jitter: func(n int64) int64 {
+			randomMu.Lock()
+			defer randomMu.Unlock()
+			return random.Int63n(n)
+		},
+		stopped: make(chan struct{}),
+		buckets: make(map[string]*tokenBucket),
+		metrics: make(map[string]*APIMetrics),
+	}
+	throttle.sleep = throttle.wait
+	return throttle, nil
+}
+
+// Stop ends the waits for the rate limit in progress and fails the requests that would wait from
+// then on, so that shutting down is not held up by throttled requests
+func (t *Throttle) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stopped)
+	})
+}
+
+// Apply makes the ECS client wait for the rate limit of each API before sending a request,
+// retry throttled and failed requests and fail straight away while the circuit is open
+func (t *Throttle) Apply(client *ecs.ECS) {
+	client.Retryer = t
+	client.Handlers.Sign.PushFrontNamed(request.NamedHandler{Name: "ecsclient.Throttle", Fn: t.beforeSend})
+	client.Handlers.Retry.PushBackNamed(request.NamedHandler{Name: "ecsclient.RecordFailure", Fn: t.afterFailure})
+	client.Handlers.AfterRetry.PushBackNamed(request.NamedHandler{Name: "ecsclient.RecordRetry", Fn: t.afterRetry})
+	client.Handlers.Unmarshal.PushBackNamed(request.NamedHandler{Name: "ecsclient.RecordSuccess", Fn: t.afterSuccess})
+}
+
+// MaxRetries returns the number of times a throttled or failed request is retried
+func (t *Throttle) MaxRetries() int {
+	return t.config.MaxRetries
+}
+
+// ShouldRetry returns whether the request was throttled, failed with a 5xx response or did not
+// reach ECS
+func (t *Throttle) ShouldRetry(r *request.Request) bool {
+	if isCircuitOpen(r) {
+		return false
+	}
+	return isThrottled(r) || isServerError(r) || r.IsErrorRetryable()
+}
+
+// RetryRules returns a random delay up to the base delay doubled with every retry, so that
+// throttled clients do not all come back at once
+func (t *Throttle) RetryRules(r *request.Request) time.Duration {
+	ceiling := t.config.RetryMaxDelay
+	if r.RetryCount < 32 {
+		if delay := t.config.RetryBaseDelay << uint(r.RetryCount); delay > 0 && delay < ceiling {
+			ceiling = delay
+		}
+	}
+	return time.Duration(t.jitter(int64(ceiling) + 1))
+}
+
+// Metrics returns the metrics of the requests made so far
+func (t *Throttle) Metrics() Metrics {
+	state, opened := t.breaker.status()
+	metrics := Metrics{
+		APIs:          make(map[string]APIMetrics),
+		Circuit:       state,
+		CircuitOpened: opened,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for api, m := range t.metrics {
+		metrics.APIs[api] = *m
+	}
+	return metrics
+}
+
+// String returns the metrics as JSON
+func (t *Throttle) String() string {
+	b, err := json.Marshal(t.Metrics())
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+func (t *Throttle) beforeSend(r *request.Request) {
+	api := r.Operation.Name
+	if !t.breaker.allow(t.now()) {
+		t.record(api, func(m *APIMetrics) { m.Rejected++ })
+		r.Error = awserr.New(ErrCodeCircuitOpen, "ECS is not called after too many throttled or failed requests", nil)
+		r.Retryable = aws.Bool(false)
+		return
+	}
+
+	if bucket := t.bucket(api); bucket != nil {
+		if wait := bucket.reserve(t.now()); wait > 0 {
+			t.record(api, func(m *APIMetrics) { m.RateLimitedSeconds += wait.Seconds() })
+			if err := t.sleep(r.Context(), wait); err != nil {
+				bucket.refund()
+				r.Error = err
+				r.Retryable = aws.Bool(false)
+				return
+			}
+		}
+	}
+	t.record(api, func(m *APIMetrics) { m.Requests++ })
+}
+
+// wait waits for d, and returns an error if the context of the request is done or the throttle
+// is stopped first
+func (t *Throttle) wait(ctx aws.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return awserr.New(request.CanceledErrorCode, "Request cancelled while waiting for the rate limit", ctx.Err())
+	case <-t.stopped:
+		return awserr.New(ErrCodeThrottleStopped, "Request not sent as the ECS client is stopping", nil)
+	}
+}
+
+func (t *Throttle) afterFailure(r *request.Request) {
+	if isCircuitOpen(r) {
+		return
+	}
+
+	api := r.Operation.Name
+	switch {
+	case isThrottled(r):
+		t.record(api, func(m *APIMetrics) { m.Throttled++ })
+	case isServerError(r):
+		t.record(api, func(m *APIMetrics) { m.ServerErrors++ })
+	case r.IsErrorRetryable() || aws.BoolValue(r.Retryable):
+		t.record(api, func(m *APIMetrics) { m.RequestErrors++ })
+	default:
+		// ECS answered, the request itself was wrong
+		t.breaker.success()
+		return
+	}
+	t.breaker.failure(t.now())
+}
+
+func (t *Throttle) afterRetry(r *request.Request) {
+	// the error is cleared when the request is going to be retried
+	if r.Error == nil {
+		t.record(r.Operation.Name, func(m *APIMetrics) { m.Retries++ })
+	}
+}
+
+func (t *Throttle) afterSuccess(r *request.Request) {
+	if r.Error == nil {
+		t.breaker.success()
+	}
+}
+
+// bucket returns the token bucket of the API, or nil if it is not rate limited
+func (t *Throttle) bucket(api string) *tokenBucket {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if bucket, ok := t.buckets[api]; ok {
+		return bucket
+	}
+
+	rate := t.config.RateLimit
+	if apiRate, ok := t.config.APIRateLimits[api]; ok {
+		rate = apiRate
+	}
+	var bucket *tokenBucket
+	if rate > 0 {
+		bucket = newTokenBucket(rate, t.config.Burst, t.now())
+	}
+	t.buckets[api] = bucket
+	return bucket
+}
+
+func (t *Throttle) record(api string, update func(*APIMetrics)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m, ok := t.metrics[api]
+	if !ok {
+		m = &APIMetrics{}
+		t.metrics[api] = m
+	}
+	update(m)
+}
+
+func isCircuitOpen(r *request.Request) bool {
+	err, ok := r.Error.(awserr.Error)
+	return ok && err.Code() == ErrCodeCircuitOpen
+}
+
+func isThrottled(r *request.Request) bool {
+	return r.IsErrorThrottle() || (r.HTTPResponse != nil && r.HTTPResponse.StatusCode == 429)
+}
+
+func isServerError(r *request.Request) bool {
+	return r.HTTPResponse != nil && r.HTTPResponse.StatusCode >= 500
+}
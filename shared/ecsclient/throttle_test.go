@@ -0,0 +1,350 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ecsclient
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	throttlingResponse = `{"__type":"ThrottlingException","message":"Rate exceeded"}`
+	serverResponse     = `{"__type":"ServerException","message":"Internal error"}`
+	clientResponse     = `{"__type":"ClientException","message":"Unknown cluster"}`
+	listTasksResponse  = `{"taskArns":["arn:aws:ecs:us-east-1:123456789012:task/test"]}`
+)
+
+// response is what the ECS server answers a request with
+type response struct {
+	status int
+	body   string
+}
+
+type ThrottleTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	ecs      *ecs.ECS
+	throttle *Throttle
+
+	// responses are answered in order, followed by listTasksResponse
+	mu        sync.Mutex
+	responses []response
+	requests  int
+
+	// clock is the time seen by the throttle, which sleeping moves forward
+	clock  time.Time
+	slept  []time.Duration
+	delays []int64
+}
+
+func (suite *ThrottleTestSuite) SetupTest() {
+	suite.responses = nil
+	suite.requests = 0
+	suite.clock = time.Unix(1000, 0)
+	suite.slept = nil
+	suite.delays = nil
+
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.mu.Lock()
+		defer suite.mu.Unlock()
+		suite.requests++
+		resp := response{status: http.StatusOK, body: listTasksResponse}
+		if len(suite.responses) > 0 {
+			resp = suite.responses[0]
+			suite.responses = suite.responses[1:]
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.WriteHeader(resp.status)
+		w.Write([]byte(resp.body))
+	}))
+
+	config := DefaultConfig()
+	config.RateLimit = 2
+	config.Burst = 1
+	config.CircuitThreshold = 3
+	suite.ecs, suite.throttle = suite.newClient(config)
+}
+
+func (suite *ThrottleTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func TestThrottleTestSuite(t *testing.T) {
+	suite.Run(t, new(ThrottleTestSuite))
+}
+
+func (suite *ThrottleTestSuite) newClient(config Config) (*ecs.ECS, *Throttle) {
+	throttle, err := NewThrottle(config)
+	assert.Nil(suite.T(), err, "Cannot initialize ThrottleTestSuite")
+	throttle.now = func() time.Time {
+		return suite.clock
+	}
+	throttle.sleep = func(_ aws.Context, d time.Duration) error {
+		suite.slept = append(suite.slept, d)
+		suite.clock = suite.clock.Add(d)
+		return nil
+	}
+	throttle.jitter = func(n int64) int64 {
+		suite.delays = append(suite.delays, n)
+		// retries are not waited for in tests
+		return 0
+	}
+
+	client := ecs.New(session.New(&aws.Config{
+		Endpoint:    aws.String(suite.server.URL),
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}))
+	throttle.Apply(client)
+	return client, throttle
+}
+
+func (suite *ThrottleTestSuite) respond(responses ...response) {
+	suite.mu.Lock()
+	defer suite.mu.Unlock()
+	suite.responses = responses
+}
+
+func (suite *ThrottleTestSuite) requestCount() int {
+	suite.mu.Lock()
+	defer suite.mu.Unlock()
+	return suite.requests
+}
+
+func (suite *ThrottleTestSuite) listTasks() error {
+	_, err := suite.ecs.ListTasks(&ecs.ListTasksInput{Cluster: aws.String("test")})
+	return err
+}
+
+func (suite *ThrottleTestSuite) TestNewThrottleInvalidConfig() {
+	config := DefaultConfig()
+	config.Burst = 0
+	_, err := NewThrottle(config)
+	assert.Error(suite.T(), err, "Expected an error when the burst is zero")
+
+	config = DefaultConfig()
+	config.APIRateLimits = map[string]float64{"ListTasks": -1}
+	_, err = NewThrottle(config)
+	assert.Error(suite.T(), err, "Expected an error when an API rate limit is negative")
+}
+
+func (suite *ThrottleTestSuite) TestParseAPIRateLimits() {
+	limits, err := ParseAPIRateLimits([]string{"DescribeTasks=20", "ListTasks=0.5"})
+	assert.Nil(suite.T(), err, "Unexpected error when parsing API rate limits")
+	assert.Equal(suite.T(), map[string]float64{"DescribeTasks": 20, "ListTasks": 0.5}, limits)
+
+	for _, value := range []string{"DescribeTasks", "=20", "DescribeTasks=fast", "DescribeTasks=-1"} {
+		_, err = ParseAPIRateLimits([]string{value})
+		assert.Error(suite.T(), err, "Expected an error when parsing %s", value)
+	}
+}
+
+func (suite *ThrottleTestSuite) TestRateLimitWaitsForToken() {
+	for i := 0; i < 3; i++ {
+		assert.Nil(suite.T(), suite.listTasks(), "Unexpected error when listing tasks")
+	}
+	assert.Equal(suite.T(), []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}, suite.slept,
+		"Expected requests over the burst to wait for the rate limit")
+
+	metrics := suite.throttle.Metrics()
+	assert.Equal(suite.T(), int64(3), metrics.APIs["ListTasks"].Requests)
+	assert.Equal(suite.T(), 1.0, metrics.APIs["ListTasks"].RateLimitedSeconds)
+}
+
+func (suite *ThrottleTestSuite) TestStopEndsRateLimitWait() {
+	suite.throttle.sleep = suite.throttle.wait
+	assert.Nil(suite.T(), suite.listTasks(), "Unexpected error when listing tasks")
+
+	suite.throttle.Stop()
+	suite.throttle.Stop()
+	err := suite.listTasks()
+	assert.Error(suite.T(), err, "Expected an error when the throttle stopped while waiting for the rate limit")
+	assert.Equal(suite.T(), ErrCodeThrottleStopped, err.(awserr.Error).Code())
+	assert.Equal(suite.T(), 1, suite.requestCount(), "Expected the request not to be sent")
+}
+
+func (suite *ThrottleTestSuite) TestCancelledContextEndsRateLimitWait() {
+	suite.throttle.sleep = suite.throttle.wait
+	assert.Nil(suite.T(), suite.listTasks(), "Unexpected error when listing tasks")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := suite.ecs.ListTasksWithContext(ctx, &ecs.ListTasksInput{Cluster: aws.String("test")})
+	assert.Error(suite.T(), err, "Expected an error when the request is cancelled while waiting for the rate limit")
+	assert.Equal(suite.T(), request.CanceledErrorCode, err.(awserr.Error).Code())
+	assert.Equal(suite.T(), 1, suite.requestCount(), "Expected the request not to be sent")
+}
+
+func (suite *ThrottleTestSuite) TestEndedRateLimitWaitReturnsToken() {
+	sleep := suite.throttle.sleep
+	suite.throttle.sleep = func(ctx aws.Context, d time.Duration) error {
+		suite.throttle.sleep = sleep
+		return awserr.New(request.CanceledErrorCode, "cancelled", nil)
+	}
+	assert.Nil(suite.T(), suite.listTasks(), "Unexpected error when listing tasks")
+	assert.Error(suite.T(), suite.listTasks(), "Expected an error when the rate limit wait ends early")
+
+	assert.Nil(suite.T(), suite.listTasks(), "Unexpected error when listing tasks")
+	assert.Equal(suite.T(), []time.Duration{500 * time.Millisecond}, suite.slept,
+		"Expected the token of the request that gave up waiting to be handed to the next one")
+}
+
+func (suite *ThrottleTestSuite) TestRateLimitPerAPI() {
+	config := DefaultConfig()
+	config.RateLimit = 1
+	config.Burst = 1
+	config.APIRateLimits = map[string]float64{"ListClusters": 0}
+	suite.ecs, suite.throttle = suite.newClient(config)
+
+	assert.Nil(suite.T(), suite.listTasks(), "Unexpected error when listing tasks")
+	for i := 0; i < 2; i++ {
+		_, err := suite.ecs.ListClusters(&ecs.ListClustersInput{})
+		assert.Nil(suite.T(), err, "Unexpected error when listing clusters")
+	}
+	assert.Empty(suite.T(), suite.slept, "Expected each API to have its own limit, and ListClusters none")
+}
+
+func (suite *ThrottleTestSuite) TestRetryThrottledAndServerErrors() {
+	suite.respond(
+		response{status: http.StatusBadRequest, body: throttlingResponse},
+		response{status: http.StatusInternalServerError, body: serverResponse},
+	)
+
+	assert.Nil(suite.T(), suite.listTasks(), "Expected the request to succeed after retries")
+	assert.Equal(suite.T(), 3, suite.requestCount())
+	assert.Equal(suite.T(), []int64{
+		int64(DefaultRetryBaseDelay) + 1,
+		int64(2*DefaultRetryBaseDelay) + 1,
+	}, suite.delays, "Expected the jittered delay to double with every retry")
+
+	metrics := suite.throttle.Metrics().APIs["ListTasks"]
+	assert.Equal(suite.T(), APIMetrics{
+		Requests:           3,
+		Throttled:          1,
+		ServerErrors:       1,
+		Retries:            2,
+		RateLimitedSeconds: 1,
+	}, metrics)
+}
+
+func (suite *ThrottleTestSuite) TestRetryGivesUp() {
+	config := DefaultConfig()
+	config.MaxRetries = 1
+	suite.ecs, suite.throttle = suite.newClient(config)
+	suite.respond(
+		response{status: http.StatusBadRequest, body: throttlingResponse},
+		response{status: http.StatusBadRequest, body: throttlingResponse},
+	)
+
+	err := suite.listTasks()
+	assert.Error(suite.T(), err, "Expected an error when the request is still throttled")
+	assert.Equal(suite.T(), "ThrottlingException", err.(awserr.Error).Code())
+	assert.Equal(suite.T(), 2, suite.requestCount())
+}
+
+func (suite *ThrottleTestSuite) TestNoRetryOnClientError() {
+	suite.respond(response{status: http.StatusBadRequest, body: clientResponse})
+
+	err := suite.listTasks()
+	assert.Error(suite.T(), err, "Expected the client error to be returned")
+	assert.Equal(suite.T(), 1, suite.requestCount(), "Expected client errors not to be retried")
+	assert.Equal(suite.T(), APIMetrics{Requests: 1}, suite.throttle.Metrics().APIs["ListTasks"])
+}
+
+func (suite *ThrottleTestSuite) TestCircuitOpensAndCloses() {
+	config := DefaultConfig()
+	config.MaxRetries = 0
+	config.CircuitThreshold = 2
+	suite.ecs, suite.throttle = suite.newClient(config)
+	suite.respond(
+		response{status: http.StatusServiceUnavailable, body: serverResponse},
+		response{status: http.StatusServiceUnavailable, body: serverResponse},
+	)
+
+	assert.Error(suite.T(), suite.listTasks(), "Expected the first failure to be returned")
+	assert.Error(suite.T(), suite.listTasks(), "Expected the second failure to be returned")
+	assert.Equal(suite.T(), CircuitOpen, suite.throttle.Metrics().Circuit)
+
+	err := suite.listTasks()
+	assert.Error(suite.T(), err, "Expected an error while the circuit is open")
+	assert.Equal(suite.T(), ErrCodeCircuitOpen, err.(awserr.Error).Code())
+	assert.Equal(suite.T(), 2, suite.requestCount(), "Expected ECS not to be called while the circuit is open")
+
+	suite.clock = suite.clock.Add(DefaultCircuitCooldown)
+	assert.Nil(suite.T(), suite.listTasks(), "Expected a request to be let through after the cooldown")
+
+	metrics := suite.throttle.Metrics()
+	assert.Equal(suite.T(), CircuitClosed, metrics.Circuit)
+	assert.Equal(suite.T(), int64(1), metrics.CircuitOpened)
+	assert.Equal(suite.T(), int64(1), metrics.APIs["ListTasks"].Rejected)
+}
+
+func (suite *ThrottleTestSuite) TestCircuitReopensAfterFailedProbe() {
+	config := DefaultConfig()
+	config.MaxRetries = 0
+	config.CircuitThreshold = 1
+	suite.ecs, suite.throttle = suite.newClient(config)
+	suite.respond(
+		response{status: http.StatusBadRequest, body: throttlingResponse},
+		response{status: http.StatusBadRequest, body: throttlingResponse},
+	)
+
+	assert.Error(suite.T(), suite.listTasks(), "Expected the throttling error to be returned")
+	suite.clock = suite.clock.Add(DefaultCircuitCooldown)
+	assert.Error(suite.T(), suite.listTasks(), "Expected the probe to be throttled")
+
+	err := suite.listTasks()
+	assert.Equal(suite.T(), ErrCodeCircuitOpen, err.(awserr.Error).Code(),
+		"Expected the circuit to open again after the probe failed")
+	assert.Equal(suite.T(), int64(2), suite.throttle.Metrics().CircuitOpened)
+}
+
+func (suite *ThrottleTestSuite) TestMetricsString() {
+	assert.Nil(suite.T(), suite.listTasks(), "Unexpected error when listing tasks")
+	metrics := suite.throttle.String()
+	assert.True(suite.T(), strings.Contains(metrics, `"ListTasks":{"requests":1,`), "Unexpected metrics %s", metrics)
+	assert.True(suite.T(), strings.Contains(metrics, `"circuit":"closed"`), "Unexpected metrics %s", metrics)
+}
+
+func (suite *ThrottleTestSuite) TestPublishMetricsTwice() {
+	suite.throttle.record("ListTasks", func(m *APIMetrics) { m.Requests++ })
+	PublishMetrics(suite.throttle)
+
+	throttle, err := NewThrottle(DefaultConfig())
+	assert.Nil(suite.T(), err, "Unexpected error creating a throttle")
+	throttle.record("DescribeTasks", func(m *APIMetrics) { m.Requests++ })
+	PublishMetrics(throttle)
+
+	var metrics Metrics
+	err = json.Unmarshal([]byte(expvar.Get(MetricsName).String()), &metrics)
+	assert.Nil(suite.T(), err, "Unexpected error reading the published metrics")
+	assert.Contains(suite.T(), metrics.APIs, "DescribeTasks", "Expected the metrics of the latest throttle")
+	assert.NotContains(suite.T(), metrics.APIs, "ListTasks", "Expected the metrics of the earlier throttle to be replaced")
+}
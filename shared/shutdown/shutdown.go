@@ -0,0 +1,93 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shutdown serves HTTP requests until the process is asked to terminate and then winds
+// the service down gracefully
+package shutdown
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// shutdownTimeout bounds how long the service drains requests and waits for
+// background workers after receiving a termination signal
+const shutdownTimeout = 30 * time.Second
+
+// ServeUntilSignal serves requests on s until the server fails or the process
+// receives SIGINT or SIGTERM. On a signal the server stops accepting
+// connections and drains in-flight requests, then stop is called to wind down
+// background work. Both share a deadline of shutdownTimeout, and stop is called
+// even if the requests could not be drained in time.
+func ServeUntilSignal(s *http.Server, stop func(ctx context.Context) error) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case sig := <-signals:
+		log.Infof("Received signal %s, shutting down", sig)
+	}
+
+	return shutdown(s, stop, shutdownTimeout)
+}
+
+func shutdown(s *http.Server, stop func(ctx context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	drainErr := s.Shutdown(ctx)
+	stopErr := stop(ctx)
+	if drainErr != nil {
+		return errors.Wrapf(drainErr, "Could not drain in-flight requests")
+	}
+	if stopErr != nil {
+		return stopErr
+	}
+
+	log.Infof("Shutdown complete")
+	return nil
+}
+
+// WaitFor calls each of waits in turn and returns an error if they do not all
+// return before ctx is done
+func WaitFor(ctx context.Context, waits ...func()) error {
+	done := make(chan struct{})
+	go func() {
+		for _, wait := range waits {
+			wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Errorf("Timed out waiting for background workers to stop")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package shutdown
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownWaitsForWorkers(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		wg.Done()
+	}()
+
+	err := shutdown(&http.Server{}, func(ctx context.Context) error {
+		return WaitFor(ctx, wg.Wait)
+	}, time.Second)
+	assert.Nil(t, err, "Unexpected error shutting down")
+}
+
+func TestShutdownTimesOut(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	defer wg.Done()
+
+	err := shutdown(&http.Server{}, func(ctx context.Context) error {
+		return WaitFor(ctx, wg.Wait)
+	}, 10*time.Millisecond)
+	assert.Error(t, err, "Expected error when workers do not stop in time")
+}
+
+func TestShutdownStopsWorkersWhenDrainTimesOut(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err, "Unexpected error listening")
+
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	s := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(handling)
+		<-release
+	})}
+	go s.Serve(listener)
+	go http.Get("http://" + listener.Addr().String())
+	<-handling
+
+	stopped := false
+	err = shutdown(s, func(ctx context.Context) error {
+		stopped = true
+		return nil
+	}, 10*time.Millisecond)
+	assert.Error(t, err, "Expected error when in-flight requests do not drain in time")
+	assert.True(t, stopped, "Expected background workers to be stopped after the drain timed out")
+}